@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aygp-dr/adtap/internal/adpreview"
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/compose"
+)
+
+func cmdAds(args []string) {
+	fs := newFlagSet("ads")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	adGroup := fs.Int64("ad-group", 0, "Show the ads of this ad group ID")
+	campaign := fs.Int64("campaign", 0, "Show the ads of this campaign ID")
+	policyOnly := fs.Bool("policy-issues-only", false, "Show only ads that are disapproved or approved with limits")
+	format := fs.String("format", "human", "Output format: human, json")
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap ads --customer-id ID [--ad-group ID] [--campaign ID] [--policy-issues-only]")
+		fmt.Fprintln(os.Stderr, "\nPreview ads as they read: responsive search ad headlines and")
+		fmt.Fprintln(os.Stderr, "descriptions with their pins, final and display URLs, and the policy")
+		fmt.Fprintln(os.Stderr, "approval status with the topics that limit or block serving.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if *customerID == "" {
+		usageError("ads", "--customer-id is required")
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	if *format != "human" && *format != "json" {
+		exitValidationError("invalid output format %q\n\nExpected: human, json", *format)
+	}
+
+	q := compose.Ads(compose.AdOptions{AdGroupID: *adGroup, CampaignID: *campaign, PolicyIssuesOnly: *policyOnly})
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+	}
+	resp, err := newClient().Search(context.Background(), id, q.String())
+	if err != nil {
+		exitAPIError(err)
+	}
+
+	ads := make([]adpreview.Ad, len(resp.Results))
+	for i, row := range resp.Results {
+		ads[i] = adpreview.Decode(row)
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(ads); err != nil {
+			exitIOError(err)
+		}
+		return
+	}
+	if len(ads) == 0 {
+		fmt.Fprintln(os.Stderr, "No ads found")
+		return
+	}
+	for _, ad := range ads {
+		if err := ad.WriteText(os.Stdout); err != nil {
+			exitIOError(err)
+		}
+	}
+}
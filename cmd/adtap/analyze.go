@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/usage"
+)
+
+func cmdAnalyze(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		analyzeUsage()
+		os.Exit(0)
+	}
+	switch args[0] {
+	case "usage":
+		analyzeFieldUsage(args[1:])
+	default:
+		usageError("analyze", fmt.Sprintf("unknown subcommand %q (expected usage)", args[0]))
+	}
+}
+
+func analyzeUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap analyze usage [flags] FILE|DIR|- ...")
+	fmt.Fprintln(os.Stderr, "\nAnalyze a corpus of stored GAQL queries (.gaql files).")
+	fmt.Fprintln(os.Stderr, "Run 'adtap analyze usage --help' for its flags.")
+}
+
+// analyzeFieldUsage reports which resources and fields the queries in
+// the given files use, and where.
+func analyzeFieldUsage(args []string) {
+	fs := newFlagSet("analyze usage")
+	format := fs.String("format", "human", "Output format: human, json")
+	match := fs.String("match", "", "Report only names starting with this prefix, such as metrics. or campaign.status")
+	locations := fs.Bool("locations", false, "List every use of each name as FILE:LINE:COLUMN (human format)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap analyze usage [flags] FILE|DIR|- ...")
+		fmt.Fprintln(os.Stderr, "\nReport which resources, fields, segments, and metrics the queries in")
+		fmt.Fprintln(os.Stderr, ".gaql files use, in which files and clauses, and which are deprecated")
+		fmt.Fprintln(os.Stderr, "or unknown to the catalog, to judge the reach of an API migration.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		usageError("analyze", "at least one file or directory is required")
+	}
+	if *format != "human" && *format != "json" {
+		exitValidationError("invalid output format %q\n\nExpected: human, json", *format)
+	}
+
+	files, err := collectQueryFiles(fs.Args())
+	if err != nil {
+		exitIOError(err)
+	}
+	a := usage.New()
+	for _, path := range files {
+		src, err := readQueryFile(path)
+		if err != nil {
+			exitIOError(err)
+		}
+		a.AddSource(path, src)
+	}
+
+	r := a.Report()
+	if *match != "" {
+		items := r.Items[:0]
+		for _, it := range r.Items {
+			if strings.HasPrefix(it.Name, *match) {
+				items = append(items, it)
+			}
+		}
+		r.Items = items
+	}
+	if *format == "json" {
+		err = r.WriteJSON(os.Stdout)
+	} else {
+		err = r.WriteText(os.Stdout, *locations)
+	}
+	if err != nil {
+		exitIOError(err)
+	}
+	if len(r.Failures) > 0 {
+		os.Exit(exitcode.ValidationError)
+	}
+}
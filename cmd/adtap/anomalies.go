@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/anomaly"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+func cmdAnomalies(args []string) {
+	fs := newFlagSet("anomalies")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	metric := fs.String("metric", "metrics.clicks", "Metric to analyse (short name or metrics.* field)")
+	by := fs.String("by", "", "Field to split series by, e.g. campaign.id (default: whole account)")
+	during := fs.String("during", "LAST_30_DAYS", "Date range keyword of the daily series")
+	lookback := fs.String("lookback", "", "Trailing period of the daily series ending yesterday, such as 90d or 12w, instead of --during")
+	threshold := fs.Float64("threshold", 3, "Absolute z-score at which a day is flagged")
+	seasonal := fs.Bool("seasonal", false, "Compare each day with the same weekday in other weeks")
+	method := fs.String("method", "mean", "Baseline: mean of the other days, or median of the --window days nearest, robust to other spikes")
+	window := fs.Int("window", 28, "Days around each day the median baseline compares it with")
+	alert := fs.Bool("alert", false, "Exit 8 when any day is flagged")
+	out := addOutputFlags(fs)
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap anomalies --customer-id ID [--metric M] [--by FIELD] [flags]")
+		fmt.Fprintln(os.Stderr, "\nPull a daily metric series and list the days that deviate sharply")
+		fmt.Fprintln(os.Stderr, "from the rest, most unusual first. With --alert, flagged days exit 8")
+		fmt.Fprintln(os.Stderr, "so scheduled runs can page on them.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if *customerID == "" {
+		usageError("anomalies", "--customer-id is required")
+	}
+	if *threshold <= 0 {
+		usageError("anomalies", "--threshold must be positive")
+	}
+	if *window < 2 {
+		usageError("anomalies", "--window must be at least 2")
+	}
+	m, err := anomaly.ParseMethod(*method)
+	if err != nil {
+		usageError("anomalies", "--method: "+strings.TrimPrefix(err.Error(), "anomaly: "))
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	var span compose.DateSpan
+	if *lookback != "" {
+		if flagSet(fs, "during") {
+			usageError("anomalies", "--lookback and --during are mutually exclusive")
+		}
+		if span, err = compose.ParseLast(*lookback, time.Now()); err != nil {
+			usageError("anomalies", "--lookback: "+strings.TrimPrefix(err.Error(), "compose: "))
+		}
+	} else if span.During, err = compose.ParseDuring(*during); err != nil {
+		usageError("anomalies", fmt.Sprintf("invalid --during %q", *during))
+	}
+	q, err := compose.DailySeries(*metric, *by, span)
+	if err != nil {
+		exitValidationError("%v", err)
+	}
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+	}
+	_, r, ropts := out.renderer()
+
+	resp, err := newClient().Search(context.Background(), id, q.String())
+	if err != nil {
+		exitAPIError(err)
+	}
+
+	// DailySeries selects [by [name]] segments.date metric.
+	metricField := q.Select[len(q.Select)-1].Name
+	var nameField string
+	if len(q.Select) == 4 {
+		nameField = q.Select[1].Name
+	}
+
+	rows := make([]map[string]any, len(resp.Results))
+	names := make(map[string]string)
+	for i, row := range resp.Results {
+		rows[i] = row
+		if nameField != "" {
+			key, _ := output.Value(row, *by)
+			name, _ := output.Value(row, nameField)
+			names[fmt.Sprint(key)] = fmt.Sprint(name)
+		}
+	}
+
+	opts := anomaly.Options{Threshold: *threshold, Seasonal: *seasonal, Method: m, Window: *window}
+	var found []anomaly.Anomaly
+	for _, s := range anomaly.SeriesFromRows(rows, *by, metricField) {
+		found = append(found, anomaly.Detect(s, opts)...)
+	}
+	if len(found) == 0 {
+		fmt.Fprintln(os.Stderr, "No anomalies found.")
+		return
+	}
+	sort.SliceStable(found, func(i, j int) bool { return math.Abs(found[i].Z) > math.Abs(found[j].Z) })
+
+	fields := []string{"date", "day"}
+	if *by != "" {
+		fields = append(fields, *by)
+		if nameField != "" {
+			fields = append(fields, nameField)
+		}
+	}
+	fields = append(fields, metricField, "expected", "z")
+
+	if err := r.WriteHeader(ropts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	for _, a := range found {
+		values := []any{a.Date.Format(time.DateOnly), a.Date.Weekday().String()[:3]}
+		if *by != "" {
+			values = append(values, a.Key)
+			if nameField != "" {
+				values = append(values, names[a.Key])
+			}
+		}
+		values = append(values, a.Value, round1(a.Expected), round1(a.Z))
+		if err := ropts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+	if *alert {
+		fmt.Fprintf(os.Stderr, "Alert: %d anomalous day(s) in %s\n", len(found), metricField)
+		os.Exit(exitcode.Alert)
+	}
+}
+
+func round1(x float64) float64 {
+	return math.Round(x*10) / 10
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/auth"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+)
+
+// loginTimeout bounds how long auth login waits for the browser.
+const loginTimeout = 5 * time.Minute
+
+func cmdAuth(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		authUsage()
+		os.Exit(0)
+	}
+	switch args[0] {
+	case "login":
+		authLogin(args[1:])
+	case "status":
+		authStatus(args[1:])
+	case "logout":
+		authLogout(args[1:])
+	default:
+		usageError("auth", fmt.Sprintf("unknown subcommand %q (expected login, status, or logout)", args[0]))
+	}
+}
+
+func authUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap auth login [--client-secrets FILE] [--no-browser]")
+	fmt.Fprintln(os.Stderr, "       adtap auth status")
+	fmt.Fprintln(os.Stderr, "       adtap auth logout")
+	fmt.Fprintln(os.Stderr, "\nSign in with a Google account instead of a service account. login opens")
+	fmt.Fprintln(os.Stderr, "a browser for consent and saves the refresh token in the OS keychain, or")
+	fmt.Fprintln(os.Stderr, "in a file in the config directory (encrypted when ADTAP_CREDENTIALS_KEY")
+	fmt.Fprintln(os.Stderr, "is set). GOOGLE_APPLICATION_CREDENTIALS still takes precedence.")
+	fmt.Fprintln(os.Stderr, "\nThe OAuth2 client is a desktop app client from the Google Cloud console,")
+	fmt.Fprintln(os.Stderr, "given as --client-secrets or GOOGLE_ADS_CLIENT_ID and GOOGLE_ADS_CLIENT_SECRET.")
+}
+
+func authLogin(args []string) {
+	fs := newFlagSet("auth login")
+	secrets := fs.String("client-secrets", "", "Client secrets JSON of a desktop OAuth2 client")
+	noBrowser := fs.Bool("no-browser", false, "Print the consent URL instead of opening a browser")
+	fs.Usage = func() {
+		authUsage()
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError("auth", fmt.Sprintf("unexpected argument %q", fs.Arg(0)))
+	}
+
+	app := &auth.InstalledApp{
+		ClientID:     os.Getenv("GOOGLE_ADS_CLIENT_ID"),
+		ClientSecret: os.Getenv("GOOGLE_ADS_CLIENT_SECRET"),
+	}
+	if *secrets != "" {
+		var err error
+		if app, err = auth.LoadClientSecrets(*secrets); err != nil {
+			exitSetupError(configError(err.Error(), "download the client secrets of a desktop app client from the Google Cloud console."))
+		}
+	}
+	if app.ClientID == "" {
+		exitSetupError(configError("no OAuth2 client configured",
+			"pass --client-secrets, or set GOOGLE_ADS_CLIENT_ID and GOOGLE_ADS_CLIENT_SECRET."))
+	}
+	store, err := auth.DefaultStore()
+	if err != nil {
+		exitSetupError(configError(err.Error(), "set ADTAP_CREDENTIALS_STORE to keychain or file."))
+	}
+
+	open := func(consentURL string) error {
+		fmt.Fprintf(os.Stderr, "Sign in at:\n\n  %s\n\n", consentURL)
+		if !*noBrowser {
+			if err := openBrowser(consentURL); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning: could not open a browser; open the URL above yourself")
+			}
+		}
+		fmt.Fprintln(os.Stderr, "Waiting for consent...")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+	creds, err := app.Login(ctx, open)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("no consent received within %v", loginTimeout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Authentication error: %v\n", err)
+		os.Exit(exitcode.AuthError)
+	}
+	if err := store.Save(creds); err != nil {
+		exitIOError(err)
+	}
+	fmt.Fprintf(os.Stderr, "Signed in; credentials saved to %s\n", store)
+}
+
+func authStatus(args []string) {
+	if len(args) > 0 {
+		usageError("auth", "status takes no arguments")
+	}
+	for _, name := range []string{"GOOGLE_ADS_JSON_KEY_FILE_PATH", "GOOGLE_APPLICATION_CREDENTIALS"} {
+		if path := os.Getenv(name); path != "" {
+			fmt.Printf("Using credentials file %s (from %s)\n", path, name)
+			return
+		}
+	}
+	if os.Getenv("GOOGLE_ADS_REFRESH_TOKEN") != "" {
+		fmt.Println("Using the refresh token in GOOGLE_ADS_REFRESH_TOKEN")
+		return
+	}
+	provider, err := secretsProvider()
+	if err != nil {
+		exitSetupError(err.(*setupError))
+	}
+	if provider != nil {
+		_, err := auth.FromSecrets(context.Background(), provider)
+		switch {
+		case err == nil:
+			fmt.Printf("Using credentials from %s\n", provider)
+			return
+		case !errors.Is(err, auth.ErrNoCredentials):
+			fmt.Fprintf(os.Stderr, "Authentication error: %v\n", err)
+			os.Exit(exitcode.AuthError)
+		}
+	}
+	store, err := auth.DefaultStore()
+	if err != nil {
+		exitSetupError(configError(err.Error(), "set ADTAP_CREDENTIALS_STORE to keychain or file."))
+	}
+	_, err = store.Load()
+	switch {
+	case errors.Is(err, auth.ErrNotStored):
+		fmt.Printf("Not signed in (store: %s)\n", store)
+		os.Exit(exitcode.AuthError)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Authentication error: %v\n", err)
+		os.Exit(exitcode.AuthError)
+	}
+	fmt.Printf("Signed in; using credentials saved in %s\n", store)
+}
+
+func authLogout(args []string) {
+	if len(args) > 0 {
+		usageError("auth", "logout takes no arguments")
+	}
+	store, err := auth.DefaultStore()
+	if err != nil {
+		exitSetupError(configError(err.Error(), "set ADTAP_CREDENTIALS_STORE to keychain or file."))
+	}
+	if err := store.Delete(); err != nil {
+		exitIOError(err)
+	}
+	fmt.Fprintf(os.Stderr, "Signed out; removed credentials from %s\n", store)
+}
+
+// openBrowser opens url in the default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// configError returns a configuration setupError.
+func configError(msg, hint string) *setupError {
+	return &setupError{exitcode.ConfigError, "Configuration error", msg, hint}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/backfill"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+)
+
+// resumableFormats are the formats whose output can be resumed by
+// appending to it.
+var resumableFormats = []output.Format{output.FormatCSV, output.FormatTSV, output.FormatJSONL}
+
+func cmdBackfill(args []string) {
+	fs := newFlagSet("backfill")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	query := fs.String("query", "", "GAQL query, or a .gaql file holding one (- for stdin)")
+	from := fs.String("from", "", "First day to fetch, YYYY-MM-DD")
+	to := fs.String("to", "", "Last day to fetch, YYYY-MM-DD (default: yesterday)")
+	chunk := fs.String("chunk", "7d", "Days per window, such as 1d, 7d, or 2w")
+	parallel := fs.Int("parallel", 1, "Windows queried at once")
+	restart := fs.Bool("restart", false, "Ignore the checkpoint of --output and start over")
+	out := addOutputFlags(fs)
+	fs.Lookup("format").DefValue = string(output.FormatCSV)
+	fs.Set("format", string(output.FormatCSV))
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap backfill --customer-id ID --query QUERY --from DATE [--to DATE] [--chunk 7d] [--output FILE]")
+		fmt.Fprintln(os.Stderr, "\nFetch a long date range as a series of shorter windows, each the query")
+		fmt.Fprintln(os.Stderr, "with its segments.date conditions replaced by a BETWEEN of the window's")
+		fmt.Fprintln(os.Stderr, "days. segments.date is selected if the query does not, so the rows of")
+		fmt.Fprintln(os.Stderr, "each day stay apart. Windows are written in date order as csv, tsv, or")
+		fmt.Fprintln(os.Stderr, "jsonl; rows repeated within a window, as paging can repeat them when")
+		fmt.Fprintln(os.Stderr, "the data changes mid-read, are written once.")
+		fmt.Fprintln(os.Stderr, "\nWith --output, progress is saved to FILE.checkpoint after every window,")
+		fmt.Fprintln(os.Stderr, "and running the same command again resumes after the last window")
+		fmt.Fprintln(os.Stderr, "written. The checkpoint is removed once the backfill completes.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	switch {
+	case *customerID == "":
+		usageError("backfill", "--customer-id is required")
+	case *query == "":
+		usageError("backfill", "--query is required")
+	case *from == "":
+		usageError("backfill", "--from is required")
+	case *parallel < 1:
+		usageError("backfill", "--parallel must be at least 1")
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	start, err := time.Parse(backfill.DateLayout, *from)
+	if err != nil {
+		exitValidationError("invalid --from date\n\nExpected: YYYY-MM-DD\nGot: %s", *from)
+	}
+	y, m, d := time.Now().AddDate(0, 0, -1).Date()
+	end := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if *to != "" {
+		if end, err = time.Parse(backfill.DateLayout, *to); err != nil {
+			exitValidationError("invalid --to date\n\nExpected: YYYY-MM-DD\nGot: %s", *to)
+		}
+	}
+	if end.Before(start) {
+		usageError("backfill", fmt.Sprintf("--to %s is before --from %s", end.Format(backfill.DateLayout), *from))
+	}
+	days, err := backfill.ParseChunk(*chunk)
+	if err != nil {
+		usageError("backfill", strings.TrimPrefix(err.Error(), "backfill: "))
+	}
+	format, err := output.ParseFormat(*out.format)
+	if err != nil || !slices.Contains(resumableFormats, format) {
+		exitValidationError("invalid output format for backfill\n\nExpected: csv, tsv, jsonl\nGot: %s", *out.format)
+	}
+
+	text := *query
+	if text == "-" || strings.HasSuffix(text, ".gaql") {
+		if text, err = readQueryFile(*query); err != nil {
+			exitIOError(err)
+		}
+	}
+	q, err := gaql.Parse(text)
+	if err != nil {
+		exitValidationError("%v", err)
+	}
+	windows := backfill.Windows(start, end, days)
+	// Every window is the same query but for its dates, so checking the
+	// first checks them all.
+	validateQuery(gaql.NewValidator(), backfill.Query(q, windows[0]).String(), "")
+	fields := backfill.Query(q, windows[0]).FieldNames()
+
+	cp := backfill.Checkpoint{
+		CustomerID: id,
+		Query:      q.String(),
+		From:       start.Format(backfill.DateLayout),
+		To:         end.Format(backfill.DateLayout),
+		ChunkDays:  days,
+		Format:     string(format),
+	}
+	var (
+		file   *os.File
+		cpPath string
+	)
+	if *out.output != "" && *out.output != "-" {
+		cpPath = *out.output + ".checkpoint"
+		if !*restart {
+			cp, err = backfill.Resume(cpPath, cp)
+			if errors.Is(err, backfill.ErrMismatch) {
+				exitValidationError("%s was written by another backfill\n\nHint: run with --restart to replace %s", cpPath, *out.output)
+			}
+			if err != nil {
+				exitIOError(err)
+			}
+		}
+		// Cut the output back to the end of the last window written, so
+		// a window interrupted midway is written again, not twice.
+		if file, err = os.OpenFile(*out.output, os.O_WRONLY|os.O_CREATE, 0o644); err != nil {
+			exitIOError(err)
+		}
+		if err := file.Truncate(cp.Offset); err != nil {
+			exitIOError(err)
+		}
+		if _, err := file.Seek(cp.Offset, io.SeekStart); err != nil {
+			exitIOError(err)
+		}
+		out.file = file
+		if cp.Done > 0 {
+			fmt.Fprintf(os.Stderr, "Resuming after %d of %d windows (%s)\n", cp.Done, len(windows), windows[cp.Done-1])
+		}
+	}
+	_, _, opts := out.renderer()
+
+	// The header of a resumed csv is in the file already; it is written
+	// to nowhere.
+	dst := &switchWriter{w: runTimings.Writer(out.writer())}
+	if cp.Done > 0 {
+		dst.w = io.Discard
+	}
+	r, _ := output.NewRenderer(dst, format)
+	r = timeRenderer(r, runTimings)
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+	dst.w = runTimings.Writer(out.writer())
+
+	ctx := shutdownContext()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	client := newClient()
+
+	// Windows run up to --parallel at once but are written in order; a
+	// window's slot frees once it is written, so at most --parallel
+	// windows wait in memory.
+	type windowResult struct {
+		rows  [][]any
+		dupes int
+		err   error
+	}
+	results := make([]chan windowResult, len(windows))
+	for i := range results {
+		results[i] = make(chan windowResult, 1)
+	}
+	slots := make(chan struct{}, *parallel)
+	go func() {
+		for i := cp.Done; i < len(windows); i++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i int) {
+				var res windowResult
+				res.rows, res.dupes, res.err = fetchWindow(ctx, client, id, backfill.Query(q, windows[i]), fields)
+				results[i] <- res
+			}(i)
+		}
+	}()
+
+	rows, dupes := 0, 0
+	for i := cp.Done; i < len(windows); i++ {
+		var res windowResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			res.err = ctx.Err()
+		}
+		if sig := interrupted(ctx); sig != nil {
+			exitInterrupted(sig, backfillProgress(cp.Done, len(windows), cpPath))
+		}
+		if res.err != nil {
+			cancel()
+			fmt.Fprintf(os.Stderr, "Window %s failed; %s\n", windows[i], backfillProgress(cp.Done, len(windows), cpPath))
+			wq := backfill.Query(q, windows[i])
+			exitQueryError(res.err, wq, wq.String())
+		}
+		for _, values := range res.rows {
+			if err := opts.WriteRecord(r, fields, values); err != nil {
+				exitIOError(err)
+			}
+		}
+		if err := r.Flush(); err != nil {
+			exitIOError(err)
+		}
+		<-slots
+		rows += len(res.rows)
+		dupes += res.dupes
+		cp.Done = i + 1
+		if file != nil {
+			if cp.Offset, err = file.Seek(0, io.SeekCurrent); err != nil {
+				exitIOError(err)
+			}
+			if err := cp.Save(cpPath); err != nil {
+				exitIOError(err)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "-- %s: %d rows (%d/%d)\n", windows[i], len(res.rows), i+1, len(windows))
+	}
+
+	if file != nil {
+		if err := file.Close(); err != nil {
+			exitIOError(err)
+		}
+		os.Remove(cpPath)
+	}
+	msg := fmt.Sprintf("Wrote %d rows from %d windows", rows, len(windows))
+	if dupes > 0 {
+		msg += fmt.Sprintf(", dropping %d repeated", dupes)
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// fetchWindow reads every row of a window query, as the values of
+// fields, and drops rows repeated within it. It returns the rows and the
+// number dropped.
+func fetchWindow(ctx context.Context, client *adsapi.Client, id string, q *gaql.Query, fields []string) ([][]any, int, error) {
+	var (
+		rows  [][]any
+		dupes int
+	)
+	seen := make(map[uint64]bool)
+	flat := rowflat.Compile(fields)
+	next := client.SearchIter(ctx, id, q.String())
+	for {
+		row, err := next()
+		if err == adsapi.Done {
+			return rows, dupes, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		values := flat.Values(row, make([]any, len(fields)))
+		data, _ := json.Marshal(values)
+		h := fnv.New64a()
+		h.Write(data)
+		key := h.Sum64()
+		if seen[key] {
+			dupes++
+			continue
+		}
+		seen[key] = true
+		rows = append(rows, values)
+	}
+}
+
+// backfillProgress describes how far a stopped backfill got, and how to
+// resume it.
+func backfillProgress(done, total int, checkpoint string) string {
+	msg := fmt.Sprintf("%d of %d windows written", done, total)
+	if checkpoint != "" {
+		msg += "; run the same command again to resume"
+	}
+	return msg
+}
+
+// switchWriter writes to w, which can be changed between writes.
+type switchWriter struct {
+	w io.Writer
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
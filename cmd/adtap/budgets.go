@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/pacing"
+)
+
+// budgetAlert is the JSON object written for each budget over the alert
+// threshold.
+type budgetAlert struct {
+	Alert      string  `json:"alert"`
+	CustomerID string  `json:"customer_id"`
+	AsOf       string  `json:"as_of"`
+	Threshold  float64 `json:"threshold"`
+	pacing.Pace
+}
+
+func cmdBudgets(args []string) {
+	fs := newFlagSet("budgets")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	out := addOutputFlags(fs)
+	threshold := fs.Float64("alert-threshold", 0, "Alert on budgets projected to spend at least this fraction of their month (e.g. 0.9); exits 8 when any do")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap budgets --customer-id ID [--alert-threshold RATIO]")
+		fmt.Fprintln(os.Stderr, "\nShow month-to-date spend of daily budgets and the spend projected for")
+		fmt.Fprintln(os.Stderr, "the whole month. With --alert-threshold, print a JSON line for each")
+		fmt.Fprintln(os.Stderr, "budget projected at or over the threshold and exit 8 if there are any.")
+		fmt.Fprintln(os.Stderr, "Amounts are in the account currency.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if *customerID == "" {
+		usageError("budgets", "--customer-id is required")
+	}
+	if *threshold < 0 {
+		usageError("budgets", "--alert-threshold must not be negative")
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+
+	resp, err := newClient().Search(context.Background(), id, pacing.Query)
+	if err != nil {
+		exitAPIError(err)
+	}
+
+	// "This month" is the account's month, not the local one.
+	now := time.Now()
+	if loc := pacing.TimeZone(resp.Results); loc != nil {
+		now = now.In(loc)
+	}
+	paces := pacing.Project(pacing.Budgets(resp.Results), now)
+
+	if *threshold == 0 {
+		_, r, opts := out.renderer()
+		printPacing(r, opts, paces)
+		return
+	}
+
+	over := pacing.Over(paces, *threshold)
+	enc := json.NewEncoder(out.writer())
+	for _, p := range over {
+		alert := budgetAlert{
+			Alert:      "budget_pacing",
+			CustomerID: id,
+			AsOf:       now.Format(time.DateOnly),
+			Threshold:  *threshold,
+			Pace:       p,
+		}
+		if err := enc.Encode(alert); err != nil {
+			exitIOError(err)
+		}
+	}
+	if len(over) > 0 {
+		fmt.Fprintf(os.Stderr, "Alert: %d budget(s) projected at or over %.0f%% of their monthly amount\n", len(over), *threshold*100)
+		os.Exit(exitcode.Alert)
+	}
+}
+
+func printPacing(r output.Renderer, opts output.Options, paces []pacing.Pace) {
+	// Pacing is about money; amounts are always shown in currency units.
+	opts.Micros = true
+	fields := []string{"budget", "campaigns", "daily_budget_micros", "spend_micros", "month_budget_micros", "projected_micros", "projected_ratio"}
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	for _, p := range paces {
+		names := make([]string, len(p.Campaigns))
+		for i, c := range p.Campaigns {
+			names[i] = c.Name
+		}
+		values := []any{
+			p.Name,
+			strings.Join(names, ", "),
+			p.DailyMicros,
+			p.SpendMicros,
+			p.MonthMicros,
+			p.ProjectedMicros,
+			math.Round(p.Ratio*100) / 100,
+		}
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
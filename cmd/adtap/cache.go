@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/cache"
+)
+
+// resultCache serves repeated queries of the commands taking the cache
+// flags; nil elsewhere.
+var resultCache *cache.Cache
+
+// cacheFlags are the result cache options of exploratory commands.
+type cacheFlags struct {
+	ttl     *time.Duration
+	noCache *bool
+}
+
+func addCacheFlags(fs *flag.FlagSet) *cacheFlags {
+	return &cacheFlags{
+		ttl:     fs.Duration("cache-ttl", cacheTTL(), "Serve repeated queries from the result cache while younger than this (0 disables; env ADTAP_CACHE_TTL)"),
+		noCache: fs.Bool("no-cache", false, "Run every query, replacing cached results"),
+	}
+}
+
+// enable turns on the result cache for the clients created afterwards.
+func (f *cacheFlags) enable() {
+	if *f.ttl < 0 {
+		exitValidationError("--cache-ttl must not be negative\n\nGot: %v", *f.ttl)
+	}
+	resultCache = cache.New(cache.DefaultDir(), *f.ttl)
+	resultCache.Refresh = *f.noCache
+	// A profile that fails to load stops the command when it makes its
+	// client.
+	if _, err := loadProfile(); err == nil {
+		resultCache.Profile = resolvedProfile
+	}
+	slog.Debug("result cache", "dir", cache.DefaultDir(), "ttl", *f.ttl, "refresh", *f.noCache, "profile", resultCache.Profile)
+}
+
+// cacheTTL returns the TTL set by ADTAP_CACHE_TTL, or the default.
+func cacheTTL() time.Duration {
+	if v := os.Getenv("ADTAP_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		fmt.Fprintf(os.Stderr, "Warning: ignoring ADTAP_CACHE_TTL=%q: not a duration such as 30m\n", v)
+	}
+	return cache.DefaultTTL
+}
+
+// reportCacheHits notes on stderr that results came from the cache, so
+// stale data is never a surprise.
+func reportCacheHits() {
+	if n := resultCache.Hits(); n > 0 {
+		noun := "result"
+		if n > 1 {
+			noun = "results"
+		}
+		fmt.Fprintf(os.Stderr, "Note: %d %s served from the cache (--no-cache to refresh).\n", n, noun)
+	}
+}
+
+func cmdCache(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		cacheUsage()
+		os.Exit(0)
+	}
+	if len(args) > 1 {
+		usageError("cache", fmt.Sprintf("unexpected argument %q", args[1]))
+	}
+	c := cache.New(cache.DefaultDir(), cacheTTL())
+	switch args[0] {
+	case "clear":
+		n, err := c.Clear()
+		if err != nil {
+			exitIOError(err)
+		}
+		fmt.Printf("Removed %d cached results from %s\n", n, c.Dir)
+	case "stats":
+		s, err := c.Stats()
+		if err != nil {
+			exitIOError(err)
+		}
+		fmt.Printf("Directory  %s\n", s.Dir)
+		fmt.Printf("TTL        %v\n", c.TTL)
+		fmt.Printf("Entries    %d (%d expired)\n", s.Entries, s.Expired)
+		fmt.Printf("Size       %.1f KiB\n", float64(s.Bytes)/1024)
+		if s.Entries > 0 {
+			fmt.Printf("Oldest     %s (%v ago)\n", s.Oldest.Local().Format(time.DateTime), time.Since(s.Oldest).Round(time.Second))
+			fmt.Printf("Newest     %s (%v ago)\n", s.Newest.Local().Format(time.DateTime), time.Since(s.Newest).Round(time.Second))
+		}
+	default:
+		usageError("cache", fmt.Sprintf("unknown subcommand %q (expected clear or stats)", args[0]))
+	}
+}
+
+func cacheUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap cache clear")
+	fmt.Fprintln(os.Stderr, "       adtap cache stats")
+	fmt.Fprintln(os.Stderr, "\nManage the result cache of 'adtap search' and 'adtap repl', kept in")
+	fmt.Fprintln(os.Stderr, cache.DefaultDir()+" (ADTAP_CACHE_DIR).")
+	fmt.Fprintln(os.Stderr, "Results are keyed by customer ID, API version, and the query as the")
+	fmt.Fprintln(os.Stderr, "formatter prints it, so differently spaced or cased queries share them.")
+	fmt.Fprintf(os.Stderr, "They are served for %v unless --cache-ttl or ADTAP_CACHE_TTL says otherwise.\n", cache.DefaultTTL)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+func cmdCampaigns(args []string) {
+	fs := newFlagSet("campaigns")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	status := fs.String("status", "", "Comma-separated statuses to keep, e.g. ENABLED,PAUSED (default: all but REMOVED)")
+	channel := fs.String("channel", "", "Comma-separated advertising channel types to keep, e.g. SEARCH")
+	nameContains := fs.String("name-contains", "", "Keep campaigns whose name contains this text")
+	since := fs.String("since", "", "Keep campaigns starting on or after this date (YYYY-MM-DD)")
+	metrics := fs.Bool("metrics", false, "Add impressions, clicks, and cost over the last 30 days")
+	out := addOutputFlags(fs)
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap campaigns --customer-id ID [flags]")
+		fmt.Fprintln(os.Stderr, "\nList campaigns for a customer. Filter flags are compiled into the")
+		fmt.Fprintln(os.Stderr, "GAQL query; --show-query prints it.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if *customerID == "" {
+		usageError("campaigns", "--customer-id is required")
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+
+	q, err := compose.Campaigns(compose.CampaignOptions{
+		Statuses:     splitList(*status),
+		Channels:     splitList(*channel),
+		NameContains: *nameContains,
+		Since:        *since,
+		Metrics:      *metrics,
+	})
+	if err != nil {
+		exitValidationError("%v", err)
+	}
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+	}
+	_, r, opts := out.renderer()
+
+	resp, err := newClient().Search(context.Background(), id, q.String())
+	if err != nil {
+		exitAPIError(err)
+	}
+
+	rows := make([]map[string]any, len(resp.Results))
+	for i, row := range resp.Results {
+		rows[i] = row
+	}
+	if err := output.WriteRows(r, q.FieldNames(), rows, opts); err != nil {
+		exitIOError(err)
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/changes"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+func cmdChanges(args []string) {
+	today := time.Now().Format(time.DateOnly)
+	fs := newFlagSet("changes")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	since := fs.String("since", time.Now().AddDate(0, 0, -6).Format(time.DateOnly), "First day of changes to show (YYYY-MM-DD), at most 30 days ago")
+	until := fs.String("until", today, "Last day of changes to show (YYYY-MM-DD)")
+	resourceType := fs.String("resource-type", "", "Comma-separated resource types to keep, e.g. CAMPAIGN,AD_GROUP")
+	limit := fs.Int("limit", 1000, fmt.Sprintf("Maximum number of changes (at most %d)", gaql.ChangeEventMaxLimit))
+	out := addOutputFlags(fs)
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap changes --customer-id ID [--since DATE] [--until DATE] [--resource-type TYPE]")
+		fmt.Fprintln(os.Stderr, "\nShow who changed what in an account, newest first, from the change_event")
+		fmt.Fprintln(os.Stderr, "resource. The table format shows each change as the old and new value of")
+		fmt.Fprintln(os.Stderr, "every changed field; other formats write one row per changed field. The")
+		fmt.Fprintln(os.Stderr, "API keeps 30 days of change history and returns at most 10000 changes.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if *customerID == "" {
+		usageError("changes", "--customer-id is required")
+	}
+	if *limit < 1 || *limit > gaql.ChangeEventMaxLimit {
+		usageError("changes", fmt.Sprintf("--limit must be between 1 and %d", gaql.ChangeEventMaxLimit))
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	// YYYY-MM-DD dates compare as text; compose reports malformed ones.
+	oldest := time.Now().AddDate(0, 0, 1-gaql.ChangeEventMaxDays).Format(time.DateOnly)
+	if _, err := time.Parse(time.DateOnly, *since); err == nil && *since < oldest {
+		exitValidationError("--since %s is more than %d days ago; the API keeps change history since %s", *since, gaql.ChangeEventMaxDays, oldest)
+	}
+
+	q, err := compose.Changes(compose.ChangesOptions{
+		Since:         *since,
+		Until:         *until,
+		ResourceTypes: splitList(*resourceType),
+		Limit:         *limit,
+	})
+	if err != nil {
+		exitValidationError("%v", err)
+	}
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+	}
+	format, r, opts := out.renderer()
+
+	resp, err := newClient().Search(context.Background(), id, q.String())
+	if err != nil {
+		exitAPIError(err)
+	}
+	events := changes.FromRows(resp.Results)
+	if len(events) == *limit {
+		fmt.Fprintf(os.Stderr, "Warning: showing the newest %d changes; narrow the dates or raise --limit for more\n", *limit)
+	}
+
+	if format == output.FormatTable {
+		if len(events) == 0 {
+			fmt.Fprintf(os.Stderr, "No changes from %s to %s\n", *since, *until)
+			return
+		}
+		if err := changes.WriteDiff(out.writer(), events); err != nil {
+			exitIOError(err)
+		}
+		return
+	}
+	writeChangeRows(r, opts, events)
+}
+
+// writeChangeRows writes one row per changed field, for formats that
+// cannot show a diff. Changes without field details get a single row.
+func writeChangeRows(r output.Renderer, opts output.Options, events []changes.Change) {
+	fields := []string{
+		"change_event.change_date_time",
+		"change_event.change_resource_type",
+		"change_event.resource_change_operation",
+		"change_event.change_resource_name",
+		"change_event.user_email",
+		"change_event.client_type",
+		"field",
+		"old_value",
+		"new_value",
+	}
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	for _, c := range events {
+		fieldChanges := c.Fields
+		if len(fieldChanges) == 0 {
+			fieldChanges = []changes.FieldChange{{}}
+		}
+		for _, f := range fieldChanges {
+			values := []any{c.Time, c.ResourceType, c.Operation, c.ResourceName, c.User, c.Client,
+				f.Field, scalarValue(f.Old), scalarValue(f.New)}
+			if err := opts.WriteRecord(r, fields, values); err != nil {
+				exitIOError(err)
+			}
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
+
+// scalarValue encodes message and list values as JSON text, so every
+// format can hold them in one column.
+func scalarValue(v any) any {
+	switch v.(type) {
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	default:
+		return v
+	}
+}
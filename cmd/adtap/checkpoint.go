@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/resume"
+)
+
+// searchCheckpoint is the progress of search --checkpoint: the rows
+// written to the --output file, saved at every page boundary and when
+// the search stops early.
+type searchCheckpoint struct {
+	resume.Checkpoint
+	path string
+	file *os.File
+
+	cur     adsapi.Cursor   // of the iterator, which may read ahead
+	pending []adsapi.Cursor // the cursor after each row read, not yet written
+}
+
+// newCheckpoint opens the --output file of a checkpointed search
+// without truncating it, before out creates it afresh.
+func newCheckpoint(path string, out *outputFlags) *searchCheckpoint {
+	file, err := os.OpenFile(*out.output, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		exitIOError(err)
+	}
+	out.file = file
+	return &searchCheckpoint{path: path, file: file}
+}
+
+// load reads the checkpoint when resuming, and cuts the output back to
+// the rows it covers; otherwise the search starts over.
+func (c *searchCheckpoint) load(resuming bool, id string, q *gaql.Query, format output.Format) {
+	c.Checkpoint = resume.Checkpoint{CustomerID: id, Query: q.String(), Format: string(format)}
+	if resuming {
+		var err error
+		c.Checkpoint, err = resume.Load(c.path, c.Checkpoint)
+		if errors.Is(err, resume.ErrMismatch) {
+			exitValidationError("%s was saved by another search\n\nHint: run without --resume to start over", c.path)
+		}
+		if err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := c.file.Truncate(c.Offset); err != nil {
+		exitIOError(err)
+	}
+	if _, err := c.file.Seek(c.Offset, io.SeekStart); err != nil {
+		exitIOError(err)
+	}
+	if c.Started() {
+		fmt.Fprintf(os.Stderr, "Resuming after %d rows\n", c.Rows)
+	}
+}
+
+// renderer returns a renderer writing to the output file with the
+// header of columns written, except to a resumed file, which has it.
+func (c *searchCheckpoint) renderer(format output.Format, columns []string) output.Renderer {
+	dst := &switchWriter{w: runTimings.Writer(c.file)}
+	if c.Started() {
+		dst.w = io.Discard
+	}
+	r, _ := output.NewRenderer(dst, format)
+	r = timeRenderer(r, runTimings)
+	if err := r.WriteHeader(columns); err != nil {
+		exitIOError(err)
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+	dst.w = runTimings.Writer(c.file)
+	return r
+}
+
+// iter returns the rows of the search from the checkpoint on.
+func (c *searchCheckpoint) iter(ctx context.Context, client *adsapi.Client) func() (adsapi.Row, error) {
+	c.cur = c.Cursor
+	next := client.SearchIterAt(ctx, c.CustomerID, c.Query, &c.cur)
+	return func() (adsapi.Row, error) {
+		row, err := next()
+		if err == nil {
+			c.pending = append(c.pending, c.cur)
+		}
+		return row, err
+	}
+}
+
+// advance is called before the next row read is written to r. When it
+// starts a page, the rows before it are flushed and the checkpoint
+// saved.
+func (c *searchCheckpoint) advance(r output.Renderer) {
+	cur := c.pending[0]
+	c.pending = c.pending[1:]
+	if cur.PageToken != c.Cursor.PageToken {
+		c.save(r)
+	}
+	c.Cursor = cur
+	c.Rows++
+}
+
+func (c *searchCheckpoint) save(r output.Renderer) {
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+	offset, err := c.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		exitIOError(err)
+	}
+	c.Offset = offset
+	if err := c.Checkpoint.Save(c.path); err != nil {
+		exitIOError(err)
+	}
+}
+
+// finish removes the checkpoint of a search that read every row, or
+// saves the progress of one cut short and says how to resume it.
+func (c *searchCheckpoint) finish(r output.Renderer, complete bool) {
+	if complete {
+		if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			exitIOError(err)
+		}
+		return
+	}
+	c.save(r)
+	fmt.Fprintf(os.Stderr, "Saved progress after %d rows to %s; run again with --resume to continue\n", c.Rows, c.path)
+}
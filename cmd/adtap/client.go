@@ -0,0 +1,383 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/auth"
+	"github.com/aygp-dr/adtap/internal/config"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/queryerr"
+	"github.com/aygp-dr/adtap/internal/secrets"
+)
+
+// loginFlag and linkedFlag are the --login-customer-id and
+// --linked-customer-id of the command, which win over the environment
+// and the profile.
+var loginFlag, linkedFlag string
+
+// customerHeaderID normalizes the customer ID given to the global flag
+// --name, --login-customer-id or --linked-customer-id.
+func customerHeaderID(name, value string) string {
+	id, err := adsapi.NormalizeCustomerID(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Usage error: invalid --%s %q\n\nExpected: 1234567890\n", name, value)
+		os.Exit(exitcode.UsageError)
+	}
+	return id
+}
+
+// managerIDs parses the managers setting, a comma-separated list of
+// customer IDs. Errors are *setupError.
+func managerIDs(setting string) ([]string, error) {
+	var ids []string
+	for _, s := range strings.Split(setting, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := adsapi.NormalizeCustomerID(s)
+		if err != nil {
+			return nil, configError(fmt.Sprintf("invalid manager ID %q in managers", s),
+				"list manager customer IDs separated by commas, such as 1234567890, 3456789012.")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// newClient builds an API client from the environment. Missing
+// configuration or credentials exit with the documented codes.
+func newClient() *adsapi.Client {
+	c, err := clientFromEnv()
+	if err != nil {
+		exitSetupError(err.(*setupError))
+	}
+	return c
+}
+
+// secretsProvider returns the secrets provider chosen by ADTAP_SECRETS or
+// the profile, or nil when none is. Errors are *setupError.
+func secretsProvider() (secrets.Provider, error) {
+	p, err := loadProfile()
+	if err != nil {
+		return nil, err
+	}
+	spec := setting("ADTAP_SECRETS", p.Secrets)
+	if spec == "" {
+		return nil, nil
+	}
+	provider, err := secrets.Open(spec)
+	if err != nil {
+		return nil, configError(err.Error(), "set secrets to env, file:PATH, keyring, vault:MOUNT/PATH, or gcp:PROJECT.")
+	}
+	if sm, ok := provider.(*secrets.SecretManager); ok {
+		// Secret Manager is reached with the Google Cloud credentials
+		// gcloud uses, not the Google Ads ones it may hold.
+		ts, err := auth.DefaultCredentials(auth.CloudPlatformScope)
+		if err != nil {
+			return nil, configError(err.Error(), "set GOOGLE_APPLICATION_CREDENTIALS or run 'gcloud auth application-default login'.")
+		}
+		sm.Token = ts.Token
+	}
+	return provider, nil
+}
+
+// credentialsInEnv reports whether environment variables name the
+// credentials to use.
+func credentialsInEnv() bool {
+	for _, name := range []string{"GOOGLE_ADS_JSON_KEY_FILE_PATH", "GOOGLE_APPLICATION_CREDENTIALS", "GOOGLE_ADS_REFRESH_TOKEN"} {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// exitSetupError reports a setup error with its hint and exits.
+func exitSetupError(se *setupError) {
+	eprintf("%s: %s\n", se.category, se.msg)
+	eprintf("\nHint: %s\n", se.hint)
+	os.Exit(se.code)
+}
+
+// setupError is a missing or invalid setting found by clientFromEnv,
+// with the exit code and hint the CLI reports it with.
+type setupError struct {
+	code     int
+	category string
+	msg      string
+	hint     string
+}
+
+func (e *setupError) Error() string { return e.msg }
+
+// clientFromEnv builds an API client from the environment, for callers
+// such as the MCP server that must not exit. Errors are *setupError.
+func clientFromEnv() (*adsapi.Client, error) {
+	if replayDir != "" {
+		return replayClient(), nil
+	}
+	if offlineDemo {
+		return demoClient(), nil
+	}
+	p, err := loadProfile()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := secretsProvider()
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := developerToken(p, provider)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := tokenSource(provider)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := clientOptions(p, token)
+	if err != nil {
+		return nil, err
+	}
+	return adsapi.New(token, ts, opts...), nil
+}
+
+// developerToken returns the developer token of the profile, or of the
+// secrets provider when the profile has none, and where it came from.
+// Errors are *setupError.
+func developerToken(p *config.Profile, provider secrets.Provider) (token, source string, err error) {
+	token, source = os.Getenv("GOOGLE_ADS_DEVELOPER_TOKEN"), "GOOGLE_ADS_DEVELOPER_TOKEN"
+	if token == "" {
+		token, source = p.DeveloperToken, "the profile"
+	}
+	if token == "" && provider != nil {
+		source = provider.String()
+		token, err = provider.Secret(context.Background(), secrets.DeveloperToken)
+		if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+			return "", "", configError(err.Error(), "check the secrets setting of your profile, or ADTAP_SECRETS.")
+		}
+	}
+	if token == "" {
+		return "", "", &setupError{exitcode.ConfigError, "Configuration error", "GOOGLE_ADS_DEVELOPER_TOKEN is not set",
+			"copy .env.template to .env and fill in your developer token, or run 'adtap config set developer_token TOKEN'."}
+	}
+	return token, source, nil
+}
+
+// tokenSource returns the access tokens of the configured credentials.
+// Errors are *setupError.
+func tokenSource(provider secrets.Provider) (*auth.TokenSource, error) {
+	// Credentials named by the environment win over the secrets provider,
+	// as environment variables win over profile settings; an interactive
+	// login is the last resort.
+	var ts *auth.TokenSource
+	err := auth.ErrNoCredentials
+	if provider != nil && !credentialsInEnv() {
+		ts, err = auth.FromSecrets(context.Background(), provider)
+	}
+	if errors.Is(err, auth.ErrNoCredentials) {
+		ts, err = auth.FromEnvironment()
+	}
+	if err != nil {
+		return nil, &setupError{exitcode.AuthError, "Authentication error", err.Error(),
+			"set GOOGLE_APPLICATION_CREDENTIALS to a service account or authorized user JSON file, or run 'adtap auth login'."}
+	}
+	return ts, nil
+}
+
+// rateLimiter paces the requests of the command when rate limits are
+// set, for the throttling report of --profile-run and --verbose; nil
+// otherwise.
+var rateLimiter *adsapi.Limiter
+
+// clientOptions returns the options of clients built for the profile
+// and developer token: recording, caching, the login and linked
+// customers, rate limits, and connection settings. Errors are
+// *setupError.
+func clientOptions(p *config.Profile, token string) ([]adsapi.Option, error) {
+	opts := []adsapi.Option{adsapi.WithRecorder(runTimings), adsapi.WithLogger(slog.Default())}
+	// Cached results would not reach the recorder.
+	if recordDir == "" {
+		opts = append(opts, adsapi.WithCache(resultCache))
+	}
+	// A fixed login customer wins over the managers, whose children
+	// are reached through the manager above each.
+	if id := cmp.Or(loginFlag, setting("GOOGLE_ADS_LOGIN_CUSTOMER_ID", p.LoginCustomerID)); id != "" {
+		opts = append(opts, adsapi.WithLoginCustomerID(id))
+	} else if s := setting("ADTAP_MANAGERS", p.Managers); s != "" {
+		ids, err := managerIDs(s)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, adsapi.WithManagers(ids...))
+	}
+	if id := cmp.Or(linkedFlag, setting("GOOGLE_ADS_LINKED_CUSTOMER_ID", p.LinkedCustomerID)); id != "" {
+		opts = append(opts, adsapi.WithLinkedCustomerID(id))
+	}
+	if p.APIVersion != "" {
+		opts = append(opts, adsapi.WithVersion(p.APIVersion))
+	}
+	limits, ok, err := rateLimits()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if limits.DailyOperations > 0 {
+			limits.DailyOperationsDir = operationsDir(token)
+		}
+		rateLimiter = adsapi.NewLimiter(limits)
+		opts = append(opts, adsapi.WithRateLimiter(rateLimiter))
+	}
+	conn, err := connectionOptions(true)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, conn...)
+	return append(opts, recordOptions()...), nil
+}
+
+// rateLimits reads the client-side rate limits from ADTAP_QPS,
+// ADTAP_CUSTOMER_QPS, and ADTAP_DAILY_OPERATIONS. It reports false when
+// none is set.
+func rateLimits() (adsapi.RateLimits, bool, error) {
+	var limits adsapi.RateLimits
+	set := false
+	var bad error
+	read := func(name string, parse func(string) error) {
+		v := os.Getenv(name)
+		if v == "" || bad != nil {
+			return
+		}
+		if err := parse(v); err != nil {
+			bad = &setupError{exitcode.ConfigError, "Configuration error", fmt.Sprintf("invalid %s %q", name, v),
+				"use a non-negative number, or leave it unset to disable the limit."}
+		}
+		set = true
+	}
+	read("ADTAP_QPS", func(v string) (err error) {
+		limits.QPS, err = parseNonNegative(v)
+		return err
+	})
+	read("ADTAP_CUSTOMER_QPS", func(v string) (err error) {
+		limits.CustomerQPS, err = parseNonNegative(v)
+		return err
+	})
+	read("ADTAP_DAILY_OPERATIONS", func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err == nil && n < 0 {
+			err = errors.New("negative")
+		}
+		limits.DailyOperations = n
+		return err
+	})
+	return limits, set, bad
+}
+
+// operationsDir returns the directory counting the daily operations of
+// token, beside the result cache in the user cache directory, so every
+// adtap run of the day draws from ADTAP_DAILY_OPERATIONS. The token
+// appears only hashed. Without a cache directory each run counts alone.
+func operationsDir(token string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return filepath.Join(dir, "adtap", "operations", hex.EncodeToString(sum[:8]))
+}
+
+// printLimiterStats writes how much the rate limits held requests back,
+// when they are set.
+func printLimiterStats() {
+	if rateLimiter == nil {
+		return
+	}
+	s := rateLimiter.Stats()
+	fmt.Fprintf(os.Stderr, "Rate limits: %d request(s), %d throttled for %v\n", s.Requests, s.Throttled, s.Wait.Round(time.Millisecond))
+}
+
+func parseNonNegative(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil && (f < 0 || math.IsNaN(f) || math.IsInf(f, 0)) {
+		err = errors.New("out of range")
+	}
+	return f, err
+}
+
+// exitAPIError reports an error from an API call and exits with the
+// matching code.
+func exitAPIError(err error) {
+	exitQueryError(err, nil, "")
+}
+
+// exitQueryError is exitAPIError for a search request: failures that
+// refer to the query are reported at their line and column in src, the
+// text q was parsed from.
+func exitQueryError(err error, q *gaql.Query, src string) {
+	os.Exit(reportQueryError(err, q, src))
+}
+
+// reportQueryError writes the report of exitQueryError to stderr and
+// returns the exit code, for callers such as the REPL that carry on.
+func reportQueryError(err error, q *gaql.Query, src string) int {
+	var apiErr *adsapi.APIError
+	var tokenErr *auth.TokenError
+	switch {
+	case errors.As(err, &tokenErr):
+		eprintf("Authentication error: %v\n", tokenErr)
+		return exitcode.AuthError
+	case errors.Is(err, adsapi.ErrDeadline):
+		eprintf("Timeout: the command did not finish within %v\n", commandTimeout)
+		eprintf("\nHint: raise --timeout, or ADTAP_TIMEOUT.\n")
+		return exitcode.APIError
+	case errors.Is(err, adsapi.ErrCallTimeout):
+		eprintf("API error: %v\n", err)
+		eprintf("\nHint: raise --call-timeout or ADTAP_CALL_TIMEOUT, or leave both unset.\n")
+		return exitcode.APIError
+	case errors.Is(err, adsapi.ErrDailyBudget):
+		eprintf("API error: %v\n", err)
+		eprintf("\nHint: raise ADTAP_DAILY_OPERATIONS or try again after midnight UTC.\n")
+		return exitcode.APIError
+	case errors.As(err, &apiErr):
+		code, category := exitcode.APIError, "API error"
+		if apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden {
+			code, category = exitcode.AuthError, "Authentication error"
+		}
+		msg := apiErr.Message
+		if apiErr.Status != "" {
+			msg = apiErr.Status + ": " + msg
+		}
+		eprintf("%s: %s\n", category, msg)
+		for _, ge := range apiErr.Errors {
+			if q != nil {
+				if e := queryerr.Locate(q, src, ge); e.Span.IsValid() {
+					fmt.Fprintf(os.Stderr, "  - %s\n", e)
+					continue
+				}
+			}
+			fmt.Fprintf(os.Stderr, "  - %s\n", ge)
+		}
+		if apiErr.RequestID != "" {
+			eprintf("\nRequest ID: %s\n", apiErr.RequestID)
+		}
+		return code
+	default:
+		eprintf("I/O error: %v\n", err)
+		return exitcode.IOError
+	}
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/accounts"
+	"github.com/aygp-dr/adtap/internal/completion"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/config"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/i18n"
+	"github.com/aygp-dr/adtap/internal/lint"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/pacing"
+	"github.com/aygp-dr/adtap/internal/querylib"
+	"github.com/aygp-dr/adtap/internal/repl"
+	"github.com/aygp-dr/adtap/internal/searchterms"
+)
+
+func cmdCompletion(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		completionUsage()
+		os.Exit(0)
+	}
+	if len(args) > 1 {
+		usageError("completion", fmt.Sprintf("unexpected argument %q", args[1]))
+	}
+	if err := completion.Script(os.Stdout, args[0], name); err != nil {
+		usageError("completion", strings.TrimPrefix(err.Error(), "completion: "))
+	}
+}
+
+func completionUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap completion bash|zsh|fish")
+	fmt.Fprintln(os.Stderr, "\nPrint a shell completion script. Besides commands and flags, it completes")
+	fmt.Fprintln(os.Stderr, "resource and field names in --query, and --customer-id from the customers")
+	fmt.Fprintln(os.Stderr, "last listed by 'adtap customers' (cached in "+accounts.DefaultCachePath()+")")
+	fmt.Fprintln(os.Stderr, "and the customer_id of each profile.")
+	fmt.Fprintln(os.Stderr, "\nInstall:")
+	fmt.Fprintln(os.Stderr, "  bash  source <(adtap completion bash)   # in ~/.bashrc")
+	fmt.Fprintln(os.Stderr, "  zsh   adtap completion zsh > \"${fpath[1]}/_adtap\"")
+	fmt.Fprintln(os.Stderr, "  fish  adtap completion fish > ~/.config/fish/completions/adtap.fish")
+}
+
+// cmdComplete answers the completion scripts: args are the words after
+// the program name, the last being completed. It runs before the global
+// flags are taken from the command line, and never calls the API.
+func cmdComplete(args []string) {
+	completion.Complete(commandTree(), args).WriteTo(os.Stdout)
+}
+
+// commandTree describes the commands for completion. It follows the flag
+// sets of the commands; keep the two in step.
+func commandTree() *completion.Command {
+	customerID := completion.Flag{Name: "customer-id", Values: customerIDs}
+	during := completion.Flag{Name: "during", Values: dateRanges}
+	showQuery := completion.Flag{Name: "show-query", Bool: true}
+	cacheFlags := []completion.Flag{
+		{Name: "cache-ttl"},
+		{Name: "no-cache", Bool: true},
+	}
+	policyFlags := []completion.Flag{
+		{Name: "policy", Files: true},
+		{Name: "role"},
+	}
+	fxFlags := []completion.Flag{
+		{Name: "currency", Values: words("USD", "EUR", "GBP", "JPY")},
+		{Name: "fx-rates", Files: true},
+	}
+	outputFlags := []completion.Flag{
+		{Name: "format", Values: outputFormats},
+		{Name: "enums", Values: words("labels", "raw", "auto")},
+		{Name: "raw-enums", Bool: true},
+		{Name: "micros-to-currency", Bool: true},
+		{Name: "rename"},
+		{Name: "output", Files: true},
+	}
+	flags := func(fs ...[]completion.Flag) []completion.Flag {
+		var out []completion.Flag
+		for _, f := range fs {
+			out = append(out, f...)
+		}
+		return out
+	}
+
+	searchFlags := flags([]completion.Flag{
+		customerID,
+		{Name: "query", Values: gaqlQuery},
+		{Name: "file", Files: true},
+		{Name: "parallel"},
+		{Name: "yes", Bool: true},
+		{Name: "explain", Bool: true},
+		{Name: "dry-run", Bool: true},
+		{Name: "param"},
+		{Name: "max-days"},
+		{Name: "warn-zero-rows", Bool: true},
+		{Name: "strict", Bool: true},
+		{Name: "auto-date", Bool: true},
+		{Name: "api-version", Values: words(gaql.DefaultAPIVersion)},
+		{Name: "default-during", Values: dateRanges},
+		{Name: "max-rows"},
+		{Name: "max-bytes"},
+		{Name: "stats", Bool: true},
+		{Name: "summary", Bool: true},
+		{Name: "all-accounts", Bool: true},
+		{Name: "concurrency"},
+		{Name: "shard-days"},
+		{Name: "shard-campaigns"},
+		{Name: "envelope", Bool: true},
+		{Name: "verbose", Bool: true},
+		{Name: "to-bigquery"},
+		{Name: "to-sqlite", Files: true},
+		{Name: "table"},
+		{Name: "humanize", Bool: true},
+		{Name: "watch", Values: words("1m", "5m", "15m")},
+		{Name: "diff-only", Bool: true},
+		{Name: "post"},
+		{Name: "sample"},
+		{Name: "checkpoint", Files: true},
+		{Name: "resume", Bool: true},
+	}, cacheFlags, policyFlags, fxFlags, outputFlags)
+
+	var templates []*completion.Command
+	for _, t := range compose.Templates {
+		templates = append(templates, &completion.Command{
+			Name:        t.Name,
+			Description: t.Description,
+			Flags:       flags(templateFlags(t), []completion.Flag{{Name: "concurrency"}, {Name: "humanize", Bool: true}, showQuery}, fxFlags, outputFlags),
+		})
+	}
+	var reports []*completion.Command
+	for _, t := range compose.Reports {
+		reports = append(reports, &completion.Command{
+			Name:        t.Name,
+			Description: t.Description,
+			Flags:       flags(templateFlags(t), []completion.Flag{{Name: "concurrency"}, {Name: "humanize", Bool: true}, showQuery}, fxFlags, outputFlags),
+		})
+	}
+	var rules []string
+	for _, r := range lint.Rules() {
+		rules = append(rules, r.Name)
+	}
+	enum := func(field string) completion.Values {
+		info, _ := gaql.DefaultCatalog().Field(field)
+		return list(words(info.EnumValues...))
+	}
+
+	return &completion.Command{
+		Name: name,
+		Flags: []completion.Flag{
+			{Name: "profile", Description: "Profile from config.toml", Values: profileNames},
+			{Name: "profile-run", Description: "Report where the time went", Bool: true},
+			{Name: "offline-demo", Description: "Query the bundled demo accounts instead of the API", Bool: true},
+			{Name: "lang", Description: "Language of help and messages", Values: words(i18n.Languages...)},
+			{Name: "timeout", Description: "Fail API requests still running after this long"},
+			{Name: "log-level", Description: "Log to stderr at this level", Values: words("debug", "info", "warn", "error")},
+			{Name: "log-format", Description: "Format of the log", Values: words("text", "json")},
+			{Name: "record", Description: "Save API responses as fixtures in this directory", Files: true},
+			{Name: "replay", Description: "Answer requests from the fixtures in this directory", Files: true},
+			{Name: "login-customer-id", Description: "Manager account requests are sent through"},
+			{Name: "linked-customer-id", Description: "Linked account of an app analytics provider"},
+		},
+		Subcommands: []*completion.Command{
+			{Name: "search", Description: "Execute a GAQL query", Flags: searchFlags},
+			{Name: "customers", Description: "List accessible customer accounts", Flags: flags([]completion.Flag{
+				{Name: "tree", Bool: true},
+				customerID,
+			}, outputFlags)},
+			{Name: "auth", Description: "Sign in with a Google account", Subcommands: []*completion.Command{
+				{Name: "login", Flags: []completion.Flag{
+					{Name: "client-secrets", Files: true},
+					{Name: "no-browser", Bool: true},
+				}},
+				{Name: "status"},
+				{Name: "logout"},
+			}},
+			{Name: "doctor", Description: "Check credentials and API access", Flags: []completion.Flag{customerID}},
+			{Name: "config", Description: "Manage named profiles", Subcommands: []*completion.Command{
+				{Name: "list"},
+				{Name: "get", Args: []completion.Values{words(config.Keys...)}},
+				{Name: "set", Args: []completion.Values{words(config.Keys...)}},
+				{Name: "unset", Args: []completion.Values{words(config.Keys...)}},
+			}},
+			{Name: "campaigns", Description: "List campaigns for a customer", Flags: flags([]completion.Flag{
+				customerID,
+				{Name: "status", Values: enum("campaign.status")},
+				{Name: "channel", Values: enum("campaign.advertising_channel_type")},
+				{Name: "name-contains"},
+				{Name: "since"},
+				{Name: "metrics", Bool: true},
+				showQuery,
+			}, outputFlags)},
+			{Name: "ads", Description: "Preview ads with their text, URLs, and policy status", Flags: []completion.Flag{
+				customerID,
+				{Name: "ad-group"},
+				{Name: "campaign"},
+				{Name: "policy-issues-only", Bool: true},
+				{Name: "format", Values: words("human", "json")},
+				showQuery,
+			}},
+			{Name: "conversions", Description: "Report conversions and value by conversion action", Flags: flags([]completion.Flag{
+				customerID,
+				{Name: "by", Values: words(compose.ConversionBys...)},
+				{Name: "last"},
+				showQuery,
+			}, outputFlags)},
+			{Name: "anomalies", Description: "Flag unusual days in a daily metric series", Flags: flags([]completion.Flag{
+				customerID,
+				{Name: "metric", Values: metrics},
+				{Name: "by"},
+				during,
+				{Name: "lookback"},
+				{Name: "threshold"},
+				{Name: "seasonal", Bool: true},
+				{Name: "method", Values: words("mean", "median")},
+				{Name: "window"},
+				{Name: "alert", Bool: true},
+				showQuery,
+			}, outputFlags)},
+			{Name: "backfill", Description: "Fetch a long date range in resumable windows", Flags: flags([]completion.Flag{
+				customerID,
+				{Name: "query", Files: true},
+				{Name: "from"},
+				{Name: "to"},
+				{Name: "chunk", Values: words("1d", "7d", "2w", "30d")},
+				{Name: "parallel"},
+				{Name: "restart", Bool: true},
+			}, outputFlags)},
+			{Name: "snapshot", Description: "Write the structure of an account to JSONL files", Flags: []completion.Flag{
+				customerID,
+				{Name: "out", Files: true},
+				{Name: "replace", Bool: true},
+				showQuery,
+			}},
+			{Name: "join", Description: "Join the rows of two queries", Flags: flags([]completion.Flag{
+				customerID,
+				{Name: "left", Files: true},
+				{Name: "right", Files: true},
+				{Name: "on"},
+				{Name: "type", Values: words("inner", "left")},
+				{Name: "to-sqlite", Files: true},
+				{Name: "table"},
+			}, outputFlags)},
+			{Name: "budgets", Description: "Show budget pacing", Flags: flags([]completion.Flag{
+				customerID,
+				{Name: "alert-threshold"},
+			}, outputFlags)},
+			{Name: "spend", Description: "Show spend to date against budgets", Flags: flags([]completion.Flag{
+				customerID,
+				{Name: "group-by", Values: words(spendGroupings()...)},
+				{Name: "period", Values: words(pacing.Periods...)},
+				{Name: "alert-threshold"},
+				showQuery,
+			}, outputFlags)},
+			{Name: "search-terms", Description: "Suggest negative keywords from search terms", Flags: flags([]completion.Flag{
+				customerID,
+				during,
+				{Name: "min-cost"},
+				{Name: "no-conversions", Bool: true},
+				{Name: "level", Values: words(searchterms.Levels...)},
+				{Name: "campaign-id"},
+				{Name: "limit"},
+				showQuery,
+			}, outputFlags)},
+			{Name: "changes", Description: "Show the account change history", Flags: flags([]completion.Flag{
+				customerID,
+				{Name: "since"},
+				{Name: "until"},
+				{Name: "resource-type", Values: enum("change_event.change_resource_type")},
+				{Name: "limit"},
+				showQuery,
+			}, outputFlags)},
+			{Name: "top", Description: "Rank campaigns, ad groups, or keywords by a metric",
+				Args: []completion.Values{words(compose.TopEntities...)},
+				Flags: flags([]completion.Flag{
+					customerID,
+					{Name: "by", Values: metrics},
+					during,
+					{Name: "limit"},
+					showQuery,
+				}, outputFlags)},
+			{Name: "template", Description: "List and run query templates", Subcommands: []*completion.Command{
+				{Name: "list"},
+				{Name: "run", Subcommands: templates},
+			}},
+			{Name: "report", Description: "Run a canned report", Subcommands: append([]*completion.Command{{Name: "list"}}, reports...)},
+			{Name: "view", Description: "Run saved views from config.toml", Subcommands: []*completion.Command{
+				{Name: "list"},
+				{Name: "show", Args: []completion.Values{viewNames}},
+				{Name: "run", Args: []completion.Values{viewNames}, Flags: searchFlags},
+			}},
+			{Name: "queries", Description: "Save and run named queries", Subcommands: []*completion.Command{
+				{Name: "list"},
+				{Name: "save", Files: true, Flags: []completion.Flag{
+					{Name: "description"},
+					customerID,
+					{Name: "param"},
+					{Name: "query"},
+					{Name: "force", Bool: true},
+				}},
+				{Name: "show", Args: []completion.Values{queryNames}},
+				{Name: "run", Args: []completion.Values{queryNames}, Flags: searchFlags},
+				{Name: "delete", Args: []completion.Values{queryNames}},
+			}},
+			{Name: "repl", Description: "Type GAQL interactively", Flags: flags([]completion.Flag{
+				customerID,
+				during,
+				{Name: "limit"},
+				{Name: "no-history", Bool: true},
+			}, cacheFlags, outputFlags)},
+			{Name: "describe", Description: "Describe a resource or field", Args: []completion.Values{fieldNames}, Flags: []completion.Flag{
+				{Name: "offline", Bool: true},
+				{Name: "format", Values: words("human", "json")},
+			}},
+			{Name: "schema", Description: "Sync the API schema for offline validation", Subcommands: []*completion.Command{
+				{Name: "sync"}, {Name: "status"},
+			}},
+			{Name: "geo", Description: "Look up geo target constants", Subcommands: []*completion.Command{
+				{Name: "lookup", Flags: flags([]completion.Flag{
+					{Name: "country"},
+					{Name: "offline", Bool: true},
+					{Name: "targets", Files: true},
+				}, outputFlags)},
+			}},
+			{Name: "lint", Description: "Lint stored GAQL query files", Files: true, Flags: []completion.Flag{
+				{Name: "format", Values: words("human", "json", "sarif")},
+				{Name: "disable", Values: list(words(rules...))},
+				{Name: "list-rules", Bool: true},
+			}},
+			{Name: "analyze", Description: "Analyze stored GAQL query files", Subcommands: []*completion.Command{
+				{Name: "usage", Files: true, Flags: []completion.Flag{
+					{Name: "format", Values: words("human", "json")},
+					{Name: "match", Values: fieldNames},
+					{Name: "locations", Bool: true},
+				}},
+			}},
+			{Name: "translate", Description: "Translate SQL into GAQL", Flags: []completion.Flag{
+				{Name: "format", Values: words("human", "json")},
+			}},
+			{Name: "convert", Description: "Convert a query between GAQL, AST, and spec forms", Files: true, Flags: []completion.Flag{
+				{Name: "from", Values: words("auto", "gaql", "ast", "spec")},
+				{Name: "to", Values: words("gaql", "ast", "spec")},
+				{Name: "schema", Bool: true},
+			}},
+			{Name: "parse", Description: "Print the syntax tree of a query", Files: true, Flags: []completion.Flag{
+				{Name: "format", Values: words("json", "yaml", "sexp", "tree")},
+				{Name: "positions", Bool: true},
+				{Name: "query"},
+				{Name: "validate", Bool: true},
+			}},
+			{Name: "diff", Description: "Compare two queries by meaning", Files: true, Flags: []completion.Flag{
+				{Name: "format", Values: words("human", "json")},
+				{Name: "exit-code", Bool: true},
+			}},
+			{Name: "debug", Description: "Shrink a rejected query", Subcommands: []*completion.Command{
+				{Name: "minimize", Files: true, Flags: []completion.Flag{
+					customerID,
+					{Name: "query", Values: gaqlQuery},
+					{Name: "local", Bool: true},
+				}},
+			}},
+			{Name: "mcp", Description: "Serve GAQL tools over MCP", Flags: flags([]completion.Flag{{Name: "debug-addr"}}, policyFlags)},
+			{Name: "serve", Description: "Serve validate, search, and customers over HTTP", Flags: flags([]completion.Flag{
+				{Name: "addr"}, {Name: "api-key-file", Files: true}, {Name: "max-rows"}, {Name: "max-bytes"}, {Name: "debug-addr"},
+			}, policyFlags)},
+			{Name: "cache", Description: "Clear or inspect the result cache", Subcommands: []*completion.Command{
+				{Name: "clear"}, {Name: "stats"},
+			}},
+			{Name: "completion", Description: "Print a shell completion script", Subcommands: []*completion.Command{
+				{Name: "bash"}, {Name: "zsh"}, {Name: "fish"},
+			}},
+			{Name: "version", Description: "Print version information"},
+			{Name: "help", Description: "Show the help message"},
+		},
+	}
+}
+
+// templateFlags returns the flags a template's parameters become.
+func templateFlags(t *compose.Template) []completion.Flag {
+	var out []completion.Flag
+	for _, p := range t.Params {
+		f := completion.Flag{Name: p.FlagName(), Description: p.Description}
+		switch p.Type {
+		case compose.ParamCustomers:
+			f.Values = customerIDs
+		case compose.ParamDateRange:
+			f.Values = dateRanges
+		case compose.ParamEnum:
+			f.Values = list(words(p.Enum...))
+		}
+		out = append(out, f)
+		if p.Name == "date_range" && p.Type == compose.ParamDateRange {
+			out = append(out, completion.Flag{Name: "last", Description: "Trailing period, such as 30d", Values: words("7d", "14d", "30d", "90d")})
+		}
+	}
+	return out
+}
+
+// words completes any of values.
+func words(values ...string) completion.Values {
+	return func(word string) []completion.Candidate {
+		return completion.Match(word, completion.Strings(values...))
+	}
+}
+
+// list completes the last item of a comma-separated list.
+func list(item completion.Values) completion.Values {
+	return func(word string) []completion.Candidate {
+		i := strings.LastIndexByte(word, ',') + 1
+		candidates := item(word[i:])
+		for j := range candidates {
+			candidates[j].Value = word[:i] + candidates[j].Value
+		}
+		return candidates
+	}
+}
+
+var customerIDs = list(func(word string) []completion.Candidate {
+	var candidates []completion.Candidate
+	seen := map[string]bool{}
+	add := func(id, desc string) {
+		if !seen[id] {
+			seen[id] = true
+			candidates = append(candidates, completion.Candidate{Value: id, Description: desc})
+		}
+	}
+	if cfg, err := config.Load(config.DefaultPath()); err == nil {
+		for _, name := range cfg.ProfileNames() {
+			if id := cfg.Profiles[name].CustomerID; id != "" {
+				add(id, "profile "+name)
+			}
+		}
+	}
+	cached, _ := accounts.LoadCache(accounts.DefaultCachePath())
+	for _, a := range cached {
+		add(a.ID, a.Name)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Value < candidates[j].Value })
+	return completion.Match(word, candidates)
+})
+
+func profileNames(word string) []completion.Candidate {
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return nil
+	}
+	return completion.Match(word, completion.Strings(cfg.ProfileNames()...))
+}
+
+func viewNames(word string) []completion.Candidate {
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return nil
+	}
+	return completion.Match(word, completion.Strings(cfg.ViewNames()...))
+}
+
+func queryNames(word string) []completion.Candidate {
+	names, err := querylib.New(querylib.DefaultDir()).Names()
+	if err != nil {
+		return nil
+	}
+	return completion.Match(word, completion.Strings(names...))
+}
+
+func outputFormats(word string) []completion.Candidate {
+	var names []string
+	for _, f := range output.Formats {
+		names = append(names, string(f))
+	}
+	return completion.Match(word, completion.Strings(names...))
+}
+
+func dateRanges(word string) []completion.Candidate {
+	keywords, _ := gaql.DateRangeKeywordsFor(gaql.DefaultAPIVersion)
+	return completion.Match(strings.ToUpper(word), completion.Strings(keywords...))
+}
+
+// metrics completes the short metric names and metrics.* fields.
+func metrics(word string) []completion.Candidate {
+	var names []string
+	for short := range compose.Metrics {
+		names = append(names, short)
+	}
+	for _, f := range gaql.DefaultCatalog().Fields() {
+		if f.Category == "METRIC" {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return completion.Match(word, completion.Strings(names...))
+}
+
+// fieldNames completes the resource and field names of the catalog.
+func fieldNames(word string) []completion.Candidate {
+	var names []completion.Candidate
+	for _, f := range gaql.DefaultCatalog().Fields() {
+		names = append(names, completion.Candidate{Value: f.Name, Description: strings.ToLower(f.Category)})
+	}
+	return completion.Match(word, names)
+}
+
+// gaqlQuery completes the word ending a query as the REPL does: resource
+// names after FROM, date ranges after DURING, and field names and
+// keywords elsewhere.
+func gaqlQuery(text string) []completion.Candidate {
+	start, candidates := repl.Complete(gaql.DefaultCatalog(), text)
+	out := make([]completion.Candidate, len(candidates))
+	for i, c := range candidates {
+		out[i] = completion.Candidate{Value: text[:start] + c}
+	}
+	return out
+}
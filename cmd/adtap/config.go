@@ -0,0 +1,170 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aygp-dr/adtap/internal/config"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+)
+
+// profileName is the profile chosen with --profile or ADTAP_PROFILE;
+// empty means the configured default.
+var profileName string
+
+var (
+	profileOnce sync.Once
+	profile     *config.Profile
+	profileErr  error
+
+	// resolvedProfile is the name of the profile loadProfile loaded,
+	// with the configured default resolved.
+	resolvedProfile string
+)
+
+// loadProfile returns the active profile from the configuration file.
+// Errors are *setupError.
+func loadProfile() (*config.Profile, error) {
+	profileOnce.Do(func() {
+		if offlineDemo {
+			profile, resolvedProfile = demoProfile(), "demo"
+			return
+		}
+		path := config.DefaultPath()
+		cfg, err := config.Load(path)
+		if err == nil {
+			profile, err = cfg.Profile(profileName)
+			resolvedProfile = cmp.Or(profileName, cfg.DefaultProfile, "default")
+		}
+		slog.Debug("config", "path", path, "profile", profileName, "error", err)
+		if err != nil {
+			profileErr = &setupError{exitcode.ConfigError, "Configuration error", err.Error(),
+				"check " + path + ", or list profiles with 'adtap config list'."}
+		}
+	})
+	return profile, profileErr
+}
+
+// activeProfile is loadProfile for commands, exiting on errors.
+func activeProfile() *config.Profile {
+	p, err := loadProfile()
+	if err != nil {
+		exitSetupError(err.(*setupError))
+	}
+	return p
+}
+
+// setting returns the environment variable env when it is set, and the
+// profile value otherwise.
+func setting(env, fromProfile string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return fromProfile
+}
+
+// defaultCustomer fills in the profile's customer ID when the
+// --customer-id flag was left empty.
+func defaultCustomer(id *string) {
+	if *id == "" {
+		*id = activeProfile().CustomerID
+	}
+}
+
+func cmdConfig(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		configUsage()
+		os.Exit(0)
+	}
+	path := config.DefaultPath()
+	cfg, err := config.Load(path)
+	if err != nil {
+		exitSetupError(configError(err.Error(), "fix or remove "+path+"."))
+	}
+
+	switch sub, rest := args[0], args[1:]; sub {
+	case "list":
+		if len(rest) > 0 {
+			usageError("config", "list takes no arguments")
+		}
+		fmt.Printf("# %s\n", path)
+		if len(cfg.Profiles) == 0 {
+			fmt.Println("# no profiles; create one with 'adtap config set KEY VALUE'")
+			return
+		}
+		for _, name := range cfg.ProfileNames() {
+			marker := ""
+			if name == cfg.DefaultProfile || cfg.DefaultProfile == "" && name == "default" {
+				marker = " (default)"
+			}
+			fmt.Printf("\n[%s]%s\n", name, marker)
+			p := cfg.Profiles[name]
+			for _, key := range config.Keys {
+				if v, _ := p.Get(key); v != "" {
+					fmt.Printf("  %s = %s\n", key, displayValue(key, v))
+				}
+			}
+		}
+	case "get":
+		if len(rest) != 1 {
+			usageError("config", "get needs exactly one key")
+		}
+		if rest[0] == "default_profile" {
+			fmt.Println(cfg.DefaultProfile)
+			return
+		}
+		p, err := cfg.Profile(profileName)
+		if err != nil {
+			exitSetupError(configError(err.Error(), "list profiles with 'adtap config list'."))
+		}
+		v, err := p.Get(rest[0])
+		if err != nil {
+			usageError("config", err.Error())
+		}
+		fmt.Println(v)
+	case "set", "unset":
+		want := 2
+		if sub == "unset" {
+			want = 1
+		}
+		if len(rest) != want {
+			usageError("config", fmt.Sprintf("%s needs %d argument(s)", sub, want))
+		}
+		value := ""
+		if sub == "set" {
+			value = rest[1]
+		}
+		if err := cfg.Set(profileName, rest[0], value); err != nil {
+			usageError("config", err.Error())
+		}
+		if err := cfg.Save(path); err != nil {
+			exitIOError(err)
+		}
+	default:
+		usageError("config", fmt.Sprintf("unknown subcommand %q (expected list, get, set, or unset)", sub))
+	}
+}
+
+func configUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap config list")
+	fmt.Fprintln(os.Stderr, "       adtap config get KEY")
+	fmt.Fprintln(os.Stderr, "       adtap config set KEY VALUE")
+	fmt.Fprintln(os.Stderr, "       adtap config unset KEY")
+	fmt.Fprintln(os.Stderr, "\nManage named profiles in "+config.DefaultPath()+".")
+	fmt.Fprintln(os.Stderr, "get, set, and unset act on the profile chosen with --profile NAME (or")
+	fmt.Fprintln(os.Stderr, "ADTAP_PROFILE), else the default profile. Every command accepts --profile.")
+	fmt.Fprintln(os.Stderr, "\nKeys: "+strings.Join(config.Keys, ", ")+", default_profile")
+	fmt.Fprintln(os.Stderr, "\nEnvironment variables take precedence over profile settings.")
+}
+
+// displayValue masks secrets in config list output.
+func displayValue(key, v string) string {
+	if key == "developer_token" && len(v) > 4 {
+		return strings.Repeat("*", len(v)-4) + v[len(v)-4:]
+	}
+	return v
+}
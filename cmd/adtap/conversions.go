@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/geo"
+	"github.com/aygp-dr/adtap/internal/rowjoin"
+)
+
+func cmdConversions(args []string) {
+	fs := newFlagSet("conversions")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	by := fs.String("by", "action", "Group conversions by: "+strings.Join(compose.ConversionBys, ", "))
+	last := fs.String("last", "30d", "Trailing period ending yesterday, such as 30d or 4w")
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	out := addOutputFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap conversions --customer-id ID [--by action|category|campaign|date] [--last 30d]")
+		fmt.Fprintln(os.Stderr, "\nReport conversions, conversion value, and value per conversion split")
+		fmt.Fprintln(os.Stderr, "by conversion action. By action, every enabled or hidden action is")
+		fmt.Fprintln(os.Stderr, "listed with its type, category, and whether it is primary for its goal,")
+		fmt.Fprintln(os.Stderr, "including actions that recorded nothing in the period.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if *customerID == "" {
+		usageError("conversions", "--customer-id is required")
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	span, err := compose.ParseLast(*last, time.Now())
+	if err != nil {
+		usageError("conversions", "--last: "+strings.TrimPrefix(err.Error(), "compose: "))
+	}
+	q, err := compose.Conversions(compose.ConversionOptions{By: *by, Span: span})
+	if err != nil {
+		usageError("conversions", "--by: "+strings.TrimPrefix(err.Error(), "compose: "))
+	}
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+		if strings.EqualFold(*by, "action") {
+			fmt.Fprintln(os.Stderr, compose.ConversionActions())
+		}
+	}
+
+	ctx := shutdownContext()
+	client := newClient()
+	fields := q.FieldNames()
+	rows := fetchJoinRows(ctx, client, id, q, q.String())
+	if strings.EqualFold(*by, "action") {
+		fields, rows = conversionsByAction(ctx, client, id, rowjoin.Table{Fields: fields, Rows: rows})
+	}
+
+	_, r, opts := out.renderer()
+	opts.Constants = geo.Default()
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	for _, values := range rows {
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
+
+// conversionActionColumns are the conversion_action fields shown before
+// the metrics of "conversions --by action".
+var conversionActionColumns = []string{
+	"conversion_action.name",
+	"conversion_action.type",
+	"conversion_action.category",
+	"conversion_action.primary_for_goal",
+}
+
+// conversionsByAction joins the metrics rows, segmented by
+// segments.conversion_action, to the account's conversion actions. Rows
+// keep the metrics order; actions without conversions follow with zero
+// metrics. Actions removed since converting keep their metrics row
+// without attributes.
+func conversionsByAction(ctx context.Context, client *adsapi.Client, id string, metrics rowjoin.Table) ([]string, [][]any) {
+	aq := compose.ConversionActions()
+	actions := rowjoin.Table{Fields: aq.FieldNames(), Rows: fetchJoinRows(ctx, client, id, aq, aq.String())}
+	key := rowjoin.Key{Left: "segments.conversion_action", Right: "conversion_action.resource_name"}
+	joined, rows, err := rowjoin.Join(metrics, actions, []rowjoin.Key{key}, rowjoin.Left)
+	if err != nil {
+		exitValidationError("%s", strings.TrimPrefix(err.Error(), "rowjoin: "))
+	}
+
+	fields := append(slices.Clone(conversionActionColumns), compose.ConversionMetrics...)
+	project := func(names []string, row []any) []any {
+		values := make([]any, len(fields))
+		for i, f := range fields {
+			if j := slices.Index(names, f); j >= 0 {
+				values[i] = row[j]
+			}
+		}
+		return values
+	}
+	seen := map[any]bool{}
+	segName := slices.Index(joined, "segments.conversion_action_name")
+	var out [][]any
+	for _, row := range rows {
+		seen[row[0]] = true
+		values := project(joined, row)
+		if values[0] == nil {
+			values[0] = row[segName]
+		}
+		out = append(out, values)
+	}
+	for _, row := range actions.Rows {
+		if seen[row[0]] {
+			continue
+		}
+		values := project(actions.Fields, row)
+		for i := len(conversionActionColumns); i < len(values); i++ {
+			values[i] = 0
+		}
+		out = append(out, values)
+	}
+	return fields, out
+}
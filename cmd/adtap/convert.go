@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// queryForms are the representations convert reads and writes.
+var queryForms = []string{"gaql", "ast", "spec"}
+
+func cmdConvert(args []string) {
+	fs := newFlagSet("convert")
+	from := fs.String("from", "auto", "Input form: auto, gaql, ast, spec")
+	to := fs.String("to", "", "Output form: gaql, ast, spec (required)")
+	schema := fs.Bool("schema", false, "Print the JSON Schema of the AST form and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap convert --to gaql|ast|spec [--from auto|gaql|ast|spec] [FILE|-]")
+		fmt.Fprintln(os.Stderr, "       adtap convert --schema")
+		fmt.Fprintln(os.Stderr, "\nConvert a query between GAQL text, the AST JSON form, and the")
+		fmt.Fprintln(os.Stderr, "declarative spec form. --from auto reads JSON whose select items are")
+		fmt.Fprintln(os.Stderr, "objects as the AST form, other JSON as a spec, and anything else as")
+		fmt.Fprintln(os.Stderr, "GAQL. The query is read from FILE, or stdin when FILE is - or absent.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+
+	if *schema {
+		if _, err := os.Stdout.Write(gaql.JSONSchema()); err != nil {
+			exitIOError(err)
+		}
+		return
+	}
+	if fs.NArg() > 1 {
+		usageError("convert", "at most one input file may be given")
+	}
+	if *to == "" {
+		usageError("convert", "--to is required")
+	}
+	if *from != "auto" && !slices.Contains(queryForms, *from) {
+		exitValidationError("invalid --from value %q\n\nExpected: auto, %s", *from, strings.Join(queryForms, ", "))
+	}
+	if !slices.Contains(queryForms, *to) {
+		exitValidationError("invalid --to value %q\n\nExpected: %s", *to, strings.Join(queryForms, ", "))
+	}
+
+	path := "-"
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+	src, err := readQueryFile(path)
+	if err != nil {
+		exitIOError(err)
+	}
+
+	form := *from
+	if form == "auto" {
+		form = detectQueryForm(src)
+	}
+	q, err := decodeQuery(src, form)
+	if err != nil {
+		exitValidationError("invalid %s input: %v", form, strings.TrimPrefix(err.Error(), "gaql: "))
+	}
+
+	var out any
+	switch *to {
+	case "gaql":
+		fmt.Println(q)
+		return
+	case "ast":
+		out = q
+	case "spec":
+		out = q.Spec()
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		exitIOError(err)
+	}
+}
+
+// detectQueryForm guesses the form of src for --from auto.
+func detectQueryForm(src string) string {
+	if !strings.HasPrefix(strings.TrimSpace(src), "{") {
+		return "gaql"
+	}
+	var probe struct {
+		Select []json.RawMessage `json:"select"`
+	}
+	if json.Unmarshal([]byte(src), &probe) == nil && len(probe.Select) > 0 &&
+		strings.HasPrefix(strings.TrimSpace(string(probe.Select[0])), "{") {
+		return "ast"
+	}
+	return "spec"
+}
+
+// decodeQuery reads src in the given form.
+func decodeQuery(src, form string) (*gaql.Query, error) {
+	switch form {
+	case "ast":
+		var q gaql.Query
+		if err := json.Unmarshal([]byte(src), &q); err != nil {
+			return nil, err
+		}
+		return &q, nil
+	case "spec":
+		// Unknown keys are rejected so a misspelled clause is reported
+		// rather than dropped from the query.
+		var s gaql.Spec
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&s); err != nil {
+			return nil, err
+		}
+		return s.Query()
+	default:
+		return gaql.Parse(src)
+	}
+}
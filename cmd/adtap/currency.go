@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/demo"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/fx"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// currencyFlags select the currency money metrics are converted into,
+// and the exchange rates used.
+type currencyFlags struct {
+	cmd      string
+	currency string
+	rates    *string
+}
+
+// ratesECB is the --fx-rates value that fetches the ECB reference rates.
+const ratesECB = "ecb"
+
+func addCurrencyFlags(fs *flag.FlagSet, cmd string) *currencyFlags {
+	f := &currencyFlags{cmd: cmd}
+	fs.StringVar(&f.currency, "currency", "", "Convert cost metrics and conversion values from each account's currency into this one, e.g. USD; requires --fx-rates")
+	fs.StringVar(&f.currency, "normalize-currency", "", "Same as --currency")
+	f.rates = fs.String("fx-rates", "", "Exchange rates for --currency: a JSON file ({\"base\": \"USD\", \"rates\": {...}}), or ecb to fetch the day's ECB reference rates from "+fx.DefaultRatesURL)
+	return f
+}
+
+// converter returns the converter the flags ask for, or nil when
+// currencies are left alone.
+func (f *currencyFlags) converter() *fx.Converter {
+	code := strings.ToUpper(f.currency)
+	if code == "" {
+		if *f.rates != "" {
+			usageError(f.cmd, "--fx-rates requires --currency")
+		}
+		return nil
+	}
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		exitValidationError("invalid currency code\n\nExpected: USD\nGot: %s", f.currency)
+	}
+
+	// Rates are only fetched when asked for: the request leaves the
+	// machine, and a run may want month-end rates instead.
+	var p fx.Provider
+	switch {
+	case offlineDemo && (*f.rates == "" || *f.rates == ratesECB):
+		p = demo.Rates()
+	case *f.rates == "":
+		usageError(f.cmd, "--currency requires --fx-rates: a file of exchange rates, or ecb to fetch the ECB reference rates")
+	case *f.rates == ratesECB:
+		p = fx.NewHTTPProvider(fx.DefaultRatesURL)
+	default:
+		table, err := fx.LoadTableFile(*f.rates)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "I/O error: %v\n\nPath: %s\n", err, *f.rates)
+			os.Exit(exitcode.IOError)
+		}
+		p = table
+	}
+	return fx.NewConverter(p, code)
+}
+
+// accountCurrency returns the currency of customer id.
+func accountCurrency(ctx context.Context, client *adsapi.Client, id string) string {
+	resp, err := client.Search(ctx, id, fx.CurrencyQuery)
+	if err != nil {
+		exitAPIError(err)
+	}
+	var code string
+	if len(resp.Results) > 0 {
+		v, _ := output.Value(resp.Results[0], "customer.currency_code")
+		code, _ = v.(string)
+	}
+	if code == "" {
+		fmt.Fprintf(os.Stderr, "API error: customer %s did not report a currency\n", id)
+		os.Exit(exitcode.APIError)
+	}
+	return code
+}
+
+// convertRow converts the money fields of row from currency from into
+// the converter's currency.
+func convertRow(ctx context.Context, conv *fx.Converter, row map[string]any, fields []string, from string) {
+	if err := conv.ConvertRow(ctx, row, fields, from); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: converting %s to %s: %v\n", from, conv.Currency(), err)
+		os.Exit(exitcode.GeneralError)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/accounts"
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+func cmdCustomers(args []string) {
+	fs := newFlagSet("customers")
+	tree := fs.Bool("tree", false, "Expand manager accounts into the full account hierarchy")
+	customerID := fs.String("customer-id", "", "Root of the hierarchy for --tree (default: every accessible customer)")
+	out := addOutputFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap customers [--tree [--customer-id ID]]")
+		fmt.Fprintln(os.Stderr, "\nList the customers the credentials can access directly. With --tree,")
+		fmt.Fprintln(os.Stderr, "walk customer_client to show manager accounts and all their children;")
+		fmt.Fprintln(os.Stderr, "formats other than table print one row per account with its parent.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+
+	if *customerID != "" && !*tree {
+		fmt.Fprintln(os.Stderr, "Usage error: --customer-id requires --tree")
+		fmt.Fprintln(os.Stderr, "\nRun 'adtap customers --help' for usage.")
+		os.Exit(exitcode.UsageError)
+	}
+
+	var rootID string
+	if *customerID != "" {
+		id, err := adsapi.NormalizeCustomerID(*customerID)
+		if err != nil {
+			exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+		}
+		rootID = id
+	}
+
+	format, r, opts := out.renderer()
+	ctx := context.Background()
+	client := newClient()
+
+	ids := []string{rootID}
+	if rootID == "" {
+		var err error
+		ids, err = client.ListAccessibleCustomers(ctx)
+		if err != nil {
+			exitAPIError(err)
+		}
+	}
+
+	if !*tree {
+		listed := make([]*accounts.Account, len(ids))
+		for i, id := range ids {
+			listed[i] = &accounts.Account{ID: id}
+		}
+		cacheCustomers(listed)
+		fields := []string{"customer_id"}
+		if err := r.WriteHeader(fields); err != nil {
+			exitIOError(err)
+		}
+		for _, id := range ids {
+			if err := opts.WriteRecord(r, fields, []any{id}); err != nil {
+				exitIOError(err)
+			}
+		}
+		if err := r.Flush(); err != nil {
+			exitIOError(err)
+		}
+		return
+	}
+
+	roots := accountTrees(ctx, client, ids)
+	var listed []*accounts.Account
+	for _, root := range roots {
+		root.Walk(func(a *accounts.Account, _ int) { listed = append(listed, a) })
+	}
+	cacheCustomers(listed)
+	if format == output.FormatTable {
+		for _, root := range roots {
+			printAccountTree(root, "", "")
+		}
+		return
+	}
+	writeAccountRows(r, opts, roots)
+}
+
+// cacheCustomers remembers the customers listed for shell completion.
+// Customers listed before stay in the cache, as do their names when the
+// listing has none.
+func cacheCustomers(listed []*accounts.Account) {
+	path := accounts.DefaultCachePath()
+	cached, err := accounts.LoadCache(path)
+	if err == nil {
+		err = accounts.SaveCache(path, append(listed, cached...))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// accountTrees returns the account hierarchies below ids, without trees
+// nested in another. Each ID is queried as its own login customer, so
+// the tree below it is reachable whatever GOOGLE_ADS_LOGIN_CUSTOMER_ID
+// is set to. Unreachable IDs are skipped with a warning; the command
+// exits when none is reachable.
+func accountTrees(ctx context.Context, client *adsapi.Client, ids []string) []*accounts.Account {
+	var roots []*accounts.Account
+	var lastErr error
+	for _, id := range ids {
+		root, err := accounts.Tree(ctx, client.WithLogin(id), id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", id, err)
+			lastErr = err
+			continue
+		}
+		roots = append(roots, root)
+	}
+	if len(roots) == 0 && lastErr != nil {
+		exitAPIError(lastErr)
+	}
+	return accounts.DropNested(roots)
+}
+
+// writeAccountRows writes one row per account in the hierarchy, with the
+// ID of its parent, for formats that cannot show a tree.
+func writeAccountRows(r output.Renderer, opts output.Options, roots []*accounts.Account) {
+	fields := []string{"customer_id", "name", "currency_code", "time_zone", "status", "manager", "test_account", "level", "parent_id"}
+	if err := r.WriteHeader(fields); err != nil {
+		exitIOError(err)
+	}
+	for _, root := range roots {
+		var path []string // IDs of the ancestors of the current account
+		var err error
+		root.Walk(func(a *accounts.Account, depth int) {
+			path = append(path[:depth], a.ID)
+			var parent string
+			if depth > 0 {
+				parent = path[depth-1]
+			}
+			if err == nil {
+				err = opts.WriteRecord(r, fields, []any{a.ID, a.Name, a.CurrencyCode, a.TimeZone, a.Status, a.Manager, a.TestAccount, depth, parent})
+			}
+		})
+		if err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
+
+// printAccountTree prints a with box-drawing branches. prefix starts a's
+// own line; childPrefix starts the lines of its descendants.
+func printAccountTree(a *accounts.Account, prefix, childPrefix string) {
+	fmt.Println(prefix + describeAccount(a))
+	for i, c := range a.Children {
+		if i == len(a.Children)-1 {
+			printAccountTree(c, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			printAccountTree(c, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+func describeAccount(a *accounts.Account) string {
+	parts := []string{a.ID}
+	if a.Name != "" {
+		parts = append(parts, a.Name)
+	}
+	if a.CurrencyCode != "" {
+		parts = append(parts, a.CurrencyCode)
+	}
+	if a.TimeZone != "" {
+		parts = append(parts, a.TimeZone)
+	}
+
+	var tags []string
+	if a.Manager {
+		tags = append(tags, "manager")
+	}
+	if a.TestAccount {
+		tags = append(tags, "test")
+	}
+	if a.Status != "" && a.Status != "ENABLED" {
+		tags = append(tags, strings.ToLower(a.Status))
+	}
+	if a.Cycle {
+		tags = append(tags, "cycle: already listed above")
+	}
+	line := strings.Join(parts, "  ")
+	if len(tags) > 0 {
+		line += "  [" + strings.Join(tags, ", ") + "]"
+	}
+	return line
+}
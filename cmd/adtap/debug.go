@@ -0,0 +1,70 @@
+package main
+
+import (
+	"expvar"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+)
+
+// started is when the process began, for the uptime debug variable.
+var started = time.Now()
+
+func init() {
+	expvar.Publish("adtap", expvar.Func(func() any {
+		return map[string]any{
+			"version":        version,
+			"uptime_seconds": int64(time.Since(started).Seconds()),
+		}
+	}))
+}
+
+// addDebugFlag adds --debug-addr to the flags of a long-running command.
+func addDebugFlag(fs *flag.FlagSet) *string {
+	return fs.String("debug-addr", "", "Serve pprof at /debug/pprof/ and expvar at /debug/vars on this address (e.g. localhost:6060)")
+}
+
+// startDebugServer serves the runtime diagnostics endpoints on addr in
+// the background: the net/http/pprof profiles, and the expvar variables
+// including memstats and any the command publishes. It exits when addr
+// cannot be listened on. An empty addr does nothing.
+func startDebugServer(addr string) {
+	if addr == "" {
+		return
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		exitSetupError(configError(err.Error(), "pass a free address to --debug-addr, such as localhost:6060."))
+	}
+	if host, _, _ := net.SplitHostPort(addr); !isLoopback(host) {
+		fmt.Fprintf(os.Stderr, "Warning: debug endpoints on %s are reachable from the network and expose memory contents\n", ln.Addr())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	fmt.Fprintf(os.Stderr, "Debug endpoints at http://%s/debug/pprof/ and /debug/vars\n", ln.Addr())
+	go func() {
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		if err := srv.Serve(ln); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: debug server stopped: %v\n", err)
+		}
+	}()
+}
+
+// isLoopback reports whether host names only the local machine.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
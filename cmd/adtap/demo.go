@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/config"
+	"github.com/aygp-dr/adtap/internal/demo"
+)
+
+// offlineDemo is set by --offline-demo or ADTAP_OFFLINE_DEMO=1: commands
+// query the bundled demo accounts instead of the API, with no
+// configuration or credentials.
+var offlineDemo bool
+
+// demoProfile is the profile of --offline-demo, defaulting commands to
+// the demo client account.
+func demoProfile() *config.Profile {
+	return &config.Profile{CustomerID: demo.CustomerID}
+}
+
+// demoClient returns a client of the demo accounts. Results are not
+// cached, since they are computed locally.
+func demoClient() *adsapi.Client {
+	srv := demo.New(time.Now())
+	opts := []adsapi.Option{
+		adsapi.WithHTTPClient(&http.Client{Transport: srv.Transport()}),
+		adsapi.WithRecorder(runTimings),
+		adsapi.WithLogger(slog.Default()),
+	}
+	conn, err := connectionOptions(false)
+	if err != nil {
+		exitSetupError(err.(*setupError))
+	}
+	opts = append(opts, conn...)
+	return adsapi.New("demo", adsapi.StaticToken("demo"), append(opts, recordOptions()...)...)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// fieldName matches GoogleAdsField names, which describe quotes into a
+// query.
+var fieldName = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z0-9_]+)*$`)
+
+func cmdDescribe(args []string) {
+	fs := newFlagSet("describe")
+	offline := fs.Bool("offline", false, "Use the synced or embedded schema catalog instead of GoogleAdsFieldService")
+	format := fs.String("format", "human", "Output format: human, json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap describe [--offline] [--format human|json] NAME...")
+		fmt.Fprintln(os.Stderr, "\nDescribe resources and fields, such as campaign or metrics.clicks: whether")
+		fmt.Fprintln(os.Stderr, "they are selectable, filterable, and sortable, their data type and enum")
+		fmt.Fprintln(os.Stderr, "values, and the resources, segments, and metrics they combine with. The")
+		fmt.Fprintln(os.Stderr, "API's GoogleAdsFieldService is asked when credentials are configured;")
+		fmt.Fprintln(os.Stderr, "otherwise, or with --offline, the schema saved by 'adtap schema sync'")
+		fmt.Fprintln(os.Stderr, "answers, or the embedded "+gaql.DefaultCatalog().Version+" catalog before any sync.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		usageError("describe", "at least one resource or field name is required")
+	}
+	if *format != "human" && *format != "json" {
+		usageError("describe", fmt.Sprintf("unknown format %q (expected human or json)", *format))
+	}
+	for _, name := range fs.Args() {
+		if !fieldName.MatchString(name) {
+			exitValidationError("invalid resource or field name\n\nExpected: campaign or metrics.clicks\nGot: %s", name)
+		}
+	}
+
+	lookup, source := catalogLookup(*offline)
+	var found []gaql.Description
+	for _, name := range fs.Args() {
+		d, ok := lookup(name)
+		if !ok {
+			exitValidationError("unknown resource or field %q in the %s\n\nHint: names are snake_case, such as campaign.advertising_channel_type; Tab completes them in 'adtap repl'.", name, source)
+		}
+		found = append(found, d)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(found); err != nil {
+			exitIOError(err)
+		}
+		return
+	}
+	for i, d := range found {
+		if i > 0 {
+			fmt.Println()
+		}
+		printDescription(d, source)
+	}
+}
+
+// catalogLookup returns how to describe names, and where the answers
+// come from: the API when credentials are configured and offline is
+// false, and the synced or embedded catalog otherwise.
+func catalogLookup(offline bool) (func(string) (gaql.Description, bool), string) {
+	local := gaql.DefaultCatalog()
+	source := "embedded " + local.Version + " catalog"
+	if synced := syncedCatalog(cmp.Or(activeProfile().APIVersion, gaql.DefaultAPIVersion)); synced != nil {
+		local, source = synced, "synced "+synced.Version+" schema"
+	}
+	if offline {
+		return local.Describe, source
+	}
+	client, err := clientFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Note: %v; describing from the %s.\n", err, source)
+		return local.Describe, source
+	}
+	ctx := context.Background()
+	return func(name string) (gaql.Description, bool) {
+		fields, err := client.SearchFields(ctx, fmt.Sprintf("name = '%s'", name))
+		if err != nil {
+			exitAPIError(err)
+		}
+		if len(fields) == 1 && fields[0].Category == "RESOURCE" {
+			attrs, err := client.SearchFields(ctx, fmt.Sprintf("name LIKE '%s.%%'", name))
+			if err != nil {
+				exitAPIError(err)
+			}
+			fields = append(fields, attrs...)
+		}
+		return gaql.NewCatalog(client.Version(), fields).Describe(name)
+	}, client.Version() + " API schema (GoogleAdsFieldService)"
+}
+
+func printDescription(d gaql.Description, source string) {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	fmt.Println(d.Name)
+	fmt.Printf("  Category     %s\n", d.Category)
+	fmt.Printf("  Data type    %s\n", d.DataType)
+	fmt.Printf("  Selectable   %s\n", yesNo(d.Selectable))
+	fmt.Printf("  Filterable   %s\n", yesNo(d.Filterable))
+	fmt.Printf("  Sortable     %s\n", yesNo(d.Sortable))
+	fmt.Printf("  Repeated     %s\n", yesNo(d.Repeated))
+	if d.Deprecated != "" {
+		fmt.Printf("  Deprecated   %s\n", d.Deprecated)
+	}
+	fmt.Printf("  Source       %s\n", source)
+	if len(d.EnumValues) > 0 {
+		printList("Enum values", d.EnumValues)
+	}
+
+	if len(d.Fields) > 0 {
+		width, typeWidth := 0, 0
+		for _, f := range d.Fields {
+			width, typeWidth = max(width, len(f.Name)), max(typeWidth, len(f.DataType))
+		}
+		fmt.Printf("\nFields (%d):\n", len(d.Fields))
+		for _, f := range d.Fields {
+			var flags []string
+			for _, flag := range []struct {
+				set  bool
+				name string
+			}{{f.Selectable, "selectable"}, {f.Filterable, "filterable"}, {f.Sortable, "sortable"}, {f.Repeated, "repeated"}} {
+				if flag.set {
+					flags = append(flags, flag.name)
+				}
+			}
+			fmt.Printf("  %-*s  %-*s  %s\n", width, f.Name, typeWidth, f.DataType, strings.Join(flags, " "))
+		}
+	}
+	printList("Compatible resources", d.Resources)
+	printList("Compatible segments", d.Segments)
+	printList("Compatible metrics", d.Metrics)
+}
+
+// printList prints a titled list of names, wrapped to fit a terminal.
+func printList(title string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Printf("\n%s (%d):\n", title, len(names))
+	line := " "
+	for i, n := range names {
+		if i < len(names)-1 {
+			n += ","
+		}
+		if len(line)+1+len(n) > 78 {
+			fmt.Println(line)
+			line = " "
+		}
+		line += " " + n
+	}
+	fmt.Println(line)
+}
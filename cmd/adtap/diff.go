@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func cmdDiff(args []string) {
+	fs := newFlagSet("diff")
+	format := fs.String("format", "human", "Output format: human, json")
+	exitCode := fs.Bool("exit-code", false, "Exit with status 8 when the queries differ")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap diff [--format human|json] [--exit-code] OLD NEW")
+		fmt.Fprintln(os.Stderr, "\nCompare two stored queries by meaning: fields added, removed, or")
+		fmt.Fprintln(os.Stderr, "reordered, conditions added, removed, or changed, and changes to FROM,")
+		fmt.Fprintln(os.Stderr, "ORDER BY, LIMIT, and PARAMETERS. Layout, keyword case, and the order")
+		fmt.Fprintln(os.Stderr, "of WHERE conditions and IN lists are ignored. Each file holds GAQL, the")
+		fmt.Fprintln(os.Stderr, "AST JSON form, or a spec, as for convert; - reads one from stdin.")
+		fmt.Fprintln(os.Stderr, "Equivalent queries print nothing.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		usageError("diff", "two query files are required")
+	}
+	if fs.Arg(0) == "-" && fs.Arg(1) == "-" {
+		usageError("diff", "only one query can be read from stdin")
+	}
+	if *format != "human" && *format != "json" {
+		exitValidationError("invalid output format %q\n\nExpected: human, json", *format)
+	}
+	a := readDiffQuery(fs.Arg(0))
+	b := readDiffQuery(fs.Arg(1))
+
+	changes := gaql.Diff(a, b)
+	if *format == "json" {
+		if changes == nil {
+			changes = []gaql.Change{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			exitIOError(err)
+		}
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+	if *exitCode && len(changes) > 0 {
+		os.Exit(exitcode.Alert)
+	}
+}
+
+// readDiffQuery reads and decodes the query in path, in any form.
+func readDiffQuery(path string) *gaql.Query {
+	src, err := readQueryFile(path)
+	if err != nil {
+		exitIOError(err)
+	}
+	form := detectQueryForm(src)
+	q, err := decodeQuery(src, form)
+	if err != nil {
+		exitValidationError("%s: invalid %s input: %v", path, form, strings.TrimPrefix(err.Error(), "gaql: "))
+	}
+	return q
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/auth"
+	"github.com/aygp-dr/adtap/internal/config"
+	"github.com/aygp-dr/adtap/internal/doctor"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/secrets"
+)
+
+// doctorTimeout bounds each network check of adtap doctor.
+const doctorTimeout = 20 * time.Second
+
+func cmdDoctor(args []string) {
+	fs := newFlagSet("doctor")
+	customerID := fs.String("customer-id", "", "Also check access to this account (default: the profile's)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap doctor [--customer-id ID]")
+		fmt.Fprintln(os.Stderr, "\nCheck each prerequisite of calling the API on its own: the developer")
+		fmt.Fprintln(os.Stderr, "token, the credentials and the access token they yield, its scopes, the")
+		fmt.Fprintln(os.Stderr, "clock, the API itself, and the login customer. Failures say what to fix;")
+		fmt.Fprintln(os.Stderr, "checks that need a failed one are skipped. The exit code is that of the")
+		fmt.Fprintln(os.Stderr, "first failure.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError("doctor", fmt.Sprintf("unexpected argument %q", fs.Arg(0)))
+	}
+	if offlineDemo || replayDir != "" {
+		usageError("doctor", "doctor checks the real setup; drop --offline-demo and --replay")
+	}
+	defaultCustomer(customerID)
+	if *customerID != "" {
+		id, err := adsapi.NormalizeCustomerID(*customerID)
+		if err != nil {
+			exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+		}
+		*customerID = id
+	}
+
+	d := &diagnosis{ctx: shutdownContext()}
+	d.run(*customerID)
+	if err := doctor.Write(os.Stdout, d.results); err != nil {
+		exitIOError(err)
+	}
+	if d.code != exitcode.Success {
+		os.Exit(d.code)
+	}
+}
+
+// diagnosis collects the results of adtap doctor and the exit code of
+// the first failure.
+type diagnosis struct {
+	ctx     context.Context
+	results []doctor.Result
+	code    int
+}
+
+func (d *diagnosis) ok(name, detail string) {
+	d.results = append(d.results, doctor.Result{Name: name, Status: doctor.OK, Detail: detail})
+}
+
+func (d *diagnosis) fail(name string, code int, detail, hint string) {
+	d.results = append(d.results, doctor.Result{Name: name, Status: doctor.Fail, Detail: detail, Hint: hint})
+	if d.code == exitcode.Success {
+		d.code = code
+	}
+}
+
+func (d *diagnosis) skip(name, why string) {
+	d.results = append(d.results, doctor.Result{Name: name, Status: doctor.Skip, Detail: why})
+}
+
+func (d *diagnosis) failSetup(name string, err error) {
+	se := err.(*setupError)
+	d.fail(name, se.code, se.msg, se.hint)
+}
+
+// timeout returns a context for one network check.
+func (d *diagnosis) timeout() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(d.ctx, doctorTimeout)
+}
+
+// run runs the checks in order, each as far as the ones it needs
+// passed.
+func (d *diagnosis) run(customerID string) {
+	p, err := loadProfile()
+	if err != nil {
+		d.failSetup("Configuration", err)
+		d.skip("Developer token", "needs the configuration")
+		d.skip("Credentials", "needs the configuration")
+	} else {
+		d.ok("Configuration", "profile "+cmp.Or(profileName, "default"))
+	}
+
+	var token string
+	var ts *auth.TokenSource
+	if p != nil {
+		provider, err := secretsProvider()
+		if err != nil {
+			d.failSetup("Secrets", err)
+		}
+		var source string
+		token, source, err = developerToken(p, provider)
+		if err != nil {
+			d.failSetup("Developer token", err)
+		} else {
+			d.ok("Developer token", "from "+source)
+		}
+		ts, err = tokenSource(provider)
+		if err != nil {
+			d.failSetup("Credentials", err)
+		} else {
+			d.ok("Credentials", credentialsSource(provider))
+		}
+	}
+
+	d.checkClock()
+
+	var access string
+	if ts == nil {
+		d.skip("Access token", "needs credentials")
+	} else {
+		access = d.checkToken(ts)
+	}
+	if access == "" {
+		d.skip("Token scopes", "needs an access token")
+	} else {
+		d.checkScopes(access)
+	}
+
+	if token == "" || access == "" {
+		d.skip("Google Ads API", "needs a developer token and an access token")
+		d.skip("Login customer", "needs the API")
+		d.skip("Customer", "needs the API")
+		return
+	}
+	opts, err := clientOptions(p, token)
+	if err != nil {
+		d.failSetup("Google Ads API", err)
+		return
+	}
+	client := adsapi.New(token, ts, opts...)
+	accessible, ok := d.checkAPI(client)
+	if !ok {
+		d.skip("Login customer", "needs the API")
+		d.skip("Customer", "needs the API")
+		return
+	}
+	d.checkLogin(p, accessible)
+	d.checkCustomer(client, customerID)
+}
+
+// credentialsSource describes where tokenSource found the credentials.
+func credentialsSource(provider secrets.Provider) string {
+	for _, name := range []string{"GOOGLE_ADS_JSON_KEY_FILE_PATH", "GOOGLE_APPLICATION_CREDENTIALS"} {
+		path := os.Getenv(name)
+		if path == "" {
+			continue
+		}
+		if c, err := auth.LoadCredentialsFile(path); err == nil && c.Type == auth.TypeServiceAccount {
+			return fmt.Sprintf("service account %s, from %s", c.ClientEmail, name)
+		}
+		return fmt.Sprintf("%s, from %s", path, name)
+	}
+	if os.Getenv("GOOGLE_ADS_REFRESH_TOKEN") != "" {
+		return "refresh token from GOOGLE_ADS_REFRESH_TOKEN"
+	}
+	if provider != nil {
+		if _, err := auth.FromSecrets(context.Background(), provider); err == nil {
+			return "from " + provider.String()
+		}
+	}
+	if store, err := auth.DefaultStore(); err == nil {
+		return "signed in; saved in " + store.String()
+	}
+	return "signed in"
+}
+
+func (d *diagnosis) checkClock() {
+	ctx, cancel := d.timeout()
+	defer cancel()
+	skew, err := doctor.ClockSkew(ctx, http.DefaultClient, auth.DefaultTokenURL)
+	if err != nil {
+		d.results = append(d.results, doctor.Result{Name: "Clock", Status: doctor.Warn,
+			Detail: "could not compare: " + err.Error(), Hint: "check the network connection and any proxy settings."})
+		return
+	}
+	r := doctor.SkewResult(skew)
+	if r.Status == doctor.Fail && d.code == exitcode.Success {
+		d.code = exitcode.ConfigError
+	}
+	d.results = append(d.results, r)
+}
+
+// checkToken fetches an access token and returns it, or "" when that
+// fails.
+func (d *diagnosis) checkToken(ts *auth.TokenSource) string {
+	ctx, cancel := d.timeout()
+	defer cancel()
+	token, err := ts.Token(ctx)
+	var te *auth.TokenError
+	switch {
+	case errors.As(err, &te) && te.Code == "invalid_grant":
+		d.fail("Access token", exitcode.AuthError, err.Error(),
+			"the refresh token expired or was revoked, or the service account key was deleted or the clock is off; run 'adtap auth login' again or download a new key.")
+	case errors.As(err, &te) && te.Code == "invalid_client":
+		d.fail("Access token", exitcode.AuthError, err.Error(),
+			"the OAuth2 client ID or secret is wrong; check GOOGLE_ADS_CLIENT_ID and GOOGLE_ADS_CLIENT_SECRET.")
+	case errors.As(err, &te):
+		d.fail("Access token", exitcode.AuthError, err.Error(), "check the credentials file.")
+	case err != nil:
+		d.fail("Access token", exitcode.IOError, err.Error(), "check the network connection and any proxy settings.")
+	default:
+		d.ok("Access token", "issued")
+	}
+	return token
+}
+
+func (d *diagnosis) checkScopes(token string) {
+	ctx, cancel := d.timeout()
+	defer cancel()
+	info, err := auth.LookupToken(ctx, http.DefaultClient, token)
+	if err != nil {
+		d.results = append(d.results, doctor.Result{Name: "Token scopes", Status: doctor.Warn,
+			Detail: "could not look up: " + err.Error()})
+		return
+	}
+	if !info.HasScope(auth.Scope) {
+		d.fail("Token scopes", exitcode.AuthError, "missing "+auth.Scope+" (granted: "+strings.Join(info.Scopes, " ")+")",
+			"sign in again with 'adtap auth login', which asks for the Google Ads scope.")
+		return
+	}
+	detail := "Google Ads scope granted"
+	if info.Email != "" {
+		detail += " to " + info.Email
+	}
+	d.ok("Token scopes", detail)
+}
+
+// checkAPI lists the accessible customers, which needs the API enabled
+// in the Cloud project and a working developer token.
+func (d *diagnosis) checkAPI(client *adsapi.Client) ([]string, bool) {
+	ctx, cancel := d.timeout()
+	defer cancel()
+	ids, err := client.ListAccessibleCustomers(ctx)
+	if err != nil {
+		detail, hint := doctor.ExplainAPIError(err)
+		d.fail("Google Ads API", apiErrorCode(err), detail, hint)
+		return nil, false
+	}
+	d.ok("Google Ads API", fmt.Sprintf("%s, %d accounts accessible", client.Version(), len(ids)))
+	return ids, true
+}
+
+// checkLogin checks that the login customer, or each manager, is one
+// the credentials reach directly, as the API requires.
+func (d *diagnosis) checkLogin(p *config.Profile, accessible []string) {
+	var ids []string
+	if id := cmp.Or(loginFlag, setting("GOOGLE_ADS_LOGIN_CUSTOMER_ID", p.LoginCustomerID)); id != "" {
+		ids = []string{id}
+	} else if s := setting("ADTAP_MANAGERS", p.Managers); s != "" {
+		var err error
+		if ids, err = managerIDs(s); err != nil {
+			d.failSetup("Login customer", err)
+			return
+		}
+	}
+	if len(ids) == 0 {
+		d.ok("Login customer", "not set; accounts are queried directly")
+		return
+	}
+	for _, id := range ids {
+		if norm, err := adsapi.NormalizeCustomerID(id); err == nil {
+			id = norm
+		}
+		if !slices.Contains(accessible, id) {
+			d.fail("Login customer", exitcode.AuthError, id+" is not among the accounts the credentials can access",
+				"set the login customer ID or managers to manager accounts the user was invited to.")
+			return
+		}
+	}
+	d.ok("Login customer", strings.Join(ids, ", ")+" accessible")
+}
+
+func (d *diagnosis) checkCustomer(client *adsapi.Client, id string) {
+	if id == "" {
+		d.skip("Customer", "pass --customer-id to check an account")
+		return
+	}
+	ctx, cancel := d.timeout()
+	defer cancel()
+	if _, err := client.Search(ctx, id, "SELECT customer.id FROM customer LIMIT 1"); err != nil {
+		detail, hint := doctor.ExplainAPIError(err)
+		d.fail("Customer", apiErrorCode(err), detail, hint)
+		return
+	}
+	d.ok("Customer", id+" can be queried")
+}
+
+// apiErrorCode is the exit code exitAPIError would use for err.
+func apiErrorCode(err error) int {
+	var apiErr *adsapi.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return exitcode.AuthError
+	}
+	return exitcode.APIError
+}
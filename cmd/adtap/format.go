@@ -0,0 +1,113 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// outputFlags are the output options shared by commands that print
+// result rows.
+type outputFlags struct {
+	format   *string
+	enums    *string
+	rawEnums *bool
+	micros   *bool
+	rename   columnNames
+	output   *string
+	file     *os.File // the --output file, once created
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	names := make([]string, len(output.Formats))
+	for i, f := range output.Formats {
+		names[i] = string(f)
+	}
+	f := &outputFlags{
+		format:   fs.String("format", cmp.Or(activeProfile().Format, string(output.FormatTable)), "Output format: "+strings.Join(names, ", ")),
+		enums:    fs.String("enums", "auto", "Enum values: labels, raw, or auto (labels for table, markdown, and html)"),
+		rawEnums: fs.Bool("raw-enums", false, "Print enum values as returned by the API (same as --enums raw)"),
+		micros:   fs.Bool("micros-to-currency", false, "Show *_micros amounts, average CPC, and similar in currency units"),
+		rename:   columnNames{},
+		output:   fs.String("output", "", "Write rows to this file instead of stdout (- for stdout)"),
+	}
+	fs.Var(f.rename, "rename", "Name the column of a field: --rename metrics.cost_micros=Cost (repeatable)")
+	return f
+}
+
+// columnNames are the --rename values, column names by field.
+type columnNames map[string]string
+
+func (c columnNames) String() string {
+	pairs := make([]string, 0, len(c))
+	for field, name := range c {
+		pairs = append(pairs, field+"="+name)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (c columnNames) Set(s string) error {
+	field, name, ok := strings.Cut(s, "=")
+	field, name = strings.TrimSpace(field), strings.TrimSpace(name)
+	if !ok || field == "" || name == "" {
+		return errors.New("expected FIELD=NAME")
+	}
+	c[field] = name
+	return nil
+}
+
+// renderer validates the flags and returns a renderer writing to stdout
+// along with the matching options.
+func (f *outputFlags) renderer() (output.Format, output.Renderer, output.Options) {
+	format, err := output.ParseFormat(*f.format)
+	if err != nil {
+		names := make([]string, len(output.Formats))
+		for i, f := range output.Formats {
+			names[i] = string(f)
+		}
+		exitValidationError("invalid output format\n\nExpected: %s\nGot: %s", strings.Join(names, ", "), *f.format)
+	}
+
+	opts := output.Options{Micros: *f.micros}
+	if len(f.rename) > 0 {
+		opts.Rename = f.rename
+	}
+	switch strings.ToLower(*f.enums) {
+	case "auto":
+		opts.RawEnums = !format.Human()
+	case "labels":
+	case "raw":
+		opts.RawEnums = true
+	default:
+		exitValidationError("invalid --enums value\n\nExpected: labels, raw, auto\nGot: %s", *f.enums)
+	}
+	if *f.rawEnums {
+		opts.RawEnums = true
+	}
+
+	r, _ := output.NewRenderer(runTimings.Writer(f.writer()), format)
+	return format, timeRenderer(r, runTimings), opts
+}
+
+// writer returns the --output file, created on first use, or stdout
+// when --output is empty or "-".
+func (f *outputFlags) writer() io.Writer {
+	if *f.output == "" || *f.output == "-" {
+		return os.Stdout
+	}
+	if f.file == nil {
+		file, err := os.Create(*f.output)
+		if err != nil {
+			exitIOError(err)
+		}
+		f.file = file
+	}
+	return f.file
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/geo"
+)
+
+func cmdGeo(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		geoUsage()
+		os.Exit(0)
+	}
+	switch args[0] {
+	case "lookup":
+		geoLookup(args[1:])
+	default:
+		usageError("geo", fmt.Sprintf("unknown subcommand %q (expected lookup)", args[0]))
+	}
+}
+
+func geoUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap geo lookup NAME... [--country US] [--offline] [--targets FILE]")
+	fmt.Fprintln(os.Stderr, "\nFind the geo target constants of locations, such as \"Boston, MA\", for")
+	fmt.Fprintln(os.Stderr, "location targeting and for reading geographic reports. Names are looked")
+	fmt.Fprintln(os.Stderr, "up with GeoTargetConstantService, best match first. --offline searches")
+	fmt.Fprintln(os.Stderr, "the embedded countries and the --targets file, Google's geo targets CSV,")
+	fmt.Fprintln(os.Stderr, "instead.")
+	fmt.Fprintln(os.Stderr, "\nThe other way round, search names the geo targets its results reference,")
+	fmt.Fprintln(os.Stderr, "such as segments.geo_target_city, instead of printing their IDs.")
+}
+
+func geoLookup(args []string) {
+	fs := newFlagSet("geo lookup")
+	country := fs.String("country", "", "Only locations in this country, as an ISO code such as US")
+	offline := fs.Bool("offline", false, "Search the embedded countries and --targets instead of the API")
+	targets := fs.String("targets", "", "Geo targets CSV from Google to search with --offline")
+	out := addOutputFlags(fs)
+	fs.Usage = func() {
+		geoUsage()
+		printFlags(fs)
+	}
+	// Names and flags may come in any order; flag parsing stops at the
+	// first name, so it resumes after each.
+	var names []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			break
+		}
+		names = append(names, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(names) == 0 {
+		usageError("geo", "a location name is required, such as \"Boston, MA\"")
+	}
+	if *targets != "" && !*offline {
+		usageError("geo", "--targets requires --offline")
+	}
+	// The demo data has no locations beyond the embedded countries.
+	*offline = *offline || offlineDemo
+
+	table := geo.Default()
+	if *targets != "" {
+		if err := table.LoadFile(*targets); err != nil {
+			exitIOError(err)
+		}
+	}
+	var matches []geo.Match
+	if *offline {
+		for _, name := range names {
+			for _, t := range table.Find(name, *country) {
+				matches = append(matches, geo.Match{Target: t})
+			}
+		}
+	} else {
+		client := newClient()
+		for _, name := range names {
+			m, err := table.Resolve(context.Background(), client, name, *country)
+			if err != nil {
+				exitAPIError(err)
+			}
+			matches = append(matches, m...)
+		}
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: no locations match %s\n", strings.Join(names, ", "))
+	}
+
+	_, r, opts := out.renderer()
+	opts.Constants = table
+	fields := []string{"geo_target_constant.id", "geo_target_constant.name", "geo_target_constant.canonical_name",
+		"geo_target_constant.target_type", "geo_target_constant.country_code", "geo_target_constant.parent_geo_target",
+		"geo_target_constant.status"}
+	if !*offline {
+		fields = append(fields, "reach")
+	}
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	for _, m := range matches {
+		var parent any
+		if m.ParentID != 0 {
+			parent = fmt.Sprintf("%s%d", geo.GeoTargetPrefix, m.ParentID)
+		}
+		values := []any{m.ID, m.Name, m.CanonicalName, m.Type, m.CountryCode, parent, m.Status, m.Reach}
+		if err := opts.WriteRecord(r, fields, values[:len(fields)]); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
+
+// geoBatch is the most rows nameGeoTargets reads ahead.
+const geoBatch = 1000
+
+// nameGeoTargets returns next reading rows ahead, a batch at a time, to
+// fetch the geo targets they reference beyond the embedded countries with
+// one query per batch, so the output names them instead of showing IDs.
+// Queries selecting no geo target field get next itself.
+func nameGeoTargets(ctx context.Context, client *adsapi.Client, customerID string, fields []string, next func() (adsapi.Row, error)) func() (adsapi.Row, error) {
+	if !slices.ContainsFunc(fields, geo.TargetField) {
+		return next
+	}
+	var (
+		buf []adsapi.Row
+		err error
+	)
+	return func() (adsapi.Row, error) {
+		if len(buf) == 0 && err == nil {
+			for len(buf) < geoBatch {
+				row, e := next()
+				if e != nil {
+					err = e
+					break
+				}
+				buf = append(buf, row)
+			}
+			fetchGeoTargets(ctx, client, customerID, buf)
+		}
+		if len(buf) == 0 {
+			return nil, err
+		}
+		row := buf[0]
+		buf = buf[1:]
+		return row, nil
+	}
+}
+
+// geoWarned is set once a failure to name geo targets has been reported.
+var geoWarned bool
+
+// fetchGeoTargets adds the geo targets rows reference, and the default
+// table lacks, to the default table. A failure is a warning: the output
+// shows the IDs instead.
+func fetchGeoTargets(ctx context.Context, client *adsapi.Client, customerID string, rows []adsapi.Row) {
+	ids := geo.Default().Unknown(rows)
+	if len(ids) == 0 || ctx.Err() != nil {
+		return
+	}
+	if err := geo.Default().Fetch(ctx, client, customerID, ids); err != nil && !geoWarned {
+		geoWarned = true
+		fmt.Fprintf(os.Stderr, "Warning: cannot name geo targets, showing their IDs: %v\n", err)
+	}
+}
+
+// fetchAccountGeoTargets is fetchGeoTargets for the results of several
+// accounts, fetching as the first account with rows, through its login
+// customer if logins has one. Queries selecting no geo target field are
+// left alone.
+func fetchAccountGeoTargets(ctx context.Context, client *adsapi.Client, fields []string, results []adsapi.AccountResult, logins map[string]string) {
+	if !slices.ContainsFunc(fields, geo.TargetField) {
+		return
+	}
+	var rows []adsapi.Row
+	customerID := ""
+	for _, res := range results {
+		if customerID == "" && len(res.Rows) > 0 {
+			customerID = res.CustomerID
+		}
+		rows = append(rows, res.Rows...)
+	}
+	if customerID == "" {
+		return
+	}
+	if login := logins[customerID]; login != "" {
+		client = client.WithLogin(login)
+	}
+	fetchGeoTargets(ctx, client, customerID, rows)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/timing"
+)
+
+// globalFlag is a flag accepted before the command name that applies to
+// every command, such as --profile NAME.
+type globalFlag struct {
+	name string
+
+	// value describes the flag's value for the error when it is
+	// missing, such as "a duration, such as 10m"; empty for a flag that
+	// takes none.
+	value string
+
+	// set receives the value, or "" for a flag that takes none.
+	set func(value string)
+}
+
+// splitGlobalFlags applies the flags at the front of args, given as
+// --name VALUE or --name=VALUE with one dash or two, and returns the
+// rest, starting with the command name. It stops at the first argument
+// that is not one of flags, or after "--", so nothing from the command's
+// own arguments is taken, even a value that looks like a global flag.
+func splitGlobalFlags(args []string, flags []globalFlag) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args[i+1:]
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return args[i:]
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		j := slices.IndexFunc(flags, func(f globalFlag) bool { return f.name == name })
+		if j < 0 {
+			return args[i:]
+		}
+		f := flags[j]
+		switch {
+		case f.value == "" && hasValue:
+			fmt.Fprintf(os.Stderr, "Usage error: --%s takes no value\n", name)
+			os.Exit(exitcode.UsageError)
+		case f.value != "" && !hasValue:
+			if i+1 == len(args) {
+				fmt.Fprintf(os.Stderr, "Usage error: --%s needs %s\n", name, f.value)
+				os.Exit(exitcode.UsageError)
+			}
+			i++
+			value = args[i]
+		}
+		f.set(value)
+	}
+	return nil
+}
+
+// parseGlobalFlags applies the global flags at the front of args, and
+// the environment variables they default to, and returns the command
+// name and its arguments.
+func parseGlobalFlags(args []string) []string {
+	logLevel, logFormat := os.Getenv("ADTAP_LOG_LEVEL"), os.Getenv("ADTAP_LOG_FORMAT")
+	lang := ""
+	timeout := os.Getenv("ADTAP_TIMEOUT")
+	profiled := false
+	profileName = os.Getenv("ADTAP_PROFILE")
+	offlineDemo = os.Getenv("ADTAP_OFFLINE_DEMO") == "1"
+	args = splitGlobalFlags(args, []globalFlag{
+		{"log-level", "a value", func(v string) { logLevel = v }},
+		{"log-format", "a value", func(v string) { logFormat = v }},
+		{"lang", "a language, such as ja or es", func(v string) { lang = v }},
+		{"profile", "a profile name", func(v string) { profileName = v }},
+		{"profile-run", "", func(string) { profiled = true }},
+		{"offline-demo", "", func(string) { offlineDemo = true }},
+		{"record", "a fixtures directory", func(v string) { recordDir = v }},
+		{"replay", "a fixtures directory", func(v string) { replayDir = v }},
+		{"timeout", "a duration, such as 10m", func(v string) { timeout = v }},
+		{"login-customer-id", "a customer ID", func(v string) { loginFlag = customerHeaderID("login-customer-id", v) }},
+		{"linked-customer-id", "a customer ID", func(v string) { linkedFlag = customerHeaderID("linked-customer-id", v) }},
+	})
+
+	setupLogging(logLevel, logFormat)
+	setLanguage(lang)
+	if profiled {
+		runTimings = timing.NewRecorder()
+	}
+	if recordDir != "" && replayDir != "" {
+		fmt.Fprintln(os.Stderr, "Usage error: --record and --replay cannot be combined")
+		os.Exit(exitcode.UsageError)
+	}
+	setTimeout(parseTimeout(timeout))
+	return args
+}
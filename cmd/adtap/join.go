@@ -0,0 +1,153 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/export"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/geo"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+	"github.com/aygp-dr/adtap/internal/rowjoin"
+)
+
+func cmdJoin(args []string) {
+	fs := newFlagSet("join")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	left := fs.String("left", "", "GAQL query of the left rows, or a .gaql file holding one (- for stdin)")
+	right := fs.String("right", "", "GAQL query of the right rows, or a .gaql file holding one (- for stdin)")
+	on := fs.String("on", "", "Join keys, LEFT=RIGHT or a field both queries select, comma-separated (default: inferred)")
+	kind := fs.String("type", "inner", "Join type: inner, or left to keep left rows without a match")
+	toSQLite := fs.String("to-sqlite", "", "Write rows into a new table of this SQLite database file, creating the file if needed")
+	sqliteTable := fs.String("table", "", "Table name for --to-sqlite (default: the two FROM resources, as campaign_campaign_budget)")
+	out := addOutputFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap join --customer-id ID --left QUERY --right QUERY [--on KEYS] [--type inner|left]")
+		fmt.Fprintln(os.Stderr, "\nRun two queries and join their rows locally, for views that need fields")
+		fmt.Fprintln(os.Stderr, "of two resources, such as campaigns with their budgets. Without --on, a")
+		fmt.Fprintln(os.Stderr, "left field naming the right resource (campaign.campaign_budget) joins")
+		fmt.Fprintln(os.Stderr, "its resource_name; otherwise the resource names, IDs, and segments both")
+		fmt.Fprintln(os.Stderr, "queries select are the keys. Rows have the left columns, then the right")
+		fmt.Fprintln(os.Stderr, "columns the left lacks.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	switch {
+	case *left == "" || *right == "":
+		usageError("join", "--left and --right are required")
+	case *left == "-" && *right == "-":
+		usageError("join", "only one query can be read from stdin")
+	case *sqliteTable != "" && *toSQLite == "":
+		usageError("join", "--table requires --to-sqlite")
+	case *customerID == "":
+		usageError("join", "--customer-id is required")
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	k, err := rowjoin.ParseKind(*kind)
+	if err != nil {
+		usageError("join", "--type: "+strings.TrimPrefix(err.Error(), "rowjoin: "))
+	}
+	lq, ltext := joinQuery(*left, "left: ")
+	rq, rtext := joinQuery(*right, "right: ")
+	var keys []rowjoin.Key
+	if *on != "" {
+		keys, err = rowjoin.ParseKeys(*on)
+	} else {
+		keys, err = rowjoin.Keys(lq, rq)
+	}
+	if err != nil {
+		usageError("join", strings.TrimPrefix(err.Error(), "rowjoin: "))
+	}
+
+	ctx := shutdownContext()
+	client := newClient()
+	lt := rowjoin.Table{Fields: lq.FieldNames()}
+	rt := rowjoin.Table{Fields: rq.FieldNames()}
+	// Checking the keys before asking the API spares two queries.
+	if _, _, err := rowjoin.Join(lt, rt, keys, k); err != nil {
+		usageError("join", strings.TrimPrefix(err.Error(), "rowjoin: "))
+	}
+	lt.Rows = fetchJoinRows(ctx, client, id, lq, ltext)
+	rt.Rows = fetchJoinRows(ctx, client, id, rq, rtext)
+	fields, rows, _ := rowjoin.Join(lt, rt, keys, k)
+	fmt.Fprintf(os.Stderr, "Joined %d left and %d right rows on %s into %d rows\n", len(lt.Rows), len(rt.Rows), joinKeys(keys), len(rows))
+
+	_, r, opts := out.renderer()
+	opts.Constants = geo.Default()
+	var db *export.SQLiteWriter
+	if *toSQLite != "" {
+		// As for search: IDs, not constant names, and raw enum values
+		// unless labels are asked for.
+		opts.Constants = nil
+		if *out.enums == "auto" {
+			opts.RawEnums = true
+		}
+		db, err = export.NewSQLiteWriter(*toSQLite, cmp.Or(*sqliteTable, lq.From+"_"+rq.From), export.SQLiteSchema(fields, opts))
+		if err != nil {
+			exitIOError(err)
+		}
+		r = timeRenderer(db, runTimings)
+	}
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	for _, values := range rows {
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+	if db != nil {
+		fmt.Fprintf(os.Stderr, "Wrote %d rows to table %s of %s\n", db.Rows(), db.Table, db.Path)
+	}
+}
+
+// joinQuery reads and validates a --left or --right query, given inline
+// or as a file, and returns it with its text.
+func joinQuery(arg, prefix string) (*gaql.Query, string) {
+	text := arg
+	if text == "-" || strings.HasSuffix(text, ".gaql") {
+		var err error
+		if text, err = readQueryFile(arg); err != nil {
+			exitIOError(err)
+		}
+	}
+	return validateQuery(gaql.NewValidator(), text, prefix), text
+}
+
+// fetchJoinRows reads every row of q as the values of its fields.
+func fetchJoinRows(ctx context.Context, client *adsapi.Client, id string, q *gaql.Query, text string) [][]any {
+	fields := q.FieldNames()
+	var rows [][]any
+	flat := rowflat.Compile(fields)
+	next := client.SearchIter(ctx, id, q.String())
+	for {
+		row, err := next()
+		if err == adsapi.Done {
+			return rows
+		}
+		if err != nil {
+			exitQueryError(err, q, text)
+		}
+		rows = append(rows, flat.Values(row, make([]any, len(fields))))
+	}
+}
+
+func joinKeys(keys []rowjoin.Key) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.String()
+	}
+	return strings.Join(names, ", ")
+}
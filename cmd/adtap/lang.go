@@ -0,0 +1,47 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/i18n"
+)
+
+// setLanguage sets the language of help and diagnostics to lang, from
+// --lang, or else ADTAP_LANG. Without either, the locale of LC_ALL,
+// LC_MESSAGES, or LANG applies if it is a language adtap speaks, and
+// English otherwise.
+func setLanguage(lang string) {
+	lang = cmp.Or(lang, os.Getenv("ADTAP_LANG"))
+	if lang == "" {
+		if l, ok := i18n.Match(cmp.Or(os.Getenv("LC_ALL"), os.Getenv("LC_MESSAGES"), os.Getenv("LANG"))); ok {
+			i18n.SetLanguage(l)
+		}
+		return
+	}
+	if err := i18n.SetLanguage(lang); err != nil {
+		fmt.Fprintf(os.Stderr, "Usage error: unsupported language %q\n\nExpected: %s\n", lang, strings.Join(i18n.Languages, ", "))
+		os.Exit(exitcode.UsageError)
+	}
+}
+
+// eprintf formats a diagnostic and writes it to stderr, translated line
+// by line into the language of --lang.
+func eprintf(format string, args ...any) {
+	fmt.Fprint(os.Stderr, i18n.Text(fmt.Sprintf(format, args...)))
+}
+
+// printFlags writes the flags of fs and their descriptions to stderr,
+// for the usage of a command, translated like eprintf.
+func printFlags(fs *flag.FlagSet) {
+	var sb strings.Builder
+	out := fs.Output()
+	fs.SetOutput(&sb)
+	fs.PrintDefaults()
+	fs.SetOutput(out)
+	eprintf("\nFlags:\n%s", sb.String())
+}
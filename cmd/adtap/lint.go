@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/lint"
+	"github.com/aygp-dr/adtap/internal/textenc"
+)
+
+func cmdLint(args []string) {
+	fset := newFlagSet("lint")
+	format := fset.String("format", "human", "Output format: human, json, sarif")
+	disable := fset.String("disable", "", "Comma-separated rules to disable")
+	listRules := fset.Bool("list-rules", false, "List available rules and exit")
+	fset.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap lint [flags] FILE|DIR|- ...")
+		fmt.Fprintln(os.Stderr, "\nLint stored GAQL queries (.gaql files). Use - to read stdin.")
+		printFlags(fset)
+	}
+	fset.Parse(args)
+
+	if *listRules {
+		for _, r := range lint.Rules() {
+			fmt.Printf("%-30s %-8s %s\n", r.Name, r.Severity, r.Description)
+		}
+		return
+	}
+
+	if fset.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage error: at least one file or directory is required")
+		fmt.Fprintln(os.Stderr, "\nRun 'adtap lint --help' for usage.")
+		os.Exit(exitcode.UsageError)
+	}
+
+	l := lint.New()
+	if *disable != "" {
+		l.Disable(strings.Split(*disable, ",")...)
+	}
+
+	files, err := collectQueryFiles(fset.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "I/O error: %v\n", err)
+		os.Exit(exitcode.IOError)
+	}
+
+	var findings []lint.Finding
+	for _, path := range files {
+		src, err := readQueryFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "I/O error: %v\n", err)
+			os.Exit(exitcode.IOError)
+		}
+		findings = append(findings, l.LintSource(path, src)...)
+	}
+
+	switch *format {
+	case "human":
+		err = lint.WriteHuman(os.Stdout, findings)
+	case "json":
+		err = lint.WriteJSON(os.Stdout, findings)
+	case "sarif":
+		err = lint.WriteSARIF(os.Stdout, findings, l.Rules, version)
+	default:
+		exitValidationError("invalid output format %q\n\nExpected: human, json, sarif", *format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "I/O error: %v\n", err)
+		os.Exit(exitcode.IOError)
+	}
+
+	if lint.HasErrors(findings) {
+		os.Exit(exitcode.ValidationError)
+	}
+}
+
+// collectQueryFiles expands directories into the .gaql files they contain.
+func collectQueryFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		if arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".gaql" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// readQueryFile reads a query from path, or from stdin when path is "-".
+// Files saved as UTF-16 or with a byte order mark, as Windows tools do,
+// are decoded to UTF-8.
+func readQueryFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	return string(textenc.Decode(data)), err
+}
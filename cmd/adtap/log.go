@@ -0,0 +1,34 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+)
+
+// setupLogging sends the default slog logger to stderr at level, in the
+// text or json format. The level defaults to warn, above everything adtap
+// logs, so without --log-level the log is silent.
+func setupLogging(level, format string) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(cmp.Or(level, "warn"))); err != nil {
+		fmt.Fprintf(os.Stderr, "Usage error: invalid --log-level %q (use debug, info, warn, or error)\n", level)
+		os.Exit(exitcode.UsageError)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch strings.ToLower(cmp.Or(format, "text")) {
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		fmt.Fprintf(os.Stderr, "Usage error: invalid --log-format %q (use text or json)\n", format)
+		os.Exit(exitcode.UsageError)
+	}
+	slog.SetDefault(slog.New(h))
+}
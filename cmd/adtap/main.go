@@ -9,19 +9,58 @@
 // Commands:
 //
 //	search      Execute a GAQL query
+//	backfill    Fetch a long date range in resumable windows
+//	join        Join the rows of two queries on resource names or IDs
+//	snapshot    Write the structure of an account to JSONL files
 //	customers   List accessible customers
+//	auth        Sign in with a Google account (login, status, logout)
+//	doctor      Check the developer token, credentials, and API access
+//	config      Manage named profiles in config.toml
 //	campaigns   List campaigns for a customer
+//	ads         Preview ads with their text, URLs, and policy status
+//	conversions Report conversions and value by conversion action
+//	anomalies   Flag unusual days in a daily metric series
+//	budgets     Show budget pacing and alert on overspend
+//	changes     Show the account change history as field diffs
+//	spend       Show spend to date against budgets, grouped, with alerts
+//	search-terms Total search terms and suggest negative keywords
+//	top         Rank campaigns, ad groups, or keywords by a metric
+//	template    List and run query templates with typed parameters
+//	report      Run a canned report without writing GAQL
+//	view        Run saved views: queries bundled with format and destination
+//	queries     Save, list, and run named queries of a .gaql library
+//	repl        Type GAQL interactively with completion and history
+//	describe    Describe a resource or field of the API schema
+//	schema      Sync the API schema to disk for offline strict validation
+//	geo         Look up geo target constants by location name
+//	lint        Lint stored GAQL query files
+//	analyze     Report where stored queries use resources and fields
+//	translate   Translate a SQL SELECT statement into GAQL
+//	convert     Convert a query between GAQL, AST JSON, and spec forms
+//	parse       Print the syntax tree of a GAQL query
+//	diff        Compare two queries by meaning
+//	debug       Shrink a rejected query to a minimal failing one
+//	mcp         Serve GAQL tools over the Model Context Protocol
+//	serve       Serve validate, search, and customers over HTTP
+//	cache       Clear or inspect the query result cache
+//	completion  Print a bash, zsh, or fish completion script
 //	version     Print version information
 //
 // This tool can be used:
 //   - Manually from the command line
 //   - Through an LLM integration
-//   - Wrapped in an MCP server
+//   - As an MCP server (adtap mcp)
+//   - As an HTTP service (adtap serve)
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/i18n"
 )
 
 const (
@@ -30,12 +69,19 @@ const (
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	if len(os.Args) > 1 && os.Args[1] == "__complete" {
+		cmdComplete(os.Args[2:])
+		return
+	}
+	args := parseGlobalFlags(os.Args[1:])
+	if len(args) < 1 {
 		printUsage()
 		os.Exit(0)
 	}
+	os.Args = append(os.Args[:1], args...)
 
 	cmd := os.Args[1]
+	slog.Debug("command", "name", cmd, "profile", profileName, "offline_demo", offlineDemo, "timeout", commandTimeout)
 
 	switch cmd {
 	case "version", "-v", "--version":
@@ -44,15 +90,83 @@ func main() {
 		printUsage()
 	case "search":
 		cmdSearch(os.Args[2:])
+	case "backfill":
+		cmdBackfill(os.Args[2:])
+	case "join":
+		cmdJoin(os.Args[2:])
+	case "snapshot":
+		cmdSnapshot(os.Args[2:])
 	case "customers":
 		cmdCustomers(os.Args[2:])
+	case "auth":
+		cmdAuth(os.Args[2:])
+	case "doctor":
+		cmdDoctor(os.Args[2:])
+	case "config":
+		cmdConfig(os.Args[2:])
+	case "ads":
+		cmdAds(os.Args[2:])
+	case "conversions":
+		cmdConversions(os.Args[2:])
 	case "campaigns":
 		cmdCampaigns(os.Args[2:])
+	case "anomalies":
+		cmdAnomalies(os.Args[2:])
+	case "budgets":
+		cmdBudgets(os.Args[2:])
+	case "changes":
+		cmdChanges(os.Args[2:])
+	case "spend":
+		cmdSpend(os.Args[2:])
+	case "search-terms":
+		cmdSearchTerms(os.Args[2:])
+	case "top":
+		cmdTop(os.Args[2:])
+	case "template":
+		cmdTemplate(os.Args[2:])
+	case "report":
+		cmdReport(os.Args[2:])
+	case "repl":
+		cmdRepl(os.Args[2:])
+	case "describe":
+		cmdDescribe(os.Args[2:])
+	case "schema":
+		cmdSchema(os.Args[2:])
+	case "geo":
+		cmdGeo(os.Args[2:])
+	case "lint":
+		cmdLint(os.Args[2:])
+	case "analyze":
+		cmdAnalyze(os.Args[2:])
+	case "translate":
+		cmdTranslate(os.Args[2:])
+	case "view":
+		cmdView(os.Args[2:])
+	case "queries":
+		cmdQueries(os.Args[2:])
+	case "convert":
+		cmdConvert(os.Args[2:])
+	case "parse":
+		cmdParse(os.Args[2:])
+	case "diff":
+		cmdDiff(os.Args[2:])
+	case "debug":
+		cmdDebug(os.Args[2:])
+	case "mcp":
+		cmdMCP(os.Args[2:])
+	case "serve":
+		cmdServe(os.Args[2:])
+	case "cache":
+		cmdCache(os.Args[2:])
+	case "completion":
+		cmdCompletion(os.Args[2:])
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", cmd)
+		eprintf("Unknown command: %s\n", cmd)
 		printUsage()
 		os.Exit(1)
 	}
+	reportTimings()
+	reportCacheHits()
 }
 
 func printVersion() {
@@ -64,44 +178,238 @@ func printUsage() {
 	usage := `adtap - Google Ads API Exploration Tool (READ-ONLY)
 
 Usage:
-  adtap <command> [options]
+  adtap [--profile NAME] [--profile-run] [--offline-demo] [--lang LANG] [--timeout DURATION] [--log-level LEVEL] [--record DIR | --replay DIR] <command> [options]
 
 Commands:
   search       Execute a GAQL query against the API
+  backfill     Fetch a long date range as date windows, resuming after interruptions
+  join         Run two queries and join their rows on resource names or IDs
+  snapshot     Back up an account's campaigns, ad groups, ads, keywords, and more as JSONL
   customers    List accessible customer accounts
+  auth         Sign in with a Google account instead of a service account
+  doctor       Check each prerequisite of API access and say what to fix
+  config       Manage named profiles (list, get, set, unset)
   campaigns    List campaigns for a customer
+  ads          Preview ads: headlines, descriptions, URLs, and policy approval
+  conversions  Report conversions, value, and value per conversion by conversion action
+  anomalies    Flag days that deviate sharply from a metric's daily series
+  budgets      Show budget pacing; --alert-threshold exits 8 on overspend
+  changes      Show who changed what in the last 30 days, as old and new field values
+  spend        Show spend to date per campaign, budget, channel, or account against budgets
+  search-terms Show search terms by cost and suggest negative keywords for those without conversions
+  top          Rank campaigns, ad groups, or keywords by a metric
+  template     List and run query templates with typed parameters
+  report       Run a canned report: campaign-overview, search-terms, keyword-performance, ...
+  view         Run a saved view from config.toml (list, show, run)
+  queries      Keep a library of named .gaql queries (list, save, show, run, delete)
+  repl         Type GAQL interactively with tab completion and history
+  describe     Describe a resource or field: selectability, type, compatible segments
+  schema       Save the API's field metadata for fast, offline --strict validation (sync, status)
+  geo          Look up the geo target constants of a location, such as "Boston, MA"
+  lint         Lint stored GAQL query files (human, JSON, or SARIF output)
+  analyze      Report which resources and fields stored queries use, and where
+  translate    Translate a SQL SELECT statement into GAQL, listing what does not carry over
+  convert      Convert a query between GAQL text, AST JSON, and the declarative spec form
+  parse        Print the syntax tree of a GAQL query as JSON, YAML, an s-expression, or a tree
+  diff         Compare two stored queries by fields, conditions, ordering, and limit
+  debug        Shrink a query the API rejects to the smallest one failing the same way (minimize)
+  mcp          Serve GAQL tools to LLM clients over MCP (stdio)
+  serve        Serve read-only validate, search, and customers endpoints over HTTP
+  cache        Clear or inspect the result cache of search and repl
+  completion   Print a bash, zsh, or fish completion script
   version      Print version information
   help         Show this help message
 
 Examples:
-  adtap customers
+  adtap --offline-demo customers --tree
+  adtap auth login --client-secrets client_secret.json
+  adtap doctor --customer-id 1234567890
+  adtap --profile agency config set login_customer_id 1234567890
+  adtap --profile agency customers --tree
   adtap campaigns --customer-id 1234567890
+  adtap campaigns --customer-id 1234567890 --status ENABLED --channel SEARCH --metrics
+  adtap ads --customer-id 1234567890 --ad-group 30001 --policy-issues-only
+  adtap conversions --customer-id 1234567890 --by action --last 30d
+  adtap budgets --customer-id 1234567890 --alert-threshold 0.9
+  adtap changes --customer-id 1234567890 --since 2026-01-01 --resource-type CAMPAIGN
+  adtap spend --customer-id 1234567890 --group-by campaign --period MTD --alert-threshold 0.9
+  adtap search-terms --customer-id 1234567890 --min-cost 50 --no-conversions
+  adtap anomalies --customer-id 1234567890 --metric metrics.clicks --by campaign.id --during LAST_30_DAYS
+  adtap anomalies --customer-id 1234567890 --metric metrics.cost_micros --lookback 90d --method median --alert
+  adtap top campaigns --customer-id 1234567890 --by clicks --during LAST_7_DAYS
+  adtap template run campaign-performance --customer-id 1234567890 --date-range LAST_7_DAYS
+  adtap report campaign-overview --customer-id 1234567890 --last 30d
+  adtap view run weekly_spend
+  adtap queries run weekly-spend --param start=2026-01-01
   adtap search --customer-id 1234567890 --query "SELECT campaign.id, campaign.name FROM campaign LIMIT 10"
+  adtap search --customer-id 1234567890 --yes --query "SELECT campaign.id FROM campaign"
+  adtap search --explain --query "SELECT campaign.id, segments.hour, segments.device FROM campaign"
+  adtap search --customer-id 1234567890 --dry-run --file queries.gaql
+  adtap search --customer-id 1234567890 --param id=123 --query "SELECT campaign.name FROM campaign WHERE campaign.id = @id"
+  adtap search --customer-id 1234567890 --format jsonl --query "..." | jq .
+  adtap search --customer-id 1234567890 --currency USD --fx-rates ecb --stats --query "..."
+  adtap search --all-accounts --concurrency 8 --format csv --query "..."
+  adtap search --customer-id 1234567890 --watch 5m --diff-only --query "..."
+  adtap search --customer-id 1234567890 --post "group by campaign.name | sum(metrics.clicks)" --query "..."
+  adtap search --customer-id 1234567890 --file report.gaql --parallel 4 --yes
+  adtap search --customer-id 1234567890 --to-bigquery my-project.ads.campaigns --query "..."
+  adtap search --customer-id 1234567890 --to-sqlite ads.db --table campaigns --query "..."
+  adtap search --customer-id 1234567890 --format csv --output rows.csv --checkpoint rows.ckpt --resume --query "..."
+  adtap backfill --customer-id 1234567890 --query daily.gaql --from 2025-01-01 --chunk 7d --output daily.csv
+  adtap snapshot --customer-id 1234567890 --out snapshots/2026-01-01
+  adtap join --customer-id 1234567890 --left campaigns.gaql --right budgets.gaql --type left
+  adtap repl --customer-id 1234567890 --during LAST_7_DAYS --limit 100
+  adtap describe campaign metrics.clicks
+  adtap schema sync
+  adtap geo lookup "Boston, MA" --country US
+  adtap lint --format sarif queries/
+  adtap analyze usage --match metrics. --locations reports/
+  adtap translate "SELECT id, name, clicks FROM campaign WHERE status <> 'REMOVED' LIMIT 10"
+  adtap convert --to spec weekly.gaql > weekly.json
+  adtap parse --format tree --positions weekly.gaql
+  adtap diff reports/weekly.gaql reports/weekly-v2.gaql
+  adtap debug minimize --customer-id 1234567890 wide-report.gaql
+  adtap serve --addr localhost:8080 --api-key-file keys.txt --policy policy.yaml --max-rows 10000
+  adtap search --customer-id 1234567890 --no-cache --query "..."
+  adtap cache stats
+  source <(adtap completion bash)
+
+Commands that print rows accept --format table (default), json, jsonl,
+csv, tsv, markdown, or html. Human formats show enum labels; machine
+formats keep API values unless --enums labels is given. search and
+report --humanize show amounts in micros as decimals in the account
+currency (1234.57 USD) and enum numbers as names; report shows currency
+units without it.
+search --envelope wraps json and jsonl rows with the failed accounts and
+query metadata, so partial --all-accounts results are recognizable.
+search --to-bigquery streams rows into a BigQuery table instead, with
+the Google Cloud credentials gcloud uses; a missing table is created
+with a column per selected field, typed from the schema catalog.
+search --to-sqlite writes them into a new table (--table, default the
+FROM resource) of a local SQLite database, for joins across queries.
+--rename FIELD=NAME renames a column, and --output FILE writes rows to
+a file instead of stdout. A [views.NAME] table in config.toml saves a
+query or template with these settings, run as 'adtap view run NAME'.
+Ctrl-C or SIGTERM stops a search but still flushes the rows read to
+the output or sink, then exits 130 or 143; a second signal quits at
+once.
+
+Expensive queries (no LIMIT, long date ranges, high-volume resources,
+explosive segmentation) ask for confirmation first; pass --yes to skip
+the prompt in scripts. search --explain prints the estimated cost of a
+query, including its row multiplier, without running it; search
+--dry-run has the API validate it against the live schema instead,
+returning no rows, which suits checking stored queries in CI.
+Queries may hold @name placeholders for condition values and the LIMIT,
+filled with --param name=value; values are quoted and checked against
+the field's type rather than pasted into the query text.
+
+--offline-demo runs any command against bundled demo accounts instead
+of the API: a manager, 1234567890, with clients 2345678901 (USD, the
+default) and 3456789012 (EUR), and 90 days of generated metrics. Queries
+go through the same parser, validator, and formats, with no
+configuration or credentials; ADTAP_OFFLINE_DEMO=1 does the same.
+
+--profile-run prints where the time went when the command finishes: auth,
+connect, wait (throttling and retries), server, stream, format, and sink
+write, so a slow API can be told apart from a slow output file.
+
+--timeout 10m fails API requests still running ten minutes after the
+command started, so a hung request cannot stall a cron job; every
+command accepts it, before or after its name, and ADTAP_TIMEOUT sets a
+default. --call-timeout or ADTAP_CALL_TIMEOUT bounds each request
+instead, retrying one that runs over, and ADTAP_KEEPALIVE sets the TCP
+keepalive interval of API connections.
+
+--log-level debug logs what adtap does to stderr: each API request, with
+its tokens redacted, and its response, retries, pages, and cache hits;
+info logs retries only. --log-format json writes the log as JSON lines
+for log collectors. ADTAP_LOG_LEVEL and ADTAP_LOG_FORMAT set defaults.
+
+--record fixtures/ saves every API response to fixtures/ as a JSON file
+keyed by the request, without credentials; --replay fixtures/ answers
+requests from those files instead of the API, so a command's output can
+be tested without credentials or network access.
+
+--login-customer-id ID, given before the command, sends requests
+through the manager account ID, overriding GOOGLE_ADS_LOGIN_CUSTOMER_ID
+and the profile; --linked-customer-id ID reads an account through an
+app analytics provider link. Without a login customer, the managers
+setting (or ADTAP_MANAGERS), such as "1234567890, 3456789012", has
+each customer queried reached through the listed manager above it.
+
+--lang ja or --lang es shows help and diagnostics, such as validation
+errors, in Japanese or Spanish; ADTAP_LANG does the same, and without
+either a ja or es locale (LC_ALL, LC_MESSAGES, or LANG) applies. Rows
+and machine-readable output are never translated.
+
+search and repl serve a query repeated within --cache-ttl (default 10m)
+from an on-disk cache, keyed by customer ID, API version, and the query
+as the formatter prints it; --no-cache runs it again. 'adtap cache clear'
+empties the cache.
 
 Environment Variables:
   GOOGLE_ADS_DEVELOPER_TOKEN     Developer token (required)
-  GOOGLE_APPLICATION_CREDENTIALS Path to service account JSON
+  GOOGLE_APPLICATION_CREDENTIALS Path to service account or authorized user JSON
+                                 (default: the credentials saved by 'adtap auth login')
+  GOOGLE_ADS_IMPERSONATED_EMAIL  User a service account acts as (domain-wide delegation)
+  GOOGLE_ADS_LOGIN_CUSTOMER_ID   Manager account used to reach child accounts
+  GOOGLE_ADS_LINKED_CUSTOMER_ID  Linked account of an app analytics provider
+  ADTAP_MANAGERS                 Managers whose child accounts are reached through them
   GOOGLE_PROJECT_ID              GCP project ID
+  ADTAP_PROFILE                  Profile used when --profile is not given
+  ADTAP_LANG                     Language of help and messages when --lang is not given
+  ADTAP_CONFIG                   Configuration file (default ~/.config/adtap/config.toml,
+                                 %AppData%\adtap\config.toml on Windows)
+  ADTAP_SECRETS                  Secrets provider: env, file:PATH, keyring, vault:MOUNT/PATH,
+                                 or gcp:PROJECT (Google Cloud Secret Manager)
+  ADTAP_CACHE_DIR                Result cache directory (default ~/.cache/adtap/results,
+                                 %LocalAppData%\adtap\results on Windows)
+  ADTAP_CACHE_TTL                Default --cache-ttl, such as 30m (0 disables the cache)
+  ADTAP_OFFLINE_DEMO             Set to 1 to act as --offline-demo
+  ADTAP_TIMEOUT                  Default --timeout, such as 10m
+  ADTAP_CALL_TIMEOUT             Default --call-timeout, such as 2m
+  ADTAP_KEEPALIVE                TCP keepalive interval of API connections (default 30s)
+  ADTAP_QPS                      Requests per second allowed with the developer token
+  ADTAP_CUSTOMER_QPS             Requests per second allowed to any one customer
+  ADTAP_DAILY_OPERATIONS         Operations allowed per UTC day, counted across runs in the
+                                 user cache directory (--profile-run shows the throttling)
+  ADTAP_LOG_LEVEL                Default --log-level: debug, info, warn, or error
+  ADTAP_LOG_FORMAT               Default --log-format: text or json
 
 Note: This is a READ-ONLY tool. No mutate operations are supported.
 `
-	fmt.Print(usage)
+	fmt.Print(i18n.Text(usage))
+}
+
+// usageError reports a usage error for cmd and exits.
+func usageError(cmd, msg string) {
+	eprintf("Usage error: %s\n", msg)
+	eprintf("\nRun 'adtap %s --help' for usage.\n", cmd)
+	os.Exit(exitcode.UsageError)
 }
 
-func cmdSearch(args []string) {
-	// TODO: Implement GAQL search
-	fmt.Println("search: Not yet implemented")
-	fmt.Println("Placeholder for: Execute GAQL query via GoogleAdsService.Search")
+// exitValidationError reports a validation error, formatted like
+// fmt.Printf, and exits.
+func exitValidationError(format string, args ...any) {
+	eprintf("Validation error: "+format+"\n", args...)
+	os.Exit(exitcode.ValidationError)
 }
 
-func cmdCustomers(args []string) {
-	// TODO: Implement list accessible customers
-	fmt.Println("customers: Not yet implemented")
-	fmt.Println("Placeholder for: CustomerService.ListAccessibleCustomers")
+// flagSet reports whether the flag called name was given on the command
+// line, as opposed to left at its default.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	found := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
 }
 
-func cmdCampaigns(args []string) {
-	// TODO: Implement list campaigns
-	fmt.Println("campaigns: Not yet implemented")
-	fmt.Println("Placeholder for: Search campaigns via GAQL")
+// exitIOError reports a failure to write output and exits.
+func exitIOError(err error) {
+	eprintf("I/O error: %v\n", err)
+	os.Exit(exitcode.IOError)
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+)
+
+// TestMain runs the test binary as adtap when ADTAP_TEST_MAIN is set, so
+// tests can run commands in a process of their own with adtapCommand.
+func TestMain(m *testing.M) {
+	if os.Getenv("ADTAP_TEST_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// adtapCommand returns a command running adtap with args, with a home
+// and cache directory of its own.
+func adtapCommand(t *testing.T, args ...string) *exec.Cmd {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := t.TempDir()
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), "ADTAP_TEST_MAIN=1", "HOME="+home, "XDG_CONFIG_HOME="+home, "XDG_CACHE_HOME="+home, "ADTAP_CACHE_DIR=")
+	return cmd
+}
+
+func TestSearchRefusesWithoutTerminal(t *testing.T) {
+	// Cron and systemd run commands with stdin from /dev/null, which is a
+	// character device but not a terminal: there is nobody to ask.
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	cmd := adtapCommand(t, "--offline-demo", "search", "--customer-id", "2345678901", "--query", "SELECT campaign.name FROM campaign")
+	cmd.Stdin = null
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != exitcode.UsageError {
+		t.Fatalf("search exited with %v, want %d\n%s", err, exitcode.UsageError, out)
+	}
+	if strings.Contains(string(out), "[y/N]") || !strings.Contains(string(out), "pass --yes") {
+		t.Errorf("search output:\n%s", out)
+	}
+}
+
+func TestTimeoutAfterCommandName(t *testing.T) {
+	for _, args := range [][]string{
+		{"--timeout", "5m", "--offline-demo", "search"},
+		{"--offline-demo", "search", "--timeout", "5m", "--call-timeout", "1m"},
+	} {
+		cmd := adtapCommand(t, append(args, "--customer-id", "2345678901", "--yes", "--query", "SELECT campaign.name FROM campaign")...)
+		if out, err := cmd.CombinedOutput(); err != nil || !strings.Contains(string(out), "Brand - Search") {
+			t.Errorf("adtap %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	cmd := adtapCommand(t, "--offline-demo", "search", "--timeout", "-1s", "--query", "SELECT campaign.name FROM campaign")
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != exitcode.UsageError {
+		t.Fatalf("search --timeout -1s exited with %v, want %d\n%s", err, exitcode.UsageError, out)
+	}
+}
+
+func TestReportCurrencyUnits(t *testing.T) {
+	for _, tt := range []struct {
+		flags       []string
+		want, avoid string
+	}{
+		{nil, "metrics.cost ", "metrics.cost_micros"},
+		{[]string{"--humanize"}, " USD", "metrics.cost_micros"},
+		{[]string{"--micros-to-currency=false"}, "metrics.cost_micros", " USD"},
+	} {
+		args := append([]string{"--offline-demo", "report", "campaign-overview", "--customer-id", "2345678901"}, tt.flags...)
+		out, err := adtapCommand(t, args...).CombinedOutput()
+		if err != nil || !strings.Contains(string(out), tt.want) || strings.Contains(string(out), tt.avoid) {
+			t.Errorf("adtap %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/geo"
+	"github.com/aygp-dr/adtap/internal/mcp"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+)
+
+// mcpMaxRows caps the rows gaql_search returns, keeping responses within
+// what a model can read.
+const mcpMaxRows = 1000
+
+// mcpMaxBytes caps the JSON the API sends for those rows, shared among
+// the customers queried, as rows of long text such as ad copy fill a
+// context window long before mcpMaxRows.
+const mcpMaxBytes = 256 << 10
+
+// mcpMaxDays caps the date range of the queries tools run, as the
+// search command asks before running longer ones.
+const mcpMaxDays = 90
+
+func cmdMCP(args []string) {
+	fs := newFlagSet("mcp")
+	debugAddr := addDebugFlag(fs)
+	pol := addPolicyFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap mcp [--debug-addr ADDR] [--policy FILE [--role NAME]]")
+		fmt.Fprintln(os.Stderr, "\nServe the Model Context Protocol over stdin/stdout, so LLM clients")
+		fmt.Fprintln(os.Stderr, "can validate and run GAQL queries. Every tool is read-only: the client")
+		fmt.Fprintln(os.Stderr, "has no mutate operations and only SELECT queries parse.")
+		fmt.Fprintln(os.Stderr, "\nTools: gaql_validate, gaql_search, list_customers, describe_resource, and")
+		fmt.Fprintln(os.Stderr, "one template_* tool per query template (see 'adtap template list')")
+		fmt.Fprintln(os.Stderr, "\nCredentials are read from the environment on the first API call.")
+		fmt.Fprintln(os.Stderr, "With --policy, every query is checked against the access policy: denied")
+		fmt.Fprintln(os.Stderr, "resources and fields are refused, redacted fields are returned as")
+		fmt.Fprintln(os.Stderr, "\"[redacted]\", and LIMIT and date ranges are capped.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError("mcp", "unexpected arguments")
+	}
+
+	s := mcp.NewServer("adtap", version)
+	t := &mcpTools{access: pol.access("mcp")}
+	for _, tool := range t.tools() {
+		s.AddTool(tool)
+	}
+	expvar.Publish("mcp", expvar.Func(func() any { return s.Stats() }))
+	startDebugServer(*debugAddr)
+	// A signal cancels the tool call in progress. Serve cannot notice it
+	// while waiting on stdin, so the server is left behind on exit.
+	ctx := shutdownContext()
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, os.Stdin, os.Stdout) }()
+	select {
+	case err := <-done:
+		if err != nil && interrupted(ctx) == nil {
+			fmt.Fprintf(os.Stderr, "I/O error: %v\n", err)
+			os.Exit(exitcode.IOError)
+		}
+	case <-ctx.Done():
+	}
+	if sig := interrupted(ctx); sig != nil {
+		exitInterrupted(sig, fmt.Sprintf("answered %d requests", s.Stats().Requests))
+	}
+}
+
+// mcpTools holds the API client, created on first use so gaql_validate
+// and describe_resource work without credentials, and the access policy
+// queries are held to. A failure to create the client is not kept, so a
+// later call tries again.
+type mcpTools struct {
+	mu     sync.Mutex
+	client *adsapi.Client
+	access *gaql.AccessPolicy
+}
+
+func (t *mcpTools) apiClient() (*adsapi.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+	client, err := clientFromEnv()
+	var se *setupError
+	if errors.As(err, &se) {
+		return nil, fmt.Errorf("%s: %s (hint: %s)", se.category, se.msg, strings.TrimSuffix(se.hint, "."))
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.client = client
+	return client, nil
+}
+
+func (t *mcpTools) tools() []mcp.Tool {
+	tools := []mcp.Tool{
+		{
+			Name:        "gaql_validate",
+			Description: "Parse and validate a Google Ads Query Language (GAQL) query without calling the API. Returns whether it is valid, the normalized query, and warnings.",
+			InputSchema: mcp.Object(map[string]any{
+				"query":       mcp.String("GAQL query, e.g. SELECT campaign.id FROM campaign"),
+				"api_version": mcp.String("Google Ads API version to check against (default " + gaql.DefaultAPIVersion + ")"),
+			}, "query"),
+			ReadOnly: true,
+			Handler:  t.validate,
+		},
+		{
+			Name:        "gaql_search",
+			Description: fmt.Sprintf("Run a read-only GAQL query against a Google Ads account and return an object whose rows are JSON objects keyed by field name. At most %d rows and %d KiB are returned, and metadata.truncated is true when rows were left out; the query's LIMIT is lowered to max_rows and its segments.date range narrowed to its most recent %d days.", mcpMaxRows, mcpMaxBytes>>10, mcpMaxDays),
+			InputSchema: mcp.Object(map[string]any{
+				"customer_id": mcp.String("Customer ID, 10 digits without hyphens"),
+				"query":       mcp.String("GAQL SELECT query"),
+				"max_rows":    mcp.Integer(fmt.Sprintf("Maximum rows to return (default and maximum %d)", mcpMaxRows)),
+			}, "customer_id", "query"),
+			ReadOnly: true,
+			Handler:  t.search,
+		},
+		{
+			Name:        "list_customers",
+			Description: "List the IDs of the Google Ads customers the credentials can access directly.",
+			InputSchema: mcp.Object(map[string]any{}),
+			ReadOnly:    true,
+			Handler:     t.listCustomers,
+		},
+		{
+			Name:        "describe_resource",
+			Description: "Describe the fields of a GAQL resource (e.g. campaign, ad_group): data type, whether it can be filtered and sorted, and enum values.",
+			InputSchema: mcp.Object(map[string]any{
+				"resource": mcp.String("Resource name, e.g. campaign"),
+			}, "resource"),
+			ReadOnly: true,
+			Handler:  t.describe,
+		},
+	}
+	for _, tmpl := range compose.Templates {
+		tools = append(tools, t.templateTool(tmpl))
+	}
+	return tools
+}
+
+// templateTool exposes a query template as a tool whose arguments are
+// the template's declared parameters.
+func (t *mcpTools) templateTool(tmpl *compose.Template) mcp.Tool {
+	return mcp.Tool{
+		Name:        "template_" + strings.ReplaceAll(tmpl.Name, "-", "_"),
+		Description: fmt.Sprintf("%s. Runs the %s query template and returns at most %d rows as JSON objects, with metadata.truncated set when rows were left out.", tmpl.Description, tmpl.Name, mcpMaxRows),
+		InputSchema: tmpl.Schema(),
+		ReadOnly:    true,
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			targs, err := tmpl.ParseJSON(args)
+			if err != nil {
+				return nil, err
+			}
+			q, err := tmpl.Query(targs)
+			if err != nil {
+				return nil, err
+			}
+			v := gaql.NewValidator()
+			v.Access = t.access
+			if q, _, err = v.Prepare(q); err != nil {
+				return nil, err
+			}
+			return t.run(ctx, tmpl.Customers(targs), q, mcpMaxRows)
+		},
+	}
+}
+
+func (t *mcpTools) validate(_ context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		Query      string `json:"query"`
+		APIVersion string `json:"api_version"`
+	}
+	if err := mcp.Decode(args, &in); err != nil {
+		return nil, err
+	}
+	return gaql.NewCheckResult(gaql.Check(in.Query, in.APIVersion, t.access)), nil
+}
+
+func (t *mcpTools) search(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		CustomerID string `json:"customer_id"`
+		Query      string `json:"query"`
+		MaxRows    int    `json:"max_rows"`
+	}
+	if err := mcp.Decode(args, &in); err != nil {
+		return nil, err
+	}
+	id, err := adsapi.NormalizeCustomerID(in.CustomerID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid customer_id %q: expected 10 digits, e.g. 1234567890", in.CustomerID)
+	}
+	maxRows := in.MaxRows
+	if maxRows <= 0 || maxRows > mcpMaxRows {
+		maxRows = mcpMaxRows
+	}
+	q, _, err := gaql.Check(in.Query, "", t.access)
+	if err != nil {
+		return nil, err
+	}
+	return t.run(ctx, []string{id}, q, maxRows)
+}
+
+// run executes q against the customers ids and returns at most maxRows
+// rows, and mcpMaxBytes of them, as a JSON envelope whose metadata tells
+// whether rows were left out. Rows from several customers are tagged
+// with customer.id; any failing customer fails the call. q is first
+// bounded to maxRows rows and mcpMaxDays days, so the API is not asked
+// for rows that would be dropped.
+func (t *mcpTools) run(ctx context.Context, ids []string, q *gaql.Query, maxRows int) (any, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("no customer to query: pass at least one customer ID")
+	}
+	client, err := t.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	client = client.WithBudget(0, mcpMaxBytes/int64(len(ids)))
+	gaql.ApplyGuardrails(q, gaql.GuardrailPolicy{MaxLimit: maxRows, MaxDays: mcpMaxDays})
+
+	var buf bytes.Buffer
+	r, err := output.NewEnvelopeRenderer(&buf, output.FormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	r.Metadata = output.Metadata{Query: q.String(), Accounts: len(ids)}
+	opts := output.Options{RawEnums: true, Constants: geo.Default()}
+	if t.access != nil {
+		opts.Redact = t.access.Redacts
+	}
+	fields := q.FieldNames()
+	if len(ids) > 1 && !slices.Contains(fields, "customer.id") {
+		fields = append([]string{"customer.id"}, fields...)
+	}
+	if err := r.WriteHeader(fields); err != nil {
+		return nil, err
+	}
+	flat := rowflat.Compile(fields)
+	values := make([]any, len(fields))
+	n := 0
+	write := func(row adsapi.Row) error {
+		n++
+		flat.Values(row, values)
+		return opts.WriteRecord(r, fields, values)
+	}
+
+	if len(ids) == 1 {
+		next := nameGeoTargets(ctx, client, ids[0], fields, client.SearchIter(ctx, ids[0], q.String()))
+		for n < maxRows {
+			row, err := next()
+			if err == adsapi.Done {
+				break
+			}
+			if err == adsapi.ErrTruncated {
+				r.Metadata.Truncated = true
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := write(row); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		results, err := client.SearchAccounts(ctx, ids, q.String(), adsapi.SearchAccountsOptions{})
+		if err != nil {
+			return nil, err
+		}
+		fetchAccountGeoTargets(ctx, client, fields, results, nil)
+	rows:
+		for _, res := range results {
+			if res.Truncated {
+				r.Metadata.Truncated = true
+			}
+			for _, row := range res.Rows {
+				if n == maxRows {
+					r.Metadata.Truncated = true
+					break rows
+				}
+				if err := write(row); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := r.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+func (t *mcpTools) listCustomers(ctx context.Context, args json.RawMessage) (any, error) {
+	if err := mcp.Decode(args, &struct{}{}); err != nil {
+		return nil, err
+	}
+	client, err := t.apiClient()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := client.ListAccessibleCustomers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"customer_ids": ids}, nil
+}
+
+func (t *mcpTools) describe(_ context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		Resource string `json:"resource"`
+	}
+	if err := mcp.Decode(args, &in); err != nil {
+		return nil, err
+	}
+	fields := gaql.DefaultCatalog().ResourceFields(in.Resource)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("unknown resource %q", in.Resource)
+	}
+	return map[string]any{"resource": in.Resource, "fields": fields}, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func cmdDebug(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		debugUsage()
+		os.Exit(0)
+	}
+	switch args[0] {
+	case "minimize":
+		cmdDebugMinimize(args[1:])
+	default:
+		usageError("debug", fmt.Sprintf("unknown subcommand %q (expected minimize)", args[0]))
+	}
+}
+
+func debugUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap debug minimize [--customer-id ID | --local] [--query GAQL | FILE|-]")
+	fmt.Fprintln(os.Stderr, "\nminimize shrinks a query the API rejects to the smallest query that")
+	fmt.Fprintln(os.Stderr, "still fails the same way, for bug reports and for finding the field or")
+	fmt.Fprintln(os.Stderr, "condition at fault. Run 'adtap debug minimize --help' for its flags.")
+}
+
+func cmdDebugMinimize(args []string) {
+	fs := newFlagSet("debug minimize")
+	customerID := fs.String("customer-id", "", "Customer ID to validate the query against (10 digits, no hyphens)")
+	query := fs.String("query", "", "GAQL query to minimize instead of FILE")
+	local := fs.Bool("local", false, "Minimize against adtap's own validator instead of the API")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap debug minimize [--customer-id ID | --local] [--query GAQL | FILE|-]")
+		fmt.Fprintln(os.Stderr, "\nShrink a failing query to a minimal one that fails the same way. Each")
+		fmt.Fprintln(os.Stderr, "try removes SELECT fields, WHERE conditions, ORDER BY items, the LIMIT,")
+		fmt.Fprintln(os.Stderr, "or PARAMETERS and sends the rest to the API with validate_only, which")
+		fmt.Fprintln(os.Stderr, "reads no rows; a try fails the same way when the API returns the same")
+		fmt.Fprintln(os.Stderr, "error codes. A 40-field query takes tens of tries rather than an hour")
+		fmt.Fprintln(os.Stderr, "of bisecting by hand. --local uses adtap's validator instead, with no")
+		fmt.Fprintln(os.Stderr, "account or network. The minimal query is printed to stdout and a")
+		fmt.Fprintln(os.Stderr, "summary to stderr. The query is read from --query, FILE, or stdin when")
+		fmt.Fprintln(os.Stderr, "FILE is - or absent.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if fs.NArg() > 1 || *query != "" && fs.NArg() > 0 {
+		usageError("debug minimize", "give one query, with --query or as a file")
+	}
+	src := *query
+	if src == "" {
+		path := "-"
+		if fs.NArg() == 1 {
+			path = fs.Arg(0)
+		}
+		var err error
+		if src, err = readQueryFile(path); err != nil {
+			exitIOError(err)
+		}
+	}
+	q, err := gaql.Parse(src)
+	if err != nil {
+		exitValidationError("%v", err)
+	}
+
+	ctx := shutdownContext()
+	var failure func(*gaql.Query) (string, error)
+	if *local {
+		v := gaql.NewValidator()
+		failure = func(q *gaql.Query) (string, error) {
+			if err := v.Validate(q); err != nil {
+				return err.Error(), nil
+			}
+			return "", nil
+		}
+	} else {
+		if *customerID == "" {
+			usageError("debug minimize", "--customer-id or --local is required")
+		}
+		id, err := adsapi.NormalizeCustomerID(*customerID)
+		if err != nil {
+			exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+		}
+		client := newClient()
+		failure = func(q *gaql.Query) (string, error) {
+			_, err := client.Validate(ctx, id, q.String())
+			return apiFailure(err)
+		}
+	}
+
+	want, err := failure(q)
+	if err != nil {
+		exitQueryError(err, q, src)
+	}
+	if want == "" {
+		fmt.Fprintln(os.Stderr, "The query does not fail; there is nothing to minimize.")
+		os.Exit(exitcode.GeneralError)
+	}
+
+	start := time.Now()
+	tries := 0
+	small := gaql.Minimize(q, func(v *gaql.Query) bool {
+		if interrupted(ctx) != nil {
+			return false
+		}
+		tries++
+		got, err := failure(v)
+		return err == nil && got == want
+	})
+	fmt.Println(small)
+	if sig := interrupted(ctx); sig != nil {
+		exitInterrupted(sig, fmt.Sprintf("printed the smallest failing query of %d tries", tries))
+	}
+	fmt.Fprintf(os.Stderr, "Reduced %s to %s in %d tries (%v)\n", queryShape(q), queryShape(small), tries, time.Since(start).Round(time.Millisecond))
+	fmt.Fprintf(os.Stderr, "Failure: %s\n", want)
+}
+
+// apiFailure returns the signature of a validate_only call's rejection:
+// the sorted error codes of an API error, which stay the same as the
+// query shrinks where messages quoting it do not, or its status and
+// message when it has none. A nil err, an accepted query, returns "".
+// Other errors, such as a lost connection, say nothing of the query and
+// are returned.
+func apiFailure(err error) (string, error) {
+	var apiErr *adsapi.APIError
+	if err == nil {
+		return "", nil
+	}
+	if !errors.As(err, &apiErr) {
+		return "", err
+	}
+	var codes []string
+	for _, e := range apiErr.Errors {
+		codes = append(codes, e.Category+"."+e.Code)
+	}
+	if len(codes) == 0 {
+		return apiErr.Status + ": " + apiErr.Message, nil
+	}
+	slices.Sort(codes)
+	return strings.Join(slices.Compact(codes), ", "), nil
+}
+
+// queryShape describes the size of q, such as "40 field(s), 2
+// condition(s)".
+func queryShape(q *gaql.Query) string {
+	s := fmt.Sprintf("%d field(s)", len(q.Select))
+	if len(q.Where) > 0 {
+		s += fmt.Sprintf(", %d condition(s)", len(q.Where))
+	}
+	if len(q.OrderBy) > 0 {
+		s += fmt.Sprintf(", %d ordering(s)", len(q.OrderBy))
+	}
+	return s
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// parseFormats are the forms parse prints the syntax tree in.
+var parseFormats = []string{"json", "yaml", "sexp", "tree"}
+
+func cmdParse(args []string) {
+	fs := newFlagSet("parse")
+	format := fs.String("format", "json", "Output format: "+strings.Join(parseFormats, ", "))
+	positions := fs.Bool("positions", false, "Include the source span of each clause, field, condition, and ordering")
+	query := fs.String("query", "", "GAQL query to parse instead of FILE")
+	validate := fs.Bool("validate", false, "Validate the query too, adding the catalog data type of each condition field")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap parse [--format json|yaml|sexp|tree] [--positions] [--validate] [--query GAQL | FILE|-]")
+		fmt.Fprintln(os.Stderr, "\nParse a GAQL query and print its syntax tree, for tools built on the")
+		fmt.Fprintln(os.Stderr, "parser in other languages. json and yaml print the AST JSON form that")
+		fmt.Fprintln(os.Stderr, "'adtap convert --schema' describes; sexp and tree print a node per")
+		fmt.Fprintln(os.Stderr, "clause, field, condition, operator, and value. Spans are written as")
+		fmt.Fprintln(os.Stderr, "1-based line and byte column, plus the byte offset in json and yaml.")
+		fmt.Fprintln(os.Stderr, "The query is read from --query, FILE, or stdin when FILE is - or absent.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 1 || *query != "" && fs.NArg() > 0 {
+		usageError("parse", "give one query, with --query or as a file")
+	}
+	if !slices.Contains(parseFormats, *format) {
+		exitValidationError("invalid output format\n\nExpected: %s\nGot: %s", strings.Join(parseFormats, ", "), *format)
+	}
+	src := *query
+	if src == "" {
+		path := "-"
+		if fs.NArg() == 1 {
+			path = fs.Arg(0)
+		}
+		var err error
+		if src, err = readQueryFile(path); err != nil {
+			exitIOError(err)
+		}
+	}
+
+	var q *gaql.Query
+	if *validate {
+		q = validateQuery(gaql.NewValidator(), src, "")
+	} else {
+		var err error
+		if q, err = gaql.Parse(src); err != nil {
+			exitValidationError("%v", err)
+		}
+	}
+
+	var err error
+	switch *format {
+	case "tree":
+		err = q.Tree().WriteTree(os.Stdout, *positions)
+	case "sexp":
+		_, err = fmt.Println(q.Tree().SExpr(*positions))
+	default:
+		var data []byte
+		if *positions {
+			data, err = q.MarshalJSONPositions()
+		} else {
+			data, err = json.Marshal(q)
+		}
+		if err != nil {
+			exitValidationError("%v", err)
+		}
+		if *format == "yaml" {
+			err = writeYAML(os.Stdout, data)
+		} else {
+			var buf bytes.Buffer
+			json.Indent(&buf, data, "", "  ")
+			buf.WriteByte('\n')
+			_, err = buf.WriteTo(os.Stdout)
+		}
+	}
+	if err != nil {
+		exitIOError(err)
+	}
+}
+
+// yamlField is a key of a JSON object, in document order.
+type yamlField struct {
+	key   string
+	value any
+}
+
+// writeYAML writes the JSON document data as YAML, keeping the order of
+// object keys. Strings are double-quoted, which YAML reads as JSON does.
+func writeYAML(w io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	if fields, ok := v.([]yamlField); ok && len(fields) > 0 {
+		yamlMapping(&sb, fields, "")
+	} else {
+		sb.WriteString(yamlScalar(v) + "\n")
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// decodeOrdered reads the next JSON value from dec, objects as
+// []yamlField and arrays as []any.
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		fields := []yamlField{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, yamlField{key.(string), v})
+		}
+		_, err = dec.Token()
+		return fields, err
+	case json.Delim('['):
+		items := []any{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		_, err = dec.Token()
+		return items, err
+	}
+	return tok, nil
+}
+
+// plainKey matches keys YAML reads unquoted as the same string.
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+func yamlMapping(sb *strings.Builder, fields []yamlField, indent string) {
+	for _, f := range fields {
+		key := f.key
+		if !plainKey.MatchString(key) || key == "true" || key == "false" || key == "null" {
+			key = strconv.Quote(key)
+		}
+		sb.WriteString(indent + key + ":")
+		yamlValue(sb, f.value, indent+"  ")
+	}
+}
+
+// yamlValue writes v after a key or list dash: scalars and empty
+// collections on the same line, others on the lines below at indent.
+func yamlValue(sb *strings.Builder, v any, indent string) {
+	switch v := v.(type) {
+	case []yamlField:
+		if len(v) == 0 {
+			sb.WriteString(" {}\n")
+			return
+		}
+		sb.WriteString("\n")
+		yamlMapping(sb, v, indent)
+	case []any:
+		if len(v) == 0 {
+			sb.WriteString(" []\n")
+			return
+		}
+		sb.WriteString("\n")
+		for _, item := range v {
+			// The first line of the item follows the dash; the rest
+			// line up with it.
+			var lines strings.Builder
+			switch item := item.(type) {
+			case []yamlField:
+				if len(item) == 0 {
+					lines.WriteString(indent + "  {}\n")
+				} else {
+					yamlMapping(&lines, item, indent+"  ")
+				}
+			case []any:
+				lines.WriteString(indent + "  -")
+				yamlValue(&lines, item, indent+"  ")
+			default:
+				lines.WriteString(indent + "  " + yamlScalar(item) + "\n")
+			}
+			sb.WriteString(indent + "- " + strings.TrimPrefix(lines.String(), indent+"  "))
+		}
+	default:
+		sb.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return "null"
+	}
+}
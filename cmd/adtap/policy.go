@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/policy"
+)
+
+type policyFlags struct {
+	path *string
+	role *string
+}
+
+// addPolicyFlags adds --policy and --role, which default to ADTAP_POLICY
+// and ADTAP_ROLE so a shared deployment can set them once.
+func addPolicyFlags(fs *flag.FlagSet) *policyFlags {
+	return &policyFlags{
+		path: fs.String("policy", os.Getenv("ADTAP_POLICY"), "Access policy file (YAML) restricting the resources, fields, rows, and days queries may read"),
+		role: fs.String("role", os.Getenv("ADTAP_ROLE"), "Role of the access policy to apply (default: its top-level rules)"),
+	}
+}
+
+// access returns the access policy the flags select, or nil when no
+// policy file is given.
+func (f *policyFlags) access(cmd string) *gaql.AccessPolicy {
+	if *f.path == "" {
+		if *f.role != "" {
+			usageError(cmd, "--role requires --policy")
+		}
+		return nil
+	}
+	p, err := policy.Load(*f.path)
+	if err != nil {
+		exitSetupError(configError(strings.TrimPrefix(err.Error(), "policy: "), "fix the policy file; see 'Access Policies' in the README."))
+	}
+	a, err := p.For(*f.role)
+	if err != nil {
+		exitSetupError(configError(strings.TrimPrefix(err.Error(), "policy: "), "pass a role the policy defines with --role or ADTAP_ROLE."))
+	}
+	return a
+}
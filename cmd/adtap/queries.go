@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/querylib"
+)
+
+func cmdQueries(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		queriesUsage()
+		os.Exit(0)
+	}
+	lib := querylib.New(querylib.DefaultDir())
+
+	switch sub, rest := args[0], args[1:]; sub {
+	case "list":
+		if len(rest) > 0 {
+			usageError("queries", "list takes no arguments")
+		}
+		listQueries(lib)
+	case "save":
+		saveQuery(lib, rest)
+	case "show", "run", "delete":
+		if len(rest) == 0 || strings.HasPrefix(rest[0], "-") {
+			usageError("queries", sub+" needs a query name; see 'adtap queries list'")
+		}
+		if sub != "run" && len(rest) > 1 {
+			usageError("queries", fmt.Sprintf("unexpected argument %q", rest[1]))
+		}
+		if sub == "delete" {
+			if err := lib.Delete(rest[0]); errors.Is(err, querylib.ErrNotFound) {
+				usageError("queries", fmt.Sprintf("unknown query %q; see 'adtap queries list'", rest[0]))
+			} else if err != nil {
+				exitIOError(err)
+			}
+			fmt.Fprintf(os.Stderr, "Deleted %s\n", rest[0])
+			return
+		}
+		q := loadQuery(lib, rest[0])
+		if sub == "show" {
+			os.Stdout.Write(q.Format())
+			return
+		}
+		// Flags after the name override the query's defaults.
+		cmdSearch(append(queryArgs(q), rest[1:]...))
+	default:
+		usageError("queries", fmt.Sprintf("unknown subcommand %q (expected list, save, show, run, or delete)", sub))
+	}
+}
+
+func queriesUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap queries list")
+	fmt.Fprintln(os.Stderr, "       adtap queries save NAME [--description TEXT] [--customer-id ID] [--param NAME=DEFAULT] [--force] [--query GAQL | FILE|-]")
+	fmt.Fprintln(os.Stderr, "       adtap queries show NAME")
+	fmt.Fprintln(os.Stderr, "       adtap queries run NAME [search flags]")
+	fmt.Fprintln(os.Stderr, "       adtap queries delete NAME")
+	fmt.Fprintln(os.Stderr, "\nKeep a library of named queries, with a description, a default")
+	fmt.Fprintln(os.Stderr, "customer, and defaults for @placeholders, as one .gaql file per query")
+	fmt.Fprintln(os.Stderr, "in "+querylib.DefaultDir()+".")
+	fmt.Fprintln(os.Stderr, "Set ADTAP_QUERIES to use another directory, such as a shared checkout.")
+	fmt.Fprintln(os.Stderr, "Flags given to run after the name override the query's defaults:")
+	fmt.Fprintln(os.Stderr, "  adtap queries run weekly-spend --param start=2026-01-01")
+}
+
+func listQueries(lib *querylib.Library) {
+	names, err := lib.Names()
+	if err != nil {
+		exitIOError(err)
+	}
+	if len(names) == 0 {
+		fmt.Printf("# no saved queries; add one with 'adtap queries save' or put .gaql files in %s\n", lib.Dir)
+		return
+	}
+	for _, name := range names {
+		fmt.Println(name)
+		q, err := lib.Load(name)
+		if err != nil {
+			fmt.Printf("  (%s)\n", strings.TrimPrefix(err.Error(), "querylib: "))
+			continue
+		}
+		if q.Description != "" {
+			fmt.Printf("  %s\n", q.Description)
+		}
+		var params []string
+		for _, p := range q.ParamNames() {
+			if v, ok := q.Params[p]; ok {
+				p += "=" + v
+			}
+			params = append(params, "@"+p)
+		}
+		if len(params) > 0 {
+			fmt.Printf("  params: %s\n", strings.Join(params, ", "))
+		}
+	}
+}
+
+func saveQuery(lib *querylib.Library, args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		usageError("queries", "save needs a query name")
+	}
+	name := args[0]
+	fs := newFlagSet("queries save")
+	description := fs.String("description", "", "One-line description of the query")
+	customerID := fs.String("customer-id", "", "Customer the query runs against unless run with --customer-id")
+	params := queryParams{}
+	fs.Var(params, "param", "Default for a placeholder: --param start=2026-01-01 fills @start unless run with --param (repeatable)")
+	query := fs.String("query", "", "GAQL query to save instead of FILE")
+	force := fs.Bool("force", false, "Replace a saved query of the same name")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap queries save NAME [flags] [--query GAQL | FILE|-]")
+		fmt.Fprintln(os.Stderr, "\nSave a query to the library as NAME.gaql. The query is read from --query,")
+		fmt.Fprintln(os.Stderr, "FILE, or stdin when FILE is - or absent; a header of '-- key: value'")
+		fmt.Fprintln(os.Stderr, "comments in it is kept, and the flags override its values.")
+		printFlags(fs)
+	}
+	fs.Parse(args[1:])
+
+	if !querylib.ValidName(name) {
+		usageError("queries", fmt.Sprintf("invalid query name %q (use letters, digits, '-', and '_')", name))
+	}
+	if fs.NArg() > 1 || *query != "" && fs.NArg() > 0 {
+		usageError("queries", "give one query, with --query or as a file")
+	}
+	src := *query
+	if src == "" {
+		path := "-"
+		if fs.NArg() == 1 {
+			path = fs.Arg(0)
+		}
+		var err error
+		if src, err = readQueryFile(path); err != nil {
+			exitIOError(err)
+		}
+	}
+	q, err := querylib.Parse(name, src)
+	if err != nil {
+		exitValidationError("%v", strings.TrimPrefix(err.Error(), "querylib: "))
+	}
+	if *description != "" {
+		q.Description = *description
+	}
+	if *customerID != "" {
+		q.CustomerID = *customerID
+	}
+	if q.CustomerID != "" {
+		id, err := adsapi.NormalizeCustomerID(q.CustomerID)
+		if err != nil {
+			exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", q.CustomerID)
+		}
+		q.CustomerID = id
+	}
+	for p, v := range params {
+		q.Params[p] = v
+	}
+	if err := q.Check(); err != nil {
+		exitValidationError("%v", strings.TrimPrefix(err.Error(), "querylib: "))
+	}
+	if err := lib.Save(q, *force); errors.Is(err, os.ErrExist) {
+		usageError("queries", fmt.Sprintf("%s is saved already; use --force to replace it", name))
+	} else if err != nil {
+		exitIOError(err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved %s in %s\n", name, lib.Dir)
+}
+
+// loadQuery reads the library query name, exiting when there is none.
+func loadQuery(lib *querylib.Library, name string) *querylib.Query {
+	q, err := lib.Load(name)
+	if errors.Is(err, querylib.ErrNotFound) {
+		usageError("queries", fmt.Sprintf("unknown query %q; see 'adtap queries list'", name))
+	}
+	if err != nil {
+		exitValidationError("%v", strings.TrimPrefix(err.Error(), "querylib: "))
+	}
+	return q
+}
+
+// queryArgs returns the adtap search arguments that run q with its
+// defaults.
+func queryArgs(q *querylib.Query) []string {
+	args := []string{"--query", q.Text}
+	if q.CustomerID != "" {
+		args = append(args, "--customer-id", q.CustomerID)
+	}
+	for _, p := range q.ParamNames() {
+		if v, ok := q.Params[p]; ok {
+			args = append(args, "--param", p+"="+v)
+		}
+	}
+	return args
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/geo"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/repl"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+)
+
+func cmdRepl(args []string) {
+	fs := newFlagSet("repl")
+	customerID := fs.String("customer-id", "", "Customer to query (change it with \\use)")
+	during := fs.String("during", "", "Date range added to metric queries without one (change it with \\during)")
+	limit := fs.Int("limit", 0, "LIMIT added to queries without one (change it with \\limit)")
+	noHistory := fs.Bool("no-history", false, "Do not read or save the history file")
+	out := addOutputFlags(fs)
+	caching := addCacheFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap repl [--customer-id ID] [flags]")
+		fmt.Fprintln(os.Stderr, "\nType GAQL queries interactively. A query runs when a line ends with ';'")
+		fmt.Fprintln(os.Stderr, "or is followed by an empty line. Tab completes resource and field names")
+		fmt.Fprintln(os.Stderr, "from the schema catalog; Up and Down recall earlier queries, which are")
+		fmt.Fprintln(os.Stderr, "saved in "+repl.DefaultHistoryPath()+" (ADTAP_HISTORY).")
+		fmt.Fprintln(os.Stderr, "\nMeta-commands:")
+		for _, usage := range repl.Commands() {
+			fmt.Fprintf(os.Stderr, "  %s\n", usage)
+		}
+		fmt.Fprintln(os.Stderr, "  \\help, \\quit")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	caching.enable()
+	if fs.NArg() > 0 {
+		usageError("repl", fmt.Sprintf("unexpected argument %q", fs.Arg(0)))
+	}
+	defaultCustomer(customerID)
+	if *limit < 0 {
+		usageError("repl", "--limit must not be negative")
+	}
+
+	if *out.output != "" {
+		usageError("repl", "--output is not supported; results are printed as queries run")
+	}
+	format, _, opts := out.renderer()
+	opts.Constants = geo.Default()
+	s := &repl.Session{Format: format, Limit: *limit}
+	for cmd, arg := range map[string]string{`\use`: *customerID, `\during`: *during} {
+		if arg == "" {
+			continue
+		}
+		if _, err := s.Command(cmd + " " + arg); err != nil {
+			usageError("repl", strings.TrimPrefix(err.Error(), "repl: "))
+		}
+	}
+
+	history := &repl.History{}
+	if !*noHistory {
+		var err error
+		if history, err = repl.LoadHistory(repl.DefaultHistoryPath()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			history = &repl.History{}
+		}
+	}
+
+	// The pending lines of a multi-line statement give completion its
+	// context, such as the FROM resource typed on an earlier line.
+	var pending []string
+	readLine := plainReader(os.Stdin)
+	if repl.IsTerminal(os.Stdin) {
+		// Without stty the session falls back to plain line input.
+		if restore, err := repl.RawMode(os.Stdin); err == nil {
+			restore()
+			ed := repl.NewEditor(os.Stdin, os.Stdout)
+			ed.History = history
+			catalog := gaql.DefaultCatalog()
+			ed.Complete = func(text string) (int, []string) {
+				prefix := strings.Join(pending, "\n")
+				if prefix != "" {
+					prefix += "\n"
+				}
+				start, candidates := repl.Complete(catalog, prefix+text)
+				return max(start-len(prefix), 0), candidates
+			}
+			readLine = func(prompt string) (string, error) {
+				restore, err := repl.RawMode(os.Stdin)
+				if err != nil {
+					return "", err
+				}
+				defer restore()
+				return ed.ReadLine(prompt)
+			}
+			fmt.Fprintln(os.Stderr, `adtap repl: end queries with ';'. \help lists commands, \quit or Ctrl-D exits.`)
+		}
+	}
+
+	var client *adsapi.Client
+	for {
+		prompt := "adtap> "
+		if len(pending) > 0 {
+			prompt = "    -> "
+		}
+		line, err := readLine(prompt)
+		if errors.Is(err, repl.ErrInterrupt) {
+			pending = nil
+			continue
+		}
+		eof := err == io.EOF
+		if err != nil && !eof {
+			fmt.Fprintf(os.Stderr, "I/O error: %v\n", err)
+			break
+		}
+		if eof {
+			// Run a statement left unterminated at the end of input.
+			if len(pending) == 0 {
+				break
+			}
+			line = ""
+		}
+
+		if len(pending) == 0 && repl.IsCommand(line) {
+			history.Add(line)
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case `\q`, `\quit`:
+				saveHistory(history)
+				return
+			case `\h`, `\help`, `\?`:
+				fmt.Fprintln(os.Stderr, strings.Join(append(repl.Commands(), `\help`, `\quit`), "\n"))
+				continue
+			}
+			msg, err := s.Command(line)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", strings.TrimPrefix(err.Error(), "repl: "))
+				continue
+			}
+			fmt.Fprintln(os.Stderr, msg)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" && len(pending) == 0 {
+			continue
+		}
+		if trimmed != "" {
+			pending = append(pending, line)
+		}
+		if trimmed != "" && !strings.HasSuffix(trimmed, ";") {
+			continue
+		}
+		src := strings.Join(pending, "\n")
+		pending = nil
+		history.Add(src)
+		for _, st := range gaql.SplitStatements(src) {
+			if client == nil {
+				c, err := clientFromEnv()
+				if err != nil {
+					se := err.(*setupError)
+					fmt.Fprintf(os.Stderr, "%s: %s\n\nHint: %s\n", se.category, se.msg, se.hint)
+					break
+				}
+				client = c
+			}
+			replQuery(client, s, st.Text, opts, out)
+		}
+		if eof {
+			break
+		}
+	}
+	saveHistory(history)
+}
+
+// replQuery runs one statement of a REPL session and prints its rows.
+// Errors are reported and the session carries on.
+func replQuery(client *adsapi.Client, s *repl.Session, src string, opts output.Options, out *outputFlags) {
+	q, diags, err := s.Prepare(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", strings.TrimPrefix(err.Error(), "repl: "))
+		return
+	}
+	printDiagnostics(diags)
+
+	format := s.OutputFormat()
+	if strings.EqualFold(*out.enums, "auto") && !*out.rawEnums {
+		opts.RawEnums = !format.Human()
+	}
+	r, _ := output.NewRenderer(os.Stdout, format)
+
+	// Ctrl-C cancels the running query, not the session.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	start, hits := time.Now(), resultCache.Hits()
+	fields := q.FieldNames()
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	values := make([]any, len(fields))
+	rows := 0
+	flat := rowflat.Compile(fields)
+	next := nameGeoTargets(ctx, client, s.CustomerID, fields, client.SearchIter(ctx, s.CustomerID, q.String()))
+	for {
+		row, err := next()
+		if err == adsapi.Done {
+			break
+		}
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return
+		}
+		if err != nil {
+			reportQueryError(err, q, src)
+			return
+		}
+		flat.Values(row, values)
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+		rows++
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+	noun := "rows"
+	if rows == 1 {
+		noun = "row"
+	}
+	elapsed := time.Since(start).Round(time.Millisecond).String()
+	if resultCache.Hits() > hits {
+		elapsed = "cached"
+	}
+	fmt.Fprintf(os.Stderr, "(%d %s, %s)\n", rows, noun, elapsed)
+}
+
+// plainReader reads lines from r without prompts or editing, for input
+// that is not a terminal.
+func plainReader(r io.Reader) func(string) (string, error) {
+	sc := bufio.NewScanner(r)
+	return func(string) (string, error) {
+		if !sc.Scan() {
+			if err := sc.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return sc.Text(), nil
+	}
+}
+
+func saveHistory(h *repl.History) {
+	if err := h.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
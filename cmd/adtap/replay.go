@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/adsapi/adsapitest"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+)
+
+// recordDir and replayDir are set by --record DIR and --replay DIR: API
+// responses are saved to recordDir as fixtures, or served from the
+// fixtures in replayDir instead of the API.
+var recordDir, replayDir string
+
+// recordOptions returns the client option saving responses to recordDir,
+// if --record was given. It goes last, to wrap the transport the other
+// options chose.
+func recordOptions() []adsapi.Option {
+	if recordDir == "" {
+		return nil
+	}
+	return []adsapi.Option{adsapi.WithTransportWrapper(func(rt http.RoundTripper) http.RoundTripper {
+		return adsapitest.Recorder(recordDir, rt)
+	})}
+}
+
+// replayClient returns a client answered by the fixtures of --replay.
+// It needs no credentials, and results are not cached.
+func replayClient() *adsapi.Client {
+	if _, err := os.Stat(replayDir); err != nil {
+		exitSetupError(&setupError{exitcode.ConfigError, "Configuration error", err.Error(),
+			"record fixtures with 'adtap --record DIR <command>' first."})
+	}
+	opts := []adsapi.Option{
+		adsapi.WithHTTPClient(&http.Client{Transport: adsapitest.Replayer(replayDir)}),
+		adsapi.WithRecorder(runTimings),
+		adsapi.WithLogger(slog.Default()),
+		adsapi.WithRetry(adsapi.NoRetry),
+	}
+	if loginFlag != "" {
+		opts = append(opts, adsapi.WithLoginCustomerID(loginFlag))
+	}
+	conn, err := connectionOptions(false)
+	if err != nil {
+		exitSetupError(err.(*setupError))
+	}
+	return adsapi.New("replay", adsapi.StaticToken("replay"), append(opts, conn...)...)
+}
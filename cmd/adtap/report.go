@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/compose"
+)
+
+func cmdReport(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		reportUsage()
+		os.Exit(0)
+	}
+	if args[0] == "list" {
+		if len(args) > 1 {
+			usageError("report", "list takes no arguments")
+		}
+		listTemplates(compose.Reports)
+		return
+	}
+	if strings.HasPrefix(args[0], "-") {
+		usageError("report", "report needs a report name; see 'adtap report list'")
+	}
+	r, ok := compose.LookupReport(args[0])
+	if !ok {
+		usageError("report", fmt.Sprintf("unknown report %q; see 'adtap report list'", args[0]))
+	}
+	runTemplate("report", r, args[1:])
+}
+
+func reportUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap report list")
+	fmt.Fprintln(os.Stderr, "       adtap report NAME --customer-id ID [--last 30d] [flags]")
+	fmt.Fprintln(os.Stderr, "\nRun a canned report, answering a common question without writing GAQL.")
+	fmt.Fprintln(os.Stderr, "Reports are templates: --show-query prints the GAQL they build, and")
+	fmt.Fprintln(os.Stderr, "--last sets their date range to the trailing days or weeks.")
+	fmt.Fprintln(os.Stderr, "\nReports:")
+	for _, r := range compose.Reports {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", r.Name, r.Description)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'adtap report NAME --help' for a report's flags.")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/schema"
+)
+
+// syncedCatalog returns the catalog of version synced by 'adtap schema
+// sync', read when first used, or nil when none was synced.
+func syncedCatalog(version string) *gaql.Catalog {
+	dir := schema.DefaultDir()
+	if _, ok := schema.Synced(dir, version); !ok {
+		return nil
+	}
+	return schema.Lazy(dir, version)
+}
+
+func cmdSchema(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		schemaUsage()
+		os.Exit(0)
+	}
+	if len(args) > 1 {
+		usageError("schema", fmt.Sprintf("unexpected argument %q", args[1]))
+	}
+	dir := schema.DefaultDir()
+	switch args[0] {
+	case "sync":
+		if offlineDemo {
+			usageError("schema", "schema sync reads the API's schema; drop --offline-demo")
+		}
+		client := newClient()
+		start := time.Now()
+		fields, err := client.SearchFields(shutdownContext(), schema.AllFields)
+		if err != nil {
+			exitAPIError(err)
+		}
+		c := gaql.NewCatalog(client.Version(), fields)
+		if err := schema.Save(dir, c); err != nil {
+			exitIOError(err)
+		}
+		fmt.Printf("Synced %d fields of %s to %s in %v\n", len(fields), c.Version, schema.Path(dir, c.Version), time.Since(start).Round(time.Millisecond))
+	case "status":
+		version := cmp.Or(activeProfile().APIVersion, gaql.DefaultAPIVersion)
+		fmt.Printf("Directory  %s\n", dir)
+		fmt.Printf("Version    %s\n", version)
+		synced, ok := schema.Synced(dir, version)
+		if !ok {
+			fmt.Printf("Synced     never; the embedded catalog (%d fields) is used\n", len(gaql.DefaultCatalog().Fields()))
+			return
+		}
+		c, err := schema.Load(dir, version)
+		if err != nil {
+			exitIOError(err)
+		}
+		fmt.Printf("Synced     %s (%v ago)\n", synced.Local().Format(time.DateTime), time.Since(synced).Round(time.Second))
+		fmt.Printf("Fields     %d\n", len(c.Fields()))
+	default:
+		usageError("schema", fmt.Sprintf("unknown subcommand %q (expected sync or status)", args[0]))
+	}
+}
+
+func schemaUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap schema sync")
+	fmt.Fprintln(os.Stderr, "       adtap schema status")
+	fmt.Fprintln(os.Stderr, "\nsync saves the complete field metadata of the configured API version from")
+	fmt.Fprintln(os.Stderr, "GoogleAdsFieldService to "+schema.DefaultDir()+",")
+	fmt.Fprintln(os.Stderr, "one file per version. Once synced, 'adtap search --strict' rejects")
+	fmt.Fprintln(os.Stderr, "fields the schema does not have before calling the API, and 'adtap")
+	fmt.Fprintln(os.Stderr, "describe --offline' answers from it; both work without a network")
+	fmt.Fprintln(os.Stderr, "connection. Sync again after upgrading the API version.")
+}
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/accounts"
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/gate"
+	"github.com/aygp-dr/adtap/internal/geo"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/repl"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+	"github.com/aygp-dr/adtap/internal/rowpipe"
+	"github.com/aygp-dr/adtap/internal/rowtransform"
+)
+
+func cmdSearch(args []string) {
+	fs := newFlagSet("search")
+	f := addSearchFlags(fs)
+	fs.Parse(args)
+	f.caching.enable()
+	if f.watchEvery > 0 {
+		// Every run asks the API; the cache would repeat the first.
+		resultCache.Refresh = true
+	}
+	f.validate()
+
+	var ckpt *searchCheckpoint
+	if f.checkpointPath != "" {
+		ckpt = newCheckpoint(f.checkpointPath, f.out)
+	}
+	id := f.id
+	format, r, opts := f.out.renderer()
+	opts.Constants = geo.Default()
+	if f.humanize {
+		// Humanized amounts are currency units, so the columns lose
+		// their _micros suffix.
+		opts.Micros = true
+	}
+	var env *output.EnvelopeRenderer
+	if f.envelope {
+		var err error
+		if env, err = output.NewEnvelopeRenderer(runTimings.Writer(f.out.writer()), format); err != nil {
+			usageError("search", "--envelope requires --format json or jsonl")
+		}
+		r = timeRenderer(env, runTimings)
+	}
+	conv := f.currency.converter()
+
+	v := gaql.NewValidator()
+	v.WarnZeroMetricRows = f.warnZero
+	v.APIVersion = f.apiVersion
+	v.Catalog = syncedCatalog(f.apiVersion)
+	if v.Catalog != nil {
+		// A corrupt catalog would otherwise fall back to the embedded one
+		// without a word.
+		if err := v.Catalog.Err(); err != nil {
+			exitSetupError(configError(err.Error(), "run 'adtap schema sync' to download the catalog again."))
+		}
+	}
+	if f.autoDate {
+		v.AutoAddDateContext = true
+		v.DefaultDateRange = f.defaultDate
+	}
+	if f.strict {
+		v.AllowUnknownResources = false
+		v.StrictParameters = true
+		v.Identifiers = gaql.IdentifierNamespace
+		v.KnownFields = v.Catalog != nil
+	}
+	if v.Access = f.pol.access("search"); v.Access != nil {
+		opts.Redact = v.Access.Redacts
+	}
+	policy := gate.DefaultPolicy()
+	policy.MaxDays = f.maxDays
+
+	if stmts := f.stmts; len(stmts) > 1 {
+		switch {
+		case f.explain:
+			for i, st := range stmts {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("-- %s\n", st.Label())
+				explainQuery(validateQuery(v, st.Text, st.Label()+": "))
+			}
+		case f.dryRun:
+			queries := make([]*gaql.Query, len(stmts))
+			for i, st := range stmts {
+				queries[i] = validateQuery(v, st.Text, st.Label()+": ")
+			}
+			dryRunQueries(id, stmts, queries)
+		default:
+			runStatements(stmts, id, v, policy, f.yes, f.parallel, f.maxRows, format, opts, f.out.writer())
+		}
+		return
+	}
+
+	q := validateQuery(v, f.query, "")
+	if f.sample > 0 {
+		diags := gaql.ApplyGuardrails(q, gaql.GuardrailPolicy{MaxLimit: f.sample, MaxDays: 1})
+		for i := range diags {
+			diags[i].Message = "--sample: " + diags[i].Message
+		}
+		printDiagnostics(diags)
+	}
+	fields := q.FieldNames()
+	if f.allAccounts && !slices.Contains(fields, "customer.id") {
+		fields = append([]string{"customer.id"}, fields...)
+	}
+	var pipe *rowpipe.Pipeline
+	if f.post != "" {
+		var err error
+		if pipe, err = rowpipe.Parse(f.post, fields); err != nil {
+			usageError("search", "--post: "+strings.TrimPrefix(err.Error(), "rowpipe: "))
+		}
+	}
+	if f.explain {
+		explainQuery(q)
+		return
+	}
+	if f.dryRun {
+		dryRunQueries(id, []gaql.Statement{{Text: f.query}}, []*gaql.Query{q})
+		return
+	}
+	if !f.yes {
+		confirmExpensive(policy.Check(q))
+	}
+
+	// A signal stops the stream; the rows read so far are still flushed
+	// to the output or sink before the command exits.
+	ctx := shutdownContext()
+	client := newClient()
+	if f.maxBytes > 0 {
+		client = client.WithBudget(0, f.maxBytes)
+	}
+	if f.watchEvery > 0 {
+		watchSearch(ctx, client, id, q, f.watchEvery, f.diffOnly, f.maxRows, format, opts, f.out)
+	}
+	if env != nil {
+		env.Metadata.Query = q.String()
+	}
+	if ckpt != nil {
+		ckpt.load(f.resuming, id, q, format)
+	}
+
+	// Rows of a single account stream from the API page by page into the
+	// renderer and the summary; only the table format buffers them, to
+	// align columns. --all-accounts collects each account's rows first.
+	var sum *output.Summary
+	if f.stats {
+		sum = output.NewSummary(fields, true)
+	}
+	header := fields
+	if pipe != nil {
+		header = pipe.Fields()
+	}
+	sink := f.newSearchSink(ctx, q, format, header, r, &opts, ckpt)
+	r = sink.renderer()
+	flat := rowflat.Compile(fields)
+	values := make([]any, len(fields))
+	// With --post, rows are kept for the pipeline and written at the end.
+	var kept [][]any
+	// record fills values from row, converted from currency from.
+	record := func(row adsapi.Row, from string) {
+		if conv != nil {
+			convertRow(ctx, conv, row, fields, from)
+		}
+		flat.Values(row, values)
+		if f.humanize {
+			if conv != nil {
+				from = conv.Currency()
+			}
+			rowtransform.Humanize(from).Values(fields, values)
+		}
+	}
+	written := 0
+	// write renders one row, converted from currency from, and reports
+	// whether more rows are wanted.
+	write := func(row adsapi.Row, from string) bool {
+		if f.maxRows > 0 && written == f.maxRows {
+			fmt.Fprintf(os.Stderr, "Warning: stopped after %d rows (--max-rows); more are available\n", f.maxRows)
+			if env != nil {
+				env.Metadata.Truncated = true
+			}
+			return false
+		}
+		written++
+		record(row, from)
+		if pipe != nil {
+			kept = append(kept, slices.Clone(values))
+			return true
+		}
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+		if sum != nil {
+			sum.Add(row)
+		}
+		return true
+	}
+
+	var (
+		accounts  = 1
+		failed    adsapi.AccountErrors
+		streamErr error
+		totals    *adsapi.SearchTotals
+		meta      *adsapi.SearchMetadata
+	)
+	if f.allAccounts {
+		var names []string // fields whose geo targets are named
+		if opts.Constants != nil {
+			names = fields
+		}
+		var truncated bool
+		meta = new(adsapi.SearchMetadata)
+		accounts, failed, truncated = searchAllAccounts(ctx, client, q.String(), f.concurrency, names, meta, write)
+		if truncated && env != nil {
+			env.Metadata.Truncated = true
+		}
+		if accounts > 0 && len(failed) == accounts && env == nil && interrupted(ctx) == nil {
+			exitAPIError(failed[0].Err)
+		}
+	} else {
+		var currencyFrom string
+		if conv != nil || f.humanize {
+			currencyFrom = accountCurrency(ctx, client, id)
+		}
+		var next func() (adsapi.Row, error)
+		if f.summary {
+			// The summary row comes with the last page, so it is read
+			// even when --max-rows stops the output early.
+			next, totals = client.SearchIterWithOptions(ctx, id, q.String(),
+				adsapi.SearchOptions{ReturnSummaryRow: true, ReturnTotalResultsCount: true})
+			meta = &totals.Metadata
+		} else if ckpt != nil {
+			next = ckpt.iter(ctx, client)
+		} else if f.shardDays > 0 || f.shardCampaigns > 0 {
+			next, meta = shardedIter(ctx, client, id, q, adsapi.ShardOptions{Days: f.shardDays, CampaignsPerShard: f.shardCampaigns, Concurrency: f.concurrency})
+		} else {
+			var t *adsapi.SearchTotals
+			next, t = client.SearchIterWithOptions(ctx, id, q.String(), adsapi.SearchOptions{})
+			meta = &t.Metadata
+		}
+		if opts.Constants != nil {
+			next = nameGeoTargets(ctx, client, id, fields, next)
+		}
+		for {
+			row, err := next()
+			if err == adsapi.Done || err != nil && interrupted(ctx) != nil {
+				break
+			}
+			if err == adsapi.ErrTruncated {
+				fmt.Fprintf(os.Stderr, "Warning: stopped at the %d-byte budget (--max-bytes); more rows are available\n", f.maxBytes)
+				if env != nil {
+					env.Metadata.Truncated = true
+				}
+				break
+			}
+			if err != nil && env == nil {
+				if ckpt != nil {
+					ckpt.finish(r, false)
+				}
+				exitQueryError(err, q, f.query)
+			}
+			if err != nil {
+				// The envelope reports the rows read so far with the
+				// error that cut them short.
+				failed = adsapi.AccountErrors{{CustomerID: id, Err: err}}
+				streamErr = err
+				break
+			}
+			if ckpt != nil {
+				ckpt.advance(r)
+			}
+			if !write(row, currencyFrom) {
+				if totals != nil {
+					// Read on to the summary row without writing.
+					write = func(adsapi.Row, string) bool { return true }
+					continue
+				}
+				break
+			}
+		}
+		if totals != nil && totals.SummaryRow != nil && streamErr == nil && interrupted(ctx) == nil {
+			record(totals.SummaryRow, currencyFrom)
+			if err := opts.WriteSummary(r, fields, values); err != nil {
+				exitIOError(err)
+			}
+		}
+		if totals != nil && env != nil {
+			env.Metadata.TotalResultsCount = totals.TotalResultsCount
+		}
+	}
+	if pipe != nil {
+		for _, values := range pipe.Run(kept) {
+			if err := opts.WriteRecord(r, header, values); err != nil {
+				exitIOError(err)
+			}
+		}
+	}
+	if env != nil && meta != nil {
+		env.Metadata.RequestIDs = meta.RequestIDs
+		env.Metadata.Pages = meta.Pages
+		env.Metadata.DurationMS = meta.Duration.Milliseconds()
+	}
+	if env != nil {
+		env.Metadata.Interrupted = interrupted(ctx) != nil
+		env.Metadata.Accounts = accounts
+		env.Metadata.FailedAccounts = len(failed)
+		for _, e := range failed {
+			env.Errors = append(env.Errors, resultError(e.CustomerID, e.Err))
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+	sink.finish(interrupted(ctx) == nil)
+
+	if totals != nil && streamErr == nil && interrupted(ctx) == nil {
+		fmt.Fprintf(os.Stderr, "%d result(s) in total\n", totals.TotalResultsCount)
+	}
+	if f.verbose && meta != nil {
+		printSearchMetadata(meta)
+	}
+	if sum != nil {
+		// Keep machine-readable output parseable: the footer goes to
+		// stderr unless the format is meant for people.
+		w := io.Writer(os.Stderr)
+		if format.Human() {
+			w = f.out.writer()
+			fmt.Fprintln(w)
+		}
+		if _, err := sum.WriteTo(w); err != nil {
+			exitIOError(err)
+		}
+	}
+
+	if sig := interrupted(ctx); sig != nil {
+		exitInterrupted(sig, fmt.Sprintf("wrote %d rows", written))
+	}
+	if streamErr != nil {
+		exitQueryError(streamErr, q, f.query)
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "API error: the query failed for %d account(s); their rows are missing\n", len(failed))
+		os.Exit(exitcode.APIError)
+	}
+}
+
+// shardedIter runs q as shards with SearchSharded and returns an
+// iterator over the merged rows, which ends with ErrTruncated when the
+// byte budget cut a shard short, and the metadata of the shards. A
+// query that cannot be split exits with a validation error.
+func shardedIter(ctx context.Context, client *adsapi.Client, id string, q *gaql.Query, opts adsapi.ShardOptions) (func() (adsapi.Row, error), *adsapi.SearchMetadata) {
+	res, err := client.SearchSharded(ctx, id, q, opts)
+	if errors.Is(err, adsapi.ErrNotShardable) {
+		exitValidationError("%s\n\nHint: select segments.date and bound it, as in segments.date DURING LAST_30_DAYS, or drop LIMIT", strings.TrimPrefix(err.Error(), "adsapi: "))
+	}
+	if err != nil {
+		// The failed shard's request ID is in the error.
+		res = new(adsapi.Result)
+	}
+	return func() (adsapi.Row, error) {
+		switch {
+		case err != nil:
+			return nil, err
+		case len(res.Rows) > 0:
+			row := res.Rows[0]
+			res.Rows = res.Rows[1:]
+			return row, nil
+		case res.Truncated:
+			return nil, adsapi.ErrTruncated
+		}
+		return nil, adsapi.Done
+	}, &res.Metadata
+}
+
+// printSearchMetadata prints the request IDs, pages, rows, and time of a
+// search for --verbose, to quote when reporting a problem to Google Ads
+// API support.
+func printSearchMetadata(m *adsapi.SearchMetadata) {
+	if m.Cached {
+		fmt.Fprintf(os.Stderr, "Read %d rows from the result cache in %v\n", m.Rows, m.Duration.Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(os.Stderr, "Fetched %d page(s), %d rows in %v\n", m.Pages, m.Rows, m.Duration.Round(time.Millisecond))
+	}
+	for _, id := range m.RequestIDs {
+		fmt.Fprintf(os.Stderr, "Request ID: %s\n", id)
+	}
+	printLimiterStats()
+}
+
+// resultError describes the failure of one account for --envelope.
+func resultError(customerID string, err error) output.ResultError {
+	re := output.ResultError{CustomerID: customerID, Message: err.Error()}
+	var apiErr *adsapi.APIError
+	if errors.As(err, &apiErr) {
+		re.Status, re.Message, re.RequestID = apiErr.Status, apiErr.Message, apiErr.RequestID
+	}
+	return re
+}
+
+// searchAllAccounts runs query against every non-manager account below
+// the accessible customers and passes the rows to write as the accounts
+// produce them; once write wants no more, every account stops. It
+// returns the number of accounts queried, the failing ones,
+// which are also reported as warnings, and whether the client's budget
+// cut any account short, and adds the requests of every account to
+// meta. The geo targets of fields are named first.
+func searchAllAccounts(ctx context.Context, client *adsapi.Client, query string, concurrency int, fields []string, meta *adsapi.SearchMetadata, write func(adsapi.Row, string) bool) (int, adsapi.AccountErrors, bool) {
+	accessible, err := client.ListAccessibleCustomers(ctx)
+	if err != nil {
+		exitAPIError(err)
+	}
+	roots := accountTrees(ctx, client, accessible)
+
+	var ids []string
+	logins := map[string]string{}
+	currencies := map[string]string{}
+	for _, root := range roots {
+		root.Walk(func(a *accounts.Account, _ int) {
+			if a.Manager || a.Cycle || currencies[a.ID] != "" {
+				return
+			}
+			ids = append(ids, a.ID)
+			logins[a.ID] = root.ID
+			currencies[a.ID] = a.CurrencyCode
+		})
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(os.Stderr, "Warning: no non-manager accounts are accessible")
+		return 0, nil, false
+	}
+
+	start := time.Now()
+	stopped := false
+	results, err := client.SearchAccounts(ctx, ids, query, adsapi.SearchAccountsOptions{
+		Concurrency: concurrency,
+		Logins:      logins,
+		OnRows: func(customerID string, rows []adsapi.Row) bool {
+			if slices.ContainsFunc(fields, geo.TargetField) {
+				fetchGeoTargets(ctx, client.WithLogin(logins[customerID]), customerID, rows)
+			}
+			for _, row := range rows {
+				if !write(row, currencies[customerID]) {
+					stopped = true
+					return false
+				}
+			}
+			return true
+		},
+	})
+	meta.Duration = time.Since(start)
+	var failed adsapi.AccountErrors
+	errors.As(err, &failed)
+	for _, f := range failed {
+		if interrupted(ctx) != nil && errors.Is(f.Err, context.Canceled) {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", f.CustomerID, f.Err)
+	}
+
+	truncated := stopped
+	for _, res := range results {
+		meta.Merge(res.Metadata)
+		if res.Truncated && !stopped {
+			fmt.Fprintf(os.Stderr, "Warning: %s: stopped at the byte budget (--max-bytes); more rows are available\n", res.CustomerID)
+			truncated = true
+		}
+	}
+	return len(ids), failed, truncated
+}
+
+// confirmExpensive asks the user to confirm an expensive query on a
+// terminal and exits when they decline. Without a terminal there is
+// nobody to ask, so the command refuses and points at --yes.
+func confirmExpensive(reasons []gate.Reason) {
+	if len(reasons) == 0 {
+		return
+	}
+
+	if !repl.IsTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "Usage error: refusing to run an expensive query non-interactively:")
+		for _, r := range reasons {
+			fmt.Fprintf(os.Stderr, "  - %s\n", r.Message)
+		}
+		fmt.Fprintln(os.Stderr, "\nHint: pass --yes to run it anyway.")
+		os.Exit(exitcode.UsageError)
+	}
+
+	ok, err := gate.Confirm(os.Stdin, os.Stderr, reasons)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "I/O error: %v\n", err)
+		os.Exit(exitcode.IOError)
+	}
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Aborted.")
+		os.Exit(exitcode.GeneralError)
+	}
+}
+
+// queryParams are the --param values of a search, by placeholder name.
+type queryParams map[string]string
+
+func (p queryParams) String() string {
+	pairs := make([]string, 0, len(p))
+	for name, value := range p {
+		pairs = append(pairs, name+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (p queryParams) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimPrefix(name, "@")
+	if !ok || name == "" {
+		return errors.New("expected NAME=VALUE")
+	}
+	p[name] = value
+	return nil
+}
+
+// bindParams fills the @placeholders of a query with --param values,
+// written as literals of the type of the field each is compared with,
+// and marks the names it used. Queries without placeholders are returned
+// unchanged. prefix labels the messages.
+func bindParams(text string, params queryParams, used map[string]bool, prefix string) string {
+	if !strings.Contains(text, "@") {
+		return text
+	}
+	t, err := gaql.NewTemplate(text)
+	if err != nil {
+		exitValidationError("%s%v", prefix, err)
+	}
+	for _, name := range t.Params() {
+		value, ok := params[name]
+		if !ok {
+			usageError("search", fmt.Sprintf("%sthe query needs --param %s=VALUE for @%s", prefix, name, name))
+		}
+		if err := t.BindText(name, value); err != nil {
+			exitValidationError("%s%v", prefix, err)
+		}
+		used[name] = true
+	}
+	text, err = t.Text()
+	if err != nil {
+		exitValidationError("%s%v", prefix, err)
+	}
+	return text
+}
+
+// validateQuery parses and validates a query, printing its diagnostics,
+// and exits on validation errors, with their position when the error
+// has one. prefix labels the messages.
+func validateQuery(v *gaql.Validator, text, prefix string) *gaql.Query {
+	q, err := gaql.Parse(text)
+	if err == nil {
+		var diags []gaql.Diagnostic
+		q, diags, err = v.Prepare(q)
+		for i := range diags {
+			diags[i].Message = prefix + diags[i].Message
+		}
+		printDiagnostics(diags)
+	}
+	var ve *gaql.ValidationError
+	if errors.As(err, &ve) && ve.Span.IsValid() {
+		exitValidationError("%s%v at line %d, column %d", prefix, err, ve.Span.Start.Line, ve.Span.Start.Column)
+	}
+	if err != nil {
+		exitValidationError("%s%v", prefix, err)
+	}
+	return q
+}
+
+// dryRunQueries sends each query to the API with validate_only, so it is
+// checked against the live schema and the account without returning
+// rows, and prints whether it would succeed. A query file reports every
+// statement; the command exits with the code of the first rejection.
+func dryRunQueries(id string, stmts []gaql.Statement, queries []*gaql.Query) {
+	ctx := context.Background()
+	client := newClient()
+	code := exitcode.Success
+	for i, q := range queries {
+		prefix := ""
+		if len(queries) > 1 {
+			prefix = stmts[i].Label() + ": "
+		}
+		resp, err := client.Validate(ctx, id, q.String())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%srejected by the API\n", prefix)
+			if c := reportQueryError(err, q, stmts[i].Text); code == exitcode.Success {
+				code = c
+			}
+			continue
+		}
+		fmt.Printf("%sValid: the API accepts the query for customer %s", prefix, id)
+		if resp.RequestID != "" {
+			fmt.Printf(" (request ID %s)", resp.RequestID)
+		}
+		fmt.Println()
+	}
+	if code != exitcode.Success {
+		os.Exit(code)
+	}
+}
+
+// explainQuery prints the local cost estimate of q for --explain.
+func explainQuery(q *gaql.Query) {
+	c := gaql.EstimateCost(q)
+	fmt.Printf("Query       %s\n", q)
+	fmt.Printf("Fields      %d\n", c.Fields)
+	if c.Bounded {
+		fmt.Printf("Date span   %d days\n", c.Days)
+	} else {
+		fmt.Printf("Date span   unbounded (estimated as %d days)\n", c.Days)
+	}
+	if c.Limit > 0 {
+		fmt.Printf("Limit       %d\n", c.Limit)
+	} else {
+		fmt.Println("Limit       none")
+	}
+	if len(c.Segments) > 0 {
+		factors := make([]string, len(c.Segments))
+		for i, s := range c.Segments {
+			guess := ""
+			if s.Guess {
+				guess = "~"
+			}
+			factors[i] = fmt.Sprintf("%s %s%d", s.Field, guess, s.Rows)
+		}
+		fmt.Printf("Segments    %s\n", strings.Join(factors, " × "))
+	}
+	fmt.Printf("Estimated row multiplier: %d row(s) per %s\n", c.Multiplier, q.From)
+	if len(c.Warnings) > 0 {
+		fmt.Println("\nWarnings:")
+		for _, w := range c.Warnings {
+			fmt.Printf("  - %s\n", w.Message)
+			if w.Hint != "" {
+				fmt.Printf("    Hint: %s\n", w.Hint)
+			}
+		}
+	}
+}
+
+// printDiagnostics writes validator warnings, notes, and hints to stderr.
+func printDiagnostics(diags []gaql.Diagnostic) {
+	for _, d := range diags {
+		label := "Warning"
+		if d.Severity == gaql.SeverityInfo {
+			label = "Note"
+		}
+		eprintf("%s: %s\n", label, d.Message)
+		if d.Hint != "" {
+			eprintf("Hint: %s\n", d.Hint)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/gate"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+)
+
+// statementResult is the rendered output of one statement of a query file.
+type statementResult struct {
+	label    string
+	out      bytes.Buffer
+	rows     int
+	duration time.Duration
+	err      error
+}
+
+// runStatements runs the statements of a query file against one account,
+// up to parallel at a time. Every statement is validated, and the
+// expensive ones confirmed together, before any request is sent. Each
+// statement renders into its own buffer, printed as a labeled section as
+// soon as it completes, to w, and a summary of all statements follows.
+// format must be one meant for people; the sections of machine formats
+// would not parse as one document.
+// The command exits with an API error when any statement failed. A
+// signal stops the statements running and skips the rest; the summary
+// still lists every statement.
+func runStatements(stmts []gaql.Statement, id string, v *gaql.Validator, policy gate.Policy, yes bool, parallel, maxRows int, format output.Format, opts output.Options, w io.Writer) {
+	queries := make([]*gaql.Query, len(stmts))
+	var reasons []gate.Reason
+	for i, st := range stmts {
+		q := validateQuery(v, st.Text, st.Label()+": ")
+		queries[i] = q
+		for _, r := range policy.Check(q) {
+			r.Message = st.Label() + ": " + r.Message
+			reasons = append(reasons, r)
+		}
+	}
+	if !yes {
+		confirmExpensive(reasons)
+	}
+
+	ctx := shutdownContext()
+	client := newClient()
+	results := make([]*statementResult, len(stmts))
+	for i, st := range stmts {
+		results[i] = &statementResult{label: st.Label(), err: errNotRun}
+	}
+	var mu sync.Mutex
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range stmts {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			res := &statementResult{label: stmts[i].Label()}
+			start := time.Now()
+			res.rows, res.err = runStatement(ctx, client, id, queries[i], maxRows, format, opts, &res.out)
+			res.duration = time.Since(start)
+			results[i] = res
+
+			mu.Lock()
+			defer mu.Unlock()
+			if res.err != nil && interrupted(ctx) != nil {
+				res.err = errNotRun
+				return
+			}
+			if res.err != nil {
+				fmt.Fprintf(os.Stderr, "API error: %s: %v\n", res.label, res.err)
+				return
+			}
+			fmt.Fprintf(w, "-- %s (%d rows)\n", res.label, res.rows)
+			if _, err := runTimings.Writer(w).Write(res.out.Bytes()); err != nil {
+				exitIOError(err)
+			}
+			fmt.Fprintln(w)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := writeStatementSummary(w, results)
+	if sig := interrupted(ctx); sig != nil {
+		done := 0
+		for _, res := range results {
+			if res.err == nil {
+				done++
+			}
+		}
+		exitInterrupted(sig, fmt.Sprintf("%d of %d queries finished", done, len(stmts)))
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "API error: %d of %d queries failed\n", failed, len(stmts))
+		os.Exit(exitcode.APIError)
+	}
+}
+
+// errNotRun marks a statement a signal kept from running or finishing.
+var errNotRun = errors.New("not run")
+
+// runStatement runs q and renders up to maxRows rows (0 for all) to w.
+func runStatement(ctx context.Context, client *adsapi.Client, id string, q *gaql.Query, maxRows int, format output.Format, opts output.Options, w io.Writer) (int, error) {
+	r, err := output.NewRenderer(w, format)
+	if err != nil {
+		return 0, err
+	}
+	r = timeRenderer(r, runTimings)
+	fields := q.FieldNames()
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		return 0, err
+	}
+	flat := rowflat.Compile(fields)
+	values := make([]any, len(fields))
+	rows := 0
+	next := client.SearchIter(ctx, id, q.String())
+	for maxRows == 0 || rows < maxRows {
+		row, err := next()
+		if err == adsapi.Done {
+			break
+		}
+		if err != nil {
+			return rows, err
+		}
+		flat.Values(row, values)
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	return rows, r.Flush()
+}
+
+// writeStatementSummary writes one line per statement, in file order, and
+// returns the number that failed.
+func writeStatementSummary(w io.Writer, results []*statementResult) int {
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "QUERY\tROWS\tTIME\tSTATUS")
+	for _, res := range results {
+		status := "ok"
+		switch res.err {
+		case nil:
+		case errNotRun:
+			status = "interrupted"
+			failed++
+		default:
+			status = "failed"
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%s\n", res.label, res.rows, res.duration.Round(time.Millisecond), status)
+	}
+	tw.Flush()
+	return failed
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/export/bigquery"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/gate"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// searchFlags are the options of adtap search. validate checks them and
+// fills in the fields derived from them.
+type searchFlags struct {
+	customerID     string
+	query          string
+	file           string
+	parallel       int
+	yes            bool
+	explain        bool
+	dryRun         bool
+	maxDays        int
+	warnZero       bool
+	strict         bool
+	autoDate       bool
+	apiVersion     string
+	defaultDuring  string
+	maxRows        int
+	maxBytes       int64
+	stats          bool
+	summary        bool
+	allAccounts    bool
+	concurrency    int
+	shardDays      int
+	shardCampaigns int
+	params         queryParams
+	envelope       bool
+	verbose        bool
+	toBigQuery     string
+	toSQLite       string
+	sqliteTable    string
+	humanize       bool
+	watchEvery     time.Duration
+	diffOnly       bool
+	sample         int
+	checkpointPath string
+	resuming       bool
+	post           string
+
+	out      *outputFlags
+	currency *currencyFlags
+	caching  *cacheFlags
+	pol      *policyFlags
+
+	// Set by validate.
+	stmts       []gaql.Statement // the queries of --file
+	id          string           // the normalized --customer-id
+	table       bigquery.TableID // the --to-bigquery table
+	defaultDate gaql.DateRange   // the --default-during range
+}
+
+func addSearchFlags(fs *flag.FlagSet) *searchFlags {
+	f := &searchFlags{params: queryParams{}}
+	fs.StringVar(&f.customerID, "customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	fs.StringVar(&f.query, "query", "", "GAQL query to execute")
+	fs.StringVar(&f.file, "file", "", "Run the semicolon-separated queries in this file (- for stdin)")
+	fs.IntVar(&f.parallel, "parallel", 1, "Queries from --file run at once")
+	fs.BoolVar(&f.yes, "yes", false, "Skip confirmation for expensive queries")
+	fs.BoolVar(&f.explain, "explain", false, "Print the estimated cost of the query and exit without running it")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Validate the query locally and with the API, without returning rows, and exit")
+	fs.IntVar(&f.maxDays, "max-days", gate.DefaultPolicy().MaxDays, "Ask for confirmation when the date range exceeds this many days (0 disables)")
+	fs.BoolVar(&f.warnZero, "warn-zero-rows", true, "Warn when selecting metrics will drop rows with zero impressions")
+	fs.BoolVar(&f.strict, "strict", false, "Reject unknown resources, field namespaces, and PARAMETERS keys, and fields missing from the schema synced by 'adtap schema sync'")
+	fs.BoolVar(&f.autoDate, "auto-date", false, "Add a segments.date condition when metrics lack date context")
+	fs.StringVar(&f.apiVersion, "api-version", cmp.Or(activeProfile().APIVersion, gaql.DefaultAPIVersion), "Google Ads API version to validate the query against")
+	fs.StringVar(&f.defaultDuring, "default-during", "LAST_30_DAYS", "Date range keyword added by --auto-date")
+	fs.IntVar(&f.maxRows, "max-rows", 0, "Stop after this many rows (0 means no limit); pages are fetched as needed")
+	fs.Int64Var(&f.maxBytes, "max-bytes", 0, "Stop before the rows read exceed this many bytes of API JSON (0 means no limit); per account with --all-accounts")
+	fs.BoolVar(&f.stats, "stats", false, "Print a footer with the row count, metric totals, and weighted averages")
+	fs.BoolVar(&f.summary, "summary", false, "Ask the API for the summary row, the selected metrics over every result, and the result count, and show them after the rows")
+	fs.BoolVar(&f.allAccounts, "all-accounts", false, "Run the query against every accessible non-manager account, tagging rows with customer.id")
+	fs.IntVar(&f.concurrency, "concurrency", adsapi.DefaultConcurrency, "Accounts queried at once with --all-accounts, or shards with --shard-days and --shard-campaigns")
+	fs.IntVar(&f.shardDays, "shard-days", 0, "Split the query into shards of this many days of its segments.date range, run concurrently and merged")
+	fs.IntVar(&f.shardCampaigns, "shard-campaigns", 0, "Also split the query into shards of this many campaigns each, reading the campaign IDs first")
+	fs.Var(f.params, "param", "Bind a query placeholder: --param campaign_id=123 fills @campaign_id (repeatable)")
+	fs.BoolVar(&f.envelope, "envelope", false, "Wrap json or jsonl rows with the errors and metadata of the query, so partial results are recognizable")
+	fs.BoolVar(&f.verbose, "verbose", false, "Print the request IDs of the pages read, the pages, rows, and time taken to stderr")
+	fs.StringVar(&f.toBigQuery, "to-bigquery", "", "Stream rows into this BigQuery table (PROJECT.DATASET.TABLE), creating it from the selected fields if needed")
+	fs.StringVar(&f.toSQLite, "to-sqlite", "", "Write rows into a new table of this SQLite database file, creating the file if needed")
+	fs.StringVar(&f.sqliteTable, "table", "", "Table name for --to-sqlite (default: the FROM resource)")
+	fs.BoolVar(&f.humanize, "humanize", false, "Show amounts in micros as decimals with the account's currency code, and enum numbers as names")
+	fs.DurationVar(&f.watchEvery, "watch", 0, "Run the query again at this interval, such as 5m, marking rows whose metrics changed, until interrupted")
+	fs.BoolVar(&f.diffOnly, "diff-only", false, "With --watch, print only the rows that changed since the previous run")
+	fs.IntVar(&f.sample, "sample", 0, "Fetch a sample: lower LIMIT to this many rows and narrow the date range to its most recent day")
+	fs.StringVar(&f.checkpointPath, "checkpoint", "", "Save progress to this file as rows are written to --output, so --resume can continue a killed run")
+	fs.BoolVar(&f.resuming, "resume", false, "Continue from the --checkpoint of an earlier run instead of starting over")
+	fs.StringVar(&f.post, "post", "", "Filter and aggregate the rows locally, as in \"group by campaign.name | sum(metrics.clicks) | having sum > 1000\"")
+	f.out = addOutputFlags(fs)
+	f.currency = addCurrencyFlags(fs, "search")
+	f.caching = addCacheFlags(fs)
+	f.pol = addPolicyFlags(fs)
+	return f
+}
+
+// multiQuery names a --file holding more than one query in
+// searchConflicts and searchRequirements.
+const multiQuery = "a multi-query --file"
+
+// searchConflicts lists, for each option, the options it cannot be
+// combined with. Each pair is listed once, under the option named first
+// in the usage error.
+var searchConflicts = []struct {
+	option string
+	with   []string
+}{
+	{multiQuery, []string{"--all-accounts", "--stats", "--envelope", "--to-bigquery", "--to-sqlite", "--humanize", "--summary", "--sample", "--post", "--max-bytes", "--verbose", "--shard-days", "--shard-campaigns", "--checkpoint", "--watch", "--currency"}},
+	{"--all-accounts", []string{"--customer-id", "--dry-run", "--summary", "--shard-days", "--shard-campaigns", "--checkpoint", "--watch"}},
+	{"--dry-run", []string{"--explain", "--checkpoint", "--watch"}},
+	{"--explain", []string{"--checkpoint", "--watch"}},
+	{"--envelope", []string{"--to-bigquery", "--to-sqlite", "--checkpoint", "--watch"}},
+	{"--to-bigquery", []string{"--to-sqlite", "--humanize", "--summary", "--post", "--checkpoint", "--watch"}},
+	{"--to-sqlite", []string{"--humanize", "--summary", "--post", "--checkpoint", "--watch"}},
+	{"--humanize", []string{"--post", "--watch"}},
+	{"--summary", []string{"--post", "--shard-days", "--shard-campaigns", "--checkpoint", "--watch"}},
+	{"--stats", []string{"--post", "--checkpoint", "--watch"}},
+	{"--sample", []string{"--shard-days", "--shard-campaigns", "--watch"}},
+	{"--post", []string{"--checkpoint", "--watch"}},
+	{"--max-bytes", []string{"--checkpoint", "--watch"}},
+	{"--max-rows", []string{"--checkpoint"}},
+	{"--verbose", []string{"--checkpoint", "--watch"}},
+	{"--shard-days", []string{"--checkpoint", "--watch"}},
+	{"--shard-campaigns", []string{"--checkpoint", "--watch"}},
+	{"--currency", []string{"--watch"}},
+	{"--checkpoint", []string{"--watch"}},
+}
+
+// searchRequirements lists the options that only apply with another.
+var searchRequirements = []struct{ option, requires string }{
+	{"--table", "--to-sqlite"},
+	{"--resume", "--checkpoint"},
+	{"--diff-only", "--watch"},
+}
+
+// validate checks the options, reads the --file queries and binds the
+// --param values into them, and exits with a usage error when they do
+// not make sense together.
+func (f *searchFlags) validate() {
+	if !f.allAccounts {
+		defaultCustomer(&f.customerID)
+	}
+	f.readQueries()
+
+	switch {
+	case f.parallel < 1:
+		usageError("search", "--parallel must be at least 1")
+	case f.sample < 0:
+		usageError("search", "--sample must not be negative")
+	case f.maxBytes < 0:
+		usageError("search", "--max-bytes must not be negative")
+	case f.shardDays < 0 || f.shardCampaigns < 0:
+		usageError("search", "--shard-days and --shard-campaigns must not be negative")
+	case f.allAccounts && f.concurrency < 1:
+		usageError("search", "--concurrency must be at least 1")
+	case f.watchEvery < 0 || f.watchEvery > 0 && f.watchEvery < minWatchInterval:
+		usageError("search", fmt.Sprintf("--watch must be at least %v", minWatchInterval))
+	}
+
+	set := map[string]bool{
+		multiQuery:          len(f.stmts) > 1,
+		"--customer-id":     f.customerID != "",
+		"--all-accounts":    f.allAccounts,
+		"--explain":         f.explain,
+		"--dry-run":         f.dryRun,
+		"--stats":           f.stats,
+		"--summary":         f.summary,
+		"--envelope":        f.envelope,
+		"--to-bigquery":     f.toBigQuery != "",
+		"--to-sqlite":       f.toSQLite != "",
+		"--table":           f.sqliteTable != "",
+		"--humanize":        f.humanize,
+		"--currency":        f.currency.currency != "",
+		"--sample":          f.sample > 0,
+		"--post":            f.post != "",
+		"--max-rows":        f.maxRows > 0,
+		"--max-bytes":       f.maxBytes > 0,
+		"--verbose":         f.verbose,
+		"--shard-days":      f.shardDays > 0,
+		"--shard-campaigns": f.shardCampaigns > 0,
+		"--checkpoint":      f.checkpointPath != "",
+		"--resume":          f.resuming,
+		"--watch":           f.watchEvery > 0,
+		"--diff-only":       f.diffOnly,
+	}
+	isSet := func(option string) bool {
+		v, ok := set[option]
+		if !ok {
+			panic("search: unknown option " + option)
+		}
+		return v
+	}
+	for _, c := range searchConflicts {
+		if !isSet(c.option) {
+			continue
+		}
+		for _, other := range c.with {
+			if isSet(other) {
+				usageError("search", c.option+" cannot be combined with "+other)
+			}
+		}
+	}
+	for _, r := range searchRequirements {
+		if isSet(r.option) && !isSet(r.requires) {
+			usageError("search", r.option+" requires "+r.requires)
+		}
+	}
+
+	if f.checkpointPath != "" && (*f.out.output == "" || *f.out.output == "-") {
+		usageError("search", "--checkpoint requires --output FILE")
+	}
+	// The format is checked again when the renderer is made; an invalid
+	// one is reported there.
+	if format, err := output.ParseFormat(*f.out.format); err == nil {
+		switch {
+		case len(f.stmts) > 1 && !format.Human():
+			// Sections of JSON arrays or CSV with different headers, one
+			// after another, would not parse as one document.
+			usageError("search", fmt.Sprintf("a multi-query --file prints a table per query; run the queries one at a time for --format %s", format))
+		case f.checkpointPath != "" && !slices.Contains(resumableFormats, format):
+			exitValidationError("invalid output format for --checkpoint\n\nExpected: csv, tsv, jsonl\nGot: %s", *f.out.format)
+		}
+	}
+	if f.toBigQuery != "" {
+		var err error
+		if f.table, err = bigquery.ParseTableID(f.toBigQuery); err != nil {
+			usageError("search", strings.TrimPrefix(err.Error(), "bigquery: "))
+		}
+	}
+	if f.autoDate {
+		dr, ok := gaql.LookupDateRange(f.defaultDuring)
+		if !ok || dr == gaql.DateRangeCustom {
+			usageError("search", fmt.Sprintf("invalid --default-during %q", f.defaultDuring))
+		}
+		f.defaultDate = dr
+	}
+
+	switch {
+	case f.allAccounts:
+	case f.customerID == "" && f.explain:
+		// Estimates are made locally; no account is needed.
+	case f.customerID == "":
+		usageError("search", "--customer-id or --all-accounts is required")
+	default:
+		var err error
+		if f.id, err = adsapi.NormalizeCustomerID(f.customerID); err != nil {
+			exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", f.customerID)
+		}
+	}
+}
+
+// readQueries reads the --file queries, and binds the --param values
+// into them or into --query.
+func (f *searchFlags) readQueries() {
+	switch {
+	case f.query != "" && f.file != "":
+		usageError("search", "--query and --file cannot be combined")
+	case f.file != "":
+		src, err := readQueryFile(f.file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "I/O error: %v\n\nPath: %s\n", err, f.file)
+			os.Exit(exitcode.IOError)
+		}
+		f.stmts = gaql.SplitStatements(src)
+		if len(f.stmts) == 0 {
+			usageError("search", f.file+" holds no queries")
+		}
+		if len(f.stmts) == 1 {
+			f.query = f.stmts[0].Text
+		}
+	case f.query == "":
+		usageError("search", "--query is required")
+	}
+
+	used := map[string]bool{}
+	if len(f.stmts) > 1 {
+		for i := range f.stmts {
+			f.stmts[i].Text = bindParams(f.stmts[i].Text, f.params, used, f.stmts[i].Label()+": ")
+		}
+	} else {
+		f.query = bindParams(f.query, f.params, used, "")
+	}
+	for name := range f.params {
+		if !used[name] {
+			usageError("search", fmt.Sprintf("--param %s matches no @%s placeholder in the query", name, name))
+		}
+	}
+}
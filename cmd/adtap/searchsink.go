@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aygp-dr/adtap/internal/auth"
+	"github.com/aygp-dr/adtap/internal/export"
+	"github.com/aygp-dr/adtap/internal/export/bigquery"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// searchSink is where search writes its rows: stdout or --output, the
+// --output file of a --checkpoint, a BigQuery table, or a SQLite table.
+type searchSink interface {
+	// renderer returns the renderer the rows go to, with the header
+	// written.
+	renderer() output.Renderer
+
+	// finish is called once the rows are flushed, with complete false
+	// when the search was cut short, and says where they went.
+	finish(complete bool)
+}
+
+// newSearchSink returns the sink the flags ask for, with the header of
+// the columns of fields written: r, which renders to stdout or --output,
+// unless the rows go elsewhere. Tables keep IDs and API enum values, so
+// opts is adjusted for them.
+func (f *searchFlags) newSearchSink(ctx context.Context, q *gaql.Query, format output.Format, fields []string, r output.Renderer, opts *output.Options, ckpt *searchCheckpoint) searchSink {
+	if f.toBigQuery != "" || f.toSQLite != "" {
+		// The table keeps IDs, not constant names, in its INTEGER columns,
+		// and API enum values unless labels are asked for.
+		opts.Constants = nil
+		if *f.out.enums == "auto" {
+			opts.RawEnums = true
+		}
+	}
+	var s searchSink
+	switch {
+	case ckpt != nil:
+		return checkpointSink{ckpt, ckpt.renderer(format, opts.Columns(fields))}
+	case f.toBigQuery != "":
+		ts, err := auth.DefaultCredentials(auth.CloudPlatformScope)
+		if err != nil {
+			exitSetupError(configError(err.Error(), "set GOOGLE_APPLICATION_CREDENTIALS or run 'gcloud auth application-default login'."))
+		}
+		// The sink outlives an interrupt so the rows read are inserted.
+		sink := bigquery.NewSink(context.WithoutCancel(ctx), f.table, bigquery.Schema(fields, *opts))
+		sink.Token = ts.Token
+		s = bigQuerySink{timeRenderer(sink, runTimings), sink}
+	case f.toSQLite != "":
+		db, err := export.NewSQLiteWriter(f.toSQLite, cmp.Or(f.sqliteTable, q.From), export.SQLiteSchema(fields, *opts))
+		if err != nil {
+			exitIOError(err)
+		}
+		s = sqliteSink{timeRenderer(db, runTimings), db}
+	default:
+		s = renderedSink{r}
+	}
+	if err := s.renderer().WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	return s
+}
+
+// renderedSink writes rows to stdout or --output in the chosen format,
+// or in an --envelope.
+type renderedSink struct{ r output.Renderer }
+
+func (s renderedSink) renderer() output.Renderer { return s.r }
+func (renderedSink) finish(bool)                 {}
+
+// checkpointSink writes rows to the --output file of a --checkpoint,
+// saving the progress of a search cut short.
+type checkpointSink struct {
+	c *searchCheckpoint
+	r output.Renderer
+}
+
+func (s checkpointSink) renderer() output.Renderer { return s.r }
+func (s checkpointSink) finish(complete bool)      { s.c.finish(s.r, complete) }
+
+// bigQuerySink streams rows into the --to-bigquery table.
+type bigQuerySink struct {
+	r    output.Renderer
+	sink *bigquery.Sink
+}
+
+func (s bigQuerySink) renderer() output.Renderer { return s.r }
+
+func (s bigQuerySink) finish(bool) {
+	fmt.Fprintf(os.Stderr, "Inserted %d rows into %s\n", s.sink.Inserted(), s.sink.Table)
+}
+
+// sqliteSink writes rows into a table of the --to-sqlite database.
+type sqliteSink struct {
+	r  output.Renderer
+	db *export.SQLiteWriter
+}
+
+func (s sqliteSink) renderer() output.Renderer { return s.r }
+
+func (s sqliteSink) finish(bool) {
+	fmt.Fprintf(os.Stderr, "Wrote %d rows to table %s of %s\n", s.db.Rows(), s.db.Table, s.db.Path)
+}
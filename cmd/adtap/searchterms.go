@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/searchterms"
+)
+
+func cmdSearchTerms(args []string) {
+	fs := newFlagSet("search-terms")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	during := fs.String("during", "LAST_30_DAYS", "Date range keyword the metrics cover")
+	minCost := fs.Float64("min-cost", 0, "Only terms that cost at least this much, in the account currency")
+	noConversions := fs.Bool("no-conversions", false, "Only terms without conversions: the negative keyword candidates")
+	level := fs.String("level", searchterms.ByCampaign, "Total terms, and suggest negatives, per "+strings.Join(searchterms.Levels, " or "))
+	campaignID := fs.Int64("campaign-id", 0, "Only terms of this campaign")
+	limit := fs.Int("limit", 0, "Maximum number of terms to show (0 means all)")
+	out := addOutputFlags(fs)
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap search-terms --customer-id ID [--min-cost AMOUNT] [--no-conversions] [--level campaign]")
+		fmt.Fprintln(os.Stderr, "\nShow the search terms that triggered ads, totalled over every ad group of")
+		fmt.Fprintln(os.Stderr, "a campaign (or of the account, with --level account), most expensive")
+		fmt.Fprintln(os.Stderr, "first. Terms without conversions get an exact match negative keyword")
+		fmt.Fprintln(os.Stderr, "suggestion, such as [cheap tents]; terms that are keywords already and")
+		fmt.Fprintln(os.Stderr, "terms excluded already are not suggested. Nothing is changed in the")
+		fmt.Fprintln(os.Stderr, "account: add the negatives you agree with yourself.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if *customerID == "" {
+		usageError("search-terms", "--customer-id is required")
+	}
+	if *minCost < 0 || math.IsNaN(*minCost) {
+		usageError("search-terms", "--min-cost must not be negative")
+	}
+	if *limit < 0 {
+		usageError("search-terms", "--limit must not be negative")
+	}
+	if *level != searchterms.ByCampaign && *level != searchterms.ByAccount {
+		exitValidationError("invalid --level value\n\nExpected: %s\nGot: %s", strings.Join(searchterms.Levels, ", "), *level)
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	dr, err := compose.ParseDuring(*during)
+	if err != nil {
+		usageError("search-terms", fmt.Sprintf("invalid --during %q", *during))
+	}
+
+	q := searchterms.Query(dr, *campaignID)
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+	}
+	format, r, opts := out.renderer()
+
+	resp, err := newClient().Search(context.Background(), id, q.String())
+	if err != nil {
+		exitQueryError(err, q, q.String())
+	}
+	terms, _ := searchterms.Aggregate(resp.Results, *level)
+	terms = searchterms.Filter(terms, int64(math.Round(*minCost*1e6)), *noConversions)
+	if *limit > 0 && len(terms) > *limit {
+		terms = terms[:*limit]
+	}
+
+	printSearchTerms(r, opts, *level, terms)
+	if format == output.FormatTable {
+		var wasted int64
+		n := 0
+		for _, t := range terms {
+			if t.Negative() != "" {
+				wasted += t.CostMicros
+				n++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%d negative keyword candidate(s) of %d term(s), %.2f spent without conversions\n", n, len(terms), float64(wasted)/1e6)
+	}
+}
+
+func printSearchTerms(r output.Renderer, opts output.Options, level string, terms []*searchterms.Term) {
+	// Costs are compared with --min-cost, which is in currency units.
+	opts.Micros = true
+	var fields []string
+	if level == searchterms.ByCampaign {
+		fields = []string{"campaign.id", "campaign.name"}
+	}
+	fields = append(fields, "search_term_view.search_term")
+	if level == searchterms.ByAccount {
+		fields = append(fields, "campaigns")
+	}
+	fields = append(fields, "ad_groups", "metrics.impressions", "metrics.clicks", "metrics.cost_micros", "metrics.conversions", "negative_keyword")
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	for _, t := range terms {
+		var values []any
+		if level == searchterms.ByCampaign {
+			values = []any{t.CampaignID, t.CampaignName}
+		}
+		values = append(values, t.Text)
+		if level == searchterms.ByAccount {
+			values = append(values, t.Campaigns)
+		}
+		values = append(values, t.AdGroups, t.Impressions, t.Clicks, t.CostMicros, t.Conversions, t.Negative())
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
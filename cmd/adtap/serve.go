@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/serve"
+)
+
+func cmdServe(args []string) {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	keyFile := fs.String("api-key-file", "", "File of API keys clients may authenticate with, one per line (adds to ADTAP_API_KEYS)")
+	maxRows := fs.Int("max-rows", 0, "Stop every search after this many rows and mark it truncated (0 means no limit)")
+	maxBytes := fs.Int64("max-bytes", 0, "Stop every search before its rows exceed this many bytes of JSON (0 means no limit)")
+	debugAddr := addDebugFlag(fs)
+	pol := addPolicyFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap serve [--addr ADDR] [--api-key-file FILE] [--policy FILE [--role NAME]]")
+		fmt.Fprintln(os.Stderr, "\nServe read-only endpoints over HTTP:")
+		fmt.Fprintln(os.Stderr, "  POST /validate   validate a GAQL query: {\"query\": \"...\"}")
+		fmt.Fprintln(os.Stderr, "  POST /search     stream the rows of a query as NDJSON: {\"customer_id\": \"...\", \"query\": \"...\"}")
+		fmt.Fprintln(os.Stderr, "  GET  /customers  list the accessible customer IDs")
+		fmt.Fprintln(os.Stderr, "\nClients send one of the API keys as 'Authorization: Bearer KEY' or")
+		fmt.Fprintln(os.Stderr, "'X-API-Key: KEY'. Keys come from ADTAP_API_KEYS (comma-separated) and")
+		fmt.Fprintln(os.Stderr, "--api-key-file; at least one is required.")
+		fmt.Fprintln(os.Stderr, "\nCredentials are read from the environment on the first API call.")
+		fmt.Fprintln(os.Stderr, "With --policy, every query is checked against the access policy, as")
+		fmt.Fprintln(os.Stderr, "'adtap mcp' does. The server speaks plain HTTP; put a TLS proxy in")
+		fmt.Fprintln(os.Stderr, "front of it to serve beyond the local machine.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError("serve", "unexpected arguments")
+	}
+	if *maxRows < 0 || *maxBytes < 0 {
+		usageError("serve", "--max-rows and --max-bytes must not be negative")
+	}
+	keys := readAPIKeys(*keyFile)
+	if len(keys) == 0 {
+		exitSetupError(configError("no API keys are configured", "set ADTAP_API_KEYS or pass --api-key-file."))
+	}
+
+	h := serve.NewHandler(serve.Config{
+		Client: func() (*adsapi.Client, error) {
+			c, err := clientFromEnv()
+			var se *setupError
+			if errors.As(err, &se) {
+				err = fmt.Errorf("%s: %s (hint: %s)", se.category, se.msg, strings.TrimSuffix(se.hint, "."))
+			}
+			return c, err
+		},
+		APIKeys:  keys,
+		Access:   pol.access("serve"),
+		MaxRows:  *maxRows,
+		MaxBytes: *maxBytes,
+		Logger:   slog.Default(),
+	})
+	var requests atomic.Int64
+	expvar.Publish("serve", expvar.Func(func() any { return map[string]any{"requests": requests.Load()} }))
+	counted := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		h.ServeHTTP(w, r)
+	})
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		exitSetupError(configError(err.Error(), "pass a free address to --addr, such as localhost:8080."))
+	}
+	if host, _, _ := net.SplitHostPort(*addr); !isLoopback(host) {
+		fmt.Fprintf(os.Stderr, "Warning: %s is reachable from the network over plain HTTP; API keys and results travel unencrypted\n", ln.Addr())
+	}
+	startDebugServer(*debugAddr)
+	fmt.Fprintf(os.Stderr, "Serving on http://%s\n", ln.Addr())
+
+	srv := &http.Server{Handler: counted, ReadHeaderTimeout: 10 * time.Second}
+	ctx := shutdownContext()
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ln) }()
+	select {
+	case err := <-done:
+		fmt.Fprintf(os.Stderr, "I/O error: %v\n", err)
+		os.Exit(exitcode.IOError)
+	case <-ctx.Done():
+	}
+	// Searches in progress get a few seconds to finish.
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdown)
+	exitInterrupted(interrupted(ctx), fmt.Sprintf("answered %d requests", requests.Load()))
+}
+
+// readAPIKeys returns the keys in ADTAP_API_KEYS and, when path is set,
+// those in the file, skipping blank lines and # comments.
+func readAPIKeys(path string) []string {
+	var keys []string
+	for _, k := range strings.Split(os.Getenv("ADTAP_API_KEYS"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if path == "" {
+		return keys
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		exitSetupError(configError(err.Error(), "pass a readable file to --api-key-file."))
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if k := strings.TrimSpace(sc.Text()); k != "" && !strings.HasPrefix(k, "#") {
+			keys = append(keys, k)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		exitSetupError(configError(err.Error(), "pass a readable file to --api-key-file."))
+	}
+	return keys
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+)
+
+// interruptError is the cause of a context canceled by a signal.
+type interruptError struct {
+	sig os.Signal
+}
+
+func (e *interruptError) Error() string {
+	return "interrupted by " + signalName(e.sig)
+}
+
+// shutdownContext returns a context canceled by the first SIGINT or
+// SIGTERM, so a long-running command stops reading, flushes its output
+// and sinks, and reports what it finished before calling
+// exitInterrupted. A second signal exits at once.
+func shutdownContext() context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-ch
+		fmt.Fprintf(os.Stderr, "\nReceived %s; finishing up (send it again to quit at once)\n", signalName(sig))
+		cancel(&interruptError{sig})
+		os.Exit(signalExitCode(<-ch))
+	}()
+	return ctx
+}
+
+// interrupted returns the signal that canceled ctx, or nil.
+func interrupted(ctx context.Context) os.Signal {
+	var ie *interruptError
+	if errors.As(context.Cause(ctx), &ie) {
+		return ie.sig
+	}
+	return nil
+}
+
+// exitInterrupted reports that sig cut the command short and exits with
+// its code. what says what was finished, such as "wrote 120 rows".
+func exitInterrupted(sig os.Signal, what string) {
+	fmt.Fprintf(os.Stderr, "Interrupted by %s: %s\n", signalName(sig), what)
+	os.Exit(signalExitCode(sig))
+}
+
+func signalName(sig os.Signal) string {
+	if sig == syscall.SIGTERM {
+		return "SIGTERM"
+	}
+	return "SIGINT"
+}
+
+func signalExitCode(sig os.Signal) int {
+	if sig == syscall.SIGTERM {
+		return exitcode.Terminated
+	}
+	return exitcode.Interrupted
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/snapshot"
+)
+
+func cmdSnapshot(args []string) {
+	fs := newFlagSet("snapshot")
+	customerID := fs.String("customer-id", "", "Customer ID to snapshot (10 digits, no hyphens)")
+	dir := fs.String("out", "", "Directory to write the snapshot to")
+	replace := fs.Bool("replace", false, "Replace an earlier snapshot in --out")
+	showQuery := fs.Bool("show-query", false, "Print the queries to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap snapshot --customer-id ID --out DIR [--replace]")
+		fmt.Fprintln(os.Stderr, "\nWrite the structure of an account, without metrics, to DIR: one JSONL")
+		fmt.Fprintln(os.Stderr, "file each of the account settings, campaign budgets, campaigns, ad")
+		fmt.Fprintln(os.Stderr, "groups, ads, keywords, and conversion actions that are not removed, and")
+		fmt.Fprintln(os.Stderr, "manifest.json with the time, queries, row counts, and checksums. Rows are")
+		fmt.Fprintln(os.Stderr, "ordered by ID with sorted keys, so two snapshots diff line by line.")
+		fmt.Fprintln(os.Stderr, "\nA table whose query fails is left out and its error recorded in the")
+		fmt.Fprintln(os.Stderr, "manifest; the command then exits 4 after writing the others.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	switch {
+	case fs.NArg() > 0:
+		usageError("snapshot", fmt.Sprintf("unexpected argument %q", fs.Arg(0)))
+	case *customerID == "":
+		usageError("snapshot", "--customer-id is required")
+	case *dir == "":
+		usageError("snapshot", "--out is required")
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	if err := snapshot.Prepare(*dir, *replace); errors.Is(err, snapshot.ErrNotEmpty) {
+		hint := "choose a new directory, such as one named by date"
+		if !*replace {
+			hint += ", or pass --replace to overwrite an earlier snapshot"
+		}
+		exitValidationError("%v\n\nHint: %s", err, hint)
+	} else if err != nil {
+		exitIOError(err)
+	}
+
+	ctx := shutdownContext()
+	client := newClient()
+	m := &snapshot.Manifest{CustomerID: id, APIVersion: client.Version(), TakenAt: time.Now().UTC().Truncate(time.Second)}
+	failed := 0
+	for _, t := range compose.SnapshotTables() {
+		query := t.Query.String()
+		if *showQuery {
+			fmt.Fprintln(os.Stderr, query)
+		}
+		table, err := snapshot.WriteTable(*dir, t.Name, query, client.SearchIter(ctx, id, query))
+		m.Tables = append(m.Tables, table)
+		if interrupted(ctx) != nil {
+			break
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", t.Name, err)
+			continue
+		}
+		fmt.Printf("%-24s %6d rows\n", table.File, table.Rows)
+	}
+	if err := m.Write(*dir); err != nil {
+		exitIOError(err)
+	}
+
+	if sig := interrupted(ctx); sig != nil {
+		written := 0
+		for _, t := range m.Tables {
+			if t.Error == "" {
+				written++
+			}
+		}
+		exitInterrupted(sig, fmt.Sprintf("wrote %d tables", written))
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "API error: %d table(s) could not be read; %s records why\n", failed, snapshot.ManifestFile)
+		os.Exit(exitcode.APIError)
+	}
+}
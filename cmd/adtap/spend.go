@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/pacing"
+)
+
+// spendAlert is the JSON object written for each group over the alert
+// threshold.
+type spendAlert struct {
+	Alert      string  `json:"alert"`
+	CustomerID string  `json:"customer_id"`
+	Period     string  `json:"period"`
+	Start      string  `json:"period_start"`
+	End        string  `json:"period_end"`
+	AsOf       string  `json:"as_of"`
+	GroupBy    string  `json:"group_by"`
+	Threshold  float64 `json:"threshold"`
+	pacing.GroupPace
+}
+
+// spendGroupings returns the --group-by values, sorted.
+func spendGroupings() []string {
+	names := make([]string, 0, len(pacing.Groupings))
+	for name := range pacing.Groupings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func cmdSpend(args []string) {
+	groupings := spendGroupings()
+	fs := newFlagSet("spend")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	groupBy := fs.String("group-by", "campaign", "Group spend by "+strings.Join(groupings, ", "))
+	period := fs.String("period", "MTD", "Period to date: "+strings.Join(pacing.Periods, ", "))
+	threshold := fs.Float64("alert-threshold", 0, "Alert on groups projected to spend at least this fraction of their period budget (e.g. 0.9); exits 8 when any do")
+	out := addOutputFlags(fs)
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap spend --customer-id ID [--group-by campaign] [--period MTD] [--alert-threshold RATIO]")
+		fmt.Fprintln(os.Stderr, "\nShow spend so far in a week, month, quarter, or year against the daily")
+		fmt.Fprintln(os.Stderr, "budgets of the campaigns grouped, and the spend projected for the whole")
+		fmt.Fprintln(os.Stderr, "period. Periods follow the account time zone; campaigns without a daily")
+		fmt.Fprintln(os.Stderr, "budget are left out. With --alert-threshold, print a JSON line for each")
+		fmt.Fprintln(os.Stderr, "group projected at or over the threshold and exit 8 if there are any.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+
+	if *customerID == "" {
+		usageError("spend", "--customer-id is required")
+	}
+	if *threshold < 0 {
+		usageError("spend", "--alert-threshold must not be negative")
+	}
+	if _, ok := pacing.Groupings[*groupBy]; !ok {
+		exitValidationError("invalid --group-by value\n\nExpected: %s\nGot: %s", strings.Join(groupings, ", "), *groupBy)
+	}
+	if _, err := pacing.ParsePeriod(*period, time.Now()); err != nil {
+		exitValidationError("invalid --period value\n\nExpected: %s\nGot: %s", strings.Join(pacing.Periods, ", "), *period)
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+
+	ctx := context.Background()
+	client := newClient()
+
+	// The period is the account's, not the local one.
+	resp, err := client.Search(ctx, id, pacing.TimeZoneQuery)
+	if err != nil {
+		exitAPIError(err)
+	}
+	now := time.Now()
+	if loc := pacing.TimeZone(resp.Results); loc != nil {
+		now = now.In(loc)
+	}
+	p, _ := pacing.ParsePeriod(*period, now)
+	q := pacing.SpendQuery(p, now)
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+	}
+
+	resp, err = client.Search(ctx, id, q.String())
+	if err != nil {
+		exitQueryError(err, q, q.String())
+	}
+	groups, _ := pacing.GroupSpend(resp.Results, *groupBy)
+	paces := pacing.ProjectPeriod(groups, p, now)
+
+	if *threshold == 0 {
+		_, r, opts := out.renderer()
+		printSpend(r, opts, *groupBy, paces)
+		return
+	}
+
+	over := pacing.GroupsOver(paces, *threshold)
+	enc := json.NewEncoder(out.writer())
+	for _, gp := range over {
+		alert := spendAlert{
+			Alert:      "spend_pacing",
+			CustomerID: id,
+			Period:     p.Name,
+			Start:      p.Start.Format(time.DateOnly),
+			End:        p.End.Format(time.DateOnly),
+			AsOf:       now.Format(time.DateOnly),
+			GroupBy:    *groupBy,
+			Threshold:  *threshold,
+			GroupPace:  gp,
+		}
+		if err := enc.Encode(alert); err != nil {
+			exitIOError(err)
+		}
+	}
+	if len(over) > 0 {
+		fmt.Fprintf(os.Stderr, "Alert: %d %s group(s) projected at or over %.0f%% of their %s budget\n", len(over), *groupBy, *threshold*100, p.Name)
+		os.Exit(exitcode.Alert)
+	}
+}
+
+func printSpend(r output.Renderer, opts output.Options, groupBy string, paces []pacing.GroupPace) {
+	// Pacing is about money; amounts are always shown in currency units.
+	opts.Micros = true
+	keyField, nameField := pacing.Groupings[groupBy][0], pacing.Groupings[groupBy][1]
+	fields := []string{keyField}
+	if nameField != "" {
+		fields = append(fields, nameField)
+	}
+	fields = append(fields, "campaigns", "daily_budget_micros", "period_budget_micros", "spend_micros", "projected_micros", "projected_ratio")
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	for _, p := range paces {
+		values := []any{p.Key}
+		if nameField != "" {
+			values = append(values, p.Name)
+		}
+		values = append(values,
+			p.Campaigns,
+			p.DailyMicros,
+			p.PeriodMicros,
+			p.SpendMicros,
+			p.ProjectedMicros,
+			math.Round(p.Ratio*100)/100,
+		)
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/geo"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+	"github.com/aygp-dr/adtap/internal/rowtransform"
+)
+
+func cmdTemplate(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		templateUsage()
+		os.Exit(0)
+	}
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			usageError("template", "list takes no arguments")
+		}
+		listTemplates(compose.Templates)
+	case "run":
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			usageError("template", "run needs a template name; see 'adtap template list'")
+		}
+		t, ok := compose.LookupTemplate(args[1])
+		if !ok {
+			usageError("template", fmt.Sprintf("unknown template %q; see 'adtap template list'", args[1]))
+		}
+		runTemplate("template run", t, args[2:])
+	default:
+		usageError("template", fmt.Sprintf("unknown subcommand %q (expected list or run)", args[0]))
+	}
+}
+
+func templateUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap template list")
+	fmt.Fprintln(os.Stderr, "       adtap template run NAME --customer-id ID [flags]")
+	fmt.Fprintln(os.Stderr, "\nRun a built-in query template. Each template declares typed parameters,")
+	fmt.Fprintln(os.Stderr, "which become its flags here and its arguments in 'adtap mcp'.")
+	fmt.Fprintln(os.Stderr, "Run 'adtap template run NAME --help' for a template's flags.")
+}
+
+func listTemplates(templates []*compose.Template) {
+	for _, t := range templates {
+		fmt.Printf("%s\n  %s\n", t.Name, t.Description)
+		for _, p := range t.Params {
+			line := fmt.Sprintf("  --%s (%s)", p.FlagName(), p.Type)
+			switch {
+			case p.Required:
+				line += " required"
+			case p.Default != "":
+				line += " default " + p.Default
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+}
+
+// runTemplate runs t with arguments from args, for command, such as
+// "template run" or "report".
+func runTemplate(command string, t *compose.Template, args []string) {
+	cmd, _, _ := strings.Cut(command, " ")
+	fs := newFlagSet(command)
+	parseArgs := t.Flags(fs)
+	var last *string
+	if p, ok := t.Param("date_range"); ok && p.Type == compose.ParamDateRange {
+		last = fs.String("last", "", "Trailing period ending yesterday, such as 30d or 4w, instead of --date-range")
+	}
+	concurrency := fs.Int("concurrency", adsapi.DefaultConcurrency, "Accounts queried at once when several customer IDs are given")
+	out := addOutputFlags(fs)
+	if cmd == "report" {
+		// Reports are read by people: amounts in currency units unless
+		// --micros-to-currency=false asks for micros.
+		fs.Lookup("micros-to-currency").DefValue = "true"
+		fs.Set("micros-to-currency", "true")
+	}
+	humanize := fs.Bool("humanize", false, "Show amounts in micros as decimals with the account's currency code, and enum numbers as names")
+	currency := addCurrencyFlags(fs, cmd)
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: adtap %s %s [flags]\n", command, t.Name)
+		fmt.Fprintf(os.Stderr, "\n%s.\n", t.Description)
+		printFlags(fs)
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError(cmd, fmt.Sprintf("unexpected argument %q", fs.Arg(0)))
+	}
+	for _, p := range t.Params {
+		f := fs.Lookup(p.FlagName())
+		if p.Type == compose.ParamCustomers && f.Value.String() == "" {
+			f.Value.Set(activeProfile().CustomerID)
+		}
+		if p.Required && f.Value.String() == "" {
+			usageError(cmd, fmt.Sprintf("--%s is required", p.FlagName()))
+		}
+	}
+	if *concurrency < 1 {
+		usageError(cmd, "--concurrency must be at least 1")
+	}
+	if last != nil && *last != "" {
+		if flagSet(fs, "date-range") {
+			usageError(cmd, "--last and --date-range are mutually exclusive")
+		}
+		span, err := compose.ParseLast(*last, time.Now())
+		if err != nil {
+			exitValidationError("%v", err)
+		}
+		fs.Set("date-range", span.String())
+	}
+
+	targs, err := parseArgs()
+	if err != nil {
+		exitValidationError("%v", err)
+	}
+	q, err := t.Query(targs)
+	if err != nil {
+		exitValidationError("%v", err)
+	}
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+	}
+	_, r, opts := out.renderer()
+	opts.Constants = geo.Default()
+	if *humanize {
+		opts.Micros = true
+	}
+	conv := currency.converter()
+
+	ids := t.Customers(targs)
+	fields := q.FieldNames()
+	if len(ids) > 1 && !slices.Contains(fields, "customer.id") {
+		fields = append([]string{"customer.id"}, fields...)
+	}
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	ctx := shutdownContext()
+	client := newClient()
+	flat := rowflat.Compile(fields)
+	values := make([]any, len(fields))
+	written := 0
+	// write renders one row, converted from currency from.
+	write := func(row adsapi.Row, from string) {
+		written++
+		if conv != nil {
+			convertRow(ctx, conv, row, fields, from)
+		}
+		flat.Values(row, values)
+		if *humanize {
+			if conv != nil {
+				from = conv.Currency()
+			}
+			rowtransform.Humanize(from).Values(fields, values)
+		}
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+
+	var failed adsapi.AccountErrors
+	if len(ids) == 1 {
+		var from string
+		if conv != nil || *humanize {
+			from = accountCurrency(ctx, client, ids[0])
+		}
+		next := nameGeoTargets(ctx, client, ids[0], fields, client.SearchIter(ctx, ids[0], q.String()))
+		for {
+			row, err := next()
+			if err == adsapi.Done || err != nil && interrupted(ctx) != nil {
+				break
+			}
+			if err != nil {
+				exitQueryError(err, q, q.String())
+			}
+			write(row, from)
+		}
+	} else {
+		results, err := client.SearchAccounts(ctx, ids, q.String(), adsapi.SearchAccountsOptions{Concurrency: *concurrency})
+		errors.As(err, &failed)
+		if len(failed) == len(ids) && interrupted(ctx) == nil {
+			exitQueryError(failed[0].Err, q, q.String())
+		}
+		for _, f := range failed {
+			if interrupted(ctx) != nil && errors.Is(f.Err, context.Canceled) {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", f.CustomerID, f.Err)
+		}
+		fetchAccountGeoTargets(ctx, client, fields, results, nil)
+		for _, res := range results {
+			var from string
+			if (conv != nil || *humanize) && len(res.Rows) > 0 {
+				from = accountCurrency(ctx, client, res.CustomerID)
+			}
+			for _, row := range res.Rows {
+				write(row, from)
+			}
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+
+	if sig := interrupted(ctx); sig != nil {
+		exitInterrupted(sig, fmt.Sprintf("wrote %d rows", written))
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "API error: the query failed for %d account(s); their rows are missing\n", len(failed))
+		os.Exit(exitcode.APIError)
+	}
+}
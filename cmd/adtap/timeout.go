@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+)
+
+// commandTimeout is the --timeout of the command, or ADTAP_TIMEOUT; zero
+// when neither is set. commandDeadline is when it runs out. callTimeout
+// is the --call-timeout of the command; zero leaves ADTAP_CALL_TIMEOUT
+// in charge.
+var (
+	commandTimeout  time.Duration
+	commandDeadline time.Time
+	callTimeout     time.Duration
+)
+
+// newFlagSet returns the flag set of a command, with the flags every
+// command accepts: --timeout, which may also come before the command
+// name, and --call-timeout.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Var(timeoutFlag{}, "timeout", "Fail API requests still running once this `duration` has passed since the command started, such as 10m (0 means none; env ADTAP_TIMEOUT)")
+	fs.Var(callTimeoutFlag{}, "call-timeout", "Retry a single API request still running after this `duration`, such as 2m (env ADTAP_CALL_TIMEOUT; ADTAP_KEEPALIVE sets the TCP keepalive interval)")
+	return fs
+}
+
+// setTimeout sets commandTimeout, and commandDeadline from now.
+func setTimeout(d time.Duration) {
+	commandTimeout, commandDeadline = d, time.Time{}
+	if d > 0 {
+		commandDeadline = time.Now().Add(d)
+	}
+}
+
+// timeoutFlag is the --timeout of a command's flag set.
+type timeoutFlag struct{}
+
+func (timeoutFlag) String() string {
+	if commandTimeout == 0 {
+		return ""
+	}
+	return commandTimeout.String()
+}
+
+func (timeoutFlag) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return errors.New("use a duration such as 90s or 10m; 0 means none")
+	}
+	setTimeout(d)
+	return nil
+}
+
+// callTimeoutFlag is the --call-timeout of a command's flag set.
+type callTimeoutFlag struct{}
+
+func (callTimeoutFlag) String() string {
+	if callTimeout == 0 {
+		return ""
+	}
+	return callTimeout.String()
+}
+
+func (callTimeoutFlag) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return errors.New("use a positive duration, such as 2m")
+	}
+	callTimeout = d
+	return nil
+}
+
+// parseTimeout parses the value of --timeout, or of ADTAP_TIMEOUT when
+// the flag is absent; an empty value means no timeout.
+func parseTimeout(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		fmt.Fprintf(os.Stderr, "Usage error: invalid --timeout %q (use a duration such as 90s or 10m; 0 means none)\n", value)
+		os.Exit(exitcode.UsageError)
+	}
+	return d
+}
+
+// connectionOptions returns the client options bounding requests: the
+// deadline of --timeout, the per-request timeout of --call-timeout or
+// ADTAP_CALL_TIMEOUT, and the TCP keepalive interval of ADTAP_KEEPALIVE.
+// keepalive is false
+// for clients with their own transport, such as the demo. Errors are
+// *setupError.
+func connectionOptions(keepalive bool) ([]adsapi.Option, error) {
+	var opts []adsapi.Option
+	if !commandDeadline.IsZero() {
+		opts = append(opts, adsapi.WithDeadline(commandDeadline))
+	}
+	if callTimeout > 0 {
+		opts = append(opts, adsapi.WithCallTimeout(callTimeout))
+	}
+	for _, s := range []struct {
+		name   string
+		option func(time.Duration) adsapi.Option
+	}{
+		{"ADTAP_CALL_TIMEOUT", adsapi.WithCallTimeout},
+		{"ADTAP_KEEPALIVE", func(d time.Duration) adsapi.Option {
+			k := adsapi.DefaultKeepalive
+			k.Interval = d
+			return adsapi.WithKeepalive(k)
+		}},
+	} {
+		v := os.Getenv(s.name)
+		if v == "" || s.name == "ADTAP_KEEPALIVE" && !keepalive || s.name == "ADTAP_CALL_TIMEOUT" && callTimeout > 0 {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, &setupError{exitcode.ConfigError, "Configuration error", fmt.Sprintf("invalid %s %q", s.name, v),
+				"use a positive duration, such as 30s, or leave it unset."}
+		}
+		opts = append(opts, s.option(d))
+	}
+	return opts, nil
+}
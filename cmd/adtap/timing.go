@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/timing"
+)
+
+// runTimings collects the phase timings reported by --profile-run; nil
+// when the flag is absent.
+var runTimings *timing.Recorder
+
+// reportTimings writes the --profile-run report to stderr.
+func reportTimings() {
+	if runTimings != nil {
+		runTimings.WriteTo(os.Stderr)
+		printLimiterStats()
+	}
+}
+
+// timedRenderer records the time r spends rendering as timing.Format,
+// less the time its writes take, which the timing writer underneath
+// records as timing.Write.
+type timedRenderer struct {
+	r   output.Renderer
+	rec *timing.Recorder
+}
+
+// timedValueRenderer is a timedRenderer for renderers that take typed
+// values.
+type timedValueRenderer struct {
+	timedRenderer
+}
+
+// valueWriter matches the renderers that output.Options.WriteRecord
+// hands typed values to.
+type valueWriter interface {
+	WriteValues(values []any) error
+}
+
+// timeRenderer wraps r to record formatting time in rec. With a nil
+// Recorder it returns r itself.
+func timeRenderer(r output.Renderer, rec *timing.Recorder) output.Renderer {
+	if rec == nil {
+		return r
+	}
+	t := timedRenderer{r, rec}
+	if _, ok := r.(valueWriter); ok {
+		return timedValueRenderer{t}
+	}
+	return t
+}
+
+func (t timedRenderer) time(f func() error) error {
+	written := t.rec.Total(timing.Write)
+	start := time.Now()
+	err := f()
+	t.rec.Add(timing.Format, time.Since(start)-(t.rec.Total(timing.Write)-written))
+	return err
+}
+
+func (t timedRenderer) WriteHeader(columns []string) error {
+	return t.time(func() error { return t.r.WriteHeader(columns) })
+}
+
+func (t timedRenderer) WriteRow(values []string) error {
+	return t.time(func() error { return t.r.WriteRow(values) })
+}
+
+func (t timedRenderer) Flush() error {
+	return t.time(t.r.Flush)
+}
+
+func (t timedValueRenderer) WriteValues(values []any) error {
+	return t.time(func() error { return t.r.(valueWriter).WriteValues(values) })
+}
+
+// summaryWriter and summaryValueWriter match the renderers that
+// output.Options.WriteSummary sets the summary row apart in; the others
+// get it as one more row.
+type (
+	summaryWriter interface {
+		WriteSummary(values []string) error
+	}
+	summaryValueWriter interface {
+		WriteSummaryValues(values []any) error
+	}
+)
+
+func (t timedRenderer) WriteSummary(values []string) error {
+	sw, ok := t.r.(summaryWriter)
+	if !ok {
+		return t.WriteRow(values)
+	}
+	return t.time(func() error { return sw.WriteSummary(values) })
+}
+
+func (t timedValueRenderer) WriteSummaryValues(values []any) error {
+	sw, ok := t.r.(summaryValueWriter)
+	if !ok {
+		return t.WriteValues(values)
+	}
+	return t.time(func() error { return sw.WriteSummaryValues(values) })
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/geo"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+)
+
+func cmdTop(args []string) {
+	fs := newFlagSet("top")
+	customerID := fs.String("customer-id", "", "Customer ID to query (10 digits, no hyphens)")
+	by := fs.String("by", "clicks", "Metric to rank by (clicks, impressions, cost, conversions, ctr, ... or a metrics.* field)")
+	during := fs.String("during", "LAST_7_DAYS", "Date range keyword the metrics cover")
+	limit := fs.Int("limit", 10, "Number of rows to show")
+	out := addOutputFlags(fs)
+	showQuery := fs.Bool("show-query", false, "Print the generated GAQL to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap top campaigns|adgroups|keywords --customer-id ID [flags]")
+		fmt.Fprintln(os.Stderr, "\nRank entities by a metric over a date range.")
+		printFlags(fs)
+	}
+
+	// The entity comes first on the command line; flag parsing stops at
+	// the first non-flag argument.
+	var entity string
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		entity, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+	defaultCustomer(customerID)
+	if entity == "" && fs.NArg() == 1 {
+		entity = fs.Arg(0)
+	} else if fs.NArg() > 0 {
+		usageError("top", fmt.Sprintf("unexpected argument %q", fs.Arg(0)))
+	}
+
+	if entity == "" {
+		usageError("top", "an entity is required (campaigns, adgroups, or keywords)")
+	}
+	if *customerID == "" {
+		usageError("top", "--customer-id is required")
+	}
+	id, err := adsapi.NormalizeCustomerID(*customerID)
+	if err != nil {
+		exitValidationError("invalid customer ID\n\nExpected: 1234567890\nGot: %s", *customerID)
+	}
+	dr, err := compose.ParseDuring(*during)
+	if err != nil {
+		usageError("top", fmt.Sprintf("invalid --during %q", *during))
+	}
+
+	q, err := compose.Top(compose.TopOptions{Entity: entity, By: *by, During: dr, Limit: *limit})
+	if err != nil {
+		usageError("top", err.Error())
+	}
+	if *showQuery {
+		fmt.Fprintln(os.Stderr, q)
+	}
+	_, r, opts := out.renderer()
+	opts.Constants = geo.Default()
+
+	resp, err := newClient().Search(context.Background(), id, q.String())
+	if err != nil {
+		exitAPIError(err)
+	}
+
+	fields := append([]string{"#"}, q.FieldNames()...)
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		exitIOError(err)
+	}
+	values := make([]any, len(fields))
+	for i, row := range resp.Results {
+		values[0] = i + 1
+		rowflat.Values(row, fields[1:], values[1:])
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/exitcode"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func cmdTranslate(args []string) {
+	fs := newFlagSet("translate")
+	format := fs.String("format", "human", "Output format: human, json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: adtap translate [--format human|json] SQL|-")
+		fmt.Fprintln(os.Stderr, "\nTranslate a SQL SELECT statement into GAQL on a best-effort basis:")
+		fmt.Fprintln(os.Stderr, "unqualified columns become fields of the FROM resource, metrics, or")
+		fmt.Fprintln(os.Stderr, "segments, <> becomes !=, and SUM(x) becomes x. Constructs GAQL cannot")
+		fmt.Fprintln(os.Stderr, "express, such as OR and OFFSET, are listed on stderr. - reads stdin.")
+		fmt.Fprintln(os.Stderr, "\nExits 9 when a WHERE or HAVING condition was left out, since the query")
+		fmt.Fprintln(os.Stderr, "then matches rows the SQL would not.")
+		printFlags(fs)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		usageError("translate", "a SQL statement is required (- reads it from stdin)")
+	}
+	if *format != "human" && *format != "json" {
+		exitValidationError("invalid output format %q\n\nExpected: human, json", *format)
+	}
+	sql := strings.Join(fs.Args(), " ")
+	if sql == "-" {
+		src, err := readQueryFile("-")
+		if err != nil {
+			exitIOError(err)
+		}
+		sql = src
+	}
+
+	t, err := gaql.FromSQL(sql)
+	if err != nil {
+		exitValidationError("%v", strings.TrimPrefix(err.Error(), "gaql: "))
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(t); err != nil {
+			exitIOError(err)
+		}
+	} else {
+		fmt.Println(t.Query)
+		if len(t.Untranslated) > 0 {
+			fmt.Fprintln(os.Stderr, "\nNot translated:")
+			for _, u := range t.Untranslated {
+				fmt.Fprintf(os.Stderr, "  %s\n", u)
+			}
+		}
+		if _, err := gaql.ValidateQuery(t.Query); err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: the translation does not validate: %v\n", strings.TrimPrefix(err.Error(), "gaql: "))
+		}
+	}
+	if t.DroppedFilter() {
+		os.Exit(exitcode.Incomplete)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/config"
+	"github.com/aygp-dr/adtap/internal/exitcode"
+)
+
+func cmdView(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		viewUsage()
+		os.Exit(0)
+	}
+	path := config.DefaultPath()
+	cfg, err := config.Load(path)
+	if err != nil {
+		exitSetupError(configError(err.Error(), "fix or remove "+path+"."))
+	}
+
+	switch sub, rest := args[0], args[1:]; sub {
+	case "list":
+		if len(rest) > 0 {
+			usageError("view", "list takes no arguments")
+		}
+		if len(cfg.Views) == 0 {
+			fmt.Printf("# no views; add a [views.NAME] table to %s\n", path)
+			return
+		}
+		for _, name := range cfg.ViewNames() {
+			v := cfg.Views[name]
+			fmt.Println(name)
+			if v.Description != "" {
+				fmt.Printf("  %s\n", v.Description)
+			}
+		}
+	case "show", "run":
+		if len(rest) == 0 || strings.HasPrefix(rest[0], "-") {
+			usageError("view", sub+" needs a view name; see 'adtap view list'")
+		}
+		if sub == "show" && len(rest) > 1 {
+			usageError("view", fmt.Sprintf("unexpected argument %q", rest[1]))
+		}
+		v, err := cfg.View(rest[0])
+		if err != nil {
+			usageError("view", fmt.Sprintf("unknown view %q; see 'adtap view list'", rest[0]))
+		}
+		// The view's profile applies unless --profile or ADTAP_PROFILE
+		// chose one.
+		if profileName == "" {
+			profileName = v.Profile
+		}
+		searchArgs, err := viewArgs(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Configuration error: view %s: %v\n\nHint: check [views.%s] in %s.\n",
+				rest[0], strings.TrimPrefix(err.Error(), "config: "), rest[0], path)
+			os.Exit(exitcode.ConfigError)
+		}
+		if sub == "show" {
+			fmt.Println(shellJoin(append([]string{name, "search"}, searchArgs...)))
+			return
+		}
+		// Flags after the name override the view's.
+		cmdSearch(append(searchArgs, rest[1:]...))
+	default:
+		usageError("view", fmt.Sprintf("unknown subcommand %q (expected list, show, or run)", sub))
+	}
+}
+
+func viewUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: adtap view list")
+	fmt.Fprintln(os.Stderr, "       adtap view show NAME")
+	fmt.Fprintln(os.Stderr, "       adtap view run NAME [search flags]")
+	fmt.Fprintln(os.Stderr, "\nRun a view: a saved query, query file, or template bundled with its")
+	fmt.Fprintln(os.Stderr, "account, output format, column names, and destination, declared as a")
+	fmt.Fprintln(os.Stderr, "[views.NAME] table in "+config.DefaultPath()+".")
+	fmt.Fprintln(os.Stderr, "show prints the equivalent 'adtap search' command; flags given to run")
+	fmt.Fprintln(os.Stderr, "after the name override the view's settings.")
+}
+
+// viewArgs returns the adtap search arguments that run v. A template
+// view is expanded to its query here, so every search flag applies.
+func viewArgs(v *config.View) ([]string, error) {
+	source, err := v.Source()
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	customerID := v.CustomerID
+	switch source {
+	case "query":
+		args = append(args, "--query", v.Query)
+	case "query_file":
+		args = append(args, "--file", v.QueryFile)
+	case "template":
+		t, ok := compose.LookupTemplate(v.Template)
+		if !ok {
+			return nil, fmt.Errorf("unknown template %q; see 'adtap template list'", v.Template)
+		}
+		raw := map[string]string{}
+		for name, value := range v.Params {
+			raw[name] = value
+		}
+		for _, p := range t.Params {
+			if _, set := raw[p.Name]; p.Type == compose.ParamCustomers && !set {
+				if id := cmp.Or(customerID, activeProfile().CustomerID); id != "" {
+					raw[p.Name] = id
+				}
+			}
+		}
+		targs, err := t.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		q, err := t.Query(targs)
+		if err != nil {
+			return nil, err
+		}
+		ids := t.Customers(targs)
+		if len(ids) > 1 {
+			return nil, fmt.Errorf("template views run against one customer, not %d", len(ids))
+		}
+		if len(ids) == 1 {
+			customerID = ids[0]
+		}
+		args = append(args, "--query", q.String())
+	}
+	if customerID != "" {
+		args = append(args, "--customer-id", customerID)
+	}
+	if source != "template" {
+		for _, name := range v.ParamNames() {
+			args = append(args, "--param", name+"="+v.Params[name])
+		}
+	}
+
+	for _, s := range []struct{ flag, value string }{
+		{"format", v.Format},
+		{"enums", v.Enums},
+		{"output", v.Output},
+		{"to-bigquery", v.ToBigQuery},
+		{"to-sqlite", v.ToSQLite},
+		{"table", v.Table},
+	} {
+		if s.value != "" {
+			args = append(args, "--"+s.flag, s.value)
+		}
+	}
+	if v.Columns != "" {
+		for _, pair := range strings.Split(v.Columns, ",") {
+			if pair = strings.TrimSpace(pair); pair != "" {
+				args = append(args, "--rename", pair)
+			}
+		}
+	}
+	for _, b := range []struct{ flag, value string }{
+		{"micros-to-currency", v.MicrosToCurrency},
+		{"yes", v.Yes},
+	} {
+		switch b.value {
+		case "", "false":
+		case "true":
+			args = append(args, "--"+b.flag)
+		default:
+			return nil, fmt.Errorf("%s must be true or false, not %q", strings.ReplaceAll(b.flag, "-", "_"), b.value)
+		}
+	}
+	return args, nil
+}
+
+// shellJoin quotes args for a POSIX shell.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		safe := a != ""
+		for _, r := range a {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@%+", r)) {
+				safe = false
+				break
+			}
+		}
+		if safe {
+			quoted[i] = a
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
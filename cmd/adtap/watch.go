@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+	"github.com/aygp-dr/adtap/internal/watch"
+)
+
+// minWatchInterval keeps --watch from spending the daily operations
+// quota in an afternoon.
+const minWatchInterval = 30 * time.Second
+
+// watchSearch runs q against customerID every interval until
+// interrupted. Each run is printed whole, or with diffOnly only its
+// changes after the first, with a leading change column: changed, new,
+// or removed. Human formats show changed metrics with their previous
+// value. A failed run is a warning; the next one is tried all the same.
+func watchSearch(ctx context.Context, client *adsapi.Client, customerID string, q *gaql.Query, interval time.Duration, diffOnly bool, maxRows int, format output.Format, opts output.Options, out *outputFlags) {
+	fields := q.FieldNames()
+	columns := append([]string{"change"}, opts.Columns(fields)...)
+	tracker := watch.NewTracker(fields)
+	for {
+		start := time.Now()
+		rows, err := watchRun(ctx, client, customerID, q, fields, maxRows)
+		if sig := interrupted(ctx); sig != nil {
+			exitInterrupted(sig, fmt.Sprintf("ran the query %d times", tracker.Runs()))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: run failed, retrying in %v: %v\n", start.Format(time.TimeOnly), interval, err)
+		} else {
+			changes := tracker.Update(rows)
+			summary := fmt.Sprintf("%d rows", len(rows))
+			if tracker.Runs() > 1 {
+				summary += watchCounts(changes)
+			}
+			fmt.Fprintf(os.Stderr, "-- %s: %s\n", start.Format(time.TimeOnly), summary)
+			if diffOnly && tracker.Runs() > 1 {
+				changes = watch.Changes(changes)
+			}
+			writeWatchRun(changes, fields, columns, format, opts, out)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Until(start.Add(interval))):
+		}
+	}
+}
+
+// watchRun runs q once and returns the values of its rows in the order
+// of fields.
+func watchRun(ctx context.Context, client *adsapi.Client, customerID string, q *gaql.Query, fields []string, maxRows int) ([][]any, error) {
+	var rows [][]any
+	flat := rowflat.Compile(fields)
+	next := nameGeoTargets(ctx, client, customerID, fields, client.SearchIter(ctx, customerID, q.String()))
+	for maxRows == 0 || len(rows) < maxRows {
+		row, err := next()
+		if err == adsapi.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		values := flat.Values(row, make([]any, len(fields)))
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// watchCounts summarizes the changes of a run, such as ", 2 changed,
+// 1 new".
+func watchCounts(rows []watch.Row) string {
+	counts := map[watch.Status]int{}
+	for _, r := range rows {
+		counts[r.Status]++
+	}
+	s := ""
+	for _, status := range []watch.Status{watch.Changed, watch.Added, watch.Removed} {
+		if counts[status] > 0 {
+			s += fmt.Sprintf(", %d %s", counts[status], status)
+		}
+	}
+	if s == "" {
+		s = ", no changes"
+	}
+	return s
+}
+
+// writeWatchRun renders the rows of one run, with their change column.
+func writeWatchRun(rows []watch.Row, fields, columns []string, format output.Format, opts output.Options, out *outputFlags) {
+	r, _ := output.NewRenderer(runTimings.Writer(out.writer()), format)
+	if err := r.WriteHeader(columns); err != nil {
+		exitIOError(err)
+	}
+	// The change column is no field, so its status is written as is;
+	// so is a changed metric of a human format, formatted already.
+	cellFields := make([]string, len(fields)+1)
+	values := make([]any, len(fields)+1)
+	for _, row := range rows {
+		copy(cellFields[1:], fields)
+		values[0] = string(row.Status)
+		copy(values[1:], row.Values)
+		if format.Human() {
+			for i, f := range fields {
+				if row.FieldChanged(i) {
+					cellFields[i+1] = ""
+					values[i+1] = fmt.Sprintf("%s (was %s)", opts.Cell(f, row.Values[i]), opts.Cell(f, row.Previous[i]))
+				}
+			}
+		}
+		if err := opts.WriteRecord(r, cellFields, values); err != nil {
+			exitIOError(err)
+		}
+	}
+	if err := r.Flush(); err != nil {
+		exitIOError(err)
+	}
+}
@@ -0,0 +1,188 @@
+// Package accounts discovers the Google Ads account hierarchy below
+// manager (MCC) accounts.
+//
+// The customer_client resource of a manager lists the manager itself at
+// level 0 and its direct children at level 1. Tree queries each manager
+// in turn, descending into child managers, so the full hierarchy is
+// built from one small query per manager.
+//
+// # Basic Usage
+//
+//	c := client.WithLogin("1234567890")
+//	root, err := accounts.Tree(ctx, c, "1234567890")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	root.Walk(func(a *accounts.Account, depth int) {
+//		fmt.Printf("%*s%s %s\n", depth*2, "", a.ID, a.Name)
+//	})
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+// MaxDepth bounds how far Tree descends below the root. Google Ads
+// hierarchies are far shallower; the limit only guards against
+// malformed responses.
+const MaxDepth = 20
+
+// clientQuery lists a manager and its direct children.
+const clientQuery = `SELECT customer_client.id, customer_client.descriptive_name, customer_client.currency_code, customer_client.time_zone, customer_client.manager, customer_client.test_account, customer_client.status, customer_client.level FROM customer_client WHERE customer_client.level <= 1`
+
+// Account is a node of the account hierarchy.
+type Account struct {
+	ID           string
+	Name         string
+	CurrencyCode string
+	TimeZone     string
+	Status       string
+	Manager      bool
+	TestAccount  bool
+	Children     []*Account
+
+	// Cycle marks an account that already appears among its own
+	// ancestors. Its children are not expanded again.
+	Cycle bool
+}
+
+// Walk calls fn for a and every descendant, depth first, with the depth
+// below a.
+func (a *Account) Walk(fn func(a *Account, depth int)) {
+	a.walk(fn, 0)
+}
+
+func (a *Account) walk(fn func(*Account, int), depth int) {
+	fn(a, depth)
+	for _, c := range a.Children {
+		c.walk(fn, depth+1)
+	}
+}
+
+// Searcher runs a GAQL query against a customer. *adsapi.Client
+// satisfies it; the client's login-customer-id must be a manager above
+// every account in the tree, usually the root itself.
+type Searcher interface {
+	Search(ctx context.Context, customerID, query string) (*adsapi.SearchResponse, error)
+}
+
+// Tree returns the hierarchy rooted at rootID.
+func Tree(ctx context.Context, s Searcher, rootID string) (*Account, error) {
+	w := &walker{s: s, seen: make(map[string][]*Account)}
+	root := &Account{ID: rootID}
+	if err := w.expand(ctx, root, map[string]bool{}, 0); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+type walker struct {
+	s Searcher
+
+	// seen caches the children of managers already queried, so a manager
+	// linked below several parents is queried only once.
+	seen map[string][]*Account
+}
+
+// expand fills in a's details and children. ancestors holds the IDs on
+// the path from the root to a.
+func (w *walker) expand(ctx context.Context, a *Account, ancestors map[string]bool, depth int) error {
+	if ancestors[a.ID] {
+		a.Cycle = true
+		return nil
+	}
+	if depth > MaxDepth {
+		return fmt.Errorf("accounts: hierarchy below %s is deeper than %d levels", a.ID, MaxDepth)
+	}
+
+	children, ok := w.seen[a.ID]
+	if !ok {
+		resp, err := w.s.Search(ctx, a.ID, clientQuery)
+		if err != nil {
+			return fmt.Errorf("accounts: listing clients of %s: %w", a.ID, err)
+		}
+		for _, row := range resp.Results {
+			c, level := parseClient(row)
+			switch {
+			case level == 0 || c.ID == a.ID:
+				a.fill(c)
+			case level == 1:
+				children = append(children, c)
+			}
+		}
+		sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+		w.seen[a.ID] = children
+	}
+
+	ancestors[a.ID] = true
+	defer delete(ancestors, a.ID)
+	for _, c := range children {
+		child := *c
+		if child.Manager {
+			if err := w.expand(ctx, &child, ancestors, depth+1); err != nil {
+				return err
+			}
+		}
+		a.Children = append(a.Children, &child)
+	}
+	return nil
+}
+
+func (a *Account) fill(from *Account) {
+	a.Name = from.Name
+	a.CurrencyCode = from.CurrencyCode
+	a.TimeZone = from.TimeZone
+	a.Status = from.Status
+	a.Manager = from.Manager
+	a.TestAccount = from.TestAccount
+}
+
+// parseClient reads a customer_client result row.
+func parseClient(row adsapi.Row) (*Account, int) {
+	cc, _ := row["customerClient"].(map[string]any)
+	str := func(key string) string {
+		switch v := cc[key].(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return ""
+		}
+	}
+	level, _ := strconv.Atoi(str("level"))
+	manager, _ := cc["manager"].(bool)
+	test, _ := cc["testAccount"].(bool)
+	return &Account{
+		ID:           str("id"),
+		Name:         str("descriptiveName"),
+		CurrencyCode: str("currencyCode"),
+		TimeZone:     str("timeZone"),
+		Status:       str("status"),
+		Manager:      manager,
+		TestAccount:  test,
+	}, level
+}
+
+// DropNested removes roots that also appear inside another root's tree,
+// as when both a manager and one of its clients are directly accessible.
+func DropNested(roots []*Account) []*Account {
+	inner := make(map[string]bool)
+	for _, r := range roots {
+		for _, c := range r.Children {
+			c.Walk(func(a *Account, _ int) { inner[a.ID] = true })
+		}
+	}
+	var out []*Account
+	for _, r := range roots {
+		if !inner[r.ID] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
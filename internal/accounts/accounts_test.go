@@ -0,0 +1,142 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+// fakeSearcher answers customer_client queries from a parent → children
+// map and counts the queries per customer.
+type fakeSearcher struct {
+	accounts map[string]map[string]any
+	children map[string][]string
+	queries  map[string]int
+	fail     map[string]bool
+}
+
+func (f *fakeSearcher) Search(_ context.Context, customerID, _ string) (*adsapi.SearchResponse, error) {
+	f.queries[customerID]++
+	self, ok := f.accounts[customerID]
+	if !ok || f.fail[customerID] {
+		return nil, errors.New("CUSTOMER_NOT_FOUND")
+	}
+	resp := &adsapi.SearchResponse{Results: []adsapi.Row{row(self, 0)}}
+	for _, id := range f.children[customerID] {
+		resp.Results = append(resp.Results, row(f.accounts[id], 1))
+	}
+	return resp, nil
+}
+
+func row(cc map[string]any, level int) adsapi.Row {
+	c := map[string]any{"level": fmt.Sprint(level)}
+	for k, v := range cc {
+		c[k] = v
+	}
+	return adsapi.Row{"customerClient": c}
+}
+
+func account(id, name string, manager bool) map[string]any {
+	return map[string]any{"id": id, "descriptiveName": name, "manager": manager, "currencyCode": "USD", "timeZone": "America/New_York"}
+}
+
+func newFake() *fakeSearcher {
+	return &fakeSearcher{
+		accounts: map[string]map[string]any{
+			"1000000001": account("1000000001", "Agency", true),
+			"1000000002": account("1000000002", "Sub-MCC", true),
+			"1000000003": account("1000000003", "Client A", false),
+			"1000000004": account("1000000004", "Client B", false),
+		},
+		children: map[string][]string{
+			"1000000001": {"1000000003", "1000000002"},
+			"1000000002": {"1000000004"},
+		},
+		queries: map[string]int{},
+	}
+}
+
+func render(root *Account) string {
+	var lines []string
+	root.Walk(func(a *Account, depth int) {
+		line := strings.Repeat("  ", depth) + a.ID + " " + a.Name
+		if a.Cycle {
+			line += " (cycle)"
+		}
+		lines = append(lines, line)
+	})
+	return strings.Join(lines, "\n")
+}
+
+func TestTree(t *testing.T) {
+	f := newFake()
+	root, err := Tree(context.Background(), f, "1000000001")
+	if err != nil {
+		t.Fatalf("Tree: %v", err)
+	}
+
+	want := "1000000001 Agency\n" +
+		"  1000000002 Sub-MCC\n" +
+		"    1000000004 Client B\n" +
+		"  1000000003 Client A"
+	if got := render(root); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if root.CurrencyCode != "USD" || !root.Manager {
+		t.Errorf("root details not filled in: %+v", root)
+	}
+	if f.queries["1000000003"] != 0 {
+		t.Errorf("non-manager accounts should not be queried")
+	}
+}
+
+func TestTreeCycle(t *testing.T) {
+	f := newFake()
+	f.children["1000000002"] = append(f.children["1000000002"], "1000000001")
+
+	root, err := Tree(context.Background(), f, "1000000001")
+	if err != nil {
+		t.Fatalf("Tree: %v", err)
+	}
+	want := "1000000001 Agency\n" +
+		"  1000000002 Sub-MCC\n" +
+		"    1000000001 Agency (cycle)\n" +
+		"    1000000004 Client B\n" +
+		"  1000000003 Client A"
+	if got := render(root); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if f.queries["1000000001"] != 1 {
+		t.Errorf("expected one query per manager, got %d", f.queries["1000000001"])
+	}
+}
+
+func TestTreeError(t *testing.T) {
+	f := newFake()
+	f.fail = map[string]bool{"1000000002": true}
+
+	_, err := Tree(context.Background(), f, "1000000001")
+	if err == nil || !strings.Contains(err.Error(), "listing clients of 1000000002") {
+		t.Fatalf("expected error from unreachable manager, got %v", err)
+	}
+}
+
+func TestDropNested(t *testing.T) {
+	f := newFake()
+	agency, _ := Tree(context.Background(), f, "1000000001")
+	sub, _ := Tree(context.Background(), f, "1000000002")
+	other := &Account{ID: "2000000000"}
+
+	roots := DropNested([]*Account{sub, agency, other})
+	var ids []string
+	for _, r := range roots {
+		ids = append(ids, r.ID)
+	}
+	if strings.Join(ids, ",") != "1000000001,2000000000" {
+		t.Errorf("unexpected roots %v", ids)
+	}
+}
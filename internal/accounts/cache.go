@@ -0,0 +1,84 @@
+package accounts
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultCachePath returns the file remembering the customers listed by
+// adtap customers: customers in the adtap directory of the user cache
+// directory. Shell completion offers its IDs without calling the API.
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "adtap", "customers")
+}
+
+// LoadCache reads the accounts saved at path, with their IDs and names
+// only. A missing file holds no accounts.
+func LoadCache(path string) ([]*Account, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("accounts: %w", err)
+	}
+	defer f.Close()
+	var accts []*Account
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		id, name, _ := strings.Cut(sc.Text(), "\t")
+		if id != "" {
+			accts = append(accts, &Account{ID: id, Name: name})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("accounts: reading cache: %w", err)
+	}
+	return accts, nil
+}
+
+// SaveCache writes the ID and name of each account to path, one per
+// line, readable only by its owner since names identify clients.
+// Accounts listed twice are saved once, keeping the first name known.
+func SaveCache(path string, accts []*Account) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("accounts: saving cache: %w", err)
+	}
+	names := map[string]string{}
+	var ids []string
+	for _, a := range accts {
+		if _, ok := names[a.ID]; !ok {
+			ids = append(ids, a.ID)
+		}
+		if names[a.ID] == "" {
+			names[a.ID] = strings.NewReplacer("\t", " ", "\n", " ").Replace(a.Name)
+		}
+	}
+	var sb strings.Builder
+	for _, id := range ids {
+		sb.WriteString(id)
+		if names[id] != "" {
+			sb.WriteString("\t" + names[id])
+		}
+		sb.WriteByte('\n')
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o600); err != nil {
+		return fmt.Errorf("accounts: saving cache: %w", err)
+	}
+	return nil
+}
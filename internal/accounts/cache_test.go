@@ -0,0 +1,43 @@
+package accounts
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "adtap", "customers")
+	if accts, err := LoadCache(path); err != nil || accts != nil {
+		t.Fatalf("LoadCache(missing) = %v, %v; want nothing", accts, err)
+	}
+
+	err := SaveCache(path, []*Account{
+		{ID: "1234567890"},
+		{ID: "2345678901", Name: "Brand\tUS"},
+		{ID: "1234567890", Name: "Main"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+		t.Errorf("cache mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	accts, err := LoadCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Account{{ID: "1234567890", Name: "Main"}, {ID: "2345678901", Name: "Brand US"}}
+	if len(accts) != len(want) {
+		t.Fatalf("LoadCache returned %d accounts, want %d", len(accts), len(want))
+	}
+	for i, a := range accts {
+		if a.ID != want[i].ID || a.Name != want[i].Name {
+			t.Errorf("account %d = %s %q, want %s %q", i, a.ID, a.Name, want[i].ID, want[i].Name)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Package adpreview decodes ad_group_ad rows into ads people can read.
+//
+// A responsive search ad arrives as nested JSON: headlines and
+// descriptions are lists of asset objects with pinning, and the policy
+// summary holds enum statuses and a list of topic entries. Printed as
+// columns, that is unreadable. Decode turns a row selecting the
+// compose.AdFields into an Ad, and WriteText lays it out:
+//
+//	Ad 50001 · Responsive Search Ad · Enabled
+//	  Brand - Search › Brand Terms (30001)
+//	  Policy: Approved Limited, Reviewed
+//	    - Trademarks In Ad Text (Limited)
+//	  Headlines:
+//	    1. Demo Outdoor Store  [pinned: Headline 1]
+//	    2. Free Shipping Over $50
+//	  Descriptions:
+//	    1. Tents, packs, and gear for every trail.
+//	  Final URL: https://www.example.com/
+//	  Display URL: www.example.com/tents/backpacking
+package adpreview
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+)
+
+// Ad is a decoded ad_group_ad row. Enum values are API names.
+type Ad struct {
+	Campaign     string        `json:"campaign"`
+	AdGroupID    string        `json:"ad_group_id"`
+	AdGroup      string        `json:"ad_group"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	Status       string        `json:"status"`
+	Headlines    []Asset       `json:"headlines,omitempty"`
+	Descriptions []Asset       `json:"descriptions,omitempty"`
+	Path1        string        `json:"path1,omitempty"`
+	Path2        string        `json:"path2,omitempty"`
+	FinalURLs    []string      `json:"final_urls,omitempty"`
+	Approval     string        `json:"approval_status,omitempty"`
+	Review       string        `json:"review_status,omitempty"`
+	PolicyTopics []PolicyTopic `json:"policy_topics,omitempty"`
+}
+
+// Asset is a headline or description of a responsive search ad.
+type Asset struct {
+	Text string `json:"text"`
+
+	// Pinned is the position the text is pinned to, such as HEADLINE_1,
+	// or empty when it may serve in any position.
+	Pinned string `json:"pinned_field,omitempty"`
+}
+
+// PolicyTopic is a policy finding against an ad, such as
+// TRADEMARKS_IN_AD_TEXT of type LIMITED.
+type PolicyTopic struct {
+	Topic string `json:"topic"`
+	Type  string `json:"type"`
+}
+
+// Decode decodes an ad_group_ad row. Fields the row lacks are left
+// empty.
+func Decode(row map[string]any) Ad {
+	str := func(field string) string {
+		v, _ := rowflat.Lookup(row, field)
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+	enum := func(field string) string {
+		v, _ := rowflat.Lookup(row, field)
+		if v == nil {
+			return ""
+		}
+		return output.EnumName(field, v)
+	}
+	ad := Ad{
+		Campaign:  str("campaign.name"),
+		AdGroupID: str("ad_group.id"),
+		AdGroup:   str("ad_group.name"),
+		ID:        str("ad_group_ad.ad.id"),
+		Type:      enum("ad_group_ad.ad.type"),
+		Status:    enum("ad_group_ad.status"),
+		Path1:     str("ad_group_ad.ad.responsive_search_ad.path1"),
+		Path2:     str("ad_group_ad.ad.responsive_search_ad.path2"),
+		Approval:  enum("ad_group_ad.policy_summary.approval_status"),
+		Review:    enum("ad_group_ad.policy_summary.review_status"),
+	}
+	ad.Headlines = assets(row, "ad_group_ad.ad.responsive_search_ad.headlines")
+	ad.Descriptions = assets(row, "ad_group_ad.ad.responsive_search_ad.descriptions")
+	for _, u := range objects(row, "ad_group_ad.ad.final_urls") {
+		if s, ok := u.(string); ok {
+			ad.FinalURLs = append(ad.FinalURLs, s)
+		}
+	}
+	for _, e := range objects(row, "ad_group_ad.policy_summary.policy_topic_entries") {
+		if m, ok := e.(map[string]any); ok {
+			ad.PolicyTopics = append(ad.PolicyTopics, PolicyTopic{
+				Topic: text(m["topic"]),
+				Type:  text(m["type"]),
+			})
+		}
+	}
+	return ad
+}
+
+func assets(row map[string]any, field string) []Asset {
+	var out []Asset
+	for _, a := range objects(row, field) {
+		if m, ok := a.(map[string]any); ok {
+			out = append(out, Asset{Text: text(m["text"]), Pinned: text(m["pinnedField"])})
+		}
+	}
+	return out
+}
+
+// objects returns the elements of a repeated field.
+func objects(row map[string]any, field string) []any {
+	v, _ := rowflat.Lookup(row, field)
+	items, _ := v.([]any)
+	return items
+}
+
+func text(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// HasPolicyIssues reports whether the ad is not fully approved or has
+// policy findings.
+func (a Ad) HasPolicyIssues() bool {
+	return a.Approval != "" && a.Approval != "APPROVED" || len(a.PolicyTopics) > 0
+}
+
+// DisplayURL returns the URL shown in the ad: the host of the first
+// final URL followed by the display paths.
+func (a Ad) DisplayURL() string {
+	if len(a.FinalURLs) == 0 {
+		return ""
+	}
+	u, err := url.Parse(a.FinalURLs[0])
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	parts := []string{u.Host}
+	for _, p := range []string{a.Path1, a.Path2} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// WriteText writes the ad in the layout the package documentation
+// shows, followed by a blank line.
+func (a Ad) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	head := []string{"Ad " + a.ID}
+	if a.Type != "" {
+		head = append(head, output.EnumLabel("ad_group_ad.ad.type", a.Type))
+	}
+	if a.Status != "" {
+		head = append(head, output.EnumLabel("ad_group_ad.status", a.Status))
+	}
+	fmt.Fprintln(&sb, strings.Join(head, " · "))
+	fmt.Fprintf(&sb, "  %s › %s (%s)\n", a.Campaign, a.AdGroup, a.AdGroupID)
+	if a.Approval != "" {
+		policy := output.EnumLabel("ad_group_ad.policy_summary.approval_status", a.Approval)
+		if a.Review != "" {
+			policy += ", " + output.EnumLabel("ad_group_ad.policy_summary.review_status", a.Review)
+		}
+		fmt.Fprintf(&sb, "  Policy: %s\n", policy)
+	}
+	for _, t := range a.PolicyTopics {
+		fmt.Fprintf(&sb, "    - %s (%s)\n", output.EnumLabel("", t.Topic), output.EnumLabel("", t.Type))
+	}
+	writeAssets(&sb, "Headlines", a.Headlines)
+	writeAssets(&sb, "Descriptions", a.Descriptions)
+	for i, u := range a.FinalURLs {
+		label := "Final URL"
+		if len(a.FinalURLs) > 1 {
+			label = fmt.Sprintf("Final URL %d", i+1)
+		}
+		fmt.Fprintf(&sb, "  %s: %s\n", label, u)
+	}
+	if d := a.DisplayURL(); d != "" {
+		fmt.Fprintf(&sb, "  Display URL: %s\n", d)
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeAssets(sb *strings.Builder, title string, assets []Asset) {
+	if len(assets) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "  %s:\n", title)
+	for i, a := range assets {
+		fmt.Fprintf(sb, "    %d. %s", i+1, a.Text)
+		if a.Pinned != "" {
+			fmt.Fprintf(sb, "  [pinned: %s]", output.EnumLabel("", a.Pinned))
+		}
+		sb.WriteString("\n")
+	}
+}
@@ -0,0 +1,109 @@
+package adpreview
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testRow() map[string]any {
+	return map[string]any{
+		"campaign": map[string]any{"name": "Brand - Search"},
+		"adGroup":  map[string]any{"id": "30001", "name": "Brand Terms"},
+		"adGroupAd": map[string]any{
+			"status": 2.0,
+			"ad": map[string]any{
+				"id":        "50001",
+				"type":      "RESPONSIVE_SEARCH_AD",
+				"finalUrls": []any{"https://www.example.com/"},
+				"responsiveSearchAd": map[string]any{
+					"headlines": []any{
+						map[string]any{"text": "Demo Outdoor Store", "pinnedField": "HEADLINE_1"},
+						map[string]any{"text": "Free Shipping Over $50"},
+					},
+					"descriptions": []any{
+						map[string]any{"text": "Tents, packs, and gear for every trail."},
+					},
+					"path1": "tents",
+					"path2": "backpacking",
+				},
+			},
+			"policySummary": map[string]any{
+				"approvalStatus": "APPROVED_LIMITED",
+				"reviewStatus":   "REVIEWED",
+				"policyTopicEntries": []any{
+					map[string]any{"topic": "TRADEMARKS_IN_AD_TEXT", "type": "LIMITED"},
+				},
+			},
+		},
+	}
+}
+
+func TestDecode(t *testing.T) {
+	got := Decode(testRow())
+	want := Ad{
+		Campaign:     "Brand - Search",
+		AdGroupID:    "30001",
+		AdGroup:      "Brand Terms",
+		ID:           "50001",
+		Type:         "RESPONSIVE_SEARCH_AD",
+		Status:       "ENABLED",
+		Headlines:    []Asset{{"Demo Outdoor Store", "HEADLINE_1"}, {"Free Shipping Over $50", ""}},
+		Descriptions: []Asset{{"Tents, packs, and gear for every trail.", ""}},
+		Path1:        "tents",
+		Path2:        "backpacking",
+		FinalURLs:    []string{"https://www.example.com/"},
+		Approval:     "APPROVED_LIMITED",
+		Review:       "REVIEWED",
+		PolicyTopics: []PolicyTopic{{"TRADEMARKS_IN_AD_TEXT", "LIMITED"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode =\n%+v\nwant\n%+v", got, want)
+	}
+	if !got.HasPolicyIssues() {
+		t.Error("HasPolicyIssues = false for a limited ad")
+	}
+	if (Ad{Approval: "APPROVED"}).HasPolicyIssues() {
+		t.Error("HasPolicyIssues = true for an approved ad")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var sb strings.Builder
+	if err := Decode(testRow()).WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `Ad 50001 · Responsive Search Ad · Enabled
+  Brand - Search › Brand Terms (30001)
+  Policy: Approved Limited, Reviewed
+    - Trademarks In Ad Text (Limited)
+  Headlines:
+    1. Demo Outdoor Store  [pinned: Headline 1]
+    2. Free Shipping Over $50
+  Descriptions:
+    1. Tents, packs, and gear for every trail.
+  Final URL: https://www.example.com/
+  Display URL: www.example.com/tents/backpacking
+
+`
+	if sb.String() != want {
+		t.Errorf("WriteText =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestDisplayURL(t *testing.T) {
+	tests := []struct {
+		ad   Ad
+		want string
+	}{
+		{Ad{FinalURLs: []string{"https://shop.example.com/a?b=c"}, Path1: "sale"}, "shop.example.com/sale"},
+		{Ad{FinalURLs: []string{"https://example.com"}}, "example.com"},
+		{Ad{FinalURLs: []string{"not a url"}}, ""},
+		{Ad{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.ad.DisplayURL(); got != tt.want {
+			t.Errorf("DisplayURL(%v) = %q, want %q", tt.ad.FinalURLs, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Package adsapitest helps test code that uses adsapi.Client without
+// credentials or network access.
+//
+// A Server is a fake GoogleAdsService: it answers googleAds:search with
+// the rows or error registered for each query, paging them as the API
+// does, and records the requests it receives. The client talks to it
+// over REST like it talks to the API, so hooks, retries, pagination,
+// and error decoding run as they do in production.
+//
+// Recorder and Replayer save real responses as fixture files and serve
+// them back, for tests of whole commands against data captured once.
+// Fixtures hold the request path and body and the response; headers,
+// and so the access and developer tokens, are never saved.
+//
+// # Basic Usage
+//
+//	srv := adsapitest.NewServer()
+//	defer srv.Close()
+//	srv.AddRows("SELECT campaign.id FROM campaign",
+//		adsapi.Row{"campaign": map[string]any{"id": "1"}})
+//	client := srv.Client()
+//	resp, err := client.Search(ctx, "1234567890", "SELECT campaign.id FROM campaign")
+//
+// Replaying fixtures recorded with Recorder:
+//
+//	client := adsapi.New("test", adsapi.StaticToken("test"),
+//		adsapi.WithHTTPClient(&http.Client{Transport: adsapitest.Replayer("testdata/fixtures")}))
+package adsapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/cache"
+)
+
+// Request is a search request received by a Server.
+type Request struct {
+	CustomerID      string
+	Query           string
+	PageToken       string
+	LoginCustomerID string
+}
+
+// Server is a fake GoogleAdsService listening on a local port. Queries
+// are matched after formatting, so whitespace and keyword case do not
+// matter.
+type Server struct {
+	// URL is the endpoint to give adsapi.WithEndpoint.
+	URL string
+
+	// PageSize is the number of rows per page; 0 returns every row in
+	// one page.
+	PageSize int
+
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]response
+	requests  []Request
+}
+
+type response struct {
+	rows []adsapi.Row
+	err  *Error
+}
+
+// Error is an error the Server answers a query with, shaped like a
+// GoogleAdsFailure.
+type Error struct {
+	Status   int    // the HTTP status, such as 400 or 429
+	Category string // the errorCode category, such as queryError
+	Code     string // the code within the category, such as UNRECOGNIZED_FIELD
+	Message  string
+}
+
+// NewServer starts a Server. Close it when done.
+func NewServer() *Server {
+	s := &Server{responses: map[string]response{}}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a client of the server, with opts applied after the
+// endpoint. It does not retry, so a registered error is returned at
+// once; pass adsapi.WithRetry to test retries.
+func (s *Server) Client(opts ...adsapi.Option) *adsapi.Client {
+	opts = append([]adsapi.Option{adsapi.WithEndpoint(s.URL), adsapi.WithRetry(adsapi.NoRetry)}, opts...)
+	return adsapi.New("test-developer-token", adsapi.StaticToken("test-access-token"), opts...)
+}
+
+// AddRows answers query with rows, for every customer.
+func (s *Server) AddRows(query string, rows ...adsapi.Row) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[cache.Canonical(query)] = response{rows: rows}
+}
+
+// AddError answers query with err, for every customer.
+func (s *Server) AddError(query string, err Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[cache.Canonical(query)] = response{err: &err}
+}
+
+// Requests returns the search requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// Paths are /{version}/customers/{id}/googleAds:search.
+	_, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	id, ok := strings.CutPrefix(path, "customers/")
+	if id, ok = strings.CutSuffix(id, "/googleAds:search"); !ok || r.Method != http.MethodPost {
+		writeError(w, Error{http.StatusNotImplemented, "", "",
+			fmt.Sprintf("adsapitest: %s %s is not served; only googleAds:search is", r.Method, r.URL.Path)})
+		return
+	}
+	var req struct {
+		Query     string `json:"query"`
+		PageToken string `json:"pageToken"`
+	}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		writeError(w, Error{http.StatusBadRequest, "", "", "Invalid JSON payload: " + err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{id, req.Query, req.PageToken, r.Header.Get("login-customer-id")})
+	resp, ok := s.responses[cache.Canonical(req.Query)]
+	pageSize := s.PageSize
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		writeError(w, Error{http.StatusBadRequest, "queryError", "QUERY_ERROR",
+			fmt.Sprintf("adsapitest: no response registered for query %q", req.Query)})
+		return
+	case resp.err != nil:
+		writeError(w, *resp.err)
+		return
+	}
+
+	offset := 0
+	if req.PageToken != "" {
+		if offset, err = strconv.Atoi(req.PageToken); err != nil || offset < 0 || offset > len(resp.rows) {
+			writeError(w, Error{http.StatusBadRequest, "requestError", "INVALID_PAGE_TOKEN", "Page token is invalid."})
+			return
+		}
+	}
+	end := len(resp.rows)
+	if pageSize > 0 {
+		end = min(offset+pageSize, end)
+	}
+	page := adsapi.SearchResponse{Results: resp.rows[offset:end], RequestID: "test-request"}
+	if page.Results == nil {
+		page.Results = []adsapi.Row{}
+	}
+	if end < len(resp.rows) {
+		page.NextPageToken = strconv.Itoa(end)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("request-id", page.RequestID)
+	json.NewEncoder(w).Encode(page)
+}
+
+// writeError writes e as the API writes errors: a google.rpc.Status
+// with a GoogleAdsFailure detail when e has a category.
+func writeError(w http.ResponseWriter, e Error) {
+	body := map[string]any{"code": e.Status, "message": e.Message, "status": rpcStatus(e.Status)}
+	if e.Category != "" {
+		body["details"] = []any{map[string]any{
+			"@type": "type.googleapis.com/google.ads.googleads.v23.errors.GoogleAdsFailure",
+			"errors": []any{map[string]any{
+				"errorCode": map[string]string{e.Category: e.Code},
+				"message":   e.Message,
+			}},
+			"requestId": "test-request",
+		}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("request-id", "test-request")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(map[string]any{"error": body})
+}
+
+func rpcStatus(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusNotImplemented:
+		return "UNIMPLEMENTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	default:
+		return "INTERNAL"
+	}
+}
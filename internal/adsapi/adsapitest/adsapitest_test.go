@@ -0,0 +1,114 @@
+package adsapitest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+func campaignRow(id string) adsapi.Row {
+	return adsapi.Row{"campaign": map[string]any{"id": id}}
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.PageSize = 2
+	srv.AddRows("SELECT campaign.id FROM campaign", campaignRow("1"), campaignRow("2"), campaignRow("3"))
+	srv.AddError("SELECT campaign.nme FROM campaign", Error{http.StatusBadRequest, "queryError", "UNRECOGNIZED_FIELD", "Unrecognized field in the query: 'campaign.nme'."})
+	c := srv.Client(adsapi.WithLoginCustomerID("1234567890"))
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		query   string
+		rows    int
+		wantErr string
+	}{
+		{"pages", "select campaign.id   from campaign", 3, ""},
+		{"registered error", "SELECT campaign.nme FROM campaign", 0, "queryError.UNRECOGNIZED_FIELD"},
+		{"unregistered query", "SELECT ad_group.id FROM ad_group", 0, "queryError.QUERY_ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := c.SearchIter(ctx, "2345678901", tt.query)
+			rows := 0
+			var err error
+			for {
+				if _, err = next(); err != nil {
+					break
+				}
+				rows++
+			}
+			if tt.wantErr == "" && err != adsapi.Done {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got error %v, want %s", err, tt.wantErr)
+			}
+			if rows != tt.rows {
+				t.Errorf("got %d rows, want %d", rows, tt.rows)
+			}
+		})
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 4 {
+		t.Fatalf("got %d requests, want 4: %+v", len(reqs), reqs)
+	}
+	if r := reqs[1]; r.PageToken != "2" || r.CustomerID != "2345678901" || r.LoginCustomerID != "1234567890" {
+		t.Errorf("second request = %+v", r)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := NewServer()
+	srv.AddRows("SELECT campaign.id FROM campaign", campaignRow("1"))
+	dir := t.TempDir()
+	rec := srv.Client(adsapi.WithHTTPClient(&http.Client{Transport: Recorder(dir, nil)}))
+	ctx := context.Background()
+	if _, err := rec.Search(ctx, "2345678901", "SELECT campaign.id FROM campaign"); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("recorded %d fixtures, want 1", len(files))
+	}
+
+	replay := adsapi.New("other-token", adsapi.StaticToken("other"), adsapi.WithEndpoint("http://replay.invalid"),
+		adsapi.WithRetry(adsapi.NoRetry), adsapi.WithHTTPClient(&http.Client{Transport: Replayer(dir)}))
+	resp, err := replay.Search(ctx, "2345678901", "SELECT campaign.id FROM campaign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 || resp.RequestID != "test-request" {
+		t.Errorf("replayed %+v", resp)
+	}
+
+	_, err = replay.Search(ctx, "2345678901", "SELECT campaign.name FROM campaign")
+	var apiErr *adsapi.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotImplemented {
+		t.Errorf("missing fixture: got %v, want a 501 APIError", err)
+	}
+}
+
+func TestFixtureName(t *testing.T) {
+	a := FixtureName("POST", "/v23/customers/1234567890/googleAds:search", []byte(`{"query": "q", "pageToken": "2"}`))
+	b := FixtureName("POST", "/v23/customers/1234567890/googleAds:search", []byte(`{"pageToken":"2","query":"q"}`))
+	if a != b {
+		t.Errorf("key order changed the name: %s, %s", a, b)
+	}
+	if c := FixtureName("POST", "/v23/customers/1234567890/googleAds:search", []byte(`{"query": "q"}`)); c == a {
+		t.Errorf("different bodies share the name %s", c)
+	}
+	if want := "googleAds-search-"; a[:len(want)] != want {
+		t.Errorf("name %s does not start with %s", a, want)
+	}
+}
@@ -0,0 +1,172 @@
+package adsapitest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Fixture is a request and its response, saved by Recorder as a JSON
+// file named by FixtureName.
+type Fixture struct {
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Request   json.RawMessage `json:"request,omitempty"`
+	Status    int             `json:"status"`
+	RequestID string          `json:"request_id,omitempty"`
+	Response  json.RawMessage `json:"response"`
+}
+
+// FixtureName returns the file name of the fixture of a request: the
+// last element of the path followed by a hash of the method, path, and
+// body. Bodies are compared as JSON, so key order does not matter.
+func FixtureName(method, path string, body []byte) string {
+	if v := any(nil); json.Unmarshal(body, &v) == nil {
+		body, _ = json.Marshal(v)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	base := strings.NewReplacer(":", "-", "/", "-").Replace(path[strings.LastIndex(path, "/")+1:])
+	return base + "-" + hex.EncodeToString(h.Sum(nil))[:16] + ".json"
+}
+
+// Recorder returns a RoundTripper that sends requests through next, or
+// http.DefaultTransport when next is nil, and saves each JSON response
+// to dir as a Fixture, replacing an earlier one of the same request.
+func Recorder(dir string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return recorder{dir, next}
+}
+
+type recorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (rec recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rec.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readResponse(resp)
+	if err != nil || !json.Valid(data) {
+		return resp, err
+	}
+	f := Fixture{
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		Status:    resp.StatusCode,
+		RequestID: resp.Header.Get("request-id"),
+		Response:  data,
+	}
+	if json.Valid(body) {
+		f.Request = body
+	}
+	out, err := json.MarshalIndent(f, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(rec.dir, 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(rec.dir, FixtureName(req.Method, req.URL.Path, body)), append(out, '\n'), 0o644)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("adsapitest: recording %s: %w", req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+// Replayer returns a RoundTripper that answers requests with the
+// fixtures in dir. A request without a fixture gets a 501 UNIMPLEMENTED
+// error naming the file it looked for, which the client does not retry.
+func Replayer(dir string) http.RoundTripper {
+	return replayer{dir}
+}
+
+type replayer struct {
+	dir string
+}
+
+func (rp replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	name := FixtureName(req.Method, req.URL.Path, body)
+	var f Fixture
+	data, err := os.ReadFile(filepath.Join(rp.dir, name))
+	if err == nil {
+		err = json.Unmarshal(data, &f)
+	}
+	if err != nil {
+		f = Fixture{Status: http.StatusNotImplemented}
+		f.Response, _ = json.Marshal(map[string]any{"error": map[string]any{
+			"code":    http.StatusNotImplemented,
+			"message": fmt.Sprintf("adsapitest: no fixture for %s %s in %s (%v)", req.Method, req.URL.Path, rp.dir, err),
+			"status":  "UNIMPLEMENTED",
+		}})
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if f.RequestID != "" {
+		header.Set("request-id", f.RequestID)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(f.Response)),
+		ContentLength: int64(len(f.Response)),
+		Request:       req,
+	}, nil
+}
+
+// readResponse reads the body of resp and replaces it, uncompressed, so
+// the fixture holds JSON and the client can still read it.
+func readResponse(resp *http.Response) ([]byte, error) {
+	var r io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+	}
+	data, err := io.ReadAll(r)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return data, err
+}
+
+// readBody reads the body of req and replaces it, so it can be sent.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
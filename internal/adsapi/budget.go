@@ -0,0 +1,106 @@
+package adsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrTruncated is returned by a search iterator in place of the next row
+// once the client's budget, set by WithMaxRows or WithMaxBytes, is spent
+// and more rows remain. Later calls repeat it.
+var ErrTruncated = errors.New("adsapi: stopped at the row or byte budget")
+
+// WithMaxRows stops every search iteration after n rows (0 means no
+// limit): the iterator returns ErrTruncated instead of row n+1, and
+// SearchAll and SearchAccounts mark their results Truncated. Pages past
+// the budget are not fetched.
+func WithMaxRows(n int) Option {
+	return func(c *Client) { c.maxRows = n }
+}
+
+// WithMaxBytes stops every search iteration before the rows returned
+// exceed n bytes, counted as the JSON the API sent for them (0 means no
+// limit), as WithMaxRows does after a number of rows. It keeps results
+// handed to a model within its context window.
+func WithMaxBytes(n int64) Option {
+	return func(c *Client) { c.maxBytes = n }
+}
+
+// WithBudget returns a copy of the client whose iterations stop at
+// maxRows rows and maxBytes bytes, as set by WithMaxRows and
+// WithMaxBytes; a zero leaves that limit unset. The copy shares
+// everything else, as WithLogin's does.
+func (c *Client) WithBudget(maxRows int, maxBytes int64) *Client {
+	cp := *c
+	cp.hooks = &hookChain{list: c.hooks.load()}
+	cp.maxRows, cp.maxBytes = maxRows, maxBytes
+	return &cp
+}
+
+// budget counts the rows an iteration returned against the client's
+// limits.
+type budget struct {
+	maxRows  int
+	maxBytes int64
+	rows     int
+	bytes    int64
+}
+
+// full reports whether the row budget is spent.
+func (b *budget) full() bool {
+	return b.maxRows > 0 && b.rows >= b.maxRows
+}
+
+// take counts row and reports whether it fits within the budget.
+func (b *budget) take(row Row) bool {
+	if b.maxBytes > 0 {
+		data, err := json.Marshal(row)
+		if err != nil || b.bytes+int64(len(data)) > b.maxBytes {
+			return false
+		}
+		b.bytes += int64(len(data))
+	}
+	b.rows++
+	return true
+}
+
+// Result is every row of a query that fit within the client's budget.
+type Result struct {
+	Rows []Row
+
+	// Truncated is set when the budget stopped the read before the last
+	// row.
+	Truncated bool
+
+	// Metadata describes the requests made for the rows.
+	Metadata SearchMetadata
+}
+
+// SearchAll reads every page of query for customerID, up to the client's
+// budget. A query cut short by the budget is not an error; the result is
+// marked Truncated instead.
+//
+//	c := adsapi.New(token, ts, adsapi.WithMaxRows(1000), adsapi.WithMaxBytes(1<<20))
+//	res, err := c.SearchAll(ctx, "1234567890", query)
+//	if res.Truncated {
+//		// ... tell the reader rows are missing ...
+//	}
+func (c *Client) SearchAll(ctx context.Context, customerID, query string) (*Result, error) {
+	res := new(Result)
+	next, totals := c.SearchIterWithOptions(ctx, customerID, query, SearchOptions{})
+	defer func() { res.Metadata = totals.Metadata }()
+	for {
+		row, err := next()
+		switch {
+		case err == Done:
+			return res, nil
+		case err == ErrTruncated:
+			res.Truncated = true
+			return res, nil
+		case err != nil:
+			return res, err
+		}
+		res.Rows = append(res.Rows, row)
+	}
+}
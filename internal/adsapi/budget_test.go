@@ -0,0 +1,79 @@
+package adsapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchAllBudget(t *testing.T) {
+	// Each row is 23 bytes of JSON: {"campaign":{"id":"1"}}.
+	pages := map[string]string{
+		"":       `{"results": [{"campaign": {"id": "1"}}, {"campaign": {"id": "2"}}], "nextPageToken": "page-2"}`,
+		"page-2": `{"results": [{"campaign": {"id": "3"}}]}`,
+	}
+	tests := []struct {
+		name          string
+		opts          []Option
+		wantRows      int
+		wantTruncated bool
+		wantPages     int
+	}{
+		{"no budget", nil, 3, false, 2},
+		{"row budget ends at a page", []Option{WithMaxRows(2)}, 2, true, 1},
+		{"row budget inside a page", []Option{WithMaxRows(1)}, 1, true, 1},
+		{"row budget fits", []Option{WithMaxRows(3)}, 3, false, 2},
+		{"row budget above", []Option{WithMaxRows(10)}, 3, false, 2},
+		{"byte budget", []Option{WithMaxBytes(50)}, 2, true, 2},
+		{"byte budget fits", []Option{WithMaxBytes(69)}, 3, false, 2},
+		{"byte budget below a row", []Option{WithMaxBytes(10)}, 0, true, 1},
+		{"both budgets", []Option{WithMaxRows(1), WithMaxBytes(50)}, 1, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					PageToken string `json:"pageToken"`
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				fetched++
+				w.Write([]byte(pages[req.PageToken]))
+			}))
+			defer srv.Close()
+
+			c := New("dev-token", StaticToken("access-token"), append([]Option{WithEndpoint(srv.URL)}, tt.opts...)...)
+			res, err := c.SearchAll(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Rows) != tt.wantRows || res.Truncated != tt.wantTruncated {
+				t.Errorf("got %d rows, truncated %v; want %d, %v", len(res.Rows), res.Truncated, tt.wantRows, tt.wantTruncated)
+			}
+			if fetched != tt.wantPages {
+				t.Errorf("fetched %d pages, want %d", fetched, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestSearchIterTruncated(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusOK, `{"results": [{"campaign": {"id": "1"}}, {"campaign": {"id": "2"}}]}`)
+	next := c.WithBudget(1, 0).SearchIter(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+	if _, err := next(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := next(); err != ErrTruncated {
+			t.Errorf("call %d: got %v, want ErrTruncated", i+2, err)
+		}
+	}
+
+	// The budget belongs to the copy.
+	res, err := c.SearchAll(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+	if err != nil || len(res.Rows) != 2 || res.Truncated {
+		t.Errorf("original client: %d rows, truncated %v, err %v", len(res.Rows), res.Truncated, err)
+	}
+}
@@ -0,0 +1,56 @@
+package adsapi
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/cache"
+)
+
+// maxCachedBytes bounds the results SearchIter keeps for the cache;
+// larger results are not cached.
+const maxCachedBytes = 64 << 20
+
+// cacheEntry returns the cache key of query for customerID and the entry
+// its results are saved in. The key is empty when the cache is off or
+// the request would fail before reaching the API.
+func (c *Client) cacheEntry(customerID, query string) (string, *cache.Entry) {
+	if !c.cache.Enabled() {
+		return "", nil
+	}
+	cid, err := NormalizeCustomerID(customerID)
+	if err != nil {
+		return "", nil
+	}
+	query, err = c.prepare(query)
+	if err != nil {
+		return "", nil
+	}
+	// The managers stand in for the login they resolve to, which takes
+	// requests to find.
+	login := c.loginCustomerID
+	if login == "" && c.managers != nil {
+		login = "managers:" + strings.Join(c.managers.managers, ",")
+	}
+	// A linked account reads the customer through its app analytics
+	// link, which shows it differently.
+	entry := &cache.Entry{CustomerID: cid, LoginCustomerID: login, LinkedCustomerID: c.linkedCustomerID, Profile: c.cache.Profile, APIVersion: c.version, Query: cache.Canonical(query)}
+	return cache.Key(cid, login, c.linkedCustomerID, c.cache.Profile, c.version, query), entry
+}
+
+// cachedRows returns the rows cached under key.
+func (c *Client) cachedRows(key string) ([]Row, bool) {
+	if key == "" {
+		return nil, false
+	}
+	e, ok := c.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	var rows []Row
+	if json.Unmarshal(e.Rows, &rows) != nil {
+		return nil, false
+	}
+	c.log().Debug("cache hit", "customer_id", e.CustomerID, "query", e.Query, "rows", len(rows))
+	return rows, true
+}
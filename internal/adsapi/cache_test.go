@@ -0,0 +1,92 @@
+package adsapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/cache"
+)
+
+func TestWithCache(t *testing.T) {
+	pages := map[string]string{
+		"":       `{"results": [{"campaign": {"id": "1"}}], "nextPageToken": "page-2"}`,
+		"page-2": `{"results": [{"campaign": {"id": "2"}}]}`,
+	}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string `json:"query"`
+			PageToken string `json:"pageToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		requests++
+		if strings.Contains(req.Query, "ad_group") {
+			w.Write([]byte(`{"results": [{"adGroup": {"id": "9"}}]}`))
+			return
+		}
+		w.Write([]byte(pages[req.PageToken]))
+	}))
+	defer srv.Close()
+
+	rc := cache.New(t.TempDir(), time.Hour)
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL), WithCache(rc))
+	ctx := context.Background()
+	ids := func(query string) string {
+		var out []string
+		next := c.SearchIter(ctx, "123-456-7890", query)
+		for {
+			row, err := next()
+			if err == Done {
+				return strings.Join(out, ",")
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			out = append(out, row["campaign"].(map[string]any)["id"].(string))
+			// Callers may change rows; the cached copy is unaffected.
+			row["campaign"] = nil
+		}
+	}
+
+	if got := ids("SELECT campaign.id FROM campaign"); got != "1,2" || requests != 2 {
+		t.Fatalf("first run = %q after %d requests", got, requests)
+	}
+	// The same query, written differently, is served from the cache.
+	if got := ids("select campaign.id\n  from campaign;"); got != "1,2" || requests != 2 {
+		t.Errorf("cached run = %q after %d requests, want no new requests", got, requests)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Search(ctx, "1234567890", "SELECT ad_group.id FROM ad_group")
+		if err != nil || len(resp.Results) != 1 {
+			t.Fatalf("Search = %v, %v", resp, err)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("made %d requests, want 3 (the single-page Search is cached)", requests)
+	}
+	// Another manager, or another profile, may see the account
+	// differently, so neither shares the entry.
+	if _, err := c.WithLogin("9876543210").Search(ctx, "1234567890", "SELECT ad_group.id FROM ad_group"); err != nil || requests != 4 {
+		t.Errorf("search through another manager made %d requests, want 4 (err %v)", requests, err)
+	}
+	// Nor does a read through an app analytics link.
+	if _, err := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL), WithCache(rc), WithLinkedCustomerID("555-555-5555")).Search(ctx, "1234567890", "SELECT ad_group.id FROM ad_group"); err != nil || requests != 5 {
+		t.Errorf("search through a linked account made %d requests, want 5 (err %v)", requests, err)
+	}
+	rc.Profile = "other"
+	if _, err := c.Search(ctx, "1234567890", "SELECT ad_group.id FROM ad_group"); err != nil || requests != 6 {
+		t.Errorf("search by another profile made %d requests, want 6 (err %v)", requests, err)
+	}
+	rc.Profile = ""
+
+	rc.Refresh = true
+	if got := ids("SELECT campaign.id FROM campaign"); got != "1,2" || requests != 8 {
+		t.Errorf("refreshed run = %q after %d requests, want 8", got, requests)
+	}
+}
@@ -0,0 +1,616 @@
+// Package adsapi is a read-only client for the Google Ads API.
+//
+// The client talks to the REST interface (gRPC-JSON transcoding) with
+// net/http, so it carries no dependency on generated client stubs.
+// Only search endpoints are exposed; there are no mutate operations.
+//
+// # Basic Usage
+//
+//	c := adsapi.New(developerToken, tokenSource)
+//	resp, err := c.Search(ctx, "1234567890", "SELECT campaign.id FROM campaign")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for _, row := range resp.Results {
+//		fmt.Println(row)
+//	}
+//
+// # Authentication
+//
+// Requests carry an access token from a TokenSource. auth.FromEnvironment
+// returns one for service accounts, authorized user files, and the
+// credentials saved by "adtap auth login"; StaticToken and
+// TokenSourceFunc plug in anything else. When the API answers
+// UNAUTHENTICATED and the source is a TokenInvalidator, as
+// auth.TokenSource is, the rejected token is discarded and the request
+// is sent once more with a new one.
+//
+// # Manager Accounts
+//
+// Accounts below a manager (MCC) are reached with the manager as the
+// login-customer-id. WithLoginCustomerID, or WithLogin on an existing
+// client, sends one manager with every request; WithManagers picks the
+// manager above each customer queried from a list.
+// WithLinkedCustomerID sets the linked-customer-id header:
+//
+//	c := adsapi.New(token, ts, adsapi.WithManagers("1234567890", "3456789012"))
+//	resp, err := c.Search(ctx, "2345678901", query) // via the manager above it
+//
+// # Pagination
+//
+// Search returns one page. SearchIter walks every page, fetching the
+// next one only when the rows already received are used up:
+//
+//	next := c.SearchIter(ctx, "1234567890", query)
+//	for row, err := next(); err != adsapi.Done; row, err = next() {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		fmt.Println(row)
+//	}
+//
+// # Summary Rows
+//
+// SearchOptions asks for the summary row, the selected metrics totalled
+// over every result, and for the number of results ignoring LIMIT.
+// SearchIterWithOptions fills in SearchTotals as the pages arrive:
+//
+//	next, totals := c.SearchIterWithOptions(ctx, "1234567890", query,
+//		adsapi.SearchOptions{ReturnSummaryRow: true, ReturnTotalResultsCount: true})
+//	// ... read rows until Done ...
+//	fmt.Println(totals.TotalResultsCount, totals.SummaryRow)
+//
+// # Retries
+//
+// Quota exhaustion (quotaError.RESOURCE_EXHAUSTED), transient internal
+// errors, and 429/5xx responses are retried with exponential backoff and
+// jitter, waiting at least as long as a Retry-After header or RetryInfo
+// detail asks. Query, authentication, and authorization errors fail at
+// once. WithRetry replaces DefaultRetryPolicy; WithRetry(NoRetry)
+// disables retries.
+//
+// # Timeouts
+//
+// Requests run until their context ends, unless the client bounds them.
+// WithCallTimeout limits each attempt, including reading the response,
+// and retries one that runs over; WithDeadline fails everything still
+// running at a fixed time, which suits batch jobs that must finish.
+// WithKeepalive tunes TCP keepalive and idle connections:
+//
+//	c := adsapi.New(token, ts,
+//		adsapi.WithCallTimeout(2*time.Minute),
+//		adsapi.WithDeadline(time.Now().Add(10*time.Minute)))
+//
+// # Rate Limits
+//
+// A Limiter shared by every client using a developer token keeps batch
+// jobs under the API quotas: a QPS budget for the token, another per
+// customer, and a daily operation cap:
+//
+//	lim := adsapi.NewLimiter(adsapi.RateLimits{QPS: 10, CustomerQPS: 2, DailyOperations: 15000})
+//	c := adsapi.New(token, ts, adsapi.WithRateLimiter(lim))
+//	// ...
+//	fmt.Println(lim.Stats().Wait) // time spent throttled
+//
+// # Query Hooks
+//
+// Hooks rewrite every outgoing query before it is sent, so a deployment
+// can enforce conditions centrally:
+//
+//	c := adsapi.New(token, ts, adsapi.WithQueryHooks(
+//		adsapi.ExcludeRemoved(),
+//		adsapi.CapDateRange(90),
+//	))
+//
+// # Concurrency
+//
+// A Client is safe for concurrent use by multiple goroutines once
+// created, as are Limiter and the hooks of this package. Use and
+// WithLogin may be called at any time: hooks are replaced copy-on-write,
+// so requests in flight are unaffected.
+//
+// # Timing
+//
+// WithRecorder accounts for the time requests spend on tokens,
+// connections, throttling, the server, and reading responses:
+//
+//	rec := timing.NewRecorder()
+//	c := adsapi.New(token, ts, adsapi.WithRecorder(rec))
+//	// ...
+//	rec.WriteTo(os.Stderr)
+//
+// # Observability
+//
+// WithTracerProvider and WithMeterProvider instrument search requests
+// with a span each and with measurements of latency, rows, retries,
+// quota errors, and response bytes, labeled by customer ID and resource.
+// The interfaces mirror OpenTelemetry's, so a thin adapter hands the
+// client a service's existing providers without this package depending
+// on OpenTelemetry.
+//
+// # Logging
+//
+// WithLogger logs requests, responses, retries, cache hits, and pages
+// to a *slog.Logger. Access and developer tokens are redacted to their
+// last four characters.
+//
+// # Caching
+//
+// WithCache serves repeated queries from an on-disk cache.Cache. Search
+// caches results that fit in one page and SearchIter every complete
+// iteration; the key is the query as sent, after hooks, in the canonical
+// form of the gaql formatter:
+//
+//	c := adsapi.New(token, ts, adsapi.WithCache(cache.New(cache.DefaultDir(), cache.DefaultTTL)))
+//
+// # Budgets
+//
+// WithMaxRows and WithMaxBytes stop every iteration once it has returned
+// that many rows or bytes, so a query cannot flood a consumer such as a
+// model's context window. The iterator then returns ErrTruncated, and
+// SearchAll and SearchAccounts mark their results Truncated:
+//
+//	c := adsapi.New(token, ts, adsapi.WithMaxRows(1000), adsapi.WithMaxBytes(1<<20))
+//	res, err := c.SearchAll(ctx, "1234567890", query)
+//	fmt.Println(len(res.Rows), res.Truncated)
+//
+// # Sharding
+//
+// SearchSharded splits a query over a long date range, and optionally
+// over groups of campaigns, into shards run concurrently, and merges
+// their rows in date order:
+//
+//	res, err := c.SearchSharded(ctx, "1234567890", q, adsapi.ShardOptions{Days: 1, CampaignsPerShard: 50, Concurrency: 8})
+//
+// # Request IDs
+//
+// Google Ads API support asks for the request ID of a call that went
+// wrong. An APIError carries the ID of the failed request; for one that
+// succeeded, the Metadata of a Result, an AccountResult, or the
+// SearchTotals of SearchIterWithOptions lists the ID of every page,
+// with the pages and rows read and the time taken:
+//
+//	res, err := c.SearchAll(ctx, "1234567890", query)
+//	fmt.Println(res.Metadata.RequestIDs, res.Metadata.Duration)
+package adsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/cache"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/timing"
+)
+
+const (
+	// DefaultEndpoint is the Google Ads API REST endpoint.
+	DefaultEndpoint = "https://googleads.googleapis.com"
+
+	// DefaultVersion is the API version used when none is configured.
+	DefaultVersion = gaql.DefaultAPIVersion
+)
+
+// TokenSource supplies OAuth2 access tokens for API requests.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// A TokenInvalidator is a TokenSource that can discard a token the API
+// rejected, so that the next Token call fetches a new one.
+type TokenInvalidator interface {
+	Invalidate(token string)
+}
+
+// TokenSourceFunc adapts a function to a TokenSource, so any credential
+// mechanism can supply tokens:
+//
+//	ts := adsapi.TokenSourceFunc(func(ctx context.Context) (string, error) {
+//		tok, err := oauthSource.Token()
+//		if err != nil {
+//			return "", err
+//		}
+//		return tok.AccessToken, nil
+//	})
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token calls f.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticToken is a TokenSource that always returns the same token.
+type StaticToken string
+
+// Token returns the static token.
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Row is a single result row as returned by the REST interface: nested
+// JSON objects keyed by resource (campaign, metrics, segments, ...) with
+// camelCase field names.
+type Row map[string]any
+
+// SearchResponse is one page of GoogleAdsService.Search results.
+type SearchResponse struct {
+	Results           []Row  `json:"results"`
+	NextPageToken     string `json:"nextPageToken"`
+	TotalResultsCount int64  `json:"totalResultsCount,string"`
+	FieldMask         string `json:"fieldMask"`
+	SummaryRow        Row    `json:"summaryRow"`
+	RequestID         string `json:"requestId"`
+}
+
+// Client is a read-only Google Ads API client.
+type Client struct {
+	httpClient       *http.Client
+	endpoint         string
+	version          string
+	developerToken   string
+	loginCustomerID  string
+	linkedCustomerID string
+	managers         *managerLogins
+	tokens           TokenSource
+	hooks            *hookChain
+	retry            RetryPolicy
+	limiter          *Limiter
+	timings          *timing.Recorder
+	cache            *cache.Cache
+	uncompressed     bool
+	maxRows          int
+	maxBytes         int64
+	callTimeout      time.Duration
+	deadline         time.Time
+	tracer           TracerProvider
+	meter            MeterProvider
+	logger           *slog.Logger
+
+	// sleep waits between retries; tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTransportWrapper wraps the transport of the client's HTTP client,
+// as set by the options before it, such as to record the responses.
+func WithTransportWrapper(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(c *Client) {
+		hc := *c.httpClient
+		if hc.Transport == nil {
+			hc.Transport = http.DefaultTransport
+		}
+		hc.Transport = wrap(hc.Transport)
+		c.httpClient = &hc
+	}
+}
+
+// WithEndpoint overrides the API endpoint (useful for tests).
+func WithEndpoint(endpoint string) Option {
+	return func(c *Client) { c.endpoint = strings.TrimRight(endpoint, "/") }
+}
+
+// WithVersion sets the API version (e.g. "v23").
+func WithVersion(version string) Option {
+	return func(c *Client) { c.version = version }
+}
+
+// WithLoginCustomerID sets the manager account used to access child accounts.
+func WithLoginCustomerID(id string) Option {
+	return func(c *Client) { c.loginCustomerID = strings.ReplaceAll(id, "-", "") }
+}
+
+// WithQueryHooks appends hooks applied to every outgoing query.
+func WithQueryHooks(hooks ...QueryHook) Option {
+	return func(c *Client) { c.hooks.add(hooks) }
+}
+
+// WithRecorder records the time spent in each phase of every request
+// in rec.
+func WithRecorder(rec *timing.Recorder) Option {
+	return func(c *Client) { c.timings = rec }
+}
+
+// WithCache serves results from, and saves them to, cache; a nil or
+// disabled cache is ignored.
+func WithCache(cache *cache.Cache) Option {
+	return func(c *Client) { c.cache = cache }
+}
+
+// New creates a client authenticated with the developer token and the
+// access tokens supplied by ts.
+func New(developerToken string, ts TokenSource, opts ...Option) *Client {
+	c := &Client{
+		httpClient:     http.DefaultClient,
+		endpoint:       DefaultEndpoint,
+		version:        DefaultVersion,
+		developerToken: developerToken,
+		tokens:         ts,
+		hooks:          &hookChain{},
+		retry:          DefaultRetryPolicy,
+		sleep:          sleep,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Use appends hooks applied to every outgoing query. It may be called
+// while requests are in flight; they keep the hooks they started with.
+func (c *Client) Use(hooks ...QueryHook) {
+	c.hooks.add(hooks)
+}
+
+// Version returns the API version requests are sent to.
+func (c *Client) Version() string {
+	return c.version
+}
+
+// Search executes a GAQL query and returns the first page of results.
+// Use SearchIter to read every page.
+func (c *Client) Search(ctx context.Context, customerID, query string) (*SearchResponse, error) {
+	key, entry := c.cacheEntry(customerID, query)
+	if rows, ok := c.cachedRows(key); ok {
+		return &SearchResponse{Results: rows}, nil
+	}
+	resp, err := c.search(ctx, customerID, query, "", SearchOptions{}, false)
+	if err == nil && key != "" && resp.NextPageToken == "" {
+		if data, err := json.Marshal(resp.Results); err == nil {
+			entry.Rows = data
+			c.cache.Put(key, entry)
+		}
+	}
+	return resp, err
+}
+
+// Validate asks the API to check query for customerID without running
+// it, through the validate_only option of the search request: the query
+// is parsed and checked against the live schema and the account, and no
+// rows are returned. A nil error means a search would be accepted;
+// rejections are *APIError with the queryError details a search would
+// report. The response carries only the request ID.
+func (c *Client) Validate(ctx context.Context, customerID, query string) (*SearchResponse, error) {
+	return c.search(ctx, customerID, query, "", SearchOptions{}, true)
+}
+
+func (c *Client) search(ctx context.Context, customerID, query, pageToken string, opts SearchOptions, validateOnly bool) (*SearchResponse, error) {
+	cid, err := NormalizeCustomerID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	query, err = c.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]any{"query": query}
+	if pageToken != "" {
+		body["pageToken"] = pageToken
+	}
+	if validateOnly {
+		body["validateOnly"] = true
+	}
+	opts.apply(body)
+
+	c.log().DebugContext(ctx, "search", "customer_id", cid, "query", query, "validate_only", validateOnly)
+	ctx, finish := c.observe(ctx, cid, query)
+	var resp SearchResponse
+	path := fmt.Sprintf("/%s/customers/%s/googleAds:search", c.version, cid)
+	header, err := c.do(ctx, cid, http.MethodPost, path, body, &resp)
+	if err != nil {
+		finish(nil, err)
+		return nil, err
+	}
+	if resp.RequestID == "" {
+		resp.RequestID = header.Get("request-id")
+	}
+	finish(&resp, nil)
+	return &resp, nil
+}
+
+// do sends an authenticated request for customerID and decodes the JSON
+// response into out, retrying transient failures according to the
+// client's policy. Every attempt passes through the rate limiter.
+func (c *Client) do(ctx context.Context, customerID, method, path string, in, out any) (http.Header, error) {
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
+	var data []byte
+	if in != nil {
+		var err error
+		if data, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+	login, err := c.login(ctx, customerID)
+	if err != nil {
+		return nil, c.timeoutError(ctx, err)
+	}
+
+	stats := statsFrom(ctx)
+	reauthorized := false
+	for attempt := 1; ; attempt++ {
+		if c.limiter != nil {
+			stop := c.timings.Start(timing.Wait)
+			err := c.limiter.Wait(ctx, customerID)
+			stop()
+			if err != nil {
+				return nil, c.timeoutError(ctx, err)
+			}
+		}
+		header, err := c.send(ctx, method, path, login, data, out)
+		stats.attempt(err)
+		if !reauthorized && c.reauthorize(err) {
+			// The token expired or was revoked early; one more attempt
+			// with a fresh token, not counted against the policy.
+			reauthorized = true
+			c.log().InfoContext(ctx, "access token rejected; retrying with a new one", "path", path)
+			attempt--
+			continue
+		}
+		if err == nil || attempt >= c.retry.MaxAttempts {
+			return header, c.timeoutError(ctx, err)
+		}
+		ok, serverDelay := retryable(ctx, err)
+		if !ok {
+			return header, c.timeoutError(ctx, err)
+		}
+		if c.retry.MaxBackoff > 0 && serverDelay > c.retry.MaxBackoff {
+			// A quota error can ask for a wait of hours; failing with the
+			// delay beats sleeping through it silently.
+			return header, fmt.Errorf("adsapi: the API asked to retry after %v, longer than the longest backoff (%v): %w", serverDelay, c.retry.MaxBackoff, err)
+		}
+		stats.retry()
+		delay := c.retry.backoff(attempt, serverDelay)
+		c.log().InfoContext(ctx, "retrying request", "path", path, "attempt", attempt, "delay", delay, "error", err)
+		stop := c.timings.Start(timing.Wait)
+		err = c.sleep(ctx, delay)
+		stop()
+		if err != nil {
+			return header, c.timeoutError(ctx, err)
+		}
+	}
+}
+
+// send makes a single attempt at a request, sent with login as the
+// login-customer-id.
+func (c *Client) send(ctx context.Context, method, path, login string, data []byte, out any) (header http.Header, err error) {
+	ctx, cancel := c.withCallTimeout(ctx)
+	defer cancel()
+	defer func() { err = c.timeoutError(ctx, err) }()
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	if c.timings != nil {
+		ctx = httptrace.WithClientTrace(ctx, c.trace())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+	stop := c.timings.Start(timing.Auth)
+	err = c.authorize(ctx, req, login)
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.acceptGzip(req)
+
+	log := c.log()
+	if log.Enabled(ctx, slog.LevelDebug) {
+		log.DebugContext(ctx, "request", "method", method, "url", req.URL.String(), redactedHeaders(req.Header))
+	}
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.DebugContext(ctx, "request failed", "url", req.URL.String(), "error", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	defer c.timings.Start(timing.Stream)()
+
+	buf, wire, err := readResponse(resp)
+	defer releaseBuffer(buf)
+	respData := buf.Bytes()
+	statsFrom(ctx).read(len(respData))
+	log.DebugContext(ctx, "response", "url", req.URL.String(), "status", resp.StatusCode, "bytes", len(respData),
+		"wire_bytes", wire, "duration", time.Since(start), "request_id", resp.Header.Get("request-id"))
+	if err != nil {
+		return resp.Header, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// The error outlives the pooled buffer.
+		apiErr := newAPIError(resp, bytes.Clone(respData))
+		apiErr.token = strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		return resp.Header, apiErr
+	}
+	if out != nil {
+		if err := json.Unmarshal(respData, out); err != nil {
+			return resp.Header, fmt.Errorf("adsapi: decoding response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// trace records the connection and server phases of one request.
+func (c *Client) trace() *httptrace.ClientTrace {
+	var dialed, wrote time.Time
+	return &httptrace.ClientTrace{
+		GetConn: func(string) { dialed = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				c.timings.Add(timing.Connect, time.Since(dialed))
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() {
+			if !wrote.IsZero() {
+				c.timings.Add(timing.Server, time.Since(wrote))
+			}
+		},
+	}
+}
+
+// reauthorize reports whether err is the API rejecting an access token
+// the token source can replace, and discards that token.
+func (c *Client) reauthorize(err error) bool {
+	inv, ok := c.tokens.(TokenInvalidator)
+	var apiErr *APIError
+	if !ok || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.token == "" {
+		return false
+	}
+	inv.Invalidate(apiErr.token)
+	return true
+}
+
+func (c *Client) authorize(ctx context.Context, req *http.Request, login string) error {
+	if c.tokens != nil {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return &tokenError{err}
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("developer-token", c.developerToken)
+	if login != "" {
+		req.Header.Set("login-customer-id", login)
+	}
+	if c.linkedCustomerID != "" {
+		req.Header.Set("linked-customer-id", c.linkedCustomerID)
+	}
+	return nil
+}
+
+// NormalizeCustomerID strips hyphens from a customer ID and checks that
+// the result is 10 digits.
+func NormalizeCustomerID(id string) (string, error) {
+	cid := strings.ReplaceAll(strings.TrimSpace(id), "-", "")
+	if len(cid) != 10 {
+		return "", fmt.Errorf("adsapi: invalid customer ID %q (expected 10 digits)", id)
+	}
+	for _, r := range cid {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("adsapi: invalid customer ID %q (expected 10 digits)", id)
+		}
+	}
+	return cid, nil
+}
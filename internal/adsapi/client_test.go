@@ -0,0 +1,611 @@
+package adsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/timing"
+)
+
+// newTestClient returns a client pointed at an httptest server that
+// records the query it receives and answers with body.
+func newTestClient(t *testing.T, status int, body string, opts ...Option) (*Client, *string) {
+	t.Helper()
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("developer-token") != "dev-token" {
+			t.Errorf("missing developer-token header")
+		}
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			t.Errorf("unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		got = req.Query
+		w.Header().Set("request-id", "req-123")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	opts = append([]Option{WithEndpoint(srv.URL)}, opts...)
+	c := New("dev-token", StaticToken("access-token"), opts...)
+	c.sleep = func(context.Context, time.Duration) error { return nil }
+	return c, &got
+}
+
+func TestSearch(t *testing.T) {
+	c, got := newTestClient(t, http.StatusOK, `{
+		"results": [{"campaign": {"resourceName": "customers/1/campaigns/2", "id": "2"}}],
+		"totalResultsCount": "1",
+		"fieldMask": "campaign.id"
+	}`)
+
+	resp, err := c.Search(context.Background(), "123-456-7890", "SELECT campaign.id FROM campaign")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *got != "SELECT campaign.id FROM campaign" {
+		t.Errorf("query sent verbatim without hooks, got %q", *got)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(resp.Results))
+	}
+	if resp.TotalResultsCount != 1 {
+		t.Errorf("expected total 1, got %d", resp.TotalResultsCount)
+	}
+	if resp.RequestID != "req-123" {
+		t.Errorf("expected request ID from header, got %q", resp.RequestID)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("request-id", "req-456")
+		w.Write([]byte(`{"fieldMask": "campaign.id"}`))
+	}))
+	defer srv.Close()
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL))
+
+	resp, err := c.Validate(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body["validateOnly"] != true {
+		t.Errorf("expected validateOnly in the request, got %v", body)
+	}
+	if body["query"] != "SELECT campaign.id FROM campaign" {
+		t.Errorf("unexpected query %v", body["query"])
+	}
+	if resp.RequestID != "req-456" || len(resp.Results) != 0 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestSearchAPIError(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusBadRequest, `{
+		"error": {"code": 400, "message": "Request contains an invalid argument.", "status": "INVALID_ARGUMENT"}
+	}`)
+
+	_, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.Status != "INVALID_ARGUMENT" || apiErr.RequestID != "req-123" {
+		t.Errorf("unexpected error fields: %+v", apiErr)
+	}
+}
+
+func TestQueryHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		hooks   []QueryHook
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "exclude removed",
+			hooks: []QueryHook{ExcludeRemoved()},
+			input: "SELECT campaign.id FROM campaign",
+			want:  "SELECT campaign.id FROM campaign WHERE campaign.status != 'REMOVED'",
+		},
+		{
+			name:  "exclude removed keeps explicit status filter",
+			hooks: []QueryHook{ExcludeRemoved()},
+			input: "SELECT campaign.id FROM campaign WHERE campaign.status = 'REMOVED'",
+			want:  "SELECT campaign.id FROM campaign WHERE campaign.status = 'REMOVED'",
+		},
+		{
+			name:  "exclude removed skips statuses without REMOVED",
+			hooks: []QueryHook{ExcludeRemoved()},
+			input: "SELECT customer_client.id FROM customer_client",
+			want:  "SELECT customer_client.id FROM customer_client",
+		},
+		{
+			name:  "chain applies in order",
+			hooks: []QueryHook{ExcludeRemoved(), CapLimit(100)},
+			input: "SELECT ad_group.id FROM ad_group WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31' LIMIT 500",
+			want:  "SELECT ad_group.id FROM ad_group WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31' AND ad_group.status != 'REMOVED' LIMIT 100",
+		},
+		{
+			name:    "date cap rejects",
+			hooks:   []QueryHook{CapDateRange(30)},
+			input:   "SELECT campaign.id FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-03-31'",
+			wantErr: "maximum is 30",
+		},
+		{
+			name:    "date cap counts a lower bound through today",
+			hooks:   []QueryHook{CapDateRange(90)},
+			input:   "SELECT metrics.clicks FROM campaign WHERE segments.date >= '2010-01-01'",
+			wantErr: "maximum is 90",
+		},
+		{
+			name:    "date cap rejects unbounded metrics",
+			hooks:   []QueryHook{CapDateRange(90)},
+			input:   "SELECT campaign.id, metrics.clicks FROM campaign",
+			wantErr: "without a bounded segments.date range",
+		},
+		{
+			name:  "date cap passes attributes",
+			hooks: []QueryHook{CapDateRange(90)},
+			input: "SELECT campaign.id FROM campaign",
+			want:  "SELECT campaign.id FROM campaign",
+		},
+		{
+			name: "add condition",
+			hooks: []QueryHook{AddCondition(gaql.Condition{
+				Field:    "campaign.advertising_channel_type",
+				Operator: gaql.OpEq,
+				Value:    gaql.Value{Type: gaql.ValueString, Str: "SEARCH"},
+			})},
+			input: "SELECT campaign.id FROM campaign",
+			want:  "SELECT campaign.id FROM campaign WHERE campaign.advertising_channel_type = 'SEARCH'",
+		},
+		{
+			name:  "gaql rewrites",
+			hooks: []QueryHook{Rewrites(gaql.StripLimit(), gaql.RenameField("campaign.name", "campaign.id"))},
+			input: "SELECT campaign.name FROM campaign ORDER BY campaign.name LIMIT 5",
+			want:  "SELECT campaign.id FROM campaign ORDER BY campaign.id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, got := newTestClient(t, http.StatusOK, `{"results": []}`, WithQueryHooks(tt.hooks...))
+			_, err := c.Search(context.Background(), "1234567890", tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if *got != "" {
+					t.Errorf("rejected query must not be sent, server saw %q", *got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestNormalizeCustomerID(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "1234567890", want: "1234567890"},
+		{input: "123-456-7890", want: "1234567890"},
+		{input: "12345", wantErr: true},
+		{input: "123456789a", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeCustomerID(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %q, got %q (%v)", tt.input, tt.want, got, err)
+		}
+	}
+}
+
+func TestListAccessibleCustomers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v23/customers:listAccessibleCustomers" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("login-customer-id"); got != "1112223333" {
+			t.Errorf("login-customer-id = %q", got)
+		}
+		w.Write([]byte(`{"resourceNames": ["customers/1234567890", "customers/9876543210"]}`))
+	}))
+	defer srv.Close()
+
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL)).WithLogin("111-222-3333")
+	ids, err := c.ListAccessibleCustomers(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(ids, ",") != "1234567890,9876543210" {
+		t.Errorf("unexpected IDs %v", ids)
+	}
+}
+
+func TestManagerLogins(t *testing.T) {
+	var mu sync.Mutex
+	logins := map[string][]string{} // customer -> login-customer-id of each request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		customer := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v23/customers/"), "/googleAds:search")
+		if got := r.Header.Get("linked-customer-id"); got != "5556667777" {
+			t.Errorf("linked-customer-id = %q", got)
+		}
+		mu.Lock()
+		logins[customer] = append(logins[customer], r.Header.Get("login-customer-id"))
+		mu.Unlock()
+		switch {
+		case req.Query != managerQuery:
+			w.Write([]byte(okBody))
+		case customer == "1112223333":
+			w.Write([]byte(`{"results": [{"customerClient": {"id": "1112223333"}}, {"customerClient": {"id": "4445556666"}},
+				{"customerClient": {"id": "1234567890"}}]}`))
+		case customer == "1234567890":
+			w.Write([]byte(`{"results": [{"customerClient": {"id": "1234567890"}}, {"customerClient": {"id": "2345678901"}}]}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": {"code": 403, "message": "denied", "status": "PERMISSION_DENIED"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL), WithRetry(NoRetry),
+		WithManagers("1234567890", "111-222-3333", "9999999999"), WithLinkedCustomerID("555-666-7777"))
+	for _, customer := range []string{"2345678901", "4445556666", "1234567890", "3456789012"} {
+		if _, err := c.Search(context.Background(), customer, "SELECT campaign.id FROM campaign"); err != nil {
+			t.Fatalf("Search(%s): %v", customer, err)
+		}
+	}
+	// The manager queries carry their own manager as login; the queries
+	// after them the manager found above each customer, the first
+	// configured winning, and none for customers below no manager.
+	want := map[string][]string{
+		"1234567890": {"1234567890", "1234567890"},
+		"1112223333": {"1112223333"},
+		"9999999999": {"9999999999"},
+		"2345678901": {"1234567890"},
+		"4445556666": {"1112223333"},
+		"3456789012": {""},
+	}
+	for customer, w := range want {
+		if got := logins[customer]; strings.Join(got, ",") != strings.Join(w, ",") {
+			t.Errorf("login-customer-id of requests to %s = %q, want %q", customer, got, w)
+		}
+	}
+
+	// An explicit login wins.
+	logins = map[string][]string{}
+	if _, err := c.WithLogin("1112223333").Search(context.Background(), "2345678901", "SELECT campaign.id FROM campaign"); err != nil {
+		t.Fatal(err)
+	}
+	if got := logins["2345678901"]; len(got) != 1 || got[0] != "1112223333" {
+		t.Errorf("login-customer-id with WithLogin = %q", got)
+	}
+}
+
+func TestSuggestGeoTargetConstants(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v23/geoTargetConstants:suggest" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req struct {
+			Locale        string `json:"locale"`
+			CountryCode   string `json:"countryCode"`
+			LocationNames struct {
+				Names []string `json:"names"`
+			} `json:"locationNames"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Locale != "en" || req.CountryCode != "US" || strings.Join(req.LocationNames.Names, "|") != "Boston, MA" {
+			t.Errorf("unexpected request body %+v", req)
+		}
+		w.Write([]byte(`{"geoTargetConstantSuggestions": [{
+			"locale": "en", "reach": "4100000", "searchTerm": "Boston, MA",
+			"geoTargetConstant": {"resourceName": "geoTargetConstants/1018127", "id": "1018127", "name": "Boston",
+				"countryCode": "US", "targetType": "City", "status": "ENABLED",
+				"canonicalName": "Boston,Massachusetts,United States", "parentGeoTarget": "geoTargetConstants/21152"},
+			"geoTargetConstantParents": [{"resourceName": "geoTargetConstants/21152", "id": "21152", "name": "Massachusetts"}]
+		}]}`))
+	}))
+	defer srv.Close()
+
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL))
+	got, err := c.SuggestGeoTargetConstants(context.Background(), "en", "US", "Boston, MA")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d suggestions, want 1", len(got))
+	}
+	s := got[0]
+	if s.Reach != 4100000 || s.GeoTargetConstant.ID != 1018127 || s.GeoTargetConstant.CanonicalName != "Boston,Massachusetts,United States" {
+		t.Errorf("unexpected suggestion %+v", s)
+	}
+	if len(s.Parents) != 1 || s.Parents[0].ID != 21152 {
+		t.Errorf("unexpected parents %+v", s.Parents)
+	}
+}
+
+func TestSearchFields(t *testing.T) {
+	pages := map[string]string{
+		"": `{"results": [{"name": "campaign", "category": "RESOURCE", "dataType": "MESSAGE",
+			"attributeResources": ["customer"], "segments": ["segments.date"], "metrics": ["metrics.clicks"],
+			"selectableWith": ["ad_group"]}], "nextPageToken": "page-2"}`,
+		"page-2": `{"results": [{"name": "campaign.status", "category": "ATTRIBUTE", "dataType": "ENUM",
+			"selectable": true, "filterable": true, "sortable": true, "enumValues": ["ENABLED", "PAUSED"]}]}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string `json:"query"`
+			PageToken string `json:"pageToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v23/googleAdsFields:search" || !strings.HasSuffix(req.Query, " WHERE name LIKE 'campaign%'") {
+			t.Errorf("unexpected request %s %q", r.URL.Path, req.Query)
+		}
+		w.Write([]byte(pages[req.PageToken]))
+	}))
+	defer srv.Close()
+
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL))
+	fields, err := c.SearchFields(context.Background(), "name LIKE 'campaign%'")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("expected 2 fields over two pages, got %d", len(fields))
+	}
+	if got := strings.Join(fields[0].SelectableWith, ","); got != "customer,segments.date,metrics.clicks" {
+		t.Errorf("resource selectable with %s, want its FROM-clause companions", got)
+	}
+	if f := fields[1]; !f.Sortable || f.DataType != "ENUM" || len(f.EnumValues) != 2 {
+		t.Errorf("unexpected attribute %+v", f)
+	}
+}
+
+func TestSearchIter(t *testing.T) {
+	pages := map[string]string{
+		"":       `{"results": [{"campaign": {"id": "1"}}, {"campaign": {"id": "2"}}], "nextPageToken": "page-2"}`,
+		"page-2": `{"results": [], "nextPageToken": "page-3"}`,
+		"page-3": `{"results": [{"campaign": {"id": "3"}}]}`,
+	}
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			PageToken string `json:"pageToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		tokens = append(tokens, req.PageToken)
+		w.Write([]byte(pages[req.PageToken]))
+	}))
+	defer srv.Close()
+
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL))
+	next := c.SearchIter(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+
+	var ids []string
+	for {
+		row, err := next()
+		if err == Done {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, row["campaign"].(map[string]any)["id"].(string))
+	}
+	if strings.Join(ids, ",") != "1,2,3" {
+		t.Errorf("got rows %v", ids)
+	}
+	if strings.Join(tokens, ",") != ",page-2,page-3" {
+		t.Errorf("unexpected page tokens %q", tokens)
+	}
+	if _, err := next(); err != Done {
+		t.Errorf("expected Done after the last row, got %v", err)
+	}
+}
+
+func TestSearchIterAt(t *testing.T) {
+	pages := map[string]string{
+		"":       `{"results": [{"campaign": {"id": "1"}}, {"campaign": {"id": "2"}}], "nextPageToken": "page-2"}`,
+		"page-2": `{"results": [{"campaign": {"id": "3"}}, {"campaign": {"id": "4"}}, {"campaign": {"id": "5"}}]}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			PageToken string `json:"pageToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Write([]byte(pages[req.PageToken]))
+	}))
+	defer srv.Close()
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL))
+
+	read := func(cur *Cursor, n int) []string {
+		next := c.SearchIterAt(context.Background(), "1234567890", "SELECT campaign.id FROM campaign", cur)
+		var ids []string
+		for n < 0 || len(ids) < n {
+			row, err := next()
+			if err == Done {
+				break
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ids = append(ids, row["campaign"].(map[string]any)["id"].(string))
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name      string
+		stopAfter int
+		want      Cursor
+		rest      string
+	}{
+		{"first page", 1, Cursor{Offset: 1}, "2,3,4,5"},
+		{"end of first page", 2, Cursor{Offset: 2}, "3,4,5"},
+		{"second page", 4, Cursor{PageToken: "page-2", Offset: 2}, "5"},
+		{"last row", 5, Cursor{PageToken: "page-2", Offset: 3}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cur Cursor
+			read(&cur, tt.stopAfter)
+			if cur != tt.want {
+				t.Errorf("cursor after %d rows = %+v, want %+v", tt.stopAfter, cur, tt.want)
+			}
+			if rest := strings.Join(read(&cur, -1), ","); rest != tt.rest {
+				t.Errorf("resumed rows = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestSearchIterError(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusInternalServerError, `{"error": {"code": 500, "message": "internal", "status": "INTERNAL"}}`)
+	next := c.SearchIter(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+	for i := 0; i < 2; i++ {
+		var apiErr *APIError
+		if _, err := next(); !errors.As(err, &apiErr) {
+			t.Fatalf("call %d: expected *APIError, got %v", i, err)
+		}
+	}
+}
+
+func TestWithRecorder(t *testing.T) {
+	rec := timing.NewRecorder()
+	c, _ := newTestClient(t, http.StatusOK, `{"results": []}`, WithRecorder(rec))
+	if _, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := map[timing.Phase]int{}
+	for _, s := range rec.Stats() {
+		got[s.Phase] = s.Count
+	}
+	for _, p := range []timing.Phase{timing.Auth, timing.Connect, timing.Server, timing.Stream} {
+		if got[p] != 1 {
+			t.Errorf("%s recorded %d times, want 1", p, got[p])
+		}
+	}
+	if got[timing.Wait] != 0 {
+		t.Errorf("wait recorded %d times without throttling or retries", got[timing.Wait])
+	}
+}
+
+// rotatingTokens hands out token-1, token-2, ..., moving on only when
+// the current token is invalidated.
+type rotatingTokens struct{ n int }
+
+func (r *rotatingTokens) Token(context.Context) (string, error) {
+	return fmt.Sprintf("token-%d", r.n+1), nil
+}
+
+func (r *rotatingTokens) Invalidate(token string) {
+	if token == fmt.Sprintf("token-%d", r.n+1) {
+		r.n++
+	}
+}
+
+func TestReauthorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		valid    string // the token the server accepts
+		wantErr  bool
+		requests int
+	}{
+		{"valid token", "token-1", false, 1},
+		{"expired token replaced", "token-2", false, 2},
+		{"replacement rejected too", "token-9", true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.Header.Get("Authorization") != "Bearer "+tt.valid {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error": {"code": 401, "message": "Request had invalid authentication credentials.", "status": "UNAUTHENTICATED"}}`))
+					return
+				}
+				w.Write([]byte(`{"results": []}`))
+			}))
+			defer srv.Close()
+
+			c := New("dev-token", &rotatingTokens{}, WithEndpoint(srv.URL), WithRetry(NoRetry))
+			_, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Search error = %v, want error %v", err, tt.wantErr)
+			}
+			if requests != tt.requests {
+				t.Errorf("sent %d requests, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
+
+// TestConcurrentUse sends requests from many goroutines while hooks are
+// added and login copies are made; run with -race.
+func TestConcurrentUse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results": []}`))
+	}))
+	defer srv.Close()
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL), WithQueryHooks(ExcludeRemoved()))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			client := c
+			if g%2 == 0 {
+				client = c.WithLogin("1234567890")
+			}
+			for i := 0; i < 20; i++ {
+				if i == 10 {
+					c.Use(CapLimit(100))
+				}
+				if _, err := client.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
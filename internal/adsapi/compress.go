@@ -0,0 +1,81 @@
+package adsapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxPooledBuffer is the largest response buffer kept for reuse, so one
+// huge page does not pin its memory for the life of the process.
+const maxPooledBuffer = 8 << 20
+
+var (
+	bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	gzipPool   sync.Pool // *gzip.Reader
+)
+
+// WithCompression sets whether responses are requested gzip-compressed,
+// which they are by default. Search results are repetitive JSON that
+// compresses several times over, so large pages arrive much sooner;
+// turn it off only for a proxy that mishandles compressed bodies.
+func WithCompression(enabled bool) Option {
+	return func(c *Client) { c.uncompressed = !enabled }
+}
+
+// acceptGzip asks for a compressed response, or an uncompressed one.
+// Setting the header here, rather than leaving it to http.Transport,
+// decompresses with pooled readers and works with any transport.
+func (c *Client) acceptGzip(req *http.Request) {
+	if c.uncompressed {
+		req.Header.Set("Accept-Encoding", "identity")
+	} else {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+}
+
+// readResponse reads the body of resp, decompressing it when the server
+// compressed it, into a pooled buffer, which the caller hands back to
+// releaseBuffer once done with its bytes. wire is the size of the body
+// as received.
+func readResponse(resp *http.Response) (buf *bytes.Buffer, wire int64, err error) {
+	buf = bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	body := &countingReader{r: resp.Body}
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		_, err = buf.ReadFrom(body)
+		return buf, body.n, err
+	}
+	zr, _ := gzipPool.Get().(*gzip.Reader)
+	if zr == nil {
+		zr, err = gzip.NewReader(body)
+	} else {
+		err = zr.Reset(body)
+	}
+	if err == nil {
+		_, err = buf.ReadFrom(zr)
+		gzipPool.Put(zr)
+	}
+	return buf, body.n, err
+}
+
+// releaseBuffer returns a buffer from readResponse to the pool.
+func releaseBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,102 @@
+package adsapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pageOf returns a search response of n keyword rows.
+func pageOf(n int) []byte {
+	rows := make([]Row, n)
+	for i := range rows {
+		rows[i] = Row{
+			"campaign":         map[string]any{"id": fmt.Sprint(1000000000 + i%50), "name": fmt.Sprintf("Campaign %d", i%50)},
+			"adGroupCriterion": map[string]any{"criterionId": fmt.Sprint(i), "keyword": map[string]any{"text": fmt.Sprintf("keyword %d", i), "matchType": "PHRASE"}},
+			"metrics":          map[string]any{"clicks": fmt.Sprint(i % 97), "impressions": fmt.Sprint(i % 1013), "costMicros": fmt.Sprint(i * 10000)},
+		}
+	}
+	data, _ := json.Marshal(map[string]any{"results": rows})
+	return data
+}
+
+// compressingServer answers every search with page, gzipped when the
+// client accepts it, and records the Accept-Encoding header.
+func compressingServer(tb testing.TB, page []byte, accepted *string) *httptest.Server {
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	zw.Write(page)
+	zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accepted != nil {
+			*accepted = r.Header.Get("Accept-Encoding")
+		}
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(zipped.Bytes())
+			return
+		}
+		w.Write(page)
+	}))
+	tb.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		accept string
+	}{
+		{"default", nil, "gzip"},
+		{"disabled", []Option{WithCompression(false)}, "identity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accepted string
+			srv := compressingServer(t, pageOf(100), &accepted)
+			c := New("dev-token", StaticToken("access-token"), append([]Option{WithEndpoint(srv.URL)}, tt.opts...)...)
+			// Twice, so the second response reuses the pooled buffer and reader.
+			for i := 0; i < 2; i++ {
+				res, err := c.SearchAll(context.Background(), "1234567890", "SELECT campaign.id FROM keyword_view")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(res.Rows) != 100 || str(res.Rows[99], "adGroupCriterion", "keyword", "text") != "keyword 99" {
+					t.Fatalf("got %d rows, last %v", len(res.Rows), res.Rows[len(res.Rows)-1])
+				}
+			}
+			if accepted != tt.accept {
+				t.Errorf("Accept-Encoding %q, want %q", accepted, tt.accept)
+			}
+		})
+	}
+}
+
+// BenchmarkSearchIter reads a page of 10,000 keyword rows from a local
+// server, compressed and not; bytes per second are of the JSON.
+func BenchmarkSearchIter(b *testing.B) {
+	page := pageOf(10000)
+	for _, compressed := range []bool{true, false} {
+		b.Run(fmt.Sprintf("gzip=%v", compressed), func(b *testing.B) {
+			srv := compressingServer(b, page, nil)
+			c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL), WithCompression(compressed))
+			b.SetBytes(int64(len(page)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				next := c.SearchIter(context.Background(), "1234567890", "SELECT campaign.id FROM keyword_view")
+				for _, err := next(); err != Done; _, err = next() {
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package adsapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// ListAccessibleCustomers returns the IDs of the customers the
+// authenticated user can access directly. Accounts reachable only
+// through a manager are not included; query customer_client for those.
+func (c *Client) ListAccessibleCustomers(ctx context.Context) ([]string, error) {
+	var resp struct {
+		ResourceNames []string `json:"resourceNames"`
+	}
+	path := "/" + c.version + "/customers:listAccessibleCustomers"
+	if _, err := c.do(ctx, "", http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(resp.ResourceNames))
+	for _, name := range resp.ResourceNames {
+		ids = append(ids, strings.TrimPrefix(name, "customers/"))
+	}
+	return ids, nil
+}
+
+// WithLogin returns a copy of the client that sends loginCustomerID as
+// the login-customer-id header, for reaching accounts below a manager.
+func (c *Client) WithLogin(loginCustomerID string) *Client {
+	cp := *c
+	cp.hooks = &hookChain{list: c.hooks.load()}
+	WithLoginCustomerID(loginCustomerID)(&cp)
+	return &cp
+}
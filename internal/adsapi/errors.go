@@ -0,0 +1,184 @@
+package adsapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned when the API responds with a non-200 status.
+type APIError struct {
+	StatusCode int
+	Status     string // google.rpc.Code name, e.g. INVALID_ARGUMENT
+	Message    string
+	RequestID  string
+	Body       []byte
+
+	// Errors are the GoogleAdsFailure details of the response, when the
+	// API supplied them.
+	Errors []GoogleAdsError
+
+	// RetryDelay is the delay the server asked for through a Retry-After
+	// header or a google.rpc.RetryInfo detail. Zero when absent.
+	RetryDelay time.Duration
+
+	token string // the access token of the failed request
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	if e.Status != "" {
+		fmt.Fprintf(&sb, "adsapi: %s (HTTP %d): %s", e.Status, e.StatusCode, e.Message)
+	} else {
+		fmt.Fprintf(&sb, "adsapi: HTTP %d: %s", e.StatusCode, e.Message)
+	}
+	if len(e.Errors) > 0 {
+		fmt.Fprintf(&sb, ": %s", e.Errors[0])
+		if n := len(e.Errors) - 1; n > 0 {
+			fmt.Fprintf(&sb, " (and %d more)", n)
+		}
+	}
+	return sb.String()
+}
+
+// GoogleAdsError is one entry of a GoogleAdsFailure.
+type GoogleAdsError struct {
+	// Category is the error code oneof that was set, e.g. queryError or
+	// quotaError, and Code its value, e.g. UNRECOGNIZED_FIELD.
+	Category string
+	Code     string
+
+	Message string
+
+	// Trigger is the value that caused the error, such as a field name
+	// in a query.
+	Trigger string
+
+	// FieldPath locates the offending part of the request, e.g.
+	// ["query"] or ["operations[0]", "create", "name"].
+	FieldPath []string
+}
+
+func (e GoogleAdsError) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s.%s", e.Category, e.Code)
+	if e.Message != "" {
+		fmt.Fprintf(&sb, ": %s", e.Message)
+	}
+	var extra []string
+	if e.Trigger != "" {
+		extra = append(extra, fmt.Sprintf("trigger %q", e.Trigger))
+	}
+	if len(e.FieldPath) > 0 {
+		extra = append(extra, "at "+strings.Join(e.FieldPath, "."))
+	}
+	if len(extra) > 0 {
+		fmt.Fprintf(&sb, " (%s)", strings.Join(extra, ", "))
+	}
+	return sb.String()
+}
+
+// errorEnvelope is the google.rpc.Status JSON error body.
+type errorEnvelope struct {
+	Error struct {
+		Code    int           `json:"code"`
+		Message string        `json:"message"`
+		Status  string        `json:"status"`
+		Details []errorDetail `json:"details"`
+	} `json:"error"`
+}
+
+// errorDetail covers the detail types the client reads: GoogleAdsFailure
+// and google.rpc.RetryInfo.
+type errorDetail struct {
+	Type   string `json:"@type"`
+	Errors []struct {
+		ErrorCode map[string]string `json:"errorCode"`
+		Message   string            `json:"message"`
+		Trigger   map[string]any    `json:"trigger"`
+		Location  struct {
+			FieldPathElements []struct {
+				FieldName string `json:"fieldName"`
+				Index     *int   `json:"index"`
+			} `json:"fieldPathElements"`
+		} `json:"location"`
+	} `json:"errors"`
+	RequestID  string `json:"requestId"`
+	RetryDelay string `json:"retryDelay"`
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("request-id"),
+		Body:       body,
+		RetryDelay: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	var env errorEnvelope
+	if err := json.Unmarshal(body, &env); err == nil && env.Error.Message != "" {
+		e.Status = env.Error.Status
+		e.Message = env.Error.Message
+	} else {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+
+	for _, d := range env.Error.Details {
+		switch {
+		case strings.HasSuffix(d.Type, ".GoogleAdsFailure"):
+			if e.RequestID == "" {
+				e.RequestID = d.RequestID
+			}
+			for _, fe := range d.Errors {
+				ge := GoogleAdsError{Message: fe.Message}
+				for category, code := range fe.ErrorCode {
+					ge.Category, ge.Code = category, code
+				}
+				for _, v := range fe.Trigger {
+					ge.Trigger = fmt.Sprint(v)
+				}
+				for _, p := range fe.Location.FieldPathElements {
+					name := p.FieldName
+					if p.Index != nil {
+						name = fmt.Sprintf("%s[%d]", name, *p.Index)
+					}
+					ge.FieldPath = append(ge.FieldPath, name)
+				}
+				e.Errors = append(e.Errors, ge)
+			}
+		case strings.HasSuffix(d.Type, "google.rpc.RetryInfo") && e.RetryDelay == 0:
+			if delay, err := time.ParseDuration(d.RetryDelay); err == nil {
+				e.RetryDelay = delay
+			}
+		}
+	}
+	return e
+}
+
+// HasCategory reports whether any GoogleAdsFailure entry belongs to the
+// category, e.g. queryError.
+func (e *APIError) HasCategory(category string) bool {
+	for _, ge := range e.Errors {
+		if ge.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header, either delay seconds or an
+// HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
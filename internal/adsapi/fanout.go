@@ -0,0 +1,189 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultConcurrency is the number of accounts SearchAccounts queries at
+// once when SearchAccountsOptions.Concurrency is zero.
+const DefaultConcurrency = 4
+
+// SearchAccountsOptions configures SearchAccounts.
+type SearchAccountsOptions struct {
+	// Concurrency is the number of accounts queried at once.
+	Concurrency int
+
+	// Logins maps a customer ID to the manager sent as its
+	// login-customer-id. Customers not listed use the client's.
+	Logins map[string]string
+
+	// OnRows, when set, receives the rows of each account in batches of
+	// up to StreamBatch as they arrive, instead of their being kept in
+	// AccountResult.Rows. Calls are made one at a time. Returning false
+	// stops every account; those cut short end without an error.
+	OnRows func(customerID string, rows []Row) bool
+}
+
+// StreamBatch is the most rows an account holds before passing them to
+// SearchAccountsOptions.OnRows.
+const StreamBatch = 1000
+
+// errStopped cancels the accounts still running once OnRows returns
+// false.
+var errStopped = errors.New("adsapi: stopped by OnRows")
+
+// AccountResult is the outcome of a query against one account.
+type AccountResult struct {
+	CustomerID string
+	Rows       []Row
+	Err        error
+
+	// Truncated is set when the client's budget stopped the account's
+	// rows short; the budget applies to each account on its own.
+	Truncated bool
+
+	// Metadata describes the requests made for the account.
+	Metadata SearchMetadata
+}
+
+// AccountErrors lists the accounts a SearchAccounts run failed for.
+type AccountErrors []AccountResult
+
+func (e AccountErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, r := range e {
+		msgs[i] = fmt.Sprintf("%s: %v", r.CustomerID, r.Err)
+	}
+	return fmt.Sprintf("adsapi: %d of the accounts failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// SearchAccounts runs query against every customer in customerIDs
+// concurrently and returns one result per customer, in the order given.
+// Every row is tagged with its account as customer.id, so merged results
+// stay attributable even when the query does not select it.
+//
+// A failing account does not stop the others. The returned error is an
+// AccountErrors listing the failures, or nil when every account
+// succeeded; the results hold the rows of the accounts that did, unless
+// OnRows streamed them.
+func (c *Client) SearchAccounts(ctx context.Context, customerIDs []string, query string, opts SearchAccountsOptions) ([]AccountResult, error) {
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	workers = min(workers, len(customerIDs))
+
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	var emit func(string, []Row) bool
+	if opts.OnRows != nil {
+		var mu sync.Mutex
+		emit = func(customerID string, rows []Row) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			if context.Cause(ctx) == errStopped {
+				return false
+			}
+			if !opts.OnRows(customerID, rows) {
+				stop(errStopped)
+				return false
+			}
+			return true
+		}
+	}
+
+	results := make([]AccountResult, len(customerIDs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if context.Cause(ctx) == errStopped {
+					results[i] = AccountResult{CustomerID: customerIDs[i]}
+					continue
+				}
+				results[i] = c.searchAccount(ctx, customerIDs[i], query, opts.Logins, emit)
+			}
+		}()
+	}
+	for i := range customerIDs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failed AccountErrors
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) > 0 {
+		return results, failed
+	}
+	return results, nil
+}
+
+// searchAccount reads every page of query for one customer, passing the
+// rows to emit in batches when it is set.
+func (c *Client) searchAccount(ctx context.Context, customerID, query string, logins map[string]string, emit func(string, []Row) bool) (res AccountResult) {
+	res = AccountResult{CustomerID: customerID}
+	client := c
+	if login, ok := logins[customerID]; ok {
+		client = c.WithLogin(login)
+	}
+	next, totals := client.SearchIterWithOptions(ctx, customerID, query, SearchOptions{})
+	defer func() { res.Metadata = totals.Metadata }()
+	// flush hands the rows read so far to emit, and reports whether
+	// more are wanted.
+	flush := func() bool {
+		if emit == nil || len(res.Rows) == 0 {
+			return true
+		}
+		more := emit(customerID, res.Rows)
+		res.Rows = nil
+		return more
+	}
+	for {
+		row, err := next()
+		if err == Done {
+			flush()
+			return res
+		}
+		if err == ErrTruncated {
+			res.Truncated = true
+			flush()
+			return res
+		}
+		if err != nil {
+			if context.Cause(ctx) != errStopped {
+				res.Err = err
+				flush()
+			}
+			return res
+		}
+		tagCustomer(row, customerID)
+		res.Rows = append(res.Rows, row)
+		if emit != nil && len(res.Rows) == StreamBatch && !flush() {
+			return res
+		}
+	}
+}
+
+// tagCustomer sets customer.id on row unless the API already did.
+func tagCustomer(row Row, customerID string) {
+	cust, ok := row["customer"].(map[string]any)
+	if !ok {
+		cust = map[string]any{}
+		row["customer"] = cust
+	}
+	if _, ok := cust["id"]; !ok {
+		cust["id"] = customerID
+	}
+}
@@ -0,0 +1,105 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSearchAccounts(t *testing.T) {
+	var mu sync.Mutex
+	logins := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Split(r.URL.Path, "/")[3]
+		mu.Lock()
+		logins[id] = r.Header.Get("login-customer-id")
+		mu.Unlock()
+		switch id {
+		case "1000000002":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": {"code": 403, "message": "The caller does not have permission", "status": "PERMISSION_DENIED"}}`))
+		case "1000000003":
+			w.Write([]byte(`{"results": [{"customer": {"id": "1000000003"}, "metrics": {"clicks": "1"}}]}`))
+		default:
+			w.Write([]byte(`{"results": [{"metrics": {"clicks": "5"}}, {"metrics": {"clicks": "7"}}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL), WithLoginCustomerID("9000000000"), WithRetry(NoRetry))
+	ids := []string{"1000000001", "1000000002", "1000000003", "1000000004"}
+	results, err := c.SearchAccounts(context.Background(), ids, "SELECT metrics.clicks FROM customer", SearchAccountsOptions{
+		Concurrency: 2,
+		Logins:      map[string]string{"1000000004": "9000000001"},
+	})
+
+	var failed AccountErrors
+	if !errors.As(err, &failed) || len(failed) != 1 || failed[0].CustomerID != "1000000002" {
+		t.Fatalf("expected one failed account, got %v", err)
+	}
+	if len(results) != len(ids) {
+		t.Fatalf("got %d results, want %d", len(results), len(ids))
+	}
+	for i, r := range results {
+		if r.CustomerID != ids[i] {
+			t.Errorf("result %d is for %s, want %s", i, r.CustomerID, ids[i])
+		}
+		for _, row := range r.Rows {
+			if got := row["customer"].(map[string]any)["id"]; got != ids[i] {
+				t.Errorf("row of %s tagged %v", ids[i], got)
+			}
+		}
+	}
+	if len(results[0].Rows) != 2 || len(results[1].Rows) != 0 || len(results[2].Rows) != 1 {
+		t.Errorf("unexpected row counts %d, %d, %d", len(results[0].Rows), len(results[1].Rows), len(results[2].Rows))
+	}
+	if logins["1000000001"] != "9000000000" || logins["1000000004"] != "9000000001" {
+		t.Errorf("unexpected login-customer-id headers %v", logins)
+	}
+}
+
+func TestSearchAccountsTruncated(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusOK, `{"results": [{"metrics": {"clicks": "5"}}, {"metrics": {"clicks": "7"}}]}`, WithMaxRows(1))
+	results, err := c.SearchAccounts(context.Background(), []string{"1000000001", "1000000002"}, "SELECT metrics.clicks FROM customer", SearchAccountsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if len(r.Rows) != 1 || !r.Truncated {
+			t.Errorf("%s: %d rows, truncated %v; want 1 row each, truncated", r.CustomerID, len(r.Rows), r.Truncated)
+		}
+		if m := r.Metadata; m.Pages != 1 || m.Rows != 1 || len(m.RequestIDs) != 1 || m.RequestIDs[0] != "req-123" {
+			t.Errorf("%s: metadata %+v", r.CustomerID, m)
+		}
+	}
+}
+
+func TestSearchAccountsOnRows(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusOK, `{"results": [{"metrics": {"clicks": "5"}}, {"metrics": {"clicks": "7"}}]}`)
+	ids := []string{"1000000001", "1000000002", "1000000003", "1000000004", "1000000005"}
+	var got []string
+	results, err := c.SearchAccounts(context.Background(), ids, "SELECT metrics.clicks FROM customer", SearchAccountsOptions{
+		Concurrency: 2,
+		OnRows: func(customerID string, rows []Row) bool {
+			for _, row := range rows {
+				got = append(got, row["customer"].(map[string]any)["id"].(string))
+			}
+			return len(got) < 3
+		},
+	})
+	if err != nil {
+		t.Fatalf("stopping is not an error: %v", err)
+	}
+	if len(got) > 4 {
+		t.Errorf("received %d rows after asking to stop at 3", len(got))
+	}
+	for _, r := range results {
+		if len(r.Rows) != 0 {
+			t.Errorf("%s kept %d streamed rows", r.CustomerID, len(r.Rows))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package adsapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// fieldColumns are the GoogleAdsField columns SearchFields selects.
+const fieldColumns = "name, category, data_type, selectable, filterable, sortable, is_repeated, " +
+	"enum_values, selectable_with, attribute_resources, metrics, segments"
+
+// SearchFields queries GoogleAdsFieldService, the API's own schema, and
+// returns the matching fields. where is the condition of the query,
+// without the WHERE keyword, such as "name LIKE 'campaign.%'".
+//
+// As in the embedded catalog, the SelectableWith list of a resource
+// names what can be selected with it in the FROM clause: its attribute
+// resources, segments, and metrics.
+func (c *Client) SearchFields(ctx context.Context, where string) ([]gaql.FieldInfo, error) {
+	var fields []gaql.FieldInfo
+	body := map[string]any{"query": "SELECT " + fieldColumns + " WHERE " + where}
+	path := "/" + c.version + "/googleAdsFields:search"
+	for {
+		var resp struct {
+			Results []struct {
+				Name               string   `json:"name"`
+				Category           string   `json:"category"`
+				DataType           string   `json:"dataType"`
+				Selectable         bool     `json:"selectable"`
+				Filterable         bool     `json:"filterable"`
+				Sortable           bool     `json:"sortable"`
+				IsRepeated         bool     `json:"isRepeated"`
+				EnumValues         []string `json:"enumValues"`
+				SelectableWith     []string `json:"selectableWith"`
+				AttributeResources []string `json:"attributeResources"`
+				Metrics            []string `json:"metrics"`
+				Segments           []string `json:"segments"`
+			} `json:"results"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if _, err := c.do(ctx, "", http.MethodPost, path, body, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Results {
+			f := gaql.FieldInfo{
+				Name:           r.Name,
+				Category:       r.Category,
+				DataType:       r.DataType,
+				Selectable:     r.Selectable,
+				Filterable:     r.Filterable,
+				Sortable:       r.Sortable,
+				Repeated:       r.IsRepeated,
+				EnumValues:     r.EnumValues,
+				SelectableWith: r.SelectableWith,
+			}
+			if r.Category == "RESOURCE" {
+				f.SelectableWith = slices.Concat(r.AttributeResources, r.Segments, r.Metrics)
+			}
+			fields = append(fields, f)
+		}
+		if resp.NextPageToken == "" {
+			return fields, nil
+		}
+		body["pageToken"] = resp.NextPageToken
+	}
+}
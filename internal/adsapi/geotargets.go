@@ -0,0 +1,50 @@
+package adsapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// GeoTargetConstant is a location that campaigns can target, such as a
+// country, region, or city.
+type GeoTargetConstant struct {
+	ResourceName    string `json:"resourceName"`
+	ID              int64  `json:"id,string"`
+	Name            string `json:"name"`
+	CountryCode     string `json:"countryCode"`
+	TargetType      string `json:"targetType"`
+	Status          string `json:"status"`
+	CanonicalName   string `json:"canonicalName"`
+	ParentGeoTarget string `json:"parentGeoTarget"`
+}
+
+// GeoTargetSuggestion is a location suggested for a name.
+type GeoTargetSuggestion struct {
+	Locale            string              `json:"locale"`
+	Reach             int64               `json:"reach,string"`
+	SearchTerm        string              `json:"searchTerm"`
+	GeoTargetConstant GeoTargetConstant   `json:"geoTargetConstant"`
+	Parents           []GeoTargetConstant `json:"geoTargetConstantParents"`
+}
+
+// SuggestGeoTargetConstants asks GeoTargetConstantService for locations
+// matching names, such as "Boston, MA". locale is the language of the
+// returned names, such as "en", and countryCode, such as "US", narrows
+// the suggestions to one country; either may be empty.
+func (c *Client) SuggestGeoTargetConstants(ctx context.Context, locale, countryCode string, names ...string) ([]GeoTargetSuggestion, error) {
+	body := map[string]any{"locationNames": map[string]any{"names": names}}
+	if locale != "" {
+		body["locale"] = locale
+	}
+	if countryCode != "" {
+		body["countryCode"] = countryCode
+	}
+	var resp struct {
+		Suggestions []GeoTargetSuggestion `json:"geoTargetConstantSuggestions"`
+	}
+	path := "/" + c.version + "/geoTargetConstants:suggest"
+	if _, err := c.do(ctx, "", http.MethodPost, path, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Suggestions, nil
+}
@@ -0,0 +1,150 @@
+package adsapi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// QueryHook rewrites a query before it is sent to the API. Returning an
+// error rejects the query; the request is never made.
+type QueryHook func(*gaql.Query) (*gaql.Query, error)
+
+// hookChain holds the hooks of a client. The list is never modified in
+// place: add replaces it, so a list returned by load stays valid while
+// hooks are added concurrently.
+type hookChain struct {
+	mu   sync.Mutex
+	list []QueryHook
+}
+
+func (h *hookChain) load() []QueryHook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.list
+}
+
+func (h *hookChain) add(hooks []QueryHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.list = slices.Concat(h.list, hooks)
+}
+
+// statusResources are resources with a <resource>.status field that
+// supports the REMOVED value.
+var statusResources = map[string]bool{
+	"ad_group":           true,
+	"ad_group_ad":        true,
+	"ad_group_criterion": true,
+	"asset_group":        true,
+	"campaign":           true,
+	"campaign_budget":    true,
+	"campaign_criterion": true,
+	"conversion_action":  true,
+	"label":              true,
+}
+
+// prepare applies the client's hooks to query and returns the query text
+// to send. Without hooks the query is sent verbatim.
+func (c *Client) prepare(query string) (string, error) {
+	hooks := c.hooks.load()
+	if len(hooks) == 0 {
+		return query, nil
+	}
+
+	q, err := gaql.Parse(query)
+	if err != nil {
+		return "", err
+	}
+	for _, hook := range hooks {
+		q, err = hook(q)
+		if err != nil {
+			return "", fmt.Errorf("adsapi: query rejected by hook: %w", err)
+		}
+		if q == nil {
+			return "", fmt.Errorf("adsapi: query hook returned nil query")
+		}
+	}
+	return q.String(), nil
+}
+
+// Rewrites returns a hook that applies gaql rewrites to every query.
+func Rewrites(fns ...gaql.RewriteFunc) QueryHook {
+	return func(q *gaql.Query) (*gaql.Query, error) {
+		return gaql.Rewrite(q, fns...)
+	}
+}
+
+// AddCondition returns a hook that appends c to every query's WHERE
+// clause unless an identical condition is already present.
+func AddCondition(c gaql.Condition) QueryHook {
+	return Rewrites(gaql.AddCondition(c))
+}
+
+// ExcludeRemoved returns a hook that adds "<resource>.status != 'REMOVED'"
+// to queries against resources with a status field, unless the query
+// already filters on that status.
+func ExcludeRemoved() QueryHook {
+	return func(q *gaql.Query) (*gaql.Query, error) {
+		if !statusResources[q.From] {
+			return q, nil
+		}
+		field := q.From + ".status"
+		for _, c := range q.Where {
+			if c.Field == field {
+				return q, nil
+			}
+		}
+		q.Where = append(q.Where, gaql.Condition{
+			Field:    field,
+			Operator: gaql.OpNeq,
+			Value:    gaql.Value{Type: gaql.ValueString, Str: "REMOVED"},
+		})
+		return q, nil
+	}
+}
+
+// CapDateRange returns a hook that rejects queries whose segments.date
+// range spans more than maxDays days. A range bounded only below runs
+// through today, and a query selecting metrics without a lower bound
+// spans the account's history, so it is rejected too.
+func CapDateRange(maxDays int) QueryHook {
+	return func(q *gaql.Query) (*gaql.Query, error) {
+		days, ok := q.DateSpanDays()
+		if !ok {
+			start, end, bounded := q.DateBounds(time.Now())
+			if !bounded {
+				if selectsMetrics(q) {
+					return nil, fmt.Errorf("metrics are selected without a bounded segments.date range, maximum is %d days", maxDays)
+				}
+				return q, nil
+			}
+			days = int(end.Sub(start).Hours()/24) + 1
+		}
+		if days > maxDays {
+			return nil, fmt.Errorf("date range spans %d days, maximum is %d", days, maxDays)
+		}
+		return q, nil
+	}
+}
+
+// selectsMetrics reports whether q selects a metrics field.
+func selectsMetrics(q *gaql.Query) bool {
+	return slices.ContainsFunc(q.Select, func(f gaql.Field) bool {
+		return strings.HasPrefix(f.Name, "metrics.")
+	})
+}
+
+// CapLimit returns a hook that adds or lowers the LIMIT clause to max.
+func CapLimit(max int) QueryHook {
+	return func(q *gaql.Query) (*gaql.Query, error) {
+		if q.Limit == 0 || q.Limit > max {
+			q.Limit = max
+		}
+		return q, nil
+	}
+}
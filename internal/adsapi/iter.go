@@ -0,0 +1,182 @@
+package adsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/cache"
+)
+
+// Done is returned by a SearchIter iterator after the last row.
+var Done = errors.New("adsapi: no more rows")
+
+// SearchIter returns an iterator over every row of a query. Each call
+// returns the next row, fetching the next page with its page token once
+// the current page is used up, and returns Done after the last row, or
+// ErrTruncated once the client's budget is spent. An error other than
+// Done ends the iteration; later calls repeat it.
+//
+//	next := client.SearchIter(ctx, customerID, query)
+//	for {
+//		row, err := next()
+//		if err == adsapi.Done {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (c *Client) SearchIter(ctx context.Context, customerID, query string) func() (Row, error) {
+	return c.searchIter(ctx, customerID, query, SearchOptions{}, nil, nil)
+}
+
+// Cursor is the position of a SearchIterAt iterator: the page it is
+// reading and how many rows of that page it returned. Saved once those
+// rows are written, it resumes an interrupted iteration at the next
+// row.
+type Cursor struct {
+	// PageToken fetched the page; it is empty for the first page.
+	PageToken string `json:"page_token,omitempty"`
+
+	// Offset is the number of rows of the page returned.
+	Offset int `json:"offset"`
+}
+
+// SearchIterAt is SearchIter starting at cur, the cursor of an earlier
+// iteration of the same query, or at the first row when cur is zero. It
+// keeps cur up to date as rows are returned. The page is fetched again
+// and its first Offset rows skipped, so rows still line up only if the
+// data has not changed since; and the API accepts a page token for a
+// limited time only. Results are not cached, as the cache keeps no page
+// tokens.
+func (c *Client) SearchIterAt(ctx context.Context, customerID, query string, cur *Cursor) func() (Row, error) {
+	return c.searchIter(ctx, customerID, query, SearchOptions{}, nil, cur)
+}
+
+// SearchIterWithOptions is SearchIter with options. The returned totals
+// are filled in from the pages as they arrive; the summary row comes
+// with the last page, so it is complete once the iterator returns Done,
+// and the metadata is kept up to date as rows are returned. Results
+// with a summary row or count are not cached.
+func (c *Client) SearchIterWithOptions(ctx context.Context, customerID, query string, opts SearchOptions) (func() (Row, error), *SearchTotals) {
+	totals := new(SearchTotals)
+	return c.searchIter(ctx, customerID, query, opts, totals, nil), totals
+}
+
+func (c *Client) searchIter(ctx context.Context, customerID, query string, opts SearchOptions, totals *SearchTotals, cur *Cursor) func() (Row, error) {
+	var (
+		page      []Row
+		pageToken string
+		started   bool
+		pages     int
+		skip      int
+		err       error
+	)
+	if cur != nil {
+		pageToken, skip = cur.PageToken, cur.Offset
+		started = pageToken != ""
+	}
+	var (
+		key   string
+		entry *cache.Entry
+	)
+	// The cache keeps rows only, so it would lose the totals and the
+	// cursor.
+	if opts == (SearchOptions{}) && cur == nil {
+		key, entry = c.cacheEntry(customerID, query)
+	}
+	if rows, ok := c.cachedRows(key); ok {
+		page, started = rows, true
+		key = ""
+		if totals != nil {
+			totals.Metadata.Cached = true
+		}
+	}
+	// The rows of each page are encoded as they arrive, before callers
+	// can change them, and saved once the last page is read.
+	var encoded []json.RawMessage
+	size := 0
+	budget := budget{maxRows: c.maxRows, maxBytes: c.maxBytes}
+	var start time.Time
+	return func() (Row, error) {
+		if totals != nil && err == nil {
+			if start.IsZero() {
+				start = time.Now()
+			}
+			defer func() { totals.Metadata.Duration = time.Since(start) }()
+		}
+		if err == ErrTruncated {
+			return nil, err
+		}
+		if budget.full() && (len(page) > 0 || started && pageToken != "") {
+			err = ErrTruncated
+			return nil, err
+		}
+		for len(page) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			if started && pageToken == "" {
+				if key != "" {
+					if data, err := json.Marshal(encoded); err == nil {
+						entry.Rows = data
+						c.cache.Put(key, entry)
+					}
+					key = ""
+				}
+				err = Done
+				return nil, err
+			}
+			if pages > 0 {
+				c.log().DebugContext(ctx, "fetching next page", "customer_id", customerID, "page", pages+1)
+			}
+			var resp *SearchResponse
+			resp, err = c.search(ctx, customerID, query, pageToken, opts, false)
+			if err != nil {
+				return nil, err
+			}
+			pages++
+			if totals != nil {
+				totals.add(resp)
+				totals.Metadata.page(resp)
+			}
+			if cur != nil {
+				*cur = Cursor{PageToken: pageToken}
+			}
+			started = true
+			page, pageToken = resp.Results, resp.NextPageToken
+			if skip > 0 {
+				n := min(skip, len(page))
+				page, skip = page[n:], 0
+				cur.Offset = n
+			}
+			for _, row := range page {
+				if key == "" {
+					break
+				}
+				data, err := json.Marshal(row)
+				if size += len(data); err != nil || size > maxCachedBytes {
+					key, encoded = "", nil
+					break
+				}
+				encoded = append(encoded, data)
+			}
+		}
+		row := page[0]
+		if !budget.take(row) {
+			err = ErrTruncated
+			return nil, err
+		}
+		page = page[1:]
+		if totals != nil {
+			totals.Metadata.Rows++
+		}
+		if cur != nil {
+			cur.Offset++
+		}
+		return row, nil
+	}
+}
@@ -0,0 +1,63 @@
+package adsapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// WithLogger logs what the client does to l: each request at debug
+// level with its credentials redacted, the response status and size,
+// cache hits, and pages fetched; retries and replaced access tokens at
+// info level. Clients without a logger log nothing.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) { c.logger = l }
+}
+
+// log returns the client's logger, which discards everything when none
+// was set.
+func (c *Client) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+	return c.logger
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// redactedHeaders returns the headers of a request as a log attribute,
+// with the access and developer tokens replaced: only their last four
+// characters are kept, enough to tell two tokens apart.
+func redactedHeaders(h http.Header) slog.Attr {
+	attrs := make([]any, 0, len(h))
+	for _, name := range []string{"Authorization", "Developer-Token", "Login-Customer-Id", "Linked-Customer-Id"} {
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+		switch name {
+		case "Authorization":
+			v = "Bearer " + redact(strings.TrimPrefix(v, "Bearer "))
+		case "Developer-Token":
+			v = redact(v)
+		}
+		attrs = append(attrs, slog.String(strings.ToLower(name), v))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// redact hides a secret but for its last four characters.
+func redact(secret string) string {
+	if len(secret) <= 8 {
+		return "REDACTED"
+	}
+	return "REDACTED..." + secret[len(secret)-4:]
+}
@@ -0,0 +1,49 @@
+package adsapi
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerRedactsTokens(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(okBody))
+	}))
+	defer srv.Close()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := New("developer-token-1234", StaticToken("ya29.secret-access-token-wxyz"), WithEndpoint(srv.URL), WithLogger(logger))
+
+	if _, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign"); err != nil {
+		t.Fatal(err)
+	}
+	log := buf.String()
+	for _, secret := range []string{"secret-access-token", "developer-token-1234"} {
+		if strings.Contains(log, secret) {
+			t.Errorf("log contains %q:\n%s", secret, log)
+		}
+	}
+	for _, want := range []string{"msg=search", "msg=request", "REDACTED...wxyz", "REDACTED...1234", "msg=response", "status=200"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log lacks %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", "REDACTED"},
+		{"short", "REDACTED"},
+		{"ya29.abcdefgh", "REDACTED...efgh"},
+	}
+	for _, tt := range tests {
+		if got := redact(tt.in); got != tt.want {
+			t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
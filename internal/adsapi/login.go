@@ -0,0 +1,104 @@
+package adsapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// managerQuery lists every account below a manager, at any depth.
+const managerQuery = "SELECT customer_client.id FROM customer_client"
+
+// WithLinkedCustomerID sets the linked-customer-id header, for reading
+// an account through the link of a third-party app analytics provider.
+func WithLinkedCustomerID(id string) Option {
+	return func(c *Client) { c.linkedCustomerID = strings.ReplaceAll(id, "-", "") }
+}
+
+// WithManagers lets the client find the login-customer-id of each
+// request itself when none is set: the first of managers whose
+// hierarchy holds the customer queried, or the customer itself when it
+// is one of them. Hierarchies are read with one customer_client query
+// per manager, on the first request that needs them, and kept for the
+// life of the client. Customers below none of the managers are queried
+// without a login-customer-id.
+func WithManagers(managers ...string) Option {
+	return func(c *Client) {
+		ids := make([]string, len(managers))
+		for i, m := range managers {
+			ids[i] = strings.ReplaceAll(m, "-", "")
+		}
+		c.managers = &managerLogins{managers: ids}
+	}
+}
+
+// managerLogins maps customers to the configured manager above them.
+type managerLogins struct {
+	managers []string
+
+	once   sync.Once
+	logins map[string]string
+	err    error
+}
+
+// login returns the login-customer-id for a request to customerID.
+func (c *Client) login(ctx context.Context, customerID string) (string, error) {
+	if c.loginCustomerID != "" || c.managers == nil || customerID == "" {
+		return c.loginCustomerID, nil
+	}
+	m := c.managers
+	m.once.Do(func() { m.logins, m.err = c.readManagers(ctx, m.managers) })
+	if m.err != nil {
+		return "", m.err
+	}
+	return m.logins[customerID], nil
+}
+
+// readManagers maps every account below managers to the first manager
+// above it. Managers that cannot be read are skipped, unless none can.
+func (c *Client) readManagers(ctx context.Context, managers []string) (map[string]string, error) {
+	logins := map[string]string{}
+	var lastErr error
+	read := 0
+	for _, m := range managers {
+		if _, ok := logins[m]; !ok {
+			logins[m] = m
+		}
+		next := c.WithLogin(m).SearchIter(ctx, m, managerQuery)
+		var err error
+		for row, e := next(); e != Done; row, e = next() {
+			if err = e; err != nil {
+				break
+			}
+			if id := str(row, "customerClient", "id"); id != "" {
+				if _, ok := logins[id]; !ok {
+					logins[id] = m
+				}
+			}
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("adsapi: reading the accounts of manager %s: %w", m, err)
+			continue
+		}
+		read++
+	}
+	if read == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return logins, nil
+}
+
+// str returns the string at the path of keys in row, or "".
+func str(row Row, keys ...string) string {
+	var v any = map[string]any(row)
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = m[k]
+	}
+	s, _ := v.(string)
+	return s
+}
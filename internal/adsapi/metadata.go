@@ -0,0 +1,41 @@
+package adsapi
+
+import "time"
+
+// SearchMetadata describes how a search ran, for reporting problems to
+// Google Ads API support, which asks for the request ID of each call.
+type SearchMetadata struct {
+	// RequestIDs are the request IDs of the pages fetched, in order.
+	RequestIDs []string
+
+	// Duration is the time from sending the first request to reading
+	// the last row; for a search cut short, to the last row returned.
+	Duration time.Duration
+
+	// Pages is the number of pages fetched and Rows the number of rows
+	// returned.
+	Pages int
+	Rows  int
+
+	// Cached is set when the rows came from the result cache, so no
+	// request was sent.
+	Cached bool
+}
+
+// page records a page fetched.
+func (m *SearchMetadata) page(resp *SearchResponse) {
+	m.Pages++
+	if resp.RequestID != "" {
+		m.RequestIDs = append(m.RequestIDs, resp.RequestID)
+	}
+}
+
+// Merge adds the requests and rows of o, a search run alongside m, such
+// as the search of another account. Duration is left to the caller,
+// which knows how the two overlapped.
+func (m *SearchMetadata) Merge(o SearchMetadata) {
+	m.RequestIDs = append(m.RequestIDs, o.RequestIDs...)
+	m.Pages += o.Pages
+	m.Rows += o.Rows
+	m.Cached = m.Cached || o.Cached
+}
@@ -0,0 +1,63 @@
+package adsapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/cache"
+)
+
+func TestSearchMetadata(t *testing.T) {
+	pages := map[string]string{
+		"":       `{"results": [{"campaign": {"id": "1"}}, {"campaign": {"id": "2"}}], "nextPageToken": "page-2"}`,
+		"page-2": `{"results": [{"campaign": {"id": "3"}}], "requestId": "req-body"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			PageToken string `json:"pageToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("request-id", "req-"+strings.TrimPrefix(req.PageToken, "page-"))
+		w.Write([]byte(pages[req.PageToken]))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want SearchMetadata
+	}{
+		{"every page", nil, SearchMetadata{RequestIDs: []string{"req-", "req-body"}, Pages: 2, Rows: 3}},
+		{"truncated", []Option{WithMaxRows(1)}, SearchMetadata{RequestIDs: []string{"req-"}, Pages: 1, Rows: 1}},
+		{"cached", []Option{WithCache(cache.New(t.TempDir(), time.Hour))}, SearchMetadata{Rows: 3, Cached: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("dev-token", StaticToken("access-token"), append([]Option{WithEndpoint(srv.URL)}, tt.opts...)...)
+			if tt.want.Cached {
+				// The first run fills the cache.
+				if _, err := c.SearchAll(context.Background(), "1234567890", "SELECT campaign.id FROM campaign"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			res, err := c.SearchAll(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := res.Metadata
+			if got.Pages > 0 && got.Duration <= 0 {
+				t.Errorf("duration %v, want it measured", got.Duration)
+			}
+			got.Duration = 0
+			if strings.Join(got.RequestIDs, ",") != strings.Join(tt.want.RequestIDs, ",") ||
+				got.Pages != tt.want.Pages || got.Rows != tt.want.Rows || got.Cached != tt.want.Cached {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RateLimits configures a Limiter. Zero fields disable the matching
+// limit.
+type RateLimits struct {
+	// QPS and Burst bound requests made with the developer token across
+	// all customers.
+	QPS   float64
+	Burst int
+
+	// CustomerQPS bounds requests to any single customer.
+	CustomerQPS float64
+
+	// DailyOperations caps the operations made with the developer token
+	// per UTC day; each request, including every page and retry, counts
+	// as one. Basic access allows 15,000.
+	DailyOperations int
+
+	// DailyOperationsDir, when set, keeps the count of DailyOperations
+	// in this directory, one per developer token, so the separate
+	// processes of cron jobs and scripts draw from one budget. Without
+	// it each Limiter counts only its own requests.
+	DailyOperationsDir string
+}
+
+// ErrDailyBudget is returned once a Limiter's DailyOperations are used
+// up for the day.
+var ErrDailyBudget = errors.New("adsapi: daily operation budget exhausted")
+
+// Limiter paces requests with token buckets, one for the developer
+// token and one per customer. A Limiter is safe for concurrent use;
+// share one between every client and goroutine using the same developer
+// token so they draw from the same budget.
+type Limiter struct {
+	limits RateLimits
+
+	mu        sync.Mutex
+	token     bucket
+	customers map[string]*bucket
+	day       string
+	ops       int
+	stats     LimiterStats
+
+	// now and sleep are replaced by tests.
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// LimiterStats reports how much a Limiter has held requests back.
+type LimiterStats struct {
+	Requests  int           // requests admitted
+	Throttled int           // requests that had to wait
+	Wait      time.Duration // total time spent waiting
+}
+
+// NewLimiter returns a Limiter enforcing limits.
+func NewLimiter(limits RateLimits) *Limiter {
+	return &Limiter{
+		limits:    limits,
+		token:     newBucket(limits.QPS, limits.Burst),
+		customers: make(map[string]*bucket),
+		now:       time.Now,
+		sleep:     sleep,
+	}
+}
+
+// WithRateLimiter paces every request through l.
+func WithRateLimiter(l *Limiter) Option {
+	return func(c *Client) { c.limiter = l }
+}
+
+// Wait blocks until a request to customerID may be sent, or returns an
+// error when ctx is done first or the daily budget is exhausted. An
+// empty customerID, for requests not made on behalf of a customer, only
+// draws from the developer token's budget.
+func (l *Limiter) Wait(ctx context.Context, customerID string) error {
+	l.mu.Lock()
+	now := l.now()
+	if day := now.UTC().Format(time.DateOnly); day != l.day {
+		l.day, l.ops = day, 0
+		l.pruneDays()
+	}
+	if l.limits.DailyOperations > 0 {
+		used := l.ops
+		if l.limits.DailyOperationsDir != "" {
+			var err error
+			if used, err = l.countOperation(); err != nil {
+				l.mu.Unlock()
+				return fmt.Errorf("adsapi: counting daily operations: %w", err)
+			}
+		}
+		if used >= l.limits.DailyOperations {
+			l.mu.Unlock()
+			return fmt.Errorf("%w (%d operations)", ErrDailyBudget, l.limits.DailyOperations)
+		}
+	}
+	l.ops++
+
+	// Reserve a token from both buckets now, so concurrent callers queue
+	// behind each other, then wait outside the lock.
+	wait := l.token.reserve(now)
+	if l.limits.CustomerQPS > 0 && customerID != "" {
+		b, ok := l.customers[customerID]
+		if !ok {
+			nb := newBucket(l.limits.CustomerQPS, 1)
+			b = &nb
+			l.customers[customerID] = b
+		}
+		wait = max(wait, b.reserve(now))
+	}
+	l.stats.Requests++
+	if wait > 0 {
+		l.stats.Throttled++
+		l.stats.Wait += wait
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	return l.sleep(ctx, wait)
+}
+
+// countOperation records an operation of the day in DailyOperationsDir
+// and returns the number recorded before it, by every process. Each
+// operation appends a byte to the day's file, so the count is its size
+// and concurrent processes need no lock. Nothing is recorded once the
+// budget is used up.
+func (l *Limiter) countOperation() (int, error) {
+	if err := os.MkdirAll(l.limits.DailyOperationsDir, 0o700); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(filepath.Join(l.limits.DailyOperationsDir, l.day), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	used := int(fi.Size())
+	if used >= l.limits.DailyOperations {
+		return used, nil
+	}
+	_, err = f.Write([]byte{'.'})
+	return used, err
+}
+
+// pruneDays removes the counts of days before the current one from
+// DailyOperationsDir.
+func (l *Limiter) pruneDays() {
+	if l.limits.DailyOperationsDir == "" {
+		return
+	}
+	entries, _ := os.ReadDir(l.limits.DailyOperationsDir)
+	for _, e := range entries {
+		if e.Name() < l.day && !e.IsDir() {
+			os.Remove(filepath.Join(l.limits.DailyOperationsDir, e.Name()))
+		}
+	}
+}
+
+// Stats returns the requests admitted and the time spent throttling
+// them so far.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// bucket is a token bucket refilled at rate tokens per second up to
+// size. A zero rate never limits.
+type bucket struct {
+	rate   float64
+	size   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, size int) bucket {
+	size = max(size, 1)
+	return bucket{rate: rate, size: float64(size), tokens: float64(size)}
+}
+
+// reserve takes a token and returns how long the caller must wait for
+// it. Tokens may go negative: each reservation queues behind the last.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	if !b.last.IsZero() {
+		b.tokens = min(b.size, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,154 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLimiter returns a limiter on a fixed clock that records waits
+// instead of sleeping.
+func fakeLimiter(limits RateLimits, now *time.Time) (*Limiter, *[]time.Duration) {
+	var waits []time.Duration
+	l := NewLimiter(limits)
+	l.now = func() time.Time { return *now }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return l, &waits
+}
+
+func TestLimiter(t *testing.T) {
+	start := time.Date(2026, 3, 2, 23, 59, 59, 0, time.UTC)
+	tests := []struct {
+		name      string
+		limits    RateLimits
+		customers []string
+		waits     []time.Duration
+	}{
+		{
+			name:      "token QPS queues requests",
+			limits:    RateLimits{QPS: 2},
+			customers: []string{"1", "2", "3"},
+			waits:     []time.Duration{500 * time.Millisecond, time.Second},
+		},
+		{
+			name:      "burst",
+			limits:    RateLimits{QPS: 1, Burst: 2},
+			customers: []string{"1", "1", "1"},
+			waits:     []time.Duration{time.Second},
+		},
+		{
+			name:      "per-customer QPS",
+			limits:    RateLimits{CustomerQPS: 1},
+			customers: []string{"1", "2", "1", "1"},
+			waits:     []time.Duration{time.Second, 2 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start
+			l, waits := fakeLimiter(tt.limits, &now)
+			for _, c := range tt.customers {
+				if err := l.Wait(context.Background(), c); err != nil {
+					t.Fatalf("Wait: %v", err)
+				}
+			}
+			if len(*waits) != len(tt.waits) {
+				t.Fatalf("waited %v, want %v", *waits, tt.waits)
+			}
+			var total time.Duration
+			for i, w := range *waits {
+				if w != tt.waits[i] {
+					t.Errorf("wait %d = %v, want %v", i, w, tt.waits[i])
+				}
+				total += w
+			}
+			stats := l.Stats()
+			if stats.Requests != len(tt.customers) || stats.Throttled != len(tt.waits) || stats.Wait != total {
+				t.Errorf("unexpected stats %+v", stats)
+			}
+		})
+	}
+}
+
+func TestLimiterDailyBudget(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{name: "in memory"},
+		{name: "shared by processes", dir: t.TempDir()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 3, 2, 23, 59, 59, 0, time.UTC)
+			limits := RateLimits{DailyOperations: 2, DailyOperationsDir: tt.dir}
+			l, _ := fakeLimiter(limits, &now)
+			ctx := context.Background()
+
+			if err := l.Wait(ctx, "1"); err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+			// A second process, such as the next cron run, draws from the
+			// same budget when the count is kept in a directory.
+			other, _ := fakeLimiter(limits, &now)
+			if tt.dir == "" {
+				other = l
+			}
+			if err := other.Wait(ctx, "1"); err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+			if err := l.Wait(ctx, "1"); !errors.Is(err, ErrDailyBudget) {
+				t.Fatalf("expected ErrDailyBudget, got %v", err)
+			}
+			now = now.Add(time.Second)
+			if err := l.Wait(ctx, "1"); err != nil {
+				t.Errorf("budget should reset at midnight UTC, got %v", err)
+			}
+			if tt.dir != "" {
+				if _, err := os.Stat(filepath.Join(tt.dir, "2026-03-02")); !os.IsNotExist(err) {
+					t.Errorf("the count of the day before was kept: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	l := NewLimiter(RateLimits{QPS: 1000, CustomerQPS: 1000})
+	l.sleep = func(context.Context, time.Duration) error { return nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Wait(context.Background(), "1234567890")
+		}()
+	}
+	wg.Wait()
+	if got := l.Stats().Requests; got != 50 {
+		t.Errorf("admitted %d requests, want 50", got)
+	}
+}
+
+func TestClientRateLimiter(t *testing.T) {
+	l := NewLimiter(RateLimits{DailyOperations: 1})
+	c, _ := newTestClient(t, http.StatusOK, `{"results": []}`, WithRateLimiter(l))
+	ctx := context.Background()
+
+	if _, err := c.Search(ctx, "1234567890", "SELECT campaign.id FROM campaign"); err != nil {
+		t.Fatalf("first search: %v", err)
+	}
+	if _, err := c.Search(ctx, "1234567890", "SELECT campaign.id FROM campaign"); !errors.Is(err, ErrDailyBudget) {
+		t.Errorf("expected ErrDailyBudget, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Only transient
+// failures are retried: quota exhaustion, internal and unavailable
+// errors, and network errors. Requests the API rejected, such as queries
+// with a queryError, fail at once.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// 1 disables retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry; each further
+	// retry multiplies it by Multiplier, up to MaxBackoff. A request
+	// whose server asks for a longer delay than MaxBackoff is not
+	// retried.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter randomizes each delay by up to this fraction, so many
+	// clients hitting the same quota do not retry in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is used by clients created without WithRetry.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// NoRetry makes every request a single attempt.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// WithRetry sets the retry policy.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// backoff returns the delay before retry number n (1-based). A delay the
+// server asked for takes precedence; the client fails rather than retry
+// when that delay exceeds MaxBackoff.
+func (p RetryPolicy) backoff(n int, serverDelay time.Duration) time.Duration {
+	if serverDelay > 0 {
+		return serverDelay
+	}
+	d := float64(p.InitialBackoff)
+	for i := 1; i < n; i++ {
+		d *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// retryable reports whether err is worth another attempt, and the delay
+// the server asked for.
+func retryable(ctx context.Context, err error) (bool, time.Duration) {
+	if ctx.Err() != nil {
+		return false, 0
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Transport errors: connection resets, timeouts, DNS hiccups.
+		// Errors obtaining a token are not retried here.
+		var tokenErr *tokenError
+		return !errors.As(err, &tokenErr), 0
+	}
+	if apiErr.HasCategory("queryError") || apiErr.HasCategory("authenticationError") || apiErr.HasCategory("authorizationError") {
+		return false, 0
+	}
+	for _, ge := range apiErr.Errors {
+		switch ge.Category + "." + ge.Code {
+		case "quotaError.RESOURCE_EXHAUSTED", "quotaError.RESOURCE_TEMPORARILY_EXHAUSTED",
+			"internalError.INTERNAL_ERROR", "internalError.TRANSIENT_ERROR", "internalError.DEADLINE_EXCEEDED":
+			return true, apiErr.RetryDelay
+		}
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, apiErr.RetryDelay
+	}
+	return false, 0
+}
+
+// tokenError wraps a failure to obtain an access token, which retrying
+// the API request cannot fix.
+type tokenError struct{ err error }
+
+func (e *tokenError) Error() string { return "adsapi: obtaining access token: " + e.err.Error() }
+func (e *tokenError) Unwrap() error { return e.err }
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
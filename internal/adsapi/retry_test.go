@@ -0,0 +1,159 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	quotaBody = `{"error": {"code": 429, "message": "Resource has been exhausted.", "status": "RESOURCE_EXHAUSTED", "details": [
+		{"@type": "type.googleapis.com/google.ads.googleads.v23.errors.GoogleAdsFailure",
+		 "errors": [{"errorCode": {"quotaError": "RESOURCE_TEMPORARILY_EXHAUSTED"}, "message": "Too many requests."}],
+		 "requestId": "req-quota"},
+		{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "7s"}]}}`
+	queryBody = `{"error": {"code": 400, "message": "Request contains an invalid argument.", "status": "INVALID_ARGUMENT", "details": [
+		{"@type": "type.googleapis.com/google.ads.googleads.v23.errors.GoogleAdsFailure",
+		 "errors": [{"errorCode": {"queryError": "UNRECOGNIZED_FIELD"}, "message": "Unrecognized field in the query: 'campaign.nme'.",
+		   "trigger": {"stringValue": "campaign.nme"}, "location": {"fieldPathElements": [{"fieldName": "query"}]}}],
+		 "requestId": "req-query"}]}}`
+	internalBody = `{"error": {"code": 500, "message": "Internal error encountered.", "status": "INTERNAL"}}`
+	okBody       = `{"results": [{"campaign": {"id": "1"}}]}`
+)
+
+type reply struct {
+	status     int
+	body       string
+	retryAfter string
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		replies  []reply
+		policy   RetryPolicy
+		attempts int
+		delays   []time.Duration
+		wantErr  string
+	}{
+		{
+			name:     "quota error honours RetryInfo",
+			replies:  []reply{{429, quotaBody, ""}, {200, okBody, ""}},
+			attempts: 2,
+			delays:   []time.Duration{7 * time.Second},
+		},
+		{
+			name:     "Retry-After header wins",
+			replies:  []reply{{503, internalBody, "3"}, {200, okBody, ""}},
+			attempts: 2,
+			delays:   []time.Duration{3 * time.Second},
+		},
+		{
+			name:     "server delay beyond MaxBackoff fails",
+			replies:  []reply{{503, internalBody, "7200"}, {200, okBody, ""}},
+			attempts: 1,
+			wantErr:  "the API asked to retry after 2h0m0s, longer than the longest backoff (30s)",
+		},
+		{
+			name:     "exponential backoff",
+			replies:  []reply{{500, internalBody, ""}, {500, internalBody, ""}, {500, internalBody, ""}, {200, okBody, ""}},
+			policy:   RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second, Multiplier: 2},
+			attempts: 4,
+			delays:   []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:     "query errors are never retried",
+			replies:  []reply{{400, queryBody, ""}, {200, okBody, ""}},
+			attempts: 1,
+			wantErr:  `queryError.UNRECOGNIZED_FIELD: Unrecognized field in the query: 'campaign.nme'. (trigger "campaign.nme", at query)`,
+		},
+		{
+			name:     "gives up after max attempts",
+			replies:  []reply{{500, internalBody, ""}, {500, internalBody, ""}},
+			policy:   RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, Multiplier: 2},
+			attempts: 2,
+			delays:   []time.Duration{time.Second},
+			wantErr:  "INTERNAL (HTTP 500)",
+		},
+		{
+			name:     "no retry",
+			replies:  []reply{{500, internalBody, ""}, {200, okBody, ""}},
+			policy:   NoRetry,
+			attempts: 1,
+			wantErr:  "INTERNAL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rep := tt.replies[attempts]
+				attempts++
+				if rep.retryAfter != "" {
+					w.Header().Set("Retry-After", rep.retryAfter)
+				}
+				w.WriteHeader(rep.status)
+				w.Write([]byte(rep.body))
+			}))
+			defer srv.Close()
+
+			opts := []Option{WithEndpoint(srv.URL)}
+			if tt.policy.MaxAttempts > 0 {
+				opts = append(opts, WithRetry(tt.policy))
+			}
+			c := New("dev-token", StaticToken("access-token"), opts...)
+			var delays []time.Duration
+			c.sleep = func(_ context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			}
+
+			_, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("made %d attempts, want %d", attempts, tt.attempts)
+			}
+			if len(delays) != len(tt.delays) {
+				t.Fatalf("slept %v, want %v", delays, tt.delays)
+			}
+			for i := range delays {
+				if delays[i] != tt.delays[i] {
+					t.Errorf("delay %d = %v, want %v", i, delays[i], tt.delays[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGoogleAdsFailureDetails(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusBadRequest, queryBody)
+	_, err := c.Search(context.Background(), "1234567890", "SELECT campaign.nme FROM campaign")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Errors) != 1 {
+		t.Fatalf("expected one GoogleAdsError, got %v", err)
+	}
+	ge := apiErr.Errors[0]
+	if ge.Category != "queryError" || ge.Code != "UNRECOGNIZED_FIELD" || ge.Trigger != "campaign.nme" || strings.Join(ge.FieldPath, ".") != "query" {
+		t.Errorf("unexpected details %+v", ge)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, Multiplier: 2, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if d := p.backoff(2, 0); d < time.Second || d > 3*time.Second {
+			t.Fatalf("jittered delay %v outside [1s, 3s]", d)
+		}
+	}
+}
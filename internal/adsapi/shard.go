@@ -0,0 +1,181 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/backfill"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// campaignIDsQuery lists the campaigns SearchSharded splits a query by.
+var campaignIDsQuery = gaql.Select("campaign.id").From("campaign").OrderBy("campaign.id", gaql.Asc).String()
+
+// ErrNotShardable is returned by SearchSharded for a query it cannot
+// split as asked, wrapped with the reason.
+var ErrNotShardable = errors.New("adsapi: the query cannot be sharded")
+
+// ShardOptions configures SearchSharded.
+type ShardOptions struct {
+	// Days is the number of days of each date shard; 0 means one.
+	Days int
+
+	// CampaignsPerShard, when positive, also splits each date shard by
+	// campaign: the account's campaign IDs are read first, and each
+	// shard asks for up to this many of them with campaign.id IN (...).
+	CampaignsPerShard int
+
+	// Concurrency is the number of shards run at once; 0 means
+	// DefaultConcurrency.
+	Concurrency int
+
+	// Today resolves date ranges such as LAST_30_DAYS; zero means the
+	// local date. The API resolves them in the account's time zone.
+	Today time.Time
+}
+
+// SearchSharded runs q for customerID as many smaller queries, run
+// concurrently, and merges their rows: one per Days days of its
+// segments.date range and, with CampaignsPerShard, per group of
+// campaigns. Queries segmented by date, hour, and device return too
+// many rows to read quickly in one stream; shards read them in
+// parallel.
+//
+// Each shard replaces the date conditions of q with its own days, so q
+// must select segments.date, keeping the rows of different shards
+// apart, and set a lower bound on it; only splitting by campaign works
+// without. Splitting by campaign suits resources whose rows each belong
+// to one campaign. Rows are merged in shard order, by date and then
+// campaign; ORDER BY applies within each shard, and queries with LIMIT
+// are rejected, as no shard can honor it. Queries that cannot be split
+// fail with ErrNotShardable before any is sent.
+//
+// The first shard to fail cancels the others and its error is
+// returned. The client's budget applies to each shard; the result is
+// Truncated when any shard was cut short. Its metadata holds the
+// requests of every shard, in shard order, and the time they all took.
+//
+//	res, err := c.SearchSharded(ctx, "1234567890", q, adsapi.ShardOptions{Days: 7, Concurrency: 8})
+func (c *Client) SearchSharded(ctx context.Context, customerID string, q *gaql.Query, opts ShardOptions) (*Result, error) {
+	start := time.Now()
+	shards, err := c.shardQueries(ctx, customerID, q, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.log().DebugContext(ctx, "running shards", "customer_id", customerID, "shards", len(shards))
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	workers = min(workers, len(shards))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	results := make([]Result, len(shards))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := c.readShard(ctx, customerID, shards[i], &results[i]); err != nil {
+					cancel(fmt.Errorf("shard %d of %d: %w", i+1, len(shards), err))
+				}
+			}
+		}()
+	}
+	for i := range shards {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	res := new(Result)
+	for _, r := range results {
+		res.Rows = append(res.Rows, r.Rows...)
+		res.Truncated = res.Truncated || r.Truncated
+		res.Metadata.Merge(r.Metadata)
+	}
+	res.Metadata.Duration = time.Since(start)
+	return res, nil
+}
+
+// readShard reads every row of one shard into res.
+func (c *Client) readShard(ctx context.Context, customerID string, q *gaql.Query, res *Result) error {
+	next, totals := c.SearchIterWithOptions(ctx, customerID, q.String(), SearchOptions{})
+	defer func() { res.Metadata = totals.Metadata }()
+	for {
+		row, err := next()
+		switch {
+		case err == Done:
+			return nil
+		case err == ErrTruncated:
+			res.Truncated = true
+			return nil
+		case err != nil:
+			return err
+		}
+		res.Rows = append(res.Rows, row)
+	}
+}
+
+// shardQueries splits q as opts asks, reading the campaign IDs of
+// customerID when they are needed.
+func (c *Client) shardQueries(ctx context.Context, customerID string, q *gaql.Query, opts ShardOptions) ([]*gaql.Query, error) {
+	if q.Limit > 0 {
+		return nil, fmt.Errorf("%w: it has a LIMIT, which each shard would apply", ErrNotShardable)
+	}
+	today := opts.Today
+	if today.IsZero() {
+		today = time.Now()
+	}
+	days := max(opts.Days, 1)
+
+	shards := []*gaql.Query{q}
+	if start, end, ok := q.DateBounds(today); ok {
+		if !slices.ContainsFunc(q.Select, func(f gaql.Field) bool { return f.Name == "segments.date" }) {
+			return nil, fmt.Errorf("%w by date: it does not select segments.date, which keeps the rows of different shards apart", ErrNotShardable)
+		}
+		shards = shards[:0]
+		for _, w := range backfill.Windows(start, end, days) {
+			shards = append(shards, backfill.Query(q, w))
+		}
+	} else if opts.CampaignsPerShard <= 0 {
+		return nil, fmt.Errorf("%w by date: its segments.date range has no start", ErrNotShardable)
+	}
+
+	if opts.CampaignsPerShard <= 0 {
+		return shards, nil
+	}
+	// Every campaign is needed, whatever the budget of the shards.
+	var ids []string
+	next := c.WithBudget(0, 0).SearchIter(ctx, customerID, campaignIDsQuery)
+	for row, err := next(); err != Done; row, err = next() {
+		if err != nil {
+			return nil, fmt.Errorf("adsapi: reading the campaigns to shard by: %w", err)
+		}
+		ids = append(ids, str(row, "campaign", "id"))
+	}
+	var out []*gaql.Query
+	for _, s := range shards {
+		for i := 0; i < len(ids); i += opts.CampaignsPerShard {
+			group := ids[i:min(i+opts.CampaignsPerShard, len(ids))]
+			cq := s.Clone()
+			cq.Where = append(cq.Where, gaql.Condition{Field: "campaign.id", Operator: gaql.OpIn, Value: gaql.ListValue(group...)})
+			out = append(out, cq)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,140 @@
+package adsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestSearchSharded(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Query == campaignIDsQuery {
+			w.Write([]byte(`{"results": [{"campaign": {"id": "11"}}, {"campaign": {"id": "12"}}, {"campaign": {"id": "13"}}]}`))
+			return
+		}
+		mu.Lock()
+		queries = append(queries, req.Query)
+		mu.Unlock()
+		if strings.Contains(req.Query, "'2026-01-15'") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"code": 400, "message": "Request contains an invalid argument.", "status": "INVALID_ARGUMENT"}}`))
+			return
+		}
+		// Each shard answers with its own query, to check the order.
+		json.NewEncoder(w).Encode(map[string]any{"results": []Row{{"query": req.Query}}})
+	}))
+	defer srv.Close()
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL), WithRetry(NoRetry))
+	today := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		query   string
+		opts    ShardOptions
+		want    []string // the WHERE clauses of the shards, in order
+		wantErr string
+		api     bool // the error comes from the API, not ErrNotShardable
+	}{
+		{
+			name:  "by week",
+			query: "SELECT segments.date, metrics.clicks FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-10'",
+			opts:  ShardOptions{Days: 7, Today: today},
+			want: []string{
+				"segments.date BETWEEN '2026-01-01' AND '2026-01-07'",
+				"segments.date BETWEEN '2026-01-08' AND '2026-01-10'",
+			},
+		},
+		{
+			name:  "by day",
+			query: "SELECT segments.date, segments.hour, metrics.clicks FROM campaign WHERE segments.date DURING YESTERDAY AND campaign.status = 'ENABLED'",
+			opts:  ShardOptions{Today: today, Concurrency: 2},
+			want:  []string{"campaign.status = 'ENABLED' AND segments.date BETWEEN '2026-03-03' AND '2026-03-03'"},
+		},
+		{
+			name:  "by campaign",
+			query: "SELECT campaign.id, metrics.clicks FROM campaign",
+			opts:  ShardOptions{CampaignsPerShard: 2},
+			want:  []string{"campaign.id IN (11, 12)", "campaign.id IN (13)"},
+		},
+		{
+			name:  "by date and campaign",
+			query: "SELECT segments.date, metrics.clicks FROM ad_group WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-02'",
+			opts:  ShardOptions{CampaignsPerShard: 2, Today: today},
+			want: []string{
+				"segments.date BETWEEN '2026-01-01' AND '2026-01-01' AND campaign.id IN (11, 12)",
+				"segments.date BETWEEN '2026-01-01' AND '2026-01-01' AND campaign.id IN (13)",
+				"segments.date BETWEEN '2026-01-02' AND '2026-01-02' AND campaign.id IN (11, 12)",
+				"segments.date BETWEEN '2026-01-02' AND '2026-01-02' AND campaign.id IN (13)",
+			},
+		},
+		{
+			name:    "limit",
+			query:   "SELECT segments.date, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS LIMIT 10",
+			wantErr: "LIMIT",
+		},
+		{
+			name:    "date not selected",
+			query:   "SELECT metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS",
+			wantErr: "select segments.date",
+		},
+		{
+			name:    "no date range",
+			query:   "SELECT segments.date, metrics.clicks FROM campaign",
+			wantErr: "segments.date range",
+		},
+		{
+			name:    "failing shard",
+			query:   "SELECT segments.date, metrics.clicks FROM campaign WHERE segments.date BETWEEN '2026-01-14' AND '2026-01-16'",
+			opts:    ShardOptions{Today: today},
+			wantErr: "INVALID_ARGUMENT",
+			api:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := gaql.Parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			queries = nil
+			res, err := c.SearchSharded(context.Background(), "1234567890", q, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want one mentioning %q", err, tt.wantErr)
+				}
+				if errors.Is(err, ErrNotShardable) == tt.api {
+					t.Errorf("errors.Is(%v, ErrNotShardable) = %v", err, !tt.api)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, row := range res.Rows {
+				_, where, _ := strings.Cut(row["query"].(string), " WHERE ")
+				got = append(got, where)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("shards:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if len(queries) != len(tt.want) {
+				t.Errorf("sent %d shard queries, want %d", len(queries), len(tt.want))
+			}
+		})
+	}
+}
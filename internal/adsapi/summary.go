@@ -0,0 +1,53 @@
+package adsapi
+
+import "context"
+
+// SearchOptions are optional parts of a search request.
+type SearchOptions struct {
+	// ReturnSummaryRow asks for the summary row: the selected metrics
+	// aggregated over every result, as the API computes them (sums for
+	// counts, weighted ratios for rates). Fields other than metrics are
+	// not part of it.
+	ReturnSummaryRow bool
+
+	// ReturnTotalResultsCount asks for the number of results matching
+	// the query, ignoring its LIMIT.
+	ReturnTotalResultsCount bool
+}
+
+// apply adds the options to a search request body.
+func (o SearchOptions) apply(body map[string]any) {
+	if o.ReturnSummaryRow {
+		body["summaryRowSetting"] = "SUMMARY_ROW_WITH_RESULTS"
+	}
+	if o.ReturnTotalResultsCount {
+		body["returnTotalResultsCount"] = true
+	}
+}
+
+// SearchTotals are the totals a search returns besides its rows, when
+// SearchOptions asks for them, and how it ran.
+type SearchTotals struct {
+	SummaryRow        Row   // nil until the page carrying it is read
+	TotalResultsCount int64 // results ignoring LIMIT
+	Metadata          SearchMetadata
+}
+
+func (t *SearchTotals) add(resp *SearchResponse) {
+	if resp.SummaryRow != nil {
+		t.SummaryRow = resp.SummaryRow
+	}
+	if resp.TotalResultsCount > 0 {
+		t.TotalResultsCount = resp.TotalResultsCount
+	}
+}
+
+// SearchWithOptions is Search with options; the summary row and count
+// are in the response. It bypasses the result cache, which keeps rows
+// only.
+func (c *Client) SearchWithOptions(ctx context.Context, customerID, query string, opts SearchOptions) (*SearchResponse, error) {
+	if opts == (SearchOptions{}) {
+		return c.Search(ctx, customerID, query)
+	}
+	return c.search(ctx, customerID, query, "", opts, false)
+}
@@ -0,0 +1,67 @@
+package adsapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchIterWithOptions(t *testing.T) {
+	pages := map[string]string{
+		"":       `{"results": [{"metrics": {"clicks": "4"}}], "nextPageToken": "page-2", "totalResultsCount": "2"}`,
+		"page-2": `{"results": [{"metrics": {"clicks": "6"}}], "totalResultsCount": "2", "summaryRow": {"metrics": {"clicks": "10"}}}`,
+	}
+	tests := []struct {
+		name        string
+		opts        SearchOptions
+		wantSetting string
+		wantCount   bool
+	}{
+		{"none", SearchOptions{}, "", false},
+		{"summary row", SearchOptions{ReturnSummaryRow: true}, "SUMMARY_ROW_WITH_RESULTS", false},
+		{"both", SearchOptions{ReturnSummaryRow: true, ReturnTotalResultsCount: true}, "SUMMARY_ROW_WITH_RESULTS", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					PageToken               string `json:"pageToken"`
+					SummaryRowSetting       string `json:"summaryRowSetting"`
+					ReturnTotalResultsCount bool   `json:"returnTotalResultsCount"`
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				if req.SummaryRowSetting != tt.wantSetting || req.ReturnTotalResultsCount != tt.wantCount {
+					t.Errorf("page %q: summaryRowSetting %q, returnTotalResultsCount %v", req.PageToken, req.SummaryRowSetting, req.ReturnTotalResultsCount)
+				}
+				w.Write([]byte(pages[req.PageToken]))
+			}))
+			defer srv.Close()
+
+			c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL))
+			next, totals := c.SearchIterWithOptions(context.Background(), "1234567890", "SELECT metrics.clicks FROM customer", tt.opts)
+			rows := 0
+			for {
+				_, err := next()
+				if err == Done {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				rows++
+			}
+			if rows != 2 {
+				t.Errorf("got %d rows, want 2", rows)
+			}
+			if totals.TotalResultsCount != 2 {
+				t.Errorf("TotalResultsCount = %d, want 2", totals.TotalResultsCount)
+			}
+			clicks := totals.SummaryRow["metrics"].(map[string]any)["clicks"]
+			if clicks != "10" {
+				t.Errorf("summary clicks = %v, want 10", clicks)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Names of the measurements recorded through a MeterProvider. Each is
+// labeled with the customer_id and resource of the search.
+const (
+	// MetricQueryDuration is a histogram of the seconds a search request
+	// took, retries and backoff included.
+	MetricQueryDuration = "adtap.query.duration"
+
+	// MetricQueryRows counts the rows returned.
+	MetricQueryRows = "adtap.query.rows"
+
+	// MetricQueryRetries counts the attempts retried after a transient
+	// failure.
+	MetricQueryRetries = "adtap.query.retries"
+
+	// MetricQuotaErrors counts the quotaError responses received, whether
+	// or not a retry then succeeded.
+	MetricQuotaErrors = "adtap.query.quota_errors"
+
+	// MetricResponseBytes counts the bytes of response bodies read.
+	MetricResponseBytes = "adtap.response.bytes"
+)
+
+// Attribute is a label of a span or measurement. Values are strings,
+// int64s, or bools.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// TracerProvider starts the spans of search requests. It mirrors the
+// part of an OpenTelemetry tracer the client uses, so a few lines adapt
+// one:
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, name string, attrs ...adsapi.Attribute) (context.Context, adsapi.Span) {
+//		ctx, span := o.t.Start(ctx, name, trace.WithAttributes(toKeyValues(attrs)...))
+//		return ctx, otelSpan{span}
+//	}
+type TracerProvider interface {
+	// Start begins a span named name, a child of any span in ctx, and
+	// returns a context carrying it.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a span begun by a TracerProvider.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// MeterProvider records the measurements named by the Metric constants,
+// as OpenTelemetry counters and histograms would.
+type MeterProvider interface {
+	// Add adds n to the counter name.
+	Add(ctx context.Context, name string, n int64, attrs ...Attribute)
+
+	// Record records v in the histogram name.
+	Record(ctx context.Context, name string, v float64, attrs ...Attribute)
+}
+
+// WithTracerProvider traces every search request: one span per request,
+// so SearchIter makes one per page, with the customer ID, resource, rows,
+// retries, quota errors, response bytes, and request ID as attributes.
+func WithTracerProvider(tp TracerProvider) Option {
+	return func(c *Client) { c.tracer = tp }
+}
+
+// WithMeterProvider records the latency, rows, retries, quota errors, and
+// response bytes of every search request.
+func WithMeterProvider(mp MeterProvider) Option {
+	return func(c *Client) { c.meter = mp }
+}
+
+// callStats counts what happens within one instrumented request. do and
+// send find it in the request's context; a nil *callStats counts
+// nothing.
+type callStats struct {
+	retries     int64
+	quotaErrors int64
+	bytes       int64
+}
+
+type callStatsKey struct{}
+
+func statsFrom(ctx context.Context) *callStats {
+	s, _ := ctx.Value(callStatsKey{}).(*callStats)
+	return s
+}
+
+// attempt counts the outcome of one attempt at a request.
+func (s *callStats) attempt(err error) {
+	var apiErr *APIError
+	if s != nil && errors.As(err, &apiErr) && apiErr.HasCategory("quotaError") {
+		s.quotaErrors++
+	}
+}
+
+func (s *callStats) retry() {
+	if s != nil {
+		s.retries++
+	}
+}
+
+func (s *callStats) read(n int) {
+	if s != nil {
+		s.bytes += int64(n)
+	}
+}
+
+// observe starts the span and measurements of a search request. The
+// returned context carries the counters do and send add to; finish ends
+// the span and records the measurements. Without a tracer or meter it
+// does nothing.
+func (c *Client) observe(ctx context.Context, customerID, query string) (_ context.Context, finish func(*SearchResponse, error)) {
+	if c.tracer == nil && c.meter == nil {
+		return ctx, func(*SearchResponse, error) {}
+	}
+	attrs := []Attribute{{"customer_id", customerID}, {"resource", queryResource(query)}}
+	stats := &callStats{}
+	ctx = context.WithValue(ctx, callStatsKey{}, stats)
+	var span Span
+	if c.tracer != nil {
+		ctx, span = c.tracer.Start(ctx, "adsapi.Search", attrs...)
+	}
+	start := time.Now()
+	return ctx, func(resp *SearchResponse, err error) {
+		var rows int64
+		if resp != nil {
+			rows = int64(len(resp.Results))
+		}
+		if c.meter != nil {
+			c.meter.Record(ctx, MetricQueryDuration, time.Since(start).Seconds(), attrs...)
+			c.meter.Add(ctx, MetricQueryRows, rows, attrs...)
+			c.meter.Add(ctx, MetricQueryRetries, stats.retries, attrs...)
+			c.meter.Add(ctx, MetricQuotaErrors, stats.quotaErrors, attrs...)
+			c.meter.Add(ctx, MetricResponseBytes, stats.bytes, attrs...)
+		}
+		if span == nil {
+			return
+		}
+		span.SetAttributes(
+			Attribute{"rows", rows},
+			Attribute{"retries", stats.retries},
+			Attribute{"quota_errors", stats.quotaErrors},
+			Attribute{"response_bytes", stats.bytes},
+		)
+		if resp != nil && resp.RequestID != "" {
+			span.SetAttributes(Attribute{"request_id", resp.RequestID})
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
+
+// queryResource returns the resource a query selects from, or "" if it
+// does not parse.
+func queryResource(query string) string {
+	q, err := gaql.Parse(query)
+	if err != nil {
+		return ""
+	}
+	return q.From
+}
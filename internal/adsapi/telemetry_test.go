@@ -0,0 +1,121 @@
+package adsapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+func (s *fakeSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+func (s *fakeSpan) RecordError(err error) { s.err = err }
+func (s *fakeSpan) End()                  { s.ended = true }
+
+type fakeTracer struct{ spans []*fakeSpan }
+
+func (t *fakeTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	s := &fakeSpan{name: name, attrs: map[string]any{}}
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+type fakeMeter struct {
+	counters   map[string]int64
+	histograms map[string]int
+	labels     []Attribute
+}
+
+func (m *fakeMeter) Add(_ context.Context, name string, n int64, attrs ...Attribute) {
+	m.counters[name] += n
+	m.labels = attrs
+}
+
+func (m *fakeMeter) Record(_ context.Context, name string, _ float64, attrs ...Attribute) {
+	m.histograms[name]++
+	m.labels = attrs
+}
+
+func TestTelemetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		replies   []reply
+		rows      int64
+		retries   int64
+		quota     int64
+		wantError bool
+	}{
+		{"success", []reply{{200, okBody, ""}}, 1, 0, 0, false},
+		{"quota error retried", []reply{{429, quotaBody, ""}, {200, okBody, ""}}, 1, 1, 1, false},
+		{"query error", []reply{{400, queryBody, ""}}, 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rep := tt.replies[attempts]
+				attempts++
+				w.Header().Set("request-id", "req-1")
+				w.WriteHeader(rep.status)
+				w.Write([]byte(rep.body))
+			}))
+			defer srv.Close()
+
+			tracer := &fakeTracer{}
+			meter := &fakeMeter{counters: map[string]int64{}, histograms: map[string]int{}}
+			c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL),
+				WithTracerProvider(tracer), WithMeterProvider(meter))
+			c.sleep = func(context.Context, time.Duration) error { return nil }
+
+			_, err := c.Search(context.Background(), "123-456-7890", "SELECT campaign.id FROM campaign")
+			if (err != nil) != tt.wantError {
+				t.Fatalf("error = %v, want error %v", err, tt.wantError)
+			}
+
+			if len(tracer.spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(tracer.spans))
+			}
+			span := tracer.spans[0]
+			if span.name != "adsapi.Search" || !span.ended {
+				t.Errorf("span %q ended %v", span.name, span.ended)
+			}
+			if (span.err != nil) != tt.wantError {
+				t.Errorf("span error = %v", span.err)
+			}
+			if span.attrs["customer_id"] != "1234567890" || span.attrs["resource"] != "campaign" {
+				t.Errorf("span attributes = %v", span.attrs)
+			}
+			if span.attrs["rows"] != tt.rows || span.attrs["retries"] != tt.retries || span.attrs["quota_errors"] != tt.quota {
+				t.Errorf("span attributes = %v", span.attrs)
+			}
+
+			if meter.histograms[MetricQueryDuration] != 1 {
+				t.Errorf("recorded %d durations, want 1", meter.histograms[MetricQueryDuration])
+			}
+			want := map[string]int64{MetricQueryRows: tt.rows, MetricQueryRetries: tt.retries, MetricQuotaErrors: tt.quota}
+			for name, n := range want {
+				if meter.counters[name] != n {
+					t.Errorf("%s = %d, want %d", name, meter.counters[name], n)
+				}
+			}
+			if meter.counters[MetricResponseBytes] == 0 {
+				t.Errorf("%s not recorded", MetricResponseBytes)
+			}
+			if len(meter.labels) != 2 || meter.labels[1] != (Attribute{"resource", "campaign"}) {
+				t.Errorf("labels = %v", meter.labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrDeadline is returned by requests still running, or not yet sent,
+// at the deadline set by WithDeadline. It is not retried.
+var ErrDeadline = errors.New("adsapi: deadline exceeded")
+
+// ErrCallTimeout is returned, wrapped, by a request attempt that did not
+// finish within the timeout set by WithCallTimeout. Such attempts are
+// retried like other transport errors.
+var ErrCallTimeout = errors.New("adsapi: request timed out")
+
+// Keepalive configures the connections of the HTTP client WithKeepalive
+// installs. The REST interface has no gRPC keepalive pings; TCP
+// keepalive probes stand in for them, so a connection a NAT or load
+// balancer dropped fails instead of hanging.
+type Keepalive struct {
+	// Interval is the time between TCP keepalive probes on a
+	// connection; zero means the net package default of 15s, and a
+	// negative value disables probes.
+	Interval time.Duration
+
+	// IdleTimeout closes pooled connections unused this long; zero
+	// keeps them open.
+	IdleTimeout time.Duration
+
+	// ResponseHeaderTimeout bounds the wait for the response headers
+	// once a request is sent; zero means no limit. Rows stream after
+	// the headers, so this catches a server that never answers without
+	// cutting long results short.
+	ResponseHeaderTimeout time.Duration
+}
+
+// DefaultKeepalive matches the connection settings of
+// http.DefaultTransport.
+var DefaultKeepalive = Keepalive{Interval: 30 * time.Second, IdleTimeout: 90 * time.Second}
+
+// WithKeepalive sends requests with an HTTP client whose connections use
+// k. It replaces WithHTTPClient.
+func WithKeepalive(k Keepalive) Option {
+	return func(c *Client) {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: k.Interval}).DialContext
+		t.IdleConnTimeout = k.IdleTimeout
+		t.ResponseHeaderTimeout = k.ResponseHeaderTimeout
+		c.httpClient = &http.Client{Transport: t}
+	}
+}
+
+// WithCallTimeout bounds each request attempt, from sending it to
+// reading the last byte of the response, to d. An attempt that takes
+// longer fails with ErrCallTimeout and is retried under the client's
+// RetryPolicy. Zero, the default, means no limit.
+func WithCallTimeout(d time.Duration) Option {
+	return func(c *Client) { c.callTimeout = d }
+}
+
+// WithDeadline fails every request of the client still running at t
+// with ErrDeadline, whatever the context of the call, so a batch job
+// ends within a known time. Waits for the rate limiter and between
+// retries count towards it. The zero time means no deadline.
+func WithDeadline(t time.Time) Option {
+	return func(c *Client) { c.deadline = t }
+}
+
+// withDeadline returns ctx bounded by the client's deadline.
+func (c *Client) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadlineCause(ctx, c.deadline, ErrDeadline)
+}
+
+// withCallTimeout returns ctx bounded by the client's call timeout.
+func (c *Client) withCallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.callTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, c.callTimeout, ErrCallTimeout)
+}
+
+// timeoutError returns the error to report for err, from a request made
+// with ctx: ErrDeadline or ErrCallTimeout when either cut it short.
+func (c *Client) timeoutError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	switch cause := context.Cause(ctx); cause {
+	case ErrDeadline:
+		return ErrDeadline
+	case ErrCallTimeout:
+		return fmt.Errorf("%w after %v", ErrCallTimeout, c.callTimeout)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package adsapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHangingServer answers okBody, except that the first hangs requests
+// stall until the client gives up: before the headers, or, if stream is
+// set, after the first bytes of the body.
+func newHangingServer(t *testing.T, hangs int32, stream bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reading the body lets the server notice the client hanging up.
+		io.Copy(io.Discard, r.Body)
+		if requests.Add(1) > hangs {
+			w.Write([]byte(okBody))
+			return
+		}
+		if stream {
+			w.Write([]byte(`{"results": [`))
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestCallTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		hangs    int32
+		stream   bool
+		retry    RetryPolicy
+		wantErr  error
+		requests int32
+	}{
+		{"retried", 1, false, DefaultRetryPolicy, nil, 2},
+		{"stalled body retried", 1, true, DefaultRetryPolicy, nil, 2},
+		{"no retry", 1, true, NoRetry, ErrCallTimeout, 1},
+		{"attempts exhausted", 5, false, RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1}, ErrCallTimeout, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := newHangingServer(t, tt.hangs, tt.stream)
+			c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL),
+				WithCallTimeout(50*time.Millisecond), WithRetry(tt.retry))
+			c.sleep = func(context.Context, time.Duration) error { return nil }
+
+			_, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Errorf("Search error = %v, want %v", err, tt.wantErr)
+			}
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("%d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	srv, requests := newHangingServer(t, 5, true)
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL),
+		WithDeadline(time.Now().Add(50*time.Millisecond)))
+
+	start := time.Now()
+	_, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+	if !errors.Is(err, ErrDeadline) {
+		t.Errorf("Search error = %v, want ErrDeadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Search returned after %v", elapsed)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("%d requests, want 1: the deadline is not retried", got)
+	}
+
+	// Once past the deadline, nothing is sent.
+	_, err = c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign")
+	if !errors.Is(err, ErrDeadline) {
+		t.Errorf("Search after the deadline: %v, want ErrDeadline", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("%d requests after the deadline, want 1", got)
+	}
+}
+
+func TestWithKeepalive(t *testing.T) {
+	srv, _ := newHangingServer(t, 1, false)
+	c := New("dev-token", StaticToken("access-token"), WithEndpoint(srv.URL),
+		WithKeepalive(Keepalive{Interval: 10 * time.Second, ResponseHeaderTimeout: 50 * time.Millisecond}),
+		WithRetry(NoRetry))
+	if _, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign"); err == nil {
+		t.Error("Search succeeded past ResponseHeaderTimeout")
+	}
+	if _, err := c.Search(context.Background(), "1234567890", "SELECT campaign.id FROM campaign"); err != nil {
+		t.Errorf("second Search: %v", err)
+	}
+}
@@ -0,0 +1,359 @@
+// Package anomaly flags unusual days in daily metric series.
+//
+// Each day is compared with the rest of its series: the z-score is the
+// day's deviation from the expected value divided by the standard
+// deviation of the other days. The expected value is the mean of the
+// other days or, with Seasonal set, the mean of the same weekday in
+// other weeks, so a quiet Sunday is not flagged just for being a Sunday.
+//
+// The mean and standard deviation are pulled around by the outliers
+// they are meant to find: two spikes in a month inflate the deviation
+// until neither stands out. The RollingMedian method is robust to that.
+// It compares each day with the median of the days nearest to it and
+// scales the deviation by their median absolute deviation (MAD), so its
+// z-scores are comparable with the mean method's on normal data.
+//
+// # Basic Usage
+//
+//	series := anomaly.SeriesFromRows(rows, "campaign.id", "metrics.clicks")
+//	for _, s := range series {
+//		for _, a := range anomaly.Detect(s, anomaly.Options{Threshold: 3}) {
+//			fmt.Printf("%s %s: %.0f (expected %.0f, z=%.1f)\n",
+//				a.Key, a.Date.Format(time.DateOnly), a.Value, a.Expected, a.Z)
+//		}
+//	}
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// Point is one day of a series.
+type Point struct {
+	Date  time.Time
+	Value float64
+}
+
+// Series is the daily values of a metric for one key, e.g. one campaign.
+type Series struct {
+	Key    string
+	Points []Point
+}
+
+// SeriesFromRows groups result rows by keyField into daily series of
+// metricField, read from segments.date. An empty keyField yields a
+// single series. Days missing from a series between the first and last
+// date seen in any row are filled with zero, because the API omits rows
+// whose metrics are all zero.
+func SeriesFromRows(rows []map[string]any, keyField, metricField string) []Series {
+	values := make(map[string]map[time.Time]float64)
+	var first, last time.Time
+	for _, row := range rows {
+		d, ok := str(row, "segments.date")
+		if !ok {
+			continue
+		}
+		date, err := time.Parse(time.DateOnly, d)
+		if err != nil {
+			continue
+		}
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+		if date.After(last) {
+			last = date
+		}
+
+		var key string
+		if keyField != "" {
+			key, _ = str(row, keyField)
+		}
+		if values[key] == nil {
+			values[key] = make(map[time.Time]float64)
+		}
+		values[key][date] += number(row, metricField)
+	}
+
+	out := make([]Series, 0, len(values))
+	for key, byDate := range values {
+		s := Series{Key: key}
+		for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+			s.Points = append(s.Points, Point{Date: d, Value: byDate[d]})
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Method selects how Detect computes a day's baseline.
+type Method int
+
+const (
+	// Mean compares each day with the mean and standard deviation of
+	// the other days.
+	Mean Method = iota
+
+	// RollingMedian compares each day with the median and MAD of the
+	// Window days nearest to it.
+	RollingMedian
+)
+
+// ParseMethod parses "mean" or "median".
+func ParseMethod(s string) (Method, error) {
+	switch strings.ToLower(s) {
+	case "mean":
+		return Mean, nil
+	case "median":
+		return RollingMedian, nil
+	}
+	return 0, fmt.Errorf("anomaly: unknown method %q (expected mean or median)", s)
+}
+
+// Options configures Detect.
+type Options struct {
+	// Threshold is the absolute z-score at which a day is flagged.
+	// Zero means 3.
+	Threshold float64
+
+	// Seasonal compares each day with the same weekday in other weeks.
+	Seasonal bool
+
+	// MinPoints is the shortest series analysed; shorter series have
+	// too little history for a baseline. Zero means 7.
+	MinPoints int
+
+	// Method is the baseline; the zero value is Mean.
+	Method Method
+
+	// Window is the number of days around each day that RollingMedian
+	// compares it with. Days near either end of the series use the
+	// Window days nearest to them. Zero means 28.
+	Window int
+}
+
+// Anomaly is a day that deviates from its baseline.
+type Anomaly struct {
+	Key      string
+	Date     time.Time
+	Value    float64
+	Expected float64
+
+	// StdDev is the spread Z is measured in: the standard deviation or,
+	// for RollingMedian, the scaled MAD.
+	StdDev float64
+
+	// Z is the deviation in standard deviations. It is ±Inf when every
+	// other day has the same value.
+	Z float64
+}
+
+// Detect returns the days of s whose absolute z-score reaches the
+// threshold, in date order.
+func Detect(s Series, opts Options) []Anomaly {
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = 3
+	}
+	minPoints := opts.MinPoints
+	if minPoints == 0 {
+		minPoints = 7
+	}
+	n := len(s.Points)
+	if n < minPoints {
+		return nil
+	}
+	if opts.Method == RollingMedian {
+		return detectRolling(s, opts, threshold)
+	}
+
+	// expected[i] is the baseline for day i, computed without day i;
+	// residuals are the deviations from it.
+	expected := make([]float64, n)
+	residuals := make([]float64, n)
+	for i, p := range s.Points {
+		expected[i] = baseline(s.Points, i, opts.Seasonal)
+		residuals[i] = p.Value - expected[i]
+	}
+
+	var out []Anomaly
+	for i, p := range s.Points {
+		sd := stddevWithout(residuals, i)
+		if z := score(residuals[i], sd); math.Abs(z) >= threshold {
+			out = append(out, Anomaly{Key: s.Key, Date: p.Date, Value: p.Value, Expected: expected[i], StdDev: sd, Z: z})
+		}
+	}
+	return out
+}
+
+// score is the deviation r in units of sd, or ±Inf when sd is zero.
+func score(r, sd float64) float64 {
+	switch {
+	case sd > 0:
+		return r / sd
+	case r != 0:
+		return math.Inf(int(math.Copysign(1, r)))
+	}
+	return 0
+}
+
+// madScale turns a median absolute deviation into an estimate of the
+// standard deviation of normal data; meanADScale does the same for a
+// mean absolute deviation.
+const (
+	madScale    = 1.4826
+	meanADScale = 1.2533
+)
+
+// detectRolling is Detect for RollingMedian.
+func detectRolling(s Series, opts Options, threshold float64) []Anomaly {
+	window := opts.Window
+	if window == 0 {
+		window = 28
+	}
+	var out []Anomaly
+	for i, p := range s.Points {
+		values := neighbours(s.Points, i, window, opts.Seasonal)
+		med := median(values)
+		devs := make([]float64, len(values))
+		var sum float64
+		for j, v := range values {
+			devs[j] = math.Abs(v - med)
+			sum += devs[j]
+		}
+		// Sparse series, mostly zeros, have a MAD of zero; the mean
+		// absolute deviation still measures their spread.
+		sd := madScale * median(devs)
+		if sd == 0 && len(devs) > 0 {
+			sd = meanADScale * sum / float64(len(devs))
+		}
+		if z := score(p.Value-med, sd); math.Abs(z) >= threshold {
+			out = append(out, Anomaly{Key: s.Key, Date: p.Date, Value: p.Value, Expected: med, StdDev: sd, Z: z})
+		}
+	}
+	return out
+}
+
+// neighbours returns the values of the window points nearest to i,
+// without i, restricted to i's weekday when seasonal and at least three
+// such points exist.
+func neighbours(points []Point, i, window int, seasonal bool) []float64 {
+	lo := max(i-window/2, 0)
+	hi := min(lo+window+1, len(points))
+	lo = max(hi-window-1, 0)
+	var all, sameDay []float64
+	for j := lo; j < hi; j++ {
+		if j == i {
+			continue
+		}
+		all = append(all, points[j].Value)
+		if points[j].Date.Weekday() == points[i].Date.Weekday() {
+			sameDay = append(sameDay, points[j].Value)
+		}
+	}
+	if seasonal && len(sameDay) >= 3 {
+		return sameDay
+	}
+	return all
+}
+
+func median(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	s := slices.Clone(xs)
+	slices.Sort(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// baseline is the mean of the points other than i, restricted to i's
+// weekday when seasonal and such points exist.
+func baseline(points []Point, i int, seasonal bool) float64 {
+	var sum float64
+	var n int
+	if seasonal {
+		for j, p := range points {
+			if j != i && p.Date.Weekday() == points[i].Date.Weekday() {
+				sum += p.Value
+				n++
+			}
+		}
+		if n > 0 {
+			return sum / float64(n)
+		}
+	}
+	for j, p := range points {
+		if j != i {
+			sum += p.Value
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// stddevWithout is the population standard deviation of xs without xs[i].
+func stddevWithout(xs []float64, i int) float64 {
+	var sum float64
+	n := 0
+	for j, x := range xs {
+		if j != i {
+			sum += x
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	mean := sum / float64(n)
+	var ss float64
+	for j, x := range xs {
+		if j != i {
+			ss += (x - mean) * (x - mean)
+		}
+	}
+	return math.Sqrt(ss / float64(n))
+}
+
+func str(row map[string]any, field string) (string, bool) {
+	v, ok := output.Value(row, field)
+	if !ok {
+		return "", false
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// number reads a numeric field; INT64 values arrive as strings.
+func number(row map[string]any, field string) float64 {
+	v, _ := output.Value(row, field)
+	switch v := v.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
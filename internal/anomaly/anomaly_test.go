@@ -0,0 +1,166 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func day(n int) time.Time {
+	// 2026-03-02 is a Monday.
+	return time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func series(values ...float64) Series {
+	s := Series{Key: "1"}
+	for i, v := range values {
+		s.Points = append(s.Points, Point{Date: day(i), Value: v})
+	}
+	return s
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     Series
+		opts  Options
+		dates []int // day offsets flagged
+	}{
+		{
+			name:  "spike",
+			s:     series(100, 102, 98, 101, 99, 100, 400, 101, 99, 100),
+			dates: []int{6},
+		},
+		{
+			name:  "drop",
+			s:     series(100, 102, 98, 101, 0, 100, 99, 101, 99, 100),
+			dates: []int{4},
+		},
+		{
+			name:  "flat series with one change",
+			s:     series(0, 0, 0, 0, 0, 0, 0, 5),
+			dates: []int{7},
+		},
+		{
+			name: "too short",
+			s:    series(1, 1, 1, 100),
+		},
+		{
+			// Weekends are low every week; only the busy Saturday stands out.
+			name:  "seasonal",
+			s:     series(100, 100, 100, 100, 100, 10, 10, 100, 100, 100, 100, 100, 10, 10, 100, 100, 100, 100, 100, 60, 10),
+			opts:  Options{Seasonal: true},
+			dates: []int{19},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.s, tt.opts)
+			if len(got) != len(tt.dates) {
+				t.Fatalf("got %d anomalies %+v, want days %v", len(got), got, tt.dates)
+			}
+			for i, a := range got {
+				if !a.Date.Equal(day(tt.dates[i])) {
+					t.Errorf("anomaly %d on %s, want %s", i, a.Date, day(tt.dates[i]))
+				}
+			}
+		})
+	}
+}
+
+func TestDetectRollingMedian(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     Series
+		opts  Options
+		dates []int
+	}{
+		{
+			// The three spikes inflate the standard deviation until the
+			// mean method flags none; the medians are unmoved.
+			name:  "three spikes",
+			s:     series(100, 102, 98, 101, 99, 100, 300, 101, 99, 100, 103, 97, 300, 100, 98, 102, 99, 101, 100, 100, 300),
+			dates: []int{6, 12, 20},
+		},
+		{
+			name:  "sparse series",
+			s:     series(0, 0, 1, 0, 0, 0, 1, 0, 0, 30, 0, 0),
+			dates: []int{9},
+		},
+		{
+			name:  "seasonal",
+			s:     series(100, 100, 100, 100, 100, 10, 10, 100, 100, 100, 100, 100, 10, 10, 100, 100, 100, 100, 100, 60, 10, 100, 100, 100, 100, 100, 10, 10),
+			opts:  Options{Seasonal: true},
+			dates: []int{19},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Method = RollingMedian
+			got := Detect(tt.s, tt.opts)
+			if len(got) != len(tt.dates) {
+				t.Fatalf("got %d anomalies %+v, want days %v", len(got), got, tt.dates)
+			}
+			for i, a := range got {
+				if !a.Date.Equal(day(tt.dates[i])) {
+					t.Errorf("anomaly %d on %s, want %s", i, a.Date, day(tt.dates[i]))
+				}
+			}
+		})
+	}
+	if got := Detect(tests[0].s, Options{}); len(got) != 0 {
+		t.Errorf("mean method flagged %+v; the three spikes case no longer shows the difference", got)
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	if m, err := ParseMethod("Median"); err != nil || m != RollingMedian {
+		t.Errorf("ParseMethod(Median) = %v, %v", m, err)
+	}
+	if _, err := ParseMethod("stl"); err == nil {
+		t.Error("ParseMethod(stl) succeeded")
+	}
+}
+
+func TestDetectNonSeasonalFlagsWeekends(t *testing.T) {
+	s := series(100, 100, 100, 100, 100, 10, 10, 100, 100, 100, 100, 100, 10, 10)
+	if got := Detect(s, Options{Threshold: 1.5}); len(got) == 0 {
+		t.Error("expected weekend dips to be flagged without the seasonal baseline")
+	}
+	if got := Detect(s, Options{Threshold: 1.5, Seasonal: true}); len(got) != 0 {
+		t.Errorf("expected no anomalies with the seasonal baseline, got %+v", got)
+	}
+}
+
+func TestDetectInfiniteZ(t *testing.T) {
+	got := Detect(series(0, 0, 0, 0, 0, 0, 0, 5), Options{})
+	if len(got) != 1 || !math.IsInf(got[0].Z, 1) {
+		t.Errorf("expected +Inf z-score, got %+v", got)
+	}
+}
+
+func TestSeriesFromRows(t *testing.T) {
+	rows := []map[string]any{
+		{"campaign": map[string]any{"id": "1"}, "segments": map[string]any{"date": "2026-03-02"}, "metrics": map[string]any{"clicks": "10"}},
+		{"campaign": map[string]any{"id": "1"}, "segments": map[string]any{"date": "2026-03-04"}, "metrics": map[string]any{"clicks": "30"}},
+		{"campaign": map[string]any{"id": "2"}, "segments": map[string]any{"date": "2026-03-03"}, "metrics": map[string]any{"clicks": "5"}},
+	}
+	got := SeriesFromRows(rows, "campaign.id", "metrics.clicks")
+	if len(got) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(got))
+	}
+	want := []float64{10, 0, 30}
+	for i, p := range got[0].Points {
+		if p.Value != want[i] {
+			t.Errorf("series 1 day %d = %v, want %v", i, p.Value, want[i])
+		}
+	}
+	if len(got[1].Points) != 3 || got[1].Points[1].Value != 5 {
+		t.Errorf("series 2 not filled over the full span: %+v", got[1].Points)
+	}
+
+	if all := SeriesFromRows(rows, "", "metrics.clicks"); len(all) != 1 || all[0].Points[1].Value != 5 {
+		t.Errorf("expected a single summed series, got %+v", all)
+	}
+}
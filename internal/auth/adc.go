@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// CloudPlatformScope is the OAuth2 scope of Google Cloud APIs such as
+// Secret Manager.
+const CloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// metadataTimeout bounds a token request to the metadata server, which
+// does not answer at all off Google Cloud.
+const metadataTimeout = 5 * time.Second
+
+// DefaultCredentials returns a token source for Google Cloud APIs,
+// found where gcloud and the Cloud client libraries look for Application
+// Default Credentials:
+//
+//   - the credentials file named by GOOGLE_APPLICATION_CREDENTIALS
+//   - the file written by gcloud auth application-default login
+//   - the service account of the VM, Cloud Run service, or other Google
+//     Cloud runtime, from the metadata server
+//
+// scope is requested for service accounts. The metadata server is not
+// contacted until a token is needed.
+func DefaultCredentials(scope string) (*TokenSource, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if p := gcloudCredentialsPath(); p != "" {
+			if _, err := os.Stat(p); err == nil {
+				path = p
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("auth: %w", err)
+			}
+		}
+	}
+	if path != "" {
+		creds, err := LoadCredentialsFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return creds.ScopedTokenSource(scope, "")
+	}
+	return metadataTokenSource(scope), nil
+}
+
+// gcloudCredentialsPath returns the Application Default Credentials file
+// of gcloud, in its configuration directory.
+func gcloudCredentialsPath() string {
+	dir := os.Getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gcloud")
+		} else {
+			return ""
+		}
+	}
+	return filepath.Join(dir, "application_default_credentials.json")
+}
+
+// metadataTokenSource returns tokens for the default service account of
+// the Google Cloud runtime. GCE_METADATA_HOST overrides the address of
+// the metadata server.
+func metadataTokenSource(scope string) *TokenSource {
+	host := cmp.Or(os.Getenv("GCE_METADATA_HOST"), "metadata.google.internal")
+	tokenURL := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token?scopes=" + url.QueryEscape(scope)
+
+	ts := &TokenSource{httpClient: http.DefaultClient, now: time.Now}
+	ts.fetch = func(ctx context.Context) (*tokenResponse, error) {
+		ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		resp, err := ts.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("auth: no Google Cloud credentials found (set GOOGLE_APPLICATION_CREDENTIALS or run gcloud auth application-default login): %w", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, &TokenError{StatusCode: resp.StatusCode, Code: http.StatusText(resp.StatusCode), Description: string(data)}
+		}
+		var tr tokenResponse
+		if err := json.Unmarshal(data, &tr); err != nil {
+			return nil, fmt.Errorf("auth: decoding metadata token: %w", err)
+		}
+		if tr.AccessToken == "" {
+			return nil, errors.New("auth: metadata token response has no access token")
+		}
+		return &tr, nil
+	}
+	return ts
+}
@@ -0,0 +1,489 @@
+// Package auth obtains OAuth2 access tokens for the Google Ads API.
+//
+// Credentials come from a Google JSON credentials file: either a service
+// account key, optionally impersonating a Workspace user through
+// domain-wide delegation, or an authorized user file holding a refresh
+// token. Access tokens are cached and renewed in the background before
+// they expire, so long exports and servers never run into an expired
+// token.
+//
+// People can instead sign in interactively: InstalledApp.Login runs the
+// OAuth2 installed-app flow in a browser, and the resulting refresh
+// token is kept in a Store, the OS keychain or an optionally encrypted
+// file, where FromEnvironment finds it.
+//
+// # Basic Usage
+//
+//	ts, err := auth.FromEnvironment()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	client := adsapi.New(developerToken, ts)
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/secrets"
+)
+
+const (
+	// Scope is the OAuth2 scope of the Google Ads API.
+	Scope = "https://www.googleapis.com/auth/adwords"
+
+	// DefaultTokenURL is Google's OAuth2 token endpoint.
+	DefaultTokenURL = "https://oauth2.googleapis.com/token"
+
+	// expiryDelta is how long before expiry a cached token is no longer
+	// used.
+	expiryDelta = time.Minute
+
+	// refreshWindow is how long before expiryDelta a token is renewed in
+	// the background, at a random point; at most a quarter of the
+	// token's lifetime.
+	refreshWindow = 5 * time.Minute
+
+	// refreshRetry is the wait before another background renewal when
+	// one fails.
+	refreshRetry = 10 * time.Second
+
+	// refreshTimeout bounds a request to the token endpoint.
+	refreshTimeout = 30 * time.Second
+)
+
+// Credential file types.
+const (
+	TypeServiceAccount = "service_account"
+	TypeAuthorizedUser = "authorized_user"
+)
+
+// ErrNoCredentials is returned by FromEnvironment when no credentials
+// file is configured.
+var ErrNoCredentials = errors.New("auth: no credentials configured (set GOOGLE_APPLICATION_CREDENTIALS or run adtap auth login)")
+
+// Credentials is the content of a Google JSON credentials file.
+type Credentials struct {
+	Type string `json:"type"`
+
+	// Service account fields.
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+
+	// Authorized user fields.
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LoadCredentialsFile reads a JSON credentials file.
+func LoadCredentialsFile(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: reading credentials: %w", err)
+	}
+	var c Credentials
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("auth: parsing credentials %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// FromEnvironment returns a token source for the first credentials
+// found among:
+//
+//   - the credentials file named by GOOGLE_ADS_JSON_KEY_FILE_PATH or
+//     GOOGLE_APPLICATION_CREDENTIALS; GOOGLE_ADS_IMPERSONATED_EMAIL sets
+//     the user a service account acts as
+//   - GOOGLE_ADS_CLIENT_ID, GOOGLE_ADS_CLIENT_SECRET, and
+//     GOOGLE_ADS_REFRESH_TOKEN
+//   - the credentials saved in DefaultStore by an interactive login
+func FromEnvironment() (*TokenSource, error) {
+	path := os.Getenv("GOOGLE_ADS_JSON_KEY_FILE_PATH")
+	if path == "" {
+		path = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if path != "" {
+		creds, err := LoadCredentialsFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return creds.TokenSource(os.Getenv("GOOGLE_ADS_IMPERSONATED_EMAIL"))
+	}
+
+	if refresh := os.Getenv("GOOGLE_ADS_REFRESH_TOKEN"); refresh != "" {
+		creds := &Credentials{
+			Type:         TypeAuthorizedUser,
+			ClientID:     os.Getenv("GOOGLE_ADS_CLIENT_ID"),
+			ClientSecret: os.Getenv("GOOGLE_ADS_CLIENT_SECRET"),
+			RefreshToken: refresh,
+		}
+		return creds.TokenSource("")
+	}
+
+	store, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	creds, err := store.Load()
+	if errors.Is(err, ErrNotStored) {
+		return nil, ErrNoCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return creds.TokenSource("")
+}
+
+// FromSecrets returns a token source for the credentials held by p:
+// the credentials secret, a whole JSON credentials file, or else an
+// authorized user made of the client_id, client_secret, and
+// refresh_token secrets. It returns ErrNoCredentials when p holds
+// neither. GOOGLE_ADS_IMPERSONATED_EMAIL sets the user a service account
+// acts as.
+func FromSecrets(ctx context.Context, p secrets.Provider) (*TokenSource, error) {
+	lookup := func(name string) (string, error) {
+		v, err := p.Secret(ctx, name)
+		if errors.Is(err, secrets.ErrNotFound) {
+			return "", nil
+		}
+		return v, err
+	}
+
+	data, err := lookup(secrets.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	if data != "" {
+		var c Credentials
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return nil, fmt.Errorf("auth: parsing credentials from %s: %w", p, err)
+		}
+		return c.TokenSource(os.Getenv("GOOGLE_ADS_IMPERSONATED_EMAIL"))
+	}
+
+	c := &Credentials{Type: TypeAuthorizedUser}
+	for name, field := range map[string]*string{
+		secrets.RefreshToken: &c.RefreshToken,
+		secrets.ClientID:     &c.ClientID,
+		secrets.ClientSecret: &c.ClientSecret,
+	} {
+		if *field, err = lookup(name); err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	}
+	if c.RefreshToken == "" {
+		return nil, ErrNoCredentials
+	}
+	return c.TokenSource("")
+}
+
+// TokenSource returns a caching token source for the credentials.
+// subject is the user a service account impersonates; it is ignored for
+// authorized user credentials.
+func (c *Credentials) TokenSource(subject string) (*TokenSource, error) {
+	return c.ScopedTokenSource(Scope, subject)
+}
+
+// ScopedTokenSource is TokenSource for an API other than Google Ads.
+// scope applies to service accounts only; the scopes of an authorized
+// user were fixed when its refresh token was granted.
+func (c *Credentials) ScopedTokenSource(scope, subject string) (*TokenSource, error) {
+	tokenURL := c.TokenURI
+	if tokenURL == "" {
+		tokenURL = DefaultTokenURL
+	}
+
+	ts := &TokenSource{httpClient: http.DefaultClient, now: time.Now}
+	switch c.Type {
+	case TypeServiceAccount:
+		key, err := parsePrivateKey(c.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		ts.fetch = func(ctx context.Context) (*tokenResponse, error) {
+			assertion, err := c.signJWT(key, scope, tokenURL, subject, ts.now())
+			if err != nil {
+				return nil, err
+			}
+			return ts.exchange(ctx, tokenURL, url.Values{
+				"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+				"assertion":  {assertion},
+			})
+		}
+	case TypeAuthorizedUser:
+		if c.RefreshToken == "" {
+			return nil, errors.New("auth: authorized user credentials have no refresh token")
+		}
+		ts.fetch = func(ctx context.Context) (*tokenResponse, error) {
+			return ts.exchange(ctx, tokenURL, url.Values{
+				"grant_type":    {"refresh_token"},
+				"client_id":     {c.ClientID},
+				"client_secret": {c.ClientSecret},
+				"refresh_token": {c.RefreshToken},
+			})
+		}
+	default:
+		return nil, fmt.Errorf("auth: unsupported credentials type %q", c.Type)
+	}
+	return ts, nil
+}
+
+// TokenSource fetches access tokens and caches them until shortly
+// before they expire. It is safe for concurrent use.
+//
+// Tokens are renewed ahead of time: once a token enters the last part of
+// its lifetime, at a randomized point so that many processes sharing
+// credentials do not all renew at once, Token starts a renewal in the
+// background and keeps returning the current token until the new one
+// arrives. Callers block only when no valid token is left, and
+// concurrent callers then share a single request to the token endpoint.
+type TokenSource struct {
+	httpClient *http.Client
+	now        func() time.Time
+	fetch      func(ctx context.Context) (*tokenResponse, error)
+	jitter     func(max time.Duration) time.Duration // nil means random
+
+	mu        sync.Mutex
+	token     string
+	expiry    time.Time
+	refreshAt time.Time // when to start renewing in the background
+	inflight  *refresh  // the running fetch, if any
+}
+
+// refresh is one fetch of a token, shared by every caller waiting for
+// it.
+type refresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// SetHTTPClient sets the HTTP client used to reach the token endpoint.
+func (ts *TokenSource) SetHTTPClient(hc *http.Client) {
+	ts.httpClient = hc
+}
+
+// Token returns a valid access token, fetching a new one when the cached
+// token is missing or about to expire.
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	now := ts.now()
+	if ts.token != "" && now.Add(expiryDelta).Before(ts.expiry) {
+		token := ts.token
+		if ts.inflight == nil && !now.Before(ts.refreshAt) {
+			ts.start()
+		}
+		ts.mu.Unlock()
+		return token, nil
+	}
+	r := ts.inflight
+	if r == nil {
+		r = ts.start()
+	}
+	ts.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.token, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Invalidate discards token if it is the cached one, so the next Token
+// call fetches a new one. Clients call it when the API rejects a token
+// as invalid or expired before its time.
+func (ts *TokenSource) Invalidate(token string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if token == ts.token {
+		ts.token = ""
+	}
+}
+
+// start fetches a token in the background; ts.mu must be held. The fetch
+// is not tied to the context of any one caller, so a caller giving up
+// does not fail the others waiting on it.
+func (ts *TokenSource) start() *refresh {
+	r := &refresh{done: make(chan struct{})}
+	ts.inflight = r
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		defer cancel()
+		resp, err := ts.fetch(ctx)
+
+		ts.mu.Lock()
+		now := ts.now()
+		switch {
+		case err == nil:
+			ts.token = resp.AccessToken
+			ts.expiry = now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+			ts.refreshAt = ts.renewal(now)
+			r.token = ts.token
+		case ts.token != "":
+			// A failed background renewal leaves the current token in
+			// use; try again a little later.
+			ts.refreshAt = now.Add(refreshRetry)
+		}
+		r.err = err
+		ts.inflight = nil
+		ts.mu.Unlock()
+		close(r.done)
+	}()
+	return r
+}
+
+// renewal returns when to renew the token just fetched: a random point
+// in the refresh window before it is due to be replaced.
+func (ts *TokenSource) renewal(now time.Time) time.Time {
+	due := ts.expiry.Add(-expiryDelta)
+	window := min(refreshWindow, due.Sub(now)/4)
+	if window <= 0 {
+		return due
+	}
+	jitter := ts.jitter
+	if jitter == nil {
+		jitter = func(max time.Duration) time.Duration { return mathrand.N(max) }
+	}
+	return due.Add(-window + jitter(window))
+}
+
+// TokenError is an error response from the token endpoint.
+type TokenError struct {
+	StatusCode  int
+	Code        string // OAuth2 error code, e.g. invalid_grant
+	Description string
+}
+
+func (e *TokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("auth: token request failed (HTTP %d): %s: %s", e.StatusCode, e.Code, e.Description)
+	}
+	return fmt.Sprintf("auth: token request failed (HTTP %d): %s", e.StatusCode, e.Code)
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (ts *TokenSource) exchange(ctx context.Context, tokenURL string, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := ts.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var tr tokenResponse
+	jsonErr := json.Unmarshal(data, &tr)
+	if resp.StatusCode != http.StatusOK {
+		code := tr.Error
+		if code == "" {
+			code = http.StatusText(resp.StatusCode)
+		}
+		return nil, &TokenError{StatusCode: resp.StatusCode, Code: code, Description: tr.ErrorDescription}
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("auth: decoding token response: %w", jsonErr)
+	}
+	if tr.AccessToken == "" {
+		return nil, errors.New("auth: token response has no access token")
+	}
+	return &tr, nil
+}
+
+// signJWT builds the RS256-signed assertion a service account exchanges
+// for an access token.
+func (c *Credentials) signJWT(key *rsa.PrivateKey, scope, audience, subject string, now time.Time) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if c.PrivateKeyID != "" {
+		header["kid"] = c.PrivateKeyID
+	}
+	claims := map[string]any{
+		"iss":   c.ClientEmail,
+		"scope": scope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+	if subject != "" {
+		claims["sub"] = subject
+	}
+
+	encode := func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	}
+	h, err := encode(header)
+	if err != nil {
+		return "", err
+	}
+	p, err := encode(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := h + "." + p
+	sum := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("auth: signing assertion: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func parsePrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("auth: service account private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parsing private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("auth: service account private key is not an RSA key")
+	}
+	return key, nil
+}
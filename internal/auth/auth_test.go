@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/secrets"
+)
+
+// newTokenServer returns a token endpoint that checks each request with
+// check and counts how many tokens it issued.
+func newTokenServer(t *testing.T, check func(r *http.Request) error) (string, *int) {
+	t.Helper()
+	issued := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if err := check(r); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": err.Error()})
+			return
+		}
+		issued++
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "token-" + string(rune('0'+issued)), "expires_in": 3600})
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &issued
+}
+
+func TestServiceAccountTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	tokenURL, issued := newTokenServer(t, func(r *http.Request) error {
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			return errors.New("wrong grant type")
+		}
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			return errors.New("malformed assertion")
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.New("bad signature")
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims map[string]any
+		json.Unmarshal(payload, &claims)
+		if claims["iss"] != "adtap@example.iam.gserviceaccount.com" || claims["sub"] != "admin@example.com" || claims["scope"] != Scope {
+			return errors.New("unexpected claims")
+		}
+		return nil
+	})
+
+	creds := &Credentials{
+		Type:        TypeServiceAccount,
+		ClientEmail: "adtap@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pemKey),
+		TokenURI:    tokenURL,
+	}
+	ts, err := creds.TokenSource("admin@example.com")
+	if err != nil {
+		t.Fatalf("TokenSource: %v", err)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ts.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		tok, err := ts.Token(context.Background())
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		if tok != "token-1" {
+			t.Errorf("expected cached token-1, got %q", tok)
+		}
+	}
+
+	// Within a minute of expiry the token is renewed.
+	now = now.Add(59*time.Minute + 30*time.Second)
+	if tok, _ := ts.Token(context.Background()); tok != "token-2" || *issued != 2 {
+		t.Errorf("expected renewal, got %q after %d fetches", tok, *issued)
+	}
+}
+
+func TestTokenSourceRenewal(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		now     = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		fetches int
+		fail    bool
+	)
+	release := make(chan struct{})
+	ts := &TokenSource{
+		now:    func() time.Time { mu.Lock(); defer mu.Unlock(); return now },
+		jitter: func(time.Duration) time.Duration { return 0 },
+		fetch: func(context.Context) (*tokenResponse, error) {
+			<-release
+			mu.Lock()
+			defer mu.Unlock()
+			if fail {
+				return nil, errors.New("token endpoint down")
+			}
+			fetches++
+			return &tokenResponse{AccessToken: fmt.Sprintf("token-%d", fetches), ExpiresIn: 3600}, nil
+		},
+	}
+	advance := func(d time.Duration) { mu.Lock(); now = now.Add(d); mu.Unlock() }
+	settle := func() {
+		ts.mu.Lock()
+		r := ts.inflight
+		ts.mu.Unlock()
+		if r != nil {
+			<-r.done
+		}
+	}
+
+	// Concurrent callers without a token share one fetch.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tok, err := ts.Token(context.Background()); err != nil || tok != "token-1" {
+				t.Errorf("Token = %q, %v; want token-1", tok, err)
+			}
+		}()
+	}
+	close(release)
+	wg.Wait()
+	if fetches != 1 {
+		t.Errorf("concurrent callers made %d fetches, want 1", fetches)
+	}
+
+	// Inside the refresh window the current token is returned while a
+	// new one is fetched in the background.
+	advance(55 * time.Minute)
+	if tok, _ := ts.Token(context.Background()); tok != "token-1" {
+		t.Errorf("Token in refresh window = %q, want token-1", tok)
+	}
+	settle()
+	if tok, _ := ts.Token(context.Background()); tok != "token-2" {
+		t.Errorf("Token after background renewal = %q, want token-2", tok)
+	}
+
+	// A failed background renewal keeps the current token in use.
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	advance(55 * time.Minute)
+	ts.Token(context.Background())
+	settle()
+	if tok, err := ts.Token(context.Background()); err != nil || tok != "token-2" {
+		t.Errorf("Token after failed renewal = %q, %v; want token-2", tok, err)
+	}
+
+	// A token the API rejected is not handed out again.
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	ts.Invalidate("token-2")
+	if tok, _ := ts.Token(context.Background()); tok != "token-3" {
+		t.Errorf("Token after Invalidate = %q, want token-3", tok)
+	}
+}
+
+func TestAuthorizedUserTokenSource(t *testing.T) {
+	tokenURL, _ := newTokenServer(t, func(r *http.Request) error {
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh" {
+			return errors.New("token has been expired or revoked")
+		}
+		return nil
+	})
+
+	creds := &Credentials{Type: TypeAuthorizedUser, ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh", TokenURI: tokenURL}
+	ts, err := creds.TokenSource("")
+	if err != nil {
+		t.Fatalf("TokenSource: %v", err)
+	}
+	if tok, err := ts.Token(context.Background()); err != nil || tok != "token-1" {
+		t.Fatalf("Token = %q, %v", tok, err)
+	}
+
+	creds.RefreshToken = "revoked"
+	ts, _ = creds.TokenSource("")
+	_, err = ts.Token(context.Background())
+	var terr *TokenError
+	if !errors.As(err, &terr) || terr.Code != "invalid_grant" || terr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected invalid_grant TokenError, got %v", err)
+	}
+}
+
+func TestFromEnvironment(t *testing.T) {
+	t.Setenv("GOOGLE_ADS_JSON_KEY_FILE_PATH", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("GOOGLE_ADS_REFRESH_TOKEN", "")
+	t.Setenv("ADTAP_CREDENTIALS_STORE", "file")
+	t.Setenv("ADTAP_CREDENTIALS_KEY", "")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if _, err := FromEnvironment(); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected ErrNoCredentials, got %v", err)
+	}
+
+	store, _ := DefaultStore()
+	store.Save(&Credentials{Type: TypeAuthorizedUser, RefreshToken: "r"})
+	if _, err := FromEnvironment(); err != nil {
+		t.Errorf("stored login: unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	os.WriteFile(path, []byte(`{"type": "external_account"}`), 0o600)
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	if _, err := FromEnvironment(); err == nil || !strings.Contains(err.Error(), "unsupported credentials type") {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+
+	os.WriteFile(path, []byte(`{"type": "authorized_user", "refresh_token": "r"}`), 0o600)
+	if _, err := FromEnvironment(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// mapSecrets is a secrets.Provider backed by a map.
+type mapSecrets map[string]string
+
+func (m mapSecrets) String() string { return "test secrets" }
+
+func (m mapSecrets) Secret(_ context.Context, name string) (string, error) {
+	if v, ok := m[name]; ok {
+		return v, nil
+	}
+	return "", secrets.ErrNotFound
+}
+
+func TestFromSecrets(t *testing.T) {
+	tokenURL, _ := newTokenServer(t, func(r *http.Request) error {
+		if r.Form.Get("refresh_token") != "refresh" || r.Form.Get("client_id") != "id" {
+			return errors.New("token has been expired or revoked")
+		}
+		return nil
+	})
+	tests := []struct {
+		name    string
+		secrets mapSecrets
+		wantErr string
+	}{
+		{name: "empty", wantErr: ErrNoCredentials.Error()},
+		{name: "client without refresh token", secrets: mapSecrets{"client_id": "id"}, wantErr: ErrNoCredentials.Error()},
+		{name: "refresh token", secrets: mapSecrets{"client_id": "id", "client_secret": "s", "refresh_token": "refresh"}},
+		{name: "credentials file", secrets: mapSecrets{"credentials": `{"type": "authorized_user", "client_id": "id", "refresh_token": "refresh", "token_uri": "` + tokenURL + `"}`}},
+		{name: "bad credentials", secrets: mapSecrets{"credentials": `{`}, wantErr: "parsing credentials from test secrets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := FromSecrets(context.Background(), tt.secrets)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.secrets["credentials"] != "" {
+				if tok, err := ts.Token(context.Background()); err != nil || tok != "token-1" {
+					t.Errorf("Token = %q, %v", tok, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultCredentials(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	gcloud := t.TempDir()
+	t.Setenv("CLOUDSDK_CONFIG", gcloud)
+
+	// Without a credentials file, tokens come from the metadata server.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Query().Get("scopes") != CloudPlatformScope {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "metadata-token", "expires_in": 3600, "token_type": "Bearer"})
+	}))
+	defer srv.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+	ts, err := DefaultCredentials(CloudPlatformScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := ts.Token(context.Background()); err != nil || tok != "metadata-token" {
+		t.Errorf("metadata Token = %q, %v", tok, err)
+	}
+
+	// gcloud's credentials file comes first.
+	tokenURL, _ := newTokenServer(t, func(r *http.Request) error { return nil })
+	creds, _ := json.Marshal(Credentials{Type: TypeAuthorizedUser, ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh", TokenURI: tokenURL})
+	if err := os.WriteFile(filepath.Join(gcloud, "application_default_credentials.json"), creds, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ts, err = DefaultCredentials(CloudPlatformScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := ts.Token(context.Background()); err != nil || tok != "token-1" {
+		t.Errorf("gcloud Token = %q, %v", tok, err)
+	}
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// DefaultAuthURL is Google's OAuth2 authorization endpoint.
+const DefaultAuthURL = "https://accounts.google.com/o/oauth2/v2/auth"
+
+// InstalledApp is an OAuth2 client of the "Desktop app" type, used to
+// sign a person in from the command line.
+type InstalledApp struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string // default DefaultAuthURL
+	TokenURL     string // default DefaultTokenURL
+
+	// HTTPClient reaches the token endpoint; nil means http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// LoadClientSecrets reads the client secrets JSON file downloaded from
+// the Google Cloud console for a desktop OAuth2 client.
+func LoadClientSecrets(path string) (*InstalledApp, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth: reading client secrets: %w", err)
+	}
+	var file struct {
+		Installed *struct {
+			ClientID     string `json:"client_id"`
+			ClientSecret string `json:"client_secret"`
+			AuthURI      string `json:"auth_uri"`
+			TokenURI     string `json:"token_uri"`
+		} `json:"installed"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("auth: parsing client secrets %s: %w", path, err)
+	}
+	if file.Installed == nil {
+		return nil, fmt.Errorf("auth: %s is not a desktop app client (no \"installed\" section)", path)
+	}
+	return &InstalledApp{
+		ClientID:     file.Installed.ClientID,
+		ClientSecret: file.Installed.ClientSecret,
+		AuthURL:      file.Installed.AuthURI,
+		TokenURL:     file.Installed.TokenURI,
+	}, nil
+}
+
+// Login runs the installed-app authorization code flow with PKCE. It
+// listens on a loopback port, calls open with the consent page URL
+// (typically to start a browser), waits for Google to redirect back, and
+// exchanges the code for a refresh token. The returned credentials are
+// of TypeAuthorizedUser and can be saved to a Store.
+func (a *InstalledApp) Login(ctx context.Context, open func(consentURL string) error) (*Credentials, error) {
+	if a.ClientID == "" {
+		return nil, errors.New("auth: OAuth2 client ID is not set")
+	}
+	authURL, tokenURL := a.AuthURL, a.TokenURL
+	if authURL == "" {
+		authURL = DefaultAuthURL
+	}
+	if tokenURL == "" {
+		tokenURL = DefaultTokenURL
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("auth: starting loopback listener: %w", err)
+	}
+	defer ln.Close()
+	redirectURL := "http://" + ln.Addr().String() + "/"
+
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	consent := authURL + "?" + url.Values{
+		"client_id":             {a.ClientID},
+		"redirect_uri":          {redirectURL},
+		"response_type":         {"code"},
+		"scope":                 {Scope},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"access_type":           {"offline"},
+		"prompt":                {"consent"},
+	}.Encode()
+
+	type result struct {
+		code string
+		err  error
+	}
+	done := make(chan result, 1)
+	srv := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			var res result
+			switch {
+			case q.Get("state") != state:
+				http.Error(w, "State mismatch; close this window and try again.", http.StatusBadRequest)
+				return
+			case q.Get("error") != "":
+				res.err = fmt.Errorf("auth: authorization denied: %s", q.Get("error"))
+			case q.Get("code") == "":
+				res.err = errors.New("auth: authorization response has no code")
+			default:
+				res.code = q.Get("code")
+			}
+			msg := "adtap is signed in. You can close this window."
+			if res.err != nil {
+				msg = "Sign-in failed: " + res.err.Error()
+			}
+			fmt.Fprintf(w, "<!DOCTYPE html><p>%s</p>\n", html.EscapeString(msg))
+			select {
+			case done <- res:
+			default:
+			}
+		}),
+	}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	if err := open(consent); err != nil {
+		return nil, err
+	}
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	ts := &TokenSource{httpClient: a.HTTPClient, now: time.Now}
+	if ts.httpClient == nil {
+		ts.httpClient = http.DefaultClient
+	}
+	tr, err := ts.exchange(ctx, tokenURL, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {a.ClientID},
+		"client_secret": {a.ClientSecret},
+		"code":          {res.code},
+		"code_verifier": {verifier},
+		"redirect_uri":  {redirectURL},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tr.RefreshToken == "" {
+		return nil, errors.New("auth: token response has no refresh token")
+	}
+	return &Credentials{
+		Type:         TypeAuthorizedUser,
+		ClientID:     a.ClientID,
+		ClientSecret: a.ClientSecret,
+		RefreshToken: tr.RefreshToken,
+		TokenURI:     a.TokenURL,
+	}, nil
+}
+
+// randomString returns n random bytes, base64url encoded.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("auth: generating random state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestInstalledAppLogin(t *testing.T) {
+	var challenge string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("grant_type") != "authorization_code" || r.Form.Get("code") != "auth-code" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "token", "expires_in": 3600, "refresh_token": "refresh-token"})
+	}))
+	defer srv.Close()
+	app := &InstalledApp{ClientID: "client", ClientSecret: "secret", AuthURL: "https://auth.invalid/auth", TokenURL: srv.URL}
+
+	// redirect returns a fake browser that follows the consent page
+	// straight back to the loopback listener with the given parameters.
+	redirect := func(params url.Values) func(string) error {
+		return func(consentURL string) error {
+			u, err := url.Parse(consentURL)
+			if err != nil {
+				return err
+			}
+			q := u.Query()
+			if q.Get("scope") != Scope || q.Get("code_challenge_method") != "S256" {
+				t.Errorf("unexpected consent URL %s", consentURL)
+			}
+			challenge = q.Get("code_challenge")
+			params.Set("state", q.Get("state"))
+			go http.Get(q.Get("redirect_uri") + "?" + params.Encode())
+			return nil
+		}
+	}
+
+	creds, err := app.Login(context.Background(), redirect(url.Values{"code": {"auth-code"}}))
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if creds.Type != TypeAuthorizedUser || creds.RefreshToken != "refresh-token" || creds.ClientID != "client" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+
+	if _, err := app.Login(context.Background(), redirect(url.Values{"error": {"access_denied"}})); err == nil {
+		t.Error("expected an error when consent is denied")
+	}
+	if _, err := app.Login(context.Background(), redirect(url.Values{"code": {"wrong-code"}})); err == nil {
+		t.Error("expected an error for a rejected code")
+	}
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/aygp-dr/adtap/internal/secrets"
+)
+
+// ErrNotStored is returned by Store.Load when no credentials are saved.
+var ErrNotStored = errors.New("auth: no stored credentials (run adtap auth login)")
+
+// Store keeps the credentials saved by an interactive login.
+type Store interface {
+	// Load returns the saved credentials, or ErrNotStored.
+	Load() (*Credentials, error)
+	Save(c *Credentials) error
+	// Delete removes the saved credentials; deleting nothing is not an
+	// error.
+	Delete() error
+	// String describes where the credentials are kept.
+	String() string
+}
+
+// DefaultStore returns the store selected by ADTAP_CREDENTIALS_STORE:
+// "keychain" for the OS keychain, "file" for a file in the user config
+// directory, or unset to use the keychain when its command-line tool is
+// installed and the file otherwise. The file is encrypted when
+// ADTAP_CREDENTIALS_KEY holds a base64-encoded 32-byte key.
+func DefaultStore() (Store, error) {
+	kind := os.Getenv("ADTAP_CREDENTIALS_STORE")
+	switch kind {
+	case "keychain":
+		if !secrets.KeyringAvailable() {
+			return nil, fmt.Errorf("auth: no keychain tool found for %s", runtime.GOOS)
+		}
+		return KeychainStore{Service: "adtap", Account: "default"}, nil
+	case "":
+		if secrets.KeyringAvailable() {
+			return KeychainStore{Service: "adtap", Account: "default"}, nil
+		}
+	case "file":
+	default:
+		return nil, fmt.Errorf("auth: invalid ADTAP_CREDENTIALS_STORE %q (expected keychain or file)", kind)
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("auth: locating config directory: %w", err)
+	}
+	store := FileStore{Path: filepath.Join(dir, "adtap", "credentials.json")}
+	if k := os.Getenv("ADTAP_CREDENTIALS_KEY"); k != "" {
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("auth: ADTAP_CREDENTIALS_KEY must be 32 bytes, base64 encoded")
+		}
+		store.Key = key
+	}
+	return store, nil
+}
+
+// FileStore keeps credentials in a file readable only by its owner. With
+// a Key, the file is sealed with AES-256-GCM.
+type FileStore struct {
+	Path string
+	Key  []byte // 32 bytes, or nil to store plain JSON
+}
+
+// sealed is the content of an encrypted credentials file.
+type sealed struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (s FileStore) String() string {
+	if s.Key != nil {
+		return s.Path + " (encrypted)"
+	}
+	return s.Path
+}
+
+// Load reads and, if needed, decrypts the credentials file.
+func (s FileStore) Load() (*Credentials, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, fmt.Errorf("auth: reading stored credentials: %w", err)
+	}
+
+	var box sealed
+	if json.Unmarshal(data, &box) == nil && box.Ciphertext != nil {
+		if s.Key == nil {
+			return nil, fmt.Errorf("auth: %s is encrypted; set ADTAP_CREDENTIALS_KEY", s.Path)
+		}
+		gcm, err := newGCM(s.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(box.Nonce) != gcm.NonceSize() {
+			return nil, fmt.Errorf("auth: decrypting %s: corrupt credential store: the nonce is %d bytes, not %d", s.Path, len(box.Nonce), gcm.NonceSize())
+		}
+		if data, err = gcm.Open(nil, box.Nonce, box.Ciphertext, nil); err != nil {
+			return nil, fmt.Errorf("auth: decrypting %s: wrong key or corrupt file", s.Path)
+		}
+	}
+	var c Credentials
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("auth: parsing stored credentials: %w", err)
+	}
+	return &c, nil
+}
+
+// Save writes the credentials, creating the directory if needed.
+func (s FileStore) Save(c *Credentials) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if s.Key != nil {
+		gcm, err := newGCM(s.Key)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("auth: generating nonce: %w", err)
+		}
+		if data, err = json.Marshal(sealed{Nonce: nonce, Ciphertext: gcm.Seal(nil, nonce, data, nil)}); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return fmt.Errorf("auth: saving credentials: %w", err)
+	}
+	if err := os.WriteFile(s.Path, data, 0o600); err != nil {
+		return fmt.Errorf("auth: saving credentials: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the credentials file.
+func (s FileStore) Delete() error {
+	if err := os.Remove(s.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("auth: deleting credentials: %w", err)
+	}
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("auth: invalid credentials key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// KeychainStore keeps credentials in the OS keychain, as the secret
+// Account of a secrets.Keyring.
+type KeychainStore struct {
+	Service string
+	Account string
+}
+
+func (s KeychainStore) String() string {
+	return fmt.Sprintf("OS keychain (service %s, account %s)", s.Service, s.Account)
+}
+
+func (s KeychainStore) keyring() secrets.Keyring {
+	return secrets.Keyring{Service: s.Service}
+}
+
+// Load reads the credentials from the keychain.
+func (s KeychainStore) Load() (*Credentials, error) {
+	data, err := s.keyring().Secret(context.Background(), s.Account)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	var c Credentials
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, fmt.Errorf("auth: parsing stored credentials: %w", err)
+	}
+	return &c, nil
+}
+
+// Save writes the credentials to the keychain, replacing any saved ones.
+func (s KeychainStore) Save(c *Credentials) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := s.keyring().SetSecret(context.Background(), s.Account, string(data)); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the credentials from the keychain.
+func (s KeychainStore) Delete() error {
+	if err := s.keyring().DeleteSecret(context.Background(), s.Account); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	return nil
+}
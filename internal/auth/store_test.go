@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	creds := &Credentials{Type: TypeAuthorizedUser, ClientID: "client", RefreshToken: "refresh-token"}
+	key := bytes.Repeat([]byte{7}, 32)
+
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{name: "plain", key: nil},
+		{name: "encrypted", key: key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := FileStore{Path: filepath.Join(t.TempDir(), "adtap", "credentials.json"), Key: tt.key}
+			if _, err := s.Load(); !errors.Is(err, ErrNotStored) {
+				t.Fatalf("expected ErrNotStored, got %v", err)
+			}
+			if err := s.Save(creds); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			fi, err := os.Stat(s.Path)
+			if err != nil || fi.Mode().Perm() != 0o600 {
+				t.Errorf("credentials file mode = %v, %v", fi.Mode(), err)
+			}
+			data, _ := os.ReadFile(s.Path)
+			if leaked := bytes.Contains(data, []byte("refresh-token")); leaked != (tt.key == nil) {
+				t.Errorf("refresh token in file = %v", leaked)
+			}
+			got, err := s.Load()
+			if err != nil || *got != *creds {
+				t.Errorf("Load = %+v, %v", got, err)
+			}
+			if err := s.Delete(); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := s.Load(); !errors.Is(err, ErrNotStored) {
+				t.Errorf("expected ErrNotStored after Delete, got %v", err)
+			}
+		})
+	}
+
+	s := FileStore{Path: filepath.Join(t.TempDir(), "credentials.json"), Key: key}
+	s.Save(creds)
+	s.Key = bytes.Repeat([]byte{8}, 32)
+	if _, err := s.Load(); err == nil {
+		t.Error("expected an error with the wrong key")
+	}
+
+	// A hand-edited file with a short nonce must not panic.
+	s.Key = key
+	os.WriteFile(s.Path, []byte(`{"nonce": "AAAA", "ciphertext": "AAAAAAAA"}`), 0o600)
+	if _, err := s.Load(); err == nil || !strings.Contains(err.Error(), "corrupt credential store") {
+		t.Errorf("Load of a short nonce = %v, want a corrupt credential store error", err)
+	}
+}
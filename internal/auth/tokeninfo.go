@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenInfoURL is Google's endpoint describing an access token.
+var TokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// TokenInfo describes an access token.
+type TokenInfo struct {
+	// Email is the account the token acts for. It is empty unless the
+	// token was granted the email scope, or belongs to a service account.
+	Email string
+
+	// Scopes are the OAuth2 scopes the token was granted.
+	Scopes []string
+
+	// ExpiresIn is how long the token stays valid.
+	ExpiresIn time.Duration
+}
+
+// HasScope reports whether the token was granted scope.
+func (ti *TokenInfo) HasScope(scope string) bool {
+	return slices.Contains(ti.Scopes, scope)
+}
+
+// LookupToken asks the tokeninfo endpoint about token. A token the
+// endpoint does not know, such as an expired one, is a *TokenError.
+func LookupToken(ctx context.Context, hc *http.Client, token string) (*TokenInfo, error) {
+	form := url.Values{"access_token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, TokenInfoURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Email     string `json:"email"`
+		Scope     string `json:"scope"`
+		ExpiresIn string `json:"expires_in"`
+
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	jsonErr := json.Unmarshal(data, &body)
+	if resp.StatusCode != http.StatusOK {
+		code := body.Error
+		if code == "" {
+			code = http.StatusText(resp.StatusCode)
+		}
+		return nil, &TokenError{StatusCode: resp.StatusCode, Code: code, Description: body.ErrorDescription}
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("auth: decoding token info: %w", jsonErr)
+	}
+	ti := &TokenInfo{Email: body.Email, Scopes: strings.Fields(body.Scope)}
+	if n, err := strconv.Atoi(body.ExpiresIn); err == nil {
+		ti.ExpiresIn = time.Duration(n) * time.Second
+	}
+	return ti, nil
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLookupToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("access_token") != "good" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_token", "error_description": "Invalid Value"}`))
+			return
+		}
+		w.Write([]byte(`{"email": "reports@example.com", "scope": "openid ` + Scope + `", "expires_in": "3599"}`))
+	}))
+	defer srv.Close()
+	old := TokenInfoURL
+	TokenInfoURL = srv.URL
+	defer func() { TokenInfoURL = old }()
+
+	ti, err := LookupToken(context.Background(), srv.Client(), "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti.Email != "reports@example.com" || !ti.HasScope(Scope) || ti.HasScope(CloudPlatformScope) || ti.ExpiresIn != 3599*time.Second {
+		t.Errorf("LookupToken = %+v", ti)
+	}
+
+	_, err = LookupToken(context.Background(), srv.Client(), "expired")
+	var te *TokenError
+	if !errors.As(err, &te) || te.Code != "invalid_token" {
+		t.Errorf("LookupToken(expired) error = %v, want invalid_token", err)
+	}
+}
@@ -0,0 +1,190 @@
+// Package backfill splits a long date range into windows, so a query
+// over months or years of daily data runs as many small queries rather
+// than one that times out or exhausts memory.
+//
+// Windows cover the range in order, each a BETWEEN condition on
+// segments.date replacing the query's own date conditions. A Checkpoint
+// records how many windows are done and how long the output was after
+// the last of them, so an interrupted backfill resumes where it stopped:
+// the output is cut back to that length, dropping a window written only
+// in part, and the remaining windows run.
+//
+// # Basic Usage
+//
+//	days, _ := backfill.ParseChunk("7d")
+//	for _, w := range backfill.Windows(from, to, days) {
+//		wq := backfill.Query(q, w)
+//		rows, err := client.Search(ctx, customerID, wq.String())
+//		...
+//	}
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// DateLayout is the form of the dates of a window, as GAQL writes them.
+const DateLayout = time.DateOnly
+
+// Window is a span of days, both ends included.
+type Window struct {
+	Start, End time.Time
+}
+
+func (w Window) String() string {
+	return w.Start.Format(DateLayout) + ".." + w.End.Format(DateLayout)
+}
+
+// Days returns the number of days in the window.
+func (w Window) Days() int {
+	return int(w.End.Sub(w.Start).Hours()/24) + 1
+}
+
+// Windows splits from..to, both included, into windows of days days;
+// the last window is shorter when the days do not divide evenly. It
+// returns nil if to is before from or days is not positive.
+func Windows(from, to time.Time, days int) []Window {
+	if days < 1 {
+		return nil
+	}
+	var out []Window
+	for start := from; !start.After(to); start = start.AddDate(0, 0, days) {
+		end := start.AddDate(0, 0, days-1)
+		if end.After(to) {
+			end = to
+		}
+		out = append(out, Window{Start: start, End: end})
+	}
+	return out
+}
+
+// ParseChunk parses a window length: a number of days or weeks, such as
+// 7d or 2w. A bare number means days.
+func ParseChunk(chunk string) (int, error) {
+	s := strings.ToLower(strings.TrimSpace(chunk))
+	unit := 1
+	switch {
+	case strings.HasSuffix(s, "d"):
+		s = strings.TrimSuffix(s, "d")
+	case strings.HasSuffix(s, "w"):
+		s, unit = strings.TrimSuffix(s, "w"), 7
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("backfill: invalid chunk %q (expected a number of days or weeks, such as 7d or 2w)", chunk)
+	}
+	return n * unit, nil
+}
+
+// Query returns a copy of q limited to the days of w: its segments.date
+// conditions are replaced by segments.date BETWEEN the window's ends,
+// and segments.date is selected if it was not, so rows of different
+// windows stay apart. LIMIT applies per window.
+func Query(q *gaql.Query, w Window) *gaql.Query {
+	out := q.Clone()
+	kept := out.Where[:0]
+	for _, c := range out.Where {
+		if c.Field != "segments.date" {
+			kept = append(kept, c)
+		}
+	}
+	out.Where = append(kept, gaql.Condition{
+		Field:    "segments.date",
+		Operator: gaql.OpBetween,
+		Value:    gaql.ListValue(w.Start.Format(DateLayout), w.End.Format(DateLayout)),
+	})
+	if !selects(out, "segments.date") {
+		out.Select = append([]gaql.Field{{Name: "segments.date"}}, out.Select...)
+	}
+	return out
+}
+
+func selects(q *gaql.Query, field string) bool {
+	for _, f := range q.Select {
+		if f.Name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Checkpoint is the progress of a backfill into an output file. The
+// fields other than Done and Offset identify the backfill, so a
+// checkpoint is only resumed by the same one.
+type Checkpoint struct {
+	CustomerID string `json:"customer_id"`
+	Query      string `json:"query"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	ChunkDays  int    `json:"chunk_days"`
+	Format     string `json:"format"`
+
+	// Done is the number of windows written, in order.
+	Done int `json:"done"`
+
+	// Offset is the length of the output after the last window written.
+	Offset int64 `json:"offset"`
+}
+
+// ErrMismatch is returned by Resume when a checkpoint belongs to another
+// backfill.
+var ErrMismatch = errors.New("backfill: the checkpoint is of another backfill")
+
+// Resume reads the checkpoint at path and checks that it belongs to the
+// same backfill as c, which it returns with Done and Offset filled in.
+// A missing checkpoint returns c unchanged, to start from the first
+// window.
+func Resume(path string, c Checkpoint) (Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("backfill: %w", err)
+	}
+	var saved Checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return c, fmt.Errorf("backfill: reading %s: %w", path, err)
+	}
+	done, offset := saved.Done, saved.Offset
+	saved.Done, saved.Offset = c.Done, c.Offset
+	if saved != c {
+		return c, fmt.Errorf("%w: %s", ErrMismatch, path)
+	}
+	c.Done, c.Offset = done, offset
+	return c, nil
+}
+
+// Save writes the checkpoint to path, replacing the file atomically so
+// an interruption leaves the previous checkpoint intact.
+func (c Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("backfill: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("backfill: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("backfill: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package backfill
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		from, to string
+		days     int
+		want     []string
+	}{
+		{"2025-01-01", "2025-01-21", 7, []string{"2025-01-01..2025-01-07", "2025-01-08..2025-01-14", "2025-01-15..2025-01-21"}},
+		{"2025-01-01", "2025-01-10", 7, []string{"2025-01-01..2025-01-07", "2025-01-08..2025-01-10"}},
+		{"2025-02-27", "2025-03-02", 1, []string{"2025-02-27..2025-02-27", "2025-02-28..2025-02-28", "2025-03-01..2025-03-01", "2025-03-02..2025-03-02"}},
+		{"2025-01-01", "2025-01-01", 30, []string{"2025-01-01..2025-01-01"}},
+		{"2025-01-02", "2025-01-01", 7, nil},
+		{"2025-01-01", "2025-01-10", 0, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, w := range Windows(date(tt.from), date(tt.to), tt.days) {
+			got = append(got, w.String())
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Windows(%s, %s, %d) = %v, want %v", tt.from, tt.to, tt.days, got, tt.want)
+		}
+	}
+	if d := (Window{date("2025-01-01"), date("2025-01-07")}).Days(); d != 7 {
+		t.Errorf("Days = %d, want 7", d)
+	}
+}
+
+func TestParseChunk(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"7d", 7, true},
+		{"2w", 14, true},
+		{"30", 30, true},
+		{" 1D ", 1, true},
+		{"0d", 0, false},
+		{"-1d", 0, false},
+		{"1m", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseChunk(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseChunk(%q) = %d, %v; want %d, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	w := Window{date("2025-01-08"), date("2025-01-14")}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_30_DAYS AND campaign.status = 'ENABLED'",
+			"SELECT segments.date, campaign.id, metrics.clicks FROM campaign WHERE campaign.status = 'ENABLED' AND segments.date BETWEEN '2025-01-08' AND '2025-01-14'"},
+		{"SELECT segments.date, metrics.clicks FROM customer WHERE segments.date >= '2024-01-01' AND segments.date <= '2024-12-31' ORDER BY segments.date LIMIT 10",
+			"SELECT segments.date, metrics.clicks FROM customer WHERE segments.date BETWEEN '2025-01-08' AND '2025-01-14' ORDER BY segments.date LIMIT 10"},
+	}
+	for _, tt := range tests {
+		q, err := gaql.Parse(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Query(q, w).String(); got != tt.want {
+			t.Errorf("Query:\n got %s\nwant %s", got, tt.want)
+		}
+		if q.String() == tt.want {
+			t.Error("Query changed its argument")
+		}
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv.checkpoint")
+	c := Checkpoint{CustomerID: "1234567890", Query: "SELECT ...", From: "2025-01-01", To: "2025-12-31", ChunkDays: 7, Format: "csv"}
+
+	got, err := Resume(path, c)
+	if err != nil || got != c {
+		t.Fatalf("Resume without a checkpoint = %+v, %v", got, err)
+	}
+	saved := c
+	saved.Done, saved.Offset = 3, 1024
+	if err := saved.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = Resume(path, c); err != nil || got != saved {
+		t.Errorf("Resume = %+v, %v; want %+v", got, err, saved)
+	}
+
+	other := c
+	other.ChunkDays = 14
+	if _, err := Resume(path, other); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Resume of another backfill: err = %v, want ErrMismatch", err)
+	}
+}
@@ -0,0 +1,255 @@
+// Package cache keeps query results on disk, so repeating an exploratory
+// query answers at once instead of calling the API again.
+//
+// Entries are keyed by customer ID, the login-customer-id the account
+// is reached through, the linked-customer-id of an app analytics
+// provider, the configuration profile, API version, and the query in its
+// canonical form: the query is parsed and printed back by the gaql
+// formatter, so queries differing only in keyword case, spacing, or line
+// breaks share an entry. An entry is served until it is older than the
+// cache's TTL.
+//
+// # Basic Usage
+//
+//	c := cache.New(cache.DefaultDir(), 10*time.Minute)
+//	client := adsapi.New(token, ts, adsapi.WithCache(c))
+//	// client.Search and client.SearchIter now consult c
+//
+//	n, err := c.Clear()
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// DefaultTTL is how long results are served from the cache unless
+// configured otherwise.
+const DefaultTTL = 10 * time.Minute
+
+// Entry is the cached result of one query.
+type Entry struct {
+	CustomerID       string          `json:"customer_id"`
+	LoginCustomerID  string          `json:"login_customer_id,omitempty"`
+	LinkedCustomerID string          `json:"linked_customer_id,omitempty"`
+	Profile          string          `json:"profile,omitempty"`
+	APIVersion       string          `json:"api_version"`
+	Query            string          `json:"query"` // canonical form
+	Created          time.Time       `json:"created"`
+	Rows             json.RawMessage `json:"rows"` // JSON array of result rows
+}
+
+// Cache is a directory of entries, one file each.
+type Cache struct {
+	Dir string
+
+	// TTL is the age past which entries are ignored; zero or less
+	// disables the cache.
+	TTL time.Duration
+
+	// Refresh makes Get miss, so every query runs and its entry is
+	// replaced.
+	Refresh bool
+
+	// Profile names the configuration profile of the clients using the
+	// cache. Profiles may reach the same account with different access,
+	// so the entries of one are never served to another.
+	Profile string
+
+	now  func() time.Time
+	hits atomic.Int64
+}
+
+// New returns a cache in dir serving entries younger than ttl.
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{Dir: dir, TTL: ttl, now: time.Now}
+}
+
+// DefaultDir returns the directory named by ADTAP_CACHE_DIR, or results
+// in the adtap directory of the user cache directory.
+func DefaultDir() string {
+	if dir := os.Getenv("ADTAP_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "adtap", "results")
+}
+
+// Canonical returns query as the gaql formatter prints it, without a
+// trailing semicolon, or with its whitespace collapsed when it does not
+// parse.
+func Canonical(query string) string {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if q, err := gaql.Parse(query); err == nil {
+		return q.String()
+	}
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// Key returns the key of the entry for query run against customerID,
+// reached through login as the login-customer-id and linked as the
+// linked-customer-id, by the clients of profile with an API version.
+func Key(customerID, login, linked, profile, version, query string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{customerID, login, linked, profile, version, Canonical(query)}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Enabled reports whether c stores and serves entries. A nil cache is
+// disabled.
+func (c *Cache) Enabled() bool {
+	return c != nil && c.Dir != "" && c.TTL > 0
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+func (c *Cache) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// Get returns the entry stored under key if it is younger than the TTL.
+// Unreadable entries count as misses.
+func (c *Cache) Get(key string) (*Entry, bool) {
+	if !c.Enabled() || c.Refresh {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e Entry
+	if json.Unmarshal(data, &e) != nil || c.clock().Sub(e.Created) >= c.TTL {
+		return nil, false
+	}
+	c.hits.Add(1)
+	return &e, true
+}
+
+// Hits returns how many times Get found an entry.
+func (c *Cache) Hits() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.hits.Load()
+}
+
+// Put stores e under key, stamped with the current time. The file is
+// written readable only by its owner, since results describe accounts,
+// and replaced atomically so concurrent readers see whole entries.
+func (c *Cache) Put(key string, e *Entry) error {
+	if !c.Enabled() {
+		return nil
+	}
+	e.Created = c.clock()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// Clear removes every entry and returns how many there were.
+func (c *Cache) Clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return n, fmt.Errorf("cache: %w", err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Stats describes the contents of a cache.
+type Stats struct {
+	Dir     string
+	Entries int
+	Bytes   int64
+	Expired int // entries older than the TTL
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// Stats reads every entry.
+func (c *Cache) Stats() (Stats, error) {
+	s := Stats{Dir: c.Dir}
+	files, err := c.files()
+	if err != nil {
+		return s, err
+	}
+	now := c.clock()
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var e Entry
+		if json.Unmarshal(data, &e) != nil {
+			continue
+		}
+		s.Entries++
+		s.Bytes += int64(len(data))
+		if now.Sub(e.Created) >= c.TTL {
+			s.Expired++
+		}
+		if s.Oldest.IsZero() || e.Created.Before(s.Oldest) {
+			s.Oldest = e.Created
+		}
+		if e.Created.After(s.Newest) {
+			s.Newest = e.Created
+		}
+	}
+	return s, nil
+}
+
+// files lists the entry files; a missing directory holds none.
+func (c *Cache) files() ([]string, error) {
+	if c.Dir == "" {
+		return nil, nil
+	}
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	const enabled = "SELECT campaign.id FROM campaign WHERE campaign.status = 'ENABLED'"
+	base := Key("1234567890", "9876543210", "", "agency", "v23", enabled)
+	tests := []struct {
+		customerID, login, linked, profile, version, query string
+		same                                               bool
+	}{
+		{"1234567890", "9876543210", "", "agency", "v23", "select campaign.id from campaign where campaign.status = ENABLED", true},
+		{"1234567890", "9876543210", "", "agency", "v23", "SELECT campaign.id\nFROM campaign\nWHERE campaign.status = 'ENABLED';", true},
+		{"1234567890", "9876543210", "", "agency", "v23", "SELECT campaign.id FROM campaign WHERE campaign.status = 'PAUSED'", false},
+		{"2345678901", "9876543210", "", "agency", "v23", enabled, false},
+		{"1234567890", "9876543210", "", "agency", "v22", enabled, false},
+		{"1234567890", "", "", "agency", "v23", enabled, false},
+		{"1234567890", "9876543210", "5555555555", "agency", "v23", enabled, false},
+		{"1234567890", "9876543210", "", "default", "v23", enabled, false},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.customerID, tt.login, tt.linked, tt.profile, tt.version, tt.query) == base; got != tt.same {
+			t.Errorf("Key(%s, %s, %s, %s, %s, %q) matches base: %v, want %v", tt.customerID, tt.login, tt.linked, tt.profile, tt.version, tt.query, got, tt.same)
+		}
+	}
+	if got := Canonical("SELEKT  a\nFROM b"); got != "SELEKT a FROM b" {
+		t.Errorf("Canonical of an invalid query = %q", got)
+	}
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := New(t.TempDir(), 10*time.Minute)
+	c.now = func() time.Time { return now }
+
+	key := Key("1234567890", "", "", "", "v23", "SELECT campaign.id FROM campaign")
+	if _, ok := c.Get(key); ok {
+		t.Fatal("Get on an empty cache hit")
+	}
+	rows := json.RawMessage(`[{"campaign":{"id":"1"}}]`)
+	if err := c.Put(key, &Entry{CustomerID: "1234567890", Rows: rows}); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := c.Get(key); !ok || string(e.Rows) != string(rows) || !e.Created.Equal(now) {
+		t.Errorf("Get = %+v, %v", e, ok)
+	}
+	if c.Hits() != 1 {
+		t.Errorf("Hits = %d, want 1", c.Hits())
+	}
+
+	c.Refresh = true
+	if _, ok := c.Get(key); ok {
+		t.Error("Get hit with Refresh set")
+	}
+	c.Refresh = false
+
+	now = now.Add(10 * time.Minute)
+	if _, ok := c.Get(key); ok {
+		t.Error("Get hit an expired entry")
+	}
+	c.Put(Key("1234567890", "", "", "", "v23", "SELECT ad_group.id FROM ad_group"), &Entry{Rows: rows})
+
+	s, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Entries != 2 || s.Expired != 1 || s.Bytes == 0 || !s.Newest.Equal(now) || !s.Oldest.Equal(now.Add(-10*time.Minute)) {
+		t.Errorf("Stats = %+v", s)
+	}
+
+	if n, err := c.Clear(); err != nil || n != 2 {
+		t.Errorf("Clear = %d, %v; want 2", n, err)
+	}
+	if s, _ := c.Stats(); s.Entries != 0 {
+		t.Errorf("%d entries left after Clear", s.Entries)
+	}
+
+	var disabled *Cache
+	if disabled.Enabled() || disabled.Put(key, &Entry{}) != nil {
+		t.Error("a nil cache is not disabled")
+	}
+}
@@ -0,0 +1,219 @@
+// Package changes reads the account change history returned for the
+// change_event resource and presents it as field-by-field diffs.
+//
+// Each change_event row names the changed fields in a field mask and
+// carries the resource before and after the change; FromRows pairs each
+// changed field with its old and new value. Fields the API omits because
+// they hold their default value are reported as nil.
+//
+// # Basic Usage
+//
+//	q, _ := compose.Changes(compose.ChangesOptions{Since: "2026-01-01", Until: "2026-01-14"})
+//	resp, err := client.Search(ctx, customerID, q.String())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	changes.WriteDiff(os.Stdout, changes.FromRows(resp.Results))
+package changes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// Change is one change_event: a resource created, updated, or removed.
+type Change struct {
+	Time         string        `json:"change_date_time"`
+	ResourceType string        `json:"change_resource_type"`
+	Operation    string        `json:"resource_change_operation"`
+	ResourceName string        `json:"change_resource_name"`
+	User         string        `json:"user_email"`
+	Client       string        `json:"client_type"`
+	Fields       []FieldChange `json:"changed_fields"`
+}
+
+// FieldChange is the old and new value of one changed field. Field is a
+// snake_case path relative to the resource, such as status or
+// bidding_strategy.target_cpa_micros.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// FromRows reads change_event result rows, keeping their order.
+func FromRows(rows []adsapi.Row) []Change {
+	out := make([]Change, 0, len(rows))
+	for _, row := range rows {
+		c := Change{
+			Time:         str(row, "change_event.change_date_time"),
+			ResourceType: str(row, "change_event.change_resource_type"),
+			Operation:    str(row, "change_event.resource_change_operation"),
+			ResourceName: str(row, "change_event.change_resource_name"),
+			User:         str(row, "change_event.user_email"),
+			Client:       str(row, "change_event.client_type"),
+		}
+		oldResource := resource(row, "change_event.old_resource")
+		newResource := resource(row, "change_event.new_resource")
+		for _, path := range changedFields(row) {
+			oldValue, _ := output.Value(oldResource, path)
+			newValue, _ := output.Value(newResource, path)
+			c.Fields = append(c.Fields, FieldChange{Field: snakeCase(path), Old: oldValue, New: newValue})
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// changedFields returns the paths of the row's field mask. The REST API
+// encodes a FieldMask as one comma-separated string of camelCase paths.
+func changedFields(row adsapi.Row) []string {
+	v, _ := output.Value(row, "change_event.changed_fields")
+	var paths []string
+	switch v := v.(type) {
+	case string:
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				paths = append(paths, p)
+			}
+		}
+	case map[string]any: // {"paths": [...]}, the protobuf form
+		list, _ := v["paths"].([]any)
+		for _, p := range list {
+			if s, ok := p.(string); ok {
+				paths = append(paths, s)
+			}
+		}
+	}
+	return paths
+}
+
+// resource returns the changed resource inside a ChangeEvent.Resource
+// oneof, e.g. the campaign in {"campaign": {...}}.
+func resource(row adsapi.Row, field string) map[string]any {
+	wrapper, _ := output.Value(row, field)
+	m, _ := wrapper.(map[string]any)
+	for _, v := range m {
+		if r, ok := v.(map[string]any); ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// WriteDiff writes changes in a readable diff format, one block per
+// change:
+//
+//	2026-01-05 14:03:22  UPDATE CAMPAIGN  customers/1234567890/campaigns/111
+//	  by jane@example.com via GOOGLE_ADS_WEB_CLIENT
+//	  status: ENABLED → PAUSED
+func WriteDiff(w io.Writer, changes []Change) error {
+	for i, c := range changes {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		when, _, _ := strings.Cut(c.Time, ".")
+		if _, err := fmt.Fprintf(w, "%s  %s %s  %s\n", when, c.Operation, c.ResourceType, c.ResourceName); err != nil {
+			return err
+		}
+		if by := attribution(c); by != "" {
+			if _, err := fmt.Fprintf(w, "  %s\n", by); err != nil {
+				return err
+			}
+		}
+		for _, f := range c.Fields {
+			if _, err := fmt.Fprintf(w, "  %s: %s → %s\n", f.Field, FormatValue(f.Field, f.Old), FormatValue(f.Field, f.New)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func attribution(c Change) string {
+	var parts []string
+	if c.User != "" {
+		parts = append(parts, "by "+c.User)
+	}
+	if c.Client != "" {
+		parts = append(parts, "via "+c.Client)
+	}
+	return strings.Join(parts, " ")
+}
+
+// FormatValue formats a field value for a diff. Missing values read
+// "(none)", amounts in micros are shown in currency units, text is
+// quoted, and messages and lists are compact JSON.
+func FormatValue(field string, v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "(none)"
+	case string:
+		if strings.HasSuffix(field, "_micros") {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return strconv.FormatFloat(float64(n)/1e6, 'f', 2, 64)
+			}
+		}
+		if isEnum(v) {
+			return v
+		}
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// isEnum reports whether s looks like an enum value such as PAUSED or
+// TARGET_CPA, which read better unquoted.
+func isEnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsUpper(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// snakeCase converts a camelCase field path to the snake_case of GAQL.
+func snakeCase(path string) string {
+	var sb strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			sb.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func str(row adsapi.Row, field string) string {
+	switch v, _ := output.Value(row, field); v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
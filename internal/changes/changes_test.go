@@ -0,0 +1,84 @@
+package changes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+func changeRow(op, changed string, oldResource, newResource map[string]any) adsapi.Row {
+	event := map[string]any{
+		"changeDateTime":          "2026-01-05 14:03:22.123456",
+		"changeResourceType":      "CAMPAIGN",
+		"resourceChangeOperation": op,
+		"changeResourceName":      "customers/1234567890/campaigns/111",
+		"userEmail":               "user@example.com",
+		"clientType":              "GOOGLE_ADS_WEB_CLIENT",
+		"changedFields":           changed,
+	}
+	if oldResource != nil {
+		event["oldResource"] = map[string]any{"campaign": oldResource}
+	}
+	if newResource != nil {
+		event["newResource"] = map[string]any{"campaign": newResource}
+	}
+	return adsapi.Row{"changeEvent": event}
+}
+
+func TestFromRows(t *testing.T) {
+	rows := []adsapi.Row{
+		changeRow("UPDATE", "status,name,targetSpend.cpcBidCeilingMicros",
+			map[string]any{"status": "ENABLED", "name": "Brand"},
+			map[string]any{"status": "PAUSED", "name": "Brand (old)", "targetSpend": map[string]any{"cpcBidCeilingMicros": "2500000"}}),
+		changeRow("CREATE", "name", nil, map[string]any{"name": "Tents"}),
+	}
+	got := FromRows(rows)
+	if len(got) != 2 {
+		t.Fatalf("got %d changes, want 2", len(got))
+	}
+	c := got[0]
+	if c.Operation != "UPDATE" || c.ResourceType != "CAMPAIGN" || c.User != "user@example.com" || c.Client != "GOOGLE_ADS_WEB_CLIENT" {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	want := []FieldChange{
+		{Field: "status", Old: "ENABLED", New: "PAUSED"},
+		{Field: "name", Old: "Brand", New: "Brand (old)"},
+		{Field: "target_spend.cpc_bid_ceiling_micros", Old: nil, New: "2500000"},
+	}
+	if !reflect.DeepEqual(c.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", c.Fields, want)
+	}
+	if f := got[1].Fields; len(f) != 1 || f[0].Old != nil || f[0].New != "Tents" {
+		t.Errorf("create fields = %+v", f)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	rows := []adsapi.Row{
+		changeRow("UPDATE", "status,targetSpend.cpcBidCeilingMicros,urlCustomParameters",
+			map[string]any{"status": "ENABLED", "targetSpend": map[string]any{"cpcBidCeilingMicros": "1000000"}},
+			map[string]any{"status": "PAUSED", "targetSpend": map[string]any{"cpcBidCeilingMicros": "2500000"},
+				"urlCustomParameters": []any{map[string]any{"key": "src", "value": "ads"}}}),
+		changeRow("CREATE", "name,advertisingChannelType", nil, map[string]any{"name": "Tents", "advertisingChannelType": "SEARCH"}),
+	}
+	var sb strings.Builder
+	if err := WriteDiff(&sb, FromRows(rows)); err != nil {
+		t.Fatal(err)
+	}
+	want := `2026-01-05 14:03:22  UPDATE CAMPAIGN  customers/1234567890/campaigns/111
+  by user@example.com via GOOGLE_ADS_WEB_CLIENT
+  status: ENABLED → PAUSED
+  target_spend.cpc_bid_ceiling_micros: 1.00 → 2.50
+  url_custom_parameters: (none) → [{"key":"src","value":"ads"}]
+
+2026-01-05 14:03:22  CREATE CAMPAIGN  customers/1234567890/campaigns/111
+  by user@example.com via GOOGLE_ADS_WEB_CLIENT
+  name: (none) → "Tents"
+  advertising_channel_type: (none) → SEARCH
+`
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
@@ -0,0 +1,380 @@
+// Package completion completes adtap command lines for bash, zsh, and
+// fish.
+//
+// The shell scripts from Script do no parsing of their own: on Tab they
+// run "adtap __complete" with the words typed so far, and Complete
+// answers from a Command tree describing the subcommands, flags, and
+// arguments. Values are computed when asked for, so flags such as
+// --customer-id and --query can offer cached customer IDs or GAQL
+// resource and field names.
+//
+// # Basic Usage
+//
+//	root := &completion.Command{Name: "adtap", Subcommands: []*completion.Command{
+//		{Name: "search", Flags: []completion.Flag{
+//			{Name: "customer-id", Values: customerIDs},
+//			{Name: "yes", Bool: true},
+//		}},
+//	}}
+//	res := completion.Complete(root, []string{"search", "--cust"})
+//	res.WriteTo(os.Stdout)
+package completion
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Candidate is one completion. The description is shown by zsh and fish.
+type Candidate struct {
+	Value       string
+	Description string
+}
+
+// Values returns the candidates for a flag value or argument, given the
+// word typed so far. Candidates replace the whole word; they need not
+// share its prefix, so a function may match case-insensitively.
+type Values func(word string) []Candidate
+
+// Flag is a flag of a command.
+type Flag struct {
+	Name        string
+	Description string
+	Bool        bool   // takes no value
+	Values      Values // nil offers nothing
+	Files       bool   // the value is a file name
+}
+
+// Command is a command or subcommand. Flags of a command are accepted by
+// its subcommands too.
+type Command struct {
+	Name        string
+	Description string
+	Flags       []Flag
+	Subcommands []*Command
+
+	// Args completes positional arguments by position; nil entries and
+	// positions past the end offer nothing.
+	Args []Values
+	// Files makes every positional argument a file name.
+	Files bool
+
+	parent *Command
+}
+
+// Result is the answer to a completion request.
+type Result struct {
+	Candidates []Candidate
+
+	// Files asks the shell to complete file names itself.
+	Files bool
+}
+
+// filesDirective is the line that stands for Result.Files in the output
+// read by the scripts.
+const filesDirective = ":files"
+
+// WriteTo writes the result as the scripts expect it: one candidate per
+// line, with its description after a tab, or the files directive alone.
+func (r Result) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	if r.Files {
+		sb.WriteString(filesDirective + "\n")
+	}
+	for _, c := range r.Candidates {
+		sb.WriteString(clean(c.Value))
+		if c.Description != "" {
+			sb.WriteString("\t" + clean(c.Description))
+		}
+		sb.WriteByte('\n')
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// clean keeps a value on its line.
+func clean(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// Complete returns the completions of the last of words, the arguments
+// typed after the program name; the last word is the one being
+// completed and is empty after a space.
+//
+// The word may keep the opening quote of a quoted argument, which is
+// ignored. Bash splits --flag=value into three words; they are joined
+// back, and candidates are then returned without the "--flag=" part
+// bash already has.
+func Complete(root *Command, words []string) Result {
+	root.link(nil)
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	words, split := joinEquals(words)
+	word := unquote(words[len(words)-1])
+
+	cmd := root
+	var pending *Flag // flag waiting for its value
+	var args []string // positional arguments of cmd
+	for _, w := range words[:len(words)-1] {
+		w = unquote(w)
+		switch {
+		case pending != nil:
+			pending = nil
+		case w == "--":
+			// Flags end; everything after is an argument, which the
+			// commands here do not take.
+		case strings.HasPrefix(w, "-") && w != "-":
+			name, _, hasValue := strings.Cut(strings.TrimLeft(w, "-"), "=")
+			if f := cmd.flag(name); f != nil && !f.Bool && !hasValue {
+				pending = f
+			}
+		default:
+			if sub := cmd.subcommand(w); sub != nil && len(args) == 0 {
+				cmd = sub
+				continue
+			}
+			args = append(args, w)
+		}
+	}
+
+	var res Result
+	switch {
+	case pending != nil:
+		res = pending.complete(word)
+	case strings.HasPrefix(word, "-") && strings.Contains(word, "="):
+		name, value, _ := strings.Cut(word, "=")
+		if f := cmd.flag(strings.TrimLeft(name, "-")); f != nil && !f.Bool {
+			res = f.complete(value)
+			if !split {
+				for i := range res.Candidates {
+					res.Candidates[i].Value = name + "=" + res.Candidates[i].Value
+				}
+			}
+		}
+	case strings.HasPrefix(word, "-"):
+		for c := cmd; c != nil; c = c.parent {
+			for _, f := range c.Flags {
+				if name := "--" + f.Name; strings.HasPrefix(name, word) {
+					res.Candidates = append(res.Candidates, Candidate{name, f.Description})
+				}
+			}
+		}
+		sortCandidates(res.Candidates)
+	case len(cmd.Subcommands) > 0 && len(args) == 0:
+		for _, sub := range cmd.Subcommands {
+			if strings.HasPrefix(sub.Name, word) {
+				res.Candidates = append(res.Candidates, Candidate{sub.Name, sub.Description})
+			}
+		}
+		sortCandidates(res.Candidates)
+	case cmd.Files:
+		res.Files = true
+	case len(args) < len(cmd.Args) && cmd.Args[len(args)] != nil:
+		res.Candidates = cmd.Args[len(args)](word)
+	}
+	return res
+}
+
+func (f *Flag) complete(word string) Result {
+	if f.Files {
+		return Result{Files: true}
+	}
+	if f.Values == nil {
+		return Result{}
+	}
+	return Result{Candidates: f.Values(word)}
+}
+
+// link sets the parent of every subcommand below c.
+func (c *Command) link(parent *Command) {
+	c.parent = parent
+	for _, sub := range c.Subcommands {
+		sub.link(c)
+	}
+}
+
+// flag finds a flag of c or of the commands above it.
+func (c *Command) flag(name string) *Flag {
+	for ; c != nil; c = c.parent {
+		for i := range c.Flags {
+			if c.Flags[i].Name == name {
+				return &c.Flags[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Command) subcommand(name string) *Command {
+	for _, sub := range c.Subcommands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// joinEquals rejoins the words bash splits at "=", reporting whether
+// the last word was one of them.
+func joinEquals(words []string) ([]string, bool) {
+	var out []string
+	split := false
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if w == "=" && len(out) > 0 && strings.HasPrefix(out[len(out)-1], "-") && !strings.Contains(out[len(out)-1], "=") {
+			out[len(out)-1] += "="
+			split = i == len(words)-1
+			if i+1 < len(words) {
+				out[len(out)-1] += words[i+1]
+				split = i+1 == len(words)-1
+				i++
+			}
+			continue
+		}
+		out = append(out, w)
+	}
+	return out, split
+}
+
+// unquote drops the opening quote of a word still being typed, and the
+// closing one of a finished word.
+func unquote(w string) string {
+	if w == "" || w[0] != '"' && w[0] != '\'' {
+		return w
+	}
+	q := w[:1]
+	return strings.TrimSuffix(w[1:], q)
+}
+
+// Match returns the candidates whose values start with word.
+func Match(word string, candidates []Candidate) []Candidate {
+	var out []Candidate
+	for _, c := range candidates {
+		if strings.HasPrefix(c.Value, word) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Strings returns values as candidates without descriptions.
+func Strings(values ...string) []Candidate {
+	out := make([]Candidate, len(values))
+	for i, v := range values {
+		out[i] = Candidate{Value: v}
+	}
+	return out
+}
+
+func sortCandidates(cs []Candidate) {
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Value < cs[j].Value })
+}
+
+// Shells lists the shells Script supports.
+var Shells = []string{"bash", "zsh", "fish"}
+
+// Script writes the completion script for shell, completing the command
+// prog.
+func Script(w io.Writer, shell, prog string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashScript
+	case "zsh":
+		script = zshScript
+	case "fish":
+		script = fishScript
+	default:
+		return fmt.Errorf("completion: unsupported shell %q (expected %s)", shell, strings.Join(Shells, ", "))
+	}
+	_, err := io.WriteString(w, strings.ReplaceAll(script, "PROG", prog))
+	return err
+}
+
+// The scripts leave no space after values ending in ".", ",", or "=",
+// which go on: "campaign." with a field, "--format=" with a value.
+
+const bashScript = `# bash completion for PROG
+#
+# Load it in the current shell with:
+#   source <(PROG completion bash)
+
+_PROG() {
+    local cur=${COMP_WORDS[COMP_CWORD]} line
+    local IFS=$'\n'
+    local -a out
+    [[ $cur == = ]] && cur=
+    out=($(PROG __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    COMPREPLY=()
+    if [[ ${out[0]} == :files ]]; then
+        compopt -o filenames 2>/dev/null
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
+    fi
+    for line in "${out[@]}"; do
+        COMPREPLY+=("${line%%$'\t'*}")
+    done
+    if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *[.,=] ]]; then
+        compopt -o nospace 2>/dev/null
+    fi
+}
+
+complete -F _PROG PROG
+`
+
+const zshScript = `#compdef PROG
+# zsh completion for PROG
+#
+# Install it as _PROG in a directory on $fpath, for example:
+#   PROG completion zsh > "${fpath[1]}/_PROG"
+
+_PROG() {
+    local line value
+    local -a lines more more_descs final final_descs
+    lines=("${(@f)$(PROG __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ $lines[1] == :files ]]; then
+        _files
+        return
+    fi
+    for line in $lines; do
+        [[ -z $line ]] && continue
+        value=${line%%$'\t'*}
+        if [[ $value == *[.,=] ]]; then
+            more+=("$value")
+            more_descs+=("${line/$'\t'/  -- }")
+        else
+            final+=("$value")
+            final_descs+=("${line/$'\t'/  -- }")
+        fi
+    done
+    (( $#more )) && compadd -l -S '' -d more_descs -- "${more[@]}"
+    (( $#final )) && compadd -l -d final_descs -- "${final[@]}"
+}
+
+if [[ $zsh_eval_context[-1] == loadautofunc ]]; then
+    _PROG "$@"
+else
+    compdef _PROG PROG
+fi
+`
+
+const fishScript = `# fish completion for PROG
+#
+# Install it with:
+#   PROG completion fish > ~/.config/fish/completions/PROG.fish
+
+function __PROG_complete
+    set -l args (commandline -opc)[2..-1] (commandline -ct)
+    set -l out (PROG __complete $args 2>/dev/null)
+    if test "$out[1]" = :files
+        __fish_complete_path (commandline -ct)
+        return
+    end
+    printf '%s\n' $out
+end
+
+complete -c PROG -f -a '(__PROG_complete)'
+`
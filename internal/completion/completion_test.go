@@ -0,0 +1,104 @@
+package completion
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testTree() *Command {
+	ids := func(word string) []Candidate {
+		return Match(word, []Candidate{{"1234567890", "Main"}, {"2345678901", "Brand"}})
+	}
+	return &Command{
+		Name:  "adtap",
+		Flags: []Flag{{Name: "profile", Values: func(string) []Candidate { return Strings("agency") }}},
+		Subcommands: []*Command{
+			{Name: "search", Description: "Execute a GAQL query", Flags: []Flag{
+				{Name: "customer-id", Values: ids},
+				{Name: "file", Files: true},
+				{Name: "format", Values: func(w string) []Candidate { return Match(w, Strings("csv", "json", "jsonl")) }},
+				{Name: "yes", Bool: true},
+			}},
+			{Name: "config", Subcommands: []*Command{
+				{Name: "set", Args: []Values{func(w string) []Candidate { return Match(w, Strings("customer_id", "format")) }, nil}},
+			}},
+			{Name: "lint", Files: true},
+		},
+	}
+}
+
+func values(res Result) []string {
+	var out []string
+	for _, c := range res.Candidates {
+		out = append(out, c.Value)
+	}
+	return out
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  []string
+		files bool
+	}{
+		{words: nil, want: []string{"config", "lint", "search"}},
+		{words: []string{"se"}, want: []string{"search"}},
+		{words: []string{"--profile", ""}, want: []string{"agency"}},
+		{words: []string{"--profile", "agency", "s"}, want: []string{"search"}},
+		{words: []string{"search", "--"}, want: []string{"--customer-id", "--file", "--format", "--profile", "--yes"}},
+		{words: []string{"search", "--customer-id", "2"}, want: []string{"2345678901"}},
+		{words: []string{"search", "--yes", "--customer-id", ""}, want: []string{"1234567890", "2345678901"}},
+		{words: []string{"search", "--customer-id=1"}, want: []string{"--customer-id=1234567890"}},
+		{words: []string{"search", "--format", "=", "js"}, want: []string{"json", "jsonl"}},
+		{words: []string{"search", "--format", "="}, want: []string{"csv", "json", "jsonl"}},
+		{words: []string{"search", "--format", "=", "csv", "--y"}, want: []string{"--yes"}},
+		{words: []string{"search", "--customer-id", `"12`}, want: []string{"1234567890"}},
+		{words: []string{"search", "--file", ""}, files: true},
+		{words: []string{"search", "--yes", ""}, want: nil},
+		{words: []string{"config", ""}, want: []string{"set"}},
+		{words: []string{"config", "set", "f"}, want: []string{"format"}},
+		{words: []string{"config", "set", "format", ""}, want: nil},
+		{words: []string{"lint", "queries/"}, files: true},
+	}
+	for _, tt := range tests {
+		res := Complete(testTree(), tt.words)
+		if got := values(res); !reflect.DeepEqual(got, tt.want) || res.Files != tt.files {
+			t.Errorf("Complete(%q) = %q, files %v; want %q, files %v", tt.words, got, res.Files, tt.want, tt.files)
+		}
+	}
+}
+
+func TestResultWriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	res := Result{Candidates: []Candidate{{"search", "Execute a GAQL query"}, {"lint", "Lint\tfiles"}, {"top", ""}}}
+	if _, err := res.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "search\tExecute a GAQL query\nlint\tLint files\ntop\n"
+	if buf.String() != want {
+		t.Errorf("WriteTo = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	Result{Files: true}.WriteTo(&buf)
+	if buf.String() != ":files\n" {
+		t.Errorf("WriteTo = %q, want the files directive", buf.String())
+	}
+}
+
+func TestScript(t *testing.T) {
+	for _, shell := range Shells {
+		var buf bytes.Buffer
+		if err := Script(&buf, shell, "adtap"); err != nil {
+			t.Fatalf("Script(%s): %v", shell, err)
+		}
+		if !strings.Contains(buf.String(), "adtap __complete") || strings.Contains(buf.String(), "PROG") {
+			t.Errorf("Script(%s) does not call adtap __complete:\n%s", shell, buf.String())
+		}
+	}
+	if err := Script(&bytes.Buffer{}, "tcsh", "adtap"); err == nil {
+		t.Error("Script(tcsh) succeeded, want an error")
+	}
+}
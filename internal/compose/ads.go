@@ -0,0 +1,58 @@
+package compose
+
+import "github.com/aygp-dr/adtap/internal/gaql"
+
+// AdOptions are the filters of "adtap ads".
+type AdOptions struct {
+	// AdGroupID keeps the ads of one ad group; zero means every ad
+	// group.
+	AdGroupID int64
+
+	// CampaignID keeps the ads of one campaign; zero means every
+	// campaign.
+	CampaignID int64
+
+	// PolicyIssuesOnly keeps the ads not fully approved: limited,
+	// restricted to areas of interest, or disapproved.
+	PolicyIssuesOnly bool
+}
+
+// AdFields are the fields "adtap ads" selects, in the order the ads
+// preview decodes them.
+var AdFields = []string{
+	"campaign.name",
+	"ad_group.id",
+	"ad_group.name",
+	"ad_group_ad.ad.id",
+	"ad_group_ad.ad.type",
+	"ad_group_ad.status",
+	"ad_group_ad.ad.responsive_search_ad.headlines",
+	"ad_group_ad.ad.responsive_search_ad.descriptions",
+	"ad_group_ad.ad.responsive_search_ad.path1",
+	"ad_group_ad.ad.responsive_search_ad.path2",
+	"ad_group_ad.ad.final_urls",
+	"ad_group_ad.policy_summary.approval_status",
+	"ad_group_ad.policy_summary.review_status",
+	"ad_group_ad.policy_summary.policy_topic_entries",
+}
+
+// Ads builds the query behind "adtap ads".
+func Ads(opts AdOptions) *gaql.Query {
+	b := gaql.Select(AdFields...).
+		From("ad_group_ad").
+		Where("ad_group_ad.status", gaql.OpNeq, gaql.StringValue("REMOVED"))
+	if opts.CampaignID != 0 {
+		b.Where("campaign.id", gaql.OpEq, gaql.NumberValue(float64(opts.CampaignID)))
+	}
+	if opts.AdGroupID != 0 {
+		b.Where("ad_group.id", gaql.OpEq, gaql.NumberValue(float64(opts.AdGroupID)))
+	}
+	if opts.PolicyIssuesOnly {
+		b.Where("ad_group_ad.policy_summary.approval_status", gaql.OpIn,
+			gaql.ListValue("APPROVED_LIMITED", "AREA_OF_INTEREST_ONLY", "DISAPPROVED"))
+	}
+	return b.OrderBy("campaign.name", gaql.Asc).
+		OrderBy("ad_group.name", gaql.Asc).
+		OrderBy("ad_group_ad.ad.id", gaql.Asc).
+		Query()
+}
@@ -0,0 +1,105 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// CampaignOptions are the filters of "adtap campaigns".
+type CampaignOptions struct {
+	// Statuses keeps campaigns in any of these states. Empty means
+	// every state except REMOVED.
+	Statuses []string
+
+	// Channels keeps campaigns of any of these advertising channel
+	// types, e.g. SEARCH.
+	Channels []string
+
+	// NameContains keeps campaigns whose name contains the text.
+	NameContains string
+
+	// Since keeps campaigns starting on or after this YYYY-MM-DD date.
+	Since string
+
+	// Metrics adds the standard metric columns over the last 30 days.
+	Metrics bool
+}
+
+// Campaigns builds the query behind "adtap campaigns".
+func Campaigns(opts CampaignOptions) (*gaql.Query, error) {
+	b := gaql.Select("campaign.id", "campaign.name", "campaign.status", "campaign.advertising_channel_type").
+		From("campaign")
+
+	if len(opts.Statuses) == 0 {
+		b.Where("campaign.status", gaql.OpNeq, gaql.StringValue("REMOVED"))
+	} else if err := whereEnum(b, "campaign.status", opts.Statuses); err != nil {
+		return nil, err
+	}
+	if len(opts.Channels) > 0 {
+		if err := whereEnum(b, "campaign.advertising_channel_type", opts.Channels); err != nil {
+			return nil, err
+		}
+	}
+	if opts.NameContains != "" {
+		b.Where("campaign.name", gaql.OpLike, gaql.StringValue("%"+EscapeLike(opts.NameContains)+"%"))
+	}
+	if opts.Since != "" {
+		if _, err := time.Parse(time.DateOnly, opts.Since); err != nil {
+			return nil, fmt.Errorf("compose: invalid date %q (expected YYYY-MM-DD)", opts.Since)
+		}
+		b.Select("campaign.start_date").
+			Where("campaign.start_date", gaql.OpGte, gaql.StringValue(opts.Since))
+	}
+	if opts.Metrics {
+		b.Select(standardMetrics...).During(gaql.DateRangeLast30Days)
+	}
+	return b.OrderBy("campaign.name", gaql.Asc).Query(), nil
+}
+
+// whereEnum restricts field to values, checked against the enum values
+// the catalog lists for it.
+func whereEnum(b *gaql.Builder, field string, values []string) error {
+	info, _ := gaql.DefaultCatalog().Field(field)
+	upper := make([]string, len(values))
+	for i, v := range values {
+		upper[i] = strings.ToUpper(strings.TrimSpace(v))
+		if !contains(info.EnumValues, upper[i]) {
+			return fmt.Errorf("compose: invalid %s value %q (expected one of %s)", field, v, strings.Join(info.EnumValues, ", "))
+		}
+	}
+	if len(upper) == 1 {
+		b.Where(field, gaql.OpEq, gaql.StringValue(upper[0]))
+	} else {
+		b.Where(field, gaql.OpIn, gaql.ListValue(upper...))
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// EscapeLike escapes the LIKE wildcards % and _, and the brackets used
+// to escape them, so s matches literally inside a LIKE pattern.
+func EscapeLike(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '%', '_', '[', ']':
+			sb.WriteByte('[')
+			sb.WriteRune(r)
+			sb.WriteByte(']')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
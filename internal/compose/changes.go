@@ -0,0 +1,74 @@
+package compose
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// ChangesOptions are the filters of "adtap changes".
+type ChangesOptions struct {
+	// Since and Until bound the changes as YYYY-MM-DD dates, both
+	// included. They may be at most gaql.ChangeEventMaxDays days apart.
+	Since, Until string
+
+	// ResourceTypes keeps changes to resources of any of these types,
+	// e.g. CAMPAIGN.
+	ResourceTypes []string
+
+	// Limit caps the number of changes. Zero means
+	// gaql.ChangeEventMaxLimit, the most the API returns.
+	Limit int
+}
+
+// ChangeFields are the change_event fields selected by Changes.
+var ChangeFields = []string{
+	"change_event.change_date_time",
+	"change_event.change_resource_type",
+	"change_event.resource_change_operation",
+	"change_event.change_resource_name",
+	"change_event.user_email",
+	"change_event.client_type",
+	"change_event.changed_fields",
+	"change_event.old_resource",
+	"change_event.new_resource",
+}
+
+// Changes builds the query behind "adtap changes": the account's change
+// history between two dates, newest first.
+func Changes(opts ChangesOptions) (*gaql.Query, error) {
+	since, err := time.Parse(time.DateOnly, opts.Since)
+	if err != nil {
+		return nil, fmt.Errorf("compose: invalid date %q (expected YYYY-MM-DD)", opts.Since)
+	}
+	until, err := time.Parse(time.DateOnly, opts.Until)
+	if err != nil {
+		return nil, fmt.Errorf("compose: invalid date %q (expected YYYY-MM-DD)", opts.Until)
+	}
+	if until.Before(since) {
+		return nil, fmt.Errorf("compose: %s is before %s", opts.Until, opts.Since)
+	}
+	if days := int(until.Sub(since).Hours()/24) + 1; days > gaql.ChangeEventMaxDays {
+		return nil, fmt.Errorf("compose: %s to %s is %d days; change history can be queried at most %d days at a time", opts.Since, opts.Until, days, gaql.ChangeEventMaxDays)
+	}
+	limit := opts.Limit
+	if limit == 0 {
+		limit = gaql.ChangeEventMaxLimit
+	}
+	if limit < 0 || limit > gaql.ChangeEventMaxLimit {
+		return nil, fmt.Errorf("compose: invalid limit %d (expected 1 to %d)", limit, gaql.ChangeEventMaxLimit)
+	}
+
+	const field = "change_event.change_date_time"
+	b := gaql.Select(ChangeFields...).
+		From("change_event").
+		Where(field, gaql.OpGte, gaql.StringValue(opts.Since)).
+		Where(field, gaql.OpLte, gaql.StringValue(opts.Until+" 23:59:59"))
+	if len(opts.ResourceTypes) > 0 {
+		if err := whereEnum(b, "change_event.change_resource_type", opts.ResourceTypes); err != nil {
+			return nil, err
+		}
+	}
+	return b.OrderBy(field, gaql.Desc).Limit(limit).Query(), nil
+}
@@ -0,0 +1,136 @@
+// Package compose turns command-line options into GAQL queries.
+//
+// Convenience commands such as "adtap top" describe what to fetch with a
+// few flags; this package builds the matching query with the gaql
+// builder so the flag handling can be tested without the CLI or the API.
+//
+// # Basic Usage
+//
+//	q, err := compose.Top(compose.TopOptions{
+//		Entity: "campaigns",
+//		By:     "clicks",
+//		During: gaql.DateRangeLast7Days,
+//	})
+//
+// # Templates
+//
+// Templates are named queries whose parameters are declared with types
+// and defaults, so the same declaration yields command-line flags and
+// the JSON Schema of an MCP tool:
+//
+//	t, _ := compose.LookupTemplate("campaign-performance")
+//	args, err := t.Parse(map[string]string{"customer_id": "1234567890"})
+//	q, err := t.Query(args)
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Metrics maps the short metric names accepted on the command line to
+// GAQL fields.
+var Metrics = map[string]string{
+	"all_conversions":   "metrics.all_conversions",
+	"clicks":            "metrics.clicks",
+	"conversions":       "metrics.conversions",
+	"conversions_value": "metrics.conversions_value",
+	"cost":              "metrics.cost_micros",
+	"cpc":               "metrics.average_cpc",
+	"ctr":               "metrics.ctr",
+	"impressions":       "metrics.impressions",
+	"interactions":      "metrics.interactions",
+}
+
+// MetricField resolves a short metric name, or a full metrics.* field
+// known to the catalog, to its GAQL field.
+func MetricField(name string) (string, error) {
+	if field, ok := Metrics[strings.ToLower(name)]; ok {
+		return field, nil
+	}
+	if info, ok := gaql.DefaultCatalog().Field(name); ok && info.Category == "METRIC" {
+		return name, nil
+	}
+	names := make([]string, 0, len(Metrics))
+	for k := range Metrics {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("compose: unknown metric %q (expected one of %s, or a metrics.* field)", name, strings.Join(names, ", "))
+}
+
+// ParseDuring resolves a DURING keyword such as LAST_7_DAYS.
+func ParseDuring(s string) (gaql.DateRange, error) {
+	dr, ok := gaql.LookupDateRange(s)
+	if !ok || dr == gaql.DateRangeCustom {
+		return 0, fmt.Errorf("compose: unknown date range %q", s)
+	}
+	return dr, nil
+}
+
+// standardMetrics are selected alongside the ranking metric.
+var standardMetrics = []string{"metrics.impressions", "metrics.clicks", "metrics.cost_micros"}
+
+// topEntity describes what "adtap top <entity>" ranks.
+type topEntity struct {
+	resource string
+	fields   []string
+}
+
+// TopEntities lists the entities Top can rank.
+var TopEntities = []string{"campaigns", "adgroups", "keywords"}
+
+var topEntities = map[string]topEntity{
+	"campaigns": {
+		resource: "campaign",
+		fields:   []string{"campaign.id", "campaign.name"},
+	},
+	"adgroups": {
+		resource: "ad_group",
+		fields:   []string{"campaign.name", "ad_group.id", "ad_group.name"},
+	},
+	"keywords": {
+		resource: "keyword_view",
+		fields: []string{
+			"campaign.name", "ad_group.name",
+			"ad_group_criterion.keyword.text", "ad_group_criterion.keyword.match_type",
+		},
+	},
+}
+
+// TopOptions configures Top.
+type TopOptions struct {
+	Entity string         // campaigns, adgroups, or keywords
+	By     string         // metric to rank by; see MetricField
+	During gaql.DateRange // date range the metrics cover
+	Limit  int            // number of rows; 0 means 10
+}
+
+// Top builds the query behind "adtap top": the entities with the highest
+// value of a metric over a date range.
+func Top(opts TopOptions) (*gaql.Query, error) {
+	entity, ok := topEntities[opts.Entity]
+	if !ok {
+		return nil, fmt.Errorf("compose: unknown entity %q (expected %s)", opts.Entity, strings.Join(TopEntities, ", "))
+	}
+	by, err := MetricField(opts.By)
+	if err != nil {
+		return nil, err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	return gaql.Select(entity.fields...).
+		Select(standardMetrics...).
+		Select(by).
+		From(entity.resource).
+		During(opts.During).
+		OrderBy(by, gaql.Desc).
+		Limit(limit).
+		Query(), nil
+}
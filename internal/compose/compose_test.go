@@ -0,0 +1,377 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestTop(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    TopOptions
+		want    string
+		wantErr string
+	}{
+		{
+			name: "campaigns by clicks",
+			opts: TopOptions{Entity: "campaigns", By: "clicks", During: gaql.DateRangeLast7Days},
+			want: "SELECT campaign.id, campaign.name, metrics.impressions, metrics.clicks, metrics.cost_micros FROM campaign WHERE segments.date DURING LAST_7_DAYS ORDER BY metrics.clicks DESC LIMIT 10",
+		},
+		{
+			name: "keywords by conversions",
+			opts: TopOptions{Entity: "keywords", By: "conversions", During: gaql.DateRangeLast30Days, Limit: 25},
+			want: "SELECT campaign.name, ad_group.name, ad_group_criterion.keyword.text, ad_group_criterion.keyword.match_type, metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.conversions FROM keyword_view WHERE segments.date DURING LAST_30_DAYS ORDER BY metrics.conversions DESC LIMIT 25",
+		},
+		{
+			name: "adgroups by full metric name",
+			opts: TopOptions{Entity: "adgroups", By: "metrics.search_impression_share", During: gaql.DateRangeYesterday},
+			want: "SELECT campaign.name, ad_group.id, ad_group.name, metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.search_impression_share FROM ad_group WHERE segments.date DURING YESTERDAY ORDER BY metrics.search_impression_share DESC LIMIT 10",
+		},
+		{
+			name:    "unknown entity",
+			opts:    TopOptions{Entity: "ads", By: "clicks"},
+			wantErr: "unknown entity",
+		},
+		{
+			name:    "unknown metric",
+			opts:    TopOptions{Entity: "campaigns", By: "campaign.name"},
+			wantErr: "unknown metric",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Top(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+			if err := gaql.NewValidator().Validate(q); err != nil {
+				t.Errorf("generated query is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseDuring(t *testing.T) {
+	if dr, err := ParseDuring("last_14_days"); err != nil || dr != gaql.DateRangeLast14Days {
+		t.Errorf("ParseDuring(last_14_days) = %v, %v", dr, err)
+	}
+	if _, err := ParseDuring("LAST_QUARTER"); err == nil {
+		t.Error("expected error for unknown keyword")
+	}
+}
+
+func TestCampaigns(t *testing.T) {
+	const base = "SELECT campaign.id, campaign.name, campaign.status, campaign.advertising_channel_type"
+	tests := []struct {
+		name    string
+		opts    CampaignOptions
+		want    string
+		wantErr string
+	}{
+		{
+			name: "defaults exclude removed",
+			want: base + " FROM campaign WHERE campaign.status != 'REMOVED' ORDER BY campaign.name",
+		},
+		{
+			name: "status and channel",
+			opts: CampaignOptions{Statuses: []string{"enabled", "PAUSED"}, Channels: []string{"search"}},
+			want: base + " FROM campaign WHERE campaign.status IN ('ENABLED', 'PAUSED') AND campaign.advertising_channel_type = 'SEARCH' ORDER BY campaign.name",
+		},
+		{
+			name: "name contains escapes wildcards",
+			opts: CampaignOptions{Statuses: []string{"ENABLED"}, NameContains: "50% off_"},
+			want: base + " FROM campaign WHERE campaign.status = 'ENABLED' AND campaign.name LIKE '%50[%] off[_]%' ORDER BY campaign.name",
+		},
+		{
+			name: "since and metrics",
+			opts: CampaignOptions{Since: "2026-01-01", Metrics: true},
+			want: base + ", campaign.start_date, metrics.impressions, metrics.clicks, metrics.cost_micros FROM campaign WHERE campaign.status != 'REMOVED' AND campaign.start_date >= '2026-01-01' AND segments.date DURING LAST_30_DAYS ORDER BY campaign.name",
+		},
+		{
+			name:    "invalid status",
+			opts:    CampaignOptions{Statuses: []string{"ACTIVE"}},
+			wantErr: `invalid campaign.status value "ACTIVE"`,
+		},
+		{
+			name:    "invalid channel",
+			opts:    CampaignOptions{Channels: []string{"TV"}},
+			wantErr: "invalid campaign.advertising_channel_type value",
+		},
+		{
+			name:    "invalid since",
+			opts:    CampaignOptions{Since: "01/01/2026"},
+			wantErr: "expected YYYY-MM-DD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Campaigns(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+			if err := gaql.NewValidator().Validate(q); err != nil {
+				t.Errorf("generated query is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestAds(t *testing.T) {
+	const base = "SELECT campaign.name, ad_group.id, ad_group.name, ad_group_ad.ad.id, ad_group_ad.ad.type, ad_group_ad.status, ad_group_ad.ad.responsive_search_ad.headlines, ad_group_ad.ad.responsive_search_ad.descriptions, ad_group_ad.ad.responsive_search_ad.path1, ad_group_ad.ad.responsive_search_ad.path2, ad_group_ad.ad.final_urls, ad_group_ad.policy_summary.approval_status, ad_group_ad.policy_summary.review_status, ad_group_ad.policy_summary.policy_topic_entries FROM ad_group_ad WHERE ad_group_ad.status != 'REMOVED'"
+	const order = " ORDER BY campaign.name, ad_group.name, ad_group_ad.ad.id"
+	tests := []struct {
+		name string
+		opts AdOptions
+		want string
+	}{
+		{"every ad", AdOptions{}, base + order},
+		{"ad group", AdOptions{AdGroupID: 30001}, base + " AND ad_group.id = 30001" + order},
+		{
+			"campaign with policy issues",
+			AdOptions{CampaignID: 20001, PolicyIssuesOnly: true},
+			base + " AND campaign.id = 20001 AND ad_group_ad.policy_summary.approval_status IN ('APPROVED_LIMITED', 'AREA_OF_INTEREST_ONLY', 'DISAPPROVED')" + order,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := Ads(tt.opts)
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+			if err := gaql.NewValidator().Validate(q); err != nil {
+				t.Errorf("generated query is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestConversions(t *testing.T) {
+	const metrics = "metrics.conversions, metrics.conversions_value, metrics.value_per_conversion, metrics.all_conversions, metrics.all_conversions_value"
+	tests := []struct {
+		name    string
+		opts    ConversionOptions
+		want    string
+		wantErr string
+	}{
+		{
+			name: "by action",
+			opts: ConversionOptions{By: "action", Span: DateSpan{During: gaql.DateRangeLast30Days}},
+			want: "SELECT segments.conversion_action, segments.conversion_action_name, " + metrics + " FROM customer WHERE segments.date DURING LAST_30_DAYS ORDER BY metrics.conversions DESC",
+		},
+		{
+			name: "by campaign",
+			opts: ConversionOptions{By: "campaign", Span: DateSpan{Start: "2026-01-01", End: "2026-01-31"}},
+			want: "SELECT campaign.id, campaign.name, segments.conversion_action_name, " + metrics + " FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31' ORDER BY metrics.conversions DESC",
+		},
+		{
+			name: "by date",
+			opts: ConversionOptions{By: "date", Span: DateSpan{During: gaql.DateRangeLast7Days}},
+			want: "SELECT segments.date, segments.conversion_action_name, " + metrics + " FROM customer WHERE segments.date DURING LAST_7_DAYS ORDER BY segments.date, metrics.conversions DESC",
+		},
+		{
+			name:    "unknown grouping",
+			opts:    ConversionOptions{By: "device"},
+			wantErr: `unknown grouping "device"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Conversions(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+			if err := gaql.NewValidator().Validate(q); err != nil {
+				t.Errorf("generated query is invalid: %v", err)
+			}
+		})
+	}
+	if err := gaql.NewValidator().Validate(ConversionActions()); err != nil {
+		t.Errorf("conversion action query is invalid: %v", err)
+	}
+}
+
+func TestDailySeries(t *testing.T) {
+	tests := []struct {
+		name    string
+		metric  string
+		by      string
+		span    DateSpan
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "by campaign",
+			metric: "metrics.clicks",
+			by:     "campaign.id",
+			want:   "SELECT campaign.id, campaign.name, segments.date, metrics.clicks FROM campaign WHERE segments.date DURING LAST_30_DAYS ORDER BY segments.date",
+		},
+		{
+			name:   "whole account",
+			metric: "cost",
+			want:   "SELECT segments.date, metrics.cost_micros FROM customer WHERE segments.date DURING LAST_30_DAYS ORDER BY segments.date",
+		},
+		{
+			name:   "by segment",
+			metric: "impressions",
+			by:     "segments.device",
+			want:   "SELECT segments.device, segments.date, metrics.impressions FROM customer WHERE segments.date DURING LAST_30_DAYS ORDER BY segments.date",
+		},
+		{
+			name:   "between dates",
+			metric: "cost",
+			span:   DateSpan{Start: "2026-01-01", End: "2026-03-31"},
+			want:   "SELECT segments.date, metrics.cost_micros FROM customer WHERE segments.date BETWEEN '2026-01-01' AND '2026-03-31' ORDER BY segments.date",
+		},
+		{
+			name:    "by metric",
+			metric:  "clicks",
+			by:      "metrics.impressions",
+			wantErr: "cannot group by",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := tt.span
+			if span == (DateSpan{}) {
+				span.During = gaql.DateRangeLast30Days
+			}
+			q, err := DailySeries(tt.metric, tt.by, span)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+		})
+	}
+}
+
+func TestChanges(t *testing.T) {
+	const base = "SELECT change_event.change_date_time, change_event.change_resource_type, change_event.resource_change_operation, change_event.change_resource_name, change_event.user_email, change_event.client_type, change_event.changed_fields, change_event.old_resource, change_event.new_resource FROM change_event WHERE change_event.change_date_time >= '2026-01-01' AND change_event.change_date_time <= '2026-01-14 23:59:59'"
+	tests := []struct {
+		name    string
+		opts    ChangesOptions
+		want    string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			opts: ChangesOptions{Since: "2026-01-01", Until: "2026-01-14"},
+			want: base + " ORDER BY change_event.change_date_time DESC LIMIT 10000",
+		},
+		{
+			name: "resource types and limit",
+			opts: ChangesOptions{Since: "2026-01-01", Until: "2026-01-14", ResourceTypes: []string{"campaign", "CAMPAIGN_BUDGET"}, Limit: 50},
+			want: base + " AND change_event.change_resource_type IN ('CAMPAIGN', 'CAMPAIGN_BUDGET') ORDER BY change_event.change_date_time DESC LIMIT 50",
+		},
+		{
+			name: "thirty days",
+			opts: ChangesOptions{Since: "2026-01-01", Until: "2026-01-30"},
+			want: strings.Replace(base, "2026-01-14", "2026-01-30", 1) + " ORDER BY change_event.change_date_time DESC LIMIT 10000",
+		},
+		{
+			name:    "range too long",
+			opts:    ChangesOptions{Since: "2026-01-01", Until: "2026-01-31"},
+			wantErr: "at most 30 days at a time",
+		},
+		{
+			name:    "until before since",
+			opts:    ChangesOptions{Since: "2026-01-14", Until: "2026-01-01"},
+			wantErr: "is before",
+		},
+		{
+			name:    "invalid resource type",
+			opts:    ChangesOptions{Since: "2026-01-01", Until: "2026-01-14", ResourceTypes: []string{"KEYWORD"}},
+			wantErr: "invalid change_event.change_resource_type value",
+		},
+		{
+			name:    "limit too large",
+			opts:    ChangesOptions{Since: "2026-01-01", Until: "2026-01-14", Limit: 20000},
+			wantErr: "invalid limit",
+		},
+		{
+			name:    "invalid since",
+			opts:    ChangesOptions{Since: "2026/01/01", Until: "2026-01-14"},
+			wantErr: "expected YYYY-MM-DD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Changes(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+			if err := gaql.NewValidator().Validate(q); err != nil {
+				t.Errorf("generated query is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestSnapshotTables(t *testing.T) {
+	seen := map[string]bool{}
+	for _, tt := range SnapshotTables() {
+		if seen[tt.Name] {
+			t.Errorf("table %s listed twice", tt.Name)
+		}
+		seen[tt.Name] = true
+		q, err := gaql.ValidateQuery(tt.Query.String())
+		if err != nil {
+			t.Errorf("%s: %v", tt.Name, err)
+			continue
+		}
+		for _, f := range q.Select {
+			if strings.HasPrefix(f.Name, "metrics.") || strings.HasPrefix(f.Name, "segments.") {
+				t.Errorf("%s selects %s; snapshots are structure only", tt.Name, f.Name)
+			}
+		}
+	}
+}
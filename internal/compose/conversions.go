@@ -0,0 +1,83 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// ConversionBys are the groupings "adtap conversions --by" accepts.
+var ConversionBys = []string{"action", "category", "campaign", "date"}
+
+// ConversionMetrics are the metrics Conversions selects. Only
+// conversion metrics may be segmented by conversion action; see
+// gaql.ConversionActionMetrics.
+var ConversionMetrics = []string{
+	"metrics.conversions",
+	"metrics.conversions_value",
+	"metrics.value_per_conversion",
+	"metrics.all_conversions",
+	"metrics.all_conversions_value",
+}
+
+// ConversionOptions are the options of "adtap conversions".
+type ConversionOptions struct {
+	// By groups the conversions: by action, by action category, by
+	// campaign and action, or by day and action.
+	By string
+
+	// Span is the reporting period.
+	Span DateSpan
+}
+
+// Conversions builds the query behind "adtap conversions": conversion
+// counts and values segmented by conversion action, most conversions
+// first.
+func Conversions(opts ConversionOptions) (*gaql.Query, error) {
+	var b *gaql.Builder
+	switch strings.ToLower(opts.By) {
+	case "action":
+		b = gaql.Select(append([]string{"segments.conversion_action", "segments.conversion_action_name"}, ConversionMetrics...)...).
+			From("customer")
+	case "category":
+		b = gaql.Select(append([]string{"segments.conversion_action_category"}, ConversionMetrics...)...).
+			From("customer")
+	case "campaign":
+		b = gaql.Select(append([]string{"campaign.id", "campaign.name", "segments.conversion_action_name"}, ConversionMetrics...)...).
+			From("campaign")
+	case "date":
+		b = gaql.Select(append([]string{"segments.date", "segments.conversion_action_name"}, ConversionMetrics...)...).
+			From("customer")
+	default:
+		return nil, fmt.Errorf("compose: unknown grouping %q (expected %s)", opts.By, strings.Join(ConversionBys, ", "))
+	}
+	opts.Span.apply(b)
+	if strings.EqualFold(opts.By, "date") {
+		b.OrderBy("segments.date", gaql.Asc)
+	}
+	return b.OrderBy("metrics.conversions", gaql.Desc).Query(), nil
+}
+
+// ConversionActionFields are the conversion_action fields selected by
+// ConversionActions.
+var ConversionActionFields = []string{
+	"conversion_action.resource_name",
+	"conversion_action.name",
+	"conversion_action.type",
+	"conversion_action.category",
+	"conversion_action.status",
+	"conversion_action.counting_type",
+	"conversion_action.primary_for_goal",
+}
+
+// ConversionActions builds the query listing the account's conversion
+// actions that are not removed, so "adtap conversions --by action" can
+// show actions that recorded nothing in the period.
+func ConversionActions() *gaql.Query {
+	return gaql.Select(ConversionActionFields...).
+		From("conversion_action").
+		Where("conversion_action.status", gaql.OpNeq, gaql.StringValue("REMOVED")).
+		OrderBy("conversion_action.name", gaql.Asc).
+		Query()
+}
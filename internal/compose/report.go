@@ -0,0 +1,194 @@
+package compose
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Reports is the canned report library behind "adtap report": templates
+// answering the questions asked most often, so they can be run without
+// writing GAQL.
+var Reports = []*Template{
+	{
+		Name:        "campaign-overview",
+		Description: "Delivery, cost, and conversions per campaign, most expensive first",
+		Params: []Param{
+			customersParam,
+			dateRangeParam,
+			statusParam("campaign.status"),
+			{Name: "channel", Type: ParamEnum, Description: "Only campaigns of these channel types", Enum: enumValues("campaign.advertising_channel_type")},
+		},
+		build: func(args Args) (*gaql.Query, error) {
+			b := gaql.Select("campaign.id", "campaign.name", "campaign.status", "campaign.advertising_channel_type").
+				Select(standardMetrics...).
+				Select("metrics.ctr", "metrics.average_cpc", "metrics.conversions", "metrics.cost_per_conversion", "metrics.conversions_value").
+				From("campaign")
+			if err := whereEnum(b, "campaign.status", args["status"].([]string)); err != nil {
+				return nil, err
+			}
+			if channels, ok := args["channel"].([]string); ok {
+				if err := whereEnum(b, "campaign.advertising_channel_type", channels); err != nil {
+					return nil, err
+				}
+			}
+			args["date_range"].(DateSpan).apply(b)
+			return b.OrderBy("metrics.cost_micros", gaql.Desc).Query(), nil
+		},
+	},
+	{
+		Name:        "search-terms",
+		Description: "Search queries that triggered ads, with their cost and conversions",
+		Params: []Param{
+			customersParam,
+			dateRangeParam,
+			campaignIDParam,
+			{Name: "min_clicks", Type: ParamInt, Description: "Only search terms with at least this many clicks", Default: "1"},
+			limitParam("search terms", "200"),
+		},
+		build: func(args Args) (*gaql.Query, error) {
+			b := gaql.Select("campaign.name", "ad_group.name", "search_term_view.search_term", "search_term_view.status").
+				Select(standardMetrics...).
+				Select("metrics.conversions", "metrics.conversions_value").
+				From("search_term_view")
+			whereCampaign(b, args)
+			if n := args["min_clicks"].(int); n > 0 {
+				b.Where("metrics.clicks", gaql.OpGte, gaql.NumberValue(float64(n)))
+			}
+			args["date_range"].(DateSpan).apply(b)
+			return b.OrderBy("metrics.cost_micros", gaql.Desc).Limit(args["limit"].(int)).Query(), nil
+		},
+	},
+	{
+		Name:        "keyword-performance",
+		Description: "Keywords with quality score, cost, and conversions, most expensive first",
+		Params: []Param{
+			customersParam,
+			dateRangeParam,
+			statusParam("ad_group_criterion.status"),
+			campaignIDParam,
+			limitParam("keywords", "200"),
+		},
+		build: func(args Args) (*gaql.Query, error) {
+			b := gaql.Select("campaign.name", "ad_group.name",
+				"ad_group_criterion.keyword.text", "ad_group_criterion.keyword.match_type",
+				"ad_group_criterion.status", "ad_group_criterion.quality_info.quality_score").
+				Select(standardMetrics...).
+				Select("metrics.ctr", "metrics.average_cpc", "metrics.conversions", "metrics.cost_per_conversion").
+				From("keyword_view")
+			if err := whereEnum(b, "ad_group_criterion.status", args["status"].([]string)); err != nil {
+				return nil, err
+			}
+			whereCampaign(b, args)
+			args["date_range"].(DateSpan).apply(b)
+			return b.OrderBy("metrics.cost_micros", gaql.Desc).Limit(args["limit"].(int)).Query(), nil
+		},
+	},
+	{
+		Name:        "pmax-placements",
+		Description: "Where Performance Max campaigns showed ads, by impressions",
+		Params: []Param{
+			customersParam,
+			dateRangeParam,
+			campaignIDParam,
+			limitParam("placements", "200"),
+		},
+		build: func(args Args) (*gaql.Query, error) {
+			// The view reports impressions only.
+			b := gaql.Select("campaign.name", "performance_max_placement_view.display_name",
+				"performance_max_placement_view.placement", "performance_max_placement_view.placement_type",
+				"performance_max_placement_view.target_url", "metrics.impressions").
+				From("performance_max_placement_view")
+			whereCampaign(b, args)
+			args["date_range"].(DateSpan).apply(b)
+			return b.OrderBy("metrics.impressions", gaql.Desc).Limit(args["limit"].(int)).Query(), nil
+		},
+	},
+	{
+		Name:        "budget-pacing",
+		Description: "Spend against each campaign's budget; see 'adtap budgets' for projections",
+		Params: []Param{
+			customersParam,
+			{Name: "date_range", Type: ParamDateRange, Description: "Date range of the spend", Default: "THIS_MONTH"},
+		},
+		build: func(args Args) (*gaql.Query, error) {
+			b := gaql.Select("campaign_budget.name", "campaign_budget.period", "campaign_budget.amount_micros",
+				"campaign.id", "campaign.name", "campaign.status", "metrics.cost_micros").
+				From("campaign").
+				Where("campaign.status", gaql.OpNeq, gaql.StringValue("REMOVED"))
+			args["date_range"].(DateSpan).apply(b)
+			return b.OrderBy("metrics.cost_micros", gaql.Desc).Query(), nil
+		},
+	},
+}
+
+// LookupReport returns the canned report called name.
+func LookupReport(name string) (*Template, bool) {
+	for _, t := range Reports {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Parameters shared by the reports.
+var campaignIDParam = Param{Name: "campaign_id", Type: ParamInt, Description: "Only this campaign"}
+
+func limitParam(what, def string) Param {
+	return Param{Name: "limit", Type: ParamInt, Description: "Maximum number of " + what, Default: def}
+}
+
+func enumValues(field string) []string {
+	info, _ := gaql.DefaultCatalog().Field(field)
+	return info.EnumValues
+}
+
+// whereCampaign applies the campaign_id argument, when given.
+func whereCampaign(b *gaql.Builder, args Args) {
+	if id, ok := args["campaign_id"].(int); ok {
+		b.Where("campaign.id", gaql.OpEq, gaql.NumberValue(float64(id)))
+	}
+}
+
+// ParseLast parses a trailing period such as 30d or 4w: that many days
+// or weeks ending yesterday, relative to today. Periods matching a
+// DURING keyword, such as 7d and LAST_7_DAYS, use the keyword.
+func ParseLast(s string, today time.Time) (DateSpan, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	unit := 1
+	switch {
+	case strings.HasSuffix(s, "d"):
+	case strings.HasSuffix(s, "w"):
+		unit = 7
+	default:
+		return DateSpan{}, fmt.Errorf("compose: invalid period %q (expected days or weeks, e.g. 30d or 4w)", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 1 {
+		return DateSpan{}, fmt.Errorf("compose: invalid period %q (expected days or weeks, e.g. 30d or 4w)", s)
+	}
+	days := n * unit
+	keyword := fmt.Sprintf("LAST_%d_DAYS", days)
+	if keywords, _ := gaql.DateRangeKeywordsFor(gaql.DefaultAPIVersion); slices.Contains(keywords, keyword) {
+		dr, _ := gaql.LookupDateRange(keyword)
+		return DateSpan{During: dr}, nil
+	}
+	end := today.AddDate(0, 0, -1)
+	return DateSpan{
+		Start: end.AddDate(0, 0, 1-days).Format(time.DateOnly),
+		End:   end.Format(time.DateOnly),
+	}, nil
+}
+
+// String returns the span in the form ParseDateSpan reads.
+func (d DateSpan) String() string {
+	if d.Start != "" {
+		return d.Start + ".." + d.End
+	}
+	return d.During.String()
+}
@@ -0,0 +1,100 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestReports(t *testing.T) {
+	tests := []struct {
+		report string
+		raw    map[string]string
+		want   string
+	}{
+		{
+			report: "campaign-overview",
+			raw:    map[string]string{"customer_id": "1234567890", "channel": "search,shopping"},
+			want:   "SELECT campaign.id, campaign.name, campaign.status, campaign.advertising_channel_type, metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.ctr, metrics.average_cpc, metrics.conversions, metrics.cost_per_conversion, metrics.conversions_value FROM campaign WHERE campaign.status IN ('ENABLED', 'PAUSED') AND campaign.advertising_channel_type IN ('SEARCH', 'SHOPPING') AND segments.date DURING LAST_30_DAYS ORDER BY metrics.cost_micros DESC",
+		},
+		{
+			report: "search-terms",
+			raw:    map[string]string{"customer_id": "1234567890", "campaign_id": "111", "min_clicks": "5", "limit": "50"},
+			want:   "SELECT campaign.name, ad_group.name, search_term_view.search_term, search_term_view.status, metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.conversions, metrics.conversions_value FROM search_term_view WHERE campaign.id = 111 AND metrics.clicks >= 5 AND segments.date DURING LAST_30_DAYS ORDER BY metrics.cost_micros DESC LIMIT 50",
+		},
+		{
+			report: "keyword-performance",
+			raw:    map[string]string{"customer_id": "1234567890", "status": "ENABLED", "date_range": "2026-01-01..2026-01-31"},
+			want:   "SELECT campaign.name, ad_group.name, ad_group_criterion.keyword.text, ad_group_criterion.keyword.match_type, ad_group_criterion.status, ad_group_criterion.quality_info.quality_score, metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.ctr, metrics.average_cpc, metrics.conversions, metrics.cost_per_conversion FROM keyword_view WHERE ad_group_criterion.status = 'ENABLED' AND segments.date BETWEEN '2026-01-01' AND '2026-01-31' ORDER BY metrics.cost_micros DESC LIMIT 200",
+		},
+		{
+			report: "pmax-placements",
+			raw:    map[string]string{"customer_id": "1234567890", "date_range": "LAST_7_DAYS"},
+			want:   "SELECT campaign.name, performance_max_placement_view.display_name, performance_max_placement_view.placement, performance_max_placement_view.placement_type, performance_max_placement_view.target_url, metrics.impressions FROM performance_max_placement_view WHERE segments.date DURING LAST_7_DAYS ORDER BY metrics.impressions DESC LIMIT 200",
+		},
+		{
+			report: "budget-pacing",
+			raw:    map[string]string{"customer_id": "1234567890"},
+			want:   "SELECT campaign_budget.name, campaign_budget.period, campaign_budget.amount_micros, campaign.id, campaign.name, campaign.status, metrics.cost_micros FROM campaign WHERE campaign.status != 'REMOVED' AND segments.date DURING THIS_MONTH ORDER BY metrics.cost_micros DESC",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.report, func(t *testing.T) {
+			r, ok := LookupReport(tt.report)
+			if !ok {
+				t.Fatalf("report %s not found", tt.report)
+			}
+			args, err := r.Parse(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q, err := r.Query(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+			if err := gaql.NewValidator().Validate(q); err != nil {
+				t.Errorf("generated query is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseLast(t *testing.T) {
+	today := time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"30d", "LAST_30_DAYS", false},
+		{"1w", "LAST_7_DAYS", false},
+		{"2W", "LAST_14_DAYS", false},
+		{"3d", "2026-03-15..2026-03-17", false},
+		{"90d", "2025-12-18..2026-03-17", false},
+		{"0d", "", true},
+		{"30", "", true},
+		{"1m", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLast(tt.in, today)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid period") {
+					t.Fatalf("ParseLast(%q) error = %v, want invalid period", tt.in, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseLast(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// DailySeries builds a query for the daily values of metric, one series
+// per value of by, over a span of dates. by is a field such as campaign.id;
+// its resource is queried. An empty by gives one series for the whole
+// account. When by is an ID whose resource has a name, the name is
+// selected too.
+func DailySeries(metric, by string, span DateSpan) (*gaql.Query, error) {
+	field, err := MetricField(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := "customer"
+	var fields []string
+	if by != "" {
+		info, ok := gaql.DefaultCatalog().Field(by)
+		if !ok || info.Category == "METRIC" || info.Category == "RESOURCE" {
+			return nil, fmt.Errorf("compose: cannot group by %q (expected an attribute or segment field)", by)
+		}
+		fields = append(fields, by)
+		if prefix, attr, _ := strings.Cut(by, "."); info.Category == "ATTRIBUTE" {
+			resource = prefix
+			if attr == "id" {
+				if _, ok := gaql.DefaultCatalog().Field(prefix + ".name"); ok {
+					fields = append(fields, prefix+".name")
+				}
+			}
+		}
+	}
+
+	b := gaql.Select(fields...).
+		Select("segments.date", field).
+		From(resource)
+	span.apply(b)
+	q := b.OrderBy("segments.date", gaql.Asc).Query()
+	if err := gaql.NewValidator().Validate(q); err != nil {
+		return nil, fmt.Errorf("compose: %w", err)
+	}
+	return q, nil
+}
@@ -0,0 +1,74 @@
+package compose
+
+import "github.com/aygp-dr/adtap/internal/gaql"
+
+// SnapshotTable is one structural query of "adtap snapshot", written to
+// the file Name.jsonl.
+type SnapshotTable struct {
+	Name  string
+	Query *gaql.Query
+}
+
+// SnapshotTables builds the queries of "adtap snapshot": the settings of
+// the account and of each campaign, ad group, ad, keyword, budget, and
+// conversion action that is not removed, without metrics. Rows are
+// ordered by ID so two snapshots of an unchanged account are identical.
+func SnapshotTables() []SnapshotTable {
+	removed := gaql.StringValue("REMOVED")
+	return []SnapshotTable{
+		{"customer", gaql.Select(
+			"customer.id", "customer.descriptive_name", "customer.currency_code", "customer.time_zone",
+			"customer.status", "customer.manager", "customer.test_account", "customer.auto_tagging_enabled").
+			From("customer").
+			Query()},
+		{"campaign_budgets", gaql.Select(
+			"campaign_budget.id", "campaign_budget.name", "campaign_budget.status", "campaign_budget.amount_micros",
+			"campaign_budget.period", "campaign_budget.delivery_method", "campaign_budget.explicitly_shared",
+			"campaign_budget.reference_count").
+			From("campaign_budget").
+			Where("campaign_budget.status", gaql.OpNeq, removed).
+			OrderBy("campaign_budget.id", gaql.Asc).
+			Query()},
+		{"campaigns", gaql.Select(
+			"campaign.id", "campaign.name", "campaign.status", "campaign.serving_status",
+			"campaign.advertising_channel_type", "campaign.bidding_strategy_type",
+			"campaign.start_date", "campaign.end_date", "campaign.campaign_budget").
+			From("campaign").
+			Where("campaign.status", gaql.OpNeq, removed).
+			OrderBy("campaign.id", gaql.Asc).
+			Query()},
+		{"ad_groups", gaql.Select(
+			"ad_group.id", "ad_group.name", "ad_group.status", "ad_group.type",
+			"ad_group.cpc_bid_micros", "ad_group.campaign").
+			From("ad_group").
+			Where("ad_group.status", gaql.OpNeq, removed).
+			OrderBy("ad_group.id", gaql.Asc).
+			Query()},
+		{"ads", gaql.Select(
+			"ad_group_ad.ad.id", "ad_group_ad.ad.type", "ad_group_ad.status", "ad_group_ad.ad_group",
+			"ad_group_ad.ad.final_urls", "ad_group_ad.ad.responsive_search_ad.headlines",
+			"ad_group_ad.ad.responsive_search_ad.descriptions", "ad_group_ad.ad.responsive_search_ad.path1",
+			"ad_group_ad.ad.responsive_search_ad.path2", "ad_group_ad.policy_summary.approval_status").
+			From("ad_group_ad").
+			Where("ad_group_ad.status", gaql.OpNeq, removed).
+			OrderBy("ad_group_ad.ad.id", gaql.Asc).
+			Query()},
+		{"keywords", gaql.Select(
+			"ad_group_criterion.criterion_id", "ad_group_criterion.ad_group", "ad_group_criterion.status",
+			"ad_group_criterion.negative", "ad_group_criterion.keyword.text",
+			"ad_group_criterion.keyword.match_type", "ad_group_criterion.cpc_bid_micros").
+			From("ad_group_criterion").
+			Where("ad_group_criterion.type", gaql.OpEq, gaql.StringValue("KEYWORD")).
+			Where("ad_group_criterion.status", gaql.OpNeq, removed).
+			OrderBy("ad_group_criterion.criterion_id", gaql.Asc).
+			Query()},
+		{"conversion_actions", gaql.Select(
+			"conversion_action.id", "conversion_action.name", "conversion_action.type",
+			"conversion_action.category", "conversion_action.status", "conversion_action.counting_type",
+			"conversion_action.primary_for_goal").
+			From("conversion_action").
+			Where("conversion_action.status", gaql.OpNeq, removed).
+			OrderBy("conversion_action.id", gaql.Asc).
+			Query()},
+	}
+}
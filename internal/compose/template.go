@@ -0,0 +1,421 @@
+package compose
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// ParamType is the type of a template parameter.
+type ParamType string
+
+// Parameter types. Every type has a text form, used for flags and
+// defaults; list types take comma-separated items.
+const (
+	ParamString    ParamType = "string"
+	ParamInt       ParamType = "int"
+	ParamDate      ParamType = "date"       // YYYY-MM-DD
+	ParamDateRange ParamType = "date_range" // DURING keyword or START..END
+	ParamCustomers ParamType = "customers"  // customer IDs
+	ParamEnum      ParamType = "enum"       // values from Param.Enum
+)
+
+// Param declares a template parameter.
+type Param struct {
+	Name        string
+	Type        ParamType
+	Description string
+
+	// Default is the text form of the value used when the parameter is
+	// not given. Empty means the parameter is omitted.
+	Default string
+
+	// Required parameters must be given; they have no default.
+	Required bool
+
+	// Enum lists the values a ParamEnum parameter accepts.
+	Enum []string
+}
+
+// FlagName returns the command-line flag of the parameter: its name with
+// underscores replaced by hyphens.
+func (p Param) FlagName() string {
+	return strings.ReplaceAll(p.Name, "_", "-")
+}
+
+// DateSpan is the value of a date_range parameter: a DURING keyword, or
+// the inclusive dates Start..End when Start is set.
+type DateSpan struct {
+	During     gaql.DateRange
+	Start, End string
+}
+
+// ParseDateSpan parses a DURING keyword such as LAST_30_DAYS, or a range
+// of dates such as 2026-01-01..2026-01-31.
+func ParseDateSpan(s string) (DateSpan, error) {
+	start, end, ok := strings.Cut(s, "..")
+	if !ok {
+		dr, err := ParseDuring(s)
+		return DateSpan{During: dr}, err
+	}
+	for _, d := range []string{start, end} {
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			return DateSpan{}, fmt.Errorf("compose: invalid date %q (expected YYYY-MM-DD)", d)
+		}
+	}
+	if end < start {
+		return DateSpan{}, fmt.Errorf("compose: date range %s ends before it starts", s)
+	}
+	return DateSpan{Start: start, End: end}, nil
+}
+
+// apply restricts segments.date to the span.
+func (d DateSpan) apply(b *gaql.Builder) {
+	if d.Start != "" {
+		b.Between(d.Start, d.End)
+	} else {
+		b.During(d.During)
+	}
+}
+
+// Args are parsed template arguments keyed by parameter name. Values are
+// string for string and date parameters, int, DateSpan, or []string for
+// customers and enum parameters. Omitted optional parameters are absent.
+type Args map[string]any
+
+// Template is a named query with declared, typed parameters. The
+// declarations drive both command-line flags (Flags) and the JSON Schema
+// offered to MCP clients (Schema).
+type Template struct {
+	Name        string
+	Description string
+	Params      []Param
+
+	build func(Args) (*gaql.Query, error)
+}
+
+// Param returns the parameter called name.
+func (t *Template) Param(name string) (Param, bool) {
+	for _, p := range t.Params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+// Parse converts text arguments to typed ones, filling in defaults.
+// Unknown parameters, missing required ones, and malformed values are
+// errors.
+func (t *Template) Parse(raw map[string]string) (Args, error) {
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := t.Param(name); !ok {
+			return nil, fmt.Errorf("compose: template %s has no parameter %q", t.Name, name)
+		}
+	}
+
+	args := make(Args)
+	for _, p := range t.Params {
+		s := strings.TrimSpace(raw[p.Name])
+		if s == "" {
+			s = p.Default
+		}
+		if s == "" {
+			if p.Required {
+				return nil, fmt.Errorf("compose: template %s requires parameter %s", t.Name, p.Name)
+			}
+			continue
+		}
+		v, err := p.parse(s)
+		if err != nil {
+			return nil, err
+		}
+		args[p.Name] = v
+	}
+	return args, nil
+}
+
+func (p Param) parse(s string) (any, error) {
+	switch p.Type {
+	case ParamString:
+		return s, nil
+	case ParamInt:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("compose: invalid %s %q (expected an integer)", p.Name, s)
+		}
+		return n, nil
+	case ParamDate:
+		if _, err := time.Parse(time.DateOnly, s); err != nil {
+			return nil, fmt.Errorf("compose: invalid %s %q (expected YYYY-MM-DD)", p.Name, s)
+		}
+		return s, nil
+	case ParamDateRange:
+		return ParseDateSpan(s)
+	case ParamCustomers:
+		var ids []string
+		for _, item := range splitItems(s) {
+			id, err := adsapi.NormalizeCustomerID(item)
+			if err != nil {
+				return nil, fmt.Errorf("compose: invalid %s %q (expected 10 digits, e.g. 1234567890)", p.Name, item)
+			}
+			ids = append(ids, id)
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("compose: %s needs at least one customer ID", p.Name)
+		}
+		return ids, nil
+	case ParamEnum:
+		var values []string
+		for _, item := range splitItems(s) {
+			v := strings.ToUpper(item)
+			if !contains(p.Enum, v) {
+				return nil, fmt.Errorf("compose: invalid %s value %q (expected one of %s)", p.Name, item, strings.Join(p.Enum, ", "))
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("compose: parameter %s has unknown type %q", p.Name, p.Type)
+}
+
+// splitItems splits a comma-separated list, dropping empty items.
+func splitItems(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// ParseJSON parses arguments given as a JSON object, as an MCP client
+// sends them: list parameters as arrays of strings, int parameters as
+// numbers, and everything else as strings.
+func (t *Template) ParseJSON(data json.RawMessage) (Args, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("compose: invalid arguments: %w", err)
+	}
+	raw := make(map[string]string, len(fields))
+	for name, v := range fields {
+		var s string
+		var n json.Number
+		var list []string
+		switch {
+		case json.Unmarshal(v, &s) == nil:
+			raw[name] = s
+		case json.Unmarshal(v, &n) == nil:
+			raw[name] = n.String()
+		case json.Unmarshal(v, &list) == nil:
+			raw[name] = strings.Join(list, ",")
+		default:
+			return nil, fmt.Errorf("compose: invalid %s %s (expected a string, number, or array of strings)", name, v)
+		}
+	}
+	return t.Parse(raw)
+}
+
+// Flags defines one string flag per parameter on fs and returns a
+// function that parses their values once fs has been parsed.
+func (t *Template) Flags(fs *flag.FlagSet) func() (Args, error) {
+	values := make(map[string]*string, len(t.Params))
+	for _, p := range t.Params {
+		usage := p.Description
+		switch p.Type {
+		case ParamDateRange:
+			usage += " (a DURING keyword such as LAST_7_DAYS, or START..END)"
+		case ParamCustomers:
+			usage += " (comma-separated)"
+		case ParamEnum:
+			usage += " (comma-separated: " + strings.Join(p.Enum, ", ") + ")"
+		}
+		if p.Required {
+			usage += "; required"
+		}
+		values[p.Name] = fs.String(p.FlagName(), p.Default, usage)
+	}
+	return func() (Args, error) {
+		raw := make(map[string]string, len(values))
+		for name, v := range values {
+			raw[name] = *v
+		}
+		return t.Parse(raw)
+	}
+}
+
+// Schema returns the JSON Schema of the template's arguments object.
+func (t *Template) Schema() map[string]any {
+	props := make(map[string]any, len(t.Params))
+	var required []string
+	for _, p := range t.Params {
+		prop := map[string]any{"description": p.Description}
+		switch p.Type {
+		case ParamInt:
+			prop["type"] = "integer"
+		case ParamDate:
+			prop["type"] = "string"
+			prop["format"] = "date"
+		case ParamDateRange:
+			prop["type"] = "string"
+			prop["description"] = p.Description + ": a DURING keyword such as LAST_7_DAYS, or START..END with dates in YYYY-MM-DD form"
+		case ParamCustomers:
+			prop["type"] = "array"
+			prop["items"] = map[string]any{"type": "string", "pattern": "^[0-9]{10}$"}
+			prop["minItems"] = 1
+		case ParamEnum:
+			prop["type"] = "array"
+			prop["items"] = map[string]any{"type": "string", "enum": p.Enum}
+		default:
+			prop["type"] = "string"
+		}
+		if p.Default != "" {
+			switch p.Type {
+			case ParamInt:
+				n, _ := strconv.Atoi(p.Default)
+				prop["default"] = n
+			case ParamCustomers, ParamEnum:
+				prop["default"] = splitItems(p.Default)
+			default:
+				prop["default"] = p.Default
+			}
+		}
+		if p.Required {
+			required = append(required, p.Name)
+		}
+		props[p.Name] = prop
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Query builds the template's query from parsed arguments.
+func (t *Template) Query(args Args) (*gaql.Query, error) {
+	return t.build(args)
+}
+
+// Customers returns the customer IDs given for the template's customers
+// parameter, which say where the query runs rather than what it selects.
+func (t *Template) Customers(args Args) []string {
+	for _, p := range t.Params {
+		if p.Type == ParamCustomers {
+			ids, _ := args[p.Name].([]string)
+			return ids
+		}
+	}
+	return nil
+}
+
+// Templates is the built-in template library.
+var Templates = []*Template{
+	{
+		Name:        "campaign-performance",
+		Description: "Impressions, clicks, cost, and conversions per campaign, most expensive first",
+		Params: []Param{
+			customersParam,
+			dateRangeParam,
+			statusParam("campaign.status"),
+		},
+		build: func(args Args) (*gaql.Query, error) {
+			b := gaql.Select("campaign.id", "campaign.name", "campaign.status").
+				Select(standardMetrics...).
+				Select("metrics.conversions").
+				From("campaign")
+			if err := whereEnum(b, "campaign.status", args["status"].([]string)); err != nil {
+				return nil, err
+			}
+			args["date_range"].(DateSpan).apply(b)
+			return b.OrderBy("metrics.cost_micros", gaql.Desc).Query(), nil
+		},
+	},
+	{
+		Name:        "ad-group-performance",
+		Description: "Impressions, clicks, cost, and conversions per ad group, most expensive first",
+		Params: []Param{
+			customersParam,
+			dateRangeParam,
+			statusParam("ad_group.status"),
+			{Name: "campaign_id", Type: ParamInt, Description: "Only ad groups of this campaign"},
+			{Name: "limit", Type: ParamInt, Description: "Maximum number of ad groups", Default: "100"},
+		},
+		build: func(args Args) (*gaql.Query, error) {
+			b := gaql.Select("campaign.id", "campaign.name", "ad_group.id", "ad_group.name", "ad_group.status").
+				Select(standardMetrics...).
+				Select("metrics.conversions").
+				From("ad_group")
+			if err := whereEnum(b, "ad_group.status", args["status"].([]string)); err != nil {
+				return nil, err
+			}
+			if id, ok := args["campaign_id"].(int); ok {
+				b.Where("campaign.id", gaql.OpEq, gaql.NumberValue(float64(id)))
+			}
+			args["date_range"].(DateSpan).apply(b)
+			return b.OrderBy("metrics.cost_micros", gaql.Desc).Limit(args["limit"].(int)).Query(), nil
+		},
+	},
+	{
+		Name:        "daily-spend",
+		Description: "Account-wide impressions, clicks, and cost per day",
+		Params: []Param{
+			customersParam,
+			dateRangeParam,
+		},
+		build: func(args Args) (*gaql.Query, error) {
+			b := gaql.Select("segments.date").
+				Select(standardMetrics...).
+				From("customer")
+			args["date_range"].(DateSpan).apply(b)
+			return b.OrderBy("segments.date", gaql.Asc).Query(), nil
+		},
+	},
+}
+
+// Parameters shared by the built-in templates.
+var (
+	customersParam = Param{Name: "customer_id", Type: ParamCustomers, Description: "Customer IDs to query", Required: true}
+	dateRangeParam = Param{Name: "date_range", Type: ParamDateRange, Description: "Date range of the metrics", Default: "LAST_30_DAYS"}
+)
+
+// statusParam declares a status filter on field, an enum attribute,
+// keeping enabled and paused entities by default.
+func statusParam(field string) Param {
+	info, _ := gaql.DefaultCatalog().Field(field)
+	return Param{
+		Name:        "status",
+		Type:        ParamEnum,
+		Description: "Statuses to keep",
+		Default:     "ENABLED,PAUSED",
+		Enum:        info.EnumValues,
+	}
+}
+
+// LookupTemplate returns the built-in template called name.
+func LookupTemplate(name string) (*Template, bool) {
+	for _, t := range Templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
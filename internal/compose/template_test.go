@@ -0,0 +1,147 @@
+package compose
+
+import (
+	"encoding/json"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		template string
+		raw      map[string]string
+		want     string
+		wantErr  string
+	}{
+		{
+			template: "campaign-performance",
+			raw:      map[string]string{"customer_id": "123-456-7890"},
+			want:     "SELECT campaign.id, campaign.name, campaign.status, metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.conversions FROM campaign WHERE campaign.status IN ('ENABLED', 'PAUSED') AND segments.date DURING LAST_30_DAYS ORDER BY metrics.cost_micros DESC",
+		},
+		{
+			template: "ad-group-performance",
+			raw:      map[string]string{"customer_id": "1234567890", "date_range": "2026-01-01..2026-01-31", "status": "enabled", "campaign_id": "111", "limit": "5"},
+			want:     "SELECT campaign.id, campaign.name, ad_group.id, ad_group.name, ad_group.status, metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.conversions FROM ad_group WHERE ad_group.status = 'ENABLED' AND campaign.id = 111 AND segments.date BETWEEN '2026-01-01' AND '2026-01-31' ORDER BY metrics.cost_micros DESC LIMIT 5",
+		},
+		{
+			template: "daily-spend",
+			raw:      map[string]string{"customer_id": "1234567890", "date_range": "last_7_days"},
+			want:     "SELECT segments.date, metrics.impressions, metrics.clicks, metrics.cost_micros FROM customer WHERE segments.date DURING LAST_7_DAYS ORDER BY segments.date",
+		},
+		{
+			template: "daily-spend",
+			raw:      map[string]string{},
+			wantErr:  "requires parameter customer_id",
+		},
+		{
+			template: "daily-spend",
+			raw:      map[string]string{"customer_id": "1234567890", "status": "ENABLED"},
+			wantErr:  `has no parameter "status"`,
+		},
+		{
+			template: "campaign-performance",
+			raw:      map[string]string{"customer_id": "1234567890", "status": "ACTIVE"},
+			wantErr:  `invalid status value "ACTIVE"`,
+		},
+		{
+			template: "ad-group-performance",
+			raw:      map[string]string{"customer_id": "1234567890", "limit": "ten"},
+			wantErr:  `invalid limit "ten"`,
+		},
+		{
+			template: "daily-spend",
+			raw:      map[string]string{"customer_id": "1234567890", "date_range": "2026-02-01..2026-01-01"},
+			wantErr:  "ends before it starts",
+		},
+		{
+			template: "daily-spend",
+			raw:      map[string]string{"customer_id": "12345"},
+			wantErr:  `invalid customer_id "12345"`,
+		},
+		{
+			template: "daily-spend",
+			raw:      map[string]string{"customer_id": " , "},
+			wantErr:  "customer_id needs at least one customer ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, ok := LookupTemplate(tt.template)
+			if !ok {
+				t.Fatalf("template %s not found", tt.template)
+			}
+			args, err := tmpl.Parse(tt.raw)
+			var q *gaql.Query
+			if err == nil {
+				q, err = tmpl.Query(args)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+			if err := gaql.NewValidator().Validate(q); err != nil {
+				t.Errorf("generated query is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestTemplateFlags(t *testing.T) {
+	tmpl, _ := LookupTemplate("ad-group-performance")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	parse := tmpl.Flags(fs)
+	if err := fs.Parse([]string{"--customer-id", "1234567890,2345678901", "--campaign-id", "42"}); err != nil {
+		t.Fatal(err)
+	}
+	args, err := parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := tmpl.Customers(args); !reflect.DeepEqual(got, []string{"1234567890", "2345678901"}) {
+		t.Errorf("customers = %v", got)
+	}
+	if args["campaign_id"] != 42 || args["limit"] != 100 {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestTemplateSchema(t *testing.T) {
+	tmpl, _ := LookupTemplate("campaign-performance")
+	schema := tmpl.Schema()
+	if !reflect.DeepEqual(schema["required"], []string{"customer_id"}) {
+		t.Errorf("required = %v", schema["required"])
+	}
+	status := schema["properties"].(map[string]any)["status"].(map[string]any)
+	if status["type"] != "array" || !reflect.DeepEqual(status["default"], []string{"ENABLED", "PAUSED"}) {
+		t.Errorf("unexpected status schema %v", status)
+	}
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("schema does not encode: %v", err)
+	}
+
+	args, err := tmpl.ParseJSON(json.RawMessage(`{"customer_id": ["1234567890"], "status": ["REMOVED"], "date_range": "YESTERDAY"}`))
+	if err != nil {
+		t.Fatalf("ParseJSON: %v", err)
+	}
+	want := Args{
+		"customer_id": []string{"1234567890"},
+		"status":      []string{"REMOVED"},
+		"date_range":  DateSpan{During: gaql.DateRangeYesterday},
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("got %#v, want %#v", args, want)
+	}
+}
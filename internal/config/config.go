@@ -0,0 +1,551 @@
+// Package config reads and writes the adtap configuration file.
+//
+// The file, config.toml in the adtap directory of the user config
+// directory (~/.config/adtap on Linux, %AppData%\adtap on Windows) by
+// default, holds named profiles
+// so one person can switch between manager accounts without juggling
+// environment variables:
+//
+//	default_profile = "agency"
+//
+//	[profiles.agency]
+//	developer_token = "..."
+//	login_customer_id = "1234567890"
+//	customer_id = "2345678901"
+//	api_version = "v23"
+//	format = "csv"
+//	secrets = "vault:secret/adtap"
+//
+// Instead of a fixed login_customer_id, managers may list manager
+// accounts; each customer queried is then reached through the manager
+// above it. linked_customer_id reads accounts through an app analytics
+// provider link.
+//
+// The secrets setting names where the developer token and OAuth2
+// credentials are kept instead of the file or the environment; see
+// package secrets.
+//
+// Views bundle a saved query with its output settings, so a recurring
+// report is one command (adtap view run weekly_spend):
+//
+//	[views.weekly_spend]
+//	description = "Spend by campaign for the last week"
+//	customer_id = "1234567890"
+//	query_file = "queries/weekly_spend.gaql"
+//	param.start = "2026-01-01"
+//	format = "csv"
+//	columns = "campaign.name=Campaign, metrics.cost_micros=Cost"
+//	micros_to_currency = true
+//	output = "weekly_spend.csv"
+//
+// Only the subset of TOML the file needs is supported: comments, tables,
+// and string, integer, and boolean values.
+//
+// # Basic Usage
+//
+//	cfg, err := config.Load(config.DefaultPath())
+//	p, err := cfg.Profile("")           // the default profile
+//	err = cfg.Set("agency", "format", "json")
+//	err = cfg.Save(config.DefaultPath())
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/textenc"
+)
+
+// Keys lists the settings a profile holds, in file order.
+var Keys = []string{"developer_token", "login_customer_id", "linked_customer_id", "managers", "customer_id", "api_version", "format", "secrets"}
+
+// Profile is a named set of settings.
+type Profile struct {
+	DeveloperToken   string
+	LoginCustomerID  string
+	LinkedCustomerID string
+	Managers         string // comma-separated managers whose children get them as login customer
+	CustomerID       string // default customer for commands that take --customer-id
+	APIVersion       string
+	Format           string // default output format
+	Secrets          string // secrets provider, as accepted by secrets.Open
+}
+
+// field returns a pointer to the setting called key.
+func (p *Profile) field(key string) (*string, bool) {
+	switch key {
+	case "developer_token":
+		return &p.DeveloperToken, true
+	case "login_customer_id":
+		return &p.LoginCustomerID, true
+	case "linked_customer_id":
+		return &p.LinkedCustomerID, true
+	case "managers":
+		return &p.Managers, true
+	case "customer_id":
+		return &p.CustomerID, true
+	case "api_version":
+		return &p.APIVersion, true
+	case "format":
+		return &p.Format, true
+	case "secrets":
+		return &p.Secrets, true
+	}
+	return nil, false
+}
+
+// Get returns the setting called key.
+func (p *Profile) Get(key string) (string, error) {
+	f, ok := p.field(key)
+	if !ok {
+		return "", unknownKey(key)
+	}
+	return *f, nil
+}
+
+// ViewKeys lists the settings a view holds, in file order. Parameters
+// of the query or template are param.NAME keys.
+var ViewKeys = []string{
+	"description", "profile", "customer_id",
+	"query", "query_file", "template",
+	"format", "enums", "micros_to_currency", "columns",
+	"output", "to_bigquery", "to_sqlite", "table", "yes",
+}
+
+// View is a saved query with the settings to run it: where it comes
+// from (exactly one of Query, QueryFile, and Template), the account,
+// the output format and column names, and where rows go.
+type View struct {
+	Description string
+	Profile     string // profile to run with, unless --profile is given
+	CustomerID  string
+	Query       string
+	QueryFile   string // a file of GAQL, relative to the working directory
+	Template    string // a built-in template, as in adtap template run
+	Params      map[string]string
+
+	Format           string
+	Enums            string
+	MicrosToCurrency string // "true" or "false"
+	Columns          string // FIELD=NAME pairs separated by commas
+
+	Output     string // a file, or empty for stdout
+	ToBigQuery string
+	ToSQLite   string
+	Table      string // table name for ToSQLite
+	Yes        string // "true" runs expensive queries without asking
+}
+
+func (v *View) field(key string) (*string, bool) {
+	switch key {
+	case "description":
+		return &v.Description, true
+	case "profile":
+		return &v.Profile, true
+	case "customer_id":
+		return &v.CustomerID, true
+	case "query":
+		return &v.Query, true
+	case "query_file":
+		return &v.QueryFile, true
+	case "template":
+		return &v.Template, true
+	case "format":
+		return &v.Format, true
+	case "enums":
+		return &v.Enums, true
+	case "micros_to_currency":
+		return &v.MicrosToCurrency, true
+	case "columns":
+		return &v.Columns, true
+	case "output":
+		return &v.Output, true
+	case "to_bigquery":
+		return &v.ToBigQuery, true
+	case "to_sqlite":
+		return &v.ToSQLite, true
+	case "table":
+		return &v.Table, true
+	case "yes":
+		return &v.Yes, true
+	}
+	return nil, false
+}
+
+// Get returns the setting called key, including param.NAME keys.
+func (v *View) Get(key string) (string, error) {
+	if name, ok := strings.CutPrefix(key, "param."); ok && name != "" {
+		return v.Params[name], nil
+	}
+	f, ok := v.field(key)
+	if !ok {
+		return "", unknownViewKey(key)
+	}
+	return *f, nil
+}
+
+func (v *View) set(key, value string) error {
+	if name, ok := strings.CutPrefix(key, "param."); ok && name != "" {
+		if v.Params == nil {
+			v.Params = map[string]string{}
+		}
+		v.Params[name] = value
+		return nil
+	}
+	f, ok := v.field(key)
+	if !ok {
+		return unknownViewKey(key)
+	}
+	*f = value
+	return nil
+}
+
+// Source returns the setting the query comes from, query, query_file,
+// or template, checking that exactly one is set.
+func (v *View) Source() (string, error) {
+	var set []string
+	for _, key := range []string{"query", "query_file", "template"} {
+		if value, _ := v.Get(key); value != "" {
+			set = append(set, key)
+		}
+	}
+	if len(set) != 1 {
+		return "", fmt.Errorf("config: a view needs exactly one of query, query_file, and template (has %d)", len(set))
+	}
+	return set[0], nil
+}
+
+// ParamNames returns the names of the view's parameters, sorted.
+func (v *View) ParamNames() []string {
+	names := make([]string, 0, len(v.Params))
+	for name := range v.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Config is the content of the configuration file.
+type Config struct {
+	// DefaultProfile is used when no profile is named.
+	DefaultProfile string
+	Profiles       map[string]*Profile
+	Views          map[string]*View
+}
+
+// DefaultPath returns the file named by ADTAP_CONFIG, or config.toml in
+// the adtap directory of the user config directory.
+func DefaultPath() string {
+	if path := os.Getenv("ADTAP_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join(".adtap", "config.toml")
+	}
+	return filepath.Join(dir, "adtap", "config.toml")
+}
+
+// Load reads the configuration file at path. A missing file is an empty
+// configuration.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{Profiles: map[string]*Profile{}, Views: map[string]*View{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+	cfg, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w (in %s)", err, path)
+	}
+	return cfg, nil
+}
+
+// Parse reads a configuration from r. Files saved as UTF-16 or with a
+// byte order mark, as Windows editors may, are accepted.
+func Parse(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	cfg := &Config{Profiles: map[string]*Profile{}, Views: map[string]*View{}}
+	var (
+		profile *Profile
+		view    *View
+	)
+	sc := bufio.NewScanner(bytes.NewReader(textenc.Decode(data)))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(sc.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("config: line %d: unterminated table header", n)
+			}
+			table := strings.TrimSpace(line[1 : len(line)-1])
+			profile, view = nil, nil
+			if name, ok := strings.CutPrefix(table, "profiles."); ok && validName(name) {
+				if cfg.Profiles[name] == nil {
+					cfg.Profiles[name] = &Profile{}
+				}
+				profile = cfg.Profiles[name]
+				continue
+			}
+			if name, ok := strings.CutPrefix(table, "views."); ok && validName(name) {
+				if cfg.Views[name] == nil {
+					cfg.Views[name] = &View{}
+				}
+				view = cfg.Views[name]
+				continue
+			}
+			return nil, fmt.Errorf("config: line %d: unknown table [%s] (expected [profiles.NAME] or [views.NAME])", n, table)
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("config: line %d: expected key = value", n)
+		}
+		key = strings.TrimSpace(key)
+		value, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("config: line %d: %v", n, err)
+		}
+		if view != nil {
+			if err := view.set(key, value); err != nil {
+				return nil, fmt.Errorf("config: line %d: %v", n, err)
+			}
+			continue
+		}
+		if profile == nil {
+			if key != "default_profile" {
+				return nil, fmt.Errorf("config: line %d: unknown key %q (expected default_profile)", n, key)
+			}
+			cfg.DefaultProfile = value
+			continue
+		}
+		f, ok := profile.field(key)
+		if !ok {
+			return nil, fmt.Errorf("config: line %d: %v", n, unknownKey(key))
+		}
+		*f = value
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return cfg, nil
+}
+
+// stripComment removes a # comment that is not inside a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseValue decodes a string, integer, or boolean value to its text.
+func parseValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return s, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw == "true" || raw == "false":
+		return raw, nil
+	}
+	if _, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64); err == nil {
+		return strings.ReplaceAll(raw, "_", ""), nil
+	}
+	return "", fmt.Errorf("invalid value %q (quote strings)", raw)
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func unknownKey(key string) error {
+	return fmt.Errorf("config: unknown key %q (expected one of %s)", key, strings.Join(Keys, ", "))
+}
+
+func unknownViewKey(key string) error {
+	return fmt.Errorf("config: unknown view key %q (expected one of %s, or param.NAME)", key, strings.Join(ViewKeys, ", "))
+}
+
+// WriteTo writes the configuration in TOML, profiles and then views
+// sorted by name.
+func (c *Config) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	if c.DefaultProfile != "" {
+		fmt.Fprintf(&buf, "default_profile = %s\n", strconv.Quote(c.DefaultProfile))
+	}
+	for _, name := range c.ProfileNames() {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "[profiles.%s]\n", name)
+		p := c.Profiles[name]
+		for _, key := range Keys {
+			if v, _ := p.Get(key); v != "" {
+				fmt.Fprintf(&buf, "%s = %s\n", key, strconv.Quote(v))
+			}
+		}
+	}
+	for _, name := range c.ViewNames() {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "[views.%s]\n", name)
+		v := c.Views[name]
+		for _, key := range ViewKeys {
+			value, _ := v.Get(key)
+			switch {
+			case value == "":
+			case value == "true" || value == "false":
+				fmt.Fprintf(&buf, "%s = %s\n", key, value)
+			default:
+				fmt.Fprintf(&buf, "%s = %s\n", key, strconv.Quote(value))
+			}
+		}
+		for _, param := range v.ParamNames() {
+			fmt.Fprintf(&buf, "param.%s = %s\n", param, strconv.Quote(v.Params[param]))
+		}
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// Save writes the configuration to path, readable only by its owner
+// since profiles may hold developer tokens.
+func (c *Config) Save(path string) error {
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}
+
+// ProfileNames returns the profile names, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ViewNames returns the view names, sorted.
+func (c *Config) ViewNames() []string {
+	names := make([]string, 0, len(c.Views))
+	for name := range c.Views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// View returns the named view.
+func (c *Config) View(name string) (*View, error) {
+	v, ok := c.Views[name]
+	if !ok {
+		return nil, fmt.Errorf("config: no view %q", name)
+	}
+	return v, nil
+}
+
+// Profile returns the named profile. An empty name selects
+// DefaultProfile, then a profile called "default"; with neither, the
+// result is an empty profile. Naming a missing profile is an error.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+		if name == "" {
+			name = "default"
+			if c.Profiles[name] == nil {
+				return &Profile{}, nil
+			}
+		}
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("config: no profile %q", name)
+	}
+	return p, nil
+}
+
+// Set stores value as the setting key of the named profile, creating
+// the profile if needed. The key default_profile sets DefaultProfile
+// instead and ignores profile. An empty value removes the setting.
+func (c *Config) Set(profile, key, value string) error {
+	if key == "default_profile" {
+		c.DefaultProfile = value
+		return nil
+	}
+	if profile == "" {
+		profile = c.DefaultProfile
+		if profile == "" {
+			profile = "default"
+		}
+	}
+	if !validName(profile) {
+		return fmt.Errorf("config: invalid profile name %q (use letters, digits, - and _)", profile)
+	}
+	p := c.Profiles[profile]
+	if p == nil {
+		p = &Profile{}
+	}
+	f, ok := p.field(key)
+	if !ok {
+		return unknownKey(key)
+	}
+	*f = value
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+	c.Profiles[profile] = p
+	return nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sample = `# adtap configuration
+default_profile = "agency"
+
+[profiles.agency]
+developer_token = "dev-token" # not a real token
+login_customer_id = 1234567890
+format = 'csv'
+
+[profiles.direct-client]
+customer_id = "2345678901"
+managers = "1234567890, 3456789012"
+api_version = "v23"
+secrets = "keyring"
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	p, err := cfg.Profile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Profile{DeveloperToken: "dev-token", LoginCustomerID: "1234567890", Format: "csv"}
+	if *p != want {
+		t.Errorf("default profile = %+v, want %+v", *p, want)
+	}
+	if p, _ := cfg.Profile("direct-client"); p.CustomerID != "2345678901" || p.APIVersion != "v23" || p.Secrets != "keyring" ||
+		p.Managers != "1234567890, 3456789012" {
+		t.Errorf("direct-client = %+v", *p)
+	}
+	if _, err := cfg.Profile("missing"); err == nil {
+		t.Error("expected an error for a missing profile")
+	}
+
+	var buf bytes.Buffer
+	cfg.WriteTo(&buf)
+	again, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("reparsing written config: %v", err)
+	}
+	if again.DefaultProfile != "agency" || len(again.Profiles) != 2 || *again.Profiles["agency"] != want {
+		t.Errorf("round trip lost settings:\n%s", buf.String())
+	}
+}
+
+func TestParseWindowsFile(t *testing.T) {
+	// Notepad's UTF-8 with a byte order mark and CRLF line endings.
+	crlf := "\xEF\xBB\xBF" + strings.ReplaceAll(sample, "\n", "\r\n")
+	cfg, err := Parse(strings.NewReader(crlf))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p, _ := cfg.Profile(""); p.LoginCustomerID != "1234567890" || p.Format != "csv" {
+		t.Errorf("default profile = %+v", *p)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{input: "[server]\n", wantErr: "line 1: unknown table [server]"},
+		{input: "[profiles.a]\ntoken = \"x\"\n", wantErr: `line 2: config: unknown key "token"`},
+		{input: "[profiles.a]\nformat = csv\n", wantErr: "line 2: invalid value"},
+		{input: "format = \"csv\"\n", wantErr: `unknown key "format" (expected default_profile)`},
+		{input: "[profiles.a\n", wantErr: "unterminated table header"},
+		{input: "[profiles.a]\nformat\n", wantErr: "expected key = value"},
+		{input: "[views.a]\nsink = \"x\"\n", wantErr: `line 2: config: unknown view key "sink"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Parse(%q): expected error containing %q, got %v", tt.input, tt.wantErr, err)
+		}
+	}
+}
+
+func TestSetAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "adtap", "config.toml")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of a missing file: %v", err)
+	}
+	if err := cfg.Set("", "customer_id", "1234567890"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("mcc", "format", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("mcc", "colour", "blue"); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+	if err := cfg.Set("bad name", "format", "json"); err == nil {
+		t.Error("expected an error for an invalid profile name")
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("config file mode = %v, %v", fi.Mode(), err)
+	}
+
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := cfg.Profile(""); p.CustomerID != "1234567890" {
+		t.Errorf("default profile = %+v", *p)
+	}
+	if v, _ := cfg.Profiles["mcc"].Get("format"); v != "json" {
+		t.Errorf("mcc format = %q", v)
+	}
+}
+
+func TestViews(t *testing.T) {
+	input := sample + `
+[views.weekly_spend]
+description = "Spend by campaign"
+profile = "agency"
+query_file = "queries/weekly.gaql"
+param.start = "2026-01-01"
+param.end = "2026-01-07"
+columns = "campaign.name=Campaign"
+micros_to_currency = true
+to_sqlite = "ads.db"
+
+[views.broken]
+query = "SELECT campaign.id FROM campaign"
+template = "campaign-performance"
+`
+	cfg, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := cfg.View("weekly_spend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Profile != "agency" || v.MicrosToCurrency != "true" || v.Params["end"] != "2026-01-07" || v.ToSQLite != "ads.db" {
+		t.Errorf("weekly_spend = %+v", *v)
+	}
+	if src, err := v.Source(); src != "query_file" || err != nil {
+		t.Errorf("Source() = %q, %v", src, err)
+	}
+	if _, err := cfg.Views["broken"].Source(); err == nil {
+		t.Error("expected an error for a view with two sources")
+	}
+	if _, err := cfg.View("missing"); err == nil {
+		t.Error("expected an error for a missing view")
+	}
+
+	var buf bytes.Buffer
+	cfg.WriteTo(&buf)
+	written := buf.String()
+	again, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("reparsing written config: %v", err)
+	}
+	if got := again.Views["weekly_spend"]; got == nil || got.Columns != v.Columns || len(got.Params) != 2 || got.MicrosToCurrency != "true" {
+		t.Errorf("round trip lost view settings:\n%s", written)
+	}
+	if !strings.Contains(written, "micros_to_currency = true\n") {
+		t.Errorf("booleans should be written bare:\n%s", written)
+	}
+}
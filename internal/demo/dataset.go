@@ -0,0 +1,624 @@
+package demo
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// datasetJSON describes the demo accounts. Daily metrics are generated
+// from the baselines it gives, relative to the server's date.
+//
+//go:embed dataset.json
+var datasetJSON []byte
+
+// historyDays is how many days of metrics the dataset holds, ending
+// today.
+const historyDays = 90
+
+type fixture struct {
+	Accounts []fixtureAccount `json:"accounts"`
+}
+
+type fixtureAccount struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Currency  string            `json:"currency"`
+	TimeZone  string            `json:"time_zone"`
+	Manager   bool              `json:"manager"`
+	Clients   []string          `json:"clients"`
+	Campaigns []fixtureCampaign `json:"campaigns"`
+
+	ConversionActions []fixtureConversionAction `json:"conversion_actions"`
+	Changes           []fixtureChange           `json:"changes"`
+}
+
+// fixtureConversionAction is a conversion action with its share of the
+// account's conversions: primary actions split the conversions that
+// count toward goals, and secondary ones the rest of all conversions.
+type fixtureConversionAction struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	Category     string  `json:"category"`
+	Status       string  `json:"status"`
+	CountingType string  `json:"counting_type"`
+	Primary      bool    `json:"primary"`
+	Share        float64 `json:"share"`
+}
+
+// fixtureChange is a change_event made days_ago days before the
+// server's date. Resource is relative to the customer, as in
+// campaigns/20001, and Old and New hold the changed fields in camelCase.
+type fixtureChange struct {
+	DaysAgo      int            `json:"days_ago"`
+	Time         string         `json:"time"` // HH:MM:SS
+	ResourceType string         `json:"resource_type"`
+	Operation    string         `json:"operation"`
+	Resource     string         `json:"resource"`
+	User         string         `json:"user"`
+	Client       string         `json:"client"`
+	Old          map[string]any `json:"old"`
+	New          map[string]any `json:"new"`
+}
+
+type fixtureCampaign struct {
+	ID            string           `json:"id"`
+	Name          string           `json:"name"`
+	Status        string           `json:"status"`
+	Channel       string           `json:"channel"`
+	Bidding       string           `json:"bidding"`
+	StartDate     string           `json:"start_date"`
+	PausedDaysAgo int              `json:"paused_days_ago"`
+	SpikeDaysAgo  int              `json:"spike_days_ago"`
+	Budget        fixtureBudget    `json:"budget"`
+	AdGroups      []fixtureAdGroup `json:"ad_groups"`
+	Daily         *baseline        `json:"daily"`
+
+	// Placements are where a Performance Max campaign showed ads.
+	Placements []fixturePlacement `json:"placements"`
+}
+
+// fixturePlacement is a row of performance_max_placement_view, whose
+// daily baseline gives impressions only.
+type fixturePlacement struct {
+	Placement   string    `json:"placement"`
+	DisplayName string    `json:"display_name"`
+	Type        string    `json:"type"`
+	TargetURL   string    `json:"target_url"`
+	Daily       *baseline `json:"daily"`
+}
+
+type fixtureBudget struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+	Period string  `json:"period"`
+}
+
+type fixtureAdGroup struct {
+	ID       string           `json:"id"`
+	Name     string           `json:"name"`
+	Status   string           `json:"status"`
+	Type     string           `json:"type"`
+	CPCBid   float64          `json:"cpc_bid"`
+	Keywords []fixtureKeyword `json:"keywords"`
+	Ads      []fixtureAd      `json:"ads"`
+	Daily    *baseline        `json:"daily"`
+
+	// SearchTerms are the queries that triggered the ad group's ads.
+	// Their traffic is part of the keywords', so it is not added to the
+	// ad group, campaign, or account.
+	SearchTerms []fixtureSearchTerm `json:"search_terms"`
+}
+
+// fixtureSearchTerm is a row of search_term_view; Status is ADDED when
+// the term is also a keyword.
+type fixtureSearchTerm struct {
+	Text   string    `json:"text"`
+	Status string    `json:"status"`
+	Daily  *baseline `json:"daily"`
+}
+
+// fixtureAd is a responsive search ad. Headlines pinned to a position
+// are written "HEADLINE_1: text".
+type fixtureAd struct {
+	ID           string   `json:"id"`
+	Status       string   `json:"status"`
+	Headlines    []string `json:"headlines"`
+	Descriptions []string `json:"descriptions"`
+	Path1        string   `json:"path1"`
+	Path2        string   `json:"path2"`
+	FinalURL     string   `json:"final_url"`
+	Approval     string   `json:"approval"`
+	PolicyTopics []string `json:"policy_topics"` // TOPIC:TYPE
+}
+
+type fixtureKeyword struct {
+	ID            string    `json:"id"`
+	Text          string    `json:"text"`
+	Match         string    `json:"match"`
+	Status        string    `json:"status"`
+	QualityScore  int       `json:"quality_score"`
+	PausedDaysAgo int       `json:"paused_days_ago"`
+	Daily         *baseline `json:"daily"`
+}
+
+// baseline is the typical day of an entity that accrues metrics.
+type baseline struct {
+	Impressions float64 `json:"impressions"`
+	CTR         float64 `json:"ctr"`
+	CPC         float64 `json:"cpc"` // in currency units
+	CVR         float64 `json:"cvr"` // conversions per click
+	OrderValue  float64 `json:"order_value"`
+}
+
+// Additive metrics, the ones facts hold; the others are ratios of them.
+const (
+	mImpressions = iota
+	mClicks
+	mCostMicros
+	mConversions
+	mConversionsValue
+	mAllConversions
+	mAllConversionsValue
+	mViewThrough
+	numMetrics
+)
+
+// fact is the metrics of one entity on one day and device, and of one
+// conversion action when facts are split by action.
+type fact struct {
+	date   time.Time
+	device string
+	action *conversionAction
+	m      [numMetrics]float64
+}
+
+// conversionAction is a conversion action that facts are split across
+// when a query segments by conversion action.
+type conversionAction struct {
+	resourceName string
+	name         string
+	category     string
+	primary      bool
+	share        float64
+}
+
+// entity is a row of a resource: its attributes, and its facts when the
+// resource has metrics.
+type entity struct {
+	row   map[string]any
+	facts []*fact
+}
+
+// account is a demo customer and its resources.
+type account struct {
+	id       string
+	name     string
+	currency string
+	manager  bool
+	tables   map[string][]*entity
+	actions  []*conversionAction
+}
+
+// devices split each day's traffic, with their share of impressions
+// and how their click-through rate and CPC differ from the baseline.
+var devices = []struct {
+	name            string
+	share, ctr, cpc float64
+}{
+	{"MOBILE", 0.55, 1.1, 0.85},
+	{"DESKTOP", 0.37, 0.95, 1.2},
+	{"TABLET", 0.08, 0.9, 1.0},
+}
+
+// loadDataset builds the accounts of the embedded dataset, with metrics
+// for the historyDays days up to today.
+func loadDataset(today time.Time) (map[string]*account, error) {
+	var fx fixture
+	if err := json.Unmarshal(datasetJSON, &fx); err != nil {
+		return nil, fmt.Errorf("demo: reading dataset: %w", err)
+	}
+	byID := make(map[string]fixtureAccount)
+	for _, a := range fx.Accounts {
+		byID[a.ID] = a
+	}
+	accounts := make(map[string]*account)
+	for _, fa := range fx.Accounts {
+		a := &account{id: fa.ID, name: fa.Name, currency: fa.Currency, manager: fa.Manager, tables: make(map[string][]*entity)}
+		customer := map[string]any{
+			"resourceName":       "customers/" + fa.ID,
+			"id":                 fa.ID,
+			"descriptiveName":    fa.Name,
+			"currencyCode":       fa.Currency,
+			"timeZone":           fa.TimeZone,
+			"manager":            fa.Manager,
+			"testAccount":        false,
+			"status":             "ENABLED",
+			"autoTaggingEnabled": true,
+		}
+		clients := []fixtureAccount{fa}
+		for _, id := range fa.Clients {
+			c, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("demo: account %s lists unknown client %s", fa.ID, id)
+			}
+			clients = append(clients, c)
+		}
+		for i, c := range clients {
+			level := min(i, 1)
+			a.add("customer_client", &entity{row: map[string]any{
+				"customer": customer,
+				"customerClient": map[string]any{
+					"resourceName":    fmt.Sprintf("customers/%s/customerClients/%s", fa.ID, c.ID),
+					"clientCustomer":  "customers/" + c.ID,
+					"id":              c.ID,
+					"descriptiveName": c.Name,
+					"currencyCode":    c.Currency,
+					"timeZone":        c.TimeZone,
+					"manager":         c.Manager,
+					"testAccount":     false,
+					"hidden":          false,
+					"status":          "ENABLED",
+					"level":           strconv.Itoa(level),
+				},
+			}})
+		}
+
+		root := &entity{row: map[string]any{"customer": customer}}
+		a.add("customer", root)
+		for _, fc := range fa.Campaigns {
+			if err := a.addCampaign(fc, customer, root, today); err != nil {
+				return nil, err
+			}
+		}
+		for _, fca := range fa.ConversionActions {
+			a.addConversionAction(fca, customer)
+		}
+		for i, fc := range fa.Changes {
+			if err := a.addChange(i, fc, customer, today); err != nil {
+				return nil, err
+			}
+		}
+		accounts[a.id] = a
+	}
+	return accounts, nil
+}
+
+func (a *account) add(resource string, e *entity) {
+	a.tables[resource] = append(a.tables[resource], e)
+}
+
+func (a *account) addConversionAction(fca fixtureConversionAction, customer map[string]any) {
+	ca := &conversionAction{
+		resourceName: fmt.Sprintf("customers/%s/conversionActions/%s", a.id, fca.ID),
+		name:         fca.Name,
+		category:     fca.Category,
+		primary:      fca.Primary,
+		share:        fca.Share,
+	}
+	a.actions = append(a.actions, ca)
+	a.add("conversion_action", &entity{row: map[string]any{
+		"customer": customer,
+		"conversionAction": map[string]any{
+			"resourceName":   ca.resourceName,
+			"id":             fca.ID,
+			"name":           fca.Name,
+			"type":           fca.Type,
+			"category":       fca.Category,
+			"status":         fca.Status,
+			"countingType":   fca.CountingType,
+			"primaryForGoal": fca.Primary,
+		},
+	}})
+}
+
+// addChange adds the i-th change_event of the account. Its field mask
+// lists the fields set in the old or new resource.
+func (a *account) addChange(i int, fc fixtureChange, customer map[string]any, today time.Time) error {
+	at, err := time.Parse(time.DateTime, today.AddDate(0, 0, -fc.DaysAgo).Format(time.DateOnly)+" "+fc.Time)
+	if err != nil {
+		return fmt.Errorf("demo: change %d of %s: %w", i, a.id, err)
+	}
+	event := map[string]any{
+		"resourceName":            fmt.Sprintf("customers/%s/changeEvents/%d~0~%d", a.id, at.UnixMicro(), i),
+		"changeDateTime":          at.Format("2006-01-02 15:04:05.000000"),
+		"changeResourceType":      fc.ResourceType,
+		"resourceChangeOperation": fc.Operation,
+		"changeResourceName":      "customers/" + a.id + "/" + fc.Resource,
+		"userEmail":               fc.User,
+		"clientType":              fc.Client,
+		"changedFields":           strings.Join(fieldPaths(fc.Old, fc.New), ","),
+	}
+	// The resources are the oneof of ChangeEvent.Resource, keyed by
+	// the resource type, as {"campaign": {...}}.
+	oneof := lowerCamel(strings.ToLower(fc.ResourceType))
+	if fc.Old != nil {
+		event["oldResource"] = map[string]any{oneof: fc.Old}
+	}
+	if fc.New != nil {
+		event["newResource"] = map[string]any{oneof: fc.New}
+	}
+	a.add("change_event", &entity{row: map[string]any{"customer": customer, "changeEvent": event}})
+	return nil
+}
+
+// fieldPaths returns the sorted paths of the fields set in any of the
+// resources, as a field mask lists them.
+func fieldPaths(resources ...map[string]any) []string {
+	seen := make(map[string]bool)
+	var walk func(prefix string, m map[string]any)
+	walk = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if sub, ok := v.(map[string]any); ok {
+				walk(prefix+k+".", sub)
+			} else {
+				seen[prefix+k] = true
+			}
+		}
+	}
+	for _, r := range resources {
+		walk("", r)
+	}
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (a *account) addCampaign(fc fixtureCampaign, customer map[string]any, root *entity, today time.Time) error {
+	start, err := time.Parse(time.DateOnly, fc.StartDate)
+	if err != nil {
+		return fmt.Errorf("demo: campaign %s: %w", fc.ID, err)
+	}
+	prefix := "customers/" + a.id
+	budget := map[string]any{
+		"resourceName":     prefix + "/campaignBudgets/" + fc.Budget.ID,
+		"id":               fc.Budget.ID,
+		"name":             fc.Budget.Name,
+		"amountMicros":     strconv.FormatInt(int64(math.Round(fc.Budget.Amount*1e6)), 10),
+		"period":           fc.Budget.Period,
+		"deliveryMethod":   "STANDARD",
+		"explicitlyShared": false,
+		"referenceCount":   "1",
+		"status":           "ENABLED",
+	}
+	campaign := map[string]any{
+		"resourceName":           prefix + "/campaigns/" + fc.ID,
+		"id":                     fc.ID,
+		"name":                   fc.Name,
+		"status":                 fc.Status,
+		"servingStatus":          servingStatus(fc.Status),
+		"advertisingChannelType": fc.Channel,
+		"biddingStrategyType":    fc.Bidding,
+		"startDate":              fc.StartDate,
+		"campaignBudget":         budget["resourceName"],
+	}
+	a.add("campaign_budget", &entity{row: map[string]any{"customer": customer, "campaignBudget": budget}})
+	ce := &entity{row: map[string]any{"customer": customer, "campaign": campaign, "campaignBudget": budget}}
+	a.add("campaign", ce)
+
+	// Metrics accrue from the start date until the campaign was paused;
+	// removed campaigns have none.
+	g := generator{today: today, start: start, channel: fc.Channel, spike: -1}
+	if fc.SpikeDaysAgo > 0 {
+		g.spike = fc.SpikeDaysAgo
+	}
+	switch fc.Status {
+	case "PAUSED":
+		g.pausedDaysAgo = fc.PausedDaysAgo
+	case "REMOVED":
+		return nil
+	}
+	accrue := func(id string, b *baseline, pausedDaysAgo int, owners ...*entity) {
+		if b == nil {
+			return
+		}
+		g := g
+		g.pausedDaysAgo = max(g.pausedDaysAgo, pausedDaysAgo)
+		facts := g.facts(id, *b)
+		for _, o := range owners {
+			o.facts = append(o.facts, facts...)
+		}
+	}
+	accrue(fc.ID, fc.Daily, 0, root, ce)
+
+	for _, fp := range fc.Placements {
+		pe := &entity{row: map[string]any{
+			"customer": customer,
+			"campaign": campaign,
+			"performanceMaxPlacementView": map[string]any{
+				"resourceName":  prefix + "/performanceMaxPlacementViews/" + base64.RawURLEncoding.EncodeToString([]byte(fp.Placement)),
+				"placement":     fp.Placement,
+				"displayName":   fp.DisplayName,
+				"placementType": fp.Type,
+				"targetUrl":     fp.TargetURL,
+			},
+		}}
+		a.add("performance_max_placement_view", pe)
+		accrue(fc.ID+"~"+fp.Placement, fp.Daily, 0, pe)
+	}
+
+	for _, fg := range fc.AdGroups {
+		adGroup := map[string]any{
+			"resourceName": prefix + "/adGroups/" + fg.ID,
+			"id":           fg.ID,
+			"name":         fg.Name,
+			"status":       fg.Status,
+			"type":         fg.Type,
+			"campaign":     campaign["resourceName"],
+			"cpcBidMicros": strconv.FormatInt(int64(math.Round(fg.CPCBid*1e6)), 10),
+		}
+		ge := &entity{row: map[string]any{"customer": customer, "campaign": campaign, "adGroup": adGroup}}
+		a.add("ad_group", ge)
+		accrue(fg.ID, fg.Daily, 0, root, ce, ge)
+
+		for _, fk := range fg.Keywords {
+			name := fmt.Sprintf("%s/adGroupCriteria/%s~%s", prefix, fg.ID, fk.ID)
+			criterion := map[string]any{
+				"resourceName": name,
+				"criterionId":  fk.ID,
+				"adGroup":      adGroup["resourceName"],
+				"status":       fk.Status,
+				"type":         "KEYWORD",
+				"negative":     false,
+				"keyword":      map[string]any{"text": fk.Text, "matchType": fk.Match},
+				"qualityInfo":  map[string]any{"qualityScore": fk.QualityScore},
+			}
+			row := map[string]any{"customer": customer, "campaign": campaign, "adGroup": adGroup, "adGroupCriterion": criterion}
+			a.add("ad_group_criterion", &entity{row: row})
+			view := map[string]any{"keywordView": map[string]any{"resourceName": fmt.Sprintf("%s/keywordViews/%s~%s", prefix, fg.ID, fk.ID)}}
+			for k, v := range row {
+				view[k] = v
+			}
+			ke := &entity{row: view}
+			a.add("keyword_view", ke)
+			accrue(fk.ID, fk.Daily, fk.PausedDaysAgo, root, ce, ge, ke)
+		}
+		for _, ft := range fg.SearchTerms {
+			se := &entity{row: map[string]any{
+				"customer": customer,
+				"campaign": campaign,
+				"adGroup":  adGroup,
+				"searchTermView": map[string]any{
+					"resourceName": fmt.Sprintf("%s/searchTermViews/%s~%s~%s", prefix, fc.ID, fg.ID, base64.RawURLEncoding.EncodeToString([]byte(ft.Text))),
+					"searchTerm":   ft.Text,
+					"status":       ft.Status,
+					"adGroup":      adGroup["resourceName"],
+				},
+			}}
+			a.add("search_term_view", se)
+			accrue(fg.ID+"~"+ft.Text, ft.Daily, 0, se)
+		}
+		for _, fa := range fg.Ads {
+			a.add("ad_group_ad", &entity{row: map[string]any{
+				"customer":  customer,
+				"campaign":  campaign,
+				"adGroup":   adGroup,
+				"adGroupAd": adGroupAd(prefix, fg.ID, adGroup["resourceName"], fa),
+			}})
+		}
+	}
+	return nil
+}
+
+// adGroupAd returns the adGroupAd object of a fixture ad, as the REST
+// interface nests it.
+func adGroupAd(prefix, adGroupID string, adGroup any, fa fixtureAd) map[string]any {
+	assets := func(texts []string) []any {
+		out := make([]any, len(texts))
+		for i, t := range texts {
+			asset := map[string]any{"text": t}
+			if pin, text, ok := strings.Cut(t, ": "); ok && strings.HasPrefix(pin, "HEADLINE_") {
+				asset = map[string]any{"text": text, "pinnedField": pin}
+			}
+			out[i] = asset
+		}
+		return out
+	}
+	var topics []any
+	for _, t := range fa.PolicyTopics {
+		topic, typ, _ := strings.Cut(t, ":")
+		topics = append(topics, map[string]any{"topic": topic, "type": typ})
+	}
+	return map[string]any{
+		"resourceName": fmt.Sprintf("%s/adGroupAds/%s~%s", prefix, adGroupID, fa.ID),
+		"adGroup":      adGroup,
+		"status":       fa.Status,
+		"ad": map[string]any{
+			"resourceName": prefix + "/ads/" + fa.ID,
+			"id":           fa.ID,
+			"type":         "RESPONSIVE_SEARCH_AD",
+			"finalUrls":    []any{fa.FinalURL},
+			"responsiveSearchAd": map[string]any{
+				"headlines":    assets(fa.Headlines),
+				"descriptions": assets(fa.Descriptions),
+				"path1":        fa.Path1,
+				"path2":        fa.Path2,
+			},
+		},
+		"policySummary": map[string]any{
+			"approvalStatus":     fa.Approval,
+			"reviewStatus":       "REVIEWED",
+			"policyTopicEntries": topics,
+		},
+	}
+}
+
+func servingStatus(status string) string {
+	if status == "ENABLED" {
+		return "SERVING"
+	}
+	return "SUSPENDED"
+}
+
+// generator derives daily facts from a baseline. The variation is a
+// hash of the entity, day, and device, so the same date always has the
+// same metrics.
+type generator struct {
+	today         time.Time
+	start         time.Time
+	channel       string
+	pausedDaysAgo int // no metrics in this many most recent days
+	spike         int // the days-ago of a day with inflated cost, or -1
+}
+
+func (g generator) facts(id string, b baseline) []*fact {
+	var facts []*fact
+	for ago := historyDays - 1; ago >= 0; ago-- {
+		date := g.today.AddDate(0, 0, -ago)
+		if date.Before(g.start) || ago < g.pausedDaysAgo {
+			continue
+		}
+		day := 1 + 0.15*float64(historyDays-ago)/historyDays // a slow upward trend
+		if wd := date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			day *= 0.8
+		}
+		for _, d := range devices {
+			noise := func(k string) float64 {
+				h := fnv.New64a()
+				fmt.Fprintf(h, "%s|%s|%s|%s", id, date.Format(time.DateOnly), d.name, k)
+				return 0.8 + 0.4*float64(h.Sum64()%10000)/10000
+			}
+			var f fact
+			f.date, f.device = date, d.name
+			impressions := math.Round(b.Impressions * day * d.share * noise("impressions"))
+			if impressions == 0 {
+				continue
+			}
+			clicks := math.Min(impressions, math.Round(impressions*b.CTR*d.ctr*noise("clicks")))
+			cpc := b.CPC * d.cpc * noise("cpc")
+			if ago == g.spike {
+				clicks, cpc = math.Round(clicks*4), cpc*1.3
+			}
+			conversions := round2(clicks * b.CVR * noise("conversions"))
+			value := round2(conversions * b.OrderValue * noise("value"))
+			f.m[mImpressions] = impressions
+			f.m[mClicks] = clicks
+			f.m[mCostMicros] = math.Round(clicks*cpc*100) * 1e4 // whole cents
+			f.m[mConversions] = conversions
+			f.m[mConversionsValue] = value
+			f.m[mAllConversions] = round2(conversions * 1.2)
+			f.m[mAllConversionsValue] = round2(value * 1.15)
+			if g.channel == "DISPLAY" || g.channel == "PERFORMANCE_MAX" {
+				f.m[mViewThrough] = math.Round(impressions * 0.0004)
+			}
+			facts = append(facts, &f)
+		}
+	}
+	return facts
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
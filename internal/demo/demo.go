@@ -0,0 +1,264 @@
+// Package demo serves a bundled set of fixture accounts through the
+// Google Ads REST API, so the whole CLI can run without credentials.
+//
+// A Server answers googleAds:search and customers:listAccessibleCustomers
+// the way the API does: it parses and validates the GAQL, filters,
+// segments, and aggregates the fixture data, and returns nested camelCase
+// rows that the client, formatters, and sinks handle like live results,
+// with the summary row and total results count when asked for.
+// Its accounts are a manager, 1234567890, with two clients: 2345678901,
+// an outdoor store billing in USD, and 3456789012, a café billing in
+// EUR. Metrics cover the 90 days up to the server's date and are
+// deterministic, so the same query on the same day gives the same rows.
+//
+// The demo covers the customer, customer_client, campaign,
+// campaign_budget, ad_group, ad_group_ad, ad_group_criterion,
+// keyword_view, search_term_view, performance_max_placement_view,
+// conversion_action, and change_event resources, which are what the
+// built-in commands query. They are segmented by date, day of week,
+// week, month, quarter, year, device, and conversion action; other
+// resources and segments are rejected with a queryError. Rates gives
+// fixed exchange rates for the demo currencies.
+//
+// # Basic Usage
+//
+//	srv := demo.New(time.Now())
+//	client := adsapi.New("demo", adsapi.StaticToken("demo"),
+//		adsapi.WithHTTPClient(&http.Client{Transport: srv.Transport()}))
+//	resp, err := client.Search(ctx, demo.CustomerID,
+//		"SELECT campaign.name, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS")
+package demo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/fx"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+const (
+	// ManagerID is the demo manager account, the only account
+	// customers:listAccessibleCustomers returns.
+	ManagerID = "1234567890"
+
+	// CustomerID is the demo client account with the most data.
+	CustomerID = "2345678901"
+)
+
+// Rates returns fixed exchange rates covering the demo currencies, for
+// converting them without fetching live rates.
+func Rates() *fx.Table {
+	return &fx.Table{Base: "EUR", Rates: map[string]float64{"USD": 1.08, "GBP": 0.85, "JPY": 162, "CHF": 0.94}}
+}
+
+// pageSize is the number of rows per page of search results.
+const pageSize = 10000
+
+// Server serves the demo accounts. It implements http.Handler.
+type Server struct {
+	today time.Time
+
+	once     sync.Once
+	accounts map[string]*account
+	err      error
+
+	requests atomic.Int64
+}
+
+// New returns a server whose data ends on today's date.
+func New(today time.Time) *Server {
+	y, m, d := today.Date()
+	return &Server{today: time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
+
+// Transport returns a RoundTripper that answers requests in process,
+// for an http.Client given to adsapi.WithHTTPClient.
+func (s *Server) Transport() http.RoundTripper {
+	return roundTripper{s}
+}
+
+type roundTripper struct {
+	h http.Handler
+}
+
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	rt.h.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// ServeHTTP answers a Google Ads API request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := fmt.Sprintf("demo-%06d", s.requests.Add(1))
+	w.Header().Set("request-id", requestID)
+
+	s.once.Do(func() { s.accounts, s.err = loadDataset(s.today) })
+	if s.err != nil {
+		writeError(w, requestID, http.StatusInternalServerError, "INTERNAL", s.err.Error(), nil)
+		return
+	}
+
+	// Paths are /{version}/customers:listAccessibleCustomers and
+	// /{version}/customers/{id}/googleAds:search.
+	_, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case path == "customers:listAccessibleCustomers" && r.Method == http.MethodGet:
+		writeJSON(w, map[string]any{"resourceNames": []string{"customers/" + ManagerID}})
+	case strings.HasPrefix(path, "customers/") && strings.HasSuffix(path, "/googleAds:search") && r.Method == http.MethodPost:
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "customers/"), "/googleAds:search")
+		s.search(w, r, requestID, id)
+	default:
+		writeError(w, requestID, http.StatusNotImplemented, "UNIMPLEMENTED",
+			fmt.Sprintf("%s %s is not part of the offline demo, which serves googleAds:search and customers:listAccessibleCustomers", r.Method, r.URL.Path), nil)
+	}
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request, requestID, customerID string) {
+	a, ok := s.accounts[customerID]
+	if !ok {
+		writeError(w, requestID, http.StatusForbidden, "PERMISSION_DENIED",
+			"The caller does not have permission",
+			&adsError{"authorizationError", "USER_PERMISSION_DENIED",
+				fmt.Sprintf("Customer %s is not one of the demo accounts (%s)", customerID, strings.Join(s.accountIDs(), ", "))})
+		return
+	}
+	var req struct {
+		Query                   string `json:"query"`
+		PageToken               string `json:"pageToken"`
+		ValidateOnly            bool   `json:"validateOnly"`
+		SummaryRowSetting       string `json:"summaryRowSetting"`
+		ReturnTotalResultsCount bool   `json:"returnTotalResultsCount"`
+	}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		writeError(w, requestID, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid JSON payload: "+err.Error(), nil)
+		return
+	}
+	switch req.SummaryRowSetting {
+	case "", "NO_SUMMARY_ROW", "SUMMARY_ROW_WITH_RESULTS", "SUMMARY_ROW_ONLY":
+	default:
+		writeError(w, requestID, http.StatusBadRequest, "INVALID_ARGUMENT",
+			fmt.Sprintf("Invalid value at 'summary_row_setting', %q", req.SummaryRowSetting), nil)
+		return
+	}
+	offset := 0
+	if req.PageToken != "" {
+		if offset, err = strconv.Atoi(req.PageToken); err != nil || offset < 0 {
+			writeError(w, requestID, http.StatusBadRequest, "INVALID_ARGUMENT", "Request contains an invalid argument.",
+				&adsError{"requestError", "INVALID_PAGE_TOKEN", "Page token is invalid."})
+			return
+		}
+	}
+
+	res, err := evaluate(a, req.Query, s.today)
+	if err != nil {
+		var ae *adsError
+		if !errors.As(err, &ae) {
+			ae = &adsError{"queryError", "QUERY_ERROR", err.Error()}
+		}
+		writeError(w, requestID, http.StatusBadRequest, "INVALID_ARGUMENT", "Request contains an invalid argument.", ae)
+		return
+	}
+
+	resp := map[string]any{"fieldMask": fieldMask(res.query), "requestId": requestID}
+	if req.ValidateOnly {
+		writeJSON(w, resp)
+		return
+	}
+	rows := res.rows
+	end := min(offset+pageSize, len(rows))
+	if req.SummaryRowSetting == "SUMMARY_ROW_ONLY" {
+		end = len(rows)
+	}
+	results := rows[min(offset, end):end]
+	if results == nil || req.SummaryRowSetting == "SUMMARY_ROW_ONLY" {
+		results = []map[string]any{}
+	}
+	resp["results"] = results
+	last := end == len(rows)
+	if !last {
+		resp["nextPageToken"] = strconv.Itoa(end)
+	}
+	// The summary row comes with the last page, like the API's.
+	if last && res.summary != nil && req.SummaryRowSetting != "NO_SUMMARY_ROW" && req.SummaryRowSetting != "" {
+		resp["summaryRow"] = res.summary
+	}
+	if req.ReturnTotalResultsCount {
+		resp["totalResultsCount"] = strconv.Itoa(res.total)
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) accountIDs() []string {
+	ids := make([]string, 0, len(s.accounts))
+	for id := range s.accounts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// fieldMask lists the selected fields in camelCase, as the API does.
+func fieldMask(q *gaql.Query) string {
+	names := make([]string, len(q.Select))
+	for i, f := range q.Select {
+		parts := strings.Split(f.Name, ".")
+		for j, p := range parts {
+			parts[j] = lowerCamel(p)
+		}
+		names[i] = strings.Join(parts, ".")
+	}
+	return strings.Join(names, ",")
+}
+
+// adsError is a GoogleAdsFailure entry.
+type adsError struct {
+	category, code, message string
+}
+
+func (e *adsError) Error() string {
+	return e.message
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a google.rpc.Status body, with a GoogleAdsFailure
+// detail when failure is not nil.
+func writeError(w http.ResponseWriter, requestID string, code int, status, message string, failure *adsError) {
+	body := map[string]any{"code": code, "message": message, "status": status}
+	if failure != nil {
+		body["details"] = []any{map[string]any{
+			"@type": "type.googleapis.com/google.ads.googleads.v23.errors.GoogleAdsFailure",
+			"errors": []any{map[string]any{
+				"errorCode": map[string]string{failure.category: failure.code},
+				"message":   failure.message,
+			}},
+			"requestId": requestID,
+		}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{"error": body})
+}
@@ -0,0 +1,335 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/accounts"
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/changes"
+	"github.com/aygp-dr/adtap/internal/compose"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/pacing"
+	"github.com/aygp-dr/adtap/internal/searchterms"
+)
+
+// today is a Wednesday.
+var today = time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+
+func newClient() *adsapi.Client {
+	return adsapi.New("demo", adsapi.StaticToken("demo"),
+		adsapi.WithHTTPClient(&http.Client{Transport: New(today).Transport()}))
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		customer string
+		query    string
+		rows     int
+		field    string // checked in the first row
+		want     any
+	}{
+		{"attributes", CustomerID, "SELECT campaign.id, campaign.name FROM campaign WHERE campaign.status != 'REMOVED' ORDER BY campaign.name", 4, "campaign.name", "All Products - PMax"},
+		{"in list", CustomerID, "SELECT campaign.id FROM campaign WHERE campaign.status IN ('PAUSED', 'REMOVED') ORDER BY campaign.id", 2, "campaign.id", "20003"},
+		{"like", CustomerID, "SELECT campaign.name FROM campaign WHERE campaign.name LIKE '%Search'", 2, "campaign.name", "Brand - Search"},
+		{"regexp", CustomerID, "SELECT ad_group_criterion.keyword.text FROM ad_group_criterion WHERE ad_group_criterion.keyword.text REGEXP_MATCH '(backpacking|ultralight) tent'", 2, "ad_group_criterion.keyword.text", "backpacking tent"},
+		{"resource name", CustomerID, "SELECT campaign.id FROM campaign WHERE campaign.id = 20001", 1, "campaign.resource_name", "customers/2345678901/campaigns/20001"},
+		{"daily", CustomerID, "SELECT segments.date, metrics.clicks FROM customer WHERE segments.date DURING LAST_7_DAYS ORDER BY segments.date", 7, "segments.date", "2026-03-11"},
+		{"devices", CustomerID, "SELECT segments.device, metrics.impressions FROM customer WHERE segments.date = '2026-03-17' ORDER BY metrics.impressions DESC", 3, "segments.device", "MOBILE"},
+		{"week", CustomerID, "SELECT segments.week, metrics.clicks FROM customer WHERE segments.date DURING LAST_WEEK_MON_SUN", 1, "segments.week", "2026-03-09"},
+		{"paused has no recent data", CustomerID, "SELECT campaign.id, metrics.clicks FROM campaign WHERE campaign.id = 20003 AND segments.date DURING LAST_7_DAYS", 0, "", nil},
+		{"metric filter", CustomerID, "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_30_DAYS AND metrics.clicks > 0", 3, "", nil},
+		{"limit", CustomerID, "SELECT keyword_view.resource_name, metrics.cost_micros FROM keyword_view WHERE segments.date DURING LAST_30_DAYS ORDER BY metrics.cost_micros DESC LIMIT 2", 2, "", nil},
+		{"ads", CustomerID, "SELECT ad_group_ad.ad.id, ad_group_ad.ad.responsive_search_ad.headlines FROM ad_group_ad WHERE ad_group_ad.policy_summary.approval_status = 'DISAPPROVED'", 1, "ad_group_ad.ad.id", "50003"},
+		{"manager clients", ManagerID, "SELECT customer_client.id, customer_client.level FROM customer_client WHERE customer_client.level <= 1", 3, "customer_client.level", "0"},
+		{"manager has no campaigns", ManagerID, "SELECT campaign.id FROM campaign", 0, "", nil},
+		{"currency", "3456789012", "SELECT customer.currency_code FROM customer", 1, "customer.currency_code", "EUR"},
+	}
+	client := newClient()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Search(context.Background(), tt.customer, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Results) != tt.rows {
+				t.Fatalf("got %d rows, want %d: %v", len(resp.Results), tt.rows, resp.Results)
+			}
+			if tt.field == "" {
+				return
+			}
+			if got, _ := output.Value(resp.Results[0], tt.field); got != tt.want {
+				t.Errorf("%s = %#v, want %#v", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		customer string
+		query    string
+		want     string // category.code
+	}{
+		{"unknown customer", "9999999999", "SELECT customer.id FROM customer", "authorizationError.USER_PERMISSION_DENIED"},
+		{"unsupported resource", CustomerID, "SELECT click_view.gclid FROM click_view WHERE segments.date = '2026-03-17'", "queryError.PROHIBITED_RESOURCE_TYPE_IN_FROM_CLAUSE"},
+		{"unsupported segment", CustomerID, "SELECT segments.hour, metrics.clicks FROM campaign", "queryError.PROHIBITED_SEGMENT_IN_SELECT_OR_WHERE_CLAUSE"},
+		{"metrics without them", CustomerID, "SELECT campaign_budget.id, metrics.clicks FROM campaign_budget", "queryError.PROHIBITED_METRIC_IN_SELECT_OR_WHERE_CLAUSE"},
+		{"syntax", CustomerID, "SELECT FROM campaign", "queryError.QUERY_ERROR"},
+	}
+	client := newClient()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Search(context.Background(), tt.customer, tt.query)
+			var apiErr *adsapi.APIError
+			if !errors.As(err, &apiErr) || len(apiErr.Errors) == 0 {
+				t.Fatalf("err = %v, want an API error", err)
+			}
+			if got := apiErr.Errors[0].Category + "." + apiErr.Errors[0].Code; got != tt.want {
+				t.Errorf("error = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryRow(t *testing.T) {
+	const q = "SELECT segments.date, metrics.clicks, metrics.cost_micros FROM customer WHERE segments.date DURING LAST_14_DAYS ORDER BY segments.date LIMIT 5"
+	resp, err := newClient().SearchWithOptions(context.Background(), CustomerID, q,
+		adsapi.SearchOptions{ReturnSummaryRow: true, ReturnTotalResultsCount: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 5 || resp.TotalResultsCount != 14 {
+		t.Fatalf("got %d rows of %d, want 5 of 14", len(resp.Results), resp.TotalResultsCount)
+	}
+	if _, ok := output.Value(resp.SummaryRow, "segments.date"); ok {
+		t.Error("summary row has segments.date")
+	}
+	// The summary covers every matching day, not only the first five.
+	all, err := newClient().Search(context.Background(), CustomerID, strings.TrimSuffix(q, " LIMIT 5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var clicks int
+	for _, row := range all.Results {
+		v, _ := output.Value(row, "metrics.clicks")
+		n, _ := strconv.Atoi(v.(string))
+		clicks += n
+	}
+	if got, _ := output.Value(resp.SummaryRow, "metrics.clicks"); got != strconv.Itoa(clicks) {
+		t.Errorf("summary clicks = %v, want %d", got, clicks)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	const q = "SELECT segments.date, metrics.cost_micros FROM customer WHERE segments.date DURING LAST_14_DAYS ORDER BY segments.date"
+	a, err := newClient().Search(context.Background(), CustomerID, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newClient().Search(context.Background(), CustomerID, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range a.Results {
+		x, _ := output.Value(a.Results[i], "metrics.cost_micros")
+		y, _ := output.Value(b.Results[i], "metrics.cost_micros")
+		if x != y {
+			t.Fatalf("row %d: cost %v, then %v", i, x, y)
+		}
+	}
+}
+
+func TestListAccessibleCustomers(t *testing.T) {
+	ids, err := newClient().ListAccessibleCustomers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != ManagerID {
+		t.Errorf("ids = %v, want [%s]", ids, ManagerID)
+	}
+}
+
+// commandQuery is a query a built-in command sends, built as the command
+// builds it with its default flags.
+type commandQuery struct {
+	command  string
+	customer string
+	query    *gaql.Query
+}
+
+// commandQueries returns the queries of every built-in command that
+// reads the API. Reports, templates, and the choices of a command's
+// flags are listed from the packages that define them, so new ones are
+// covered without a case here; a new command needs a case of its own.
+func commandQueries(t *testing.T) []commandQuery {
+	t.Helper()
+	must := func(q *gaql.Query, err error) *gaql.Query {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+	parse := func(s string) *gaql.Query {
+		t.Helper()
+		return must(gaql.Parse(s))
+	}
+	last30, err := compose.ParseLast("30d", today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := today.AddDate(0, 0, -6).Format(time.DateOnly)
+
+	qs := []commandQuery{
+		{"campaigns", CustomerID, must(compose.Campaigns(compose.CampaignOptions{}))},
+		{"campaigns --metrics", CustomerID, must(compose.Campaigns(compose.CampaignOptions{Metrics: true}))},
+		{"ads", CustomerID, compose.Ads(compose.AdOptions{})},
+		{"conversions (actions)", CustomerID, compose.ConversionActions()},
+		{"anomalies", CustomerID, must(compose.DailySeries("clicks", "", compose.DateSpan{During: gaql.DateRangeLast30Days}))},
+		{"anomalies --by campaign.id", CustomerID, must(compose.DailySeries("clicks", "campaign.id", compose.DateSpan{During: gaql.DateRangeLast30Days}))},
+		{"budgets", CustomerID, parse(pacing.Query)},
+		{"spend (time zone)", CustomerID, parse(pacing.TimeZoneQuery)},
+		{"changes", CustomerID, must(compose.Changes(compose.ChangesOptions{Since: since, Until: today.Format(time.DateOnly)}))},
+		{"search-terms", CustomerID, searchterms.Query(gaql.DateRangeLast30Days, 0)},
+	}
+	for _, by := range compose.ConversionBys {
+		qs = append(qs, commandQuery{"conversions --by " + by, CustomerID, must(compose.Conversions(compose.ConversionOptions{By: by, Span: last30}))})
+	}
+	for _, p := range pacing.Periods {
+		period, err := pacing.ParsePeriod(p, today)
+		if err != nil {
+			t.Fatal(err)
+		}
+		qs = append(qs, commandQuery{"spend --period " + p, CustomerID, pacing.SpendQuery(period, today)})
+	}
+	for _, e := range compose.TopEntities {
+		qs = append(qs, commandQuery{"top " + e, CustomerID, must(compose.Top(compose.TopOptions{Entity: e, By: "clicks", During: gaql.DateRangeLast7Days}))})
+	}
+	for _, table := range compose.SnapshotTables() {
+		qs = append(qs, commandQuery{"snapshot " + table.Name, CustomerID, table.Query})
+	}
+	for _, group := range []struct {
+		command   string
+		templates []*compose.Template
+	}{{"report", compose.Reports}, {"template run", compose.Templates}} {
+		for _, tmpl := range group.templates {
+			args, err := tmpl.Parse(map[string]string{"customer_id": CustomerID})
+			if err != nil {
+				t.Fatalf("%s %s: %v", group.command, tmpl.Name, err)
+			}
+			qs = append(qs, commandQuery{group.command + " " + tmpl.Name, CustomerID, must(tmpl.Query(args))})
+		}
+	}
+	return qs
+}
+
+// TestCommands checks that every built-in command works in
+// --offline-demo mode: the demo serves each query it sends, with rows.
+func TestCommands(t *testing.T) {
+	client := newClient()
+	for _, c := range commandQueries(t) {
+		t.Run(c.command, func(t *testing.T) {
+			resp, err := client.Search(context.Background(), c.customer, c.query.String())
+			if err != nil {
+				t.Fatalf("%s: %v", c.query, err)
+			}
+			if len(resp.Results) == 0 {
+				t.Errorf("%s: no rows", c.query)
+			}
+		})
+	}
+
+	// customers walks the account hierarchy from the manager.
+	t.Run("customers", func(t *testing.T) {
+		root, err := accounts.Tree(context.Background(), client, ManagerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		root.Walk(func(*accounts.Account, int) { n++ })
+		if n != 3 {
+			t.Errorf("got %d accounts, want 3", n)
+		}
+	})
+}
+
+func TestConversionActions(t *testing.T) {
+	client := newClient()
+	// totals returns the conversions and all conversions of each
+	// conversion action, or of the account under "".
+	totals := func(q string) map[string][2]float64 {
+		t.Helper()
+		resp, err := client.Search(context.Background(), CustomerID, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[string][2]float64)
+		for _, row := range resp.Results {
+			name, _ := output.Value(row, "segments.conversion_action_name")
+			conversions, _ := output.Value(row, "metrics.conversions")
+			all, _ := output.Value(row, "metrics.all_conversions")
+			key, _ := name.(string)
+			sum := out[key]
+			out[key] = [2]float64{sum[0] + conversions.(float64), sum[1] + all.(float64)}
+		}
+		return out
+	}
+	const where = " FROM customer WHERE segments.date DURING LAST_30_DAYS"
+	account := totals("SELECT metrics.conversions, metrics.all_conversions" + where)[""]
+	byAction := totals("SELECT segments.conversion_action_name, metrics.conversions, metrics.all_conversions" + where)
+
+	// Primary actions share the conversions, and secondary ones the all
+	// conversions beyond them; actions without a share have no rows.
+	var sum [2]float64
+	for _, v := range byAction {
+		sum[0] += v[0]
+		sum[1] += v[1]
+	}
+	if math.Abs(sum[0]-account[0]) > 0.1 || math.Abs(sum[1]-account[1]) > 0.1 {
+		t.Errorf("actions sum to %v, want the account's %v", sum, account)
+	}
+	if v := byAction["Newsletter Signup"]; v[0] != 0 || v[1] == 0 {
+		t.Errorf("secondary action has %v", v)
+	}
+	if v, ok := byAction["Store Locator Click"]; ok {
+		t.Errorf("action without a share has %v", v)
+	}
+}
+
+func TestChangeEvents(t *testing.T) {
+	q, err := compose.Changes(compose.ChangesOptions{Since: today.AddDate(0, 0, -6).Format(time.DateOnly), Until: today.Format(time.DateOnly)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := newClient().Search(context.Background(), CustomerID, q.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := changes.FromRows(resp.Results)
+	if len(got) != 4 {
+		t.Fatalf("got %d changes in the last week, want 4", len(got))
+	}
+	want := changes.Change{
+		Time:         "2026-03-17 16:42:08.000000",
+		ResourceType: "CAMPAIGN_BUDGET",
+		Operation:    "UPDATE",
+		ResourceName: "customers/2345678901/campaignBudgets/90002",
+		User:         "sam@example.com",
+		Client:       "GOOGLE_ADS_WEB_CLIENT",
+		Fields:       []changes.FieldChange{{Field: "amount_micros", Old: "220000000", New: "260000000"}},
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("newest change = %+v, want %+v", got[0], want)
+	}
+}
@@ -0,0 +1,613 @@
+package demo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// resources are the FROM resources the demo serves, and whether each
+// has metrics.
+var resources = map[string]bool{
+	"customer":                       true,
+	"customer_client":                false,
+	"campaign":                       true,
+	"campaign_budget":                false,
+	"ad_group":                       true,
+	"ad_group_criterion":             false,
+	"ad_group_ad":                    false,
+	"keyword_view":                   true,
+	"search_term_view":               true,
+	"performance_max_placement_view": true,
+	"conversion_action":              false,
+	"change_event":                   false,
+}
+
+// segments are the segments facts can be grouped by.
+var segments = map[string]func(*fact) any{
+	"segments.date":        func(f *fact) any { return f.date.Format(time.DateOnly) },
+	"segments.day_of_week": func(f *fact) any { return strings.ToUpper(f.date.Weekday().String()) },
+	"segments.week": func(f *fact) any {
+		return f.date.AddDate(0, 0, -(int(f.date.Weekday())+6)%7).Format(time.DateOnly)
+	},
+	"segments.month": func(f *fact) any {
+		return f.date.AddDate(0, 0, 1-f.date.Day()).Format(time.DateOnly)
+	},
+	"segments.quarter": func(f *fact) any {
+		y, m, _ := f.date.Date()
+		return time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+	},
+	"segments.year":   func(f *fact) any { return float64(f.date.Year()) },
+	"segments.device": func(f *fact) any { return f.device },
+
+	// The conversion action segments apply to facts split by action;
+	// see splitByAction.
+	"segments.conversion_action":          func(f *fact) any { return f.action.resourceName },
+	"segments.conversion_action_name":     func(f *fact) any { return f.action.name },
+	"segments.conversion_action_category": func(f *fact) any { return f.action.category },
+}
+
+// isActionSegment reports whether a segment splits facts by conversion
+// action.
+func isActionSegment(field string) bool {
+	return strings.HasPrefix(field, "segments.conversion_action")
+}
+
+// result is an evaluated query.
+type result struct {
+	query *gaql.Query
+	rows  []map[string]any
+	total int // rows matching the query, ignoring LIMIT
+
+	// summary holds the selected metrics over every matching row, as the
+	// API's summary row does; it is nil without metrics.
+	summary map[string]any
+}
+
+// evaluate runs query against an account and returns its result.
+func evaluate(a *account, query string, today time.Time) (*result, error) {
+	q, err := gaql.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	v := gaql.NewValidator()
+	v.RequireMetricDateContext = false
+	v.APIVersion = ""
+	if err := v.Validate(q); err != nil {
+		return nil, err
+	}
+	hasMetrics, ok := resources[q.From]
+	if !ok {
+		return nil, &adsError{"queryError", "PROHIBITED_RESOURCE_TYPE_IN_FROM_CLAUSE",
+			fmt.Sprintf("The offline demo has no %s data; it covers %s.", q.From, strings.Join(resourceNames(), ", "))}
+	}
+
+	// Sort the conditions by what they filter: entities, facts, or the
+	// aggregated rows.
+	var attrConds, segConds, metricConds []gaql.Condition
+	for _, c := range q.Where {
+		switch namespace(c.Field) {
+		case "segments":
+			segConds = append(segConds, c)
+		case "metrics":
+			metricConds = append(metricConds, c)
+		default:
+			attrConds = append(attrConds, c)
+		}
+	}
+	var segFields []string
+	aggregate := len(segConds) > 0 || len(metricConds) > 0
+	for _, f := range q.Select {
+		switch namespace(f.Name) {
+		case "segments":
+			segFields = append(segFields, f.Name)
+			aggregate = true
+		case "metrics":
+			aggregate = true
+		}
+	}
+	if aggregate && !hasMetrics {
+		return nil, &adsError{"queryError", "PROHIBITED_METRIC_IN_SELECT_OR_WHERE_CLAUSE",
+			fmt.Sprintf("Resource %s has no metrics or segments.", q.From)}
+	}
+	for _, c := range segConds {
+		if segments[c.Field] == nil {
+			return nil, unsupportedSegment(c.Field)
+		}
+	}
+	for _, f := range segFields {
+		if segments[f] == nil {
+			return nil, unsupportedSegment(f)
+		}
+	}
+	split := false
+	for _, c := range segConds {
+		split = split || isActionSegment(c.Field)
+	}
+	for _, f := range segFields {
+		split = split || isActionSegment(f)
+	}
+
+	var (
+		rows  []map[string]any
+		total bucket
+	)
+	for _, e := range a.tables[q.From] {
+		ok, err := matchAll(attrConds, func(field string) (any, bool) { return output.Value(e.row, field) }, today)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if !aggregate {
+			rows = append(rows, project(q, e.row, nil))
+			continue
+		}
+		facts := e.facts
+		if split {
+			facts = splitByAction(facts, a.actions)
+		}
+		groups, err := group(facts, segConds, segFields, today)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			row := project(q, e.row, g)
+			ok, err := matchAll(metricConds, func(field string) (any, bool) { return output.Value(row, field) }, today)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				rows = append(rows, row)
+				for i, v := range g.m {
+					total.m[i] += v
+				}
+			}
+		}
+	}
+
+	if len(q.OrderBy) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, o := range q.OrderBy {
+				a, _ := output.Value(rows[i], o.Field)
+				b, _ := output.Value(rows[j], o.Field)
+				c := compare(a, b)
+				if c == 0 {
+					continue
+				}
+				if o.Direction == gaql.Desc {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+	res := &result{query: q, rows: rows, total: len(rows)}
+	if q.Limit > 0 && len(rows) > q.Limit {
+		res.rows = rows[:q.Limit]
+	}
+	for _, f := range q.Select {
+		if v, ok := metric(f.Name, total.m); ok && namespace(f.Name) == "metrics" {
+			if res.summary == nil {
+				res.summary = make(map[string]any)
+			}
+			output.SetValue(res.summary, f.Name, v)
+		}
+	}
+	return res, nil
+}
+
+func resourceNames() []string {
+	names := make([]string, 0, len(resources))
+	for r := range resources {
+		names = append(names, r)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func unsupportedSegment(field string) error {
+	names := make([]string, 0, len(segments))
+	for s := range segments {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	return &adsError{"queryError", "PROHIBITED_SEGMENT_IN_SELECT_OR_WHERE_CLAUSE",
+		fmt.Sprintf("The offline demo cannot segment by %s; it supports %s.", field, strings.Join(names, ", "))}
+}
+
+func namespace(field string) string {
+	ns, _, _ := strings.Cut(field, ".")
+	return ns
+}
+
+// bucket sums the facts sharing a set of segment values.
+type bucket struct {
+	segs []any
+	m    [numMetrics]float64
+}
+
+// splitByAction splits facts across conversion actions, as segmenting
+// by conversion action does: each action gets its share of the
+// conversion metrics, and no impressions, clicks, or cost. Primary
+// actions share the conversions, and secondary ones the all conversions
+// beyond them.
+func splitByAction(facts []*fact, actions []*conversionAction) []*fact {
+	out := make([]*fact, 0, len(facts)*len(actions))
+	for _, f := range facts {
+		for _, ca := range actions {
+			s := &fact{date: f.date, device: f.device, action: ca}
+			if ca.primary {
+				s.m[mConversions] = f.m[mConversions] * ca.share
+				s.m[mConversionsValue] = f.m[mConversionsValue] * ca.share
+				s.m[mAllConversions] = s.m[mConversions]
+				s.m[mAllConversionsValue] = s.m[mConversionsValue]
+				s.m[mViewThrough] = f.m[mViewThrough] * ca.share
+			} else {
+				s.m[mAllConversions] = (f.m[mAllConversions] - f.m[mConversions]) * ca.share
+				s.m[mAllConversionsValue] = (f.m[mAllConversionsValue] - f.m[mConversionsValue]) * ca.share
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// group filters facts by the segment conditions and sums them by the
+// values of segFields, in date order. Groups without impressions, or
+// without conversions when split by conversion action, are dropped, as
+// the API drops rows without traffic.
+func group(facts []*fact, conds []gaql.Condition, segFields []string, today time.Time) ([]*bucket, error) {
+	byKey := make(map[string]*bucket)
+	var order []string
+	for _, f := range facts {
+		ok, err := matchAll(conds, func(field string) (any, bool) { return segments[field](f), true }, today)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		segs := make([]any, len(segFields))
+		for i, s := range segFields {
+			segs[i] = segments[s](f)
+		}
+		key := fmt.Sprint(segs...)
+		b, ok := byKey[key]
+		if !ok {
+			b = &bucket{segs: segs}
+			byKey[key] = b
+			order = append(order, key)
+		}
+		for i, v := range f.m {
+			b.m[i] += v
+		}
+	}
+	var out []*bucket
+	for _, k := range order {
+		if b := byKey[k]; b.m[mImpressions] > 0 || b.m[mAllConversions] > 0 {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+// project builds the result row of the selected fields from an entity's
+// attributes and, when aggregating, a bucket's segments and metrics. The
+// FROM resource's resource name is always included, as the API does.
+func project(q *gaql.Query, attrs map[string]any, b *bucket) map[string]any {
+	row := make(map[string]any)
+	if v, ok := output.Value(attrs, q.From+".resource_name"); ok {
+		output.SetValue(row, q.From+".resource_name", v)
+	}
+	seg := 0
+	for _, f := range q.Select {
+		switch namespace(f.Name) {
+		case "segments":
+			output.SetValue(row, f.Name, b.segs[seg])
+			seg++
+		case "metrics":
+			if v, ok := metric(f.Name, b.m); ok {
+				output.SetValue(row, f.Name, v)
+			}
+		default:
+			if v, ok := output.Value(attrs, f.Name); ok {
+				output.SetValue(row, f.Name, v)
+			}
+		}
+	}
+	return row
+}
+
+// metric returns the value of a metric from the summed facts, in the
+// API's JSON form: INT64 metrics as strings, DOUBLE ones as numbers.
+// Metrics the demo does not model are left out of rows.
+func metric(field string, m [numMetrics]float64) (any, bool) {
+	ratio := func(a, b float64) float64 {
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	}
+	count := func(v float64) string { return strconv.FormatFloat(v, 'f', 0, 64) }
+	switch strings.TrimPrefix(field, "metrics.") {
+	case "impressions":
+		return count(m[mImpressions]), true
+	case "clicks":
+		return count(m[mClicks]), true
+	case "interactions":
+		return count(m[mClicks]), true
+	case "cost_micros":
+		return count(m[mCostMicros]), true
+	case "conversions":
+		return round2(m[mConversions]), true
+	case "conversions_value":
+		return round2(m[mConversionsValue]), true
+	case "all_conversions":
+		return round2(m[mAllConversions]), true
+	case "all_conversions_value":
+		return round2(m[mAllConversionsValue]), true
+	case "view_through_conversions":
+		return count(m[mViewThrough]), true
+	case "ctr", "interaction_rate":
+		return ratio(m[mClicks], m[mImpressions]), true
+	case "average_cpc", "average_cost":
+		return ratio(m[mCostMicros], m[mClicks]), true
+	case "average_cpm":
+		return ratio(m[mCostMicros]*1000, m[mImpressions]), true
+	case "cost_per_conversion":
+		return ratio(m[mCostMicros], m[mConversions]), true
+	case "cost_per_all_conversions":
+		return ratio(m[mCostMicros], m[mAllConversions]), true
+	case "value_per_conversion":
+		return ratio(m[mConversionsValue], m[mConversions]), true
+	case "value_per_all_conversions":
+		return ratio(m[mAllConversionsValue], m[mAllConversions]), true
+	case "conversions_from_interactions_rate":
+		return ratio(m[mConversions], m[mClicks]), true
+	case "all_conversions_from_interactions_rate":
+		return ratio(m[mAllConversions], m[mClicks]), true
+	}
+	return nil, false
+}
+
+// matchAll reports whether the values lookup returns satisfy every
+// condition.
+func matchAll(conds []gaql.Condition, lookup func(field string) (any, bool), today time.Time) (bool, error) {
+	for _, c := range conds {
+		v, ok := lookup(c.Field)
+		if !ok {
+			v = nil
+		}
+		match, err := matches(c, v, today)
+		if err != nil || !match {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matches reports whether v, a row value, satisfies c.
+func matches(c gaql.Condition, v any, today time.Time) (bool, error) {
+	switch c.Operator {
+	case gaql.OpIsNull:
+		return v == nil, nil
+	case gaql.OpIsNotNull:
+		return v != nil, nil
+	case gaql.OpContainsAny, gaql.OpContainsAll, gaql.OpContainsNone:
+		items, _ := v.([]any)
+		if v != nil && items == nil {
+			items = []any{v}
+		}
+		found := 0
+		for _, want := range c.Value.List {
+			for _, item := range items {
+				if compare(item, want) == 0 {
+					found++
+					break
+				}
+			}
+		}
+		switch c.Operator {
+		case gaql.OpContainsAny:
+			return found > 0, nil
+		case gaql.OpContainsAll:
+			return found == len(c.Value.List), nil
+		default:
+			return found == 0, nil
+		}
+	}
+	if v == nil {
+		return false, nil
+	}
+
+	switch c.Operator {
+	case gaql.OpEq, gaql.OpNeq, gaql.OpGt, gaql.OpGte, gaql.OpLt, gaql.OpLte:
+		cmp := compare(v, literal(c.Value))
+		switch c.Operator {
+		case gaql.OpEq:
+			return cmp == 0, nil
+		case gaql.OpNeq:
+			return cmp != 0, nil
+		case gaql.OpGt:
+			return cmp > 0, nil
+		case gaql.OpGte:
+			return cmp >= 0, nil
+		case gaql.OpLt:
+			return cmp < 0, nil
+		default:
+			return cmp <= 0, nil
+		}
+	case gaql.OpIn, gaql.OpNotIn:
+		in := false
+		for _, want := range c.Value.List {
+			if compare(v, want) == 0 {
+				in = true
+				break
+			}
+		}
+		return in == (c.Operator == gaql.OpIn), nil
+	case gaql.OpBetween:
+		if len(c.Value.List) != 2 {
+			return false, fmt.Errorf("BETWEEN needs two values")
+		}
+		return compare(v, c.Value.List[0]) >= 0 && compare(v, c.Value.List[1]) <= 0, nil
+	case gaql.OpDuring:
+		start, end, err := dateRange(c.Value.DateRange, today)
+		if err != nil {
+			return false, err
+		}
+		s := text(v)
+		return s >= start.Format(time.DateOnly) && s <= end.Format(time.DateOnly), nil
+	case gaql.OpLike, gaql.OpNotLike:
+		re, err := likePattern(c.Value.Str)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(text(v)) == (c.Operator == gaql.OpLike), nil
+	case gaql.OpRegexpMatch, gaql.OpNotRegexpMatch:
+		re, err := regexp.Compile("^(?:" + c.Value.Str + ")$")
+		if err != nil {
+			return false, &adsError{"queryError", "BAD_VALUE_IN_WHERE_CLAUSE", fmt.Sprintf("Invalid regular expression %q: %v", c.Value.Str, err)}
+		}
+		return re.MatchString(text(v)) == (c.Operator == gaql.OpRegexpMatch), nil
+	}
+	return false, fmt.Errorf("operator %s is not supported by the offline demo", c.Operator)
+}
+
+// literal returns the text of a condition value.
+func literal(v gaql.Value) string {
+	if v.Type == gaql.ValueNumber {
+		return strconv.FormatFloat(v.Number, 'f', -1, 64)
+	}
+	return v.Str
+}
+
+// text returns a row value as the text a condition compares it as.
+func text(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// compare orders two values: numerically when both are numbers, which
+// INT64 values are even as strings, and otherwise by text. Booleans
+// compare case-insensitively, so TRUE matches true.
+func compare(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	sa, sb := text(a), text(b)
+	fa, errA := strconv.ParseFloat(sa, 64)
+	fb, errB := strconv.ParseFloat(sb, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	if _, ok := a.(bool); ok {
+		sb = strings.ToLower(sb)
+	}
+	return strings.Compare(sa, sb)
+}
+
+// likePattern translates a LIKE pattern, where % matches any text, _ one
+// character, and [c] a literal c, to an anchored regular expression.
+func likePattern(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, &adsError{"queryError", "BAD_VALUE_IN_WHERE_CLAUSE", fmt.Sprintf("Unterminated [ in LIKE pattern %q", pattern)}
+			}
+			sb.WriteString(regexp.QuoteMeta(pattern[i+1 : i+end]))
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile("(?s)" + sb.String())
+}
+
+// dateRange resolves a DURING keyword to its first and last day,
+// relative to today.
+func dateRange(dr gaql.DateRange, today time.Time) (start, end time.Time, err error) {
+	yesterday := today.AddDate(0, 0, -1)
+	weekday := int(today.Weekday()) // Sunday is 0
+	switch dr {
+	case gaql.DateRangeToday:
+		return today, today, nil
+	case gaql.DateRangeYesterday:
+		return yesterday, yesterday, nil
+	case gaql.DateRangeThisMonth:
+		return today.AddDate(0, 0, 1-today.Day()), today, nil
+	case gaql.DateRangeLastMonth:
+		first := today.AddDate(0, 0, 1-today.Day())
+		return first.AddDate(0, -1, 0), first.AddDate(0, 0, -1), nil
+	case gaql.DateRangeThisWeekSunToday:
+		return today.AddDate(0, 0, -weekday), today, nil
+	case gaql.DateRangeThisWeekMonToday:
+		return today.AddDate(0, 0, -(weekday+6)%7), today, nil
+	case gaql.DateRangeLastWeekSunSat:
+		sunday := today.AddDate(0, 0, -weekday-7)
+		return sunday, sunday.AddDate(0, 0, 6), nil
+	case gaql.DateRangeLastWeekMonSun, gaql.DateRangeLastBusinessWeek:
+		monday := today.AddDate(0, 0, -(weekday+6)%7-7)
+		if dr == gaql.DateRangeLastBusinessWeek {
+			return monday, monday.AddDate(0, 0, 4), nil
+		}
+		return monday, monday.AddDate(0, 0, 6), nil
+	}
+	// LAST_7_DAYS, LAST_30_DAYS, and the like end yesterday.
+	if n := dr.Days(); n > 0 {
+		return today.AddDate(0, 0, -n), yesterday, nil
+	}
+	return time.Time{}, time.Time{}, &adsError{"queryError", "BAD_VALUE_IN_WHERE_CLAUSE",
+		fmt.Sprintf("The offline demo cannot resolve date range %s.", dr)}
+}
+
+// lowerCamel converts a snake_case field name part to lowerCamelCase.
+func lowerCamel(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,200 @@
+// Package doctor reports on the prerequisites of calling the Google Ads
+// API, one check at a time, so that a broken setup fails with the step
+// to fix rather than one opaque error from the first query.
+//
+// The checks themselves live with the command, which knows where each
+// setting comes from; the package holds what they share: the Result of
+// a check, the report, and the probes that need no credentials.
+//
+//	ok    Developer token   from GOOGLE_ADS_DEVELOPER_TOKEN
+//	ok    Credentials       service account reports@example.iam.gserviceaccount.com
+//	FAIL  Access token      invalid_grant: Invalid JWT Signature.
+//	      Hint: the key was deleted or rotated; download a new key.
+//	skip  Token scopes      needs an access token
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	OK Status = iota
+	Warn
+	Fail
+
+	// Skip means the check did not run: one it needs failed, or it
+	// does not apply.
+	Skip
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Warn:
+		return "warn"
+	case Fail:
+		return "FAIL"
+	case Skip:
+		return "skip"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name   string
+	Status Status
+
+	// Detail says what was found, or what went wrong.
+	Detail string
+
+	// Hint says how to fix a failure or warning.
+	Hint string
+}
+
+// Failed reports whether any check failed.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes the report shown in the package documentation.
+func Write(w io.Writer, results []Result) error {
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.Name))
+	}
+	var sb strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&sb, "%-4s  %-*s  %s\n", r.Status, width, r.Name, r.Detail)
+		if r.Hint != "" && r.Status != OK {
+			fmt.Fprintf(&sb, "      Hint: %s\n", r.Hint)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Clock skew beyond MaxSkew makes Google reject the signed assertions
+// of service accounts; beyond WarnSkew it is worth fixing before it
+// grows.
+const (
+	WarnSkew = 30 * time.Second
+	MaxSkew  = 5 * time.Minute
+)
+
+// ClockSkew returns how far the local clock is ahead of the server at
+// url, from the Date header of a HEAD request. The header has whole
+// seconds, so skews under a second or two read as noise.
+func ClockSkew(ctx context.Context, hc *http.Client, url string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	sent := time.Now()
+	resp, err := hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	received := time.Now()
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("doctor: %s sent no usable Date header", url)
+	}
+	// The server stamped the response somewhere between sent and
+	// received; assume halfway.
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(date).Round(time.Second), nil
+}
+
+// SkewResult is the Result of the clock check for skew.
+func SkewResult(skew time.Duration) Result {
+	r := Result{Name: "Clock", Status: OK}
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case skew > 0:
+		r.Detail = fmt.Sprintf("%v ahead of Google", skew)
+	case skew < 0:
+		r.Detail = fmt.Sprintf("%v behind Google", -skew)
+	default:
+		r.Detail = "in sync with Google"
+	}
+	switch {
+	case abs > MaxSkew:
+		r.Status = Fail
+		r.Hint = "service account token requests fail with this much skew; sync the clock with NTP."
+	case abs > WarnSkew:
+		r.Status = Warn
+		r.Hint = "sync the clock with NTP."
+	}
+	return r
+}
+
+// apiHints map Google Ads error codes to what to do about them.
+var apiHints = map[string]string{
+	"DEVELOPER_TOKEN_NOT_APPROVED":         "the developer token has test access only; query test accounts, or apply for basic access in the API Center.",
+	"DEVELOPER_TOKEN_PROHIBITED":           "the developer token may not be used with this Google Cloud project; use the token of the manager account the project is registered with.",
+	"DEVELOPER_TOKEN_INVALID":              "copy the developer token again from the API Center of your manager account.",
+	"DEVELOPER_TOKEN_NOT_ON_ALLOWLIST":     "the developer token is not allowed to use this API version or feature.",
+	"PROJECT_DISABLED":                     "the Google Cloud project of the credentials is disabled; enable it in the Cloud console.",
+	"USER_PERMISSION_DENIED":               "the signed-in user cannot reach this account; check the login customer ID, or grant the user access.",
+	"CUSTOMER_NOT_ENABLED":                 "the account is not enabled, or is cancelled; pick another account.",
+	"NOT_ADS_USER":                         "the credentials' Google account has no Google Ads access; sign in with a user of the account.",
+	"OAUTH_TOKEN_INVALID":                  "run 'adtap auth login' again, or check the credentials file.",
+	"OAUTH_TOKEN_EXPIRED":                  "run 'adtap auth login' again, or check the credentials file.",
+	"OAUTH_TOKEN_REVOKED":                  "the refresh token was revoked; run 'adtap auth login' again.",
+	"CUSTOMER_NOT_FOUND":                   "check the customer ID; it has no dashes in API calls.",
+	"GOOGLE_ACCOUNT_AUTHENTICATION_FAILED": "the Google account could not sign in; run 'adtap auth login' again.",
+}
+
+// ExplainAPIError returns what err, returned by an API call, means for
+// the setup and how to fix it. The hint is empty for errors it does not
+// know.
+func ExplainAPIError(err error) (detail, hint string) {
+	var apiErr *adsapi.APIError
+	if !errors.As(err, &apiErr) {
+		return err.Error(), "check the network connection and any proxy settings."
+	}
+	detail = apiErr.Message
+	if apiErr.Status != "" {
+		detail = apiErr.Status + ": " + detail
+	}
+	for _, ge := range apiErr.Errors {
+		if h, ok := apiHints[ge.Code]; ok {
+			return ge.String(), h
+		}
+	}
+	body := string(apiErr.Body)
+	switch {
+	case strings.Contains(body, "SERVICE_DISABLED") || strings.Contains(apiErr.Message, "has not been used in project"):
+		hint = "enable the Google Ads API (googleads.googleapis.com) in the Google Cloud project of the credentials."
+	case strings.Contains(body, "ACCESS_TOKEN_SCOPE_INSUFFICIENT"):
+		hint = "the access token lacks the adwords scope; sign in again granting it."
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		hint = "the access token was rejected; run 'adtap auth login' again, or check the credentials file."
+	}
+	if len(apiErr.Errors) > 0 && hint == "" {
+		detail = apiErr.Errors[0].String()
+	}
+	return detail, hint
+}
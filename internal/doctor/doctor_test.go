@@ -0,0 +1,113 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+func TestWrite(t *testing.T) {
+	var sb strings.Builder
+	err := Write(&sb, []Result{
+		{Name: "Developer token", Status: OK, Detail: "from GOOGLE_ADS_DEVELOPER_TOKEN", Hint: "unused"},
+		{Name: "Access token", Status: Fail, Detail: "invalid_grant", Hint: "sign in again."},
+		{Name: "Scopes", Status: Skip, Detail: "needs an access token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ok    Developer token  from GOOGLE_ADS_DEVELOPER_TOKEN\n" +
+		"FAIL  Access token     invalid_grant\n" +
+		"      Hint: sign in again.\n" +
+		"skip  Scopes           needs an access token\n"
+	if sb.String() != want {
+		t.Errorf("Write =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestSkewResult(t *testing.T) {
+	tests := []struct {
+		skew       time.Duration
+		wantStatus Status
+		wantDetail string
+	}{
+		{0, OK, "in sync with Google"},
+		{2 * time.Second, OK, "2s ahead of Google"},
+		{-45 * time.Second, Warn, "45s behind Google"},
+		{10 * time.Minute, Fail, "10m0s ahead of Google"},
+	}
+	for _, tt := range tests {
+		r := SkewResult(tt.skew)
+		if r.Status != tt.wantStatus || r.Detail != tt.wantDetail {
+			t.Errorf("SkewResult(%v) = %v %q, want %v %q", tt.skew, r.Status, r.Detail, tt.wantStatus, tt.wantDetail)
+		}
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer srv.Close()
+
+	skew, err := ClockSkew(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skew < time.Hour-2*time.Second || skew > time.Hour+2*time.Second {
+		t.Errorf("ClockSkew = %v, want about 1h", skew)
+	}
+}
+
+func TestExplainAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantDetail string
+		wantHint   string // substring
+	}{
+		{
+			name: "unapproved token",
+			err: &adsapi.APIError{StatusCode: 403, Status: "PERMISSION_DENIED", Message: "The caller does not have permission",
+				Errors: []adsapi.GoogleAdsError{{Category: "authorizationError", Code: "DEVELOPER_TOKEN_NOT_APPROVED", Message: "The developer token is only approved for use with test accounts."}}},
+			wantDetail: "authorizationError.DEVELOPER_TOKEN_NOT_APPROVED: The developer token is only approved for use with test accounts.",
+			wantHint:   "test accounts",
+		},
+		{
+			name: "API disabled",
+			err: &adsapi.APIError{StatusCode: 403, Status: "PERMISSION_DENIED",
+				Message: "Google Ads API has not been used in project 123456789012 before or it is disabled.",
+				Body:    []byte(`{"error": {"details": [{"reason": "SERVICE_DISABLED"}]}}`)},
+			wantDetail: "PERMISSION_DENIED: Google Ads API has not been used in project 123456789012 before or it is disabled.",
+			wantHint:   "enable the Google Ads API",
+		},
+		{
+			name:       "unknown",
+			err:        &adsapi.APIError{StatusCode: 500, Status: "INTERNAL", Message: "Internal error encountered."},
+			wantDetail: "INTERNAL: Internal error encountered.",
+		},
+		{
+			name:       "network",
+			err:        errors.New("dial tcp: lookup googleads.googleapis.com: no such host"),
+			wantDetail: "dial tcp: lookup googleads.googleapis.com: no such host",
+			wantHint:   "network",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, hint := ExplainAPIError(tt.err)
+			if detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", detail, tt.wantDetail)
+			}
+			if tt.wantHint == "" && hint != "" || !strings.Contains(hint, tt.wantHint) {
+				t.Errorf("hint = %q, want it to contain %q", hint, tt.wantHint)
+			}
+		})
+	}
+}
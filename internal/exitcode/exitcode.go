@@ -0,0 +1,63 @@
+// Package exitcode defines the adtap process exit codes.
+//
+// See docs/exit-codes.md for the full taxonomy and when each code applies.
+package exitcode
+
+// Exit codes per clig.dev conventions.
+const (
+	Success         = 0
+	GeneralError    = 1
+	UsageError      = 2
+	AuthError       = 3
+	APIError        = 4
+	ConfigError     = 5
+	IOError         = 6
+	ValidationError = 7
+
+	// Alert means the command ran but found conditions it was asked to
+	// alert on, such as budgets pacing over a threshold.
+	Alert = 8
+
+	// Incomplete means the command wrote its output, but left out part
+	// of what was asked for, such as a filter condition adtap translate
+	// could not carry over to GAQL.
+	Incomplete = 9
+
+	// Interrupted and Terminated mean a SIGINT or SIGTERM stopped the
+	// command after it flushed what it had; as in the shell, they are 128
+	// plus the signal number.
+	Interrupted = 130
+	Terminated  = 143
+)
+
+// Category returns the error category name for an exit code.
+func Category(code int) string {
+	switch code {
+	case Success:
+		return "SUCCESS"
+	case GeneralError:
+		return "GENERAL_ERROR"
+	case UsageError:
+		return "USAGE_ERROR"
+	case AuthError:
+		return "AUTH_ERROR"
+	case APIError:
+		return "API_ERROR"
+	case ConfigError:
+		return "CONFIG_ERROR"
+	case IOError:
+		return "IO_ERROR"
+	case ValidationError:
+		return "VALIDATION_ERROR"
+	case Alert:
+		return "ALERT"
+	case Incomplete:
+		return "INCOMPLETE"
+	case Interrupted:
+		return "INTERRUPTED"
+	case Terminated:
+		return "TERMINATED"
+	default:
+		return "UNKNOWN"
+	}
+}
@@ -0,0 +1,439 @@
+// Package bigquery streams query result rows into a BigQuery table.
+//
+// A Sink is an output.Renderer: search writes rows to it as it would to
+// stdout, and the sink sends them in batches with the streaming insert
+// API (tabledata.insertAll). The table is created on first use with a
+// schema derived from the selected fields; an existing table must have
+// a compatible column for every field.
+//
+// Every row carries an insert ID, and a failed batch is retried with the
+// same IDs, so BigQuery drops the rows of an attempt that did land
+// instead of storing them twice.
+//
+// # Basic Usage
+//
+//	table, _ := bigquery.ParseTableID("my-project.ads.campaign_daily")
+//	sink := bigquery.NewSink(ctx, table, bigquery.Schema(fields, opts))
+//	sink.Token = ts.Token
+//	output.WriteRows(sink, fields, rows, opts)
+package bigquery
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// DefaultBatchSize is the number of rows sent per insert request, the
+// batch size BigQuery recommends for streaming inserts.
+const DefaultBatchSize = 500
+
+// TableID names a BigQuery table.
+type TableID struct {
+	Project, Dataset, Table string
+}
+
+// ParseTableID parses PROJECT.DATASET.TABLE, or DATASET.TABLE with the
+// project taken from GOOGLE_PROJECT_ID or GOOGLE_CLOUD_PROJECT. Domain-
+// scoped projects such as example.com:ads hold dots of their own, so
+// the table and dataset are read from the right.
+func ParseTableID(s string) (TableID, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return TableID{}, fmt.Errorf("bigquery: %q is not PROJECT.DATASET.TABLE", s)
+	}
+	t := TableID{
+		Project: strings.Join(parts[:len(parts)-2], "."),
+		Dataset: parts[len(parts)-2],
+		Table:   parts[len(parts)-1],
+	}
+	t.Project = cmp.Or(t.Project, os.Getenv("GOOGLE_PROJECT_ID"), os.Getenv("GOOGLE_CLOUD_PROJECT"))
+	switch {
+	case t.Project == "":
+		return TableID{}, fmt.Errorf("bigquery: %q names no project (use PROJECT.DATASET.TABLE or set GOOGLE_PROJECT_ID)", s)
+	case t.Dataset == "" || t.Table == "":
+		return TableID{}, fmt.Errorf("bigquery: %q is not PROJECT.DATASET.TABLE", s)
+	}
+	return t, nil
+}
+
+func (t TableID) String() string {
+	return t.Project + "." + t.Dataset + "." + t.Table
+}
+
+// Column is a column of a BigQuery table schema.
+type Column struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // STRING, INTEGER, FLOAT, BOOLEAN, or DATE
+	Mode        string `json:"mode"` // NULLABLE or REPEATED
+	Description string `json:"description,omitempty"`
+}
+
+// Schema derives a table schema for fields, written as opts writes them:
+// one column per field, named after it with dots replaced by
+// underscores (metrics.clicks becomes metrics_clicks), and typed from
+// the catalog. Amounts in micros are FLOAT with opts.Micros, and enum
+// values STRING in either form. Fields the catalog lacks are STRING.
+// opts.Constants is expected to be nil, as constant names would not fit
+// the ID columns they replace.
+func Schema(fields []string, opts output.Options) []Column {
+	catalog := cmp.Or(opts.Catalog, gaql.DefaultCatalog())
+	cols := make([]Column, len(fields))
+	for i, f := range fields {
+		rc, _ := catalog.Column(f)
+		col := Column{
+			Name:        ColumnName(opts.Column(f)),
+			Type:        columnType(rc.DataType),
+			Mode:        "NULLABLE",
+			Description: f,
+		}
+		if opts.Micros && output.IsMicros(f) {
+			col.Type = "FLOAT"
+		}
+		if rc.Repeated {
+			col.Mode = "REPEATED"
+		}
+		cols[i] = col
+	}
+	return cols
+}
+
+// ColumnName returns the BigQuery column name of a result column, which
+// may hold only letters, digits, and underscores.
+func ColumnName(column string) string {
+	return strings.ReplaceAll(column, ".", "_")
+}
+
+func columnType(dataType string) string {
+	switch dataType {
+	case "INT64", "INT32", "UINT64":
+		return "INTEGER"
+	case "DOUBLE", "FLOAT":
+		return "FLOAT"
+	case "BOOLEAN":
+		return "BOOLEAN"
+	case "DATE":
+		return "DATE"
+	default:
+		return "STRING"
+	}
+}
+
+// Sink writes result rows into a BigQuery table. It implements
+// output.Renderer; rows are buffered and sent BatchSize at a time, and
+// Flush sends the rest. A Sink is not safe for concurrent use.
+type Sink struct {
+	Table  TableID
+	Schema []Column
+
+	// Token returns an access token with the bigquery or cloud-platform
+	// scope, for example the Token method of auth.DefaultCredentials.
+	Token func(ctx context.Context) (string, error)
+
+	// Endpoint is the API root; empty means
+	// https://bigquery.googleapis.com.
+	Endpoint string
+
+	// HTTPClient is used for requests; nil means http.DefaultClient.
+	HTTPClient *http.Client
+
+	// BatchSize is the number of rows per insert request; zero means
+	// DefaultBatchSize.
+	BatchSize int
+
+	// MaxAttempts bounds the attempts at each request, including the
+	// first; zero means 5. Network errors, 429, and 5xx responses are
+	// retried after InitialBackoff (zero means one second), doubling
+	// each time up to 30 seconds.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+
+	ctx      context.Context
+	runID    string
+	rows     []insertRow
+	sent     int  // rows of earlier batches, for insert IDs
+	inserted int  // rows BigQuery accepted
+	created  bool // the table was created by this sink
+	ready    bool // the table exists with a compatible schema
+}
+
+// insertRow is one row of an insertAll request.
+type insertRow struct {
+	InsertID string         `json:"insertId"`
+	JSON     map[string]any `json:"json"`
+}
+
+// NewSink returns a sink writing to table, which is created with schema
+// if it does not exist. ctx bounds every request. The caller sets Token.
+func NewSink(ctx context.Context, table TableID, schema []Column) *Sink {
+	return &Sink{Table: table, Schema: schema, ctx: ctx, runID: fmt.Sprintf("%016x", rand.Uint64())}
+}
+
+// Inserted returns the number of rows BigQuery has accepted so far.
+func (s *Sink) Inserted() int {
+	return s.inserted
+}
+
+// WriteHeader checks the table, creating it if needed, so a missing
+// dataset or an incompatible table fails before any row is read.
+func (s *Sink) WriteHeader(columns []string) error {
+	if len(columns) != len(s.Schema) {
+		return fmt.Errorf("bigquery: %d columns for a schema of %d", len(columns), len(s.Schema))
+	}
+	return s.ensureTable()
+}
+
+func (s *Sink) WriteRow(values []string) error {
+	vs := make([]any, len(values))
+	for i, v := range values {
+		if v != "" {
+			vs[i] = v
+		}
+	}
+	return s.WriteValues(vs)
+}
+
+// WriteValues buffers a row of typed values, as output.Options.Typed
+// returns them, and sends the batch once it is full.
+func (s *Sink) WriteValues(values []any) error {
+	row := make(map[string]any, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if _, isList := v.([]any); s.Schema[i].Mode == "REPEATED" && !isList {
+			v = []any{v}
+		}
+		row[s.Schema[i].Name] = v
+	}
+	s.rows = append(s.rows, insertRow{InsertID: fmt.Sprintf("%s-%d", s.runID, s.sent+len(s.rows)), JSON: row})
+	if len(s.rows) >= cmp.Or(s.BatchSize, DefaultBatchSize) {
+		return s.send()
+	}
+	return nil
+}
+
+// Flush sends the buffered rows.
+func (s *Sink) Flush() error {
+	if len(s.rows) == 0 {
+		return nil
+	}
+	return s.send()
+}
+
+// send inserts the buffered rows.
+func (s *Sink) send() error {
+	if err := s.ensureTable(); err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]any{"rows": s.rows})
+	if err != nil {
+		return fmt.Errorf("bigquery: %w", err)
+	}
+	var resp struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}
+	err = s.do(http.MethodPost, s.tablePath()+"/insertAll", body, &resp)
+	if err != nil {
+		return s.failed(err)
+	}
+	for _, ie := range resp.InsertErrors {
+		for _, e := range ie.Errors {
+			if e.Reason != "stopped" {
+				return s.failed(fmt.Errorf("bigquery: row %d: %s: %s", s.sent+ie.Index+1, e.Reason, e.Message))
+			}
+		}
+	}
+	s.sent += len(s.rows)
+	s.inserted += len(s.rows)
+	s.rows = s.rows[:0]
+	return nil
+}
+
+// failed adds how far the export got to the error of a batch.
+func (s *Sink) failed(err error) error {
+	return fmt.Errorf("%w (%d rows were inserted into %s before the failure)", err, s.inserted, s.Table)
+}
+
+// ensureTable creates the table, or checks that the existing one has a
+// column of the right type for every column of the schema.
+func (s *Sink) ensureTable() error {
+	if s.ready {
+		return nil
+	}
+	var table struct {
+		Schema struct {
+			Fields []Column `json:"fields"`
+		} `json:"schema"`
+	}
+	err := s.do(http.MethodGet, s.tablePath(), nil, &table)
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		body, err := json.Marshal(map[string]any{
+			"tableReference": map[string]string{"projectId": s.Table.Project, "datasetId": s.Table.Dataset, "tableId": s.Table.Table},
+			"schema":         map[string]any{"fields": s.Schema},
+			"description":    "Google Ads query results written by adtap",
+		})
+		if err != nil {
+			return fmt.Errorf("bigquery: %w", err)
+		}
+		path := fmt.Sprintf("/projects/%s/datasets/%s/tables", url.PathEscape(s.Table.Project), url.PathEscape(s.Table.Dataset))
+		if err := s.do(http.MethodPost, path, body, nil); err != nil {
+			return fmt.Errorf("bigquery: creating %s: %w", s.Table, err)
+		}
+		s.created = true
+	case err != nil:
+		return err
+	default:
+		existing := map[string]Column{}
+		for _, c := range table.Schema.Fields {
+			existing[strings.ToLower(c.Name)] = c
+		}
+		for _, c := range s.Schema {
+			have, ok := existing[strings.ToLower(c.Name)]
+			if !ok {
+				return fmt.Errorf("bigquery: %s has no column %s for %s; write to a new table or add the column", s.Table, c.Name, c.Description)
+			}
+			if !compatible(have, c) {
+				return fmt.Errorf("bigquery: column %s of %s is %s %s, not %s %s", c.Name, s.Table, cmp.Or(have.Mode, "NULLABLE"), have.Type, c.Mode, c.Type)
+			}
+		}
+	}
+	s.ready = true
+	return nil
+}
+
+// compatible reports whether values for want can be stored in have.
+func compatible(have, want Column) bool {
+	aliases := map[string]string{"INT64": "INTEGER", "FLOAT64": "FLOAT", "BOOL": "BOOLEAN"}
+	haveType := cmp.Or(aliases[have.Type], have.Type)
+	if (have.Mode == "REPEATED") != (want.Mode == "REPEATED") {
+		return false
+	}
+	return haveType == want.Type || haveType == "STRING" && want.Type != "BOOLEAN" || haveType == "FLOAT" && want.Type == "INTEGER"
+}
+
+func (s *Sink) tablePath() string {
+	return fmt.Sprintf("/projects/%s/datasets/%s/tables/%s",
+		url.PathEscape(s.Table.Project), url.PathEscape(s.Table.Dataset), url.PathEscape(s.Table.Table))
+}
+
+// APIError is an error response of the BigQuery API.
+type APIError struct {
+	StatusCode int
+	Status     string // e.g. NOT_FOUND
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bigquery: HTTP %d: %s", e.StatusCode, cmp.Or(e.Message, e.Status, http.StatusText(e.StatusCode)))
+}
+
+// retryable reports whether a request that failed with err may succeed
+// if sent again.
+func (s *Sink) retryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return s.ctx.Err() == nil
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusNotFound:
+		// A table just created may not accept streamed rows at once.
+		return s.created
+	}
+	return false
+}
+
+// do sends a request to the API, retrying transient failures, and
+// decodes the response into out when it is not nil.
+func (s *Sink) do(method, path string, body []byte, out any) error {
+	attempts := cmp.Or(s.MaxAttempts, 5)
+	delay := cmp.Or(s.InitialBackoff, time.Second)
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = s.request(method, path, body, out); err == nil {
+			return nil
+		}
+		if attempt == attempts || !s.retryable(err) {
+			return err
+		}
+		wait := time.Duration(float64(delay) * (0.8 + 0.4*rand.Float64()))
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(wait):
+		}
+		delay = min(2*delay, 30*time.Second)
+	}
+}
+
+func (s *Sink) request(method, path string, body []byte, out any) error {
+	if s.Token == nil {
+		return fmt.Errorf("bigquery: no Google Cloud credentials for %s", s.Table)
+	}
+	token, err := s.Token(s.ctx)
+	if err != nil {
+		return fmt.Errorf("bigquery: %w", err)
+	}
+	endpoint := strings.TrimRight(cmp.Or(s.Endpoint, "https://bigquery.googleapis.com"), "/")
+	req, err := http.NewRequestWithContext(s.ctx, method, endpoint+"/bigquery/v2"+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("bigquery: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	hc := s.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("bigquery: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("bigquery: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}
+		json.Unmarshal(data, &e)
+		return &APIError{StatusCode: resp.StatusCode, Status: e.Error.Status, Message: e.Error.Message}
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("bigquery: decoding response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+func TestParseTableID(t *testing.T) {
+	t.Setenv("GOOGLE_PROJECT_ID", "env-project")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	tests := []struct {
+		in      string
+		want    TableID
+		wantErr bool
+	}{
+		{in: "my-project.ads.daily", want: TableID{"my-project", "ads", "daily"}},
+		{in: "example.com:ads.reports.daily", want: TableID{"example.com:ads", "reports", "daily"}},
+		{in: "ads.daily", want: TableID{"env-project", "ads", "daily"}},
+		{in: "daily", wantErr: true},
+		{in: "p..daily", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTableID(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseTableID(%q): expected an error", tt.in)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTableID(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	fields := []string{"campaign.id", "campaign.status", "segments.date", "metrics.ctr", "metrics.cost_micros", "campaign.labels", "custom.field"}
+	got := Schema(fields, output.Options{Micros: true})
+	want := []string{
+		"campaign_id INTEGER NULLABLE",
+		"campaign_status STRING NULLABLE",
+		"segments_date DATE NULLABLE",
+		"metrics_ctr FLOAT NULLABLE",
+		"metrics_cost FLOAT NULLABLE",
+		"campaign_labels STRING REPEATED",
+		"custom_field STRING NULLABLE",
+	}
+	for i, c := range got {
+		if s := c.Name + " " + c.Type + " " + c.Mode; s != want[i] {
+			t.Errorf("column %d = %s, want %s", i, s, want[i])
+		}
+		if c.Description != fields[i] {
+			t.Errorf("column %d description = %q, want %q", i, c.Description, fields[i])
+		}
+	}
+}
+
+// fakeBigQuery serves the table and insertAll endpoints of one table.
+type fakeBigQuery struct {
+	mu          sync.Mutex
+	table       map[string]any // nil until created
+	failInserts int            // insertAll requests to answer with 503
+	inserts     [][]string     // insert IDs of each insertAll request
+	rows        []map[string]any
+}
+
+func (f *fakeBigQuery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		http.Error(w, `{"error": {"message": "no token"}}`, http.StatusUnauthorized)
+		return
+	}
+	const tables = "/bigquery/v2/projects/p/datasets/d/tables"
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == tables+"/t":
+		if f.table == nil {
+			http.Error(w, `{"error": {"message": "Not found: Table p:d.t", "status": "NOT_FOUND"}}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(f.table)
+	case r.Method == http.MethodPost && r.URL.Path == tables:
+		json.Unmarshal(body, &f.table)
+		w.Write(body)
+	case r.Method == http.MethodPost && r.URL.Path == tables+"/t/insertAll":
+		var req struct {
+			Rows []insertRow `json:"rows"`
+		}
+		json.Unmarshal(body, &req)
+		var ids []string
+		for _, row := range req.Rows {
+			ids = append(ids, row.InsertID)
+		}
+		f.inserts = append(f.inserts, ids)
+		if f.failInserts > 0 {
+			f.failInserts--
+			http.Error(w, `{"error": {"message": "backend error"}}`, http.StatusServiceUnavailable)
+			return
+		}
+		for _, row := range req.Rows {
+			f.rows = append(f.rows, row.JSON)
+		}
+		w.Write([]byte(`{"kind": "bigquery#tableDataInsertAllResponse"}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestSink(t *testing.T, f *fakeBigQuery, fields []string) *Sink {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	s := NewSink(context.Background(), TableID{"p", "d", "t"}, Schema(fields, output.Options{}))
+	s.Endpoint = srv.URL
+	s.Token = func(context.Context) (string, error) { return "test-token", nil }
+	s.BatchSize = 2
+	s.InitialBackoff = 1
+	return s
+}
+
+func TestSinkCreatesTableAndRetries(t *testing.T) {
+	f := &fakeBigQuery{failInserts: 1}
+	fields := []string{"campaign.id", "campaign.name", "metrics.clicks"}
+	s := newTestSink(t, f, fields)
+	rows := []map[string]any{
+		{"campaign": map[string]any{"id": "1", "name": "Brand"}, "metrics": map[string]any{"clicks": "10"}},
+		{"campaign": map[string]any{"id": "2", "name": "Generic"}, "metrics": map[string]any{"clicks": "20"}},
+		{"campaign": map[string]any{"id": "3"}},
+	}
+	if err := output.WriteRows(s, fields, rows, output.Options{RawEnums: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var created struct {
+		Schema struct {
+			Fields []Column `json:"fields"`
+		} `json:"schema"`
+	}
+	data, _ := json.Marshal(f.table)
+	json.Unmarshal(data, &created)
+	if cols := created.Schema.Fields; len(cols) != 3 || cols[2] != (Column{Name: "metrics_clicks", Type: "INTEGER", Mode: "NULLABLE", Description: "metrics.clicks"}) {
+		t.Errorf("created schema = %+v", cols)
+	}
+	// The failed first batch is sent again with the same insert IDs.
+	if len(f.inserts) != 3 || strings.Join(f.inserts[0], ",") != strings.Join(f.inserts[1], ",") {
+		t.Fatalf("insert requests = %v", f.inserts)
+	}
+	if f.inserts[2][0] == f.inserts[1][0] || !strings.HasSuffix(f.inserts[2][0], "-2") {
+		t.Errorf("third row has insert ID %s", f.inserts[2][0])
+	}
+	if s.Inserted() != 3 || len(f.rows) != 3 {
+		t.Fatalf("inserted %d rows, stored %d", s.Inserted(), len(f.rows))
+	}
+	got, _ := json.Marshal(f.rows)
+	want := `[{"campaign_id":1,"campaign_name":"Brand","metrics_clicks":10},{"campaign_id":2,"campaign_name":"Generic","metrics_clicks":20},{"campaign_id":3}]`
+	if string(got) != want {
+		t.Errorf("rows = %s\nwant   %s", got, want)
+	}
+}
+
+func TestSinkIncompatibleTable(t *testing.T) {
+	f := &fakeBigQuery{table: map[string]any{"schema": map[string]any{"fields": []map[string]string{
+		{"name": "campaign_id", "type": "INT64"},
+		{"name": "metrics_clicks", "type": "BOOL"},
+	}}}}
+	tests := []struct {
+		fields  []string
+		wantErr string
+	}{
+		{fields: []string{"campaign.id"}},
+		{fields: []string{"campaign.id", "campaign.name"}, wantErr: "p.d.t has no column campaign_name for campaign.name"},
+		{fields: []string{"metrics.clicks"}, wantErr: "column metrics_clicks of p.d.t is NULLABLE BOOL, not NULLABLE INTEGER"},
+	}
+	for _, tt := range tests {
+		s := newTestSink(t, f, tt.fields)
+		err := s.WriteHeader(tt.fields)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%v: %v", tt.fields, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.fields, tt.wantErr, err)
+		}
+	}
+}
+
+func TestSinkGivesUp(t *testing.T) {
+	f := &fakeBigQuery{failInserts: 10}
+	s := newTestSink(t, f, []string{"campaign.id"})
+	s.MaxAttempts = 3
+	if err := s.WriteHeader([]string{"campaign.id"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteValues([]any{json.Number("1")}); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Flush()
+	if err == nil || !strings.Contains(err.Error(), "HTTP 503: backend error (0 rows were inserted into p.d.t before the failure)") {
+		t.Fatalf("expected the batch to fail, got %v", err)
+	}
+	if len(f.inserts) != 3 {
+		t.Errorf("made %d attempts, want 3", len(f.inserts))
+	}
+}
@@ -0,0 +1,466 @@
+// Package export writes query result rows to local files for offline
+// analysis; its bigquery subpackage streams them into BigQuery instead.
+//
+// A SQLiteWriter is an output.Renderer that stores rows in a table of a
+// SQLite database, which sqlite3, DuckDB (ATTACH 'results.db' (TYPE
+// sqlite)), and most data tools open directly. Each export adds one
+// table, so results of several queries can be joined in one file.
+//
+// # Basic Usage
+//
+//	w, err := export.NewSQLiteWriter("results.db", "campaigns", export.SQLiteSchema(fields, opts))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = output.WriteRows(w, fields, rows, opts)
+package export
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// SQLiteColumn is a column of an exported SQLite table.
+type SQLiteColumn struct {
+	Name  string // the column name, e.g. metrics_clicks
+	Type  string // INTEGER, REAL, or TEXT
+	Field string // the field the column holds, e.g. metrics.clicks
+}
+
+// SQLiteSchema derives the columns for fields, written as opts writes
+// them: one column per field, named after it with dots replaced by
+// underscores, and typed from the catalog. Amounts in micros are REAL
+// with opts.Micros, booleans INTEGER, and dates, enums, and repeated
+// fields TEXT, the last as JSON arrays. Fields the catalog lacks are
+// TEXT.
+func SQLiteSchema(fields []string, opts output.Options) []SQLiteColumn {
+	catalog := opts.Catalog
+	if catalog == nil {
+		catalog = gaql.DefaultCatalog()
+	}
+	cols := make([]SQLiteColumn, len(fields))
+	for i, f := range fields {
+		rc, _ := catalog.Column(f)
+		col := SQLiteColumn{Name: strings.ReplaceAll(opts.Column(f), ".", "_"), Type: "TEXT", Field: f}
+		switch rc.DataType {
+		case "INT64", "INT32", "UINT64", "BOOLEAN":
+			col.Type = "INTEGER"
+		case "DOUBLE", "FLOAT":
+			col.Type = "REAL"
+		}
+		if opts.Micros && output.IsMicros(f) {
+			col.Type = "REAL"
+		}
+		if rc.Repeated {
+			col.Type = "TEXT"
+		}
+		cols[i] = col
+	}
+	return cols
+}
+
+// SQLiteWriter writes result rows into a new table of a SQLite
+// database, creating the database if needed. It implements
+// output.Renderer; rows are written as they arrive, and Flush completes
+// the table and adds it to the schema, so the table appears whole or
+// not at all. A SQLiteWriter is not safe for concurrent use.
+//
+// The database file is never written in place. A new database is built
+// in a temporary file beside it, and an existing one is copied there
+// first; Flush renames the result over the original. Flush fails,
+// leaving the original alone, if another program wrote the database
+// after it was copied, and a program that keeps the database open
+// across the export goes on seeing the old file.
+//
+// Existing databases must use a rollback journal rather than WAL, must
+// not use auto_vacuum, and need room for the new table on the first
+// page of their schema, which holds dozens of tables.
+type SQLiteWriter struct {
+	Path    string
+	Table   string
+	Columns []SQLiteColumn
+
+	f      *os.File
+	tmp    string // the file renamed over Path by Flush
+	page1  []byte // the first page of an existing database
+	stamp  fileStamp
+	p      *pager
+	b      *tableBuilder
+	record []byte
+	rows   int
+	done   bool
+}
+
+// NewSQLiteWriter opens the database at path, or prepares a new one, to
+// receive a table with the given columns. It fails if the table exists.
+func NewSQLiteWriter(path, table string, columns []SQLiteColumn) (*SQLiteWriter, error) {
+	if table == "" || strings.HasPrefix(strings.ToLower(table), "sqlite_") {
+		return nil, fmt.Errorf("export: invalid table name %q", table)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("export: table %s has no columns", table)
+	}
+	w := &SQLiteWriter{Path: path, Table: table, Columns: columns}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) || err == nil && info.Size() == 0:
+		err = w.create(0o644)
+	case err == nil:
+		err = w.open(info.Mode().Perm())
+	}
+	if err != nil {
+		if w.tmp != "" {
+			os.Remove(w.tmp)
+		}
+		return nil, fmt.Errorf("export: %s: %w", path, err)
+	}
+	w.b = newTableBuilder(w.p)
+	return w, nil
+}
+
+// create starts a new database in a temporary file beside path, renamed
+// over it by Flush.
+func (w *SQLiteWriter) create(perm fs.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(w.Path), "."+filepath.Base(w.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	w.f, w.tmp = f, f.Name()
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	w.p = &pager{f: f, pageSize: sqlitePageSize, usable: sqlitePageSize, next: 2}
+	return nil
+}
+
+// fileStamp identifies a version of a database file: its change counter,
+// which SQLite increments on every write transaction, its size, and its
+// modification time.
+type fileStamp struct {
+	counter uint32
+	size    int64
+	modTime int64 // in nanoseconds since the Unix epoch
+}
+
+// stampFile returns the stamp of the database at path.
+func stampFile(path string) (fileStamp, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fileStamp{}, err
+	}
+	header := make([]byte, 100)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{binary.BigEndian.Uint32(header[24:]), info.Size(), info.ModTime().UnixNano()}, nil
+}
+
+// open checks that the existing database can take the new table, and
+// copies it to the temporary file that receives the table.
+func (w *SQLiteWriter) open(perm fs.FileMode) error {
+	src, err := os.Open(w.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	header := make([]byte, 100)
+	if _, err := src.ReadAt(header, 0); err != nil || string(header[:16]) != sqliteMagic {
+		return errors.New("not a SQLite database")
+	}
+	pageSize := int(binary.BigEndian.Uint16(header[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	switch {
+	case header[18] != 1 || header[19] != 1:
+		return errors.New("the database uses WAL journaling; run PRAGMA journal_mode=DELETE on it first")
+	case binary.BigEndian.Uint32(header[52:]) != 0:
+		return errors.New("auto_vacuum databases are not supported")
+	case binary.BigEndian.Uint32(header[56:]) != 1:
+		return errors.New("only UTF-8 databases are supported")
+	}
+	if journal, err := os.Stat(w.Path + "-journal"); err == nil && journal.Size() > 0 {
+		return fmt.Errorf("the database has an unfinished transaction (%s-journal); open it with sqlite3 to recover", w.Path)
+	}
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	w.stamp = fileStamp{binary.BigEndian.Uint32(header[24:]), info.Size(), info.ModTime().UnixNano()}
+
+	if err := w.create(perm); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w.f, src); err != nil {
+		return err
+	}
+	if now, err := stampFile(w.Path); err != nil || now != w.stamp {
+		return errors.New("the database was written while it was being copied; try again")
+	}
+
+	w.p = &pager{f: w.f, pageSize: pageSize, usable: pageSize - int(header[20])}
+	pages := binary.BigEndian.Uint32(header[28:])
+	if pages == 0 || binary.BigEndian.Uint32(header[24:]) != binary.BigEndian.Uint32(header[92:]) {
+		pages = uint32(info.Size() / int64(pageSize))
+	}
+	w.p.next = pages + 1
+
+	if w.page1, err = w.p.read(1); err != nil {
+		return err
+	}
+	if w.page1[100] != pageLeafTable {
+		return errors.New("the schema does not fit on the first page, which is not supported")
+	}
+	for _, cell := range w.schemaCells() {
+		if values, _ := readRecord(cell.payload); len(values) > 1 {
+			if name, ok := values[1].(string); ok && strings.EqualFold(name, w.Table) {
+				return fmt.Errorf("table %s already exists", w.Table)
+			}
+		}
+	}
+	return nil
+}
+
+// schemaCell is a row of the sqlite_schema table on the first page.
+type schemaCell struct {
+	rowid   int64
+	payload []byte // the part of the record stored on the page
+}
+
+func (w *SQLiteWriter) schemaCells() []schemaCell {
+	n := int(binary.BigEndian.Uint16(w.page1[103:]))
+	cells := make([]schemaCell, 0, n)
+	for i := 0; i < n; i++ {
+		off := int(binary.BigEndian.Uint16(w.page1[108+2*i:]))
+		size, m := readVarint(w.page1[off:])
+		rowid, k := readVarint(w.page1[off+m:])
+		start := off + m + k
+		end := min(start+w.p.localPayload(int(size)), len(w.page1))
+		cells = append(cells, schemaCell{rowid: int64(rowid), payload: w.page1[start:end]})
+	}
+	return cells
+}
+
+// Rows returns the number of rows written so far.
+func (w *SQLiteWriter) Rows() int {
+	return w.rows
+}
+
+// WriteHeader checks that there is a column for each result column.
+func (w *SQLiteWriter) WriteHeader(columns []string) error {
+	if len(columns) != len(w.Columns) {
+		return fmt.Errorf("export: %d columns for a table of %d", len(columns), len(w.Columns))
+	}
+	return nil
+}
+
+func (w *SQLiteWriter) WriteRow(values []string) error {
+	vs := make([]any, len(values))
+	for i, v := range values {
+		if v != "" {
+			vs[i] = v
+		}
+	}
+	return w.WriteValues(vs)
+}
+
+// WriteValues writes a row of typed values, as output.Options.Typed
+// returns them, converted to the column types.
+func (w *SQLiteWriter) WriteValues(values []any) error {
+	if w.done {
+		return fmt.Errorf("export: %s: write after Flush", w.Path)
+	}
+	row := make([]any, len(values))
+	for i, v := range values {
+		row[i] = sqliteValue(w.Columns[i].Type, v)
+	}
+	w.record = appendRecord(w.record[:0], row)
+	if err := w.b.add(w.record); err != nil {
+		return fmt.Errorf("export: %s: %w", w.Path, err)
+	}
+	w.rows++
+	return nil
+}
+
+// sqliteValue converts a typed value to the int64, float64, string, or
+// nil stored in a column of the given type, as SQLite's type affinity
+// would on insert.
+func sqliteValue(typ string, v any) any {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		if x {
+			v = int64(1)
+		} else {
+			v = int64(0)
+		}
+	case int:
+		v = int64(x)
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			v = i
+		} else if f, err := x.Float64(); err == nil {
+			v = f
+		} else {
+			v = x.String()
+		}
+	case int64, float64:
+	case string:
+		if typ == "INTEGER" {
+			if i, err := strconv.ParseInt(x, 10, 64); err == nil {
+				return i
+			}
+		}
+		if typ == "INTEGER" || typ == "REAL" {
+			if f, err := strconv.ParseFloat(x, 64); err == nil {
+				return sqliteValue(typ, f)
+			}
+		}
+		return x
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+
+	switch x := v.(type) {
+	case int64:
+		switch typ {
+		case "REAL":
+			return float64(x)
+		case "TEXT":
+			return strconv.FormatInt(x, 10)
+		}
+	case float64:
+		switch {
+		case typ == "INTEGER" && x == math.Trunc(x) && math.Abs(x) < 1<<63:
+			return int64(x)
+		case typ == "TEXT":
+			return strconv.FormatFloat(x, 'f', -1, 64)
+		}
+	}
+	return v
+}
+
+// Flush completes the table and adds it to the database schema. Later
+// calls do nothing.
+func (w *SQLiteWriter) Flush() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	err := w.commit()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && w.page1 != nil {
+		if now, serr := stampFile(w.Path); serr != nil || now != w.stamp {
+			err = errors.New("the database was written by another program during the export; it was left unchanged")
+		}
+	}
+	if err == nil {
+		err = os.Rename(w.tmp, w.Path)
+	}
+	if err != nil {
+		os.Remove(w.tmp)
+	}
+	if err != nil {
+		return fmt.Errorf("export: %s: %w", w.Path, err)
+	}
+	return nil
+}
+
+func (w *SQLiteWriter) commit() error {
+	root, err := w.b.finish()
+	if err != nil {
+		return err
+	}
+	page1 := w.page1
+	rowid := int64(1)
+	if page1 == nil {
+		page1 = make([]byte, w.p.pageSize)
+		w.p.fillPage(page1, 100, pageLeafTable, nil, 0)
+		copy(page1, sqliteMagic)
+		binary.BigEndian.PutUint16(page1[16:], sqlitePageSize)
+		page1[18], page1[19] = 1, 1
+		page1[21], page1[22], page1[23] = 64, 32, 32
+		binary.BigEndian.PutUint32(page1[44:], 4) // schema format
+		binary.BigEndian.PutUint32(page1[56:], 1) // UTF-8
+	} else {
+		for _, c := range w.schemaCells() {
+			rowid = max(rowid, c.rowid+1)
+		}
+	}
+
+	record := appendRecord(nil, []any{"table", w.Table, w.Table, int64(root), w.createSQL()})
+	cell, err := w.p.leafCell(rowid, record)
+	if err != nil {
+		return err
+	}
+	n := int(binary.BigEndian.Uint16(page1[103:]))
+	content := int(binary.BigEndian.Uint16(page1[105:]))
+	if content == 0 {
+		content = 65536
+	}
+	pointers := 108 + 2*n
+	if content-pointers < 2+len(cell) {
+		return errors.New("the first page of the schema has no room for another table")
+	}
+	content -= len(cell)
+	copy(page1[content:], cell)
+	binary.BigEndian.PutUint16(page1[pointers:], uint16(content))
+	binary.BigEndian.PutUint16(page1[103:], uint16(n+1))
+	binary.BigEndian.PutUint16(page1[105:], uint16(content))
+
+	counter := binary.BigEndian.Uint32(page1[24:]) + 1
+	binary.BigEndian.PutUint32(page1[24:], counter)
+	binary.BigEndian.PutUint32(page1[28:], w.p.next-1)
+	binary.BigEndian.PutUint32(page1[40:], binary.BigEndian.Uint32(page1[40:])+1) // schema cookie
+	binary.BigEndian.PutUint32(page1[92:], counter)
+	binary.BigEndian.PutUint32(page1[96:], sqliteVersion)
+
+	if err := w.p.write(1, page1); err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+// createSQL returns the CREATE TABLE statement recorded in the schema.
+func (w *SQLiteWriter) createSQL() string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE " + quoteIdent(w.Table) + " (")
+	for i, c := range w.Columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteIdent(c.Name) + " " + c.Type)
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,264 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	values := []any{nil, int64(0), int64(-1), int64(300), int64(-1 << 40), int64(1 << 62), 2.5, "", strings.Repeat("x", 200)}
+	got, err := readRecord(appendRecord(nil, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("got %v, want %v", got, values)
+	}
+	for _, v := range []uint64{0, 127, 128, 16383, 16384, 1<<56 - 1, 1 << 56, 1<<64 - 1} {
+		if got, n := readVarint(appendVarint(nil, v)); got != v || n != varintLen(v) {
+			t.Errorf("varint %d read back as %d (%d bytes)", v, got, n)
+		}
+	}
+}
+
+func TestSQLiteSchema(t *testing.T) {
+	fields := []string{"campaign.id", "campaign.status", "metrics.ctr", "metrics.cost_micros", "campaign.labels", "segments.date"}
+	var got []string
+	for _, c := range SQLiteSchema(fields, output.Options{Micros: true}) {
+		got = append(got, c.Name+" "+c.Type)
+	}
+	want := []string{"campaign_id INTEGER", "campaign_status TEXT", "metrics_ctr REAL", "metrics_cost REAL", "campaign_labels TEXT", "segments_date TEXT"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// readTable reads the CREATE statement and the rows of a table from the
+// database file at path, walking the b-trees as the file format
+// describes them, so the format is checked without the sqlite3 shell.
+func readTable(t *testing.T, path, table string) (string, [][]any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 100 || string(data[:16]) != sqliteMagic {
+		t.Fatalf("%s: not a SQLite database", path)
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if len(data)%pageSize != 0 || int(binary.BigEndian.Uint32(data[28:])) != len(data)/pageSize {
+		t.Fatalf("%s: %d bytes do not match a database of %d pages", path, len(data), binary.BigEndian.Uint32(data[28:]))
+	}
+	p := &pager{pageSize: pageSize, usable: pageSize - int(data[20])}
+	page := func(pgno uint32) []byte {
+		if pgno < 1 || int(pgno) > len(data)/pageSize {
+			t.Fatalf("page %d is out of range", pgno)
+		}
+		return data[int(pgno-1)*pageSize : int(pgno)*pageSize]
+	}
+
+	var walk func(pgno uint32, last *int64, rows *[][]any)
+	walk = func(pgno uint32, last *int64, rows *[][]any) {
+		pg, offset := page(pgno), 0
+		if pgno == 1 {
+			offset = 100
+		}
+		n := int(binary.BigEndian.Uint16(pg[offset+3:]))
+		switch pg[offset] {
+		case pageInteriorTable:
+			for i := 0; i < n; i++ {
+				cell := pg[binary.BigEndian.Uint16(pg[offset+interiorHeaderSize+2*i:]):]
+				walk(binary.BigEndian.Uint32(cell), last, rows)
+				if key, _ := readVarint(cell[4:]); int64(key) != *last {
+					t.Fatalf("page %d: key %d after rowid %d", pgno, key, *last)
+				}
+			}
+			walk(binary.BigEndian.Uint32(pg[offset+8:]), last, rows)
+		case pageLeafTable:
+			for i := 0; i < n; i++ {
+				cell := pg[binary.BigEndian.Uint16(pg[offset+leafHeaderSize+2*i:]):]
+				size, m := readVarint(cell)
+				rowid, k := readVarint(cell[m:])
+				if int64(rowid) <= *last {
+					t.Fatalf("page %d: rowid %d after %d", pgno, rowid, *last)
+				}
+				*last = int64(rowid)
+				local := p.localPayload(int(size))
+				payload := append([]byte(nil), cell[m+k:m+k+local]...)
+				for next := uint32(0); len(payload) < int(size); {
+					if next == 0 {
+						next = binary.BigEndian.Uint32(cell[m+k+local:])
+					}
+					overflow := page(next)
+					payload = append(payload, overflow[4:4+min(p.usable-4, int(size)-len(payload))]...)
+					next = binary.BigEndian.Uint32(overflow)
+				}
+				values, err := readRecord(payload)
+				if err != nil {
+					t.Fatalf("page %d, rowid %d: %v", pgno, rowid, err)
+				}
+				*rows = append(*rows, values)
+			}
+		default:
+			t.Fatalf("page %d: unexpected page type %#x", pgno, pg[offset])
+		}
+	}
+
+	var schema [][]any
+	walk(1, new(int64), &schema)
+	for _, entry := range schema {
+		if entry[0] == "table" && entry[1] == table {
+			var rows [][]any
+			walk(uint32(entry[3].(int64)), new(int64), &rows)
+			return entry[4].(string), rows
+		}
+	}
+	t.Fatalf("%s: no table %s", path, table)
+	return "", nil
+}
+
+// sqlite3 runs the sqlite3 shell on db, or returns false without it.
+func sqlite3(t *testing.T, db, sql string) (string, bool) {
+	t.Helper()
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return "", false
+	}
+	out, err := exec.Command(bin, db, sql).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3 %q: %v\n%s", sql, err, out)
+	}
+	return strings.TrimSpace(string(out)), true
+}
+
+func TestSQLiteWriter(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "results.db")
+	fields := []string{"campaign.id", "campaign.name", "metrics.clicks", "metrics.ctr", "campaign.labels"}
+	opts := output.Options{RawEnums: true}
+
+	// Enough rows, some long, for overflow pages and two interior levels.
+	var rows []map[string]any
+	for i := 0; i < 60000; i++ {
+		name := "Campaign " + strings.Repeat("n", i%7)
+		if i%5000 == 0 {
+			name = strings.Repeat("long ", 2000)
+		}
+		rows = append(rows, map[string]any{
+			"campaign": map[string]any{"id": json.Number(strconv.Itoa(i + 1)), "name": name, "labels": []any{"customers/1/labels/" + strconv.Itoa(i%3)}},
+			"metrics":  map[string]any{"clicks": strconv.Itoa(i % 100), "ctr": 0.25},
+		})
+	}
+	w, err := NewSQLiteWriter(db, "campaigns", SQLiteSchema(fields, opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := output.WriteRows(w, fields, rows, opts); err != nil {
+		t.Fatal(err)
+	}
+	if w.Rows() != len(rows) {
+		t.Errorf("Rows() = %d", w.Rows())
+	}
+
+	// A second export adds a table to the same database.
+	w, err = NewSQLiteWriter(db, "totals", SQLiteSchema([]string{"metrics.clicks"}, opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := output.WriteRows(w, []string{"metrics.clicks"}, rows[:3], opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSQLiteWriter(db, "Campaigns", SQLiteSchema(fields, opts)); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected an existing table to be refused, got %v", err)
+	}
+
+	sql, got := readTable(t, db, "campaigns")
+	if want := `CREATE TABLE "campaigns" ("campaign_id" INTEGER, "campaign_name" TEXT, "metrics_clicks" INTEGER, "metrics_ctr" REAL, "campaign_labels" TEXT)`; sql != want {
+		t.Errorf("campaigns: %s, want %s", sql, want)
+	}
+	if len(got) != len(rows) {
+		t.Fatalf("campaigns has %d rows, want %d", len(got), len(rows))
+	}
+	if want := []any{int64(3), "Campaign nn", int64(2), 0.25, `["customers/1/labels/2"]`}; !reflect.DeepEqual(got[2], want) {
+		t.Errorf("row 3 = %v, want %v", got[2], want)
+	}
+	if name := got[5000][1].(string); len(name) != 10000 {
+		t.Errorf("long name read back with %d bytes", len(name))
+	}
+	sql, got = readTable(t, db, "totals")
+	if want := `CREATE TABLE "totals" ("metrics_clicks" INTEGER)`; sql != want {
+		t.Errorf("totals: %s, want %s", sql, want)
+	}
+	if want := [][]any{{int64(0)}, {int64(1)}, {int64(2)}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("totals = %v, want %v", got, want)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(db)); len(entries) != 1 {
+		t.Errorf("left %d files behind", len(entries)-1)
+	}
+
+	// Where the sqlite3 shell is installed, SQLite itself checks the file.
+	if got, ok := sqlite3(t, db, "PRAGMA integrity_check"); !ok {
+		t.Log("sqlite3 is not installed; skipping its checks")
+		return
+	} else if got != "ok" {
+		t.Fatalf("integrity_check: %s", got)
+	}
+	tests := []struct{ sql, want string }{
+		{"SELECT count(*), sum(metrics_clicks), typeof(campaign_id), typeof(metrics_ctr) FROM campaigns", "60000|2970000|integer|real"},
+		{"SELECT campaign_id, campaign_name, campaign_labels FROM campaigns WHERE campaign_id = 3", `3|Campaign nn|["customers/1/labels/2"]`},
+		{"SELECT length(campaign_name) FROM campaigns WHERE campaign_id = 5001", "10000"},
+		{"SELECT group_concat(metrics_clicks) FROM totals", "0,1,2"},
+	}
+	for _, tt := range tests {
+		if got, _ := sqlite3(t, db, tt.sql); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteWriterConcurrentWrite(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "results.db")
+	fields := []string{"metrics.clicks"}
+	w, err := NewSQLiteWriter(db, "first", SQLiteSchema(fields, output.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = NewSQLiteWriter(db, "second", SQLiteSchema(fields, output.Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another program commits a transaction, bumping the change counter.
+	before, err := os.ReadFile(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint32(before[24:], binary.BigEndian.Uint32(before[24:])+1)
+	if err := os.WriteFile(db, before, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err == nil || !strings.Contains(err.Error(), "another program") {
+		t.Errorf("expected the export to be refused, got %v", err)
+	}
+	if after, _ := os.ReadFile(db); !bytes.Equal(after, before) {
+		t.Error("the database was modified")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(db)); len(entries) != 1 {
+		t.Errorf("left %d files behind", len(entries)-1)
+	}
+}
@@ -0,0 +1,379 @@
+package export
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// This file writes the SQLite database file format directly, as
+// documented at https://www.sqlite.org/fileformat2.html, so exports need
+// no cgo driver. It supports what SQLiteWriter needs: new databases,
+// and adding one table b-tree to a copy of an existing rollback-journal
+// database whose schema fits on its first page.
+
+const (
+	sqlitePageSize = 4096
+	sqliteMagic    = "SQLite format 3\x00"
+	sqliteVersion  = 3046000 // the SQLite release whose format is written
+
+	pageLeafTable     = 0x0d
+	pageInteriorTable = 0x05
+)
+
+// pager writes pages of a database file.
+type pager struct {
+	f        *os.File
+	pageSize int
+	usable   int    // page size less the reserved bytes at each page end
+	next     uint32 // next page number to allocate
+}
+
+func (p *pager) allocate() uint32 {
+	n := p.next
+	p.next++
+	return n
+}
+
+func (p *pager) write(pgno uint32, page []byte) error {
+	_, err := p.f.WriteAt(page, int64(pgno-1)*int64(p.pageSize))
+	return err
+}
+
+func (p *pager) read(pgno uint32) ([]byte, error) {
+	page := make([]byte, p.pageSize)
+	_, err := p.f.ReadAt(page, int64(pgno-1)*int64(p.pageSize))
+	return page, err
+}
+
+// tableBuilder writes a table b-tree from rows added in rowid order:
+// leaf pages as they fill, and the interior pages above them on finish.
+type tableBuilder struct {
+	p      *pager
+	cells  [][]byte // cells of the leaf being filled
+	used   int      // bytes of the leaf being filled
+	rowid  int64
+	leaves []childPage
+}
+
+// childPage is a page of a b-tree level with the largest rowid under it.
+type childPage struct {
+	pgno   uint32
+	maxKey int64
+}
+
+const leafHeaderSize, interiorHeaderSize = 8, 12
+
+func newTableBuilder(p *pager) *tableBuilder {
+	return &tableBuilder{p: p, used: leafHeaderSize}
+}
+
+// add appends a row holding the given record.
+func (b *tableBuilder) add(record []byte) error {
+	cell, err := b.p.leafCell(b.rowid+1, record)
+	if err != nil {
+		return err
+	}
+	if b.used+2+len(cell) > b.p.usable {
+		if err := b.flushLeaf(); err != nil {
+			return err
+		}
+	}
+	b.rowid++
+	b.cells = append(b.cells, cell)
+	b.used += 2 + len(cell)
+	return nil
+}
+
+func (b *tableBuilder) flushLeaf() error {
+	pgno := b.p.allocate()
+	page := make([]byte, b.p.pageSize)
+	b.p.fillPage(page, 0, pageLeafTable, b.cells, 0)
+	if err := b.p.write(pgno, page); err != nil {
+		return err
+	}
+	b.leaves = append(b.leaves, childPage{pgno, b.rowid})
+	b.cells, b.used = nil, leafHeaderSize
+	return nil
+}
+
+// finish writes the last leaf and the interior pages, and returns the
+// root page number.
+func (b *tableBuilder) finish() (uint32, error) {
+	if len(b.cells) > 0 || len(b.leaves) == 0 {
+		if err := b.flushLeaf(); err != nil {
+			return 0, err
+		}
+	}
+	level := b.leaves
+	for len(level) > 1 {
+		var up []childPage
+		for _, group := range b.p.groupChildren(level) {
+			cells := make([][]byte, len(group)-1)
+			for i, c := range group[:len(group)-1] {
+				cell := binary.BigEndian.AppendUint32(nil, c.pgno)
+				cells[i] = appendVarint(cell, uint64(c.maxKey))
+			}
+			last := group[len(group)-1]
+			pgno := b.p.allocate()
+			page := make([]byte, b.p.pageSize)
+			b.p.fillPage(page, 0, pageInteriorTable, cells, last.pgno)
+			if err := b.p.write(pgno, page); err != nil {
+				return 0, err
+			}
+			up = append(up, childPage{pgno, last.maxKey})
+		}
+		level = up
+	}
+	return level[0].pgno, nil
+}
+
+// groupChildren splits a b-tree level into the children of each page of
+// the level above, filling pages in order. No page is left with a single
+// child, which SQLite would read as an interior page without cells.
+func (p *pager) groupChildren(level []childPage) [][]childPage {
+	var groups [][]childPage
+	start, used := 0, interiorHeaderSize
+	for i, c := range level {
+		size := 2 + 4 + varintLen(uint64(c.maxKey))
+		// The last child of a page is its right-most pointer, which
+		// takes no cell, so a page is full once the next cell would
+		// not fit.
+		if i > start && used+size > p.usable {
+			groups = append(groups, level[start:i])
+			start, used = i, interiorHeaderSize
+		}
+		used += size
+	}
+	groups = append(groups, level[start:])
+	if n := len(groups); n > 1 && len(groups[n-1]) == 1 {
+		prev := groups[n-2]
+		groups[n-2] = prev[:len(prev)-1]
+		groups[n-1] = level[len(level)-2:]
+	}
+	return groups
+}
+
+// fillPage lays out a b-tree page whose header starts at offset: the
+// cell pointer array after the header, and the cells packed at the end.
+func (p *pager) fillPage(page []byte, offset int, kind byte, cells [][]byte, rightMost uint32) {
+	header := leafHeaderSize
+	if kind == pageInteriorTable {
+		header = interiorHeaderSize
+		binary.BigEndian.PutUint32(page[offset+8:], rightMost)
+	}
+	page[offset] = kind
+	content := p.usable
+	for i, cell := range cells {
+		content -= len(cell)
+		copy(page[content:], cell)
+		binary.BigEndian.PutUint16(page[offset+header+2*i:], uint16(content))
+	}
+	binary.BigEndian.PutUint16(page[offset+3:], uint16(len(cells)))
+	binary.BigEndian.PutUint16(page[offset+5:], uint16(content))
+}
+
+// leafCell returns the table leaf cell of a row, writing the part of a
+// long record that does not fit on the page to overflow pages.
+func (p *pager) leafCell(rowid int64, record []byte) ([]byte, error) {
+	cell := appendVarint(nil, uint64(len(record)))
+	cell = appendVarint(cell, uint64(rowid))
+	local := p.localPayload(len(record))
+	cell = append(cell, record[:local]...)
+	if local == len(record) {
+		return cell, nil
+	}
+
+	rest := record[local:]
+	chunk := p.usable - 4
+	n := (len(rest) + chunk - 1) / chunk
+	first := p.next
+	p.next += uint32(n)
+	for i := 0; i < n; i++ {
+		page := make([]byte, p.pageSize)
+		if i < n-1 {
+			binary.BigEndian.PutUint32(page, first+uint32(i)+1)
+		}
+		copy(page[4:], rest[min(i*chunk, len(rest)):min((i+1)*chunk, len(rest))])
+		if err := p.write(first+uint32(i), page); err != nil {
+			return nil, err
+		}
+	}
+	return binary.BigEndian.AppendUint32(cell, first), nil
+}
+
+// localPayload returns how many bytes of a payload of size n stay in a
+// table leaf cell, by the rule of the file format.
+func (p *pager) localPayload(n int) int {
+	maxLocal := p.usable - 35
+	if n <= maxLocal {
+		return n
+	}
+	minLocal := (p.usable-12)*32/255 - 23
+	k := minLocal + (n-minLocal)%(p.usable-4)
+	if k <= maxLocal {
+		return k
+	}
+	return minLocal
+}
+
+// appendRecord appends the record format of values: a header of serial
+// types followed by the values. Values are nil, int64, float64, or
+// string.
+func appendRecord(dst []byte, values []any) []byte {
+	var types, body []byte
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			types = appendVarint(types, 0)
+		case int64:
+			typ, size := intSerialType(v)
+			types = appendVarint(types, typ)
+			for i := size - 1; i >= 0; i-- {
+				body = append(body, byte(v>>(8*i)))
+			}
+		case float64:
+			types = appendVarint(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			types = appendVarint(types, uint64(13+2*len(v)))
+			body = append(body, v...)
+		default:
+			panic(fmt.Sprintf("export: cannot store %T in a SQLite record", v))
+		}
+	}
+	// The header size counts itself, so its varint length may grow it.
+	size := len(types) + 1
+	for size != len(types)+varintLen(uint64(size)) {
+		size = len(types) + varintLen(uint64(size))
+	}
+	dst = appendVarint(dst, uint64(size))
+	dst = append(dst, types...)
+	return append(dst, body...)
+}
+
+// intSerialType returns the serial type and byte size of the smallest
+// integer encoding of v. Types 8 and 9, which need schema format 4, are
+// not used, so records suit databases in any format.
+func intSerialType(v int64) (uint64, int) {
+	switch {
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return 1, 1
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2, 2
+	case v >= -1<<23 && v < 1<<23:
+		return 3, 3
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4, 4
+	case v >= -1<<47 && v < 1<<47:
+		return 5, 6
+	default:
+		return 6, 8
+	}
+}
+
+// readRecord decodes the record at the start of b. When b holds only
+// the part of a record stored on its page, the values that extend past
+// it are left out and an error returned with the values before them.
+func readRecord(b []byte) ([]any, error) {
+	size, n := readVarint(b)
+	if n == 0 || int(size) > len(b) {
+		return nil, errors.New("record header out of range")
+	}
+	var values []any
+	body := b[size:]
+	for pos := n; pos < int(size); {
+		typ, m := readVarint(b[pos:int(size)])
+		if m == 0 {
+			return nil, errors.New("truncated record header")
+		}
+		pos += m
+		var width int
+		switch {
+		case typ <= 4:
+			width = int(typ)
+		case typ == 5:
+			width = 6
+		case typ == 6 || typ == 7:
+			width = 8
+		case typ == 8 || typ == 9:
+		case typ >= 12:
+			width = int(typ-12) / 2
+		default:
+			return nil, fmt.Errorf("serial type %d", typ)
+		}
+		if width > len(body) {
+			return values, errors.New("record extends past the page")
+		}
+		raw := body[:width]
+		body = body[width:]
+		switch {
+		case typ == 0:
+			values = append(values, nil)
+		case typ <= 6:
+			var v int64
+			for _, c := range raw {
+				v = v<<8 | int64(c)
+			}
+			if width < 8 && raw[0]&0x80 != 0 {
+				v -= 1 << (8 * width)
+			}
+			values = append(values, v)
+		case typ == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(raw)))
+		case typ == 8 || typ == 9:
+			values = append(values, int64(typ-8))
+		case typ%2 == 1:
+			values = append(values, string(raw))
+		default:
+			values = append(values, raw)
+		}
+	}
+	return values, nil
+}
+
+// appendVarint appends v in SQLite's big-endian variable-length integer
+// encoding: seven bits per byte with the high bit set on all but the
+// last, and all eight bits of a ninth byte.
+func appendVarint(dst []byte, v uint64) []byte {
+	if v > 1<<56-1 {
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(dst, buf[:]...)
+	}
+	var buf [8]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(dst, buf[i:]...)
+}
+
+func varintLen(v uint64) int {
+	return len(appendVarint(nil, v))
+}
+
+// readVarint decodes a varint, returning its length, or 0 when b ends
+// first.
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
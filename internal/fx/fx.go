@@ -0,0 +1,239 @@
+// Package fx converts cost metrics between currencies.
+//
+// Google Ads reports amounts in each account's own currency, so totals
+// across accounts mix currencies unless they are converted first. A
+// Provider supplies exchange rates, either from a static Table or from
+// an HTTP endpoint, and a Converter applies them to result rows.
+//
+// # Basic Usage
+//
+//	conv := fx.NewConverter(fx.NewHTTPProvider(fx.DefaultRatesURL), "USD")
+//	for _, row := range rows {
+//		if err := conv.ConvertRow(ctx, row, fields, "EUR"); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// DefaultRatesURL serves the latest European Central Bank reference
+// rates in the JSON form LoadTable reads.
+const DefaultRatesURL = "https://api.frankfurter.app/latest"
+
+// valueFields are conversion values, amounts in units of the account
+// currency rather than micros.
+var valueFields = map[string]bool{
+	"metrics.all_conversions_value":                         true,
+	"metrics.all_conversions_value_by_conversion_date":      true,
+	"metrics.conversions_value":                             true,
+	"metrics.conversions_value_by_conversion_date":          true,
+	"metrics.current_model_attributed_conversions_value":    true,
+	"metrics.value_per_all_conversions":                     true,
+	"metrics.value_per_all_conversions_by_conversion_date":  true,
+	"metrics.value_per_conversion":                          true,
+	"metrics.value_per_conversions_by_conversion_date":      true,
+	"metrics.value_per_current_model_attributed_conversion": true,
+}
+
+// CurrencyQuery fetches an account's currency.
+const CurrencyQuery = "SELECT customer.currency_code FROM customer LIMIT 1"
+
+// Provider returns the rate to convert from one currency to another:
+// an amount in from times the rate is the amount in to.
+type Provider interface {
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// Table holds exchange rates against a base currency: one unit of Base
+// buys Rates[code] units of code.
+type Table struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// LoadTable reads a table in the JSON form
+// {"base": "USD", "rates": {"EUR": 0.92, "JPY": 149.5}}.
+func LoadTable(r io.Reader) (*Table, error) {
+	var t Table
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("fx: reading rates: %w", err)
+	}
+	if t.Base == "" {
+		return nil, fmt.Errorf("fx: reading rates: no base currency")
+	}
+	return &t, nil
+}
+
+// LoadTableFile reads a table from a JSON file.
+func LoadTableFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fx: %w", err)
+	}
+	defer f.Close()
+	return LoadTable(f)
+}
+
+// Rate implements Provider, crossing through the base currency when
+// neither side is the base.
+func (t *Table) Rate(_ context.Context, from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+	perBase := func(code string) (float64, bool) {
+		if code == strings.ToUpper(t.Base) {
+			return 1, true
+		}
+		r, ok := t.Rates[code]
+		return r, ok && r > 0
+	}
+	f, ok := perBase(from)
+	if !ok {
+		return 0, fmt.Errorf("fx: no rate for %s", from)
+	}
+	tt, ok := perBase(to)
+	if !ok {
+		return 0, fmt.Errorf("fx: no rate for %s", to)
+	}
+	return tt / f, nil
+}
+
+// HTTPProvider fetches a rate table from a URL on first use and keeps
+// it for the life of the provider. It is safe for concurrent use.
+type HTTPProvider struct {
+	URL    string
+	Client *http.Client
+
+	mu    sync.Mutex
+	table *Table
+}
+
+// NewHTTPProvider returns a provider reading rates from url.
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{URL: url, Client: http.DefaultClient}
+}
+
+// Rate implements Provider.
+func (p *HTTPProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	t, err := p.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return t.Rate(ctx, from, to)
+}
+
+func (p *HTTPProvider) load(ctx context.Context) (*Table, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.table != nil {
+		return p.table, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fx: %w", err)
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fx: fetching rates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fx: fetching rates: %s", resp.Status)
+	}
+	t, err := LoadTable(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	p.table = t
+	return t, nil
+}
+
+// Converter converts amounts into one target currency, caching rates per
+// source currency. It is safe for concurrent use.
+type Converter struct {
+	provider Provider
+	to       string
+
+	mu    sync.Mutex
+	rates map[string]float64
+}
+
+// NewConverter returns a converter into currency to.
+func NewConverter(p Provider, to string) *Converter {
+	return &Converter{provider: p, to: strings.ToUpper(to), rates: make(map[string]float64)}
+}
+
+// Currency returns the target currency.
+func (c *Converter) Currency() string {
+	return c.to
+}
+
+func (c *Converter) rate(ctx context.Context, from string) (float64, error) {
+	from = strings.ToUpper(from)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.rates[from]; ok {
+		return r, nil
+	}
+	r, err := c.provider.Rate(ctx, from, c.to)
+	if err != nil {
+		return 0, err
+	}
+	c.rates[from] = r
+	return r, nil
+}
+
+// ConvertRow converts the money fields among fields in row from currency
+// from, in place: amounts in micros and conversion values. INT64 micros
+// stay integer strings; doubles stay numbers. A selected
+// customer.currency_code is set to the target.
+func (c *Converter) ConvertRow(ctx context.Context, row map[string]any, fields []string, from string) error {
+	r, err := c.rate(ctx, from)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch {
+		case output.IsMicros(f):
+			v, ok := output.Value(row, f)
+			if !ok {
+				continue
+			}
+			switch v := v.(type) {
+			case string:
+				n, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					continue
+				}
+				output.SetValue(row, f, strconv.FormatInt(int64(math.Round(n*r)), 10))
+			case float64:
+				output.SetValue(row, f, v*r)
+			}
+		case valueFields[f]:
+			if v, ok := output.Value(row, f); ok {
+				if n, ok := v.(float64); ok {
+					output.SetValue(row, f, n*r)
+				}
+			}
+		case f == "customer.currency_code":
+			output.SetValue(row, f, c.to)
+		}
+	}
+	return nil
+}
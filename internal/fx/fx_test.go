@@ -0,0 +1,91 @@
+package fx
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const ratesJSON = `{"amount": 1.0, "base": "EUR", "date": "2026-03-02", "rates": {"USD": 1.25, "GBP": 0.8, "JPY": 160}}`
+
+func TestTableRate(t *testing.T) {
+	table, err := LoadTable(strings.NewReader(ratesJSON))
+	if err != nil {
+		t.Fatalf("LoadTable: %v", err)
+	}
+
+	tests := []struct {
+		from, to string
+		want     float64
+		wantErr  bool
+	}{
+		{"EUR", "USD", 1.25, false},
+		{"USD", "EUR", 0.8, false},
+		{"GBP", "USD", 1.5625, false},
+		{"usd", "usd", 1, false},
+		{"CHF", "USD", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := table.Rate(context.Background(), tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Rate(%s, %s) error = %v", tt.from, tt.to, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Rate(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if _, err := LoadTable(strings.NewReader(`{"rates": {}}`)); err == nil {
+		t.Error("expected error for a table without a base")
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write([]byte(ratesJSON))
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider(srv.URL)
+	for i := 0; i < 2; i++ {
+		if r, err := p.Rate(context.Background(), "EUR", "JPY"); err != nil || r != 160 {
+			t.Fatalf("Rate = %v, %v", r, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("expected one fetch, got %d", fetches)
+	}
+}
+
+func TestConvertRow(t *testing.T) {
+	table, _ := LoadTable(strings.NewReader(ratesJSON))
+	conv := NewConverter(table, "usd")
+
+	row := map[string]any{
+		"customer": map[string]any{"currencyCode": "EUR"},
+		"campaign": map[string]any{"id": "1"},
+		"metrics":  map[string]any{"costMicros": "1000000", "averageCpc": 200000.0, "clicks": "5", "conversionsValue": 40.0},
+	}
+	fields := []string{"customer.currency_code", "campaign.id", "metrics.cost_micros", "metrics.average_cpc", "metrics.clicks", "metrics.conversions_value"}
+	if err := conv.ConvertRow(context.Background(), row, fields, "EUR"); err != nil {
+		t.Fatalf("ConvertRow: %v", err)
+	}
+
+	m := row["metrics"].(map[string]any)
+	if m["costMicros"] != "1250000" || m["averageCpc"] != 250000.0 || m["clicks"] != "5" || m["conversionsValue"] != 50.0 {
+		t.Errorf("unexpected metrics %v", m)
+	}
+	if cur := row["customer"].(map[string]any)["currencyCode"]; cur != "USD" {
+		t.Errorf("currency_code = %v", cur)
+	}
+
+	if err := conv.ConvertRow(context.Background(), row, fields, "CHF"); err == nil {
+		t.Error("expected error for unknown currency")
+	}
+}
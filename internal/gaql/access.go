@@ -0,0 +1,128 @@
+package gaql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AccessPolicy restricts what queries may read, for deployments shared
+// by people who should not see everything, such as analysts using the
+// MCP server. A Validator with an Access policy rejects queries that
+// touch denied resources or fields and caps the rest.
+//
+// Field entries match a field or, as a prefix, the fields under it:
+// user_list matches user_list.name, and customer_user_access.email_address
+// only itself.
+type AccessPolicy struct {
+	// AllowResources, when not empty, are the only resources queries
+	// may select FROM.
+	AllowResources []string
+
+	// DenyResources may not be selected FROM, nor may their fields be
+	// selected through another resource.
+	DenyResources []string
+
+	// DenyFields may not appear anywhere in a query.
+	DenyFields []string
+
+	// RedactFields may be selected, but their values are hidden in the
+	// results; see Redacts. They may not be filtered or sorted on.
+	RedactFields []string
+
+	// MaxLimit and MaxDays cap the LIMIT and segments.date range of
+	// queries, as ApplyGuardrails does. Zero leaves them alone.
+	MaxLimit int
+	MaxDays  int
+}
+
+// Redacts reports whether the values of field are hidden.
+func (p *AccessPolicy) Redacts(field string) bool {
+	return p != nil && matchField(p.RedactFields, field)
+}
+
+// matchField reports whether field is one of entries or under one.
+func matchField(entries []string, field string) bool {
+	for _, e := range entries {
+		if field == e || strings.HasPrefix(field, e+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAccess checks q against v.Access and applies its caps,
+// recording them in diags when it is non-nil.
+func (v *Validator) validateAccess(q *Query, diags *[]Diagnostic) error {
+	p := v.Access
+	if p == nil {
+		return nil
+	}
+	if len(p.AllowResources) > 0 && !slices.Contains(p.AllowResources, q.From) {
+		return &ValidationError{
+			Message: fmt.Sprintf("resource %s is not allowed by the access policy (allowed: %s)", q.From, strings.Join(p.AllowResources, ", ")),
+			Field:   "FROM",
+			Span:    q.FromSpan,
+		}
+	}
+	if slices.Contains(p.DenyResources, q.From) {
+		return &ValidationError{
+			Message: fmt.Sprintf("resource %s is denied by the access policy", q.From),
+			Field:   "FROM",
+			Span:    q.FromSpan,
+		}
+	}
+	check := func(name string, span Span) error {
+		resource, _, _ := strings.Cut(name, ".")
+		if slices.Contains(p.DenyResources, resource) || matchField(p.DenyFields, name) {
+			return &ValidationError{
+				Message: fmt.Sprintf("field %s is denied by the access policy", name),
+				Field:   name,
+				Span:    span,
+			}
+		}
+		return nil
+	}
+	for _, f := range q.Select {
+		if err := check(f.Name, f.Span); err != nil {
+			return err
+		}
+	}
+	// Filtering or sorting on a redacted field would reveal its values,
+	// a LIKE prefix at a time.
+	redacted := func(name, clause string, span Span) error {
+		if !p.Redacts(name) {
+			return nil
+		}
+		return &ValidationError{
+			Message: fmt.Sprintf("field %s is redacted by the access policy and cannot be used in %s", name, clause),
+			Field:   name,
+			Span:    span,
+		}
+	}
+	for _, c := range q.Where {
+		if err := check(c.Field, c.FieldSpan); err != nil {
+			return err
+		}
+		if err := redacted(c.Field, "WHERE", c.FieldSpan); err != nil {
+			return err
+		}
+	}
+	for _, o := range q.OrderBy {
+		if err := check(o.Field, o.Span); err != nil {
+			return err
+		}
+		if err := redacted(o.Field, "ORDER BY", o.Span); err != nil {
+			return err
+		}
+	}
+
+	capped := ApplyGuardrails(q, GuardrailPolicy{MaxLimit: p.MaxLimit, MaxDays: p.MaxDays})
+	if diags != nil {
+		for _, d := range capped {
+			d.Message += " (access policy)"
+			*diags = append(*diags, d)
+		}
+	}
+	return nil
+}
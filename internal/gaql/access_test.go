@@ -0,0 +1,92 @@
+package gaql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAccess(t *testing.T) {
+	p := &AccessPolicy{
+		DenyResources: []string{"change_event", "customer_user_access"},
+		DenyFields:    []string{"customer.descriptive_name"},
+		RedactFields:  []string{"user_list.name"},
+		MaxLimit:      500,
+	}
+	tests := []struct {
+		name    string
+		input   string
+		policy  *AccessPolicy
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "allowed query is capped",
+			input:  "SELECT campaign.id FROM campaign",
+			policy: p,
+			want:   "SELECT campaign.id FROM campaign LIMIT 500",
+		},
+		{
+			name:    "denied resource",
+			input:   "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time DURING LAST_7_DAYS LIMIT 10",
+			policy:  p,
+			wantErr: "resource change_event is denied by the access policy",
+		},
+		{
+			name:    "denied field",
+			input:   "SELECT campaign.id FROM campaign ORDER BY customer.descriptive_name",
+			policy:  p,
+			wantErr: "field customer.descriptive_name is denied",
+		},
+		{
+			name:    "resource outside the allow list",
+			input:   "SELECT ad_group.id FROM ad_group",
+			policy:  &AccessPolicy{AllowResources: []string{"campaign", "keyword_view"}},
+			wantErr: "resource ad_group is not allowed by the access policy (allowed: campaign, keyword_view)",
+		},
+		{
+			name:   "redacted fields may be selected",
+			input:  "SELECT user_list.name FROM user_list LIMIT 5",
+			policy: p,
+			want:   "SELECT user_list.name FROM user_list LIMIT 5",
+		},
+		{
+			name:    "redacted fields may not be filtered on",
+			input:   "SELECT user_list.id FROM user_list WHERE user_list.name LIKE 'Secret%'",
+			policy:  p,
+			wantErr: "field user_list.name is redacted by the access policy and cannot be used in WHERE",
+		},
+		{
+			name:    "redacted fields may not be sorted on",
+			input:   "SELECT user_list.id FROM user_list ORDER BY user_list.name",
+			policy:  p,
+			wantErr: "field user_list.name is redacted by the access policy and cannot be used in ORDER BY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := NewValidator()
+			v.Access = tt.policy
+			q, _, err = v.Prepare(q)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+		})
+	}
+
+	if !p.Redacts("user_list.name") || p.Redacts("user_list.id") || (*AccessPolicy)(nil).Redacts("user_list.name") {
+		t.Error("Redacts matched the wrong fields")
+	}
+}
@@ -3,6 +3,8 @@ package gaql
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -14,11 +16,20 @@ type Query struct {
 	OrderBy    []Ordering
 	Limit      int
 	Parameters map[string]string
+
+	// Clause spans, from the keyword to the end of the clause.
+	SelectSpan     Span
+	FromSpan       Span
+	WhereSpan      Span
+	OrderBySpan    Span
+	LimitSpan      Span
+	ParametersSpan Span
 }
 
 // Field represents a field reference (e.g., campaign.id, metrics.clicks).
 type Field struct {
 	Name string
+	Span Span
 }
 
 // Condition represents a WHERE clause condition.
@@ -26,12 +37,23 @@ type Condition struct {
 	Field    string
 	Operator Operator
 	Value    Value
+
+	Span      Span // the whole condition
+	FieldSpan Span // the field name only
+	ValueSpan Span // the value only; zero for IS NULL and IS NOT NULL
+
+	// DataType is the catalog data type of Field, e.g. INT64, ENUM, or
+	// DATE, annotated by Validator so later stages agree on how to read
+	// the value. Empty before validation and for fields the catalog
+	// does not know.
+	DataType string
 }
 
 // Ordering represents an ORDER BY clause item.
 type Ordering struct {
 	Field     string
 	Direction Direction
+	Span      Span
 }
 
 // Direction represents sort direction.
@@ -160,8 +182,11 @@ const (
 	DateRangeCustom // For BETWEEN date ranges
 )
 
-// DateRangeKeywords maps string keywords to DateRange values.
-var DateRangeKeywords = map[string]DateRange{
+// dateRangeKeywords maps string keywords to DateRange values. It holds
+// every keyword registered for any API version and is guarded by
+// dateRangeMu; LookupDateRange and DateRangeKeywordsFor read it and
+// RegisterDateRange extends it.
+var dateRangeKeywords = map[string]DateRange{
 	"TODAY":               DateRangeToday,
 	"YESTERDAY":           DateRangeYesterday,
 	"LAST_7_DAYS":         DateRangeLast7Days,
@@ -176,13 +201,13 @@ var DateRangeKeywords = map[string]DateRange{
 	"LAST_BUSINESS_WEEK":  DateRangeLastBusinessWeek,
 }
 
-func (d DateRange) String() string {
-	for k, v := range DateRangeKeywords {
-		if v == d {
-			return k
-		}
+// FieldNames returns the names of the SELECT fields in order.
+func (q *Query) FieldNames() []string {
+	names := make([]string, len(q.Select))
+	for i, f := range q.Select {
+		names[i] = f.Name
 	}
-	return "CUSTOM"
+	return names
 }
 
 // String returns the GAQL query as a string.
@@ -209,11 +234,7 @@ func (q *Query) String() string {
 			if i > 0 {
 				sb.WriteString(" AND ")
 			}
-			sb.WriteString(c.Field)
-			sb.WriteString(" ")
-			sb.WriteString(c.Operator.String())
-			sb.WriteString(" ")
-			sb.WriteString(c.Value.String())
+			sb.WriteString(c.String())
 		}
 	}
 
@@ -236,31 +257,51 @@ func (q *Query) String() string {
 		sb.WriteString(fmt.Sprintf(" LIMIT %d", q.Limit))
 	}
 
-	// PARAMETERS
+	// PARAMETERS (sorted so the output is deterministic)
 	if len(q.Parameters) > 0 {
 		sb.WriteString(" PARAMETERS ")
-		first := true
-		for k, v := range q.Parameters {
-			if !first {
+		keys := make([]string, 0, len(q.Parameters))
+		for k := range q.Parameters {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i > 0 {
 				sb.WriteString(", ")
 			}
-			sb.WriteString(fmt.Sprintf("%s = %s", k, v))
-			first = false
+			sb.WriteString(fmt.Sprintf("%s = %s", k, q.Parameters[k]))
 		}
 	}
 
 	return sb.String()
 }
 
+// String returns the condition in GAQL syntax.
+func (c Condition) String() string {
+	switch c.Operator {
+	case OpIsNull, OpIsNotNull:
+		return c.Field + " " + c.Operator.String()
+	case OpBetween:
+		if len(c.Value.List) == 2 {
+			return fmt.Sprintf("%s BETWEEN %s AND %s", c.Field, quoteLiteral(c.Value.List[0]), quoteLiteral(c.Value.List[1]))
+		}
+	}
+	return c.Field + " " + c.Operator.String() + " " + c.Value.String()
+}
+
 // String returns the value as a string representation.
 func (v Value) String() string {
 	switch v.Type {
 	case ValueString:
-		return fmt.Sprintf("'%s'", v.Str)
+		return QuoteString(v.Str)
 	case ValueNumber:
-		return fmt.Sprintf("%v", v.Number)
+		return strconv.FormatFloat(v.Number, 'f', -1, 64)
 	case ValueList:
-		return fmt.Sprintf("(%s)", strings.Join(v.List, ", "))
+		items := make([]string, len(v.List))
+		for i, item := range v.List {
+			items[i] = quoteLiteral(item)
+		}
+		return fmt.Sprintf("(%s)", strings.Join(items, ", "))
 	case ValueDateRange:
 		return v.DateRange.String()
 	case ValueNull:
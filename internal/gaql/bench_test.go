@@ -0,0 +1,95 @@
+package gaql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchQueries are representative inputs: a short exploratory query and
+// a wide report query near the size of generated reports.
+var benchQueries = []struct {
+	name  string
+	query string
+}{
+	{"small", "SELECT campaign.id, campaign.name, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS LIMIT 10"},
+	{"large", largeQuery()},
+}
+
+// largeQuery selects every campaign attribute and metric of the default
+// catalog, filters on several kinds of condition, and orders the rows.
+func largeQuery() string {
+	var fields []string
+	for _, f := range DefaultCatalog().Fields() {
+		if f.Selectable && (strings.HasPrefix(f.Name, "campaign.") || f.Category == "METRIC") {
+			fields = append(fields, f.Name)
+		}
+	}
+	return fmt.Sprintf("SELECT %s, segments.date, segments.device FROM campaign"+
+		" WHERE campaign.status IN ('ENABLED', 'PAUSED') AND campaign.name LIKE '%%brand%%'"+
+		" AND metrics.impressions > 100 AND segments.date BETWEEN '2026-01-01' AND '2026-03-31'"+
+		" ORDER BY metrics.clicks DESC, campaign.id LIMIT 1000", strings.Join(fields, ", "))
+}
+
+func BenchmarkLex(b *testing.B) {
+	for _, bq := range benchQueries {
+		b.Run(bq.name, func(b *testing.B) {
+			b.SetBytes(int64(len(bq.query)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := NewLexer(bq.query).Tokenize(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, bq := range benchQueries {
+		b.Run(bq.name, func(b *testing.B) {
+			b.SetBytes(int64(len(bq.query)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := Parse(bq.query); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCheck validates parsed queries; each iteration checks a fresh
+// clone, since Check annotates the query.
+func BenchmarkCheck(b *testing.B) {
+	v := NewValidator()
+	for _, bq := range benchQueries {
+		b.Run(bq.name, func(b *testing.B) {
+			q, err := Parse(bq.query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := v.Check(q.Clone()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	for _, bq := range benchQueries {
+		b.Run(bq.name, func(b *testing.B) {
+			q, err := Parse(bq.query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = q.String()
+			}
+		})
+	}
+}
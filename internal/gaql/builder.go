@@ -0,0 +1,125 @@
+package gaql
+
+// Builder composes a Query in code instead of string concatenation, so
+// values are quoted correctly and the result can be validated and
+// rewritten like a parsed query.
+//
+//	q := gaql.Select("campaign.id", "campaign.name", "metrics.clicks").
+//		From("campaign").
+//		Where("campaign.status", gaql.OpEq, gaql.StringValue("ENABLED")).
+//		During(gaql.DateRangeLast7Days).
+//		OrderBy("metrics.clicks", gaql.Desc).
+//		Limit(10).
+//		Query()
+type Builder struct {
+	q Query
+}
+
+// Select starts a query selecting fields.
+func Select(fields ...string) *Builder {
+	b := &Builder{}
+	return b.Select(fields...)
+}
+
+// Select appends fields to the SELECT clause, skipping duplicates.
+func (b *Builder) Select(fields ...string) *Builder {
+	for _, name := range fields {
+		if !b.selects(name) {
+			b.q.Select = append(b.q.Select, Field{Name: name})
+		}
+	}
+	return b
+}
+
+func (b *Builder) selects(name string) bool {
+	for _, f := range b.q.Select {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// From sets the resource.
+func (b *Builder) From(resource string) *Builder {
+	b.q.From = resource
+	return b
+}
+
+// Where appends a condition to the WHERE clause.
+func (b *Builder) Where(field string, op Operator, v Value) *Builder {
+	b.q.Where = append(b.q.Where, Condition{Field: field, Operator: op, Value: v})
+	return b
+}
+
+// During restricts segments.date to a predefined date range.
+func (b *Builder) During(dr DateRange) *Builder {
+	return b.Where("segments.date", OpDuring, Value{Type: ValueDateRange, DateRange: dr})
+}
+
+// Between restricts segments.date to start..end inclusive, both in
+// YYYY-MM-DD form.
+func (b *Builder) Between(start, end string) *Builder {
+	return b.Where("segments.date", OpBetween, ListValue(start, end))
+}
+
+// OrderBy appends an ORDER BY item. Ordering by a field already ordered
+// by changes its direction instead, keeping its precedence.
+func (b *Builder) OrderBy(field string, dir Direction) *Builder {
+	for i, o := range b.q.OrderBy {
+		if o.Field == field {
+			b.q.OrderBy[i].Direction = dir
+			return b
+		}
+	}
+	b.q.OrderBy = append(b.q.OrderBy, Ordering{Field: field, Direction: dir})
+	return b
+}
+
+// OrderByDesc appends an ORDER BY item in descending order, as for the
+// top rows by a metric.
+func (b *Builder) OrderByDesc(field string) *Builder {
+	return b.OrderBy(field, Desc)
+}
+
+// ThenBy appends an ORDER BY item breaking ties of the ones before it;
+// it is OrderBy, named for reading chains of keys:
+//
+//	gaql.Select("campaign.name", "segments.date", "metrics.clicks").
+//		From("campaign").
+//		OrderByDesc("segments.date").
+//		ThenBy("metrics.clicks", gaql.Desc)
+func (b *Builder) ThenBy(field string, dir Direction) *Builder {
+	return b.OrderBy(field, dir)
+}
+
+// Limit sets the LIMIT clause; 0 removes it.
+func (b *Builder) Limit(n int) *Builder {
+	b.q.Limit = n
+	return b
+}
+
+// Query returns a copy of the query built so far.
+func (b *Builder) Query() *Query {
+	return b.q.Clone()
+}
+
+// String returns the GAQL text of the query built so far.
+func (b *Builder) String() string {
+	return b.q.String()
+}
+
+// StringValue returns a string literal value.
+func StringValue(s string) Value {
+	return Value{Type: ValueString, Str: s}
+}
+
+// NumberValue returns a numeric literal value.
+func NumberValue(n float64) Value {
+	return Value{Type: ValueNumber, Number: n}
+}
+
+// ListValue returns a list value for IN, CONTAINS, and BETWEEN.
+func ListValue(items ...string) Value {
+	return Value{Type: ValueList, List: items}
+}
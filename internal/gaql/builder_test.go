@@ -0,0 +1,69 @@
+package gaql
+
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *Builder
+		want string
+	}{
+		{
+			name: "full query",
+			b: Select("campaign.id", "campaign.name", "metrics.clicks").
+				From("campaign").
+				Where("campaign.status", OpIn, ListValue("ENABLED", "PAUSED")).
+				Where("campaign.name", OpLike, StringValue("%Brand's%")).
+				During(DateRangeLast7Days).
+				OrderBy("metrics.clicks", Desc).
+				Limit(10),
+			want: "SELECT campaign.id, campaign.name, metrics.clicks FROM campaign WHERE campaign.status IN ('ENABLED', 'PAUSED') AND campaign.name LIKE '%Brand\\'s%' AND segments.date DURING LAST_7_DAYS ORDER BY metrics.clicks DESC LIMIT 10",
+		},
+		{
+			name: "duplicate select fields and between",
+			b: Select("ad_group.id").Select("ad_group.id", "metrics.cost_micros").
+				From("ad_group").
+				Where("metrics.cost_micros", OpGt, NumberValue(0)).
+				Between("2026-01-01", "2026-01-31"),
+			want: "SELECT ad_group.id, metrics.cost_micros FROM ad_group WHERE metrics.cost_micros > 0 AND segments.date BETWEEN '2026-01-01' AND '2026-01-31'",
+		},
+		{
+			name: "ordering keys",
+			b: Select("campaign.name", "segments.date", "metrics.clicks").
+				From("campaign").
+				During(DateRangeLast7Days).
+				OrderByDesc("segments.date").
+				ThenBy("metrics.clicks", Desc).
+				ThenBy("campaign.name", Asc),
+			want: "SELECT campaign.name, segments.date, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS ORDER BY segments.date DESC, metrics.clicks DESC, campaign.name",
+		},
+		{
+			name: "ordering by a field again changes its direction",
+			b: Select("campaign.id").
+				From("campaign").
+				OrderBy("campaign.name", Asc).
+				ThenBy("campaign.id", Asc).
+				OrderByDesc("campaign.name"),
+			want: "SELECT campaign.id FROM campaign ORDER BY campaign.name DESC, campaign.id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.b.String(); got != tt.want {
+				t.Errorf("got:  %s\nwant: %s", got, tt.want)
+			}
+			// The built text must parse back to the same query.
+			q, err := Parse(tt.b.String())
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if q.String() != tt.want {
+				t.Errorf("round trip: %s", q)
+			}
+			if err := NewValidator().Validate(tt.b.Query()); err != nil {
+				t.Errorf("validate: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package gaql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldInfo describes a GAQL field or resource, mirroring the metadata
+// returned by GoogleAdsFieldService.
+type FieldInfo struct {
+	Name           string   `json:"name"`
+	Category       string   `json:"category"`  // RESOURCE, ATTRIBUTE, SEGMENT, or METRIC
+	DataType       string   `json:"data_type"` // INT64, STRING, ENUM, DATE, ...
+	Selectable     bool     `json:"selectable"`
+	Filterable     bool     `json:"filterable"`
+	Sortable       bool     `json:"sortable"`
+	Repeated       bool     `json:"is_repeated,omitempty"`
+	EnumValues     []string `json:"enum_values,omitempty"`
+	SelectableWith []string `json:"selectable_with,omitempty"`
+
+	// Deprecated explains why the field should no longer be used.
+	// Empty for current fields.
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+// Catalog is a set of field metadata for one API version. A catalog is
+// immutable once created, so one catalog, such as DefaultCatalog, can be
+// shared by any number of goroutines. The EnumValues and SelectableWith
+// slices of the FieldInfo it returns are shared too and must not be
+// modified.
+type Catalog struct {
+	Version string
+	fields  map[string]FieldInfo
+
+	// A lazy catalog loads its fields on first use.
+	once *sync.Once
+	load func() (*Catalog, error)
+	err  error
+}
+
+// catalogFile is the on-disk JSON form of a Catalog.
+type catalogFile struct {
+	Version string      `json:"version"`
+	Fields  []FieldInfo `json:"fields"`
+}
+
+//go:embed catalog_v23.json
+var defaultCatalogJSON []byte
+
+var (
+	defaultCatalog     *Catalog
+	defaultCatalogOnce sync.Once
+)
+
+// DefaultCatalog returns the embedded catalog for API v23.
+// It covers the commonly queried resources, attributes, segments, and
+// metrics; it is not a complete copy of the API schema.
+func DefaultCatalog() *Catalog {
+	defaultCatalogOnce.Do(func() {
+		c, err := LoadCatalog(strings.NewReader(string(defaultCatalogJSON)))
+		if err != nil {
+			panic("gaql: embedded catalog is invalid: " + err.Error())
+		}
+		defaultCatalog = c
+	})
+	return defaultCatalog
+}
+
+// NewCatalog creates a catalog from field metadata. The fields are
+// copied; changing them afterwards does not change the catalog.
+func NewCatalog(version string, fields []FieldInfo) *Catalog {
+	c := &Catalog{Version: version, fields: make(map[string]FieldInfo, len(fields))}
+	for _, f := range fields {
+		f.EnumValues = slices.Clone(f.EnumValues)
+		f.SelectableWith = slices.Clone(f.SelectableWith)
+		c.fields[f.Name] = f
+	}
+	return c
+}
+
+// NewLazyCatalog returns a catalog for version whose fields are read by
+// load when it is first used, so a program that never looks a field up
+// never pays for reading a large catalog. If load fails, the catalog
+// falls back to DefaultCatalog and Err reports why.
+func NewLazyCatalog(version string, load func() (*Catalog, error)) *Catalog {
+	return &Catalog{Version: version, once: new(sync.Once), load: load}
+}
+
+func (c *Catalog) init() {
+	if c.once == nil {
+		return
+	}
+	c.once.Do(func() {
+		loaded, err := c.load()
+		if err != nil {
+			c.err = err
+			loaded = DefaultCatalog()
+		}
+		c.fields = loaded.fields
+	})
+}
+
+// Err returns the error of loading a lazy catalog, loading it first if
+// it was not used yet; the catalog then holds the fields of
+// DefaultCatalog. It is nil for other catalogs.
+func (c *Catalog) Err() error {
+	c.init()
+	return c.err
+}
+
+// LoadCatalog reads a catalog in the JSON form written by WriteTo.
+func LoadCatalog(r io.Reader) (*Catalog, error) {
+	var file catalogFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("gaql: reading catalog: %w", err)
+	}
+	return NewCatalog(file.Version, file.Fields), nil
+}
+
+// WriteTo writes the catalog as JSON.
+func (c *Catalog) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(catalogFile{Version: c.Version, Fields: c.Fields()}, "", " ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// Field returns the metadata for a field or resource name.
+func (c *Catalog) Field(name string) (FieldInfo, bool) {
+	c.init()
+	f, ok := c.fields[name]
+	return f, ok
+}
+
+// Fields returns all entries sorted by name.
+func (c *Catalog) Fields() []FieldInfo {
+	c.init()
+	out := make([]FieldInfo, 0, len(c.fields))
+	for _, f := range c.fields {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ResourceFields returns the attribute fields of a resource, sorted by name.
+func (c *Catalog) ResourceFields(resource string) []FieldInfo {
+	var out []FieldInfo
+	prefix := resource + "."
+	for _, f := range c.Fields() {
+		if f.Category == "ATTRIBUTE" && strings.HasPrefix(f.Name, prefix) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Description is what the catalog knows about one field or resource: its
+// metadata, and what it can be queried with.
+type Description struct {
+	FieldInfo
+
+	// Fields are the attributes of a resource.
+	Fields []FieldInfo `json:"fields,omitempty"`
+
+	// Resources, Segments, and Metrics can be selected together with the
+	// field; for a resource, they can be selected with the resource in
+	// the FROM clause.
+	Resources []string `json:"compatible_resources,omitempty"`
+	Segments  []string `json:"compatible_segments,omitempty"`
+	Metrics   []string `json:"compatible_metrics,omitempty"`
+}
+
+// Describe returns the description of a field or resource. Fields the
+// catalog has no selectable_with list for are compatible with the
+// resources listing them, and attributes with their own resource.
+func (c *Catalog) Describe(name string) (Description, bool) {
+	f, ok := c.Field(name)
+	if !ok {
+		return Description{}, false
+	}
+	d := Description{FieldInfo: f}
+	compatible := slices.Clone(f.SelectableWith)
+	if f.Category == "RESOURCE" {
+		d.Fields = c.ResourceFields(name)
+	} else if len(compatible) == 0 {
+		if resource, _, ok := strings.Cut(name, "."); ok && f.Category == "ATTRIBUTE" {
+			compatible = append(compatible, resource)
+		}
+		for _, r := range c.Fields() {
+			if r.Category == "RESOURCE" && slices.Contains(r.SelectableWith, name) {
+				compatible = append(compatible, r.Name)
+			}
+		}
+	}
+	for _, n := range compatible {
+		switch {
+		case strings.HasPrefix(n, "segments."):
+			d.Segments = append(d.Segments, n)
+		case strings.HasPrefix(n, "metrics."):
+			d.Metrics = append(d.Metrics, n)
+		default:
+			d.Resources = append(d.Resources, n)
+		}
+	}
+	for _, names := range [][]string{d.Resources, d.Segments, d.Metrics} {
+		sort.Strings(names)
+	}
+	d.Resources = slices.Compact(d.Resources)
+	return d, true
+}
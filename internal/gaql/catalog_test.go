@@ -0,0 +1,139 @@
+package gaql
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNewCatalogCopiesFields(t *testing.T) {
+	fields := []FieldInfo{{Name: "campaign.status", EnumValues: []string{"ENABLED", "PAUSED"}}}
+	c := NewCatalog("v23", fields)
+	fields[0].EnumValues[0] = "CHANGED"
+	f, _ := c.Field("campaign.status")
+	if f.EnumValues[0] != "ENABLED" {
+		t.Errorf("catalog changed with its input: %v", f.EnumValues)
+	}
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	c := DefaultCatalog()
+	if c.Version != "v23" {
+		t.Errorf("expected version v23, got %q", c.Version)
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		dataType string
+		sortable bool
+	}{
+		{"campaign", "RESOURCE", "MESSAGE", false},
+		{"campaign.status", "ATTRIBUTE", "ENUM", true},
+		{"metrics.clicks", "METRIC", "INT64", true},
+		{"segments.date", "SEGMENT", "DATE", true},
+		{"ad_group_ad.ad.final_urls", "ATTRIBUTE", "STRING", false},
+	}
+	for _, tt := range tests {
+		f, ok := c.Field(tt.name)
+		if !ok {
+			t.Errorf("%s: not in catalog", tt.name)
+			continue
+		}
+		if f.Category != tt.category || f.DataType != tt.dataType || f.Sortable != tt.sortable {
+			t.Errorf("%s: unexpected metadata %+v", tt.name, f)
+		}
+	}
+
+	if len(c.ResourceFields("campaign")) == 0 {
+		t.Error("expected campaign attributes")
+	}
+}
+
+func TestCatalogRoundTrip(t *testing.T) {
+	c := NewCatalog("v99", []FieldInfo{
+		{Name: "widget.id", Category: "ATTRIBUTE", DataType: "INT64", Selectable: true},
+		{Name: "widget.kind", Category: "ATTRIBUTE", DataType: "ENUM", EnumValues: []string{"A", "B"}},
+	})
+
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	loaded, err := LoadCatalog(&buf)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Version != "v99" || len(loaded.Fields()) != 2 {
+		t.Fatalf("unexpected catalog: %s %v", loaded.Version, loaded.Fields())
+	}
+	if f, _ := loaded.Field("widget.kind"); len(f.EnumValues) != 2 {
+		t.Errorf("enum values lost: %+v", f)
+	}
+}
+
+func TestLazyCatalog(t *testing.T) {
+	loads := 0
+	c := NewLazyCatalog("v99", func() (*Catalog, error) {
+		loads++
+		return NewCatalog("v99", []FieldInfo{{Name: "widget.id", Category: "ATTRIBUTE"}}), nil
+	})
+	if loads != 0 {
+		t.Fatal("loaded before first use")
+	}
+	if _, ok := c.Field("widget.id"); !ok {
+		t.Error("widget.id missing")
+	}
+	if len(c.Fields()) != 1 || loads != 1 || c.Err() != nil {
+		t.Errorf("loads = %d, err = %v, want one load and no error", loads, c.Err())
+	}
+
+	failing := NewLazyCatalog("v99", func() (*Catalog, error) { return nil, errors.New("no such file") })
+	if _, ok := failing.Field("campaign.status"); !ok {
+		t.Error("a failed load should fall back to the default catalog")
+	}
+	if failing.Err() == nil {
+		t.Error("Err = nil after a failed load")
+	}
+}
+
+func TestCatalogDescribe(t *testing.T) {
+	c := NewCatalog("v99", []FieldInfo{
+		{Name: "widget", Category: "RESOURCE", SelectableWith: []string{"segments.date", "metrics.clicks", "gadget"}},
+		{Name: "gadget", Category: "RESOURCE", SelectableWith: []string{"metrics.clicks"}},
+		{Name: "widget.id", Category: "ATTRIBUTE", DataType: "INT64", Selectable: true},
+		{Name: "widget.name", Category: "ATTRIBUTE", DataType: "STRING", Selectable: true},
+		{Name: "metrics.clicks", Category: "METRIC", DataType: "INT64", Selectable: true},
+		{Name: "segments.date", Category: "SEGMENT", DataType: "DATE", Selectable: true, SelectableWith: []string{"widget", "metrics.clicks"}},
+	})
+
+	tests := []struct {
+		name      string
+		fields    int
+		resources []string
+		segments  []string
+		metrics   []string
+	}{
+		{"widget", 2, []string{"gadget"}, []string{"segments.date"}, []string{"metrics.clicks"}},
+		{"widget.id", 0, []string{"widget"}, nil, nil},
+		{"metrics.clicks", 0, []string{"gadget", "widget"}, nil, nil},
+		{"segments.date", 0, []string{"widget"}, nil, []string{"metrics.clicks"}},
+	}
+	for _, tt := range tests {
+		d, ok := c.Describe(tt.name)
+		if !ok {
+			t.Errorf("%s: not described", tt.name)
+			continue
+		}
+		if d.Name != tt.name || len(d.Fields) != tt.fields {
+			t.Errorf("%s: got %s with %d fields", tt.name, d.Name, len(d.Fields))
+		}
+		if !slices.Equal(d.Resources, tt.resources) || !slices.Equal(d.Segments, tt.segments) || !slices.Equal(d.Metrics, tt.metrics) {
+			t.Errorf("%s: compatible %v %v %v, want %v %v %v", tt.name, d.Resources, d.Segments, d.Metrics, tt.resources, tt.segments, tt.metrics)
+		}
+	}
+	if _, ok := c.Describe("widget.color"); ok {
+		t.Error("unknown field described")
+	}
+}
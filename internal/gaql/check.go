@@ -0,0 +1,57 @@
+package gaql
+
+import "errors"
+
+// Check parses and validates a query sent by another program, as the
+// MCP and HTTP servers receive them, and returns the prepared copy to
+// run. apiVersion, when not empty, selects the API version validated
+// against, and access, when not nil, is the policy the query is held to.
+//
+// Literals are checked for quotes and line breaks, which in a query
+// written by a program more likely came from unescaped input than from
+// intent.
+func Check(query, apiVersion string, access *AccessPolicy) (*Query, []Diagnostic, error) {
+	if query == "" {
+		return nil, nil, errors.New("query is required")
+	}
+	q, err := Parse(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := NewValidator()
+	v.FlagSuspiciousLiterals = true
+	if apiVersion != "" {
+		v.APIVersion = apiVersion
+	}
+	v.Access = access
+	return v.Prepare(q)
+}
+
+// CheckResult is the JSON answer of a server to a request to validate a
+// query: the prepared query and its diagnostics, or the error.
+type CheckResult struct {
+	Valid       bool              `json:"valid"`
+	Error       string            `json:"error,omitempty"`
+	Query       string            `json:"query,omitempty"`
+	Diagnostics []CheckDiagnostic `json:"diagnostics,omitempty"`
+}
+
+// CheckDiagnostic is a Diagnostic as a CheckResult reports it.
+type CheckDiagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// NewCheckResult returns the CheckResult of what Check returned.
+func NewCheckResult(q *Query, diags []Diagnostic, err error) CheckResult {
+	if err != nil {
+		return CheckResult{Error: err.Error()}
+	}
+	result := CheckResult{Valid: true, Query: q.String()}
+	for _, d := range diags {
+		result.Diagnostics = append(result.Diagnostics, CheckDiagnostic{d.Severity.String(), d.Code, d.Message, d.Hint})
+	}
+	return result
+}
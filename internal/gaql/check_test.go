@@ -0,0 +1,32 @@
+package gaql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	policy := &AccessPolicy{DenyResources: []string{"change_event"}, MaxLimit: 100}
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty", "", `{"valid":false,"error":"query is required"}`},
+		{"capped", "SELECT campaign.id FROM campaign", `{"valid":true,"query":"SELECT campaign.id FROM campaign LIMIT 100",`},
+		{"denied", "SELECT change_event.change_date_time FROM change_event", `"error":"`},
+		{"suspicious literal", "SELECT campaign.id FROM campaign WHERE campaign.name = 'a\\' OR 1=1'", `"severity":"warning"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(NewCheckResult(Check(tt.query, "", policy)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("got %s, want it to contain %s", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package gaql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Thresholds above which EstimateCost warns.
+const (
+	// ExpensiveMultiplier is the estimated row multiplier past which
+	// segmentation is considered explosive.
+	ExpensiveMultiplier = 1000
+
+	// ManyFields is the number of selected fields past which a query is
+	// considered wide.
+	ManyFields = 50
+
+	// unboundedDays stands in for the date span of a query without a
+	// date bound; real account histories are often longer.
+	unboundedDays = 365
+
+	// unknownSegmentRows is the guess for segments whose number of
+	// distinct values the catalog cannot tell, such as
+	// segments.conversion_action.
+	unknownSegmentRows = 10
+)
+
+// Cost is a local estimate of how much a query asks of the API, made
+// without sending it.
+type Cost struct {
+	// Fields is the number of selected fields.
+	Fields int
+
+	// Segments are the selected segments, each splitting every row of
+	// the resource into up to Rows rows.
+	Segments []SegmentCost
+
+	// Days is the number of days the segments.date conditions cover;
+	// when Bounded is false the query has no date bound and Days is a
+	// one-year stand-in.
+	Days    int
+	Bounded bool
+
+	// Multiplier is the estimated number of rows returned per entity of
+	// the FROM resource: the product of the segments' rows. 1 for
+	// unsegmented queries.
+	Multiplier int
+
+	// Limit is the query's LIMIT, 0 when absent.
+	Limit int
+
+	// Warnings are the expensive patterns found, with codes such as
+	// "segment-explosion" and "no-limit".
+	Warnings []Diagnostic
+}
+
+// SegmentCost is the contribution of one segment to a Cost.
+type SegmentCost struct {
+	Field string
+	Rows  int  // distinct values expected
+	Guess bool // Rows is a default, not derived from the query or catalog
+}
+
+// EstimateCost estimates the cost of q from its shape and the default
+// catalog: the selected fields, the row multiplier of its segments (for
+// example segments.hour × segments.device multiplies every campaign
+// row by 24 × 5), its date span, and its LIMIT. It warns about
+// explosive segmentation, missing LIMITs and date bounds, high-volume
+// resources, and single-day resources queried over several days.
+func EstimateCost(q *Query) Cost {
+	c := Cost{Fields: len(q.Select), Limit: q.Limit, Multiplier: 1}
+	c.Days, c.Bounded = q.DateSpanDays()
+	if !c.Bounded {
+		c.Days = unboundedDays
+	}
+
+	catalog := DefaultCatalog()
+	for _, f := range q.Select {
+		if !strings.HasPrefix(f.Name, "segments.") {
+			continue
+		}
+		s := segmentCost(catalog, f.Name, c.Days)
+		c.Segments = append(c.Segments, s)
+		c.Multiplier = min(c.Multiplier*s.Rows, 1<<30)
+	}
+
+	warn := func(code, field, msg, hint string) {
+		c.Warnings = append(c.Warnings, Diagnostic{Severity: SeverityWarning, Code: code, Field: field, Message: msg, Hint: hint})
+	}
+	if c.Multiplier > ExpensiveMultiplier {
+		factors := make([]string, len(c.Segments))
+		for i, s := range c.Segments {
+			factors[i] = fmt.Sprintf("%s (%d)", s.Field, s.Rows)
+		}
+		warn("segment-explosion", "",
+			fmt.Sprintf("segments multiply each %s row by about %d: %s", q.From, c.Multiplier, strings.Join(factors, " × ")),
+			"drop a segment, or shorten the date range of date segments")
+	}
+	if c.Fields > ManyFields {
+		warn("many-fields", "", fmt.Sprintf("%d fields are selected", c.Fields), "select only the fields you need")
+	}
+	if q.Limit == 0 {
+		warn("no-limit", "", "query has no LIMIT clause", "add LIMIT while exploring")
+	}
+	if !c.Bounded && (selectsCategory(q, "METRIC") || len(c.Segments) > 0) {
+		warn("unbounded-date", "segments.date", "metrics or segments are selected without a bounded segments.date range",
+			"add WHERE segments.date DURING LAST_30_DAYS")
+	}
+	if SingleDayResources[q.From] && (!c.Bounded || c.Days != 1) {
+		warn("single-day", "segments.date", q.From+" accepts only single-day date ranges",
+			"use WHERE segments.date DURING YESTERDAY, or one date with =")
+	}
+	if HighVolumeResources[q.From] {
+		warn("high-volume", "", q.From+" is a high-volume resource", "filter it tightly and add LIMIT")
+	}
+	return c
+}
+
+// segmentCost returns how many distinct values a segment takes over a
+// span of days.
+func segmentCost(catalog *Catalog, name string, days int) SegmentCost {
+	s := SegmentCost{Field: name}
+	switch name {
+	case "segments.date":
+		s.Rows = days
+	case "segments.week":
+		s.Rows = min(days, ceilDiv(days, 7)+1)
+	case "segments.month":
+		s.Rows = min(days, ceilDiv(days, 30)+1)
+	case "segments.quarter":
+		s.Rows = min(days, ceilDiv(days, 91)+1)
+	case "segments.year":
+		s.Rows = min(days, ceilDiv(days, 365)+1)
+	case "segments.hour":
+		s.Rows = 24
+	case "segments.day_of_week":
+		s.Rows = min(days, 7)
+	default:
+		if f, ok := catalog.Field(name); ok && len(f.EnumValues) > 0 {
+			for _, v := range f.EnumValues {
+				if v != "UNSPECIFIED" && v != "UNKNOWN" {
+					s.Rows++
+				}
+			}
+		}
+		if s.Rows == 0 {
+			s.Rows, s.Guess = unknownSegmentRows, true
+		}
+	}
+	s.Rows = max(s.Rows, 1)
+	return s
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+// selectsCategory reports whether q selects a field of category.
+func selectsCategory(q *Query, category string) bool {
+	for _, f := range q.Select {
+		if fieldCategory(f.Name) == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package gaql
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		multiplier int
+		days       int
+		bounded    bool
+		warnings   []string
+	}{
+		{
+			name:       "unsegmented with limit",
+			query:      "SELECT campaign.id, campaign.name FROM campaign LIMIT 10",
+			multiplier: 1, days: 365,
+		},
+		{
+			name:       "daily metrics",
+			query:      "SELECT campaign.id, metrics.clicks, segments.date FROM campaign WHERE segments.date DURING LAST_30_DAYS LIMIT 100",
+			multiplier: 30, days: 30, bounded: true,
+		},
+		{
+			name:       "hour by device",
+			query:      "SELECT campaign.id, segments.hour, segments.device, segments.date FROM campaign WHERE segments.date DURING LAST_14_DAYS",
+			multiplier: 24 * 5 * 14, days: 14, bounded: true,
+			warnings: []string{"segment-explosion", "no-limit"},
+		},
+		{
+			name:       "weeks of a month",
+			query:      "SELECT campaign.id, segments.week FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31' LIMIT 5",
+			multiplier: 6, days: 31, bounded: true,
+		},
+		{
+			name:       "unknown segment guessed",
+			query:      "SELECT campaign.id, segments.conversion_action FROM campaign WHERE segments.date DURING YESTERDAY LIMIT 5",
+			multiplier: 10, days: 1, bounded: true,
+		},
+		{
+			name:       "unbounded metrics",
+			query:      "SELECT campaign.id, metrics.clicks FROM campaign LIMIT 5",
+			multiplier: 1, days: 365,
+			warnings: []string{"unbounded-date"},
+		},
+		{
+			name:       "click_view over a week",
+			query:      "SELECT click_view.gclid FROM click_view WHERE segments.date DURING LAST_7_DAYS LIMIT 5",
+			multiplier: 1, days: 7, bounded: true,
+			warnings: []string{"single-day", "high-volume"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			c := EstimateCost(q)
+			if c.Multiplier != tt.multiplier || c.Days != tt.days || c.Bounded != tt.bounded {
+				t.Errorf("multiplier %d over %d days (bounded %v), want %d over %d (%v)",
+					c.Multiplier, c.Days, c.Bounded, tt.multiplier, tt.days, tt.bounded)
+			}
+			var codes []string
+			for _, w := range c.Warnings {
+				codes = append(codes, w.Code)
+			}
+			if !slices.Equal(codes, tt.warnings) {
+				t.Errorf("warnings %v, want %v", codes, tt.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package gaql
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultAPIVersion is the Google Ads API version adtap targets by default.
+const DefaultAPIVersion = "v23"
+
+// dateRangeKeywordsJSON holds the DURING keywords of each API version
+// as changes to the version before it, in the form LoadDateRangeKeywords
+// reads: the oldest lists every keyword it accepts with the number of
+// days it covers, and each later version only the keywords it adds, or
+// removes with null.
+//
+//go:embed daterange_keywords.json
+var dateRangeKeywordsJSON []byte
+
+var (
+	// dateRangeMu guards dateRangeKeywords and the tables below.
+	dateRangeMu sync.RWMutex
+
+	dateRangeNames    = make(map[DateRange]string)
+	dateRangeDays     = make(map[DateRange]int)
+	dateRangeVersions = make(map[string]map[string]bool)
+	nextDateRange     = DateRangeCustom + 1
+)
+
+func init() {
+	for k, v := range dateRangeKeywords {
+		dateRangeNames[v] = k
+	}
+	if err := loadDateRangeChanges(bytes.NewReader(dateRangeKeywordsJSON)); err != nil {
+		panic("gaql: embedded date range keywords are invalid: " + err.Error())
+	}
+}
+
+// RegisterDateRange makes keyword a valid DURING date range for the
+// given API version and returns its DateRange value. Keywords new to
+// adtap are allocated a value after DateRangeCustom; days is the number
+// of days the range covers, or 0 when unknown.
+//
+// Register keywords before parsing queries that use them; the lexer only
+// recognizes keywords that are registered for some version.
+func RegisterDateRange(version, keyword string, days int) DateRange {
+	keyword = strings.ToUpper(keyword)
+
+	dateRangeMu.Lock()
+	defer dateRangeMu.Unlock()
+
+	dr, ok := dateRangeKeywords[keyword]
+	if !ok {
+		dr = nextDateRange
+		nextDateRange++
+		dateRangeKeywords[keyword] = dr
+		dateRangeNames[dr] = keyword
+	}
+	if days > 0 {
+		dateRangeDays[dr] = days
+	}
+	if dateRangeVersions[version] == nil {
+		dateRangeVersions[version] = make(map[string]bool)
+	}
+	dateRangeVersions[version][keyword] = true
+	return dr
+}
+
+// LoadDateRangeKeywords registers the keywords in r, a JSON object
+// mapping API version to the keywords it changes, in the form of the
+// embedded daterange_keywords.json:
+//
+//	{"v24": {"LAST_90_DAYS": 90}, "v25": {"LAST_BUSINESS_WEEK": null}}
+//
+// Each version starts from the keywords it already has, or when it is
+// new from those of the newest version before it, as loaded so far. A
+// keyword with a day count is added, with 0 when the count is unknown,
+// and one with null removed. Above, v24 accepts the keywords of v23 and
+// LAST_90_DAYS, and v25 those of v24 but LAST_BUSINESS_WEEK.
+func LoadDateRangeKeywords(r io.Reader) error {
+	if err := loadDateRangeChanges(r); err != nil {
+		return fmt.Errorf("gaql: reading date range keywords: %w", err)
+	}
+	return nil
+}
+
+// loadDateRangeChanges registers the keyword changes in r, applying the
+// versions in order; see LoadDateRangeKeywords.
+func loadDateRangeChanges(r io.Reader) error {
+	var changes map[string]map[string]*int
+	if err := json.NewDecoder(r).Decode(&changes); err != nil {
+		return err
+	}
+	versions := make([]string, 0, len(changes))
+	for version := range changes {
+		versions = append(versions, version)
+	}
+	slices.SortFunc(versions, compareAPIVersions)
+	for _, version := range versions {
+		for keyword := range baseKeywords(version) {
+			RegisterDateRange(version, keyword, 0)
+		}
+		for keyword, days := range changes[version] {
+			if days != nil {
+				RegisterDateRange(version, keyword, *days)
+			}
+		}
+		dateRangeMu.Lock()
+		if dateRangeVersions[version] == nil {
+			dateRangeVersions[version] = make(map[string]bool)
+		}
+		for keyword, days := range changes[version] {
+			if days == nil {
+				delete(dateRangeVersions[version], strings.ToUpper(keyword))
+			}
+		}
+		dateRangeMu.Unlock()
+	}
+	return nil
+}
+
+// baseKeywords returns the keywords a version's changes apply to: its
+// own when it is registered, otherwise those of the newest registered
+// version before it.
+func baseKeywords(version string) map[string]bool {
+	dateRangeMu.RLock()
+	defer dateRangeMu.RUnlock()
+	if set, ok := dateRangeVersions[version]; ok {
+		return maps.Clone(set)
+	}
+	var newest string
+	for v := range dateRangeVersions {
+		if compareAPIVersions(v, version) < 0 && (newest == "" || compareAPIVersions(v, newest) > 0) {
+			newest = v
+		}
+	}
+	return maps.Clone(dateRangeVersions[newest])
+}
+
+// compareAPIVersions orders API versions such as v9 and v23 by number.
+func compareAPIVersions(a, b string) int {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return cmp.Compare(na, nb)
+}
+
+// LookupDateRange returns the DateRange for a DURING keyword registered
+// for any API version.
+func LookupDateRange(keyword string) (DateRange, bool) {
+	dateRangeMu.RLock()
+	defer dateRangeMu.RUnlock()
+	dr, ok := dateRangeKeywords[strings.ToUpper(keyword)]
+	return dr, ok
+}
+
+// DateRangeKeywordsFor returns the sorted DURING keywords accepted by an
+// API version. The boolean is false when the version is not registered.
+func DateRangeKeywordsFor(version string) ([]string, bool) {
+	dateRangeMu.RLock()
+	defer dateRangeMu.RUnlock()
+	set, ok := dateRangeVersions[version]
+	if !ok {
+		return nil, false
+	}
+	keywords := make([]string, 0, len(set))
+	for k := range set {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+	return keywords, true
+}
+
+// DateRangeSupported reports whether keyword is a DURING date range in
+// the given API version. Versions that are not registered accept every
+// known keyword, so a newer API version does not break validation before
+// its keyword list is loaded.
+func DateRangeSupported(version, keyword string) bool {
+	keyword = strings.ToUpper(keyword)
+
+	dateRangeMu.RLock()
+	defer dateRangeMu.RUnlock()
+	if set, ok := dateRangeVersions[version]; ok {
+		return set[keyword]
+	}
+	_, ok := dateRangeKeywords[keyword]
+	return ok
+}
+
+func (d DateRange) String() string {
+	dateRangeMu.RLock()
+	defer dateRangeMu.RUnlock()
+	if name, ok := dateRangeNames[d]; ok {
+		return name
+	}
+	return "CUSTOM"
+}
+
+// registeredDays returns the day count recorded for a registered range.
+func registeredDays(d DateRange) int {
+	dateRangeMu.RLock()
+	defer dateRangeMu.RUnlock()
+	return dateRangeDays[d]
+}
@@ -0,0 +1,111 @@
+package gaql
+
+import "time"
+
+// dateLayout is the YYYY-MM-DD layout used for GAQL date literals.
+const dateLayout = "2006-01-02"
+
+// Days returns the maximum number of days covered by the date range.
+// Ranges whose length depends on the calendar (THIS_MONTH, LAST_MONTH)
+// report their upper bound. DateRangeCustom returns 0.
+func (d DateRange) Days() int {
+	switch d {
+	case DateRangeToday, DateRangeYesterday:
+		return 1
+	case DateRangeLastBusinessWeek:
+		return 5
+	case DateRangeLast7Days, DateRangeThisWeekSunToday, DateRangeThisWeekMonToday,
+		DateRangeLastWeekSunSat, DateRangeLastWeekMonSun:
+		return 7
+	case DateRangeLast14Days:
+		return 14
+	case DateRangeLast30Days:
+		return 30
+	case DateRangeThisMonth, DateRangeLastMonth:
+		return 31
+	default:
+		return registeredDays(d)
+	}
+}
+
+// DateSpanDays returns the number of days the query's segments.date
+// conditions cover. The boolean is false when the query has no usable
+// date bound, i.e. it spans the account's entire history.
+func (q *Query) DateSpanDays() (int, bool) {
+	var lower, upper time.Time
+	for _, c := range q.Where {
+		if c.Field != "segments.date" {
+			continue
+		}
+		switch c.Operator {
+		case OpDuring:
+			if n := c.Value.DateRange.Days(); n > 0 {
+				return n, true
+			}
+		case OpEq:
+			return 1, true
+		case OpBetween:
+			if len(c.Value.List) != 2 {
+				continue
+			}
+			start, err1 := time.Parse(dateLayout, c.Value.List[0])
+			end, err2 := time.Parse(dateLayout, c.Value.List[1])
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			return daysInclusive(start, end), true
+		case OpGt, OpGte:
+			if t, err := time.Parse(dateLayout, c.Value.Str); err == nil {
+				if c.Operator == OpGt {
+					t = t.AddDate(0, 0, 1)
+				}
+				lower = t
+			}
+		case OpLt, OpLte:
+			if t, err := time.Parse(dateLayout, c.Value.Str); err == nil {
+				if c.Operator == OpLt {
+					t = t.AddDate(0, 0, -1)
+				}
+				upper = t
+			}
+		}
+	}
+	if !lower.IsZero() && !upper.IsZero() {
+		return daysInclusive(lower, upper), true
+	}
+	return 0, false
+}
+
+func daysInclusive(start, end time.Time) int {
+	if end.Before(start) {
+		return 0
+	}
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// DateBounds returns the first and last day the segments.date
+// conditions of q allow, both included, resolving DURING keywords as on
+// the date of today. A range bounded only below runs through today. The
+// boolean is false when q sets no lower bound.
+func (q *Query) DateBounds(today time.Time) (start, end time.Time, ok bool) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	for _, c := range q.Where {
+		if c.Field != "segments.date" {
+			continue
+		}
+		s, e := conditionDates(c, today)
+		if !s.IsZero() && (start.IsZero() || s.After(start)) {
+			start = s
+		}
+		if !e.IsZero() && (end.IsZero() || e.Before(end)) {
+			end = e
+		}
+	}
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	if end.IsZero() {
+		end = today
+	}
+	return start, end, true
+}
@@ -0,0 +1,43 @@
+package gaql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateBounds(t *testing.T) {
+	// 2026-03-04 is a Wednesday.
+	today := time.Date(2026, 3, 4, 15, 0, 0, 0, time.Local)
+	tests := []struct {
+		where      string
+		start, end string // empty when unbounded
+	}{
+		{"segments.date DURING LAST_7_DAYS", "2026-02-25", "2026-03-03"},
+		{"segments.date DURING TODAY", "2026-03-04", "2026-03-04"},
+		{"segments.date BETWEEN '2026-01-01' AND '2026-01-31'", "2026-01-01", "2026-01-31"},
+		{"segments.date = '2026-02-14'", "2026-02-14", "2026-02-14"},
+		{"segments.date >= '2026-03-01'", "2026-03-01", "2026-03-04"},
+		{"segments.date > '2026-02-01' AND segments.date < '2026-02-10'", "2026-02-02", "2026-02-09"},
+		{"segments.date DURING LAST_30_DAYS AND segments.date >= '2026-03-01'", "2026-03-01", "2026-03-03"},
+		{"segments.date <= '2026-03-01'", "", ""},
+		{"campaign.status = 'ENABLED'", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			q, err := Parse("SELECT segments.date, metrics.clicks FROM campaign WHERE " + tt.where)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start, end, ok := q.DateBounds(today)
+			if ok != (tt.start != "") {
+				t.Fatalf("ok = %v", ok)
+			}
+			if !ok {
+				return
+			}
+			if got := start.Format(dateLayout) + ".." + end.Format(dateLayout); got != tt.start+".."+tt.end {
+				t.Errorf("got %s, want %s..%s", got, tt.start, tt.end)
+			}
+		})
+	}
+}
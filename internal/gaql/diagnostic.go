@@ -0,0 +1,182 @@
+package gaql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity classifies a Diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Diagnostic is a non-fatal finding about a query: something that is
+// valid GAQL but likely to surprise the user.
+type Diagnostic struct {
+	Severity Severity
+	Code     string // stable identifier, e.g. "zero-metric-rows"
+	Message  string
+	Field    string
+	Span     Span
+	Hint     string // actionable suggestion, may span several lines
+}
+
+func (d Diagnostic) String() string {
+	if d.Span.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", d.Span.Start, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+}
+
+// zeroMetricRows warns when a query selects metrics alongside attributes
+// of the FROM resource. The API omits rows whose metrics are all zero, so
+// entities with no impressions in the date range silently disappear from
+// results. It stays silent when a WHERE condition on a metric drops those
+// rows anyway, and for resources with a single row, such as customer.
+func zeroMetricRows(q *Query) *Diagnostic {
+	if SingleRowResources[q.From] {
+		return nil
+	}
+	var metric *Field
+	attribute := false
+	for i, f := range q.Select {
+		switch {
+		case fieldCategory(f.Name) == "METRIC":
+			if metric == nil {
+				metric = &q.Select[i]
+			}
+		case strings.HasPrefix(f.Name, q.From+"."):
+			attribute = true
+		}
+	}
+	if metric == nil || !attribute {
+		return nil
+	}
+	for _, c := range q.Where {
+		if fieldCategory(c.Field) == "METRIC" && excludesZero(c) {
+			return nil
+		}
+	}
+
+	workaround := ZeroMetricWorkaround(q)
+	return &Diagnostic{
+		Severity: SeverityWarning,
+		Code:     "zero-metric-rows",
+		Message:  fmt.Sprintf("rows for %s entities with zero impressions are omitted when metrics are selected", q.From),
+		Field:    metric.Name,
+		Span:     metric.Span,
+		Hint:     "to list every " + q.From + ", including inactive ones, query the attributes separately:\n  " + workaround.String(),
+	}
+}
+
+// excludesZero reports whether a numeric condition is false for zero, as
+// in metrics.impressions > 0.
+func excludesZero(c Condition) bool {
+	if c.Value.Type != ValueNumber {
+		return false
+	}
+	n := c.Value.Number
+	switch c.Operator {
+	case OpEq:
+		return n != 0
+	case OpNeq:
+		return n == 0
+	case OpGt:
+		return n >= 0
+	case OpGte:
+		return n > 0
+	case OpLt:
+		return n <= 0
+	case OpLte:
+		return n < 0
+	}
+	return false
+}
+
+// suspiciousLiterals warns about condition values that look like input
+// pasted into the query unescaped.
+func suspiciousLiterals(q *Query) []Diagnostic {
+	var diags []Diagnostic
+	for _, c := range q.Where {
+		values := c.Value.List
+		if c.Value.Type == ValueString {
+			values = []string{c.Value.Str}
+		}
+		for _, s := range values {
+			if why := suspiciousLiteral(s); why != "" {
+				diags = append(diags, Diagnostic{
+					Severity: SeverityWarning,
+					Code:     "suspicious-literal",
+					Message:  fmt.Sprintf("the value %s compared with %s %s", QuoteString(s), c.Field, why),
+					Field:    c.Field,
+					Span:     c.Span,
+					Hint:     "build queries from input with gaql.QuoteString or a gaql.Template rather than string formatting",
+				})
+				break
+			}
+		}
+	}
+	return diags
+}
+
+// ZeroMetricWorkaround returns the documented workaround for zero-metric
+// row filtering: the same query with metrics and segments removed, which
+// returns every matching entity regardless of activity. Join its results
+// with the original query's to find entities that had no impressions.
+func ZeroMetricWorkaround(q *Query) *Query {
+	w := &Query{
+		From:       q.From,
+		Limit:      q.Limit,
+		Parameters: make(map[string]string),
+	}
+	for _, f := range q.Select {
+		if fieldCategory(f.Name) == "" {
+			w.Select = append(w.Select, Field{Name: f.Name})
+		}
+	}
+	if len(w.Select) == 0 {
+		w.Select = []Field{{Name: q.From + ".resource_name"}}
+	}
+	for _, c := range q.Where {
+		if fieldCategory(c.Field) == "" {
+			c.Span, c.FieldSpan, c.ValueSpan = Span{}, Span{}, Span{}
+			w.Where = append(w.Where, c)
+		}
+	}
+	for _, o := range q.OrderBy {
+		if fieldCategory(o.Field) == "" {
+			o.Span = Span{}
+			w.OrderBy = append(w.OrderBy, o)
+		}
+	}
+	for k, v := range q.Parameters {
+		w.Parameters[k] = v
+	}
+	return w
+}
+
+// fieldCategory returns the FieldCategories entry for a field's prefix,
+// or "" for resource attributes.
+func fieldCategory(name string) string {
+	for prefix, category := range FieldCategories {
+		if len(name) > len(prefix) && name[:len(prefix)] == prefix && name[len(prefix)] == '.' {
+			return category
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package gaql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZeroMetricRowsDiagnostic(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		warn       bool
+		workaround string
+	}{
+		{
+			name:  "attributes only",
+			input: "SELECT campaign.id, campaign.name FROM campaign",
+		},
+		{
+			name:       "metrics with attributes",
+			input:      "SELECT campaign.id, campaign.name, metrics.impressions FROM campaign WHERE segments.date DURING LAST_7_DAYS AND campaign.status = 'ENABLED' ORDER BY metrics.impressions DESC LIMIT 50",
+			warn:       true,
+			workaround: "SELECT campaign.id, campaign.name FROM campaign WHERE campaign.status = 'ENABLED' LIMIT 50",
+		},
+		{
+			name:  "metrics and segments only",
+			input: "SELECT metrics.clicks, segments.date FROM ad_group WHERE segments.date DURING LAST_7_DAYS",
+		},
+		{
+			name:  "attributes of another resource",
+			input: "SELECT campaign.name, metrics.clicks FROM ad_group WHERE segments.date DURING LAST_7_DAYS",
+		},
+		{
+			name:  "metric restricted to nonzero",
+			input: "SELECT campaign.name, metrics.impressions FROM campaign WHERE segments.date DURING LAST_7_DAYS AND metrics.impressions > 0",
+		},
+		{
+			name:  "metric restricted to at least one",
+			input: "SELECT campaign.name, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS AND metrics.clicks >= 1",
+		},
+		{
+			name:       "metric restricted without excluding zero",
+			input:      "SELECT campaign.name, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS AND metrics.clicks < 100",
+			warn:       true,
+			workaround: "SELECT campaign.name FROM campaign",
+		},
+		{
+			name:  "single-row resource",
+			input: "SELECT metrics.clicks FROM customer WHERE segments.date DURING LAST_7_DAYS",
+		},
+		{
+			name:  "single-row resource with attributes",
+			input: "SELECT customer.descriptive_name, metrics.clicks FROM customer WHERE segments.date DURING LAST_7_DAYS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			diags, err := NewValidator().Check(q)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.warn {
+				if len(diags) != 0 {
+					t.Errorf("expected no diagnostics, got %v", diags)
+				}
+				return
+			}
+			if len(diags) != 1 || diags[0].Code != "zero-metric-rows" {
+				t.Fatalf("expected zero-metric-rows diagnostic, got %v", diags)
+			}
+			if !strings.Contains(diags[0].Hint, tt.workaround) {
+				t.Errorf("expected hint to contain %q, got %q", tt.workaround, diags[0].Hint)
+			}
+			if got := ZeroMetricWorkaround(q).String(); got != tt.workaround {
+				t.Errorf("expected workaround %q, got %q", tt.workaround, got)
+			}
+		})
+	}
+}
+
+func TestZeroMetricRowsDisabled(t *testing.T) {
+	q, err := Parse("SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	v := NewValidator()
+	v.WarnZeroMetricRows = false
+	diags, err := v.Check(q)
+	if err != nil || len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v (%v)", diags, err)
+	}
+}
+
+func TestSuspiciousLiteralDiagnostic(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // message fragment, or "" for no warning
+	}{
+		{"plain values", `SELECT campaign.id FROM campaign WHERE campaign.name = 'Brand | US' AND campaign.status IN ('ENABLED', 'PAUSED')`, ""},
+		{"quote", `SELECT campaign.id FROM campaign WHERE campaign.name = 'x\' OR \'1\'=\'1'`, "holds a quote"},
+		{"line break in list", "SELECT campaign.id FROM campaign WHERE campaign.name IN ('a', 'b\nc')", "holds a line break"},
+		{"backslash", `SELECT campaign.id FROM campaign WHERE campaign.name LIKE '%\\%'`, "holds a backslash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			v := NewValidator()
+			v.WarnZeroMetricRows = false
+			v.FlagSuspiciousLiterals = true
+			diags, err := v.Check(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if len(diags) != 0 {
+					t.Errorf("unexpected diagnostics %v", diags)
+				}
+				return
+			}
+			if len(diags) != 1 || diags[0].Code != "suspicious-literal" || !strings.Contains(diags[0].Message, tt.want) {
+				t.Errorf("expected one suspicious-literal warning containing %q, got %v", tt.want, diags)
+			}
+		})
+	}
+}
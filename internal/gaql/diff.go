@@ -0,0 +1,192 @@
+package gaql
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind says how a clause item differs between two queries.
+type ChangeKind string
+
+// Kinds of Change.
+const (
+	ChangeAdded     ChangeKind = "added"
+	ChangeRemoved   ChangeKind = "removed"
+	ChangeModified  ChangeKind = "changed"
+	ChangeReordered ChangeKind = "reordered"
+)
+
+// Change is one difference between two queries found by Diff.
+type Change struct {
+	Clause string     `json:"clause"` // SELECT, FROM, WHERE, ORDER BY, LIMIT, or PARAMETERS
+	Kind   ChangeKind `json:"kind"`
+	Old    string     `json:"old,omitempty"` // the item in the first query, in GAQL
+	New    string     `json:"new,omitempty"` // the item in the second query
+}
+
+// String returns the change as one line, such as
+// "WHERE changed: campaign.status = 'ENABLED' -> campaign.status != 'REMOVED'".
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return c.Clause + " added: " + c.New
+	case ChangeRemoved:
+		return c.Clause + " removed: " + c.Old
+	default:
+		return c.Clause + " " + string(c.Kind) + ": " + c.Old + " -> " + c.New
+	}
+}
+
+// Diff compares two queries by meaning rather than text, returning the
+// changes that turn a into b in clause order. Layout, keyword case, the
+// order of WHERE conditions, and the order of IN list items are ignored;
+// the order of SELECT fields, which orders result columns, and of ORDER
+// BY items is not. A WHERE condition replaced by one on the same field
+// is reported as ChangeModified. Diff returns nil for equivalent queries.
+func Diff(a, b *Query) []Change {
+	var changes []Change
+	changes = append(changes, diffNames("SELECT", a.FieldNames(), b.FieldNames())...)
+	if a.From != b.From {
+		changes = append(changes, Change{Clause: "FROM", Kind: ChangeModified, Old: a.From, New: b.From})
+	}
+	changes = append(changes, diffConditions(a.Where, b.Where)...)
+	changes = append(changes, diffOrderings(a.OrderBy, b.OrderBy)...)
+	if a.Limit != b.Limit {
+		c := Change{Clause: "LIMIT", Kind: ChangeModified, Old: strconv.Itoa(a.Limit), New: strconv.Itoa(b.Limit)}
+		switch {
+		case a.Limit == 0:
+			c = Change{Clause: "LIMIT", Kind: ChangeAdded, New: c.New}
+		case b.Limit == 0:
+			c = Change{Clause: "LIMIT", Kind: ChangeRemoved, Old: c.Old}
+		}
+		changes = append(changes, c)
+	}
+	return append(changes, diffParameters(a.Parameters, b.Parameters)...)
+}
+
+// diffNames reports the names removed from and added to a list, and one
+// ChangeReordered change when the names in both are in a different order.
+func diffNames(clause string, a, b []string) []Change {
+	var changes []Change
+	var keptA, keptB []string
+	for _, name := range a {
+		if slices.Contains(b, name) {
+			keptA = append(keptA, name)
+		} else {
+			changes = append(changes, Change{Clause: clause, Kind: ChangeRemoved, Old: name})
+		}
+	}
+	for _, name := range b {
+		if slices.Contains(a, name) {
+			keptB = append(keptB, name)
+		} else {
+			changes = append(changes, Change{Clause: clause, Kind: ChangeAdded, New: name})
+		}
+	}
+	if !slices.Equal(keptA, keptB) {
+		changes = append(changes, Change{Clause: clause, Kind: ChangeReordered, Old: strings.Join(keptA, ", "), New: strings.Join(keptB, ", ")})
+	}
+	return changes
+}
+
+func diffConditions(a, b []Condition) []Change {
+	// Conditions found in both queries cancel out; of the rest, those on
+	// the same field pair up as changes, in the order they were written.
+	old := slices.Clone(a)
+	var added []Condition
+	for _, c := range b {
+		key := conditionKey(c)
+		i := slices.IndexFunc(old, func(o Condition) bool { return conditionKey(o) == key })
+		if i < 0 {
+			added = append(added, c)
+			continue
+		}
+		old = slices.Delete(old, i, i+1)
+	}
+	var modified, additions []Change
+	for _, c := range added {
+		i := slices.IndexFunc(old, func(o Condition) bool { return o.Field == c.Field })
+		if i < 0 {
+			additions = append(additions, Change{Clause: "WHERE", Kind: ChangeAdded, New: c.String()})
+			continue
+		}
+		modified = append(modified, Change{Clause: "WHERE", Kind: ChangeModified, Old: old[i].String(), New: c.String()})
+		old = slices.Delete(old, i, i+1)
+	}
+	var changes []Change
+	for _, c := range old {
+		changes = append(changes, Change{Clause: "WHERE", Kind: ChangeRemoved, Old: c.String()})
+	}
+	return append(append(changes, additions...), modified...)
+}
+
+// conditionKey returns c in GAQL with the items of set operators sorted,
+// so conditions that match the same rows have the same key.
+func conditionKey(c Condition) string {
+	switch c.Operator {
+	case OpIn, OpNotIn, OpContainsAny, OpContainsAll, OpContainsNone:
+		c.Value.List = slices.Clone(c.Value.List)
+		slices.Sort(c.Value.List)
+	}
+	return c.String()
+}
+
+func diffOrderings(a, b []Ordering) []Change {
+	fields := func(items []Ordering) []string {
+		names := make([]string, len(items))
+		for i, o := range items {
+			names[i] = o.Field
+		}
+		return names
+	}
+	changes := diffNames("ORDER BY", fields(a), fields(b))
+	for i, c := range changes {
+		// Name the direction of added and removed items.
+		switch c.Kind {
+		case ChangeRemoved:
+			changes[i].Old = orderingString(a[slices.Index(fields(a), c.Old)])
+		case ChangeAdded:
+			changes[i].New = orderingString(b[slices.Index(fields(b), c.New)])
+		}
+	}
+	for _, o := range a {
+		if i := slices.Index(fields(b), o.Field); i >= 0 && b[i].Direction != o.Direction {
+			changes = append(changes, Change{Clause: "ORDER BY", Kind: ChangeModified, Old: orderingString(o), New: orderingString(b[i])})
+		}
+	}
+	return changes
+}
+
+func orderingString(o Ordering) string {
+	return fmt.Sprintf("%s %s", o.Field, o.Direction)
+}
+
+func diffParameters(a, b map[string]string) []Change {
+	names := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var changes []Change
+	for _, name := range names {
+		old, inA := a[name]
+		cur, inB := b[name]
+		switch {
+		case !inB:
+			changes = append(changes, Change{Clause: "PARAMETERS", Kind: ChangeRemoved, Old: name + " = " + old})
+		case !inA:
+			changes = append(changes, Change{Clause: "PARAMETERS", Kind: ChangeAdded, New: name + " = " + cur})
+		case old != cur:
+			changes = append(changes, Change{Clause: "PARAMETERS", Kind: ChangeModified, Old: name + " = " + old, New: name + " = " + cur})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,82 @@
+package gaql
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{
+			"layout and condition order",
+			"SELECT campaign.id, metrics.clicks FROM campaign WHERE campaign.status IN ('ENABLED', 'PAUSED') AND metrics.clicks > 10",
+			"select campaign.id,\n  metrics.clicks\nfrom campaign\nwhere metrics.clicks > 10\n  and campaign.status in ('PAUSED', 'ENABLED')",
+			nil,
+		},
+		{
+			"fields",
+			"SELECT campaign.id, campaign.name, metrics.clicks FROM campaign",
+			"SELECT campaign.name, campaign.id, metrics.conversions FROM ad_group",
+			[]string{
+				"SELECT removed: metrics.clicks",
+				"SELECT added: metrics.conversions",
+				"SELECT reordered: campaign.id, campaign.name -> campaign.name, campaign.id",
+				"FROM changed: campaign -> ad_group",
+			},
+		},
+		{
+			"conditions",
+			"SELECT campaign.id FROM campaign WHERE campaign.status = 'ENABLED' AND metrics.clicks > 10 AND segments.date DURING LAST_7_DAYS",
+			"SELECT campaign.id FROM campaign WHERE segments.date DURING LAST_30_DAYS AND campaign.status = 'ENABLED' AND metrics.impressions > 0",
+			[]string{
+				"WHERE removed: metrics.clicks > 10",
+				"WHERE added: metrics.impressions > 0",
+				"WHERE changed: segments.date DURING LAST_7_DAYS -> segments.date DURING LAST_30_DAYS",
+			},
+		},
+		{
+			"order and limit",
+			"SELECT campaign.id FROM campaign ORDER BY metrics.clicks DESC, campaign.id LIMIT 10",
+			"SELECT campaign.id FROM campaign ORDER BY campaign.id, metrics.clicks, campaign.name DESC",
+			[]string{
+				"ORDER BY added: campaign.name DESC",
+				"ORDER BY reordered: metrics.clicks, campaign.id -> campaign.id, metrics.clicks",
+				"ORDER BY changed: metrics.clicks DESC -> metrics.clicks ASC",
+				"LIMIT removed: 10",
+			},
+		},
+		{
+			"limit and parameters",
+			"SELECT campaign.id FROM campaign LIMIT 10 PARAMETERS include_drafts = true, omit_unselected_resource_names = true",
+			"SELECT campaign.id FROM campaign LIMIT 50 PARAMETERS include_drafts = false",
+			[]string{
+				"LIMIT changed: 10 -> 50",
+				"PARAMETERS changed: include_drafts = true -> include_drafts = false",
+				"PARAMETERS removed: omit_unselected_resource_names = true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Parse(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Parse(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range Diff(a, b) {
+				got = append(got, c.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Diff:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,16 @@
 //	}
 //	// Use q.Select, q.From, q.Where, etc.
 //
+// # Parse Options
+//
+// Parse accepts keywords in any case and bare enum values such as
+// campaign.status = ENABLED. ParseWithOptions tightens or loosens that:
+// StrictParseOptions also requires upper-case keywords and quoted enum
+// values, while the zero ParseOptions additionally ignores trailing
+// semicolons and tokens, which is forgiving for interactive input:
+//
+//	q, err := gaql.ParseWithOptions(input, gaql.StrictParseOptions)
+//
 // # Validation
 //
 // The ValidateQuery function parses and validates a query:
@@ -25,6 +35,14 @@
 //   - Valid operators and date range keywords
 //   - Metrics require date context (segments.date)
 //   - Single-day resources (click_view) require single-day date ranges
+//   - ORDER BY fields are listed once, are sortable in the catalog, and
+//     are selected when they are segments
+//
+// Validation never modifies the query. Validator.Prepare returns a
+// copy ready to send: with the date context of AutoAddDateContext and
+// the caps of an access policy applied, and each WHERE condition
+// annotated with the catalog DataType of its field (INT64, ENUM, DATE,
+// ...), so later stages read values the same way.
 //
 // # Custom Validation
 //
@@ -38,11 +56,166 @@
 //	v := gaql.NewValidator()
 //	v.AllowUnknownResources = false  // Strict mode
 //	v.RequireMetricDateContext = true
+//	v.Identifiers = gaql.IdentifierNamespace  // reject metric.clicks
 //
 //	if err := v.Validate(q); err != nil {
 //		log.Fatal(err)
 //	}
 //
+// # Diagnostics
+//
+// Validator.Check returns non-fatal diagnostics alongside validation
+// errors. For example, selecting metrics together with attributes of
+// the FROM resource yields a "zero-metric-rows" warning: the API omits rows
+// whose metrics are all zero, so inactive entities silently vanish.
+// ZeroMetricWorkaround builds the attribute-only query to run instead.
+//
+// With AutoAddDateContext set, metric queries lacking date context are
+// not rejected; the validator appends "segments.date DURING
+// DefaultDateRange" (LAST_30_DAYS unless changed) to the query and
+// reports a "date-context-added" info diagnostic.
+//
+// Field paths must be lowercase snake_case segments. A path under a
+// namespace that is not metrics, segments, or a known resource, such as
+// metric.clicks, draws an "unknown-namespace" warning suggesting the
+// nearest known one; IdentifierNamespace makes it an error instead.
+//
+// # Guardrails
+//
+// ApplyGuardrails rewrites a query to fit a GuardrailPolicy: it adds or
+// lowers LIMIT, and narrows the segments.date range to its most recent
+// days. Callers that let others write queries, such as an MCP server,
+// apply it before running them; each rewrite is reported as an info
+// diagnostic.
+//
+// # Cost Estimates
+//
+// EstimateCost sizes a query before it is sent: the fields it selects,
+// its date span and LIMIT, and the row multiplier of its segments, since
+// segments.hour × segments.device turns each campaign row into up to
+// 24 × 5 rows. Its warnings flag explosive segmentation, missing LIMITs
+// and date bounds, and resources that are high-volume or single-day:
+//
+//	c := gaql.EstimateCost(q)
+//	fmt.Printf("about %d rows per %s\n", c.Multiplier, q.From)
+//
+// # Source Positions
+//
+// Parsed fields, conditions, orderings, and clauses carry a Span with
+// line, column, and byte offset of their start and end, and validation
+// errors report the Span of the offending node. Editor tooling can map
+// diagnostics back onto the source text:
+//
+//	var verr *gaql.ValidationError
+//	if errors.As(err, &verr) && verr.Span.IsValid() {
+//		fmt.Printf("%s: %s (%q)\n", verr.Span.Start, verr.Message, verr.Span.Text(input))
+//	}
+//
+// # Rewriting Queries
+//
+// Walk visits the query, its SELECT fields, WHERE conditions, and ORDER
+// BY items. Rewrite applies RewriteFuncs to a copy of a query, leaving
+// the original untouched:
+//
+//	out, err := gaql.Rewrite(q,
+//		gaql.AddCondition(notRemoved),
+//		gaql.RenameField("metrics.cost_micros", "metrics.cost_per_conversion"),
+//		gaql.StripLimit(),
+//	)
+//
+// # Minimizing Queries
+//
+// Minimize shrinks a failing query to a smallest one that still fails,
+// removing SELECT fields, WHERE conditions, ORDER BY items, the LIMIT,
+// and PARAMETERS by delta debugging while a predicate holds:
+//
+//	small := gaql.Minimize(q, func(v *gaql.Query) bool {
+//		err := validator.Validate(v)
+//		return err != nil && err.Error() == want
+//	})
+//
+// # Dependencies
+//
+// Extract lists what a query depends on: its FROM resource, the other
+// resources whose attributes it uses, and its attributes, metrics, and
+// segments, also split by the clause that uses them:
+//
+//	d := gaql.Extract(q)
+//	d.AttributedResources // [campaign] for a query FROM ad_group
+//	d.Filtered            // fields of the WHERE clause
+//
+// # Result Schema
+//
+// ResultSchema types the columns of a query's results from the catalog,
+// without running it, for exporters that create tables up front:
+//
+//	cols, err := gaql.ResultSchema(q)
+//	for _, c := range cols {
+//		fmt.Println(c.Name, c.GoType, c.ParquetType, c.SQLType)
+//	}
+//
+// # Building Queries
+//
+// Builder composes a query in code, quoting values correctly:
+//
+//	q := gaql.Select("campaign.id", "metrics.clicks").
+//		From("campaign").
+//		During(gaql.DateRangeLast7Days).
+//		OrderByDesc("metrics.clicks").
+//		ThenBy("campaign.id", gaql.Asc).
+//		Limit(10).
+//		Query()
+//
+// # JSON Forms
+//
+// A Query marshals to an AST JSON form with operators, directions, and
+// date ranges by name and without source spans, and unmarshals from it.
+// JSONSchema describes the form for consumers in other languages; the
+// enumerations themselves marshal as names too.
+// Spec is a terser declarative form for hand-written queries, with the
+// segments.date range in its own during or between key:
+//
+//	s := q.Spec()
+//	q2, err := s.Query()
+//
+// # Search Requests
+//
+// ToSearchRequest validates a query and compiles it, in canonical form,
+// into the SearchGoogleAdsRequest of the API, for programs that call the
+// API through their own gRPC or REST client. MarshalBinary encodes the
+// protobuf message; the JSON form is the REST body:
+//
+//	req, err := gaql.ToSearchRequest(q, "123-456-7890", gaql.RequestOptions{ValidateOnly: true})
+//	data, err := req.Stream().MarshalBinary()
+//
+// # Untrusted Input
+//
+// Values from untrusted input must never be formatted into query text.
+// QuoteString, QuoteList, and SafeLiteral write them as literals that
+// read back as exactly the value given; Validator.FlagSuspiciousLiterals
+// warns about values that look like input pasted in unescaped.
+//
+// # Lexical Structure
+//
+// Queries are read as UTF-8: identifiers and strings may hold any
+// letters, and malformed encodings are parse errors. "--" and "#" start
+// comments running to the end of the line, which Parse ignores and
+// Lexer.Comments keeps. FuzzLexer and FuzzParse check that no input
+// makes the lexer or parser panic:
+//
+//	go test ./internal/gaql -run '^$' -fuzz FuzzParse
+//
+// # Templates
+//
+// A Template holds @name placeholders for condition values and the
+// LIMIT. Bound values are written as literals checked against the
+// field's catalog type, never pasted into the text, so they cannot
+// change the query:
+//
+//	t, err := gaql.NewTemplate("SELECT campaign.name FROM campaign WHERE campaign.id = @id")
+//	err = t.Bind("id", int64(123))
+//	q, err := t.Query()
+//
 // # Query Structure
 //
 // A GAQL query has the following structure:
@@ -50,12 +223,23 @@
 //	SELECT field1, field2, ...
 //	FROM resource
 //	WHERE condition1 AND condition2 ...
-//	ORDER BY field [ASC|DESC]
+//	ORDER BY field1 [ASC|DESC], field2 [ASC|DESC], ...
 //	LIMIT count
 //	PARAMETERS key=value, ...
 //
 // Only SELECT and FROM are required. All other clauses are optional.
 //
+// # Parameters
+//
+// The PARAMETERS clause accepts include_drafts and
+// omit_unselected_resource_names, both boolean. The validator rejects
+// non-boolean values, and unknown keys when StrictParameters is set.
+// Read them with the typed accessors rather than the raw map:
+//
+//	if q.IncludeDrafts() {
+//		// draft campaigns are included
+//	}
+//
 // # Supported Operators
 //
 // Comparison: =, !=, >, >=, <, <=
@@ -65,6 +249,13 @@
 // Null: IS NULL, IS NOT NULL
 // Date: DURING, BETWEEN
 //
+// The validator checks pattern values before the API sees them. In LIKE
+// patterns % and _ are wildcards, and the characters % _ [ ] are
+// matched literally only when bracketed, as in '50[%]'. REGEXP_MATCH
+// patterns use RE2 syntax and are compiled locally, so a malformed
+// expression is reported at its literal. CompileRegexp turns either
+// kind into a *regexp.Regexp for filtering rows client-side.
+//
 // # Date Ranges
 //
 // The DURING operator accepts predefined date ranges:
@@ -76,7 +267,29 @@
 //	LAST_WEEK_SUN_SAT, LAST_WEEK_MON_SUN
 //	LAST_BUSINESS_WEEK
 //
+// Keyword validity is tracked per API version. Validator.APIVersion
+// (DefaultAPIVersion unless changed) rejects keywords the version does
+// not accept. Keywords introduced by newer API releases can be added at
+// runtime without a new adtap build:
+//
+//	gaql.RegisterDateRange("v24", "LAST_90_DAYS", 90)
+//
+// or loaded in bulk with LoadDateRangeKeywords.
+//
 // For custom ranges, use BETWEEN with dates in YYYY-MM-DD format:
 //
 //	WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31'
+//
+// # Concurrency
+//
+// Parse, the formatter, EstimateCost, and catalogs are safe to use from
+// many goroutines, as is a Validator that is not being reconfigured. A
+// Query belongs to one goroutine at a time: use Clone to hand it to
+// another. Validation and Rewrite already work on a copy.
+//
+// The exported tables, such as KnownResources, Keywords, and
+// DefaultParseOptions, are read without locks. Change them only while
+// the program initializes, before queries are parsed concurrently. Date
+// range keywords are the exception: RegisterDateRange and
+// LoadDateRangeKeywords may be called at any time.
 package gaql
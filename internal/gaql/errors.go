@@ -7,6 +7,7 @@ type ParseError struct {
 	Message string
 	Line    int
 	Column  int
+	Offset  int
 }
 
 func (e *ParseError) Error() string {
@@ -17,6 +18,7 @@ func (e *ParseError) Error() string {
 type ValidationError struct {
 	Message string
 	Field   string
+	Span    Span // source range of the offending node, if parsed
 }
 
 func (e *ValidationError) Error() string {
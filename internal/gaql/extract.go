@@ -0,0 +1,91 @@
+package gaql
+
+import (
+	"slices"
+	"strings"
+)
+
+// Dependencies are the parts of the API schema a query refers to. Each
+// list is sorted and holds no duplicates.
+type Dependencies struct {
+	// Resource is the resource in the FROM clause.
+	Resource string `json:"resource"`
+
+	// AttributedResources are the other resources whose attributes the
+	// query selects, filters, or sorts by, such as campaign in a query
+	// FROM ad_group.
+	AttributedResources []string `json:"attributed_resources"`
+
+	// Attributes, Metrics, and Segments are the fields used anywhere in
+	// the query, by category.
+	Attributes []string `json:"attributes"`
+	Metrics    []string `json:"metrics"`
+	Segments   []string `json:"segments"`
+
+	// Selected, Filtered, and Sorted are the fields of the SELECT, WHERE,
+	// and ORDER BY clauses.
+	Selected []string `json:"selected"`
+	Filtered []string `json:"filtered"`
+	Sorted   []string `json:"sorted"`
+}
+
+// Resources returns the FROM resource followed by the attributed ones.
+func (d Dependencies) Resources() []string {
+	return append([]string{d.Resource}, d.AttributedResources...)
+}
+
+// Fields returns every field the query uses, sorted.
+func (d Dependencies) Fields() []string {
+	return sortedSet(slices.Concat(d.Attributes, d.Metrics, d.Segments))
+}
+
+// Extract returns the resources and fields q refers to. It is the
+// common ground of usage reports, policy checks, and cache invalidation:
+// a query depends on exactly these names.
+func Extract(q *Query) Dependencies {
+	d := Dependencies{Resource: q.From}
+	for _, f := range q.Select {
+		d.Selected = append(d.Selected, f.Name)
+	}
+	for _, c := range q.Where {
+		d.Filtered = append(d.Filtered, c.Field)
+	}
+	for _, o := range q.OrderBy {
+		d.Sorted = append(d.Sorted, o.Field)
+	}
+
+	var resources []string
+	for _, name := range slices.Concat(d.Selected, d.Filtered, d.Sorted) {
+		switch fieldCategory(name) {
+		case "METRIC":
+			d.Metrics = append(d.Metrics, name)
+		case "SEGMENT":
+			d.Segments = append(d.Segments, name)
+		default:
+			d.Attributes = append(d.Attributes, name)
+			if resource, _, _ := strings.Cut(name, "."); resource != q.From {
+				resources = append(resources, resource)
+			}
+		}
+	}
+	d.AttributedResources = sortedSet(resources)
+	d.Attributes = sortedSet(d.Attributes)
+	d.Metrics = sortedSet(d.Metrics)
+	d.Segments = sortedSet(d.Segments)
+	d.Selected = sortedSet(d.Selected)
+	d.Filtered = sortedSet(d.Filtered)
+	d.Sorted = sortedSet(d.Sorted)
+	return d
+}
+
+// sortedSet returns names sorted without duplicates, never nil, so
+// empty sets encode as [] in JSON.
+func sortedSet(names []string) []string {
+	out := slices.Clone(names)
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if out == nil {
+		out = []string{}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package gaql
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	q, err := Parse(`SELECT campaign.id, ad_group.name, metrics.clicks, segments.date, metrics.clicks
+		FROM ad_group
+		WHERE campaign.status = 'ENABLED' AND segments.date DURING LAST_7_DAYS AND ad_group.status = 'ENABLED'
+		ORDER BY metrics.clicks DESC`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Extract(q)
+
+	check := func(name string, got []string, want ...string) {
+		t.Helper()
+		if want == nil {
+			want = []string{}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	if d.Resource != "ad_group" {
+		t.Errorf("Resource = %q", d.Resource)
+	}
+	check("AttributedResources", d.AttributedResources, "campaign")
+	check("Resources()", d.Resources(), "ad_group", "campaign")
+	check("Attributes", d.Attributes, "ad_group.name", "ad_group.status", "campaign.id", "campaign.status")
+	check("Metrics", d.Metrics, "metrics.clicks")
+	check("Segments", d.Segments, "segments.date")
+	check("Selected", d.Selected, "ad_group.name", "campaign.id", "metrics.clicks", "segments.date")
+	check("Filtered", d.Filtered, "ad_group.status", "campaign.status", "segments.date")
+	check("Sorted", d.Sorted, "metrics.clicks")
+	check("Fields()", d.Fields(), "ad_group.name", "ad_group.status", "campaign.id", "campaign.status", "metrics.clicks", "segments.date")
+}
+
+func TestExtractEmptySets(t *testing.T) {
+	q, err := Parse("SELECT campaign.id FROM campaign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(Extract(q))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"attributed_resources":[]`, `"metrics":[]`, `"filtered":[]`, `"sorted":[]`} {
+		if !strings.Contains(string(b), key) {
+			t.Errorf("%s lacks %s", b, key)
+		}
+	}
+}
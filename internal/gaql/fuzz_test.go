@@ -0,0 +1,85 @@
+package gaql
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fuzzSeeds are the starting corpus of the fuzz targets: valid queries,
+// near misses, and the edge cases the lexer has to survive.
+var fuzzSeeds = []string{
+	"SELECT campaign.id, campaign.name FROM campaign WHERE campaign.status = 'ENABLED' ORDER BY campaign.id DESC LIMIT 10",
+	"SELECT metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS",
+	"SELECT ad_group.id FROM ad_group WHERE ad_group.name IN ('a', \"b\") AND metrics.cost_micros >= -1.5",
+	"SELECT campaign.id FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31' PARAMETERS include_drafts=true",
+	"-- name: weekly\nSELECT campaign.id # id\nFROM campaign",
+	"SELECT campaña.número FROM campaña WHERE campaña.nombre LIKE '%Größe%'",
+	"SELECT 'unterminated",
+	"SELECT a FROM b WHERE c = 'x\\",
+	"ORDER ORDER BY BY",
+	"SELECT \xff\xfe FROM \xc3",
+	"#",
+	"--",
+	"-",
+	"",
+}
+
+// FuzzLexer checks that no input makes the lexer panic, that tokens
+// stay in order within the input, and that an error is the last token.
+func FuzzLexer(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		l := NewLexer(input)
+		tokens, err := l.Tokenize()
+		if len(tokens) == 0 {
+			t.Fatal("no tokens")
+		}
+		last := tokens[len(tokens)-1]
+		if err == nil && last.Type != TokenEOF || err != nil && last.Type != TokenError {
+			t.Fatalf("last token %s with error %v", last.Type, err)
+		}
+		end := 0
+		for _, tok := range append(tokens, l.Comments...) {
+			if tok.Offset < 0 || tok.End.Offset < tok.Offset || tok.End.Offset > len(input) {
+				t.Fatalf("token %s %q spans %d..%d of %d bytes", tok.Type, tok.Value, tok.Offset, tok.End.Offset, len(input))
+			}
+		}
+		for _, tok := range tokens {
+			if tok.Offset < end {
+				t.Fatalf("token %s at %d overlaps the previous one, ending at %d", tok.Type, tok.Offset, end)
+			}
+			end = tok.End.Offset
+			if tok.Type == TokenIdent && !utf8.ValidString(tok.Value) {
+				t.Fatalf("identifier %q is not valid UTF-8", tok.Value)
+			}
+		}
+	})
+}
+
+// FuzzParse checks that no input makes the parser panic, and that a
+// query it accepts prints as text it parses to the same query.
+func FuzzParse(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		q, err := Parse(input)
+		if err != nil {
+			return
+		}
+		text := q.String()
+		again, err := Parse(text)
+		if err != nil {
+			t.Fatalf("Parse(%q) printed %q, which does not parse: %v", input, text, err)
+		}
+		if got := again.String(); got != text {
+			t.Fatalf("Parse(%q) printed %q, then %q", input, text, got)
+		}
+		if strings.ContainsRune(text, utf8.RuneError) && utf8.ValidString(input) {
+			t.Fatalf("Parse(%q) printed %q, with a replacement character", input, text)
+		}
+	})
+}
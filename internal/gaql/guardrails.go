@@ -0,0 +1,228 @@
+package gaql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GuardrailPolicy bounds how much data a query may pull. ApplyGuardrails
+// rewrites a query to fit it, so a caller exploring an account, such as
+// an LLM client, gets a sample instead of millions of rows.
+type GuardrailPolicy struct {
+	// MaxLimit is the largest LIMIT allowed. Queries without a LIMIT or
+	// with a larger one get MaxLimit. Zero leaves LIMIT alone.
+	MaxLimit int
+
+	// MaxDays narrows the segments.date range to its most recent
+	// MaxDays days. Queries selecting metrics without a date range get
+	// one ending yesterday. Zero leaves the date range alone.
+	MaxDays int
+
+	// Today resolves DURING keywords to dates. The zero value means
+	// time.Now.
+	Today time.Time
+}
+
+// ApplyGuardrails rewrites q in place to fit p and returns an info
+// diagnostic for each change. Apply it to validated queries; it does not
+// look at change_event dates or the single-day rule of click_view, whose
+// ranges narrowing keeps valid.
+func ApplyGuardrails(q *Query, p GuardrailPolicy) []Diagnostic {
+	var diags []Diagnostic
+	if p.MaxLimit > 0 && (q.Limit == 0 || q.Limit > p.MaxLimit) {
+		d := Diagnostic{Severity: SeverityInfo, Code: "limit-added", Message: fmt.Sprintf("added LIMIT %d", p.MaxLimit)}
+		if q.Limit > 0 {
+			d.Code = "limit-lowered"
+			d.Message = fmt.Sprintf("lowered LIMIT %d to %d", q.Limit, p.MaxLimit)
+			d.Span = q.LimitSpan
+		}
+		q.Limit = p.MaxLimit
+		diags = append(diags, d)
+	}
+	if p.MaxDays > 0 {
+		today := p.Today
+		if today.IsZero() {
+			today = time.Now()
+		}
+		if d, ok := narrowDates(q, p.MaxDays, today); ok {
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+// narrowDates replaces the segments.date conditions of q with a range
+// of at most days days ending where they end.
+func narrowDates(q *Query, days int, today time.Time) (Diagnostic, bool) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+
+	var old []string
+	first := -1
+	var start, end time.Time
+	for i, c := range q.Where {
+		if c.Field != "segments.date" {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		old = append(old, c.String())
+		s, e := conditionDates(c, today)
+		if !s.IsZero() && (start.IsZero() || s.After(start)) {
+			start = s
+		}
+		if !e.IsZero() && (end.IsZero() || e.Before(end)) {
+			end = e
+		}
+	}
+
+	if first < 0 {
+		if !selectsMetrics(q) {
+			return Diagnostic{}, false
+		}
+		cond := recentDays(yesterday.AddDate(0, 0, 1-days), yesterday, today)
+		q.Where = append(q.Where, cond)
+		return Diagnostic{
+			Severity: SeverityInfo,
+			Code:     "date-added",
+			Message:  "added " + cond.String() + " to bound the metrics",
+		}, true
+	}
+	if end.IsZero() {
+		// Only a lower bound: the range runs through today.
+		end = today
+	}
+	if !start.IsZero() && daysInclusive(start, end) <= days {
+		return Diagnostic{}, false
+	}
+
+	cond := recentDays(end.AddDate(0, 0, 1-days), end, today)
+	where := make([]Condition, 0, len(q.Where))
+	for i, c := range q.Where {
+		if i == first {
+			where = append(where, cond)
+		}
+		if c.Field != "segments.date" {
+			where = append(where, c)
+		}
+	}
+	q.Where = where
+	return Diagnostic{
+		Severity: SeverityInfo,
+		Code:     "date-narrowed",
+		Message:  fmt.Sprintf("narrowed %s to %s", strings.Join(old, " AND "), cond.String()),
+		Field:    "segments.date",
+	}, true
+}
+
+// recentDays returns the segments.date condition for start..end,
+// using TODAY or YESTERDAY for a single such day.
+func recentDays(start, end, today time.Time) Condition {
+	cond := Condition{Field: "segments.date"}
+	switch {
+	case start.Equal(end) && end.Equal(today):
+		cond.Operator = OpDuring
+		cond.Value = Value{Type: ValueDateRange, DateRange: DateRangeToday}
+	case start.Equal(end) && end.Equal(today.AddDate(0, 0, -1)):
+		cond.Operator = OpDuring
+		cond.Value = Value{Type: ValueDateRange, DateRange: DateRangeYesterday}
+	case start.Equal(end):
+		cond.Operator = OpEq
+		cond.Value = Value{Type: ValueString, Str: end.Format(dateLayout)}
+	default:
+		cond.Operator = OpBetween
+		cond.Value = Value{Type: ValueList, List: []string{start.Format(dateLayout), end.Format(dateLayout)}}
+	}
+	return cond
+}
+
+// conditionDates returns the first and last day a segments.date
+// condition allows, zero where it sets no bound.
+func conditionDates(c Condition, today time.Time) (start, end time.Time) {
+	parse := func(s string) time.Time {
+		t, _ := time.Parse(dateLayout, s)
+		return t
+	}
+	switch c.Operator {
+	case OpDuring:
+		return keywordDates(c.Value.DateRange, today)
+	case OpEq:
+		t := parse(c.Value.Str)
+		return t, t
+	case OpBetween:
+		if len(c.Value.List) == 2 {
+			return parse(c.Value.List[0]), parse(c.Value.List[1])
+		}
+	case OpIn:
+		for _, s := range c.Value.List {
+			t := parse(s)
+			if start.IsZero() || t.Before(start) {
+				start = t
+			}
+			if t.After(end) {
+				end = t
+			}
+		}
+		return start, end
+	case OpGte:
+		return parse(c.Value.Str), time.Time{}
+	case OpGt:
+		if t := parse(c.Value.Str); !t.IsZero() {
+			return t.AddDate(0, 0, 1), time.Time{}
+		}
+	case OpLte:
+		return time.Time{}, parse(c.Value.Str)
+	case OpLt:
+		if t := parse(c.Value.Str); !t.IsZero() {
+			return time.Time{}, t.AddDate(0, 0, -1)
+		}
+	}
+	return time.Time{}, time.Time{}
+}
+
+// keywordDates resolves a DURING keyword to the days it covers. Weeks
+// and months follow the calendar; registered keywords such as
+// LAST_90_DAYS end yesterday.
+func keywordDates(dr DateRange, today time.Time) (start, end time.Time) {
+	yesterday := today.AddDate(0, 0, -1)
+	sinceMonday := (int(today.Weekday()) + 6) % 7
+	switch dr {
+	case DateRangeToday:
+		return today, today
+	case DateRangeThisMonth:
+		return today.AddDate(0, 0, 1-today.Day()), today
+	case DateRangeThisWeekSunToday:
+		return today.AddDate(0, 0, -int(today.Weekday())), today
+	case DateRangeThisWeekMonToday:
+		return today.AddDate(0, 0, -sinceMonday), today
+	case DateRangeLastMonth:
+		end = today.AddDate(0, 0, -today.Day())
+		return end.AddDate(0, 0, 1-end.Day()), end
+	case DateRangeLastWeekSunSat:
+		end = today.AddDate(0, 0, -int(today.Weekday())-1)
+		return end.AddDate(0, 0, -6), end
+	case DateRangeLastWeekMonSun:
+		end = today.AddDate(0, 0, -sinceMonday-1)
+		return end.AddDate(0, 0, -6), end
+	case DateRangeLastBusinessWeek:
+		end = today.AddDate(0, 0, -sinceMonday-3)
+		return end.AddDate(0, 0, -4), end
+	}
+	n := dr.Days()
+	if n == 0 {
+		return time.Time{}, yesterday
+	}
+	return yesterday.AddDate(0, 0, 1-n), yesterday
+}
+
+// selectsMetrics reports whether q selects a metrics field.
+func selectsMetrics(q *Query) bool {
+	for _, f := range q.Select {
+		if strings.HasPrefix(f.Name, "metrics.") {
+			return true
+		}
+	}
+	return false
+}
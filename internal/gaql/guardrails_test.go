@@ -0,0 +1,92 @@
+package gaql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyGuardrails(t *testing.T) {
+	// 2026-03-04 is a Wednesday.
+	today := time.Date(2026, 3, 4, 15, 0, 0, 0, time.Local)
+	sample := GuardrailPolicy{MaxLimit: 100, MaxDays: 1, Today: today}
+	tests := []struct {
+		name   string
+		input  string
+		policy GuardrailPolicy
+		want   string
+		codes  []string
+	}{
+		{
+			name:   "keyword range",
+			input:  "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_30_DAYS AND campaign.status = 'ENABLED'",
+			policy: sample,
+			want:   "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING YESTERDAY AND campaign.status = 'ENABLED' LIMIT 100",
+			codes:  []string{"limit-added", "date-narrowed"},
+		},
+		{
+			name:   "range ending today",
+			input:  "SELECT metrics.clicks FROM customer WHERE segments.date DURING THIS_MONTH LIMIT 10",
+			policy: sample,
+			want:   "SELECT metrics.clicks FROM customer WHERE segments.date DURING TODAY LIMIT 10",
+			codes:  []string{"date-narrowed"},
+		},
+		{
+			name:   "last week",
+			input:  "SELECT metrics.clicks FROM customer WHERE segments.date DURING LAST_WEEK_MON_SUN LIMIT 500",
+			policy: sample,
+			want:   "SELECT metrics.clicks FROM customer WHERE segments.date = '2026-03-01' LIMIT 100",
+			codes:  []string{"limit-lowered", "date-narrowed"},
+		},
+		{
+			name:   "bounds",
+			input:  "SELECT metrics.clicks FROM customer WHERE segments.date >= '2026-01-01' AND segments.date <= '2026-01-31'",
+			policy: GuardrailPolicy{MaxDays: 7, Today: today},
+			want:   "SELECT metrics.clicks FROM customer WHERE segments.date BETWEEN '2026-01-25' AND '2026-01-31'",
+			codes:  []string{"date-narrowed"},
+		},
+		{
+			name:   "within the days",
+			input:  "SELECT metrics.clicks FROM customer WHERE segments.date BETWEEN '2026-01-25' AND '2026-01-31'",
+			policy: GuardrailPolicy{MaxDays: 7, Today: today},
+			want:   "SELECT metrics.clicks FROM customer WHERE segments.date BETWEEN '2026-01-25' AND '2026-01-31'",
+		},
+		{
+			name:   "metrics without dates",
+			input:  "SELECT campaign.id, metrics.clicks FROM campaign",
+			policy: GuardrailPolicy{MaxDays: 7, Today: today},
+			want:   "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date BETWEEN '2026-02-25' AND '2026-03-03'",
+			codes:  []string{"date-added"},
+		},
+		{
+			name:   "attributes only",
+			input:  "SELECT campaign.id FROM campaign",
+			policy: sample,
+			want:   "SELECT campaign.id FROM campaign LIMIT 100",
+			codes:  []string{"limit-added"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diags := ApplyGuardrails(q, tt.policy)
+			if q.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", q, tt.want)
+			}
+			var codes []string
+			for _, d := range diags {
+				codes = append(codes, d.Code)
+			}
+			if len(codes) != len(tt.codes) {
+				t.Fatalf("codes = %v, want %v", codes, tt.codes)
+			}
+			for i := range codes {
+				if codes[i] != tt.codes[i] {
+					t.Errorf("codes = %v, want %v", codes, tt.codes)
+				}
+			}
+		})
+	}
+}
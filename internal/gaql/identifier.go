@@ -0,0 +1,151 @@
+package gaql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IdentifierCheck is how strictly a Validator checks field paths such as
+// campaign.network_settings.target_search_network.
+type IdentifierCheck int
+
+const (
+	// IdentifierSyntax requires every dot-separated segment of a field
+	// path to be lowercase snake_case that does not start with a digit.
+	// Field paths under a namespace that is neither metrics, segments,
+	// nor a known resource draw an "unknown-namespace" warning from
+	// Check. This is the default.
+	IdentifierSyntax IdentifierCheck = iota
+
+	// IdentifierNamespace also rejects field paths whose first segment
+	// is not metrics, segments, the FROM resource, or a resource in
+	// KnownResources or the catalog, catching typos such as
+	// metric.clicks before the query is sent.
+	IdentifierNamespace
+
+	// IdentifierAny accepts any non-empty field path.
+	IdentifierAny
+)
+
+// checkIdentifier returns why name is not a valid field path, or "".
+func checkIdentifier(name string) string {
+	for _, seg := range strings.Split(name, ".") {
+		switch {
+		case seg == "":
+			return "has an empty segment"
+		case isDigit(seg[0]):
+			return fmt.Sprintf("segment %q starts with a digit", seg)
+		}
+		for i := 0; i < len(seg); i++ {
+			if ch := seg[i]; !(ch >= 'a' && ch <= 'z' || isDigit(ch) || ch == '_') || i == 0 && ch == '_' {
+				return fmt.Sprintf("segment %q is not lowercase snake_case", seg)
+			}
+		}
+	}
+	return ""
+}
+
+// knownNamespace reports whether the first segment of a field path is
+// metrics, segments, from, or a known resource, and when it is not,
+// suggests the known namespace it is most likely a typo of.
+func knownNamespace(name, from string, c *Catalog) (ok bool, suggestion string) {
+	ns, _, _ := strings.Cut(name, ".")
+	if ns == "metrics" || ns == "segments" || ns == from || KnownResources[ns] {
+		return true, ""
+	}
+	if f, found := c.Field(ns); found && f.Category == "RESOURCE" {
+		return true, ""
+	}
+
+	best := 3 // suggest only names within two edits
+	consider := func(candidate string) {
+		if d := editDistance(ns, candidate); d < best {
+			best, suggestion = d, candidate
+		}
+	}
+	consider("metrics")
+	consider("segments")
+	consider(from)
+	for r := range KnownResources {
+		consider(r)
+	}
+	return false, suggestion
+}
+
+// similarField returns the catalog field under the same namespace as
+// name that it is most likely a typo of, or "".
+func similarField(c *Catalog, name string) string {
+	ns, _, _ := strings.Cut(name, ".")
+	best, suggestion := 3, "" // within two edits
+	for _, f := range c.Fields() {
+		if !strings.HasPrefix(f.Name, ns+".") {
+			continue
+		}
+		if d := editDistance(name, f.Name); d < best {
+			best, suggestion = d, f.Name
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// unknownNamespaces warns about field paths outside the known
+// namespaces, for validators that do not reject them.
+func unknownNamespaces(q *Query, c *Catalog) []Diagnostic {
+	var diags []Diagnostic
+	seen := map[string]bool{}
+	Walk(q, func(n Node) bool {
+		var name string
+		var span Span
+		switch n := n.(type) {
+		case *Field:
+			name, span = n.Name, n.Span
+		case *Condition:
+			name, span = n.Field, n.FieldSpan
+		case *Ordering:
+			name, span = n.Field, n.Span
+		default:
+			return true
+		}
+		if seen[name] {
+			return true
+		}
+		seen[name] = true
+		if ok, suggestion := knownNamespace(name, q.From, c); !ok {
+			ns, _, _ := strings.Cut(name, ".")
+			d := Diagnostic{
+				Severity: SeverityWarning,
+				Code:     "unknown-namespace",
+				Message:  fmt.Sprintf("%s is not metrics, segments, or a known resource", ns),
+				Field:    name,
+				Span:     span,
+			}
+			if suggestion != "" {
+				d.Hint = "did you mean " + suggestion + strings.TrimPrefix(name, ns) + "?"
+			}
+			diags = append(diags, d)
+		}
+		return true
+	})
+	return diags
+}
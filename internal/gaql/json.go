@@ -0,0 +1,386 @@
+package gaql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//go:embed query.schema.json
+var querySchema []byte
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of the AST JSON form
+// of a Query, for services consuming parsed queries without this
+// package.
+func JSONSchema() []byte {
+	return append([]byte(nil), querySchema...)
+}
+
+// astQuery is the JSON form of a Query. Operators, directions, and date
+// ranges are written by name, and source spans are left out unless asked
+// for, so the form is stable across parser changes and reads the same
+// whether the query was parsed or built.
+type astQuery struct {
+	Select     []astField        `json:"select"`
+	From       string            `json:"from"`
+	Where      []astCondition    `json:"where,omitempty"`
+	OrderBy    []astOrdering     `json:"order_by,omitempty"`
+	Limit      int               `json:"limit,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Spans      *astClauseSpans   `json:"spans,omitempty"`
+}
+
+// astClauseSpans are the spans of the clauses of a query, from the
+// keyword to the end of the clause.
+type astClauseSpans struct {
+	Select     *astSpan `json:"select,omitempty"`
+	From       *astSpan `json:"from,omitempty"`
+	Where      *astSpan `json:"where,omitempty"`
+	OrderBy    *astSpan `json:"order_by,omitempty"`
+	Limit      *astSpan `json:"limit,omitempty"`
+	Parameters *astSpan `json:"parameters,omitempty"`
+}
+
+type astSpan struct {
+	Start astPos `json:"start"`
+	End   astPos `json:"end"`
+}
+
+type astPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+type astField struct {
+	Name string   `json:"name"`
+	Span *astSpan `json:"span,omitempty"`
+}
+
+type astCondition struct {
+	Field     string    `json:"field"`
+	Operator  string    `json:"operator"`
+	Value     *astValue `json:"value,omitempty"`
+	DataType  string    `json:"data_type,omitempty"`
+	Span      *astSpan  `json:"span,omitempty"`
+	FieldSpan *astSpan  `json:"field_span,omitempty"`
+	ValueSpan *astSpan  `json:"value_span,omitempty"`
+}
+
+// astValue holds a condition value. Value is a string for "string" and
+// "date_range", a number for "number", a list of strings for "list",
+// and left out for "null", the value of IS NULL and IS NOT NULL.
+type astValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type astOrdering struct {
+	Field     string   `json:"field"`
+	Direction string   `json:"direction"`
+	Span      *astSpan `json:"span,omitempty"`
+}
+
+// spanJSON returns the JSON form of s, or nil when s was not parsed.
+func spanJSON(s Span) *astSpan {
+	if !s.IsValid() {
+		return nil
+	}
+	return &astSpan{
+		Start: astPos{Line: s.Start.Line, Column: s.Start.Column, Offset: s.Start.Offset},
+		End:   astPos{Line: s.End.Line, Column: s.End.Column, Offset: s.End.Offset},
+	}
+}
+
+// span returns the Span s describes; nil is the zero Span.
+func (s *astSpan) span() Span {
+	if s == nil {
+		return Span{}
+	}
+	return Span{
+		Start: Pos{Line: s.Start.Line, Column: s.Start.Column, Offset: s.Start.Offset},
+		End:   Pos{Line: s.End.Line, Column: s.End.Column, Offset: s.End.Offset},
+	}
+}
+
+var valueTypeNames = map[ValueType]string{
+	ValueString:    "string",
+	ValueNumber:    "number",
+	ValueList:      "list",
+	ValueDateRange: "date_range",
+	ValueNull:      "null",
+}
+
+// MarshalJSON writes the query in its AST JSON form, which JSONSchema
+// describes:
+//
+//	{"select": [{"name": "campaign.id"}], "from": "campaign",
+//	 "where": [{"field": "segments.date", "operator": "DURING",
+//	            "value": {"type": "date_range", "value": "LAST_7_DAYS"}}],
+//	 "order_by": [{"field": "campaign.id", "direction": "ASC"}],
+//	 "limit": 10}
+func (q Query) MarshalJSON() ([]byte, error) {
+	out, err := q.ast(false)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// MarshalJSONPositions writes the AST JSON form with the source spans of
+// the clauses, fields, conditions, and orderings the parser recorded, as
+// the line, column, and byte offset of their start and end. UnmarshalJSON
+// reads them back.
+func (q Query) MarshalJSONPositions() ([]byte, error) {
+	out, err := q.ast(true)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// ast returns the AST JSON form of q, with source spans if positions
+// is set.
+func (q Query) ast(positions bool) (astQuery, error) {
+	span := func(s Span) *astSpan {
+		if !positions {
+			return nil
+		}
+		return spanJSON(s)
+	}
+	out := astQuery{
+		Select:     make([]astField, len(q.Select)),
+		From:       q.From,
+		Limit:      q.Limit,
+		Parameters: q.Parameters,
+	}
+	for i, f := range q.Select {
+		out.Select[i] = astField{Name: f.Name, Span: span(f.Span)}
+	}
+	for _, c := range q.Where {
+		v, err := marshalValue(c.Value)
+		if err != nil {
+			return astQuery{}, fmt.Errorf("gaql: condition on %s: %w", c.Field, err)
+		}
+		out.Where = append(out.Where, astCondition{Field: c.Field, Operator: c.Operator.String(), Value: v, DataType: c.DataType,
+			Span: span(c.Span), FieldSpan: span(c.FieldSpan), ValueSpan: span(c.ValueSpan)})
+	}
+	for _, o := range q.OrderBy {
+		out.OrderBy = append(out.OrderBy, astOrdering{Field: o.Field, Direction: o.Direction.String(), Span: span(o.Span)})
+	}
+	if positions && q.SelectSpan.IsValid() {
+		out.Spans = &astClauseSpans{
+			Select:     spanJSON(q.SelectSpan),
+			From:       spanJSON(q.FromSpan),
+			Where:      spanJSON(q.WhereSpan),
+			OrderBy:    spanJSON(q.OrderBySpan),
+			Limit:      spanJSON(q.LimitSpan),
+			Parameters: spanJSON(q.ParametersSpan),
+		}
+	}
+	return out, nil
+}
+
+func marshalValue(v Value) (*astValue, error) {
+	name, ok := valueTypeNames[v.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown value type %d", v.Type)
+	}
+	var raw any
+	switch v.Type {
+	case ValueString:
+		raw = v.Str
+	case ValueNumber:
+		raw = v.Number
+	case ValueList:
+		raw = append([]string{}, v.List...)
+	case ValueDateRange:
+		raw = v.DateRange.String()
+	case ValueNull:
+		return &astValue{Type: name}, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &astValue{Type: name, Value: data}, nil
+}
+
+// UnmarshalJSON reads the AST JSON form written by MarshalJSON or
+// MarshalJSONPositions. Spans left out are zero.
+func (q *Query) UnmarshalJSON(data []byte) error {
+	var in astQuery
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	out := Query{From: in.From, Limit: in.Limit, Parameters: in.Parameters}
+	if s := in.Spans; s != nil {
+		out.SelectSpan, out.FromSpan, out.WhereSpan = s.Select.span(), s.From.span(), s.Where.span()
+		out.OrderBySpan, out.LimitSpan, out.ParametersSpan = s.OrderBy.span(), s.Limit.span(), s.Parameters.span()
+	}
+	for _, f := range in.Select {
+		out.Select = append(out.Select, Field{Name: f.Name, Span: f.Span.span()})
+	}
+	for _, ac := range in.Where {
+		op, err := LookupOperator(ac.Operator)
+		if err != nil {
+			return err
+		}
+		c := Condition{Field: ac.Field, Operator: op, Value: Value{Type: ValueNull}, DataType: ac.DataType,
+			Span: ac.Span.span(), FieldSpan: ac.FieldSpan.span(), ValueSpan: ac.ValueSpan.span()}
+		if ac.Value != nil {
+			if c.Value, err = unmarshalValue(*ac.Value); err != nil {
+				return fmt.Errorf("gaql: condition on %s: %w", ac.Field, err)
+			}
+		} else if op != OpIsNull && op != OpIsNotNull {
+			return fmt.Errorf("gaql: condition on %s: value is missing", ac.Field)
+		}
+		out.Where = append(out.Where, c)
+	}
+	for _, ao := range in.OrderBy {
+		dir, err := lookupDirection(ao.Direction)
+		if err != nil {
+			return err
+		}
+		out.OrderBy = append(out.OrderBy, Ordering{Field: ao.Field, Direction: dir, Span: ao.Span.span()})
+	}
+	*q = out
+	return nil
+}
+
+func unmarshalValue(av astValue) (Value, error) {
+	var v Value
+	var dest any
+	switch av.Type {
+	case "string":
+		v.Type, dest = ValueString, &v.Str
+	case "number":
+		v.Type, dest = ValueNumber, &v.Number
+	case "list":
+		v.Type, dest = ValueList, &v.List
+	case "date_range":
+		var keyword string
+		if err := json.Unmarshal(av.Value, &keyword); err != nil {
+			return Value{}, fmt.Errorf("date_range value: %w", err)
+		}
+		dr, ok := LookupDateRange(keyword)
+		if !ok {
+			return Value{}, fmt.Errorf("unknown date range %q", keyword)
+		}
+		return Value{Type: ValueDateRange, DateRange: dr}, nil
+	case "null":
+		return Value{Type: ValueNull}, nil
+	default:
+		return Value{}, fmt.Errorf("unknown value type %q", av.Type)
+	}
+	if len(av.Value) == 0 {
+		return Value{}, fmt.Errorf("%s value is missing", av.Type)
+	}
+	if err := json.Unmarshal(av.Value, dest); err != nil {
+		return Value{}, fmt.Errorf("%s value: %w", av.Type, err)
+	}
+	return v, nil
+}
+
+// LookupOperator returns the operator written as s, such as ">=" or
+// "not in". Case and the spacing between words do not matter.
+func LookupOperator(s string) (Operator, error) {
+	norm := strings.ToUpper(strings.Join(strings.Fields(s), " "))
+	for op := OpEq; op <= OpNotRegexpMatch; op++ {
+		if op.String() == norm {
+			return op, nil
+		}
+	}
+	if norm == "<>" {
+		return OpNeq, nil
+	}
+	return 0, fmt.Errorf("gaql: unknown operator %q", s)
+}
+
+func lookupDirection(s string) (Direction, error) {
+	switch strings.ToUpper(s) {
+	case "", "ASC":
+		return Asc, nil
+	case "DESC":
+		return Desc, nil
+	}
+	return 0, fmt.Errorf("gaql: unknown sort direction %q", s)
+}
+
+// The enumerations of the AST encode as their names in JSON and other
+// text formats, as in the AST JSON form, rather than as numbers.
+
+// MarshalText returns the operator as written in GAQL, such as "NOT IN".
+func (o Operator) MarshalText() ([]byte, error) {
+	if o < OpEq || o > OpNotRegexpMatch {
+		return nil, fmt.Errorf("gaql: unknown operator %d", int(o))
+	}
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText reads an operator as LookupOperator does.
+func (o *Operator) UnmarshalText(text []byte) error {
+	op, err := LookupOperator(string(text))
+	if err != nil {
+		return err
+	}
+	*o = op
+	return nil
+}
+
+// MarshalText returns "ASC" or "DESC".
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText reads "ASC" or "DESC" in any case.
+func (d *Direction) UnmarshalText(text []byte) error {
+	dir, err := lookupDirection(string(text))
+	if err != nil {
+		return err
+	}
+	*d = dir
+	return nil
+}
+
+// MarshalText returns the DURING keyword, such as "LAST_7_DAYS", or
+// "CUSTOM" for DateRangeCustom.
+func (d DateRange) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText reads a registered DURING keyword or "CUSTOM".
+func (d *DateRange) UnmarshalText(text []byte) error {
+	if strings.EqualFold(string(text), "CUSTOM") {
+		*d = DateRangeCustom
+		return nil
+	}
+	dr, ok := LookupDateRange(string(text))
+	if !ok {
+		return fmt.Errorf("gaql: unknown date range %q", text)
+	}
+	*d = dr
+	return nil
+}
+
+// MarshalText returns the name of the value type in the AST JSON form,
+// such as "date_range".
+func (t ValueType) MarshalText() ([]byte, error) {
+	name, ok := valueTypeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("gaql: unknown value type %d", int(t))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText reads a value type name written by MarshalText.
+func (t *ValueType) UnmarshalText(text []byte) error {
+	for vt, name := range valueTypeNames {
+		if name == string(text) {
+			*t = vt
+			return nil
+		}
+	}
+	return fmt.Errorf("gaql: unknown value type %q", text)
+}
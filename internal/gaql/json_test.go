@@ -0,0 +1,156 @@
+package gaql
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEnumText(t *testing.T) {
+	tests := []struct {
+		v    any // pointer to the value to marshal and unmarshal into
+		want string
+	}{
+		{ptr(OpNotIn), `"NOT IN"`},
+		{ptr(OpRegexpMatch), `"REGEXP_MATCH"`},
+		{ptr(Desc), `"DESC"`},
+		{ptr(DateRangeLast7Days), `"LAST_7_DAYS"`},
+		{ptr(DateRangeCustom), `"CUSTOM"`},
+		{ptr(ValueDateRange), `"date_range"`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.v)
+		if err != nil || string(data) != tt.want {
+			t.Errorf("Marshal(%v) = %s, %v; want %s", reflect.ValueOf(tt.v).Elem(), data, err, tt.want)
+			continue
+		}
+		back := reflect.New(reflect.TypeOf(tt.v).Elem())
+		if err := json.Unmarshal(data, back.Interface()); err != nil {
+			t.Errorf("Unmarshal(%s): %v", data, err)
+		} else if back.Elem().Interface() != reflect.ValueOf(tt.v).Elem().Interface() {
+			t.Errorf("Unmarshal(%s) = %v", data, back.Elem())
+		}
+	}
+
+	// A condition marshaled on its own, outside the AST form, is readable too.
+	data, _ := json.Marshal(Condition{Field: "segments.date", Operator: OpDuring, Value: Value{Type: ValueDateRange, DateRange: DateRangeYesterday}})
+	var got struct {
+		Operator string
+		Value    struct{ Type, DateRange string }
+	}
+	json.Unmarshal(data, &got)
+	if got.Operator != "DURING" || got.Value.Type != "date_range" || got.Value.DateRange != "YESTERDAY" {
+		t.Errorf("Condition marshaled as %s", data)
+	}
+
+	for _, bad := range []struct {
+		v    any
+		text string
+	}{
+		{new(Operator), `"=="`},
+		{new(Direction), `"UP"`},
+		{new(DateRange), `"LAST_8_DAYS"`},
+		{new(ValueType), `"date"`},
+	} {
+		if err := json.Unmarshal([]byte(bad.text), bad.v); err == nil {
+			t.Errorf("Unmarshal(%s) into %T succeeded", bad.text, bad.v)
+		}
+	}
+	if _, err := json.Marshal(Operator(99)); err == nil {
+		t.Error("Marshal(Operator(99)) succeeded")
+	}
+}
+
+func TestMarshalJSONPositions(t *testing.T) {
+	q, err := Parse("SELECT campaign.id\nFROM campaign\nWHERE campaign.status = 'ENABLED'\nORDER BY campaign.id LIMIT 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := json.Marshal(q)
+	if strings.Contains(string(plain), "span") {
+		t.Errorf("MarshalJSON wrote spans: %s", plain)
+	}
+	data, err := q.MarshalJSONPositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"name":"campaign.id","span":{"start":{"line":1,"column":8,"offset":7},"end":{"line":1,"column":19,"offset":18}}`,
+		`"field_span":{"start":{"line":3,"column":7,"offset":39}`,
+		`"spans":{"select":{"start":{"line":1,"column":1,"offset":0}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("MarshalJSONPositions lacks %s:\n%s", want, data)
+		}
+	}
+	var got Query
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Parameters) == 0 {
+		got.Parameters = q.Parameters // empty either way
+	}
+	if !reflect.DeepEqual(&got, q) {
+		t.Errorf("round trip with positions:\n got %+v\nwant %+v", got, *q)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// TestJSONSchema checks that the schema lists the names the AST form
+// uses and that its example is a query.
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		Defs struct {
+			Condition struct {
+				Properties struct {
+					Operator struct {
+						Enum []string `json:"enum"`
+					} `json:"operator"`
+				} `json:"properties"`
+			} `json:"condition"`
+			Value struct {
+				Properties struct {
+					Type struct {
+						Enum []string `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+			} `json:"value"`
+		} `json:"$defs"`
+		Examples []json.RawMessage `json:"examples"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+
+	var operators []string
+	for op := OpEq; op <= OpNotRegexpMatch; op++ {
+		operators = append(operators, op.String())
+	}
+	if !slices.Equal(schema.Defs.Condition.Properties.Operator.Enum, operators) {
+		t.Errorf("schema operators %q, want %q", schema.Defs.Condition.Properties.Operator.Enum, operators)
+	}
+	var types []string
+	for vt := ValueString; vt <= ValueNull; vt++ {
+		types = append(types, valueTypeNames[vt])
+	}
+	if !slices.Equal(schema.Defs.Value.Properties.Type.Enum, types) {
+		t.Errorf("schema value types %q, want %q", schema.Defs.Value.Properties.Type.Enum, types)
+	}
+
+	if len(schema.Examples) == 0 {
+		t.Fatal("schema has no examples")
+	}
+	for _, ex := range schema.Examples {
+		var q Query
+		if err := json.Unmarshal(ex, &q); err != nil {
+			t.Errorf("example %s: %v", ex, err)
+			continue
+		}
+		if _, err := Parse(q.String()); err != nil {
+			t.Errorf("example %s is not a valid query: %v", ex, err)
+		}
+	}
+}
@@ -1,17 +1,29 @@
 package gaql
 
 import (
+	"fmt"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
-// Lexer tokenizes GAQL input.
+// Lexer tokenizes GAQL input. It reads the input as UTF-8, one rune at
+// a time, so identifiers and strings may hold any letters; positions
+// are still counted in bytes.
+//
+// "--" and "#" start comments running to the end of the line. They are
+// not tokens the parser sees: the lexer skips them and keeps them in
+// Comments, as TokenComment tokens, for tools that must preserve them.
 type Lexer struct {
-	input   string
-	pos     int
-	line    int
-	column  int
-	tokens  []Token
+	input  string
+	pos    int
+	line   int
+	column int
+	tokens []Token
+
+	// Comments holds the comments skipped so far, in order, with the
+	// comment marker included in their Value.
+	Comments []Token
 }
 
 // NewLexer creates a new lexer for the given input.
@@ -27,7 +39,11 @@ func NewLexer(input string) *Lexer {
 // Tokenize returns all tokens from the input.
 func (l *Lexer) Tokenize() ([]Token, error) {
 	for {
+		l.skipTrivia()
+		start := l.pos
 		tok := l.nextToken()
+		tok.Offset = start
+		tok.End = Pos{Line: l.line, Column: l.column, Offset: l.pos}
 		l.tokens = append(l.tokens, tok)
 		if tok.Type == TokenEOF {
 			break
@@ -37,6 +53,7 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 				Message: tok.Value,
 				Line:    tok.Line,
 				Column:  tok.Column,
+				Offset:  tok.Offset,
 			}
 		}
 	}
@@ -44,15 +61,17 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 }
 
 func (l *Lexer) nextToken() Token {
-	l.skipWhitespace()
-
 	if l.pos >= len(l.input) {
 		return Token{Type: TokenEOF, Line: l.line, Column: l.column}
 	}
 
-	ch := l.input[l.pos]
+	ch, size := l.peekRune(0)
 	startLine := l.line
 	startCol := l.column
+	if ch == utf8.RuneError && size == 1 {
+		l.advance()
+		return Token{Type: TokenError, Value: "invalid UTF-8 encoding", Line: startLine, Column: startCol}
+	}
 
 	// Single character tokens
 	switch ch {
@@ -95,21 +114,21 @@ func (l *Lexer) nextToken() Token {
 		l.advance()
 		return Token{Type: TokenLt, Value: "<", Line: startLine, Column: startCol}
 	case '\'', '"':
-		return l.readString(ch)
+		return l.readString(byte(ch))
 	}
 
 	// Numbers (including negative)
-	if ch == '-' || isDigit(ch) {
+	if ch >= '0' && ch <= '9' || ch == '-' && isDigit(l.peek(1)) {
 		return l.readNumber()
 	}
 
 	// Identifiers and keywords
-	if isLetter(ch) || ch == '_' {
+	if isIdentStart(ch) {
 		return l.readIdentOrKeyword()
 	}
 
 	l.advance()
-	return Token{Type: TokenError, Value: "unexpected character '" + string(ch) + "'", Line: startLine, Column: startCol}
+	return Token{Type: TokenError, Value: fmt.Sprintf("unexpected character %q", ch), Line: startLine, Column: startCol}
 }
 
 func (l *Lexer) readString(quote byte) Token {
@@ -119,32 +138,32 @@ func (l *Lexer) readString(quote byte) Token {
 
 	var sb strings.Builder
 	for l.pos < len(l.input) {
-		ch := l.input[l.pos]
-		if ch == quote {
+		ch, size := l.peekRune(0)
+		if ch == utf8.RuneError && size == 1 {
+			return Token{Type: TokenError, Value: "invalid UTF-8 encoding in string", Line: l.line, Column: l.column}
+		}
+		if ch == rune(quote) {
 			l.advance() // consume closing quote
 			return Token{Type: TokenString, Value: sb.String(), Line: startLine, Column: startCol}
 		}
 		if ch == '\\' && l.pos+1 < len(l.input) {
 			l.advance()
-			escaped := l.input[l.pos]
+			escaped, size := l.peekRune(0)
+			if escaped == utf8.RuneError && size == 1 {
+				return Token{Type: TokenError, Value: "invalid UTF-8 encoding in string", Line: l.line, Column: l.column}
+			}
 			switch escaped {
 			case 'n':
 				sb.WriteByte('\n')
 			case 't':
 				sb.WriteByte('\t')
-			case '\\':
-				sb.WriteByte('\\')
-			case '\'':
-				sb.WriteByte('\'')
-			case '"':
-				sb.WriteByte('"')
 			default:
-				sb.WriteByte(escaped)
+				sb.WriteRune(escaped)
 			}
 			l.advance()
 			continue
 		}
-		sb.WriteByte(ch)
+		sb.WriteRune(ch)
 		l.advance()
 	}
 
@@ -183,26 +202,28 @@ func (l *Lexer) readIdentOrKeyword() Token {
 	startCol := l.column
 	startPos := l.pos
 
-	for l.pos < len(l.input) && (isLetter(l.input[l.pos]) || isDigit(l.input[l.pos]) || l.input[l.pos] == '_') {
-		l.advance()
-	}
-
+	l.skipIdent()
 	value := l.input[startPos:l.pos]
 	upper := strings.ToUpper(value)
 
-	// Check for ORDER BY (two-word keyword)
+	// Check for ORDER BY (two-word keyword). BY must be a word of its
+	// own; otherwise ORDER stays an identifier and ends where it did.
 	if upper == "ORDER" {
-		l.skipWhitespace()
-		if l.pos+2 <= len(l.input) && strings.ToUpper(l.input[l.pos:l.pos+2]) == "BY" {
-			l.advance()
-			l.advance()
-			return Token{Type: TokenOrderBy, Value: "ORDER BY", Line: startLine, Column: startCol}
+		saved := *l
+		l.skipTrivia()
+		byStart := l.pos
+		if ch, _ := l.peekRune(0); isIdentStart(ch) {
+			l.skipIdent()
+			if strings.EqualFold(l.input[byStart:l.pos], "BY") {
+				return Token{Type: TokenOrderBy, Value: "ORDER BY", Line: startLine, Column: startCol}
+			}
 		}
+		*l = saved
 		return Token{Type: TokenIdent, Value: value, Line: startLine, Column: startCol}
 	}
 
 	// Check for date range keywords
-	if _, ok := DateRangeKeywords[upper]; ok {
+	if _, ok := LookupDateRange(upper); ok {
 		return Token{Type: TokenDateRange, Value: upper, Line: startLine, Column: startCol}
 	}
 
@@ -214,34 +235,59 @@ func (l *Lexer) readIdentOrKeyword() Token {
 	return Token{Type: TokenIdent, Value: value, Line: startLine, Column: startCol}
 }
 
-func (l *Lexer) skipWhitespace() {
+// skipIdent advances past the rest of an identifier.
+func (l *Lexer) skipIdent() {
 	for l.pos < len(l.input) {
-		ch := l.input[l.pos]
-		if ch == ' ' || ch == '\t' || ch == '\r' {
-			l.column++
-			l.pos++
-		} else if ch == '\n' {
-			l.line++
-			l.column = 1
-			l.pos++
-		} else {
+		ch, _ := l.peekRune(0)
+		if !isIdentPart(ch) {
 			break
 		}
+		l.advance()
 	}
 }
 
-func (l *Lexer) advance() {
-	if l.pos < len(l.input) {
-		if l.input[l.pos] == '\n' {
-			l.line++
-			l.column = 1
-		} else {
-			l.column++
+// skipTrivia advances past whitespace and comments, keeping the
+// comments in l.Comments.
+func (l *Lexer) skipTrivia() {
+	for l.pos < len(l.input) {
+		ch, _ := l.peekRune(0)
+		switch {
+		case ch == '\n' || ch == ' ' || ch == '\t' || ch == '\r':
+			l.advance()
+		case ch == '#' || ch == '-' && l.peek(1) == '-':
+			tok := Token{Type: TokenComment, Line: l.line, Column: l.column, Offset: l.pos}
+			end := strings.IndexByte(l.input[l.pos:], '\n')
+			if end < 0 {
+				end = len(l.input) - l.pos
+			}
+			tok.Value = strings.TrimRight(l.input[l.pos:l.pos+end], "\r")
+			l.column += end
+			l.pos += end
+			tok.End = Pos{Line: l.line, Column: l.column, Offset: l.pos}
+			l.Comments = append(l.Comments, tok)
+		case unicode.IsSpace(ch):
+			l.advance()
+		default:
+			return
 		}
-		l.pos++
 	}
 }
 
+// advance moves past the rune at the current position.
+func (l *Lexer) advance() {
+	if l.pos >= len(l.input) {
+		return
+	}
+	_, size := l.peekRune(0)
+	if l.input[l.pos] == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column += size
+	}
+	l.pos += size
+}
+
 func (l *Lexer) peek(offset int) byte {
 	pos := l.pos + offset
 	if pos >= len(l.input) {
@@ -250,8 +296,28 @@ func (l *Lexer) peek(offset int) byte {
 	return l.input[pos]
 }
 
+// peekRune returns the rune offset bytes ahead and its size in bytes.
+// Invalid UTF-8 reads as utf8.RuneError of size 1.
+func (l *Lexer) peekRune(offset int) (rune, int) {
+	pos := l.pos + offset
+	if pos >= len(l.input) {
+		return 0, 0
+	}
+	return utf8.DecodeRuneInString(l.input[pos:])
+}
+
+func isIdentStart(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch)
+}
+
+func isIdentPart(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch) || unicode.Is(unicode.Mn, ch)
+}
+
+// isLetter and isDigit classify ASCII bytes, for scanners of other
+// syntaxes that work on bytes.
 func isLetter(ch byte) bool {
-	return unicode.IsLetter(rune(ch))
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
 }
 
 func isDigit(ch byte) bool {
@@ -0,0 +1,105 @@
+package gaql
+
+import "sort"
+
+// Minimize returns a smallest variant of q for which fails still holds,
+// for debugging a query the API rejects: fails reports whether a
+// variant still shows the problem, such as the same API error, and
+// Minimize removes SELECT fields, WHERE conditions, ORDER BY items, the
+// LIMIT, and PARAMETERS while it does. FROM and at least one SELECT
+// field are kept.
+//
+// The search is delta debugging: parts are removed in chunks, halved
+// until single parts, so a 40-field query with one bad field takes tens
+// of calls to fails rather than one per subset. The result is
+// 1-minimal: removing any one remaining part makes fails false. fails
+// is assumed to hold for q, which is returned unchanged when nothing
+// can be removed; q itself is never modified, and each variant passed
+// to fails is a new Query.
+//
+//	min := gaql.Minimize(q, func(v *gaql.Query) bool {
+//		_, err := client.Validate(ctx, customerID, v.String())
+//		return sameFailure(err, original)
+//	})
+func Minimize(q *Query, fails func(*Query) bool) *Query {
+	parts := queryParts(q)
+	for n := 2; len(parts) > 1; {
+		size := (len(parts) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(parts); start += size {
+			end := min(start+size, len(parts))
+			rest := append(append([]queryPart(nil), parts[:start]...), parts[end:]...)
+			if v := buildParts(q, rest); v != nil && fails(v) {
+				parts = rest
+				n = max(n-1, 2)
+				reduced = true
+				break
+			}
+		}
+		if !reduced {
+			if size == 1 {
+				break
+			}
+			n = min(n*2, len(parts))
+		}
+	}
+	return buildParts(q, parts)
+}
+
+// queryPart is one removable part of a query.
+type queryPart struct {
+	clause string // SELECT, WHERE, ORDER BY, LIMIT, or PARAMETERS
+	index  int    // of the field, condition, or ordering
+	key    string // of the parameter
+}
+
+// queryParts lists the removable parts of q in clause order.
+func queryParts(q *Query) []queryPart {
+	var parts []queryPart
+	for i := range q.Select {
+		parts = append(parts, queryPart{clause: "SELECT", index: i})
+	}
+	for i := range q.Where {
+		parts = append(parts, queryPart{clause: "WHERE", index: i})
+	}
+	for i := range q.OrderBy {
+		parts = append(parts, queryPart{clause: "ORDER BY", index: i})
+	}
+	if q.Limit > 0 {
+		parts = append(parts, queryPart{clause: "LIMIT"})
+	}
+	keys := make([]string, 0, len(q.Parameters))
+	for k := range q.Parameters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, queryPart{clause: "PARAMETERS", key: k})
+	}
+	return parts
+}
+
+// buildParts returns the variant of q keeping only parts, or nil when
+// it would select nothing.
+func buildParts(q *Query, parts []queryPart) *Query {
+	src := q.Clone()
+	v := &Query{From: src.From, Parameters: map[string]string{}, FromSpan: src.FromSpan}
+	for _, p := range parts {
+		switch p.clause {
+		case "SELECT":
+			v.Select = append(v.Select, src.Select[p.index])
+		case "WHERE":
+			v.Where = append(v.Where, src.Where[p.index])
+		case "ORDER BY":
+			v.OrderBy = append(v.OrderBy, src.OrderBy[p.index])
+		case "LIMIT":
+			v.Limit = src.Limit
+		case "PARAMETERS":
+			v.Parameters[p.key] = src.Parameters[p.key]
+		}
+	}
+	if len(v.Select) == 0 {
+		return nil
+	}
+	return v
+}
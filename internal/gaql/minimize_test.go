@@ -0,0 +1,88 @@
+package gaql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	// A 40-field query, as from a report builder.
+	var fields []string
+	for i := 0; i < 38; i++ {
+		fields = append(fields, fmt.Sprintf("campaign.field_%d", i))
+	}
+	wide := "SELECT " + strings.Join(fields, ", ") + ", segments.device, metrics.clicks FROM campaign WHERE campaign.status = 'ENABLED' AND segments.date DURING LAST_7_DAYS ORDER BY metrics.clicks DESC LIMIT 10"
+
+	selects := func(name string) func(*Query) bool {
+		return func(q *Query) bool {
+			return slices.ContainsFunc(q.Select, func(f Field) bool { return f.Name == name })
+		}
+	}
+	tests := []struct {
+		name     string
+		input    string
+		fails    func(*Query) bool
+		want     string
+		maxCalls int
+	}{
+		{
+			name:     "one field",
+			input:    wide,
+			fails:    selects("segments.device"),
+			want:     "SELECT segments.device FROM campaign",
+			maxCalls: 30,
+		},
+		{
+			name:  "field and condition",
+			input: wide,
+			fails: func(q *Query) bool {
+				return selects("metrics.clicks")(q) && slices.ContainsFunc(q.Where, func(c Condition) bool { return c.Field == "segments.date" })
+			},
+			want:     "SELECT metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS",
+			maxCalls: 40,
+		},
+		{
+			name:  "ordering by an unselected segment",
+			input: "SELECT campaign.id, campaign.name, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS ORDER BY segments.device, campaign.id LIMIT 5 PARAMETERS include_drafts = true",
+			fails: func(q *Query) bool {
+				err := NewValidator().Validate(q)
+				return err != nil && strings.Contains(err.Error(), "segments in ORDER BY")
+			},
+			// Any one field would do; the last is kept, as the search
+			// removes from the front first.
+			want:     "SELECT metrics.clicks FROM campaign ORDER BY segments.device",
+			maxCalls: 30,
+		},
+		{
+			name:  "nothing to remove",
+			input: "SELECT campaign.id, campaign.name FROM campaign",
+			fails: func(q *Query) bool { return len(q.Select) == 2 },
+			want:  "SELECT campaign.id, campaign.name FROM campaign",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := q.String()
+			calls := 0
+			got := Minimize(q, func(v *Query) bool {
+				calls++
+				return tt.fails(v)
+			})
+			if got.String() != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+			if q.String() != before {
+				t.Errorf("the query was modified: %s", q)
+			}
+			if tt.maxCalls > 0 && calls > tt.maxCalls {
+				t.Errorf("%d calls to fails, want at most %d", calls, tt.maxCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package gaql
+
+import "strings"
+
+// Known PARAMETERS clause keys.
+const (
+	ParamIncludeDrafts               = "include_drafts"
+	ParamOmitUnselectedResourceNames = "omit_unselected_resource_names"
+)
+
+// KnownParameters lists the PARAMETERS keys accepted by the API. All of
+// them take boolean values.
+var KnownParameters = map[string]bool{
+	ParamIncludeDrafts:               true,
+	ParamOmitUnselectedResourceNames: true,
+}
+
+// IncludeDrafts reports whether the query sets include_drafts = true.
+func (q *Query) IncludeDrafts() bool {
+	return q.boolParameter(ParamIncludeDrafts)
+}
+
+// OmitUnselectedResourceNames reports whether the query sets
+// omit_unselected_resource_names = true.
+func (q *Query) OmitUnselectedResourceNames() bool {
+	return q.boolParameter(ParamOmitUnselectedResourceNames)
+}
+
+// SetParameter sets a boolean PARAMETERS value.
+func (q *Query) SetParameter(name string, value bool) {
+	if q.Parameters == nil {
+		q.Parameters = make(map[string]string)
+	}
+	if value {
+		q.Parameters[name] = "true"
+	} else {
+		q.Parameters[name] = "false"
+	}
+}
+
+func (q *Query) boolParameter(name string) bool {
+	v, _ := parseBool(q.Parameters[name])
+	return v
+}
+
+// parseBool parses a GAQL boolean literal, which is case-insensitive.
+func parseBool(s string) (value, ok bool) {
+	switch strings.ToLower(s) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	default:
+		return false, false
+	}
+}
@@ -1,6 +1,7 @@
 package gaql
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -9,26 +10,99 @@ import (
 type Parser struct {
 	tokens []Token
 	pos    int
+	src    string
+	opts   ParseOptions
 }
 
-// Parse parses a GAQL query string and returns the AST.
+// ParseOptions selects how forgiving the parser is. The zero value is
+// the most lenient; StrictParseOptions enables every check, which suits
+// gateways that forward queries to the API, while interactive use can
+// accept what the user probably meant.
+type ParseOptions struct {
+	// StrictKeywordCase requires keywords and date range keywords in
+	// upper case, e.g. SELECT and LAST_7_DAYS rather than select.
+	StrictKeywordCase bool
+
+	// DisallowUnquotedEnums rejects bare identifiers as condition
+	// values, e.g. campaign.status = ENABLED instead of 'ENABLED'.
+	// PARAMETERS values are not affected.
+	DisallowUnquotedEnums bool
+
+	// DisallowTrailingTokens rejects anything after the last clause.
+	// When it is false, trailing semicolons are dropped and tokens after
+	// a complete query are ignored.
+	DisallowTrailingTokens bool
+}
+
+var (
+	// DefaultParseOptions are the options Parse uses.
+	DefaultParseOptions = ParseOptions{DisallowTrailingTokens: true}
+
+	// StrictParseOptions enable every check.
+	StrictParseOptions = ParseOptions{
+		StrictKeywordCase:      true,
+		DisallowUnquotedEnums:  true,
+		DisallowTrailingTokens: true,
+	}
+)
+
+// Parse parses a GAQL query string with DefaultParseOptions and returns
+// the AST.
 func Parse(input string) (*Query, error) {
+	return ParseWithOptions(input, DefaultParseOptions)
+}
+
+// ParseWithOptions parses a GAQL query string and returns the AST.
+func ParseWithOptions(input string, opts ParseOptions) (*Query, error) {
+	if !opts.DisallowTrailingTokens {
+		input = strings.TrimRight(input, "; \t\r\n")
+	}
 	lexer := NewLexer(input)
 	tokens, err := lexer.Tokenize()
 	if err != nil {
 		return nil, err
 	}
 
-	p := &Parser{tokens: tokens, pos: 0}
+	p := &Parser{tokens: tokens, pos: 0, src: input, opts: opts}
+	if opts.StrictKeywordCase {
+		if err := p.checkKeywordCase(); err != nil {
+			return nil, err
+		}
+	}
 	return p.parseQuery()
 }
 
+// checkKeywordCase reports the first keyword not written in upper case.
+func (p *Parser) checkKeywordCase() error {
+	for i, tok := range p.tokens {
+		keyword := tok.Type >= TokenSelect && tok.Type <= TokenRegexpMatch || tok.Type == TokenDateRange
+		if !keyword {
+			continue
+		}
+		text := p.src[tok.Offset:tok.End.Offset]
+		if text != strings.ToUpper(text) {
+			p.pos = i
+			return p.error(fmt.Sprintf("keyword %q must be upper case", text))
+		}
+	}
+	return nil
+}
+
+// checkQuoted rejects an unquoted enum value when the options ask for it.
+func (p *Parser) checkQuoted() error {
+	if tok := p.current(); p.opts.DisallowUnquotedEnums && tok.Type == TokenIdent {
+		return p.error(fmt.Sprintf("unquoted value %s; write it as '%s'", tok.Value, tok.Value))
+	}
+	return nil
+}
+
 func (p *Parser) parseQuery() (*Query, error) {
 	query := &Query{
 		Parameters: make(map[string]string),
 	}
 
 	// Parse SELECT clause (required)
+	start := p.start()
 	if !p.match(TokenSelect) {
 		return nil, p.error("expected SELECT clause")
 	}
@@ -38,8 +112,13 @@ func (p *Parser) parseQuery() (*Query, error) {
 		return nil, err
 	}
 	query.Select = fields
+	query.SelectSpan = p.spanFrom(start)
+	if err := p.checkDirection("a SELECT field"); err != nil {
+		return nil, err
+	}
 
 	// Parse FROM clause (required)
+	start = p.start()
 	if !p.match(TokenFrom) {
 		return nil, p.error("expected FROM clause")
 	}
@@ -49,26 +128,38 @@ func (p *Parser) parseQuery() (*Query, error) {
 	}
 	query.From = p.current().Value
 	p.advance()
+	query.FromSpan = p.spanFrom(start)
+	if err := p.checkDirection("the FROM resource"); err != nil {
+		return nil, err
+	}
 
 	// Parse optional WHERE clause
+	start = p.start()
 	if p.match(TokenWhere) {
 		conditions, err := p.parseConditions()
 		if err != nil {
 			return nil, err
 		}
 		query.Where = conditions
+		query.WhereSpan = p.spanFrom(start)
+		if err := p.checkDirection("a WHERE condition"); err != nil {
+			return nil, err
+		}
 	}
 
 	// Parse optional ORDER BY clause
+	start = p.start()
 	if p.match(TokenOrderBy) {
 		orderings, err := p.parseOrderings()
 		if err != nil {
 			return nil, err
 		}
 		query.OrderBy = orderings
+		query.OrderBySpan = p.spanFrom(start)
 	}
 
 	// Parse optional LIMIT clause
+	start = p.start()
 	if p.match(TokenLimit) {
 		if !p.check(TokenNumber) {
 			return nil, p.error("expected number after LIMIT")
@@ -82,19 +173,25 @@ func (p *Parser) parseQuery() (*Query, error) {
 		}
 		query.Limit = limit
 		p.advance()
+		query.LimitSpan = p.spanFrom(start)
+		if err := p.checkDirection("LIMIT"); err != nil {
+			return nil, err
+		}
 	}
 
 	// Parse optional PARAMETERS clause
+	start = p.start()
 	if p.match(TokenParameters) {
 		params, err := p.parseParameters()
 		if err != nil {
 			return nil, err
 		}
 		query.Parameters = params
+		query.ParametersSpan = p.spanFrom(start)
 	}
 
 	// Should be at EOF
-	if !p.check(TokenEOF) {
+	if p.opts.DisallowTrailingTokens && !p.check(TokenEOF) {
 		return nil, p.error("unexpected token: " + p.current().Value)
 	}
 
@@ -125,6 +222,7 @@ func (p *Parser) parseFieldList() ([]Field, error) {
 
 func (p *Parser) parseField() (Field, error) {
 	var parts []string
+	start := p.start()
 
 	if !p.check(TokenIdent) {
 		return Field{}, p.error("expected field name")
@@ -141,7 +239,7 @@ func (p *Parser) parseField() (Field, error) {
 		p.advance()
 	}
 
-	return Field{Name: strings.Join(parts, ".")}, nil
+	return Field{Name: strings.Join(parts, "."), Span: p.spanFrom(start)}, nil
 }
 
 func (p *Parser) parseConditions() ([]Condition, error) {
@@ -164,6 +262,7 @@ func (p *Parser) parseConditions() ([]Condition, error) {
 
 func (p *Parser) parseCondition() (Condition, error) {
 	cond := Condition{}
+	start := p.start()
 
 	// Parse field name
 	field, err := p.parseField()
@@ -171,6 +270,7 @@ func (p *Parser) parseCondition() (Condition, error) {
 		return cond, err
 	}
 	cond.Field = field.Name
+	cond.FieldSpan = field.Span
 
 	// Parse operator
 	op, err := p.parseOperator()
@@ -182,14 +282,18 @@ func (p *Parser) parseCondition() (Condition, error) {
 	// Parse value (not needed for IS NULL, IS NOT NULL)
 	if op == OpIsNull || op == OpIsNotNull {
 		cond.Value = Value{Type: ValueNull}
+		cond.Span = p.spanFrom(start)
 		return cond, nil
 	}
 
+	valueStart := p.start()
 	value, err := p.parseValue(op)
 	if err != nil {
 		return cond, err
 	}
 	cond.Value = value
+	cond.ValueSpan = p.spanFrom(valueStart)
+	cond.Span = p.spanFrom(start)
 
 	return cond, nil
 }
@@ -280,7 +384,7 @@ func (p *Parser) parseValue(op Operator) (Value, error) {
 		if !p.check(TokenDateRange) {
 			return Value{}, p.error("expected date range keyword after DURING")
 		}
-		dr, ok := DateRangeKeywords[tok.Value]
+		dr, ok := LookupDateRange(tok.Value)
 		if !ok {
 			return Value{}, p.error("unknown date range: " + tok.Value)
 		}
@@ -290,6 +394,9 @@ func (p *Parser) parseValue(op Operator) (Value, error) {
 
 	// Handle BETWEEN
 	if op == OpBetween {
+		if err := p.checkQuoted(); err != nil {
+			return Value{}, err
+		}
 		start, err := p.parseSimpleValue()
 		if err != nil {
 			return Value{}, err
@@ -297,6 +404,9 @@ func (p *Parser) parseValue(op Operator) (Value, error) {
 		if !p.match(TokenAnd) {
 			return Value{}, p.error("expected AND in BETWEEN clause")
 		}
+		if err := p.checkQuoted(); err != nil {
+			return Value{}, err
+		}
 		end, err := p.parseSimpleValue()
 		if err != nil {
 			return Value{}, err
@@ -326,6 +436,9 @@ func (p *Parser) parseValue(op Operator) (Value, error) {
 		return Value{Type: ValueNumber, Number: num}, nil
 	case TokenIdent:
 		// Could be an enum value without quotes
+		if err := p.checkQuoted(); err != nil {
+			return Value{}, err
+		}
 		p.advance()
 		return Value{Type: ValueString, Str: tok.Value}, nil
 	default:
@@ -357,6 +470,9 @@ func (p *Parser) parseList() (Value, error) {
 
 	var items []string
 	for {
+		if err := p.checkQuoted(); err != nil {
+			return Value{}, err
+		}
 		val, err := p.parseSimpleValue()
 		if err != nil {
 			return Value{}, err
@@ -390,8 +506,11 @@ func (p *Parser) parseOrderings() ([]Ordering, error) {
 		} else if p.match(TokenAsc) {
 			dir = Asc
 		}
+		if p.check(TokenAsc) || p.check(TokenDesc) {
+			return nil, p.error(fmt.Sprintf("%s after %s %s: an ORDER BY field takes one direction", p.directionText(), field.Name, dir))
+		}
 
-		orderings = append(orderings, Ordering{Field: field.Name, Direction: dir})
+		orderings = append(orderings, Ordering{Field: field.Name, Direction: dir, Span: p.spanFrom(field.Span.Start)})
 
 		if !p.match(TokenComma) {
 			break
@@ -456,11 +575,42 @@ func (p *Parser) match(t TokenType) bool {
 	return false
 }
 
+// start returns the position of the current token.
+func (p *Parser) start() Pos {
+	return p.current().Pos()
+}
+
+// spanFrom returns the span from start to the end of the last consumed token.
+func (p *Parser) spanFrom(start Pos) Span {
+	if p.pos == 0 || p.pos > len(p.tokens) {
+		return Span{Start: start, End: start}
+	}
+	return Span{Start: start, End: p.tokens[p.pos-1].End}
+}
+
+// checkDirection rejects ASC or DESC after the clause part named by
+// after, which has no ORDER BY field for it to apply to.
+func (p *Parser) checkDirection(after string) error {
+	if p.check(TokenAsc) || p.check(TokenDesc) {
+		return p.error(fmt.Sprintf("%s after %s: ASC and DESC only follow an ORDER BY field", p.directionText(), after))
+	}
+	return nil
+}
+
+// directionText returns the current ASC or DESC keyword in upper case.
+func (p *Parser) directionText() string {
+	if p.check(TokenDesc) {
+		return "DESC"
+	}
+	return "ASC"
+}
+
 func (p *Parser) error(msg string) error {
 	tok := p.current()
 	return &ParseError{
 		Message: msg,
 		Line:    tok.Line,
 		Column:  tok.Column,
+		Offset:  tok.Offset,
 	}
 }
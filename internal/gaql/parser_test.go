@@ -1,6 +1,9 @@
 package gaql
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -256,6 +259,21 @@ func TestLexer(t *testing.T) {
 			input:    "DURING LAST_7_DAYS",
 			expected: []TokenType{TokenDuring, TokenDateRange, TokenEOF},
 		},
+		{
+			name:     "unicode identifiers and strings",
+			input:    "campaña.número = 'Größe €'",
+			expected: []TokenType{TokenIdent, TokenDot, TokenIdent, TokenEq, TokenString, TokenEOF},
+		},
+		{
+			name:     "comments",
+			input:    "-- weekly\nSELECT campaign.id # the ID\nFROM campaign --",
+			expected: []TokenType{TokenSelect, TokenIdent, TokenDot, TokenIdent, TokenFrom, TokenIdent, TokenEOF},
+		},
+		{
+			name:     "order by needs a separate BY",
+			input:    "ORDER BYTES ORDER -- sort\n by",
+			expected: []TokenType{TokenIdent, TokenIdent, TokenOrderBy, TokenEOF},
+		},
 	}
 
 	for _, tt := range tests {
@@ -281,3 +299,188 @@ func TestLexer(t *testing.T) {
 		})
 	}
 }
+
+func TestLexerComments(t *testing.T) {
+	lexer := NewLexer("-- name: weekly\nSELECT campaign.id # ID\r\nFROM campaign")
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Token{
+		{Type: TokenComment, Value: "-- name: weekly", Line: 1, Column: 1, Offset: 0, End: Pos{Line: 1, Column: 16, Offset: 15}},
+		{Type: TokenComment, Value: "# ID", Line: 2, Column: 20, Offset: 35, End: Pos{Line: 2, Column: 25, Offset: 40}},
+	}
+	if !reflect.DeepEqual(lexer.Comments, want) {
+		t.Errorf("comments = %+v, want %+v", lexer.Comments, want)
+	}
+	if from := tokens[4]; from.Type != TokenFrom || from.Line != 3 || from.Column != 1 {
+		t.Errorf("FROM at %d:%d, want 3:1", from.Line, from.Column)
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		column  int
+	}{
+		{"SELECT campaign.id FROM campaign WHERE x = 'a\xffb'", "invalid UTF-8 encoding in string", 46},
+		{"SELECT \xc3 FROM campaign", "invalid UTF-8 encoding", 8},
+		{"SELECT a FROM b WHERE c = - 1", "unexpected character '-'", 27},
+		{"SELECT ☃ FROM campaign", "unexpected character '☃'", 8},
+	}
+	for _, tt := range tests {
+		_, err := NewLexer(tt.input).Tokenize()
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Message != tt.message || perr.Column != tt.column {
+			t.Errorf("Tokenize(%q) = %v, want %q at column %d", tt.input, err, tt.message, tt.column)
+		}
+	}
+}
+
+func TestQueryStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "between",
+			input: "SELECT campaign.id FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31'",
+			want:  "SELECT campaign.id FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31'",
+		},
+		{
+			name:  "is null",
+			input: "SELECT campaign.id FROM campaign WHERE campaign.end_date IS NOT NULL",
+			want:  "SELECT campaign.id FROM campaign WHERE campaign.end_date IS NOT NULL",
+		},
+		{
+			name:  "quoted list",
+			input: "SELECT campaign.id FROM campaign WHERE campaign.status IN (ENABLED, 'PAUSED') AND campaign.id IN (1, 2)",
+			want:  "SELECT campaign.id FROM campaign WHERE campaign.status IN ('ENABLED', 'PAUSED') AND campaign.id IN (1, 2)",
+		},
+		{
+			name:  "escaped string",
+			input: `SELECT campaign.id FROM campaign WHERE campaign.name = 'Bob\'s Shoes'`,
+			want:  `SELECT campaign.id FROM campaign WHERE campaign.name = 'Bob\'s Shoes'`,
+		},
+		{
+			name:  "sorted parameters",
+			input: "SELECT campaign.id FROM campaign PARAMETERS omit_unselected_resource_names = true, include_drafts = false",
+			want:  "SELECT campaign.id FROM campaign PARAMETERS include_drafts = false, omit_unselected_resource_names = true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := q.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if _, err := Parse(q.String()); err != nil {
+				t.Errorf("serialized query does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    ParseOptions
+		wantErr string
+	}{
+		{
+			name:  "lenient accepts lower-case keywords and bare enums",
+			input: "select campaign.id from campaign where campaign.status in (ENABLED, PAUSED) during last_7_days",
+		},
+		{
+			name:  "lenient drops trailing semicolon",
+			input: "SELECT campaign.id FROM campaign;\n",
+		},
+		{
+			name:    "default rejects trailing tokens",
+			input:   "SELECT campaign.id FROM campaign LIMIT 10 campaign.name",
+			opts:    DefaultParseOptions,
+			wantErr: "unexpected token: campaign at line 1, column 43",
+		},
+		{
+			name:  "lenient ignores trailing tokens",
+			input: "SELECT campaign.id FROM campaign LIMIT 10 campaign.name",
+		},
+		{
+			name:    "strict keyword case",
+			input:   "SELECT campaign.id from campaign",
+			opts:    StrictParseOptions,
+			wantErr: `keyword "from" must be upper case at line 1, column 20`,
+		},
+		{
+			name:    "strict date range case",
+			input:   "SELECT campaign.id FROM campaign WHERE segments.date DURING Last_7_Days",
+			opts:    StrictParseOptions,
+			wantErr: `keyword "Last_7_Days" must be upper case`,
+		},
+		{
+			name:    "strict rejects unquoted enum",
+			input:   "SELECT campaign.id FROM campaign WHERE campaign.status = ENABLED",
+			opts:    StrictParseOptions,
+			wantErr: "unquoted value ENABLED; write it as 'ENABLED'",
+		},
+		{
+			name:    "strict rejects unquoted list item",
+			input:   "SELECT campaign.id FROM campaign WHERE campaign.status IN ('ENABLED', PAUSED)",
+			opts:    StrictParseOptions,
+			wantErr: "unquoted value PAUSED",
+		},
+		{
+			name:  "strict allows bare PARAMETERS values",
+			input: "SELECT campaign.id FROM campaign WHERE campaign.status = 'ENABLED' ORDER BY campaign.id LIMIT 5 PARAMETERS include_drafts = true",
+			opts:  StrictParseOptions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWithOptions(tt.input, tt.opts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseMisplacedDirection(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+		wantCol int
+	}{
+		{"SELECT campaign.id DESC FROM campaign", "DESC after a SELECT field: ASC and DESC only follow an ORDER BY field", 20},
+		{"SELECT campaign.id FROM campaign desc", "DESC after the FROM resource", 34},
+		{"SELECT campaign.id FROM campaign WHERE campaign.status = 'ENABLED' ASC", "ASC after a WHERE condition", 68},
+		{"SELECT campaign.id FROM campaign LIMIT 10 DESC", "DESC after LIMIT", 43},
+		{"SELECT campaign.id FROM campaign ORDER BY campaign.id DESC ASC", "ASC after campaign.id DESC: an ORDER BY field takes one direction", 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var pe *ParseError
+			if !errors.As(err, &pe) || !strings.Contains(pe.Message, tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if pe.Column != tt.wantCol {
+				t.Errorf("column %d, want %d", pe.Column, tt.wantCol)
+			}
+		})
+	}
+}
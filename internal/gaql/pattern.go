@@ -0,0 +1,126 @@
+package gaql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+)
+
+// CompileRegexp compiles the pattern of a LIKE, NOT LIKE, REGEXP_MATCH,
+// or NOT REGEXP_MATCH condition into a regular expression matching the
+// values the API would match, for filtering rows client-side. The
+// expression matches the whole value, as the API does: % and _ of a
+// LIKE pattern match any run of characters and any one character, and
+// a REGEXP_MATCH pattern is RE2 syntax, which package regexp shares.
+// For the NOT operators the caller negates the match.
+//
+//	re, err := gaql.CompileRegexp(cond)
+//	if err != nil {
+//		return err
+//	}
+//	keep := re.MatchString(name) != cond.Operator.Negated()
+func CompileRegexp(c Condition) (*regexp.Regexp, error) {
+	re, err := compilePattern(c)
+	if err != nil {
+		return nil, fmt.Errorf("gaql: %s on %s: %w", c.Operator, c.Field, err)
+	}
+	return re, nil
+}
+
+func compilePattern(c Condition) (*regexp.Regexp, error) {
+	if c.Value.Type != ValueString {
+		return nil, errors.New("requires a quoted string pattern")
+	}
+	switch c.Operator {
+	case OpLike, OpNotLike:
+		expr, err := likeRegexp(c.Value.Str)
+		if err != nil {
+			return nil, err
+		}
+		return regexp.MustCompile(expr), nil
+	case OpRegexpMatch, OpNotRegexpMatch:
+		// Compiled alone first, so a pattern such as a)|(b cannot
+		// escape the anchors.
+		if _, err := regexp.Compile(c.Value.Str); err != nil {
+			return nil, regexpError(err)
+		}
+		return regexp.MustCompile(`^(?:` + c.Value.Str + `)$`), nil
+	}
+	return nil, errors.New("is not a pattern operator")
+}
+
+// Negated reports whether o is the negation of another operator, such
+// as NOT LIKE or NOT IN.
+func (o Operator) Negated() bool {
+	switch o {
+	case OpNotLike, OpNotRegexpMatch, OpNotIn, OpNeq, OpIsNotNull:
+		return true
+	}
+	return false
+}
+
+// likeRegexp translates a LIKE pattern into an anchored regular
+// expression. In LIKE patterns % and _ are wildcards, and [ starts an
+// escape: [%], [_], [[], and []] match the character they enclose.
+func likeRegexp(pattern string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(`^(?s:`)
+	for i := 0; i < len(pattern); {
+		ch, size := utf8.DecodeRuneInString(pattern[i:])
+		switch ch {
+		case '%':
+			sb.WriteString(`.*`)
+		case '_':
+			sb.WriteString(`.`)
+		case '[':
+			if i+2 >= len(pattern) || pattern[i+2] != ']' || !strings.ContainsRune("%_[]", rune(pattern[i+1])) {
+				end := min(i+3, len(pattern))
+				return "", fmt.Errorf("invalid escape %q at byte %d of the pattern: enclose one of %% _ [ ] in brackets, as in [%%]", pattern[i:end], i)
+			}
+			sb.WriteString(regexp.QuoteMeta(pattern[i+1 : i+2]))
+			size = 3
+		default:
+			// Whole characters, so _ and the text around it match
+			// non-ASCII text rune by rune.
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+		i += size
+	}
+	sb.WriteString(`)$`)
+	return sb.String(), nil
+}
+
+// regexpError shortens the errors of package regexp to what is wrong
+// and where, such as "missing closing ): `(brand`".
+func regexpError(err error) error {
+	var serr *syntax.Error
+	if errors.As(err, &serr) {
+		return fmt.Errorf("invalid regular expression: %s: `%s`", serr.Code, serr.Expr)
+	}
+	return err
+}
+
+// validatePattern checks the pattern of a LIKE or REGEXP_MATCH
+// condition, reporting errors at the pattern literal.
+func validatePattern(cond Condition) error {
+	switch cond.Operator {
+	case OpLike, OpNotLike, OpRegexpMatch, OpNotRegexpMatch:
+	default:
+		return nil
+	}
+	span := cond.ValueSpan
+	if !span.Start.IsValid() {
+		span = cond.Span
+	}
+	if _, err := compilePattern(cond); err != nil {
+		msg := err.Error()
+		if cond.Value.Type != ValueString {
+			msg = cond.Operator.String() + " " + msg
+		}
+		return &ValidationError{Message: msg, Field: cond.Field, Span: span}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package gaql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompileRegexp(t *testing.T) {
+	tests := []struct {
+		cond    string
+		match   []string
+		nomatch []string
+		wantErr string
+	}{
+		{cond: "campaign.name LIKE 'Brand%'", match: []string{"Brand", "Brand - Search"}, nomatch: []string{"My Brand", "brand"}},
+		{cond: "campaign.name LIKE '_rand'", match: []string{"Brand", "Grand"}, nomatch: []string{"rand", "BBrand"}},
+		{cond: "campaign.name NOT LIKE '%[%]%'", match: []string{"50% off"}, nomatch: []string{"50 off"}},
+		{cond: "campaign.name LIKE 'a[_]b.c'", match: []string{"a_b.c"}, nomatch: []string{"axb.c", "a_bxc"}},
+		{cond: "campaign.name LIKE '[[]x[]]'", match: []string{"[x]"}},
+		{cond: "campaign.name LIKE '%café%'", match: []string{"Le café du coin", "café"}, nomatch: []string{"Le cafe du coin"}},
+		{cond: "campaign.name LIKE 'caf_ [%] 東_'", match: []string{"café % 東京", "cafe % 東x"}, nomatch: []string{"café % 東", "café % 東京都"}},
+		{cond: "campaign.name REGEXP_MATCH '(?i)brand.*'", match: []string{"Brand - Search"}, nomatch: []string{"My Brand"}},
+		{cond: "campaign.name REGEXP_MATCH 'a)|(b'", wantErr: "gaql: REGEXP_MATCH on campaign.name: invalid regular expression: unexpected ): `a)|(b`"},
+		{cond: "campaign.name LIKE 'a[b]'", wantErr: `gaql: LIKE on campaign.name: invalid escape "[b]" at byte 1 of the pattern: enclose one of % _ [ ] in brackets, as in [%]`},
+		{cond: "campaign.id = 1", wantErr: "gaql: = on campaign.id: requires a quoted string pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			q, err := Parse("SELECT campaign.id FROM campaign WHERE " + tt.cond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			re, err := CompileRegexp(q.Where[0])
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.match {
+				if !re.MatchString(s) {
+					t.Errorf("%s does not match %q", re, s)
+				}
+			}
+			for _, s := range tt.nomatch {
+				if re.MatchString(s) {
+					t.Errorf("%s matches %q", re, s)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr string
+		column  int
+	}{
+		{query: "SELECT campaign.id FROM campaign WHERE campaign.name LIKE '%[%]%'"},
+		{query: "SELECT campaign.id FROM campaign WHERE campaign.name REGEXP_MATCH '^Brand'"},
+		{"SELECT campaign.id FROM campaign WHERE campaign.name REGEXP_MATCH '(Brand'", "invalid regular expression: missing closing ): `(Brand`", 67},
+		{"SELECT campaign.id FROM campaign WHERE campaign.name NOT LIKE 'x[y'", `invalid escape "[y"`, 63},
+		{"SELECT campaign.id FROM campaign WHERE campaign.name LIKE 5", "LIKE requires a quoted string pattern", 59},
+	}
+	for _, tt := range tests {
+		_, err := ValidateQuery(tt.query)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ValidateQuery(%q): %v", tt.query, err)
+			}
+			continue
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("ValidateQuery(%q) = %v, want a ValidationError", tt.query, err)
+			continue
+		}
+		if !strings.Contains(verr.Message, tt.wantErr) || verr.Span.Start.Column != tt.column {
+			t.Errorf("ValidateQuery(%q) = %q at column %d, want %q at column %d", tt.query, verr.Message, verr.Span.Start.Column, tt.wantErr, tt.column)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package gaql
+
+import "fmt"
+
+// Pos is a position in GAQL source text.
+type Pos struct {
+	Line   int // 1-based line number
+	Column int // 1-based column, counted in bytes
+	Offset int // 0-based byte offset
+}
+
+// IsValid reports whether the position was set by the parser.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the half-open source range [Start, End) of a syntax node.
+// Nodes built programmatically rather than parsed have a zero Span.
+type Span struct {
+	Start Pos
+	End   Pos
+}
+
+// IsValid reports whether the span was set by the parser.
+func (s Span) IsValid() bool {
+	return s.Start.IsValid()
+}
+
+func (s Span) String() string {
+	return s.Start.String() + "-" + s.End.String()
+}
+
+// Text returns the source text covered by the span.
+func (s Span) Text(src string) string {
+	if !s.IsValid() || s.Start.Offset > s.End.Offset || s.End.Offset > len(src) {
+		return ""
+	}
+	return src[s.Start.Offset:s.End.Offset]
+}
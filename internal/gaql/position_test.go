@@ -0,0 +1,73 @@
+package gaql
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	input := "SELECT campaign.id, metrics.clicks\nFROM campaign\nWHERE campaign.status = 'ENABLED'\n  AND segments.date DURING LAST_7_DAYS\nORDER BY metrics.clicks DESC\nLIMIT 10"
+
+	q, err := Parse(input)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		span  Span
+		text  string
+		start Pos
+	}{
+		{"select clause", q.SelectSpan, "SELECT campaign.id, metrics.clicks", Pos{1, 1, 0}},
+		{"first field", q.Select[0].Span, "campaign.id", Pos{1, 8, 7}},
+		{"second field", q.Select[1].Span, "metrics.clicks", Pos{1, 21, 20}},
+		{"from clause", q.FromSpan, "FROM campaign", Pos{2, 1, 35}},
+		{"first condition", q.Where[0].Span, "campaign.status = 'ENABLED'", Pos{3, 7, 55}},
+		{"first condition field", q.Where[0].FieldSpan, "campaign.status", Pos{3, 7, 55}},
+		{"second condition", q.Where[1].Span, "segments.date DURING LAST_7_DAYS", Pos{4, 7, 89}},
+		{"ordering", q.OrderBy[0].Span, "metrics.clicks DESC", Pos{5, 10, 131}},
+		{"limit clause", q.LimitSpan, "LIMIT 10", Pos{6, 1, 151}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.span.Text(input); got != tt.text {
+				t.Errorf("expected text %q, got %q", tt.text, got)
+			}
+			if tt.span.Start != tt.start {
+				t.Errorf("expected start %+v, got %+v", tt.start, tt.span.Start)
+			}
+			if tt.span.End.Offset-tt.span.Start.Offset != len(tt.text) {
+				t.Errorf("span length mismatch: %s", tt.span)
+			}
+		})
+	}
+}
+
+func TestValidationErrorSpan(t *testing.T) {
+	input := "SELECT campaign.id,\n  metrics.clicks\nFROM campaign"
+
+	_, err := ValidateQuery(input)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if got := verr.Span.Text(input); got != "metrics.clicks" {
+		t.Errorf("expected span over metrics.clicks, got %q", got)
+	}
+	if verr.Span.Start.Line != 2 || verr.Span.Start.Column != 3 {
+		t.Errorf("expected 2:3, got %s", verr.Span.Start)
+	}
+}
+
+func TestParseErrorOffset(t *testing.T) {
+	_, err := Parse("SELECT campaign.id\nFROM campaign\nWHERE campaign.id ~ 1")
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *ParseError, got %v", err)
+	}
+	if perr.Line != 3 || perr.Column != 19 || perr.Offset != 51 {
+		t.Errorf("expected 3:19 @51, got %d:%d @%d", perr.Line, perr.Column, perr.Offset)
+	}
+}
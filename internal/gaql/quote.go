@@ -0,0 +1,176 @@
+package gaql
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuoteString returns s as a single-quoted GAQL string literal, escaping
+// backslashes and single quotes, so that s reads back as exactly one
+// string value whatever it holds. Use it, QuoteList, or SafeLiteral when
+// building queries from input that is not trusted, or a Template.
+func QuoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// QuoteList returns items as a parenthesized list of string literals for
+// IN, NOT IN, and CONTAINS:
+//
+//	QuoteList([]string{"ENABLED", "PAUSED"}) // ('ENABLED', 'PAUSED')
+func QuoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = QuoteString(item)
+	}
+	return "(" + strings.Join(quoted, ", ") + ")"
+}
+
+// SafeLiteral returns a Go value as a GAQL literal: strings quoted with
+// QuoteString, integers and finite floats as numbers, bools as TRUE or
+// FALSE, a time.Time as its quoted date, a DateRange as its keyword, and
+// []string, []int, []int64, []float64, or []any as a list of literals.
+// Other types, NaN, infinities, empty lists, and nested lists are
+// errors, never text that could change the query.
+func SafeLiteral(v any) (string, error) {
+	if n, ok := asInt(v); ok {
+		return strconv.FormatInt(n, 10), nil
+	}
+	if items, ok := listItems(v); ok {
+		if len(items) == 0 {
+			return "", fmt.Errorf("gaql: an empty list has no literal")
+		}
+		lits := make([]string, len(items))
+		for i, item := range items {
+			if _, nested := listItems(item); nested {
+				return "", fmt.Errorf("gaql: lists cannot be nested")
+			}
+			lit, err := SafeLiteral(item)
+			if err != nil {
+				return "", err
+			}
+			lits[i] = lit
+		}
+		return "(" + strings.Join(lits, ", ") + ")", nil
+	}
+	switch v := v.(type) {
+	case string:
+		return QuoteString(v), nil
+	case float32, float64:
+		f := toFloat(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("gaql: %v has no literal", f)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case time.Time:
+		return QuoteString(v.Format(time.DateOnly)), nil
+	case DateRange:
+		if name := v.String(); v != DateRangeCustom && name != "CUSTOM" {
+			return name, nil
+		}
+		return "", fmt.Errorf("gaql: date range %d has no keyword", int(v))
+	}
+	return "", fmt.Errorf("gaql: %T has no literal", v)
+}
+
+// listItems returns the items of a slice value.
+func listItems(value any) ([]any, bool) {
+	var items []any
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case []string:
+		for _, s := range v {
+			items = append(items, s)
+		}
+	case []int:
+		for _, n := range v {
+			items = append(items, n)
+		}
+	case []int64:
+		for _, n := range v {
+			items = append(items, n)
+		}
+	case []float64:
+		for _, f := range v {
+			items = append(items, f)
+		}
+	default:
+		return nil, false
+	}
+	return items, true
+}
+
+// asInt returns an integer value as int64.
+func asInt(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		if v <= math.MaxInt64 {
+			return int64(v), true
+		}
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v any) float64 {
+	if f, ok := v.(float32); ok {
+		return float64(f)
+	}
+	return v.(float64)
+}
+
+// quoteLiteral quotes list items that are not numbers. The parser keeps
+// list items as raw strings, so numbers are recognized by their shape.
+func quoteLiteral(s string) string {
+	if numberPattern.MatchString(s) {
+		return s
+	}
+	return QuoteString(s)
+}
+
+// numberPattern matches the number literals the lexer accepts.
+var numberPattern = regexp.MustCompile(`^-?\d+(\.\d*)?$`)
+
+// suspiciousLiteral returns why a string value looks like input pasted
+// into a query without escaping, or "" when it does not.
+func suspiciousLiteral(s string) string {
+	switch {
+	case strings.ContainsAny(s, `'"`):
+		return "holds a quote"
+	case strings.Contains(s, `\`):
+		return "holds a backslash"
+	case strings.ContainsAny(s, "\n\r"):
+		return "holds a line break"
+	}
+	for _, r := range s {
+		if r < ' ' && r != '\t' || r == 0x7f {
+			return "holds a control character"
+		}
+	}
+	return ""
+}
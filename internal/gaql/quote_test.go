@@ -0,0 +1,62 @@
+package gaql
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSafeLiteral(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{"string", "Brand | US", "'Brand | US'", false},
+		{"quotes and backslashes", `it's a \ test`, `'it\'s a \\ test'`, false},
+		{"injection", "x' OR campaign.id > 0 OR 'y", `'x\' OR campaign.id > 0 OR \'y'`, false},
+		{"int64", int64(-42), "-42", false},
+		{"uint", uint(7), "7", false},
+		{"float", 0.25, "0.25", false},
+		{"bool", true, "TRUE", false},
+		{"date", time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC), "'2026-01-31'", false},
+		{"date range", DateRangeLast30Days, "LAST_30_DAYS", false},
+		{"strings", []string{"ENABLED", "PAUSED"}, "('ENABLED', 'PAUSED')", false},
+		{"mixed list", []any{1, "a"}, "(1, 'a')", false},
+		{"NaN", math.NaN(), "", true},
+		{"empty list", []string{}, "", true},
+		{"nested list", []any{[]string{"a"}}, "", true},
+		{"custom range", DateRangeCustom, "", true},
+		{"struct", struct{}{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SafeLiteral(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SafeLiteral(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SafeLiteral(%v) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestQuoteStringRoundTrip checks that quoted strings read back as the
+// value quoted and as a single condition.
+func TestQuoteStringRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "plain", `'`, `\`, `\'`, `'; DROP`, "x' OR '1'='1", "two\nlines", `"double"`} {
+		q, err := Parse("SELECT campaign.id FROM campaign WHERE campaign.name = " + QuoteString(s))
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if len(q.Where) != 1 || q.Where[0].Value.Str != s {
+			t.Errorf("%q read back as %+v", s, q.Where)
+		}
+	}
+	if got := QuoteList([]string{"a'b", "c"}); got != `('a\'b', 'c')` {
+		t.Errorf("QuoteList = %s", got)
+	}
+}
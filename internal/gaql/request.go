@@ -0,0 +1,204 @@
+package gaql
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// SummaryRowSetting is the summary_row_setting of a search request: the
+// SummaryRowSettingEnum of the API, by name in JSON and by number on
+// the wire.
+type SummaryRowSetting string
+
+const (
+	// NoSummaryRow is the API default: results only.
+	NoSummaryRow SummaryRowSetting = "NO_SUMMARY_ROW"
+
+	// SummaryRowWithResults returns the summary row, the selected
+	// metrics aggregated over every result, after the results.
+	SummaryRowWithResults SummaryRowSetting = "SUMMARY_ROW_WITH_RESULTS"
+
+	// SummaryRowOnly returns the summary row and no results.
+	SummaryRowOnly SummaryRowSetting = "SUMMARY_ROW_ONLY"
+)
+
+// number is the enum number of s, or 0 (UNSPECIFIED) for none.
+func (s SummaryRowSetting) number() (uint64, bool) {
+	switch s {
+	case "":
+		return 0, true
+	case NoSummaryRow:
+		return 2, true
+	case SummaryRowWithResults:
+		return 3, true
+	case SummaryRowOnly:
+		return 4, true
+	}
+	return 0, false
+}
+
+// RequestOptions are the parts of a search request besides the query
+// and the account.
+type RequestOptions struct {
+	// Validator checks the query before it is compiled. Nil uses
+	// NewValidator.
+	Validator *Validator
+
+	// PageToken continues a search from the page it names.
+	PageToken string
+
+	// PageSize is the number of rows per page. Zero leaves it unset;
+	// API versions since v17 fix pages at 10,000 rows and reject
+	// requests that set it.
+	PageSize int32
+
+	// ValidateOnly asks the API to check the query without running it.
+	ValidateOnly bool
+
+	// ReturnTotalResultsCount asks for the number of results matching
+	// the query, ignoring its LIMIT.
+	ReturnTotalResultsCount bool
+
+	// SummaryRow asks for the summary row. Empty leaves it unset.
+	SummaryRow SummaryRowSetting
+}
+
+// SearchRequest is a GoogleAdsService.Search request: the
+// SearchGoogleAdsRequest message. Its JSON form is the request body of
+// the REST interface (with CustomerID in the URL rather than the body),
+// and MarshalBinary encodes the protobuf message, so callers holding
+// their own gRPC connection can send it without this package's client.
+type SearchRequest struct {
+	CustomerID              string            `json:"customerId"`
+	Query                   string            `json:"query"`
+	PageToken               string            `json:"pageToken,omitempty"`
+	PageSize                int32             `json:"pageSize,omitempty"`
+	ValidateOnly            bool              `json:"validateOnly,omitempty"`
+	ReturnTotalResultsCount bool              `json:"returnTotalResultsCount,omitempty"`
+	SummaryRowSetting       SummaryRowSetting `json:"summaryRowSetting,omitempty"`
+}
+
+// SearchStreamRequest is a GoogleAdsService.SearchStream request: the
+// SearchGoogleAdsStreamRequest message. Streams have no pages and no
+// validate-only mode.
+type SearchStreamRequest struct {
+	CustomerID        string            `json:"customerId"`
+	Query             string            `json:"query"`
+	SummaryRowSetting SummaryRowSetting `json:"summaryRowSetting,omitempty"`
+}
+
+// ToSearchRequest validates q and compiles it into a search request for
+// customerID, which may have dashes. The query is written in the
+// canonical form of Query.String, so equal queries compile to equal
+// requests however they were typed.
+func ToSearchRequest(q *Query, customerID string, opts RequestOptions) (*SearchRequest, error) {
+	id, err := requestCustomerID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := opts.SummaryRow.number(); !ok {
+		return nil, fmt.Errorf("gaql: unknown summary row setting %q", opts.SummaryRow)
+	}
+	if opts.PageSize < 0 {
+		return nil, fmt.Errorf("gaql: negative page size %d", opts.PageSize)
+	}
+	v := opts.Validator
+	if v == nil {
+		v = NewValidator()
+	}
+	q, _, err = v.Prepare(q)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchRequest{
+		CustomerID:              id,
+		Query:                   q.String(),
+		PageToken:               opts.PageToken,
+		PageSize:                opts.PageSize,
+		ValidateOnly:            opts.ValidateOnly,
+		ReturnTotalResultsCount: opts.ReturnTotalResultsCount,
+		SummaryRowSetting:       opts.SummaryRow,
+	}, nil
+}
+
+// Stream returns the streaming form of r, dropping the options streams
+// do not have.
+func (r *SearchRequest) Stream() *SearchStreamRequest {
+	return &SearchStreamRequest{CustomerID: r.CustomerID, Query: r.Query, SummaryRowSetting: r.SummaryRowSetting}
+}
+
+// requestCustomerID returns id without dashes, checking it is the ten
+// digits the API expects.
+func requestCustomerID(id string) (string, error) {
+	norm := strings.ReplaceAll(id, "-", "")
+	if len(norm) != 10 || strings.Trim(norm, "0123456789") != "" {
+		return "", fmt.Errorf("gaql: invalid customer ID %q: want 10 digits", id)
+	}
+	return norm, nil
+}
+
+// MarshalBinary encodes r as a SearchGoogleAdsRequest protobuf message.
+func (r *SearchRequest) MarshalBinary() ([]byte, error) {
+	summary, ok := r.SummaryRowSetting.number()
+	if !ok {
+		return nil, fmt.Errorf("gaql: unknown summary row setting %q", r.SummaryRowSetting)
+	}
+	var b []byte
+	b = appendString(b, 1, r.CustomerID)
+	b = appendString(b, 2, r.Query)
+	b = appendString(b, 3, r.PageToken)
+	b = appendVarint(b, 4, uint64(int64(r.PageSize)))
+	b = appendVarint(b, 5, boolNumber(r.ValidateOnly))
+	b = appendVarint(b, 7, boolNumber(r.ReturnTotalResultsCount))
+	b = appendVarint(b, 8, summary)
+	return b, nil
+}
+
+// MarshalBinary encodes r as a SearchGoogleAdsStreamRequest protobuf
+// message.
+func (r *SearchStreamRequest) MarshalBinary() ([]byte, error) {
+	summary, ok := r.SummaryRowSetting.number()
+	if !ok {
+		return nil, fmt.Errorf("gaql: unknown summary row setting %q", r.SummaryRowSetting)
+	}
+	var b []byte
+	b = appendString(b, 1, r.CustomerID)
+	b = appendString(b, 2, r.Query)
+	b = appendVarint(b, 3, summary)
+	return b, nil
+}
+
+// Protobuf wire types of the fields of search requests.
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// appendString appends a string field, leaving out the empty string as
+// proto3 does.
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendVarint appends an integer, bool, or enum field, leaving out
+// zero as proto3 does.
+func appendVarint(b []byte, field int, n uint64) []byte {
+	if n == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(b, n)
+}
+
+func boolNumber(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
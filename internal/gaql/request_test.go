@@ -0,0 +1,114 @@
+package gaql
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestToSearchRequest(t *testing.T) {
+	q, err := Parse("select campaign.id,metrics.clicks from campaign where segments.date during LAST_7_DAYS limit 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := ToSearchRequest(q, "123-456-7890", RequestOptions{ValidateOnly: true, SummaryRow: SummaryRowWithResults})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &SearchRequest{
+		CustomerID:        "1234567890",
+		Query:             "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS LIMIT 10",
+		ValidateOnly:      true,
+		SummaryRowSetting: SummaryRowWithResults,
+	}
+	if *req != *want {
+		t.Errorf("ToSearchRequest = %+v, want %+v", req, want)
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"customerId":"1234567890","query":"` + want.Query + `","validateOnly":true,"summaryRowSetting":"SUMMARY_ROW_WITH_RESULTS"}`
+	if string(data) != wantJSON {
+		t.Errorf("JSON = %s, want %s", data, wantJSON)
+	}
+
+	stream := req.Stream()
+	if stream.CustomerID != want.CustomerID || stream.Query != want.Query || stream.SummaryRowSetting != SummaryRowWithResults {
+		t.Errorf("Stream = %+v", stream)
+	}
+}
+
+func TestToSearchRequestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		customerID string
+		opts       RequestOptions
+	}{
+		{"customer ID", "SELECT campaign.id FROM campaign", "12345", RequestOptions{}},
+		{"summary row", "SELECT campaign.id FROM campaign", "1234567890", RequestOptions{SummaryRow: "SOMETIMES"}},
+		{"page size", "SELECT campaign.id FROM campaign", "1234567890", RequestOptions{PageSize: -1}},
+		{"validation", "SELECT metrics.clicks FROM campaign", "1234567890", RequestOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ToSearchRequest(q, tt.customerID, tt.opts); err == nil {
+				t.Error("ToSearchRequest succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestSearchRequestMarshalBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		req  interface{ MarshalBinary() ([]byte, error) }
+		want []byte
+	}{
+		{
+			name: "search",
+			req: &SearchRequest{CustomerID: "1234567890", Query: "SELECT customer.id FROM customer",
+				PageToken: "t", PageSize: 300, ValidateOnly: true, ReturnTotalResultsCount: true, SummaryRowSetting: SummaryRowOnly},
+			want: concat(
+				[]byte{0x0a, 10}, []byte("1234567890"),
+				[]byte{0x12, 32}, []byte("SELECT customer.id FROM customer"),
+				[]byte{0x1a, 1, 't'},
+				[]byte{0x20, 0xac, 0x02}, // 300
+				[]byte{0x28, 1},
+				[]byte{0x38, 1},
+				[]byte{0x40, 4},
+			),
+		},
+		{
+			name: "defaults left out",
+			req:  &SearchRequest{CustomerID: "1234567890", Query: "q"},
+			want: concat([]byte{0x0a, 10}, []byte("1234567890"), []byte{0x12, 1, 'q'}),
+		},
+		{
+			name: "stream",
+			req:  &SearchStreamRequest{CustomerID: "1234567890", Query: "q", SummaryRowSetting: SummaryRowWithResults},
+			want: concat([]byte{0x0a, 10}, []byte("1234567890"), []byte{0x12, 1, 'q'}, []byte{0x18, 3}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.req.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("MarshalBinary = % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
@@ -0,0 +1,88 @@
+package gaql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Column describes one column of a query's results: a selected field
+// and the types its values take in Go, Parquet, and SQL.
+type Column struct {
+	Name     string // the GAQL field name, such as metrics.cost_micros
+	Category string // ATTRIBUTE, SEGMENT, METRIC, or RESOURCE
+	DataType string // the catalog's type: INT64, DOUBLE, ENUM, DATE, ...
+	Repeated bool   // the field holds a list of values
+
+	// GoType is the Go type of the values once decoded, such as int64
+	// or []string. INT64 fields arrive as JSON strings but are numbers;
+	// DATE, ENUM, and RESOURCE_NAME fields are strings, and MESSAGE
+	// fields map[string]any.
+	GoType string
+
+	// ParquetType is the Parquet physical type, followed by its logical
+	// type in parentheses where one applies, such as "INT32 (DATE)".
+	// Repeated fields use the REPEATED repetition of this type.
+	ParquetType string
+
+	// SQLType is the standard SQL type, such as BIGINT or DATE, with
+	// ARRAY appended for repeated fields.
+	SQLType string
+}
+
+// ResultSchema returns the columns of q's results, one per selected
+// field in order, typed by DefaultCatalog. It fails if a field is not in
+// the catalog, which can happen for queries validated with
+// AllowUnknownResources.
+func ResultSchema(q *Query) ([]Column, error) {
+	return DefaultCatalog().ResultSchema(q)
+}
+
+// ResultSchema returns the columns of q's results typed by c.
+func (c *Catalog) ResultSchema(q *Query) ([]Column, error) {
+	cols := make([]Column, len(q.Select))
+	var unknown []string
+	for i, f := range q.Select {
+		col, ok := c.Column(f.Name)
+		if !ok {
+			unknown = append(unknown, f.Name)
+		}
+		cols[i] = col
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("gaql: no type known for %s (catalog %s)", strings.Join(unknown, ", "), c.Version)
+	}
+	return cols, nil
+}
+
+// Column returns the result column of a field. Fields missing from the
+// catalog are reported as not found, and typed as strings, which every
+// value the API returns can be read as.
+func (c *Catalog) Column(field string) (Column, bool) {
+	info, ok := c.Field(field)
+	col := Column{Name: field, Category: info.Category, DataType: info.DataType, Repeated: info.Repeated}
+	switch info.DataType {
+	case "INT64":
+		col.GoType, col.ParquetType, col.SQLType = "int64", "INT64", "BIGINT"
+	case "INT32":
+		col.GoType, col.ParquetType, col.SQLType = "int32", "INT32", "INTEGER"
+	case "UINT64":
+		col.GoType, col.ParquetType, col.SQLType = "uint64", "INT64 (UINT_64)", "NUMERIC(20)"
+	case "DOUBLE":
+		col.GoType, col.ParquetType, col.SQLType = "float64", "DOUBLE", "DOUBLE PRECISION"
+	case "FLOAT":
+		col.GoType, col.ParquetType, col.SQLType = "float32", "FLOAT", "REAL"
+	case "BOOLEAN":
+		col.GoType, col.ParquetType, col.SQLType = "bool", "BOOLEAN", "BOOLEAN"
+	case "DATE":
+		col.GoType, col.ParquetType, col.SQLType = "string", "INT32 (DATE)", "DATE"
+	case "MESSAGE":
+		col.GoType, col.ParquetType, col.SQLType = "map[string]any", "BYTE_ARRAY (JSON)", "JSON"
+	default: // STRING, ENUM, RESOURCE_NAME, and unknown fields
+		col.GoType, col.ParquetType, col.SQLType = "string", "BYTE_ARRAY (STRING)", "VARCHAR"
+	}
+	if col.Repeated {
+		col.GoType = "[]" + col.GoType
+		col.SQLType += " ARRAY"
+	}
+	return col, ok
+}
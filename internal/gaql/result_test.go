@@ -0,0 +1,60 @@
+package gaql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResultSchema(t *testing.T) {
+	q, err := Parse("SELECT campaign.id, campaign.name, campaign.status, campaign.labels, " +
+		"ad_group_criterion.quality_info.quality_score, ad_group_criterion.negative, " +
+		"ad_group_ad.ad.responsive_search_ad.headlines, segments.date, metrics.clicks, metrics.ctr " +
+		"FROM ad_group_criterion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := ResultSchema(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name, goType, parquet, sql string
+	}{
+		{"campaign.id", "int64", "INT64", "BIGINT"},
+		{"campaign.name", "string", "BYTE_ARRAY (STRING)", "VARCHAR"},
+		{"campaign.status", "string", "BYTE_ARRAY (STRING)", "VARCHAR"},
+		{"campaign.labels", "[]string", "BYTE_ARRAY (STRING)", "VARCHAR ARRAY"},
+		{"ad_group_criterion.quality_info.quality_score", "int32", "INT32", "INTEGER"},
+		{"ad_group_criterion.negative", "bool", "BOOLEAN", "BOOLEAN"},
+		{"ad_group_ad.ad.responsive_search_ad.headlines", "[]map[string]any", "BYTE_ARRAY (JSON)", "JSON ARRAY"},
+		{"segments.date", "string", "INT32 (DATE)", "DATE"},
+		{"metrics.clicks", "int64", "INT64", "BIGINT"},
+		{"metrics.ctr", "float64", "DOUBLE", "DOUBLE PRECISION"},
+	}
+	if len(cols) != len(want) {
+		t.Fatalf("got %d columns, want %d", len(cols), len(want))
+	}
+	for i, w := range want {
+		c := cols[i]
+		if c.Name != w.name || c.GoType != w.goType || c.ParquetType != w.parquet || c.SQLType != w.sql {
+			t.Errorf("column %d = %s %s %s %s, want %s %s %s %s", i, c.Name, c.GoType, c.ParquetType, c.SQLType, w.name, w.goType, w.parquet, w.sql)
+		}
+	}
+	if cols[8].Category != "METRIC" || !cols[3].Repeated {
+		t.Errorf("categories or repetition lost: %+v, %+v", cols[8], cols[3])
+	}
+}
+
+func TestResultSchemaUnknownField(t *testing.T) {
+	q, err := Parse("SELECT campaign.id, campaign.made_up, metrics.made_up FROM campaign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ResultSchema(q)
+	if err == nil || !strings.Contains(err.Error(), "campaign.made_up, metrics.made_up") {
+		t.Errorf("err = %v, want the unknown fields named", err)
+	}
+	if col, ok := DefaultCatalog().Column("campaign.made_up"); ok || col.GoType != "string" {
+		t.Errorf("Column(campaign.made_up) = %+v, %v; want an untyped string", col, ok)
+	}
+}
@@ -0,0 +1,258 @@
+package gaql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// randomQuery wraps a generated Query so testing/quick can produce it.
+type randomQuery struct {
+	q *Query
+}
+
+// Generate builds a random query that the grammar accepts: identifiers
+// avoid keywords, values match what each operator takes, and PARAMETERS
+// values are bare identifiers.
+func (randomQuery) Generate(r *rand.Rand, size int) reflect.Value {
+	g := &queryGen{r: r}
+	b := Select(g.field())
+	for n := r.Intn(5); n > 0; n-- {
+		b.Select(g.field())
+	}
+	b.From(g.ident())
+
+	for n := r.Intn(5); n > 0; n-- {
+		switch op := Operator(r.Intn(int(OpNotRegexpMatch) + 1)); op {
+		case OpIsNull, OpIsNotNull:
+			b.Where(g.field(), op, Value{Type: ValueNull})
+		case OpDuring:
+			b.During(g.dateRange())
+		case OpBetween:
+			b.Between(g.date(), g.date())
+		case OpIn, OpNotIn, OpContainsAny, OpContainsAll, OpContainsNone:
+			items := make([]string, 1+r.Intn(4))
+			for i := range items {
+				items[i] = g.listItem()
+			}
+			b.Where(g.field(), op, ListValue(items...))
+		case OpLike, OpNotLike, OpRegexpMatch, OpNotRegexpMatch:
+			b.Where(g.field(), op, StringValue(g.str()))
+		default:
+			if r.Intn(2) == 0 {
+				b.Where(g.field(), op, NumberValue(g.number()))
+			} else {
+				b.Where(g.field(), op, StringValue(g.str()))
+			}
+		}
+	}
+
+	for n := r.Intn(3); n > 0; n-- {
+		b.OrderBy(g.field(), Direction(r.Intn(2)))
+	}
+	if r.Intn(2) == 0 {
+		b.Limit(1 + r.Intn(10000))
+	}
+
+	q := b.Query()
+	if r.Intn(4) == 0 {
+		q.Parameters = map[string]string{}
+		for n := 1 + r.Intn(2); n > 0; n-- {
+			q.Parameters[g.ident()] = g.ident()
+		}
+	}
+	return reflect.ValueOf(randomQuery{q})
+}
+
+type queryGen struct {
+	r *rand.Rand
+}
+
+const (
+	identChars  = "abcdefghijklmnopqrstuvwxyz_0123456789"
+	stringChars = "abcXYZ 019_-%.'\"\\\n\t()[],=é"
+)
+
+// ident returns a lowercase identifier that is not a keyword.
+func (g *queryGen) ident() string {
+	for {
+		var sb strings.Builder
+		sb.WriteByte(identChars[g.r.Intn(26)])
+		for n := g.r.Intn(10); n > 0; n-- {
+			sb.WriteByte(identChars[g.r.Intn(len(identChars))])
+		}
+		s := sb.String()
+		upper := strings.ToUpper(s)
+		if _, ok := Keywords[upper]; ok {
+			continue
+		}
+		if _, ok := LookupDateRange(upper); ok {
+			continue
+		}
+		return s
+	}
+}
+
+func (g *queryGen) field() string {
+	return g.ident() + "." + g.ident()
+}
+
+func (g *queryGen) str() string {
+	chars := []rune(stringChars)
+	var sb strings.Builder
+	for n := g.r.Intn(12); n > 0; n-- {
+		sb.WriteRune(chars[g.r.Intn(len(chars))])
+	}
+	return sb.String()
+}
+
+func (g *queryGen) number() float64 {
+	switch g.r.Intn(3) {
+	case 0:
+		return float64(g.r.Intn(1000000))
+	case 1:
+		return -float64(g.r.Intn(1000))
+	default:
+		return float64(g.r.Intn(100000)) / 100
+	}
+}
+
+// listItem returns a list item; numeric-looking items are serialized
+// bare and come back as number tokens with the same text.
+func (g *queryGen) listItem() string {
+	switch g.r.Intn(3) {
+	case 0:
+		return fmt.Sprint(g.r.Intn(1000) - 500)
+	case 1:
+		return strings.ToUpper(g.ident())
+	default:
+		return g.str()
+	}
+}
+
+func (g *queryGen) date() string {
+	return fmt.Sprintf("%04d-%02d-%02d", 2000+g.r.Intn(30), 1+g.r.Intn(12), 1+g.r.Intn(28))
+}
+
+func (g *queryGen) dateRange() DateRange {
+	var keys []string
+	dateRangeMu.RLock()
+	for k := range dateRangeKeywords {
+		keys = append(keys, k)
+	}
+	dateRangeMu.RUnlock()
+	// Map order is random; sort for a reproducible choice per seed.
+	sort.Strings(keys)
+	dr, _ := LookupDateRange(keys[g.r.Intn(len(keys))])
+	return dr
+}
+
+// withoutSpans returns a copy of q with every source span cleared, so a
+// parsed query can be compared with a built one. An empty PARAMETERS map
+// is treated like a nil one.
+func withoutSpans(q *Query) *Query {
+	c := q.Clone()
+	if len(c.Parameters) == 0 {
+		c.Parameters = nil
+	}
+	c.SelectSpan, c.FromSpan, c.WhereSpan = Span{}, Span{}, Span{}
+	c.OrderBySpan, c.LimitSpan, c.ParametersSpan = Span{}, Span{}, Span{}
+	for i := range c.Select {
+		c.Select[i].Span = Span{}
+	}
+	for i := range c.Where {
+		c.Where[i].Span, c.Where[i].FieldSpan, c.Where[i].ValueSpan = Span{}, Span{}, Span{}
+	}
+	for i := range c.OrderBy {
+		c.OrderBy[i].Span = Span{}
+	}
+	return c
+}
+
+func TestRoundTrip(t *testing.T) {
+	property := func(rq randomQuery) bool {
+		text := rq.q.String()
+		parsed, err := Parse(text)
+		if err != nil {
+			t.Logf("Parse(%q): %v", text, err)
+			return false
+		}
+		if got, want := withoutSpans(parsed), withoutSpans(rq.q); !reflect.DeepEqual(got, want) {
+			t.Logf("round trip of %q\ngot:  %#v\nwant: %#v", text, *got, *want)
+			return false
+		}
+		if again := parsed.String(); again != text {
+			t.Logf("String not stable:\nfirst:  %s\nsecond: %s", text, again)
+			return false
+		}
+		return true
+	}
+	cfg := &quick.Config{MaxCount: 2000, Rand: rand.New(rand.NewSource(1))}
+	if err := quick.Check(property, cfg); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	property := func(rq randomQuery) bool {
+		data, err := json.Marshal(rq.q)
+		if err != nil {
+			t.Logf("Marshal(%s): %v", rq.q, err)
+			return false
+		}
+		var got Query
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Logf("Unmarshal(%s): %v", data, err)
+			return false
+		}
+		if !reflect.DeepEqual(withoutSpans(&got), withoutSpans(rq.q)) {
+			t.Logf("JSON round trip of %s\ngot:  %s", data, got.String())
+			return false
+		}
+		return true
+	}
+	cfg := &quick.Config{MaxCount: 1000, Rand: rand.New(rand.NewSource(1))}
+	if err := quick.Check(property, cfg); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSpecRoundTrip(t *testing.T) {
+	property := func(rq randomQuery) bool {
+		data, err := json.Marshal(rq.q.Spec())
+		if err != nil {
+			t.Logf("Marshal(%s): %v", rq.q, err)
+			return false
+		}
+		var spec Spec
+		if err := json.Unmarshal(data, &spec); err != nil {
+			t.Logf("Unmarshal(%s): %v", data, err)
+			return false
+		}
+		got, err := spec.Query()
+		if err != nil {
+			t.Logf("Query(%s): %v", data, err)
+			return false
+		}
+		// The date condition lifted into during or between comes back last.
+		want := withoutSpans(rq.q)
+		if i := specDateCondition(want); i >= 0 {
+			date := want.Where[i]
+			want.Where = append(append(want.Where[:i:i], want.Where[i+1:]...), date)
+		}
+		if !reflect.DeepEqual(withoutSpans(got), want) {
+			t.Logf("spec round trip of %s\ngot:  %s\nwant: %s", data, got, want)
+			return false
+		}
+		return true
+	}
+	cfg := &quick.Config{MaxCount: 1000, Rand: rand.New(rand.NewSource(1))}
+	if err := quick.Check(property, cfg); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,262 @@
+package gaql
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// Spec is the declarative form of a query, meant to be written by hand
+// or generated by other tools in JSON:
+//
+//	{"select": ["campaign.name", "metrics.clicks"],
+//	 "from": "campaign",
+//	 "where": [{"field": "campaign.status", "value": "ENABLED"}],
+//	 "during": "LAST_7_DAYS",
+//	 "order_by": ["metrics.clicks DESC"],
+//	 "limit": 10}
+//
+// A Spec and the Query it describes convert both ways. The segments.date
+// condition is kept in During or Between rather than Where, so a query
+// converted to a Spec and back has it last in the WHERE clause.
+type Spec struct {
+	Select []string        `json:"select"`
+	From   string          `json:"from"`
+	Where  []SpecCondition `json:"where,omitempty"`
+
+	// During is a date range keyword for segments.date, and Between its
+	// first and last day in YYYY-MM-DD form. At most one may be set.
+	During  string   `json:"during,omitempty"`
+	Between []string `json:"between,omitempty"`
+
+	// OrderBy items are a field optionally followed by ASC or DESC.
+	OrderBy    []string          `json:"order_by,omitempty"`
+	Limit      int               `json:"limit,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// SpecCondition is one WHERE condition of a Spec. Value is a string, a
+// number, or a list of strings, and is left out for IS NULL and IS NOT
+// NULL. Op defaults to IN when Value is a list and to = otherwise; the
+// value of DURING is a date range keyword.
+type SpecCondition struct {
+	Field string `json:"field"`
+	Op    string `json:"op,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Spec returns the declarative form of q.
+func (q *Query) Spec() *Spec {
+	s := &Spec{
+		Select:     q.FieldNames(),
+		From:       q.From,
+		Limit:      q.Limit,
+		Parameters: maps.Clone(q.Parameters),
+	}
+	date := specDateCondition(q)
+	for i, c := range q.Where {
+		if i == date {
+			if c.Operator == OpDuring {
+				s.During = c.Value.DateRange.String()
+			} else {
+				s.Between = append([]string{}, c.Value.List...)
+			}
+			continue
+		}
+		sc := SpecCondition{Field: c.Field}
+		switch c.Value.Type {
+		case ValueString:
+			sc.Value = c.Value.Str
+		case ValueNumber:
+			sc.Value = c.Value.Number
+		case ValueList:
+			sc.Value = append([]string{}, c.Value.List...)
+		case ValueDateRange:
+			sc.Value = c.Value.DateRange.String()
+		}
+		switch {
+		case c.Operator == OpIsNull || c.Operator == OpIsNotNull:
+			sc.Value = nil
+			sc.Op = c.Operator.String()
+		case c.Operator == OpIn && c.Value.Type == ValueList:
+		case c.Operator == OpEq && c.Value.Type != ValueList:
+		default:
+			sc.Op = c.Operator.String()
+		}
+		s.Where = append(s.Where, sc)
+	}
+	for _, o := range q.OrderBy {
+		item := o.Field
+		if o.Direction == Desc {
+			item += " DESC"
+		}
+		s.OrderBy = append(s.OrderBy, item)
+	}
+	return s
+}
+
+// specDateCondition returns the index of the condition that becomes
+// During or Between, or -1 when there is none or more than one.
+func specDateCondition(q *Query) int {
+	found := -1
+	for i, c := range q.Where {
+		if c.Field != "segments.date" {
+			continue
+		}
+		during := c.Operator == OpDuring && c.Value.Type == ValueDateRange && c.Value.DateRange != DateRangeCustom
+		between := c.Operator == OpBetween && c.Value.Type == ValueList && len(c.Value.List) == 2
+		if !during && !between {
+			continue
+		}
+		if found >= 0 {
+			return -1
+		}
+		found = i
+	}
+	return found
+}
+
+// Query returns the query s describes. It is not validated.
+func (s *Spec) Query() (*Query, error) {
+	if len(s.Select) == 0 {
+		return nil, fmt.Errorf("gaql: spec: select is required")
+	}
+	if s.From == "" {
+		return nil, fmt.Errorf("gaql: spec: from is required")
+	}
+	q := &Query{From: s.From, Limit: s.Limit, Parameters: maps.Clone(s.Parameters)}
+	for _, name := range s.Select {
+		q.Select = append(q.Select, Field{Name: name})
+	}
+	for i, sc := range s.Where {
+		if sc.Field == "" {
+			return nil, fmt.Errorf("gaql: spec: where[%d]: field is required", i)
+		}
+		op, v, err := sc.condition()
+		if err != nil {
+			return nil, fmt.Errorf("gaql: spec: where[%d] (%s): %w", i, sc.Field, err)
+		}
+		q.Where = append(q.Where, Condition{Field: sc.Field, Operator: op, Value: v})
+	}
+	switch {
+	case s.During != "" && len(s.Between) > 0:
+		return nil, fmt.Errorf("gaql: spec: during and between cannot both be set")
+	case s.During != "":
+		dr, ok := LookupDateRange(s.During)
+		if !ok || dr == DateRangeCustom {
+			return nil, fmt.Errorf("gaql: spec: unknown date range %q", s.During)
+		}
+		q.Where = append(q.Where, Condition{Field: "segments.date", Operator: OpDuring, Value: Value{Type: ValueDateRange, DateRange: dr}})
+	case len(s.Between) == 2:
+		q.Where = append(q.Where, Condition{Field: "segments.date", Operator: OpBetween, Value: ListValue(s.Between[0], s.Between[1])})
+	case len(s.Between) > 0:
+		return nil, fmt.Errorf("gaql: spec: between needs a first and last day, got %d values", len(s.Between))
+	}
+	for _, item := range s.OrderBy {
+		parts := strings.Fields(item)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("gaql: spec: invalid order_by item %q", item)
+		}
+		dir := Asc
+		if len(parts) == 2 {
+			var err error
+			if dir, err = lookupDirection(parts[1]); err != nil {
+				return nil, fmt.Errorf("gaql: spec: invalid order_by item %q", item)
+			}
+		}
+		q.OrderBy = append(q.OrderBy, Ordering{Field: parts[0], Direction: dir})
+	}
+	return q, nil
+}
+
+// condition resolves the operator and value of sc.
+func (sc SpecCondition) condition() (Operator, Value, error) {
+	var list []string
+	isList := false
+	switch v := sc.Value.(type) {
+	case []string:
+		list, isList = v, true
+	case []any:
+		isList = true
+		for _, item := range v {
+			s, ok := specScalar(item)
+			if !ok {
+				return 0, Value{}, fmt.Errorf("list items must be strings or numbers, got %T", item)
+			}
+			list = append(list, s)
+		}
+	}
+
+	op := OpEq
+	if isList {
+		op = OpIn
+	}
+	if sc.Op != "" {
+		var err error
+		if op, err = LookupOperator(sc.Op); err != nil {
+			return 0, Value{}, err
+		}
+	}
+
+	switch op {
+	case OpIsNull, OpIsNotNull:
+		if sc.Value != nil {
+			return 0, Value{}, fmt.Errorf("%s takes no value", op)
+		}
+		return op, Value{Type: ValueNull}, nil
+	case OpIn, OpNotIn, OpContainsAny, OpContainsAll, OpContainsNone:
+		if !isList {
+			return 0, Value{}, fmt.Errorf("%s needs a list value", op)
+		}
+		return op, ListValue(list...), nil
+	case OpBetween:
+		if len(list) != 2 {
+			return 0, Value{}, fmt.Errorf("BETWEEN needs a list of two values")
+		}
+		return op, ListValue(list...), nil
+	case OpDuring:
+		keyword, _ := sc.Value.(string)
+		dr, ok := LookupDateRange(keyword)
+		if !ok || dr == DateRangeCustom {
+			return 0, Value{}, fmt.Errorf("DURING needs a date range keyword, got %v", sc.Value)
+		}
+		return op, Value{Type: ValueDateRange, DateRange: dr}, nil
+	}
+	if isList {
+		return 0, Value{}, fmt.Errorf("%s needs a single value, not a list", op)
+	}
+	switch v := sc.Value.(type) {
+	case nil:
+		return 0, Value{}, fmt.Errorf("value is required")
+	case string:
+		return op, StringValue(v), nil
+	}
+	s, ok := specScalar(sc.Value)
+	if !ok {
+		return 0, Value{}, fmt.Errorf("value must be a string, number, or list, got %T", sc.Value)
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, Value{}, err
+	}
+	return op, NumberValue(n), nil
+}
+
+// specScalar formats a string or number of a decoded spec value.
+func specScalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case json.Number:
+		return v.String(), true
+	}
+	return "", false
+}
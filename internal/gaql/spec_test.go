@@ -0,0 +1,80 @@
+package gaql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSpecQuery(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr string
+	}{
+		{
+			spec: `{"select": ["campaign.name", "metrics.clicks"], "from": "campaign",
+				"where": [{"field": "campaign.status", "value": "ENABLED"},
+					{"field": "campaign.id", "value": [1, 2]},
+					{"field": "metrics.clicks", "op": ">", "value": 10},
+					{"field": "campaign.end_date", "op": "is null"}],
+				"during": "LAST_7_DAYS", "order_by": ["metrics.clicks DESC", "campaign.name"], "limit": 5}`,
+			want: "SELECT campaign.name, metrics.clicks FROM campaign WHERE campaign.status = 'ENABLED' AND campaign.id IN (1, 2) AND metrics.clicks > 10 AND campaign.end_date IS NULL AND segments.date DURING LAST_7_DAYS ORDER BY metrics.clicks DESC, campaign.name LIMIT 5",
+		},
+		{
+			spec: `{"select": ["segments.week"], "from": "customer", "between": ["2026-01-01", "2026-01-31"]}`,
+			want: "SELECT segments.week FROM customer WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31'",
+		},
+		{spec: `{"from": "campaign"}`, wantErr: "select is required"},
+		{spec: `{"select": ["campaign.id"], "from": "campaign", "during": "LAST_7_DAYS", "between": ["2026-01-01", "2026-01-31"]}`, wantErr: "cannot both be set"},
+		{spec: `{"select": ["campaign.id"], "from": "campaign", "during": "LAST_8_DAYS"}`, wantErr: `unknown date range "LAST_8_DAYS"`},
+		{spec: `{"select": ["campaign.id"], "from": "campaign", "where": [{"field": "campaign.id", "op": "IN", "value": 1}]}`, wantErr: "IN needs a list value"},
+		{spec: `{"select": ["campaign.id"], "from": "campaign", "where": [{"field": "campaign.id"}]}`, wantErr: "value is required"},
+		{spec: `{"select": ["campaign.id"], "from": "campaign", "where": [{"field": "campaign.id", "op": "~", "value": 1}]}`, wantErr: `unknown operator "~"`},
+		{spec: `{"select": ["campaign.id"], "from": "campaign", "order_by": ["campaign.id DOWN"]}`, wantErr: "invalid order_by item"},
+	}
+	for _, tt := range tests {
+		var s Spec
+		if err := json.Unmarshal([]byte(tt.spec), &s); err != nil {
+			t.Fatal(err)
+		}
+		q, err := s.Query()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.spec, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+		if got := q.String(); got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestQueryJSON(t *testing.T) {
+	q, err := Parse("SELECT campaign.id FROM campaign WHERE campaign.status != 'REMOVED' AND segments.date DURING LAST_7_DAYS ORDER BY campaign.id DESC LIMIT 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"select":[{"name":"campaign.id"}],"from":"campaign","where":[` +
+		`{"field":"campaign.status","operator":"!=","value":{"type":"string","value":"REMOVED"}},` +
+		`{"field":"segments.date","operator":"DURING","value":{"type":"date_range","value":"LAST_7_DAYS"}}],` +
+		`"order_by":[{"field":"campaign.id","direction":"DESC"}],"limit":10}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+
+	var bad Query
+	err = json.Unmarshal([]byte(`{"select":[{"name":"campaign.id"}],"from":"campaign","where":[{"field":"campaign.id","operator":"="}]}`), &bad)
+	if err == nil || !strings.Contains(err.Error(), "value is missing") {
+		t.Errorf("expected a missing value error, got %v", err)
+	}
+}
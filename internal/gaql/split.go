@@ -0,0 +1,86 @@
+package gaql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Statement is one query of a multi-statement source.
+type Statement struct {
+	// Name is taken from a "-- name: NAME" comment in the statement,
+	// or empty.
+	Name string
+
+	// Text is the query with "--" comments removed and surrounding
+	// space trimmed.
+	Text string
+
+	// Line is the 1-based line of the source where the query starts.
+	Line int
+}
+
+// Label returns the statement's name, or "line N" when it has none.
+func (s Statement) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return "line " + strconv.Itoa(s.Line)
+}
+
+// SplitStatements splits src into the queries separated by semicolons
+// outside string literals and "--" line comments. Statements holding
+// only comments and space are dropped.
+func SplitStatements(src string) []Statement {
+	var stmts []Statement
+	var text strings.Builder
+	var name string
+	line, start := 1, 0
+	var quote byte
+
+	flush := func() {
+		if t := strings.TrimSpace(text.String()); t != "" {
+			stmts = append(stmts, Statement{Name: name, Text: t, Line: start})
+		}
+		text.Reset()
+		name, start = "", 0
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			text.WriteByte(c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				text.WriteByte(src[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '-' && i+1 < len(src) && src[i+1] == '-':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			comment := strings.TrimSpace(src[i+2 : i+end])
+			if n, ok := strings.CutPrefix(comment, "name:"); ok && name == "" {
+				name = strings.TrimSpace(n)
+			}
+			i += end - 1
+		case c == ';':
+			flush()
+		default:
+			if c == '\'' || c == '"' {
+				quote = c
+			}
+			if start == 0 && c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				start = line
+			}
+			text.WriteByte(c)
+		}
+		if c == '\n' {
+			line++
+		}
+	}
+	flush()
+	return stmts
+}
@@ -0,0 +1,66 @@
+package gaql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Statement
+	}{
+		{
+			name: "single statement without semicolon",
+			src:  "SELECT campaign.id FROM campaign",
+			want: []Statement{{Text: "SELECT campaign.id FROM campaign", Line: 1}},
+		},
+		{
+			name: "named statements",
+			src: `-- Weekly bundle
+-- name: spend
+SELECT metrics.cost_micros FROM customer WHERE segments.date DURING LAST_7_DAYS;
+
+-- name: campaigns
+SELECT campaign.name
+FROM campaign; -- trailing comment
+`,
+			want: []Statement{
+				{Name: "spend", Text: "SELECT metrics.cost_micros FROM customer WHERE segments.date DURING LAST_7_DAYS", Line: 3},
+				{Name: "campaigns", Text: "SELECT campaign.name\nFROM campaign", Line: 6},
+			},
+		},
+		{
+			name: "semicolons and dashes in strings",
+			src:  "SELECT campaign.id FROM campaign WHERE campaign.name = 'a;b--c';;\n  SELECT ad_group.id FROM ad_group",
+			want: []Statement{
+				{Text: "SELECT campaign.id FROM campaign WHERE campaign.name = 'a;b--c'", Line: 1},
+				{Text: "SELECT ad_group.id FROM ad_group", Line: 2},
+			},
+		},
+		{
+			name: "comments only",
+			src:  "-- nothing here\n;\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitStatements(tt.src)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementLabel(t *testing.T) {
+	if got := (Statement{Name: "spend", Line: 3}).Label(); got != "spend" {
+		t.Errorf("Label() = %q", got)
+	}
+	if got := (Statement{Line: 3}).Label(); got != "line 3" {
+		t.Errorf("Label() = %q", got)
+	}
+}
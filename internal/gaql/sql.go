@@ -0,0 +1,800 @@
+package gaql
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Translation is a GAQL query translated from SQL by FromSQL.
+type Translation struct {
+	// Query is the GAQL query. It parses but is not validated: the field
+	// guessed for an unqualified column may not exist or combine with
+	// the others.
+	Query string `json:"query"`
+
+	// Untranslated lists the SQL constructs left out of Query, or whose
+	// meaning changed on the way. It is empty, never nil, when the SQL
+	// translated in full.
+	Untranslated []Untranslated `json:"untranslated"`
+}
+
+// Untranslated is a SQL construct FromSQL could not carry over.
+type Untranslated struct {
+	SQL    string `json:"sql"` // the construct as written
+	Reason string `json:"reason"`
+
+	// Filter is true for a WHERE or HAVING condition, whose loss makes
+	// Query match rows the SQL would not.
+	Filter bool `json:"filter,omitempty"`
+}
+
+func (u Untranslated) String() string {
+	return u.SQL + ": " + u.Reason
+}
+
+// DroppedFilter reports whether any WHERE or HAVING condition was left
+// out of the query.
+func (t *Translation) DroppedFilter() bool {
+	return slices.ContainsFunc(t.Untranslated, func(u Untranslated) bool { return u.Filter })
+}
+
+// FromSQL translates a SQL SELECT statement into GAQL on a best-effort
+// basis, for users who know SQL but not GAQL:
+//
+//	t, err := gaql.FromSQL("SELECT id, name, clicks FROM campaign WHERE status <> 'REMOVED' LIMIT 10")
+//	// t.Query: SELECT campaign.id, campaign.name, metrics.clicks FROM campaign
+//	//          WHERE campaign.status != 'REMOVED' LIMIT 10
+//
+// Unqualified columns are qualified with the FROM resource, or become
+// metrics or segments when DefaultCatalog knows them as such: clicks is
+// metrics.clicks and date is segments.date. Table aliases and joined
+// resources are resolved, <> becomes !=, DATE literals become strings,
+// SUM(x) becomes x since GAQL aggregates metrics itself, and HAVING
+// conditions join the WHERE clause.
+//
+// A NOT before a condition negates its operator, so NOT status = 'PAUSED'
+// becomes status != 'PAUSED'. Constructs GAQL cannot express, such as
+// OR, OFFSET, expressions, and column aliases, are left out and listed
+// in Untranslated. FromSQL fails only when no query is left: SQL it
+// cannot read, SELECT *, a missing FROM clause, or statements such as
+// UNION.
+func FromSQL(sql string) (*Translation, error) {
+	toks, err := lexSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+	clauses, err := splitSQLClauses(sql, toks)
+	if err != nil {
+		return nil, err
+	}
+	tr := &sqlTranslator{
+		src:     sql,
+		catalog: DefaultCatalog(),
+		aliases: map[string]string{},
+		columns: map[string]string{},
+		t:       Translation{Untranslated: []Untranslated{}},
+	}
+	return tr.translate(clauses)
+}
+
+// sqlTokenKind classifies SQL tokens.
+type sqlTokenKind int
+
+const (
+	sqlWord   sqlTokenKind = iota // keyword or identifier, possibly dotted
+	sqlString                     // 'text', unquoted in value
+	sqlNumber                     // 12 or 4.5
+	sqlSymbol                     // operator or punctuation
+)
+
+type sqlToken struct {
+	kind       sqlTokenKind
+	value      string
+	start, end int // byte offsets in the SQL
+}
+
+// is reports whether t is the keyword or symbol s, ignoring case.
+func (t sqlToken) is(s string) bool {
+	return (t.kind == sqlWord || t.kind == sqlSymbol) && strings.EqualFold(t.value, s)
+}
+
+// lexSQL splits SQL into tokens, skipping comments. Quoted identifiers
+// ("name" or `name`) are words.
+func lexSQL(src string) ([]sqlToken, error) {
+	var toks []sqlToken
+	fail := func(i int, msg string) error {
+		line, col := position(src, i)
+		return &ParseError{Message: msg + " in SQL", Line: line, Column: col, Offset: i}
+	}
+	for i := 0; i < len(src); {
+		ch := src[i]
+		start := i
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fail(i, "unterminated comment")
+			}
+			i += end + 4
+			continue
+		case ch == '\'':
+			var sb strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, fail(start, "unterminated string")
+				}
+				if src[i] == '\'' {
+					if i+1 < len(src) && src[i+1] == '\'' {
+						sb.WriteByte('\'')
+						i++
+						continue
+					}
+					break
+				}
+				sb.WriteByte(src[i])
+			}
+			i++
+			toks = append(toks, sqlToken{kind: sqlString, value: sb.String(), start: start, end: i})
+		case ch == '"' || ch == '`':
+			end := strings.IndexByte(src[i+1:], ch)
+			if end < 0 {
+				return nil, fail(i, "unterminated quoted identifier")
+			}
+			i += end + 2
+			toks = append(toks, sqlToken{kind: sqlWord, value: src[start+1 : i-1], start: start, end: i})
+		case isDigit(ch) || ch == '.' && i+1 < len(src) && isDigit(src[i+1]):
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			toks = append(toks, sqlToken{kind: sqlNumber, value: src[start:i], start: start, end: i})
+		case isLetter(ch) || ch == '_':
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i]) || src[i] == '_' || src[i] == '.') {
+				i++
+			}
+			toks = append(toks, sqlToken{kind: sqlWord, value: src[start:i], start: start, end: i})
+		default:
+			sym := string(ch)
+			if two := src[i:min(i+2, len(src))]; two == "<>" || two == "!=" || two == "<=" || two == ">=" {
+				sym = two
+			} else if !strings.ContainsRune("=<>(),*;-+/%", rune(ch)) {
+				return nil, fail(i, fmt.Sprintf("unexpected character %q", ch))
+			}
+			i += len(sym)
+			toks = append(toks, sqlToken{kind: sqlSymbol, value: sym, start: start, end: i})
+		}
+	}
+	return toks, nil
+}
+
+// sqlClause is one clause of a SQL statement: its keyword, upper case
+// and single-spaced, and the tokens that follow it.
+type sqlClause struct {
+	keyword string
+	start   int // byte offset of the keyword
+	toks    []sqlToken
+}
+
+// sqlUnsupported are statement keywords with no GAQL equivalent.
+var sqlUnsupported = []string{"UNION", "INTERSECT", "EXCEPT", "WITH", "INSERT", "UPDATE", "DELETE", "WINDOW"}
+
+// splitSQLClauses splits a statement into its clauses at the top-level
+// SELECT, FROM, WHERE, GROUP BY, HAVING, ORDER BY, LIMIT, and OFFSET
+// keywords.
+func splitSQLClauses(src string, toks []sqlToken) ([]sqlClause, error) {
+	if n := len(toks); n > 0 && toks[n-1].is(";") {
+		toks = toks[:n-1]
+	}
+	var clauses []sqlClause
+	depth := 0
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case tok.is(";"):
+			line, _ := position(src, tok.start)
+			return nil, fmt.Errorf("gaql: FromSQL translates one statement; found more after line %d", line)
+		}
+		keyword := ""
+		if depth == 0 && tok.kind == sqlWord {
+			switch upper := strings.ToUpper(tok.value); upper {
+			case "SELECT", "FROM", "WHERE", "HAVING", "LIMIT", "OFFSET":
+				keyword = upper
+			case "GROUP", "ORDER":
+				if i+1 < len(toks) && toks[i+1].is("BY") {
+					keyword = upper + " BY"
+					i++
+				}
+			default:
+				if slices.Contains(sqlUnsupported, upper) {
+					return nil, fmt.Errorf("gaql: %s has no GAQL equivalent", upper)
+				}
+			}
+		}
+		if keyword == "" {
+			if len(clauses) == 0 {
+				return nil, fmt.Errorf("gaql: expected SELECT at the start of the SQL, got %q", tok.value)
+			}
+			clauses[len(clauses)-1].toks = append(clauses[len(clauses)-1].toks, tok)
+			continue
+		}
+		if slices.ContainsFunc(clauses, func(c sqlClause) bool { return c.keyword == keyword }) {
+			return nil, fmt.Errorf("gaql: the SQL has more than one %s clause", keyword)
+		}
+		clauses = append(clauses, sqlClause{keyword: keyword, start: tok.start})
+	}
+	if len(clauses) == 0 || clauses[0].keyword != "SELECT" {
+		return nil, fmt.Errorf("gaql: expected a SQL SELECT statement")
+	}
+	for _, c := range clauses {
+		if len(c.toks) == 0 {
+			return nil, fmt.Errorf("gaql: the SQL %s clause is empty", c.keyword)
+		}
+	}
+	return clauses, nil
+}
+
+// sqlTranslator carries the state of one FromSQL call.
+type sqlTranslator struct {
+	src     string
+	catalog *Catalog
+
+	from    string
+	joined  []string          // joined resources, in order
+	aliases map[string]string // table name or alias to resource
+	columns map[string]string // column alias to field
+
+	selected []string // fields selected, in order
+	items    []string // field of each SQL select item, "" when dropped
+	where    []string
+	orderBy  []string
+	limit    string
+
+	t Translation
+}
+
+// drop records toks as untranslated.
+func (tr *sqlTranslator) drop(toks []sqlToken, reason string) {
+	if len(toks) > 0 {
+		tr.dropText(toks[0].start, toks[len(toks)-1].end, reason)
+	}
+}
+
+// dropFilter records the condition toks as untranslated.
+func (tr *sqlTranslator) dropFilter(toks []sqlToken, reason string) {
+	tr.drop(toks, reason)
+	tr.t.Untranslated[len(tr.t.Untranslated)-1].Filter = true
+}
+
+// dropText records the SQL from start to end as untranslated.
+func (tr *sqlTranslator) dropText(start, end int, reason string) {
+	sql := strings.Join(strings.Fields(tr.src[start:end]), " ")
+	tr.t.Untranslated = append(tr.t.Untranslated, Untranslated{SQL: sql, Reason: reason})
+}
+
+func (tr *sqlTranslator) translate(clauses []sqlClause) (*Translation, error) {
+	byKeyword := map[string][]sqlToken{}
+	for _, c := range clauses {
+		byKeyword[c.keyword] = c.toks
+	}
+	from, ok := byKeyword["FROM"]
+	if !ok {
+		return nil, fmt.Errorf("gaql: the SQL has no FROM clause naming a resource")
+	}
+	if err := tr.fromClause(from); err != nil {
+		return nil, err
+	}
+	if err := tr.selectClause(byKeyword["SELECT"]); err != nil {
+		return nil, err
+	}
+	for _, c := range clauses {
+		switch c.keyword {
+		case "WHERE", "HAVING":
+			tr.conditions(c.toks)
+		case "GROUP BY":
+			tr.groupBy(c.toks)
+		case "ORDER BY":
+			tr.orderByClause(c.toks)
+		case "LIMIT":
+			tr.limitClause(c.toks)
+		case "OFFSET":
+			tr.dropText(c.start, c.toks[len(c.toks)-1].end, noOffset)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + strings.Join(tr.selected, ", ") + " FROM " + tr.from)
+	if len(tr.where) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(tr.where, " AND "))
+	}
+	if len(tr.orderBy) > 0 {
+		sb.WriteString(" ORDER BY " + strings.Join(tr.orderBy, ", "))
+	}
+	if tr.limit != "" {
+		sb.WriteString(" LIMIT " + tr.limit)
+	}
+	q, err := Parse(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("gaql: the translation %q does not parse: %w", sb.String(), err)
+	}
+	tr.t.Query = q.String()
+	return &tr.t, nil
+}
+
+// fromClause reads the resource, its alias, and any joined resources.
+func (tr *sqlTranslator) fromClause(toks []sqlToken) error {
+	table := func(i int) (string, int, error) {
+		if i >= len(toks) || toks[i].kind != sqlWord {
+			return "", i, fmt.Errorf("gaql: expected a resource name in the FROM clause")
+		}
+		name := strings.ToLower(toks[i].value)
+		if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+			name = name[dot+1:] // a schema or dataset prefix
+		}
+		tr.aliases[name] = name
+		i++
+		if i < len(toks) && toks[i].is("AS") {
+			i++
+		}
+		if i < len(toks) && toks[i].kind == sqlWord && !isJoinWord(toks[i]) {
+			tr.aliases[strings.ToLower(toks[i].value)] = name
+			i++
+		}
+		return name, i, nil
+	}
+
+	var err error
+	i := 0
+	if tr.from, i, err = table(0); err != nil {
+		return err
+	}
+	for i < len(toks) {
+		start := i
+		for i < len(toks) && isJoinWord(toks[i]) && !toks[i].is("JOIN") && !toks[i].is("ON") {
+			i++ // INNER, LEFT, OUTER, ...
+		}
+		if i >= len(toks) || !toks[i].is("JOIN") && !toks[i].is(",") {
+			tr.drop(toks[start:], "not understood as part of the FROM clause")
+			return nil
+		}
+		var name string
+		if name, i, err = table(i + 1); err != nil {
+			return err
+		}
+		if i < len(toks) && toks[i].is("ON") {
+			for i++; i < len(toks) && !isJoinWord(toks[i]) && !toks[i].is(","); i++ {
+			}
+		}
+		tr.joined = append(tr.joined, name)
+		tr.drop(toks[start:i], fmt.Sprintf("GAQL joins related resources implicitly; %s fields are selected from %s directly", name, tr.from))
+	}
+	return nil
+}
+
+func isJoinWord(t sqlToken) bool {
+	for _, w := range []string{"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "OUTER", "CROSS", "NATURAL", "ON"} {
+		if t.is(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// column returns the GAQL field a SQL column names.
+func (tr *sqlTranslator) column(name string) string {
+	name = strings.ToLower(name)
+	if prefix, rest, ok := strings.Cut(name, "."); ok {
+		if resource, ok := tr.aliases[prefix]; ok {
+			return resource + "." + rest
+		}
+		return name
+	}
+	if field, ok := tr.columns[name]; ok {
+		return field
+	}
+	candidates := []string{tr.from + "." + name, "metrics." + name, "segments." + name}
+	for _, resource := range tr.joined {
+		candidates = append(candidates, resource+"."+name)
+	}
+	for _, c := range candidates {
+		if _, ok := tr.catalog.Field(c); ok {
+			return c
+		}
+	}
+	return candidates[0]
+}
+
+// operand reads a column, or an aggregate of one, at toks[i]. It
+// returns the field and the index after the operand, or ok false.
+func (tr *sqlTranslator) operand(toks []sqlToken, i int) (field string, next int, ok bool) {
+	if i >= len(toks) || toks[i].kind != sqlWord || isSQLKeyword(toks[i]) {
+		return "", i, false
+	}
+	if i+3 < len(toks) && toks[i+1].is("(") && toks[i+2].kind == sqlWord && toks[i+3].is(")") && isAggregate(toks[i]) {
+		return tr.column(toks[i+2].value), i + 4, true
+	}
+	if i+1 < len(toks) && toks[i+1].is("(") {
+		return "", i, false
+	}
+	return tr.column(toks[i].value), i + 1, true
+}
+
+func isAggregate(t sqlToken) bool {
+	return t.is("SUM") || t.is("AVG") || t.is("MIN") || t.is("MAX")
+}
+
+// isSQLKeyword reports whether t is a keyword that cannot be a column.
+func isSQLKeyword(t sqlToken) bool {
+	for _, w := range []string{"NOT", "AND", "OR", "NULL", "TRUE", "FALSE", "CASE", "EXISTS", "DISTINCT"} {
+		if t.is(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectClause translates the select list, dropping aliases and
+// unwrapping aggregates.
+func (tr *sqlTranslator) selectClause(toks []sqlToken) error {
+	if len(toks) > 0 && (toks[0].is("DISTINCT") || toks[0].is("ALL")) {
+		if toks[0].is("DISTINCT") {
+			tr.drop(toks[:1], "GAQL returns one row per combination of the selected attributes and segments")
+		}
+		toks = toks[1:]
+	}
+	for _, item := range splitSQLList(toks) {
+		if len(item) == 1 && item[0].is("*") {
+			return fmt.Errorf("gaql: SELECT * has no GAQL equivalent; list the fields to select")
+		}
+		if n := len(item); n >= 3 && item[n-2].is("AS") && item[n-1].kind == sqlWord {
+			item = tr.columnAlias(item, n-2)
+		} else if n >= 2 && item[n-1].kind == sqlWord && (item[n-2].kind == sqlWord || item[n-2].is(")")) && !isSQLKeyword(item[n-1]) {
+			item = tr.columnAlias(item, n-1)
+		}
+		field, next, ok := tr.operand(item, 0)
+		switch {
+		case !ok || next != len(item):
+			tr.drop(item, "GAQL selects fields, not expressions")
+			field = ""
+		case len(item) > 1 && !item[0].is("SUM"):
+			tr.drop(item, fmt.Sprintf("GAQL aggregates metrics itself; %s is selected instead", field))
+		}
+		tr.items = append(tr.items, field)
+		if field != "" && !slices.Contains(tr.selected, field) {
+			tr.selected = append(tr.selected, field)
+		}
+	}
+	if len(tr.selected) == 0 {
+		return fmt.Errorf("gaql: no column of the SELECT clause translates to a GAQL field")
+	}
+	return nil
+}
+
+// columnAlias records the alias starting at item[at], so ORDER BY can
+// use it, and returns the item without it.
+func (tr *sqlTranslator) columnAlias(item []sqlToken, at int) []sqlToken {
+	alias := item[len(item)-1]
+	if field, next, ok := tr.operand(item, 0); ok && next == at {
+		tr.columns[strings.ToLower(alias.value)] = field
+	}
+	tr.drop(item[at:], "GAQL has no column aliases")
+	return item[:at]
+}
+
+// splitSQLList splits toks at top-level commas, skipping empty items.
+func splitSQLList(toks []sqlToken) [][]sqlToken {
+	var items [][]sqlToken
+	depth, start := 0, 0
+	for i, tok := range toks {
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case tok.is(",") && depth == 0:
+			if i > start {
+				items = append(items, toks[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(toks) {
+		items = append(items, toks[start:])
+	}
+	return items
+}
+
+// conditions translates a WHERE or HAVING clause into GAQL conditions,
+// which can only be joined with AND.
+func (tr *sqlTranslator) conditions(toks []sqlToken) {
+	for _, cond := range splitSQLConjuncts(toks) {
+		if len(cond) > 2 && cond[0].is("(") && closingParen(cond, 0) == len(cond)-1 {
+			tr.conditions(cond[1 : len(cond)-1])
+			continue
+		}
+		if slices.ContainsFunc(cond, func(t sqlToken) bool { return t.is("OR") }) {
+			tr.dropFilter(cond, "GAQL conditions can only be combined with AND")
+			continue
+		}
+		text, reason := tr.condition(cond)
+		if reason == "" {
+			if _, err := Parse("SELECT " + tr.from + ".resource_name FROM " + tr.from + " WHERE " + text); err != nil {
+				reason = strings.TrimPrefix(err.Error(), "gaql: ")
+			}
+		}
+		if reason != "" {
+			tr.dropFilter(cond, reason)
+			continue
+		}
+		tr.where = append(tr.where, text)
+	}
+}
+
+// splitSQLConjuncts splits toks at top-level ANDs, keeping the AND of
+// BETWEEN x AND y.
+func splitSQLConjuncts(toks []sqlToken) [][]sqlToken {
+	var conds [][]sqlToken
+	depth, start, between := 0, 0, false
+	for i, tok := range toks {
+		switch {
+		case tok.is("("):
+			depth++
+		case tok.is(")"):
+			depth--
+		case depth > 0:
+		case tok.is("BETWEEN"):
+			between = true
+		case tok.is("AND") && between:
+			between = false
+		case tok.is("AND"):
+			conds = append(conds, toks[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(toks) {
+		conds = append(conds, toks[start:])
+	}
+	return conds
+}
+
+// closingParen returns the index of the parenthesis closing toks[open].
+func closingParen(toks []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(toks); i++ {
+		switch {
+		case toks[i].is("("):
+			depth++
+		case toks[i].is(")"):
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// sqlNegations maps each GAQL operator to its negation, for conditions
+// under a SQL NOT. BETWEEN has none.
+var sqlNegations = map[string]string{
+	"=": "!=", "!=": "=",
+	"<": ">=", ">=": "<",
+	">": "<=", "<=": ">",
+	"IN": "NOT IN", "NOT IN": "IN",
+	"LIKE": "NOT LIKE", "NOT LIKE": "LIKE",
+	"IS NULL": "IS NOT NULL", "IS NOT NULL": "IS NULL",
+	"NOT BETWEEN": "BETWEEN",
+}
+
+// condition translates one comparison, or returns why it cannot. A
+// leading NOT is folded into the operator: NOT status = 'PAUSED' becomes
+// status != 'PAUSED'.
+func (tr *sqlTranslator) condition(toks []sqlToken) (string, string) {
+	const literalsOnly = "GAQL conditions compare a field with literal values"
+	not := false
+	for len(toks) > 1 && toks[0].is("NOT") {
+		not, toks = !not, toks[1:]
+		if len(toks) > 2 && toks[0].is("(") && closingParen(toks, 0) == len(toks)-1 {
+			toks = toks[1 : len(toks)-1]
+		}
+	}
+	if len(splitSQLConjuncts(toks)) > 1 {
+		return "", "GAQL cannot negate conditions combined with AND"
+	}
+	field, i, ok := tr.operand(toks, 0)
+	if !ok {
+		return "", literalsOnly
+	}
+	rest := toks[i:]
+	at := func(words ...string) bool {
+		if len(rest) < len(words) {
+			return false
+		}
+		for j, w := range words {
+			if !rest[j].is(w) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var op, value string
+	switch {
+	case at("IS", "NOT", "NULL") && len(rest) == 3:
+		op = "IS NOT NULL"
+	case at("IS", "NULL") && len(rest) == 2:
+		op = "IS NULL"
+	case at("BETWEEN"), at("NOT", "BETWEEN"):
+		op = "BETWEEN"
+		if rest[0].is("NOT") {
+			op, rest = "NOT BETWEEN", rest[1:]
+		}
+		low, j, ok := sqlLiteral(rest, 1)
+		if !ok || j >= len(rest) || !rest[j].is("AND") {
+			return "", literalsOnly
+		}
+		high, k, ok := sqlLiteral(rest, j+1)
+		if !ok || k != len(rest) {
+			return "", literalsOnly
+		}
+		value = low + " AND " + high
+	case at("IN"), at("NOT", "IN"):
+		op = "IN"
+		if rest[0].is("NOT") {
+			op, rest = "NOT IN", rest[1:]
+		}
+		if len(rest) < 3 || !rest[1].is("(") || closingParen(rest, 1) != len(rest)-1 {
+			return "", literalsOnly
+		}
+		var items []string
+		for _, item := range splitSQLList(rest[2 : len(rest)-1]) {
+			lit, j, ok := sqlLiteral(item, 0)
+			if !ok || j != len(item) {
+				return "", "GAQL lists hold literal values, not subqueries or expressions"
+			}
+			items = append(items, lit)
+		}
+		value = "(" + strings.Join(items, ", ") + ")"
+	case at("LIKE"), at("NOT", "LIKE"):
+		op = "LIKE"
+		if rest[0].is("NOT") {
+			op, rest = "NOT LIKE", rest[1:]
+		}
+		lit, j, ok := sqlLiteral(rest, 1)
+		if !ok || j != len(rest) {
+			return "", literalsOnly
+		}
+		value = lit
+	case len(rest) > 0 && rest[0].kind == sqlSymbol:
+		op = rest[0].value
+		switch op {
+		case "<>":
+			op = "!="
+		case "=", "!=", "<", "<=", ">", ">=":
+		default:
+			return "", literalsOnly
+		}
+		lit, j, ok := sqlLiteral(rest, 1)
+		if !ok || j != len(rest) {
+			return "", literalsOnly
+		}
+		value = lit
+	default:
+		return "", literalsOnly
+	}
+	if not {
+		if op = sqlNegations[op]; op == "" {
+			return "", "GAQL has no NOT BETWEEN"
+		}
+	}
+	if op == "NOT BETWEEN" {
+		return "", "GAQL has no NOT BETWEEN"
+	}
+	if value == "" {
+		return field + " " + op, ""
+	}
+	return field + " " + op + " " + value, ""
+}
+
+// sqlLiteral reads a literal at toks[i] and returns it as GAQL text and
+// the index after it: strings, numbers with an optional sign, TRUE and
+// FALSE, and DATE '2026-01-31', which GAQL writes as a string.
+func sqlLiteral(toks []sqlToken, i int) (string, int, bool) {
+	if i >= len(toks) {
+		return "", i, false
+	}
+	tok := toks[i]
+	switch {
+	case tok.kind == sqlString:
+		return QuoteString(tok.value), i + 1, true
+	case tok.kind == sqlNumber:
+		if _, err := strconv.ParseFloat(tok.value, 64); err == nil {
+			return tok.value, i + 1, true
+		}
+	case (tok.is("-") || tok.is("+")) && i+1 < len(toks) && toks[i+1].kind == sqlNumber:
+		lit, next, ok := sqlLiteral(toks, i+1)
+		return strings.TrimPrefix(tok.value, "+") + lit, next, ok
+	case tok.is("TRUE"), tok.is("FALSE"):
+		return strings.ToUpper(tok.value), i + 1, true
+	case tok.is("DATE") && i+1 < len(toks) && toks[i+1].kind == sqlString:
+		return QuoteString(toks[i+1].value), i + 2, true
+	}
+	return "", i, false
+}
+
+// groupBy checks the grouping against the selection: GAQL groups by the
+// selected attributes and segments, so only unselected columns are lost.
+func (tr *sqlTranslator) groupBy(toks []sqlToken) {
+	for _, item := range splitSQLList(toks) {
+		if len(item) == 1 && item[0].kind == sqlNumber {
+			continue
+		}
+		field, next, ok := tr.operand(item, 0)
+		if !ok || next != len(item) || !slices.Contains(tr.selected, field) {
+			tr.drop(item, "GAQL groups by the selected attributes and segments; select it to group by it")
+		}
+	}
+}
+
+// orderByClause translates the ordering, resolving positions and
+// column aliases.
+func (tr *sqlTranslator) orderByClause(toks []sqlToken) {
+	for _, item := range splitSQLList(toks) {
+		var field string
+		next := 1
+		if item[0].kind == sqlNumber {
+			if n, err := strconv.Atoi(item[0].value); err == nil && n >= 1 && n <= len(tr.items) {
+				field = tr.items[n-1]
+			}
+		} else {
+			var ok bool
+			if field, next, ok = tr.operand(item, 0); !ok {
+				field = ""
+			}
+		}
+		if field == "" {
+			tr.drop(item, "GAQL orders by fields only")
+			continue
+		}
+		dir := ""
+		if next < len(item) && (item[next].is("ASC") || item[next].is("DESC")) {
+			if item[next].is("DESC") {
+				dir = " DESC"
+			}
+			next++
+		}
+		if next < len(item) {
+			tr.drop(item[next:], "GAQL has no NULLS FIRST or NULLS LAST")
+		}
+		tr.orderBy = append(tr.orderBy, field+dir)
+	}
+}
+
+const noOffset = "GAQL has no OFFSET; page through the results instead"
+
+// limitClause translates LIMIT n, and LIMIT offset, n with the offset
+// dropped.
+func (tr *sqlTranslator) limitClause(toks []sqlToken) {
+	if len(toks) == 3 && toks[1].is(",") {
+		tr.drop(toks[:2], noOffset)
+		toks = toks[2:]
+	}
+	if len(toks) == 1 && toks[0].kind == sqlNumber {
+		if n, err := strconv.Atoi(toks[0].value); err == nil && n > 0 {
+			tr.limit = toks[0].value
+			return
+		}
+	}
+	tr.drop(toks, "GAQL's LIMIT takes a positive integer")
+}
@@ -0,0 +1,122 @@
+package gaql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromSQL(t *testing.T) {
+	tests := []struct {
+		name         string
+		sql          string
+		want         string
+		untranslated []string // SQL of each untranslated construct
+	}{
+		{
+			name: "unqualified columns",
+			sql:  "SELECT id, name, clicks FROM campaign WHERE status <> 'REMOVED' LIMIT 10",
+			want: "SELECT campaign.id, campaign.name, metrics.clicks FROM campaign WHERE campaign.status != 'REMOVED' LIMIT 10",
+		},
+		{
+			name: "dates, lists, and ordering",
+			sql: `SELECT c.name, date, SUM(impressions) AS impr
+				FROM campaign c
+				WHERE date BETWEEN DATE '2026-01-01' AND '2026-01-31' AND c.status IN ('ENABLED', 'PAUSED')
+				GROUP BY c.name, date
+				HAVING SUM(clicks) > 10
+				ORDER BY impr DESC, 1;`,
+			want:         "SELECT campaign.name, segments.date, metrics.impressions FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31' AND campaign.status IN ('ENABLED', 'PAUSED') AND metrics.clicks > 10 ORDER BY metrics.impressions DESC, campaign.name",
+			untranslated: []string{"AS impr"},
+		},
+		{
+			name:         "offset",
+			sql:          "SELECT id FROM campaign ORDER BY id LIMIT 10 OFFSET 20",
+			want:         "SELECT campaign.id FROM campaign ORDER BY campaign.id LIMIT 10",
+			untranslated: []string{"OFFSET 20"},
+		},
+		{
+			name:         "or and expressions",
+			sql:          "SELECT id, clicks / impressions, AVG(ctr) FROM campaign WHERE (status = 'ENABLED' OR status = 'PAUSED') AND name NOT LIKE '%test%' AND id = campaign_budget",
+			want:         "SELECT campaign.id, metrics.ctr FROM campaign WHERE campaign.name NOT LIKE '%test%'",
+			untranslated: []string{"clicks / impressions", "AVG(ctr)", "status = 'ENABLED' OR status = 'PAUSED'", "id = campaign_budget"},
+		},
+		{
+			name:         "join",
+			sql:          "SELECT ag.name, c.name FROM ad_group ag JOIN campaign c ON ag.campaign = c.resource_name WHERE c.id = -1 AND ag.name IS NOT NULL",
+			want:         "SELECT ad_group.name, campaign.name FROM ad_group WHERE campaign.id = -1 AND ad_group.name IS NOT NULL",
+			untranslated: []string{"JOIN campaign c ON ag.campaign = c.resource_name"},
+		},
+		{
+			name:         "negation",
+			sql:          "SELECT id FROM campaign WHERE NOT status = 'PAUSED' AND NOT (clicks < 10) AND NOT id IN (1, 2) AND NOT name NOT LIKE 'Brand%' AND NOT bidding_strategy IS NULL AND NOT date BETWEEN '2026-01-01' AND '2026-01-31' AND NOT (status = 'ENABLED' AND id = 3)",
+			want:         "SELECT campaign.id FROM campaign WHERE campaign.status != 'PAUSED' AND metrics.clicks >= 10 AND campaign.id NOT IN (1, 2) AND campaign.name LIKE 'Brand%' AND campaign.bidding_strategy IS NOT NULL",
+			untranslated: []string{"NOT date BETWEEN '2026-01-01' AND '2026-01-31'", "NOT (status = 'ENABLED' AND id = 3)"},
+		},
+		{
+			name:         "escaped quotes and unselected grouping",
+			sql:          "SELECT DISTINCT name FROM campaign WHERE name = 'Brand''s' GROUP BY name, status",
+			want:         `SELECT campaign.name FROM campaign WHERE campaign.name = 'Brand\'s'`,
+			untranslated: []string{"DISTINCT", "status"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromSQL(tt.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Query != tt.want {
+				t.Errorf("got  %s\nwant %s", got.Query, tt.want)
+			}
+			var dropped []string
+			for _, u := range got.Untranslated {
+				dropped = append(dropped, u.SQL)
+			}
+			if strings.Join(dropped, "|") != strings.Join(tt.untranslated, "|") {
+				t.Errorf("untranslated = %q, want %q", dropped, tt.untranslated)
+			}
+		})
+	}
+}
+
+func TestFromSQLDroppedFilter(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT id FROM campaign WHERE NOT status = 'PAUSED' LIMIT 10 OFFSET 20", false},
+		{"SELECT id AS i FROM campaign GROUP BY status", false},
+		{"SELECT id FROM campaign WHERE status = 'ENABLED' OR status = 'PAUSED'", true},
+		{"SELECT id FROM campaign HAVING SUM(clicks) > impressions", true},
+	}
+	for _, tt := range tests {
+		got, err := FromSQL(tt.sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.DroppedFilter() != tt.want {
+			t.Errorf("%s: DroppedFilter() = %v, want %v (untranslated %v)", tt.sql, got.DroppedFilter(), tt.want, got.Untranslated)
+		}
+	}
+}
+
+func TestFromSQLErrors(t *testing.T) {
+	tests := []struct {
+		sql     string
+		wantErr string
+	}{
+		{"SELECT * FROM campaign", "SELECT *"},
+		{"SELECT id", "no FROM clause"},
+		{"SELECT id FROM campaign UNION SELECT id FROM ad_group", "UNION has no GAQL equivalent"},
+		{"SELECT id FROM campaign; SELECT 1", "one statement"},
+		{"SELECT name FROM campaign WHERE name = 'open", "unterminated string"},
+		{"UPDATE campaign SET name = 'x'", "UPDATE has no GAQL equivalent"},
+		{"SELECT 1 + 1 FROM campaign", "no column"},
+	}
+	for _, tt := range tests {
+		_, err := FromSQL(tt.sql)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("FromSQL(%q): expected error containing %q, got %v", tt.sql, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package gaql
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Template is a GAQL query with named placeholders for condition values
+// and the LIMIT:
+//
+//	SELECT campaign.id FROM campaign
+//	WHERE campaign.id = @campaign_id AND campaign.status IN (@statuses)
+//	  AND segments.date DURING @range
+//	LIMIT @limit
+//
+// Values are bound with Go types and written into the query as GAQL
+// literals: strings quoted and escaped, numbers formatted, slices
+// expanded into the list they stand in, so a value can never change the
+// structure of the query. Each value is checked against the catalog type
+// of the field it is compared with. Placeholders inside quoted strings
+// and comments are left alone.
+type Template struct {
+	// Catalog supplies the field types values are checked against. Nil
+	// uses DefaultCatalog.
+	Catalog *Catalog
+
+	text   string
+	holes  []hole
+	values map[string]any
+}
+
+// hole is one occurrence of a placeholder in the template text.
+type hole struct {
+	name       string
+	start, end int // byte offsets of @name in the text
+
+	// Where the placeholder stands: the condition it is a value of, or
+	// the DURING range or the LIMIT.
+	field string
+	op    Operator
+	limit bool
+}
+
+// standInRange is substituted for placeholders after DURING while the
+// template is parsed; any registered keyword would do.
+const standInRange = "LAST_30_DAYS"
+
+var (
+	duringPrefix = regexp.MustCompile(`(?i)\bDURING\s*$`)
+	limitPrefix  = regexp.MustCompile(`(?i)\bLIMIT\s*$`)
+)
+
+// NewTemplate parses a query template. Placeholders are @ followed by a
+// name of letters, digits, and underscores; each stands for a condition
+// value, a list item, a DURING range, or the LIMIT, and may appear more
+// than once.
+func NewTemplate(text string) (*Template, error) {
+	t := &Template{text: text, values: map[string]any{}}
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; ch {
+		case '\'', '"':
+			for i++; i < len(text) && text[i] != ch; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case '#', '-':
+			// Comments run to the end of the line, as in the lexer.
+			if ch == '-' && (i+1 == len(text) || text[i+1] != '-') {
+				continue
+			}
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case '@':
+			end := i + 1
+			for end < len(text) && (text[end] == '_' || isLetter(text[end]) || (end > i+1 && isDigit(text[end]))) {
+				end++
+			}
+			if end == i+1 {
+				line, col := position(text, i)
+				return nil, &ParseError{Message: "expected a parameter name after @", Line: line, Column: col, Offset: i}
+			}
+			t.holes = append(t.holes, hole{name: text[i+1 : end], start: i, end: end})
+			i = end - 1
+		}
+	}
+	if err := t.locate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// locate parses the template with a stand-in for every placeholder and
+// records the condition, range, or LIMIT each one stands in.
+func (t *Template) locate() error {
+	var sb strings.Builder
+	standIns := make([]int, len(t.holes))
+	last := 0
+	for i, h := range t.holes {
+		sb.WriteString(t.text[last:h.start])
+		standIns[i] = sb.Len()
+		switch prefix := t.text[:h.start]; {
+		case duringPrefix.MatchString(prefix):
+			sb.WriteString(standInRange)
+		case limitPrefix.MatchString(prefix):
+			sb.WriteString("1")
+			t.holes[i].limit = true
+		default:
+			sb.WriteString("''")
+		}
+		last = h.end
+	}
+	sb.WriteString(t.text[last:])
+
+	q, err := Parse(sb.String())
+	if err != nil {
+		return err
+	}
+	for i := range t.holes {
+		h := &t.holes[i]
+		if h.limit {
+			continue
+		}
+		for _, c := range q.Where {
+			if c.Span.Start.Offset <= standIns[i] && standIns[i] < c.Span.End.Offset && c.FieldSpan.End.Offset <= standIns[i] {
+				h.field, h.op = c.Field, c.Operator
+				break
+			}
+		}
+		if h.field == "" {
+			return &ValidationError{Message: fmt.Sprintf("placeholder @%s must be a condition value or the LIMIT", h.name)}
+		}
+	}
+	return nil
+}
+
+// position returns the 1-based line and column of a byte offset.
+func position(text string, offset int) (line, col int) {
+	line = 1 + strings.Count(text[:offset], "\n")
+	return line, offset - strings.LastIndex(text[:offset], "\n")
+}
+
+// Params returns the names of the placeholders in order of first
+// appearance.
+func (t *Template) Params() []string {
+	var names []string
+	for _, h := range t.holes {
+		if !slices.Contains(names, h.name) {
+			names = append(names, h.name)
+		}
+	}
+	return names
+}
+
+// Bind sets the value of a placeholder, replacing any earlier one.
+// Accepted values are strings, integers, floats, bools, time.Time for
+// DATE fields, DateRange for DURING, and []string, []int, []int64,
+// []float64, or []any for placeholders in a list such as IN (@ids).
+// The value must suit every use of the placeholder: an integer for an
+// INT64 field, a string naming a value of an ENUM field, and so on.
+func (t *Template) Bind(name string, value any) error {
+	found := false
+	for _, h := range t.holes {
+		if h.name != name {
+			continue
+		}
+		found = true
+		if _, err := t.literal(h, value); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("gaql: template has no placeholder @%s", name)
+	}
+	t.values[name] = value
+	return nil
+}
+
+// BindText binds a value given as text, such as a command-line argument,
+// converting it to the type of the field the placeholder is compared
+// with: "123" binds an integer to an INT64 field and a string to a
+// STRING field. In lists, commas separate the items.
+func (t *Template) BindText(name, text string) error {
+	for _, h := range t.holes {
+		if h.name == name {
+			return t.Bind(name, t.convert(h, text))
+		}
+	}
+	return fmt.Errorf("gaql: template has no placeholder @%s", name)
+}
+
+// convert returns text as the Go type the placeholder expects, or as a
+// string when it does not convert, so Bind reports the mismatch.
+func (t *Template) convert(h hole, text string) any {
+	if h.limit {
+		if n, err := strconv.Atoi(text); err == nil {
+			return n
+		}
+		return text
+	}
+	if h.op == OpDuring {
+		return text
+	}
+	if isListOperator(h.op) {
+		items := strings.Split(text, ",")
+		values := make([]any, len(items))
+		for i, item := range items {
+			values[i] = t.convertScalar(h, strings.TrimSpace(item))
+		}
+		return values
+	}
+	return t.convertScalar(h, text)
+}
+
+func (t *Template) convertScalar(h hole, text string) any {
+	switch t.dataType(h) {
+	case "INT64", "INT32", "UINT64":
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+	case "DOUBLE", "FLOAT":
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	case "BOOLEAN":
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	}
+	return text
+}
+
+// Text returns the template with every placeholder replaced by the
+// literal of its value. Unbound placeholders are an error.
+func (t *Template) Text() (string, error) {
+	var sb strings.Builder
+	last := 0
+	for _, h := range t.holes {
+		value, ok := t.values[h.name]
+		if !ok {
+			return "", fmt.Errorf("gaql: placeholder @%s is not bound", h.name)
+		}
+		lit, err := t.literal(h, value)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(t.text[last:h.start])
+		sb.WriteString(lit)
+		last = h.end
+	}
+	sb.WriteString(t.text[last:])
+	return sb.String(), nil
+}
+
+// Query returns the parsed query with the bound values.
+func (t *Template) Query() (*Query, error) {
+	text, err := t.Text()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(text)
+}
+
+func (t *Template) dataType(h hole) string {
+	c := t.Catalog
+	if c == nil {
+		c = DefaultCatalog()
+	}
+	f, _ := c.Field(h.field)
+	return f.DataType
+}
+
+// literal returns value as GAQL text for one use of a placeholder,
+// checking that it suits that use.
+func (t *Template) literal(h hole, value any) (string, error) {
+	mismatch := func(want string) error {
+		return &ValidationError{Field: h.field, Message: fmt.Sprintf("@%s is bound to %T %v; expected %s", h.name, value, value, want)}
+	}
+	switch {
+	case h.limit:
+		n, ok := asInt(value)
+		if !ok || n <= 0 {
+			return "", &ValidationError{Message: fmt.Sprintf("@%s is bound to %T %v; the LIMIT must be a positive integer", h.name, value, value)}
+		}
+		return strconv.FormatInt(n, 10), nil
+	case h.op == OpDuring:
+		var dr DateRange
+		switch v := value.(type) {
+		case DateRange:
+			if _, ok := LookupDateRange(v.String()); !ok || v == DateRangeCustom {
+				return "", mismatch("a date range keyword such as LAST_30_DAYS")
+			}
+			dr = v
+		case string:
+			var ok bool
+			if dr, ok = LookupDateRange(v); !ok || dr == DateRangeCustom {
+				return "", mismatch("a date range keyword such as LAST_30_DAYS")
+			}
+		default:
+			return "", mismatch("a DateRange or a date range keyword")
+		}
+		return dr.String(), nil
+	}
+
+	items, isList := listItems(value)
+	if !isList {
+		items = []any{value}
+	} else if !isListOperator(h.op) {
+		return "", mismatch("a single value for " + h.op.String())
+	} else if len(items) == 0 {
+		return "", mismatch("at least one list item")
+	}
+	dataType := t.dataType(h)
+	lits := make([]string, len(items))
+	for i, item := range items {
+		lit, ok := t.scalar(h, dataType, item)
+		if !ok {
+			want := "a value of type " + dataType
+			if dataType == "" {
+				want = "a string, number, or bool"
+			}
+			return "", mismatch(want)
+		}
+		lits[i] = lit
+	}
+	return strings.Join(lits, ", "), nil
+}
+
+// scalar returns one value as a literal for a field of dataType, and
+// whether the value suits the field.
+func (t *Template) scalar(h hole, dataType string, value any) (string, bool) {
+	var fits bool
+	if _, ok := asInt(value); ok {
+		fits = slices.Contains([]string{"", "INT64", "INT32", "UINT64", "DOUBLE", "FLOAT"}, dataType)
+	} else {
+		switch v := value.(type) {
+		case float32, float64:
+			fits = dataType == "" || dataType == "DOUBLE" || dataType == "FLOAT"
+		case bool:
+			fits = dataType == "" || dataType == "BOOLEAN"
+		case time.Time:
+			fits = dataType == "" || dataType == "DATE"
+		case string:
+			switch dataType {
+			case "INT64", "INT32", "UINT64", "DOUBLE", "FLOAT", "BOOLEAN":
+			case "DATE":
+				fits = datePattern.MatchString(v)
+			case "ENUM":
+				c := t.Catalog
+				if c == nil {
+					c = DefaultCatalog()
+				}
+				f, _ := c.Field(h.field)
+				fits = len(f.EnumValues) == 0 || slices.Contains(f.EnumValues, v)
+			default:
+				fits = true
+			}
+		}
+	}
+	if !fits {
+		return "", false
+	}
+	lit, err := SafeLiteral(value)
+	return lit, err == nil
+}
+
+// isListOperator reports whether op takes a parenthesized list.
+func isListOperator(op Operator) bool {
+	switch op {
+	case OpIn, OpNotIn, OpContainsAny, OpContainsAll, OpContainsNone:
+		return true
+	}
+	return false
+}
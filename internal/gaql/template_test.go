@@ -0,0 +1,154 @@
+package gaql
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		values   map[string]any
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "integer",
+			template: "SELECT campaign.id FROM campaign WHERE campaign.id = @campaign_id",
+			values:   map[string]any{"campaign_id": int64(123)},
+			want:     "SELECT campaign.id FROM campaign WHERE campaign.id = 123",
+		},
+		{
+			name:     "string is quoted and escaped",
+			template: "SELECT campaign.id FROM campaign WHERE campaign.name = @name",
+			values:   map[string]any{"name": `Brand' OR campaign.id > 0 \`},
+			want:     `SELECT campaign.id FROM campaign WHERE campaign.name = 'Brand\' OR campaign.id > 0 \\'`,
+		},
+		{
+			name:     "list, range, and limit",
+			template: "SELECT campaign.id FROM campaign WHERE campaign.status IN (@statuses) AND segments.date DURING @range LIMIT @n",
+			values:   map[string]any{"statuses": []string{"ENABLED", "PAUSED"}, "range": DateRangeLast7Days, "n": 10},
+			want:     "SELECT campaign.id FROM campaign WHERE campaign.status IN ('ENABLED', 'PAUSED') AND segments.date DURING LAST_7_DAYS LIMIT 10",
+		},
+		{
+			name:     "dates and a repeated placeholder",
+			template: "SELECT campaign.id FROM campaign WHERE segments.date BETWEEN @day AND @day",
+			values:   map[string]any{"day": time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+			want:     "SELECT campaign.id FROM campaign WHERE segments.date BETWEEN '2026-03-01' AND '2026-03-01'",
+		},
+		{
+			name:     "placeholder in a string is text",
+			template: "SELECT campaign.id FROM campaign WHERE campaign.name LIKE '%@home%' AND campaign.id = @id",
+			values:   map[string]any{"id": 7},
+			want:     "SELECT campaign.id FROM campaign WHERE campaign.name LIKE '%@home%' AND campaign.id = 7",
+		},
+		{
+			name:     "placeholder in a comment is text",
+			template: "-- filter by @campaign_id\nSELECT campaign.id FROM campaign # not @status\nWHERE campaign.id = @id",
+			values:   map[string]any{"id": 7},
+			want:     "-- filter by @campaign_id\nSELECT campaign.id FROM campaign # not @status\nWHERE campaign.id = 7",
+		},
+		{
+			name:     "string for an integer field",
+			template: "SELECT campaign.id FROM campaign WHERE campaign.id = @id",
+			values:   map[string]any{"id": "1 OR 1=1"},
+			wantErr:  "expected a value of type INT64",
+		},
+		{
+			name:     "unknown enum value",
+			template: "SELECT campaign.id FROM campaign WHERE campaign.status = @status",
+			values:   map[string]any{"status": "RUNNING"},
+			wantErr:  "expected a value of type ENUM",
+		},
+		{
+			name:     "list for a single value",
+			template: "SELECT campaign.id FROM campaign WHERE campaign.id = @id",
+			values:   map[string]any{"id": []int{1, 2}},
+			wantErr:  "expected a single value for =",
+		},
+		{
+			name:     "custom date range",
+			template: "SELECT campaign.id FROM campaign WHERE segments.date DURING @range",
+			values:   map[string]any{"range": DateRangeCustom},
+			wantErr:  "expected a date range keyword",
+		},
+		{
+			name:     "custom date range keyword",
+			template: "SELECT campaign.id FROM campaign WHERE segments.date DURING @range",
+			values:   map[string]any{"range": "CUSTOM"},
+			wantErr:  "expected a date range keyword",
+		},
+		{
+			name:     "unbound",
+			template: "SELECT campaign.id FROM campaign WHERE campaign.id = @id",
+			wantErr:  "placeholder @id is not bound",
+		},
+		{
+			name:     "placeholder outside a value",
+			template: "SELECT @field FROM campaign",
+			wantErr:  "expected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := NewTemplate(tt.template)
+			if err == nil {
+				for name, v := range tt.values {
+					if err = tmpl.Bind(name, v); err != nil {
+						break
+					}
+				}
+			}
+			var got string
+			if err == nil {
+				got, err = tmpl.Text()
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+			if _, err := Parse(got); err != nil {
+				t.Fatalf("result does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestTemplateBindText(t *testing.T) {
+	tmpl, err := NewTemplate("SELECT campaign.id FROM campaign WHERE campaign.id IN (@ids) AND metrics.ctr > @ctr AND campaign.name = @name LIMIT @n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(tmpl.Params(), ","); got != "ids,ctr,name,n" {
+		t.Errorf("Params() = %s", got)
+	}
+	for name, text := range map[string]string{"ids": "1, 2", "ctr": "0.05", "name": "42", "n": "5"} {
+		if err := tmpl.BindText(name, text); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	got, err := tmpl.Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT campaign.id FROM campaign WHERE campaign.id IN (1, 2) AND metrics.ctr > 0.05 AND campaign.name = '42' LIMIT 5"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if err := tmpl.BindText("ids", "1, two"); err == nil {
+		t.Error("expected an error for a non-integer ID")
+	}
+	if err := tmpl.BindText("missing", "1"); err == nil {
+		t.Error("expected an error for an unknown placeholder")
+	}
+}
@@ -50,6 +50,9 @@ const (
 	TokenLParen     // (
 	TokenRParen     // )
 	TokenDot        // .
+
+	// Trivia, kept in Lexer.Comments rather than returned by Tokenize
+	TokenComment // -- comment or # comment
 )
 
 // Token represents a lexical token.
@@ -58,6 +61,13 @@ type Token struct {
 	Value   string
 	Line    int
 	Column  int
+	Offset  int // byte offset of the first character
+	End     Pos // position just past the last character
+}
+
+// Pos returns the position of the token's first character.
+func (t Token) Pos() Pos {
+	return Pos{Line: t.Line, Column: t.Column, Offset: t.Offset}
 }
 
 func (t TokenType) String() string {
@@ -138,6 +148,8 @@ func (t TokenType) String() string {
 		return ")"
 	case TokenDot:
 		return "."
+	case TokenComment:
+		return "COMMENT"
 	default:
 		return "UNKNOWN"
 	}
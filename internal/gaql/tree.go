@@ -0,0 +1,136 @@
+package gaql
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TreeNode is a node of the syntax tree view of a query, for printing the
+// parse of a query as a tree or an s-expression. Kind names the node,
+// such as "select" or "field"; Value holds the text of a leaf, such as a
+// field name or a literal as written in GAQL.
+type TreeNode struct {
+	Kind     string
+	Value    string
+	Span     Span // zero when not parsed
+	Children []*TreeNode
+}
+
+// Tree returns the syntax tree of q:
+//
+//	query
+//	  select: field...
+//	  from
+//	  where: condition (field, operator, value)...
+//	  order_by: ordering (field, direction)...
+//	  limit
+//	  parameters: parameter...
+//
+// Clauses the query lacks are left out.
+func (q *Query) Tree() *TreeNode {
+	root := &TreeNode{Kind: "query"}
+	add := func(parent *TreeNode, n *TreeNode) *TreeNode {
+		parent.Children = append(parent.Children, n)
+		return n
+	}
+
+	sel := add(root, &TreeNode{Kind: "select", Span: q.SelectSpan})
+	for _, f := range q.Select {
+		add(sel, &TreeNode{Kind: "field", Value: f.Name, Span: f.Span})
+	}
+	add(root, &TreeNode{Kind: "from", Value: q.From, Span: q.FromSpan})
+	if len(q.Where) > 0 {
+		where := add(root, &TreeNode{Kind: "where", Span: q.WhereSpan})
+		for _, c := range q.Where {
+			cond := add(where, &TreeNode{Kind: "condition", Span: c.Span})
+			add(cond, &TreeNode{Kind: "field", Value: c.Field, Span: c.FieldSpan})
+			add(cond, &TreeNode{Kind: "operator", Value: c.Operator.String()})
+			if c.Value.Type != ValueNull {
+				add(cond, &TreeNode{Kind: "value", Value: c.Value.String()})
+			}
+		}
+	}
+	if len(q.OrderBy) > 0 {
+		order := add(root, &TreeNode{Kind: "order_by", Span: q.OrderBySpan})
+		for _, o := range q.OrderBy {
+			ordering := add(order, &TreeNode{Kind: "ordering", Span: o.Span})
+			add(ordering, &TreeNode{Kind: "field", Value: o.Field})
+			add(ordering, &TreeNode{Kind: "direction", Value: o.Direction.String()})
+		}
+	}
+	if q.Limit > 0 {
+		add(root, &TreeNode{Kind: "limit", Value: strconv.Itoa(q.Limit), Span: q.LimitSpan})
+	}
+	if len(q.Parameters) > 0 {
+		params := add(root, &TreeNode{Kind: "parameters", Span: q.ParametersSpan})
+		names := make([]string, 0, len(q.Parameters))
+		for name := range q.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			add(params, &TreeNode{Kind: "parameter", Value: name + " = " + q.Parameters[name]})
+		}
+	}
+	return root
+}
+
+// WriteTree writes n and its descendants to w, one per line, with
+// box-drawing branches. With positions, each parsed node ends with its
+// span, such as 1:8-1:19.
+func (n *TreeNode) WriteTree(w io.Writer, positions bool) error {
+	return n.writeTree(w, "", "", positions)
+}
+
+func (n *TreeNode) writeTree(w io.Writer, prefix, childPrefix string, positions bool) error {
+	line := n.Kind
+	if n.Value != "" {
+		line += " " + n.Value
+	}
+	if positions && n.Span.IsValid() {
+		line += "  " + n.Span.String()
+	}
+	if _, err := fmt.Fprintln(w, prefix+line); err != nil {
+		return err
+	}
+	for i, c := range n.Children {
+		var err error
+		if i == len(n.Children)-1 {
+			err = c.writeTree(w, childPrefix+"└── ", childPrefix+"    ", positions)
+		} else {
+			err = c.writeTree(w, childPrefix+"├── ", childPrefix+"│   ", positions)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SExpr returns n as an s-expression, such as
+// (query (select (field "campaign.id")) (from "campaign")). Values are
+// quoted strings; with positions, parsed nodes carry their span as
+// :span "1:8-1:19".
+func (n *TreeNode) SExpr(positions bool) string {
+	var sb strings.Builder
+	n.sexpr(&sb, positions)
+	return sb.String()
+}
+
+func (n *TreeNode) sexpr(sb *strings.Builder, positions bool) {
+	sb.WriteString("(" + n.Kind)
+	if n.Value != "" {
+		sb.WriteString(" " + strconv.Quote(n.Value))
+	}
+	if positions && n.Span.IsValid() {
+		sb.WriteString(" :span " + strconv.Quote(n.Span.String()))
+	}
+	for _, c := range n.Children {
+		sb.WriteByte(' ')
+		c.sexpr(sb, positions)
+	}
+	sb.WriteByte(')')
+}
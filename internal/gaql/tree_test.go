@@ -0,0 +1,91 @@
+package gaql
+
+import (
+	"strings"
+	"testing"
+)
+
+const treeQuery = "SELECT campaign.id, metrics.clicks\nFROM campaign\n" +
+	"WHERE campaign.status = 'ENABLED' AND campaign.end_date IS NULL\n" +
+	"ORDER BY metrics.clicks DESC LIMIT 10"
+
+func TestWriteTree(t *testing.T) {
+	q, err := Parse(treeQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		positions bool
+		want      string
+	}{
+		{false, `query
+├── select
+│   ├── field campaign.id
+│   └── field metrics.clicks
+├── from campaign
+├── where
+│   ├── condition
+│   │   ├── field campaign.status
+│   │   ├── operator =
+│   │   └── value 'ENABLED'
+│   └── condition
+│       ├── field campaign.end_date
+│       └── operator IS NULL
+├── order_by
+│   └── ordering
+│       ├── field metrics.clicks
+│       └── direction DESC
+└── limit 10
+`},
+		{true, `query
+├── select  1:1-1:35
+│   ├── field campaign.id  1:8-1:19
+│   └── field metrics.clicks  1:21-1:35
+├── from campaign  2:1-2:14
+├── where  3:1-3:64
+│   ├── condition  3:7-3:34
+│   │   ├── field campaign.status  3:7-3:22
+│   │   ├── operator =
+│   │   └── value 'ENABLED'
+│   └── condition  3:39-3:64
+│       ├── field campaign.end_date  3:39-3:56
+│       └── operator IS NULL
+├── order_by  4:1-4:29
+│   └── ordering  4:10-4:29
+│       ├── field metrics.clicks
+│       └── direction DESC
+└── limit 10  4:30-4:38
+`},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		if err := q.Tree().WriteTree(&sb, tt.positions); err != nil {
+			t.Fatal(err)
+		}
+		if sb.String() != tt.want {
+			t.Errorf("WriteTree(positions=%v):\n%s\nwant:\n%s", tt.positions, sb.String(), tt.want)
+		}
+	}
+}
+
+func TestSExpr(t *testing.T) {
+	q, err := Parse("SELECT campaign.id FROM campaign WHERE segments.date DURING LAST_7_DAYS PARAMETERS include_drafts = true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `(query (select (field "campaign.id")) (from "campaign") ` +
+		`(where (condition (field "segments.date") (operator "DURING") (value "LAST_7_DAYS"))) ` +
+		`(parameters (parameter "include_drafts = true")))`
+	if got := q.Tree().SExpr(false); got != want {
+		t.Errorf("SExpr:\n got %s\nwant %s", got, want)
+	}
+	if got := q.Tree().SExpr(true); !strings.HasPrefix(got, `(query (select :span "1:1-1:19" (field "campaign.id" :span "1:8-1:19"))`) {
+		t.Errorf("SExpr with positions: %s", got)
+	}
+
+	// Built queries have no spans to print.
+	built := Select("campaign.id").From("campaign").Query()
+	if got := built.Tree().SExpr(true); got != `(query (select (field "campaign.id")) (from "campaign"))` {
+		t.Errorf("SExpr of a built query: %s", got)
+	}
+}
@@ -1,8 +1,13 @@
 package gaql
 
 import (
+	"cmp"
+	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 )
 
 // KnownResources lists the common Google Ads API resources.
@@ -42,6 +47,59 @@ var SingleDayResources = map[string]bool{
 	"click_view": true,
 }
 
+// SingleRowResources are resources with a single row per account, so
+// no entities go missing when rows with zero metrics are omitted.
+var SingleRowResources = map[string]bool{
+	"customer": true,
+}
+
+// change_event queries must bound change_event.change_date_time to a
+// range of at most ChangeEventMaxDays and set a LIMIT of at most
+// ChangeEventMaxLimit.
+const (
+	ChangeEventMaxDays  = 30
+	ChangeEventMaxLimit = 10000
+)
+
+// ConversionActionSegments are the segments that split rows by
+// conversion action.
+var ConversionActionSegments = map[string]bool{
+	"segments.conversion_action":          true,
+	"segments.conversion_action_category": true,
+	"segments.conversion_action_name":     true,
+}
+
+// ConversionActionMetrics are the metrics that may be selected with a
+// ConversionActionSegments segment. Other metrics, such as clicks or
+// cost, do not belong to a conversion action and are rejected.
+var ConversionActionMetrics = map[string]bool{
+	"metrics.all_conversions":                              true,
+	"metrics.all_conversions_by_conversion_date":           true,
+	"metrics.all_conversions_value":                        true,
+	"metrics.all_conversions_value_by_conversion_date":     true,
+	"metrics.conversions":                                  true,
+	"metrics.conversions_by_conversion_date":               true,
+	"metrics.conversions_value":                            true,
+	"metrics.conversions_value_by_conversion_date":         true,
+	"metrics.value_per_all_conversions":                    true,
+	"metrics.value_per_all_conversions_by_conversion_date": true,
+	"metrics.value_per_conversion":                         true,
+	"metrics.value_per_conversions_by_conversion_date":     true,
+}
+
+// HighVolumeResources are resources that commonly return very large
+// result sets (one row per click, search term, placement, etc.).
+var HighVolumeResources = map[string]bool{
+	"click_view":                     true,
+	"detail_placement_view":          true,
+	"geographic_view":                true,
+	"keyword_view":                   true,
+	"performance_max_placement_view": true,
+	"search_term_view":               true,
+	"shopping_performance_view":      true,
+	"user_location_view":             true,
+}
+
 // FieldCategories maps field prefixes to their categories.
 var FieldCategories = map[string]string{
 	"metrics":  "METRIC",
@@ -53,24 +111,120 @@ var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
 
 // Validator performs semantic validation on parsed GAQL queries.
 type Validator struct {
-	// AllowUnknownResources permits resources not in KnownResources.
-	// Useful for newer API resources not yet in the list.
+	// AllowUnknownResources permits resources neither in KnownResources
+	// nor in the catalog. Useful for newer API resources not yet in the
+	// list.
 	AllowUnknownResources bool
 
 	// RequireMetricDateContext enforces that metrics require date segments.
 	RequireMetricDateContext bool
+
+	// APIVersion is the Google Ads API version queries are checked
+	// against, e.g. "v23". DURING keywords not registered for the version
+	// are rejected. Empty accepts every known keyword.
+	APIVersion string
+
+	// AutoAddDateContext makes Prepare add "segments.date DURING
+	// DefaultDateRange" to queries whose metrics lack date context
+	// instead of rejecting them. The addition is reported as a
+	// diagnostic.
+	AutoAddDateContext bool
+
+	// DefaultDateRange is the range injected by AutoAddDateContext.
+	DefaultDateRange DateRange
+
+	// StrictParameters rejects PARAMETERS keys not in KnownParameters.
+	StrictParameters bool
+
+	// WarnZeroMetricRows makes Check warn when selecting metrics will
+	// silently drop rows for entities with zero impressions.
+	WarnZeroMetricRows bool
+
+	// FlagSuspiciousLiterals makes Check warn about string values that
+	// hold quotes, backslashes, line breaks, or control characters. In
+	// machine-generated queries they usually mean input was pasted into
+	// the query text instead of quoted with QuoteString or a Template.
+	FlagSuspiciousLiterals bool
+
+	// Identifiers sets how strictly field paths are checked. The zero
+	// value, IdentifierSyntax, rejects malformed paths and warns about
+	// unknown namespaces; IdentifierNamespace rejects those too.
+	Identifiers IdentifierCheck
+
+	// Catalog supplies the data types annotated onto WHERE conditions
+	// and the resources field paths may start with. Nil uses
+	// DefaultCatalog.
+	Catalog *Catalog
+
+	// KnownFields rejects resources and fields the catalog does not
+	// know. It needs a complete catalog, such as one synced from
+	// GoogleAdsFieldService; DefaultCatalog covers common fields only.
+	KnownFields bool
+
+	// Access restricts the resources and fields queries may read and
+	// caps their LIMIT and date range. Prepare applies the caps and
+	// reports them as diagnostics. Nil allows everything.
+	Access *AccessPolicy
 }
 
 // NewValidator creates a new validator with default settings.
+//
+// A Validator is safe for concurrent use as long as its fields are not
+// changed while it is; configure it first, or give each goroutine its
+// own copy. The query checked is never modified; Prepare returns a
+// rewritten copy.
 func NewValidator() *Validator {
 	return &Validator{
 		AllowUnknownResources:    true, // Default permissive for forward compat
 		RequireMetricDateContext: true,
+		APIVersion:               DefaultAPIVersion,
+		DefaultDateRange:         DateRangeLast30Days,
+		WarnZeroMetricRows:       true,
 	}
 }
 
-// Validate performs semantic validation on a parsed query.
+// Check validates q like Validate and also returns non-fatal diagnostics
+// about query shapes that are valid but likely to surprise the user,
+// including the rewrites Prepare would make.
+func (v *Validator) Check(q *Query) ([]Diagnostic, error) {
+	_, diags, err := v.Prepare(q)
+	return diags, err
+}
+
+// Prepare validates q like Check and returns a copy rewritten for
+// sending: with the date context of AutoAddDateContext added, the caps
+// of Access applied, and each WHERE condition annotated with the data
+// type of its field. q itself is not modified.
+func (v *Validator) Prepare(q *Query) (*Query, []Diagnostic, error) {
+	q = q.Clone()
+	var diags []Diagnostic
+	if err := v.validate(q, &diags); err != nil {
+		return nil, nil, err
+	}
+
+	if v.WarnZeroMetricRows {
+		if d := zeroMetricRows(q); d != nil {
+			diags = append(diags, *d)
+		}
+	}
+	if v.FlagSuspiciousLiterals {
+		diags = append(diags, suspiciousLiterals(q)...)
+	}
+	if v.Identifiers == IdentifierSyntax {
+		diags = append(diags, unknownNamespaces(q, v.catalog())...)
+	}
+	return q, diags, nil
+}
+
+// Validate performs semantic validation on a parsed query. q is not
+// modified.
 func (v *Validator) Validate(q *Query) error {
+	return v.validate(q.Clone(), nil)
+}
+
+// validate runs every check, rewriting q as Prepare describes; callers
+// pass a copy. The rewrites are recorded in diags when it is non-nil.
+func (v *Validator) validate(q *Query, diags *[]Diagnostic) error {
 	if err := v.validateSelect(q); err != nil {
 		return err
 	}
@@ -80,25 +234,61 @@ func (v *Validator) Validate(q *Query) error {
 	if err := v.validateWhere(q); err != nil {
 		return err
 	}
+	if err := v.validateOrderBy(q); err != nil {
+		return err
+	}
 	if err := v.validateLimit(q); err != nil {
 		return err
 	}
+	if err := v.validateKnownFields(q); err != nil {
+		return err
+	}
+	if err := v.validateParameters(q); err != nil {
+		return err
+	}
 	if err := v.validateSingleDayResource(q); err != nil {
 		return err
 	}
-	if err := v.validateMetricDateContext(q); err != nil {
+	if err := v.validateChangeEvent(q); err != nil {
 		return err
 	}
+	if err := v.validateConversionSegments(q); err != nil {
+		return err
+	}
+	if err := v.validateMetricDateContext(q, diags); err != nil {
+		return err
+	}
+	if err := v.validateAccess(q, diags); err != nil {
+		return err
+	}
+	v.annotateTypes(q)
 	return nil
 }
 
+// annotateTypes records the catalog data type of each WHERE condition's
+// field, including conditions added by AutoAddDateContext.
+func (v *Validator) annotateTypes(q *Query) {
+	c := v.catalog()
+	for i := range q.Where {
+		info, _ := c.Field(q.Where[i].Field)
+		q.Where[i].DataType = info.DataType
+	}
+}
+
+func (v *Validator) catalog() *Catalog {
+	if v.Catalog == nil {
+		return DefaultCatalog()
+	}
+	return v.Catalog
+}
+
 func (v *Validator) validateSelect(q *Query) error {
 	if len(q.Select) == 0 {
-		return &ValidationError{Message: "SELECT must contain at least one field"}
+		return &ValidationError{Message: "SELECT must contain at least one field", Span: q.SelectSpan}
 	}
 
 	for _, f := range q.Select {
-		if err := v.validateFieldName(f.Name); err != nil {
+		if err := v.validateFieldName(q, f.Name, f.Span); err != nil {
 			return err
 		}
 	}
@@ -108,14 +298,23 @@ func (v *Validator) validateSelect(q *Query) error {
 
 func (v *Validator) validateFrom(q *Query) error {
 	if q.From == "" {
-		return &ValidationError{Message: "FROM clause is required"}
+		return &ValidationError{Message: "FROM clause is required", Span: q.FromSpan}
+	}
+	if v.Identifiers != IdentifierAny {
+		if why := checkIdentifier(q.From); why != "" || strings.Contains(q.From, ".") {
+			return &ValidationError{Message: "invalid resource name " + q.From + ": " + cmp.Or(why, "resource names have no dots"), Field: "FROM", Span: q.FromSpan}
+		}
 	}
 
 	if !v.AllowUnknownResources {
+		if f, ok := v.catalog().Field(q.From); ok && f.Category == "RESOURCE" {
+			return nil
+		}
 		if _, ok := KnownResources[q.From]; !ok {
 			return &ValidationError{
 				Message: "unknown resource: " + q.From,
 				Field:   "FROM",
+				Span:    q.FromSpan,
 			}
 		}
 	}
@@ -125,7 +324,7 @@ func (v *Validator) validateFrom(q *Query) error {
 
 func (v *Validator) validateWhere(q *Query) error {
 	for _, cond := range q.Where {
-		if err := v.validateFieldName(cond.Field); err != nil {
+		if err := v.validateFieldName(q, cond.Field, cond.FieldSpan); err != nil {
 			return err
 		}
 
@@ -135,8 +334,20 @@ func (v *Validator) validateWhere(q *Query) error {
 				return &ValidationError{
 					Message: "DURING requires a date range keyword",
 					Field:   cond.Field,
+					Span:    cond.Span,
 				}
 			}
+			if v.APIVersion != "" && !DateRangeSupported(v.APIVersion, cond.Value.DateRange.String()) {
+				return &ValidationError{
+					Message: "date range " + cond.Value.DateRange.String() + " is not supported in API " + v.APIVersion,
+					Field:   cond.Field,
+					Span:    cond.Span,
+				}
+			}
+		}
+
+		if err := validatePattern(cond); err != nil {
+			return err
 		}
 
 		// Validate BETWEEN dates
@@ -145,6 +356,7 @@ func (v *Validator) validateWhere(q *Query) error {
 				return &ValidationError{
 					Message: "BETWEEN requires two values",
 					Field:   cond.Field,
+					Span:    cond.Span,
 				}
 			}
 			for _, d := range cond.Value.List {
@@ -152,6 +364,7 @@ func (v *Validator) validateWhere(q *Query) error {
 					return &ValidationError{
 						Message: "invalid date format (expected YYYY-MM-DD): " + d,
 						Field:   cond.Field,
+						Span:    cond.Span,
 					}
 				}
 			}
@@ -161,9 +374,109 @@ func (v *Validator) validateWhere(q *Query) error {
 	return nil
 }
 
+// validateOrderBy checks that each ORDER BY field is listed once, is
+// sortable according to the catalog, and, for segments, is selected:
+// rows are split only by the segments selected, so ordering by another
+// has nothing to order.
+func (v *Validator) validateOrderBy(q *Query) error {
+	c := v.catalog()
+	seen := make(map[string]bool, len(q.OrderBy))
+	for _, o := range q.OrderBy {
+		if err := v.validateFieldName(q, o.Field, o.Span); err != nil {
+			return err
+		}
+		if seen[o.Field] {
+			return &ValidationError{Message: "ordered by twice; keep the first, which takes precedence", Field: o.Field, Span: o.Span}
+		}
+		seen[o.Field] = true
+		if info, ok := c.Field(o.Field); ok && !info.Sortable {
+			msg := "not sortable"
+			switch {
+			case info.Repeated:
+				msg += ": repeated fields have no order"
+			case info.Category == "RESOURCE" || info.DataType == "RESOURCE_NAME":
+				msg += ": order by an ID or name instead of a resource name"
+			}
+			return &ValidationError{Message: msg, Field: o.Field, Span: o.Span}
+		}
+		if strings.HasPrefix(o.Field, "segments.") && !slices.ContainsFunc(q.Select, func(f Field) bool { return f.Name == o.Field }) {
+			return &ValidationError{Message: "segments in ORDER BY must also be in SELECT", Field: o.Field, Span: o.Span}
+		}
+	}
+	return nil
+}
+
+// validateKnownFields checks the resource and fields of q against the
+// catalog when KnownFields is set.
+func (v *Validator) validateKnownFields(q *Query) error {
+	if !v.KnownFields {
+		return nil
+	}
+	c := v.catalog()
+	if f, ok := c.Field(q.From); !ok || f.Category != "RESOURCE" {
+		return &ValidationError{Message: "unknown resource: " + q.From + " (not in the " + c.Version + " catalog)", Field: "FROM", Span: q.FromSpan}
+	}
+	check := func(name string, span Span) error {
+		if _, ok := c.Field(name); ok {
+			return nil
+		}
+		msg := "unknown field " + name + " (not in the " + c.Version + " catalog)"
+		if s := similarField(c, name); s != "" {
+			msg += "; did you mean " + s + "?"
+		}
+		return &ValidationError{Message: msg, Field: name, Span: span}
+	}
+	for _, f := range q.Select {
+		if err := check(f.Name, f.Span); err != nil {
+			return err
+		}
+	}
+	for _, cond := range q.Where {
+		if err := check(cond.Field, cond.FieldSpan); err != nil {
+			return err
+		}
+	}
+	for _, o := range q.OrderBy {
+		if err := check(o.Field, o.Span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (v *Validator) validateLimit(q *Query) error {
 	if q.Limit < 0 {
-		return &ValidationError{Message: "LIMIT must be non-negative"}
+		return &ValidationError{Message: "LIMIT must be non-negative", Span: q.LimitSpan}
+	}
+	return nil
+}
+
+func (v *Validator) validateParameters(q *Query) error {
+	names := make([]string, 0, len(q.Parameters))
+	for name := range q.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := q.Parameters[name]
+		if !KnownParameters[name] {
+			if v.StrictParameters {
+				return &ValidationError{
+					Message: "unknown parameter: " + name,
+					Field:   "PARAMETERS",
+					Span:    q.ParametersSpan,
+				}
+			}
+			continue
+		}
+		if _, ok := parseBool(value); !ok {
+			return &ValidationError{
+				Message: "parameter " + name + " requires a boolean value (true or false), got " + value,
+				Field:   "PARAMETERS",
+				Span:    q.ParametersSpan,
+			}
+		}
 	}
 	return nil
 }
@@ -184,6 +497,7 @@ func (v *Validator) validateSingleDayResource(q *Query) error {
 				return &ValidationError{
 					Message: "click_view requires single-day date range (TODAY or YESTERDAY)",
 					Field:   "segments.date",
+					Span:    cond.Span,
 				}
 			}
 			if cond.Operator == OpEq {
@@ -197,6 +511,7 @@ func (v *Validator) validateSingleDayResource(q *Query) error {
 				return &ValidationError{
 					Message: "click_view requires single-day date range",
 					Field:   "segments.date",
+					Span:    cond.Span,
 				}
 			}
 		}
@@ -205,23 +520,173 @@ func (v *Validator) validateSingleDayResource(q *Query) error {
 	return &ValidationError{
 		Message: "click_view requires segments.date in WHERE clause with single-day range",
 		Field:   "FROM",
+		Span:    q.FromSpan,
 	}
 }
 
-func (v *Validator) validateMetricDateContext(q *Query) error {
-	if !v.RequireMetricDateContext {
+// changeDateTimeLayouts are the forms change_event.change_date_time
+// literals may take.
+var changeDateTimeLayouts = []string{"2006-01-02 15:04:05.999999", dateLayout}
+
+func parseChangeDateTime(s string) (time.Time, bool) {
+	for _, layout := range changeDateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (v *Validator) validateChangeEvent(q *Query) error {
+	if q.From != "change_event" {
 		return nil
 	}
+	if q.Limit == 0 {
+		return &ValidationError{
+			Message: fmt.Sprintf("change_event requires a LIMIT of at most %d", ChangeEventMaxLimit),
+			Field:   "FROM",
+			Span:    q.FromSpan,
+		}
+	}
+	if q.Limit > ChangeEventMaxLimit {
+		return &ValidationError{
+			Message: fmt.Sprintf("change_event LIMIT must be at most %d, got %d", ChangeEventMaxLimit, q.Limit),
+			Field:   "LIMIT",
+			Span:    q.LimitSpan,
+		}
+	}
+
+	// change_event requires a bounded change_date_time range of at most
+	// ChangeEventMaxDays.
+	const field = "change_event.change_date_time"
+	var lower, upper time.Time
+	for _, cond := range q.Where {
+		if cond.Field != field {
+			continue
+		}
+		var values []string
+		switch cond.Operator {
+		case OpDuring:
+			if n := cond.Value.DateRange.Days(); n > ChangeEventMaxDays {
+				return &ValidationError{
+					Message: fmt.Sprintf("change_event date range %s is longer than %d days", cond.Value.DateRange, ChangeEventMaxDays),
+					Field:   field,
+					Span:    cond.Span,
+				}
+			}
+			return nil
+		case OpBetween:
+			values = cond.Value.List
+		case OpEq, OpGt, OpGte, OpLt, OpLte:
+			values = []string{cond.Value.Str}
+		default:
+			continue
+		}
+		times := make([]time.Time, len(values))
+		for i, s := range values {
+			t, ok := parseChangeDateTime(s)
+			if !ok {
+				return &ValidationError{
+					Message: fmt.Sprintf("invalid %s value %q (expected YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)", field, s),
+					Field:   field,
+					Span:    cond.Span,
+				}
+			}
+			times[i] = t
+		}
+		switch cond.Operator {
+		case OpEq:
+			lower, upper = times[0], times[0]
+		case OpBetween:
+			if len(times) == 2 {
+				lower, upper = times[0], times[1]
+			}
+		case OpGt, OpGte:
+			lower = times[0]
+		default:
+			upper = times[0]
+		}
+	}
+	if lower.IsZero() || upper.IsZero() {
+		return &ValidationError{
+			Message: fmt.Sprintf("change_event requires %s bounded on both sides (DURING, BETWEEN, or >= and <=) within %d days", field, ChangeEventMaxDays),
+			Field:   "FROM",
+			Span:    q.FromSpan,
+		}
+	}
+	if upper.Before(lower) {
+		return &ValidationError{Message: "change_event date range ends before it starts", Field: field, Span: q.WhereSpan}
+	}
+	if upper.Sub(lower) > ChangeEventMaxDays*24*time.Hour {
+		return &ValidationError{
+			Message: fmt.Sprintf("change_event date range is longer than %d days", ChangeEventMaxDays),
+			Field:   field,
+			Span:    q.WhereSpan,
+		}
+	}
+	return nil
+}
 
-	hasMetrics := false
+// validateConversionSegments rejects metrics that cannot be split by
+// the conversion action segment a query selects or filters on.
+func (v *Validator) validateConversionSegments(q *Query) error {
+	segment := ""
+	for _, f := range q.Select {
+		if ConversionActionSegments[f.Name] {
+			segment = f.Name
+			break
+		}
+	}
+	for _, cond := range q.Where {
+		if segment == "" && ConversionActionSegments[cond.Field] {
+			segment = cond.Field
+		}
+	}
+	if segment == "" {
+		return nil
+	}
+	check := func(name string, span Span) error {
+		if !strings.HasPrefix(name, "metrics.") || ConversionActionMetrics[name] {
+			return nil
+		}
+		return &ValidationError{
+			Message: fmt.Sprintf("%s cannot be combined with %s: segmenting by conversion action splits only conversion metrics (metrics.conversions, metrics.conversions_value, metrics.all_conversions, ...)", name, segment),
+			Field:   name,
+			Span:    span,
+		}
+	}
 	for _, f := range q.Select {
+		if err := check(f.Name, f.Span); err != nil {
+			return err
+		}
+	}
+	for _, cond := range q.Where {
+		if err := check(cond.Field, cond.FieldSpan); err != nil {
+			return err
+		}
+	}
+	for _, o := range q.OrderBy {
+		if err := check(o.Field, o.Span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateMetricDateContext(q *Query, diags *[]Diagnostic) error {
+	if !v.RequireMetricDateContext {
+		return nil
+	}
+
+	var metric *Field
+	for i, f := range q.Select {
 		if strings.HasPrefix(f.Name, "metrics.") {
-			hasMetrics = true
+			metric = &q.Select[i]
 			break
 		}
 	}
 
-	if !hasMetrics {
+	if metric == nil {
 		return nil
 	}
 
@@ -244,33 +709,69 @@ func (v *Validator) validateMetricDateContext(q *Query) error {
 		}
 	}
 
+	if !hasDateContext && v.AutoAddDateContext {
+		cond := Condition{
+			Field:    "segments.date",
+			Operator: OpDuring,
+			Value:    Value{Type: ValueDateRange, DateRange: v.DefaultDateRange},
+		}
+		q.Where = append(q.Where, cond)
+		if diags != nil {
+			*diags = append(*diags, Diagnostic{
+				Severity: SeverityInfo,
+				Code:     "date-context-added",
+				Message:  "added " + cond.String() + " because metrics require date context",
+				Field:    metric.Name,
+				Span:     metric.Span,
+			})
+		}
+		return nil
+	}
+
 	if !hasDateContext {
 		return &ValidationError{
 			Message: "metrics require date context (segments.date in SELECT or WHERE)",
+			Field:   metric.Name,
+			Span:    metric.Span,
 		}
 	}
 
 	return nil
 }
 
-func (v *Validator) validateFieldName(name string) error {
+func (v *Validator) validateFieldName(q *Query, name string, span Span) error {
 	if name == "" {
-		return &ValidationError{Message: "field name cannot be empty"}
+		return &ValidationError{Message: "field name cannot be empty", Span: span}
+	}
+	if v.Identifiers == IdentifierAny {
+		return nil
 	}
 
-	// Field names should contain at least one dot for qualified names
-	// e.g., campaign.id, metrics.clicks
-	// Single-part names are also valid (e.g., for resources)
-
+	// Single-segment names are valid too: a resource selects its
+	// resource name, e.g. SELECT campaign FROM campaign.
+	if why := checkIdentifier(name); why != "" {
+		return &ValidationError{Message: "invalid field path: " + why, Field: name, Span: span}
+	}
+	if v.Identifiers == IdentifierNamespace {
+		if ok, suggestion := knownNamespace(name, q.From, v.catalog()); !ok {
+			ns, _, _ := strings.Cut(name, ".")
+			msg := "unknown namespace " + ns + ": expected metrics, segments, or a known resource"
+			if suggestion != "" {
+				msg += " (did you mean " + suggestion + strings.TrimPrefix(name, ns) + "?)"
+			}
+			return &ValidationError{Message: msg, Field: name, Span: span}
+		}
+	}
 	return nil
 }
 
 func isDateRangeKeyword(s string) bool {
-	_, ok := DateRangeKeywords[strings.ToUpper(s)]
+	_, ok := LookupDateRange(s)
 	return ok
 }
 
-// ValidateQuery parses and validates a GAQL query string.
+// ValidateQuery parses and validates a GAQL query string, returning
+// the query as Prepare does.
 func ValidateQuery(input string) (*Query, error) {
 	q, err := Parse(input)
 	if err != nil {
@@ -278,7 +779,8 @@ func ValidateQuery(input string) (*Query, error) {
 	}
 
 	v := NewValidator()
-	if err := v.Validate(q); err != nil {
+	q, _, err = v.Prepare(q)
+	if err != nil {
 		return nil, err
 	}
 
@@ -1,7 +1,12 @@
 package gaql
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -52,6 +57,78 @@ func TestValidateQuery(t *testing.T) {
 			name:  "valid between dates",
 			input: "SELECT campaign.id FROM campaign WHERE segments.date BETWEEN '2026-01-01' AND '2026-01-31'",
 		},
+		{
+			name:  "conversion action segment with conversion metrics",
+			input: "SELECT segments.conversion_action, metrics.conversions, metrics.value_per_conversion FROM customer WHERE segments.date DURING LAST_30_DAYS ORDER BY metrics.conversions DESC",
+		},
+		{
+			name:    "conversion action segment with clicks",
+			input:   "SELECT segments.conversion_action_name, metrics.conversions, metrics.clicks FROM campaign WHERE segments.date DURING LAST_30_DAYS",
+			wantErr: true,
+			errMsg:  "metrics.clicks cannot be combined with segments.conversion_action_name",
+		},
+		{
+			name:    "conversion action filter with cost ordering",
+			input:   "SELECT campaign.id, metrics.conversions FROM campaign WHERE segments.conversion_action_category = 'PURCHASE' AND segments.date DURING LAST_7_DAYS ORDER BY metrics.cost_micros DESC",
+			wantErr: true,
+			errMsg:  "metrics.cost_micros cannot be combined with segments.conversion_action_category",
+		},
+		{
+			name:  "change_event with bounded range",
+			input: "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time >= '2026-01-01' AND change_event.change_date_time <= '2026-01-14 23:59:59' LIMIT 100",
+		},
+		{
+			name:  "change_event with DURING",
+			input: "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time DURING LAST_7_DAYS LIMIT 100",
+		},
+		{
+			name:    "change_event without LIMIT",
+			input:   "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time DURING LAST_7_DAYS",
+			wantErr: true,
+			errMsg:  "change_event requires a LIMIT",
+		},
+		{
+			name:    "change_event LIMIT too large",
+			input:   "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time DURING LAST_7_DAYS LIMIT 20000",
+			wantErr: true,
+			errMsg:  "LIMIT must be at most 10000",
+		},
+		{
+			name:    "change_event without date range",
+			input:   "SELECT change_event.change_date_time FROM change_event LIMIT 100",
+			wantErr: true,
+			errMsg:  "bounded on both sides",
+		},
+		{
+			name:    "change_event with open range",
+			input:   "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time >= '2026-01-01' LIMIT 100",
+			wantErr: true,
+			errMsg:  "bounded on both sides",
+		},
+		{
+			name:    "change_event range too long",
+			input:   "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time BETWEEN '2026-01-01' AND '2026-03-01' LIMIT 100",
+			wantErr: true,
+			errMsg:  "longer than 30 days",
+		},
+		{
+			name:    "change_event DURING too long",
+			input:   "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time DURING THIS_MONTH LIMIT 100",
+			wantErr: true,
+			errMsg:  "longer than 30 days",
+		},
+		{
+			name:    "change_event reversed range",
+			input:   "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time BETWEEN '2026-01-14' AND '2026-01-01' LIMIT 100",
+			wantErr: true,
+			errMsg:  "ends before it starts",
+		},
+		{
+			name:    "change_event invalid date-time",
+			input:   "SELECT change_event.change_date_time FROM change_event WHERE change_event.change_date_time >= 'yesterday' AND change_event.change_date_time <= '2026-01-14' LIMIT 100",
+			wantErr: true,
+			errMsg:  "invalid change_event.change_date_time value",
+		},
 	}
 
 	for _, tt := range tests {
@@ -160,6 +237,130 @@ func TestValidateResource(t *testing.T) {
 	}
 }
 
+func TestValidateKnownFields(t *testing.T) {
+	catalog := NewCatalog("v99", []FieldInfo{
+		{Name: "campaign", Category: "RESOURCE"},
+		{Name: "campaign.id", Category: "ATTRIBUTE", Sortable: true},
+		{Name: "campaign.name", Category: "ATTRIBUTE", Sortable: true},
+		{Name: "metrics.clicks", Category: "METRIC", Sortable: true},
+		{Name: "segments.date", Category: "SEGMENT", Sortable: true},
+	})
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"known", "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS ORDER BY campaign.name", ""},
+		{"resource", "SELECT campaign.id FROM campaign_widget", "unknown resource: campaign_widget"},
+		{"select", "SELECT campaign.nmae FROM campaign", "unknown field campaign.nmae (not in the v99 catalog); did you mean campaign.name?"},
+		{"where", "SELECT campaign.id FROM campaign WHERE campaign.budget_id = 1", "unknown field campaign.budget_id (not in the v99 catalog)"},
+		{"order by", "SELECT campaign.id FROM campaign ORDER BY metrics.impressions", "unknown field metrics.impressions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := NewValidator()
+			v.Catalog = catalog
+			v.KnownFields = true
+			err = v.Validate(q)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOrderBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantErr  string
+		wantSpan string // source text of the offending ordering
+	}{
+		{"multi-key", "SELECT campaign.id, segments.date, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS ORDER BY segments.date DESC, metrics.clicks DESC, campaign.id", "", ""},
+		{"unselected attribute", "SELECT campaign.id FROM campaign ORDER BY campaign.name", "", ""},
+		{"twice", "SELECT campaign.id FROM campaign ORDER BY campaign.name, campaign.id DESC, campaign.name DESC", "campaign.name: ordered by twice", "campaign.name DESC"},
+		{"repeated", "SELECT campaign.id FROM campaign ORDER BY campaign.labels", "campaign.labels: not sortable: repeated fields have no order", "campaign.labels"},
+		{"resource name", "SELECT ad_group.id FROM ad_group ORDER BY ad_group.campaign ASC", "not sortable: order by an ID or name instead of a resource name", "ad_group.campaign ASC"},
+		{"unselected segment", "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS ORDER BY segments.device", "segments.device: segments in ORDER BY must also be in SELECT", "segments.device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = NewValidator().Validate(q)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if got := tt.input[ve.Span.Start.Offset:ve.Span.End.Offset]; got != tt.wantSpan {
+				t.Errorf("span covers %q, want %q", got, tt.wantSpan)
+			}
+		})
+	}
+}
+
+func TestValidateIdentifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    IdentifierCheck
+		fields   []string
+		wantErr  string
+		wantDiag string // Hint of the unknown-namespace diagnostic
+	}{
+		{name: "valid", fields: []string{"campaign.id", "ad_group.name", "campaign.network_settings.target_search_network"}},
+		{name: "upper case", fields: []string{"Campaign.id"}, wantErr: `segment "Campaign" is not lowercase snake_case`},
+		{name: "leading digit", fields: []string{"campaign.1st"}, wantErr: `segment "1st" starts with a digit`},
+		{name: "empty segment", fields: []string{"campaign..id"}, wantErr: "empty segment"},
+		{name: "leading underscore", fields: []string{"campaign._id"}, wantErr: "not lowercase snake_case"},
+		{name: "typo warns by default", fields: []string{"metric.clicks"}, wantDiag: "did you mean metrics.clicks?"},
+		{name: "typo rejected", check: IdentifierNamespace, fields: []string{"metric.clicks"}, wantErr: "unknown namespace metric: expected metrics, segments, or a known resource (did you mean metrics.clicks?)"},
+		{name: "FROM resource is known", check: IdentifierNamespace, fields: []string{"campaign.id"}},
+		{name: "anything goes", check: IdentifierAny, fields: []string{"Metric.Clicks"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator()
+			v.RequireMetricDateContext = false
+			v.WarnZeroMetricRows = false
+			v.Identifiers = tt.check
+			diags, err := v.Check(Select(tt.fields...).From("campaign").Query())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var hints []string
+			for _, d := range diags {
+				if d.Code == "unknown-namespace" {
+					hints = append(hints, d.Hint)
+				}
+			}
+			if got := strings.Join(hints, "; "); got != tt.wantDiag {
+				t.Errorf("unknown-namespace hints = %q, want %q", got, tt.wantDiag)
+			}
+		})
+	}
+}
+
 func TestParseAndValidate(t *testing.T) {
 	// Integration test for common GAQL patterns from the documentation
 	queries := []string{
@@ -222,3 +423,307 @@ func TestParseAndValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateParameters(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		strict  bool
+		wantErr string
+	}{
+		{
+			name:  "known boolean parameters",
+			input: "SELECT campaign.id FROM campaign PARAMETERS include_drafts = true, omit_unselected_resource_names = FALSE",
+		},
+		{
+			name:    "non-boolean value",
+			input:   "SELECT campaign.id FROM campaign PARAMETERS include_drafts = yes",
+			wantErr: "requires a boolean value",
+		},
+		{
+			name:  "unknown key is allowed by default",
+			input: "SELECT campaign.id FROM campaign PARAMETERS future_option = 1",
+		},
+		{
+			name:    "unknown key is rejected in strict mode",
+			input:   "SELECT campaign.id FROM campaign PARAMETERS future_option = 1",
+			strict:  true,
+			wantErr: "unknown parameter: future_option",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			v := NewValidator()
+			v.StrictParameters = tt.strict
+			err = v.Validate(q)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParameterAccessors(t *testing.T) {
+	q, err := Parse("SELECT campaign.id FROM campaign PARAMETERS include_drafts = TRUE")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !q.IncludeDrafts() {
+		t.Error("expected IncludeDrafts to be true")
+	}
+	if q.OmitUnselectedResourceNames() {
+		t.Error("expected OmitUnselectedResourceNames to default to false")
+	}
+
+	q.SetParameter(ParamOmitUnselectedResourceNames, true)
+	if !q.OmitUnselectedResourceNames() {
+		t.Error("expected SetParameter to take effect")
+	}
+}
+
+func TestAutoAddDateContext(t *testing.T) {
+	q, err := Parse("SELECT campaign.id, metrics.clicks FROM campaign WHERE campaign.status = 'ENABLED'")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	v := NewValidator()
+	v.WarnZeroMetricRows = false
+	if _, err := v.Check(q); err == nil {
+		t.Fatal("expected date context error without AutoAddDateContext")
+	}
+
+	v.AutoAddDateContext = true
+	v.DefaultDateRange = DateRangeLast7Days
+	before := q.String()
+	p, diags, err := v.Prepare(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diags) != 1 || diags[0].Code != "date-context-added" || diags[0].Severity != SeverityInfo {
+		t.Fatalf("expected one date-context-added diagnostic, got %+v", diags)
+	}
+
+	want := "SELECT campaign.id, metrics.clicks FROM campaign WHERE campaign.status = 'ENABLED' AND segments.date DURING LAST_7_DAYS"
+	if got := p.String(); got != want {
+		t.Errorf("rewritten query:\n got: %s\nwant: %s", got, want)
+	}
+	if q.String() != before {
+		t.Errorf("Prepare modified its input: %s", q)
+	}
+	if _, err := v.Check(q); err != nil || q.String() != before {
+		t.Errorf("Check modified its input: %s, %v", q, err)
+	}
+
+	// Re-checking the rewritten query must not inject a second condition.
+	p, diags, err = v.Prepare(p)
+	if err != nil || len(diags) != 0 || len(p.Where) != 2 {
+		t.Errorf("expected idempotent check, got diags=%+v err=%v where=%d", diags, err, len(p.Where))
+	}
+}
+
+// restoreDateRanges puts back the date range registry as it is now when
+// t ends, so keywords a test registers do not leak into later tests.
+func restoreDateRanges(t *testing.T) {
+	dateRangeMu.RLock()
+	keywords, names, days := maps.Clone(dateRangeKeywords), maps.Clone(dateRangeNames), maps.Clone(dateRangeDays)
+	versions := make(map[string]map[string]bool, len(dateRangeVersions))
+	for v, set := range dateRangeVersions {
+		versions[v] = maps.Clone(set)
+	}
+	next := nextDateRange
+	dateRangeMu.RUnlock()
+
+	t.Cleanup(func() {
+		dateRangeMu.Lock()
+		defer dateRangeMu.Unlock()
+		dateRangeKeywords, dateRangeNames, dateRangeDays = keywords, names, days
+		dateRangeVersions, nextDateRange = versions, next
+	})
+}
+
+func TestDateRangeAPIVersion(t *testing.T) {
+	restoreDateRanges(t)
+	if err := LoadDateRangeKeywords(strings.NewReader(`{"v99": {"LAST_30_DAYS": 30, "LAST_90_DAYS": 90}}`)); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	q, err := Parse("SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_90_DAYS")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	dr := q.Where[0].Value.DateRange
+	if dr.String() != "LAST_90_DAYS" || dr.Days() != 90 {
+		t.Errorf("registered range: got %s (%d days)", dr, dr.Days())
+	}
+
+	tests := []struct {
+		version string
+		wantErr bool
+	}{
+		{version: "v99"},
+		{version: DefaultAPIVersion, wantErr: true},
+		{version: "v100"}, // unregistered versions accept every keyword
+		{version: ""},
+	}
+	for _, tt := range tests {
+		v := NewValidator()
+		v.APIVersion = tt.version
+		err := v.Validate(q)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("APIVersion %q: wantErr=%v, got %v", tt.version, tt.wantErr, err)
+		}
+	}
+
+	// v99 adds LAST_90_DAYS to the keywords of the newest version before it.
+	if kws, ok := DateRangeKeywordsFor("v99"); !ok || len(kws) != 13 || !slices.Contains(kws, "LAST_7_DAYS") || !slices.Contains(kws, "LAST_90_DAYS") {
+		t.Errorf("DateRangeKeywordsFor(v99) = %v, %v", kws, ok)
+	}
+	if _, ok := DateRangeKeywordsFor(DefaultAPIVersion); !ok {
+		t.Errorf("embedded keywords missing for %s", DefaultAPIVersion)
+	}
+}
+
+func TestDateRangeChanges(t *testing.T) {
+	restoreDateRanges(t)
+	latest, _ := DateRangeKeywordsFor(DefaultAPIVersion)
+	// The embedded versions share the oldest one's keywords.
+	if oldest, _ := DateRangeKeywordsFor("v21"); len(oldest) != 12 || !slices.Equal(oldest, latest) {
+		t.Errorf("v21 keywords = %v, %s keywords = %v", oldest, DefaultAPIVersion, latest)
+	}
+
+	// Versions apply in numeric order, each starting from the newest
+	// version before it.
+	err := LoadDateRangeKeywords(strings.NewReader(`{
+		"v100": {"today": null},
+		"v98": {"LAST_90_DAYS": 90},
+		"v99": {"LAST_BUSINESS_WEEK": null}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	without := func(keywords []string, drop ...string) []string {
+		return slices.DeleteFunc(slices.Clone(keywords), func(k string) bool { return slices.Contains(drop, k) })
+	}
+	v98 := append(slices.Clone(latest), "LAST_90_DAYS")
+	slices.Sort(v98)
+	for version, want := range map[string][]string{
+		"v98":  v98,
+		"v99":  without(v98, "LAST_BUSINESS_WEEK"),
+		"v100": without(v98, "LAST_BUSINESS_WEEK", "TODAY"),
+	} {
+		if got, _ := DateRangeKeywordsFor(version); !slices.Equal(got, want) {
+			t.Errorf("%s keywords = %v, want %v", version, got, want)
+		}
+	}
+	if !DateRangeSupported("v98", "LAST_7_DAYS") || DateRangeSupported("v100", "TODAY") {
+		t.Error("v98 rejects LAST_7_DAYS or v100 accepts TODAY")
+	}
+
+	// A registered version starts from its own keywords.
+	if err := LoadDateRangeKeywords(strings.NewReader(`{"v99": {"TODAY": null}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := DateRangeKeywordsFor("v99"); !slices.Equal(got, without(v98, "LAST_BUSINESS_WEEK", "TODAY")) {
+		t.Errorf("v99 keywords after removing TODAY = %v", got)
+	}
+}
+
+func TestAnnotateTypes(t *testing.T) {
+	q, err := Parse("SELECT campaign.id, metrics.clicks FROM campaign WHERE campaign.status = 'ENABLED' AND metrics.clicks > 10 AND campaign.name LIKE '%brand%' AND campaign.unknown_field = 1")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	v := NewValidator()
+	v.AutoAddDateContext = true
+	p, _, err := v.Prepare(q)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	want := []string{"ENUM", "INT64", "STRING", "", "DATE"}
+	if len(p.Where) != len(want) {
+		t.Fatalf("expected %d conditions, got %d", len(want), len(p.Where))
+	}
+	for i, c := range p.Where {
+		if c.DataType != want[i] {
+			t.Errorf("%s: DataType = %q, want %q", c.Field, c.DataType, want[i])
+		}
+	}
+	if q.Where[0].DataType != "" {
+		t.Errorf("Prepare annotated its input")
+	}
+
+	custom := NewCatalog("v0", []FieldInfo{{Name: "campaign.unknown_field", DataType: "DOUBLE"}})
+	v.Catalog = custom
+	q, _, _ = v.Prepare(p)
+	if got := q.Where[3].DataType; got != "DOUBLE" {
+		t.Errorf("custom catalog DataType = %q, want DOUBLE", got)
+	}
+	if got := q.Where[0].DataType; got != "" {
+		t.Errorf("fields missing from the catalog should lose their annotation, got %q", got)
+	}
+}
+
+// TestConcurrentUse shares one Validator and the default catalog among
+// goroutines that parse, check, and format queries while date range
+// keywords are registered; run with -race.
+func TestConcurrentUse(t *testing.T) {
+	restoreDateRanges(t)
+	v := NewValidator()
+	v.AutoAddDateContext = true
+	queries := []string{
+		"SELECT campaign.id, metrics.clicks FROM campaign",
+		"SELECT ad_group.id FROM ad_group WHERE ad_group.status = 'ENABLED' LIMIT 10",
+		"SELECT campaign.id, segments.device FROM campaign WHERE segments.date DURING LAST_7_DAYS",
+	}
+	want := make([]string, len(queries))
+	for i, input := range queries {
+		q, err := Parse(input)
+		if err == nil {
+			_, err = v.Check(q)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		want[i] = q.String()
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			RegisterDateRange("v99", fmt.Sprintf("ADTAP_TEST_RANGE_%d", g), g+1)
+			for i := 0; i < 50; i++ {
+				n := (g + i) % len(queries)
+				q, err := Parse(queries[n])
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := v.Check(q); err != nil {
+					t.Error(err)
+					return
+				}
+				if got := q.String(); got != want[n] {
+					t.Errorf("got %q, want %q", got, want[n])
+				}
+				EstimateCost(q)
+				DefaultCatalog().Field("campaign.status")
+			}
+		}(g)
+	}
+	wg.Wait()
+}
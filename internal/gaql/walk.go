@@ -0,0 +1,130 @@
+package gaql
+
+import "fmt"
+
+// Node is an element of a query visited by Walk: *Query, *Field,
+// *Condition, or *Ordering.
+type Node interface {
+	gaqlNode()
+}
+
+func (*Query) gaqlNode()     {}
+func (*Field) gaqlNode()     {}
+func (*Condition) gaqlNode() {}
+func (*Ordering) gaqlNode()  {}
+
+// Walk calls fn for q, then for each SELECT field, WHERE condition, and
+// ORDER BY item in source order. Returning false for the query skips its
+// children. Nodes are passed by pointer, so fn may edit them in place;
+// adding or removing clauses is done on the *Query itself.
+func Walk(q *Query, fn func(Node) bool) {
+	if q == nil || !fn(q) {
+		return
+	}
+	for i := range q.Select {
+		fn(&q.Select[i])
+	}
+	for i := range q.Where {
+		fn(&q.Where[i])
+	}
+	for i := range q.OrderBy {
+		fn(&q.OrderBy[i])
+	}
+}
+
+// Clone returns a deep copy of q.
+func (q *Query) Clone() *Query {
+	c := *q
+	c.Select = append([]Field(nil), q.Select...)
+	c.Where = nil
+	for _, cond := range q.Where {
+		cond.Value.List = append([]string(nil), cond.Value.List...)
+		c.Where = append(c.Where, cond)
+	}
+	c.OrderBy = append([]Ordering(nil), q.OrderBy...)
+	if q.Parameters != nil {
+		c.Parameters = make(map[string]string, len(q.Parameters))
+		for k, v := range q.Parameters {
+			c.Parameters[k] = v
+		}
+	}
+	return &c
+}
+
+// RewriteFunc edits a query in place. Returning an error aborts the
+// rewrite.
+type RewriteFunc func(*Query) error
+
+// Rewrite applies fns in order to a copy of q and returns the copy; q
+// itself is never modified, even when a rewrite fails.
+func Rewrite(q *Query, fns ...RewriteFunc) (*Query, error) {
+	out := q.Clone()
+	for _, fn := range fns {
+		if err := fn(out); err != nil {
+			return nil, fmt.Errorf("gaql: rewrite: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// AddCondition appends c to the WHERE clause unless an identical
+// condition is already present.
+func AddCondition(c Condition) RewriteFunc {
+	return func(q *Query) error {
+		for _, existing := range q.Where {
+			if existing.String() == c.String() {
+				return nil
+			}
+		}
+		q.Where = append(q.Where, c)
+		return nil
+	}
+}
+
+// RemoveConditions drops every WHERE condition for which match is true.
+func RemoveConditions(match func(Condition) bool) RewriteFunc {
+	return func(q *Query) error {
+		kept := q.Where[:0]
+		for _, c := range q.Where {
+			if !match(c) {
+				kept = append(kept, c)
+			}
+		}
+		q.Where = kept
+		return nil
+	}
+}
+
+// RenameField renames every reference to a field: in SELECT, WHERE, and
+// ORDER BY alike.
+func RenameField(from, to string) RewriteFunc {
+	return func(q *Query) error {
+		Walk(q, func(n Node) bool {
+			switch n := n.(type) {
+			case *Field:
+				if n.Name == from {
+					n.Name = to
+				}
+			case *Condition:
+				if n.Field == from {
+					n.Field = to
+				}
+			case *Ordering:
+				if n.Field == from {
+					n.Field = to
+				}
+			}
+			return true
+		})
+		return nil
+	}
+}
+
+// StripLimit removes the LIMIT clause.
+func StripLimit() RewriteFunc {
+	return func(q *Query) error {
+		q.Limit = 0
+		q.LimitSpan = Span{}
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package gaql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	q, err := Parse("SELECT campaign.id, metrics.clicks FROM campaign WHERE campaign.status = 'ENABLED' ORDER BY metrics.clicks DESC")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var visited []string
+	Walk(q, func(n Node) bool {
+		switch n := n.(type) {
+		case *Query:
+			visited = append(visited, "query")
+		case *Field:
+			visited = append(visited, "field:"+n.Name)
+		case *Condition:
+			visited = append(visited, "cond:"+n.Field)
+		case *Ordering:
+			visited = append(visited, "order:"+n.Field)
+		}
+		return true
+	})
+	want := "query,field:campaign.id,field:metrics.clicks,cond:campaign.status,order:metrics.clicks"
+	if got := strings.Join(visited, ","); got != want {
+		t.Errorf("visit order:\n got: %s\nwant: %s", got, want)
+	}
+
+	count := 0
+	Walk(q, func(Node) bool { count++; return false })
+	if count != 1 {
+		t.Errorf("returning false should skip children, visited %d nodes", count)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	notRemoved := Condition{Field: "campaign.status", Operator: OpNeq, Value: Value{Type: ValueString, Str: "REMOVED"}}
+
+	tests := []struct {
+		name  string
+		input string
+		fns   []RewriteFunc
+		want  string
+	}{
+		{
+			name:  "add condition",
+			input: "SELECT campaign.id FROM campaign",
+			fns:   []RewriteFunc{AddCondition(notRemoved)},
+			want:  "SELECT campaign.id FROM campaign WHERE campaign.status != 'REMOVED'",
+		},
+		{
+			name:  "add condition is idempotent",
+			input: "SELECT campaign.id FROM campaign WHERE campaign.status != 'REMOVED'",
+			fns:   []RewriteFunc{AddCondition(notRemoved)},
+			want:  "SELECT campaign.id FROM campaign WHERE campaign.status != 'REMOVED'",
+		},
+		{
+			name:  "rename field everywhere",
+			input: "SELECT metrics.cost_micros FROM campaign WHERE metrics.cost_micros > 0 ORDER BY metrics.cost_micros DESC",
+			fns:   []RewriteFunc{RenameField("metrics.cost_micros", "metrics.clicks")},
+			want:  "SELECT metrics.clicks FROM campaign WHERE metrics.clicks > 0 ORDER BY metrics.clicks DESC",
+		},
+		{
+			name:  "strip limit",
+			input: "SELECT campaign.id FROM campaign LIMIT 10",
+			fns:   []RewriteFunc{StripLimit()},
+			want:  "SELECT campaign.id FROM campaign",
+		},
+		{
+			name:  "remove conditions",
+			input: "SELECT campaign.id FROM campaign WHERE segments.date DURING LAST_7_DAYS AND campaign.status = 'ENABLED'",
+			fns: []RewriteFunc{RemoveConditions(func(c Condition) bool {
+				return c.Field == "segments.date"
+			})},
+			want: "SELECT campaign.id FROM campaign WHERE campaign.status = 'ENABLED'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			before := q.String()
+			got, err := Rewrite(q, tt.fns...)
+			if err != nil {
+				t.Fatalf("rewrite: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got:  %s\nwant: %s", got, tt.want)
+			}
+			if q.String() != before {
+				t.Errorf("original query modified: %s", q)
+			}
+		})
+	}
+}
+
+func TestRewriteError(t *testing.T) {
+	q, _ := Parse("SELECT campaign.id FROM campaign LIMIT 10")
+	boom := errors.New("boom")
+	_, err := Rewrite(q, StripLimit(), func(*Query) error { return boom })
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected wrapped error, got %v", err)
+	}
+	if q.Limit != 10 {
+		t.Errorf("failed rewrite modified the original query")
+	}
+}
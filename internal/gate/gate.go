@@ -0,0 +1,134 @@
+// Package gate decides whether a GAQL query is expensive enough to
+// require confirmation before it is sent to the Google Ads API.
+//
+// A Policy inspects a parsed query and returns the reasons it considers
+// the query expensive. Callers decide what to do with those reasons:
+// the CLI prompts on a terminal, refuses in pipelines, and skips the
+// gate entirely when --yes is given.
+package gate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Reason describes why a query was considered expensive.
+type Reason struct {
+	Code    string
+	Message string
+}
+
+// Policy configures which query shapes require confirmation.
+type Policy struct {
+	// RequireLimit flags queries without a LIMIT clause.
+	RequireLimit bool
+
+	// MaxDays flags queries whose segments.date range spans more than
+	// MaxDays days, or that have no date bound at all while selecting
+	// metrics. Zero disables the check.
+	MaxDays int
+
+	// MaxMultiplier flags queries whose segments are estimated to
+	// multiply each row of the resource more than this many times, such
+	// as segments.hour × segments.device over a month. Zero disables
+	// the check.
+	MaxMultiplier int
+
+	// HighVolume flags queries against these resources.
+	// Nil means gaql.HighVolumeResources.
+	HighVolume map[string]bool
+}
+
+// DefaultPolicy returns the policy used by the CLI.
+func DefaultPolicy() Policy {
+	return Policy{
+		RequireLimit:  true,
+		MaxDays:       90,
+		MaxMultiplier: gaql.ExpensiveMultiplier,
+	}
+}
+
+// Check returns the reasons q requires confirmation under p.
+// An empty result means the query may run without asking.
+func (p Policy) Check(q *gaql.Query) []Reason {
+	var reasons []Reason
+
+	if p.RequireLimit && q.Limit == 0 {
+		reasons = append(reasons, Reason{
+			Code:    "no-limit",
+			Message: "query has no LIMIT clause",
+		})
+	}
+
+	if p.MaxDays > 0 {
+		days, bounded := q.DateSpanDays()
+		switch {
+		case bounded && days > p.MaxDays:
+			reasons = append(reasons, Reason{
+				Code:    "date-span",
+				Message: fmt.Sprintf("date range spans %d days (policy maximum %d)", days, p.MaxDays),
+			})
+		case !bounded && selectsMetrics(q):
+			reasons = append(reasons, Reason{
+				Code:    "date-span",
+				Message: "metrics are selected without a bounded segments.date range",
+			})
+		}
+	}
+
+	if p.MaxMultiplier > 0 {
+		if c := gaql.EstimateCost(q); c.Multiplier > p.MaxMultiplier {
+			reasons = append(reasons, Reason{
+				Code:    "segment-explosion",
+				Message: fmt.Sprintf("segments multiply each %s row by about %d (policy maximum %d)", q.From, c.Multiplier, p.MaxMultiplier),
+			})
+		}
+	}
+
+	highVolume := p.HighVolume
+	if highVolume == nil {
+		highVolume = gaql.HighVolumeResources
+	}
+	if highVolume[q.From] {
+		reasons = append(reasons, Reason{
+			Code:    "high-volume",
+			Message: q.From + " is a high-volume resource",
+		})
+	}
+
+	return reasons
+}
+
+// Confirm writes the reasons to out and reads a yes/no answer from in.
+// Anything other than "y" or "yes" (case-insensitive) declines.
+func Confirm(in io.Reader, out io.Writer, reasons []Reason) (bool, error) {
+	fmt.Fprintln(out, "This query may be expensive:")
+	for _, r := range reasons {
+		fmt.Fprintf(out, "  - %s\n", r.Message)
+	}
+	fmt.Fprint(out, "Run it anyway? [y/N] ")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func selectsMetrics(q *gaql.Query) bool {
+	for _, f := range q.Select {
+		if strings.HasPrefix(f.Name, "metrics.") {
+			return true
+		}
+	}
+	return false
+}
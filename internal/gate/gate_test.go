@@ -0,0 +1,91 @@
+package gate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "bounded query",
+			input: "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS LIMIT 10",
+		},
+		{
+			name:  "missing limit",
+			input: "SELECT campaign.id FROM campaign",
+			want:  []string{"no-limit"},
+		},
+		{
+			name:  "long between range",
+			input: "SELECT campaign.id FROM campaign WHERE segments.date BETWEEN '2025-01-01' AND '2025-12-31' LIMIT 10",
+			want:  []string{"date-span"},
+		},
+		{
+			name:  "metrics without date bound",
+			input: "SELECT campaign.id, metrics.clicks, segments.date FROM campaign LIMIT 10",
+			want:  []string{"date-span"},
+		},
+		{
+			name:  "high volume resource",
+			input: "SELECT search_term_view.search_term FROM search_term_view WHERE segments.date DURING LAST_7_DAYS",
+			want:  []string{"no-limit", "high-volume"},
+		},
+		{
+			name:  "hourly by device",
+			input: "SELECT campaign.id, segments.hour, segments.device, segments.date FROM campaign WHERE segments.date DURING LAST_30_DAYS LIMIT 10",
+			want:  []string{"segment-explosion"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := gaql.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			reasons := DefaultPolicy().Check(q)
+			var got []string
+			for _, r := range reasons {
+				got = append(got, r.Code)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected reasons %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		ok, err := Confirm(strings.NewReader(tt.answer), &out, []Reason{{Code: "no-limit", Message: "query has no LIMIT clause"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ok != tt.want {
+			t.Errorf("answer %q: expected %v, got %v", tt.answer, tt.want, ok)
+		}
+		if !strings.Contains(out.String(), "no LIMIT") {
+			t.Errorf("expected prompt to list reasons, got %q", out.String())
+		}
+	}
+}
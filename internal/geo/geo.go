@@ -0,0 +1,256 @@
+// Package geo resolves Google Ads geo target and language constants to
+// readable names.
+//
+// Results reference locations and languages by criterion ID, either as
+// resource names ("geoTargetConstants/2840") or as bare IDs
+// (geographic_view.country_criterion_id). A Table maps those IDs to names
+// without a round trip to the API. The default table embeds every country
+// and the common languages; the full geo target list, which Google
+// publishes as a CSV download, can be loaded on top of it, and targets
+// the table lacks can be fetched from geo_target_constant with Fetch.
+// Resolve goes the other way, from a location name to its targets.
+//
+// # Basic Usage
+//
+//	t := geo.Default()
+//	name, ok := t.ResolveConstant("geoTargetConstants/2840") // "United States"
+//
+//	if err := t.LoadFile("geotargets-2026-09-01.csv"); err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	matches, err := geo.Resolve(ctx, client, "Boston, MA", "US")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(matches[0].ID) // 1018127
+package geo
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Resource name prefixes of the constants a Table resolves.
+const (
+	GeoTargetPrefix = "geoTargetConstants/"
+	LanguagePrefix  = "languageConstants/"
+)
+
+// Target is a geo target constant.
+type Target struct {
+	ID            int64
+	Name          string
+	CanonicalName string // e.g. "Boston,Massachusetts,United States"
+	ParentID      int64
+	CountryCode   string
+	Type          string // Country, State, City, ...
+	Status        string
+}
+
+// Language is a language constant.
+type Language struct {
+	ID   int64
+	Name string
+	Code string
+}
+
+// Table is an in-memory set of geo target and language constants. It is
+// safe for concurrent use.
+type Table struct {
+	mu        sync.RWMutex
+	targets   map[int64]Target
+	languages map[int64]Language
+}
+
+// NewTable returns an empty table.
+func NewTable() *Table {
+	return &Table{
+		targets:   make(map[int64]Target),
+		languages: make(map[int64]Language),
+	}
+}
+
+//go:embed countries.csv
+var countriesCSV string
+
+//go:embed languages.csv
+var languagesCSV string
+
+var (
+	defaultTable     *Table
+	defaultTableOnce sync.Once
+)
+
+// Default returns the shared table of embedded countries and languages.
+func Default() *Table {
+	defaultTableOnce.Do(func() {
+		t := NewTable()
+		if err := t.LoadGeoTargets(strings.NewReader(countriesCSV)); err != nil {
+			panic("geo: embedded countries are invalid: " + err.Error())
+		}
+		if err := t.LoadLanguages(strings.NewReader(languagesCSV)); err != nil {
+			panic("geo: embedded languages are invalid: " + err.Error())
+		}
+		defaultTable = t
+	})
+	return defaultTable
+}
+
+// AddTarget adds or replaces a geo target.
+func (t *Table) AddTarget(target Target) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.targets[target.ID] = target
+}
+
+// AddLanguage adds or replaces a language.
+func (t *Table) AddLanguage(lang Language) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.languages[lang.ID] = lang
+}
+
+// GeoTarget returns the geo target with the given criterion ID.
+func (t *Table) GeoTarget(id int64) (Target, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	target, ok := t.targets[id]
+	return target, ok
+}
+
+// Language returns the language with the given criterion ID.
+func (t *Table) Language(id int64) (Language, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	lang, ok := t.languages[id]
+	return lang, ok
+}
+
+// ResolveConstant returns the name of a geo target or language resource
+// name such as "geoTargetConstants/2840" or "languageConstants/1000".
+func (t *Table) ResolveConstant(resourceName string) (string, bool) {
+	switch {
+	case strings.HasPrefix(resourceName, GeoTargetPrefix):
+		id, err := strconv.ParseInt(strings.TrimPrefix(resourceName, GeoTargetPrefix), 10, 64)
+		if err != nil {
+			return "", false
+		}
+		target, ok := t.GeoTarget(id)
+		return target.Name, ok
+	case strings.HasPrefix(resourceName, LanguagePrefix):
+		id, err := strconv.ParseInt(strings.TrimPrefix(resourceName, LanguagePrefix), 10, 64)
+		if err != nil {
+			return "", false
+		}
+		lang, ok := t.Language(id)
+		return lang.Name, ok
+	default:
+		return "", false
+	}
+}
+
+// LoadGeoTargets reads Google's geo targets CSV, whose header is:
+//
+//	Criteria ID,Name,Canonical Name,Parent ID,Country Code,Target Type,Status
+func (t *Table) LoadGeoTargets(r io.Reader) error {
+	return readCSV(r, []string{"Criteria ID", "Name"}, func(get func(string) string) error {
+		id, err := strconv.ParseInt(get("Criteria ID"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid criteria ID %q", get("Criteria ID"))
+		}
+		parent, _ := strconv.ParseInt(get("Parent ID"), 10, 64)
+		t.AddTarget(Target{
+			ID:            id,
+			Name:          get("Name"),
+			CanonicalName: get("Canonical Name"),
+			ParentID:      parent,
+			CountryCode:   get("Country Code"),
+			Type:          get("Target Type"),
+			Status:        get("Status"),
+		})
+		return nil
+	})
+}
+
+// LoadLanguages reads Google's language codes CSV, whose header is:
+//
+//	Language name,Language code,Criterion ID
+func (t *Table) LoadLanguages(r io.Reader) error {
+	return readCSV(r, []string{"Language name", "Criterion ID"}, func(get func(string) string) error {
+		id, err := strconv.ParseInt(get("Criterion ID"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid criterion ID %q", get("Criterion ID"))
+		}
+		t.AddLanguage(Language{ID: id, Name: get("Language name"), Code: get("Language code")})
+		return nil
+	})
+}
+
+// LoadFile loads a cached geo targets or language codes CSV, telling the
+// two apart by their header.
+func (t *Table) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	header, _, _ := strings.Cut(string(data), "\n")
+	if strings.Contains(header, "Language name") {
+		err = t.LoadLanguages(strings.NewReader(string(data)))
+	} else {
+		err = t.LoadGeoTargets(strings.NewReader(string(data)))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// readCSV calls fn for each record, with get looking values up by
+// header name. The header must contain every required column.
+func readCSV(r io.Reader, required []string, fn func(get func(string) string) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("geo: reading header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("geo: missing column %q", name)
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("geo: %w", err)
+		}
+		get := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}
+		if err := fn(get); err != nil {
+			line, _ := cr.FieldPos(0)
+			return fmt.Errorf("geo: line %d: %w", line, err)
+		}
+	}
+}
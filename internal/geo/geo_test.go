@@ -0,0 +1,79 @@
+package geo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultResolveConstant(t *testing.T) {
+	tests := []struct {
+		resourceName string
+		want         string
+		ok           bool
+	}{
+		{"geoTargetConstants/2840", "United States", true},
+		{"geoTargetConstants/2392", "Japan", true},
+		{"languageConstants/1000", "English", true},
+		{"languageConstants/1005", "Japanese", true},
+		{"geoTargetConstants/1018127", "", false},
+		{"geoTargetConstants/abc", "", false},
+		{"campaigns/1", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Default().ResolveConstant(tt.resourceName)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ResolveConstant(%q) = %q, %v; want %q, %v", tt.resourceName, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	geoPath := filepath.Join(dir, "geotargets.csv")
+	geoCSV := "\ufeffCriteria ID,Name,Canonical Name,Parent ID,Country Code,Target Type,Status\n" +
+		"1018127,Boston,\"Boston,Massachusetts,United States\",21152,US,City,Active\n"
+	if err := os.WriteFile(geoPath, []byte(geoCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	langPath := filepath.Join(dir, "languagecodes.csv")
+	if err := os.WriteFile(langPath, []byte("Language name,Language code,Criterion ID\nKlingon,tlh,9999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl := NewTable()
+	if err := tbl.LoadFile(geoPath); err != nil {
+		t.Fatalf("load geo targets: %v", err)
+	}
+	if err := tbl.LoadFile(langPath); err != nil {
+		t.Fatalf("load languages: %v", err)
+	}
+
+	boston, ok := tbl.GeoTarget(1018127)
+	if !ok || boston.CanonicalName != "Boston,Massachusetts,United States" || boston.ParentID != 21152 || boston.Type != "City" {
+		t.Errorf("unexpected target: %+v", boston)
+	}
+	if name, ok := tbl.ResolveConstant("languageConstants/9999"); !ok || name != "Klingon" {
+		t.Errorf("ResolveConstant(languageConstants/9999) = %q, %v", name, ok)
+	}
+}
+
+func TestLoadGeoTargetsErrors(t *testing.T) {
+	tbl := NewTable()
+	if err := tbl.LoadFile(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.csv")
+	os.WriteFile(bad, []byte("Criteria ID,Name\nnot-a-number,Nowhere\n"), 0o644)
+	if err := tbl.LoadFile(bad); err == nil {
+		t.Error("expected error for invalid criteria ID")
+	}
+
+	noHeader := filepath.Join(dir, "noheader.csv")
+	os.WriteFile(noHeader, []byte("ID,Title\n1,x\n"), 0o644)
+	if err := tbl.LoadFile(noHeader); err == nil {
+		t.Error("expected error for missing columns")
+	}
+}
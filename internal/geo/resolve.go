@@ -0,0 +1,217 @@
+package geo
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Suggester suggests geo target constants for location names.
+// *adsapi.Client satisfies it.
+type Suggester interface {
+	SuggestGeoTargetConstants(ctx context.Context, locale, countryCode string, names ...string) ([]adsapi.GeoTargetSuggestion, error)
+}
+
+// Searcher runs a GAQL query against a customer. *adsapi.Client
+// satisfies it.
+type Searcher interface {
+	Search(ctx context.Context, customerID, query string) (*adsapi.SearchResponse, error)
+}
+
+// Match is a geo target suggested for a location name.
+type Match struct {
+	Target
+	Reach int64 // users in the location, as estimated by Google
+}
+
+// Resolve returns the geo targets matching name, such as "Boston, MA",
+// best match first, using the default table.
+func Resolve(ctx context.Context, s Suggester, name, countryCode string) ([]Match, error) {
+	return Default().Resolve(ctx, s, name, countryCode)
+}
+
+// Resolve returns the geo targets GeoTargetConstantService suggests for
+// name, such as "Boston, MA", best match first. A countryCode such as
+// "US" narrows the suggestions to one country. The suggested targets and
+// their parents are added to t, so results referencing them resolve too.
+func (t *Table) Resolve(ctx context.Context, s Suggester, name, countryCode string) ([]Match, error) {
+	suggestions, err := s.SuggestGeoTargetConstants(ctx, "en", countryCode, name)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]Match, 0, len(suggestions))
+	for _, sg := range suggestions {
+		target := fromConstant(sg.GeoTargetConstant)
+		t.AddTarget(target)
+		for _, p := range sg.Parents {
+			t.AddTarget(fromConstant(p))
+		}
+		matches = append(matches, Match{Target: target, Reach: sg.Reach})
+	}
+	return matches, nil
+}
+
+// Find returns the geo targets in t named name, ignoring case, without
+// asking the API; only the embedded countries and loaded files are
+// searched. Comma-separated parts after the first, as in "Boston, MA",
+// narrow the matches to targets with a canonical name part starting
+// with each, if any has. A countryCode narrows them to one country.
+// Matches are ordered by ID.
+func (t *Table) Find(name, countryCode string) []Target {
+	parts := strings.Split(name, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	t.mu.RLock()
+	var found []Target
+	for _, target := range t.targets {
+		if !strings.EqualFold(target.Name, parts[0]) && !strings.EqualFold(target.CanonicalName, strings.Join(parts, ",")) {
+			continue
+		}
+		if countryCode != "" && !strings.EqualFold(target.CountryCode, countryCode) {
+			continue
+		}
+		found = append(found, target)
+	}
+	t.mu.RUnlock()
+
+	if len(parts) > 1 {
+		narrowed := slices.DeleteFunc(slices.Clone(found), func(target Target) bool {
+			return !containsParts(target, parts[1:])
+		})
+		if len(narrowed) > 0 {
+			found = narrowed
+		}
+	}
+	slices.SortFunc(found, func(a, b Target) int { return cmp.Compare(a.ID, b.ID) })
+	return found
+}
+
+// containsParts reports whether every part is target's country code or
+// the start of one of its canonical name parts, ignoring case.
+func containsParts(target Target, parts []string) bool {
+	names := strings.Split(strings.ToLower(target.CanonicalName), ",")
+	for _, part := range parts {
+		part = strings.ToLower(part)
+		if strings.EqualFold(part, target.CountryCode) {
+			continue
+		}
+		if !slices.ContainsFunc(names, func(n string) bool { return strings.HasPrefix(n, part) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// TargetField reports whether field, such as segments.geo_target_city,
+// holds geo target resource names by its name.
+func TargetField(field string) bool {
+	return strings.Contains(field, "geo_target")
+}
+
+// Unknown returns the IDs of the geo targets rows reference by resource
+// name, such as segments.geo_target_city, that t cannot name, in the
+// order they first appear.
+func (t *Table) Unknown(rows []adsapi.Row) []int64 {
+	var ids []int64
+	seen := make(map[int64]bool)
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for _, e := range v {
+				walk(e)
+			}
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case string:
+			if !strings.HasPrefix(v, GeoTargetPrefix) {
+				return
+			}
+			id, err := strconv.ParseInt(strings.TrimPrefix(v, GeoTargetPrefix), 10, 64)
+			if err != nil || seen[id] {
+				return
+			}
+			seen[id] = true
+			if _, ok := t.GeoTarget(id); !ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	for _, row := range rows {
+		walk(map[string]any(row))
+	}
+	return ids
+}
+
+// fetchBatch is the most IDs Fetch asks for in one query.
+const fetchBatch = 1000
+
+// FetchQuery returns the query selecting the geo targets with the given
+// IDs.
+func FetchQuery(ids []int64) *gaql.Query {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = strconv.FormatInt(id, 10)
+	}
+	return gaql.Select("geo_target_constant.id", "geo_target_constant.name",
+		"geo_target_constant.canonical_name", "geo_target_constant.parent_geo_target",
+		"geo_target_constant.country_code", "geo_target_constant.target_type",
+		"geo_target_constant.status").
+		From("geo_target_constant").
+		Where("geo_target_constant.id", gaql.OpIn, gaql.ListValue(list...)).
+		Query()
+}
+
+// Fetch queries geo_target_constant, as customerID, for the geo targets
+// with the given IDs and adds them to t. Any customer will do: the
+// constants are the same for all.
+func (t *Table) Fetch(ctx context.Context, s Searcher, customerID string, ids []int64) error {
+	for len(ids) > 0 {
+		n := min(len(ids), fetchBatch)
+		resp, err := s.Search(ctx, customerID, FetchQuery(ids[:n]).String())
+		if err != nil {
+			return err
+		}
+		for _, row := range resp.Results {
+			c, _ := row["geoTargetConstant"].(map[string]any)
+			str := func(key string) string { s, _ := c[key].(string); return s }
+			id, err := strconv.ParseInt(str("id"), 10, 64)
+			if err != nil {
+				continue
+			}
+			t.AddTarget(fromConstant(adsapi.GeoTargetConstant{
+				ID:              id,
+				Name:            str("name"),
+				CanonicalName:   str("canonicalName"),
+				ParentGeoTarget: str("parentGeoTarget"),
+				CountryCode:     str("countryCode"),
+				TargetType:      str("targetType"),
+				Status:          str("status"),
+			}))
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// fromConstant converts an API geo target constant to a Target.
+func fromConstant(c adsapi.GeoTargetConstant) Target {
+	parent, _ := strconv.ParseInt(strings.TrimPrefix(c.ParentGeoTarget, GeoTargetPrefix), 10, 64)
+	return Target{
+		ID:            c.ID,
+		Name:          c.Name,
+		CanonicalName: c.CanonicalName,
+		ParentID:      parent,
+		CountryCode:   c.CountryCode,
+		Type:          c.TargetType,
+		Status:        c.Status,
+	}
+}
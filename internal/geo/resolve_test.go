@@ -0,0 +1,136 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+type fakeSuggester struct {
+	suggestions []adsapi.GeoTargetSuggestion
+	countryCode string
+}
+
+func (f *fakeSuggester) SuggestGeoTargetConstants(_ context.Context, _, countryCode string, _ ...string) ([]adsapi.GeoTargetSuggestion, error) {
+	f.countryCode = countryCode
+	return f.suggestions, nil
+}
+
+func TestResolve(t *testing.T) {
+	s := &fakeSuggester{suggestions: []adsapi.GeoTargetSuggestion{{
+		Reach: 4100000,
+		GeoTargetConstant: adsapi.GeoTargetConstant{ID: 1018127, Name: "Boston", CountryCode: "US", TargetType: "City",
+			CanonicalName: "Boston,Massachusetts,United States", ParentGeoTarget: "geoTargetConstants/21152"},
+		Parents: []adsapi.GeoTargetConstant{{ID: 21152, Name: "Massachusetts", TargetType: "State"}},
+	}}}
+	tbl := NewTable()
+	matches, err := tbl.Resolve(context.Background(), s, "Boston, MA", "US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.countryCode != "US" {
+		t.Errorf("country code %q not passed on", s.countryCode)
+	}
+	if len(matches) != 1 || matches[0].ID != 1018127 || matches[0].ParentID != 21152 || matches[0].Reach != 4100000 {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+	for resourceName, want := range map[string]string{"geoTargetConstants/1018127": "Boston", "geoTargetConstants/21152": "Massachusetts"} {
+		if got, ok := tbl.ResolveConstant(resourceName); !ok || got != want {
+			t.Errorf("after Resolve, ResolveConstant(%q) = %q, %v; want %q", resourceName, got, ok, want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	tbl := NewTable()
+	for _, target := range []Target{
+		{ID: 1018127, Name: "Boston", CanonicalName: "Boston,Massachusetts,United States", CountryCode: "US"},
+		{ID: 1006524, Name: "Boston", CanonicalName: "Boston,England,United Kingdom", CountryCode: "GB"},
+		{ID: 1026339, Name: "Boston", CanonicalName: "Boston,Georgia,United States", CountryCode: "US"},
+		{ID: 2840, Name: "United States", CanonicalName: "United States", CountryCode: "US"},
+	} {
+		tbl.AddTarget(target)
+	}
+	tests := []struct {
+		name, countryCode string
+		want              []int64
+	}{
+		{"boston", "", []int64{1006524, 1018127, 1026339}},
+		{"Boston", "us", []int64{1018127, 1026339}},
+		{"Boston, MA", "", []int64{1018127}},
+		{"Boston, GB", "", []int64{1006524}},
+		{"Boston, Nowhere", "", []int64{1006524, 1018127, 1026339}}, // nothing narrower
+		{"Boston,Georgia,United States", "", []int64{1026339}},
+		{"United States", "", []int64{2840}},
+		{"Springfield", "", nil},
+	}
+	for _, tt := range tests {
+		var got []int64
+		for _, target := range tbl.Find(tt.name, tt.countryCode) {
+			got = append(got, target.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Find(%q, %q) = %v, want %v", tt.name, tt.countryCode, got, tt.want)
+		}
+	}
+}
+
+type fakeSearcher struct {
+	queries []string
+	err     error
+}
+
+func (f *fakeSearcher) Search(_ context.Context, _, query string) (*adsapi.SearchResponse, error) {
+	f.queries = append(f.queries, query)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &adsapi.SearchResponse{Results: []adsapi.Row{{
+		"geoTargetConstant": map[string]any{"id": "1018127", "name": "Boston", "canonicalName": "Boston,Massachusetts,United States",
+			"parentGeoTarget": "geoTargetConstants/21152", "countryCode": "US", "targetType": "City", "status": "ENABLED"},
+	}}}, nil
+}
+
+func TestUnknownAndFetch(t *testing.T) {
+	rows := []adsapi.Row{
+		{"segments": map[string]any{"geoTargetCity": "geoTargetConstants/1018127", "geoTargetCountry": "geoTargetConstants/2840"}},
+		{"segments": map[string]any{"geoTargetCity": "geoTargetConstants/1018127"}},
+		{"campaignCriterion": map[string]any{"location": map[string]any{"geoTargetConstant": "geoTargetConstants/21152"}}},
+		{"campaign": map[string]any{"name": "geoTargetConstants/x"}},
+	}
+	tbl := NewTable()
+	tbl.AddTarget(Target{ID: 2840, Name: "United States"})
+	ids := tbl.Unknown(rows)
+	if !slices.Equal(ids, []int64{1018127, 21152}) {
+		t.Fatalf("Unknown = %v, want [1018127 21152]", ids)
+	}
+
+	s := &fakeSearcher{}
+	if err := tbl.Fetch(context.Background(), s, "1234567890", ids); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.queries) != 1 || !strings.HasSuffix(s.queries[0], "FROM geo_target_constant WHERE geo_target_constant.id IN (1018127, 21152)") {
+		t.Errorf("unexpected queries %q", s.queries)
+	}
+	if boston, ok := tbl.GeoTarget(1018127); !ok || boston.ParentID != 21152 || boston.Type != "City" {
+		t.Errorf("fetched target = %+v, %v", boston, ok)
+	}
+	if got := tbl.Unknown(rows); !slices.Equal(got, []int64{21152}) {
+		t.Errorf("Unknown after Fetch = %v, want [21152]", got)
+	}
+
+	many := make([]int64, fetchBatch+1)
+	s = &fakeSearcher{}
+	tbl.Fetch(context.Background(), s, "1234567890", many)
+	if len(s.queries) != 2 {
+		t.Errorf("Fetch of %d IDs ran %d queries, want 2", len(many), len(s.queries))
+	}
+	s = &fakeSearcher{err: errors.New("PERMISSION_DENIED")}
+	if err := tbl.Fetch(context.Background(), s, "1234567890", ids); err == nil {
+		t.Error("Fetch error not returned")
+	}
+}
@@ -0,0 +1,84 @@
+// Package golden compares test output with golden files.
+//
+// A golden file holds the expected output of a test verbatim. Run the
+// tests with -update to rewrite the files from the current output, then
+// review the diff before committing it:
+//
+//	go test ./internal/output -update
+//
+// # Basic Usage
+//
+//	func TestRender(t *testing.T) {
+//		got := render()
+//		golden.Assert(t, "testdata/render.golden", got)
+//	}
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Assert fails t unless got matches the golden file at path. With
+// -update it writes got to path instead.
+func Assert(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("golden: %v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run with -update to accept):\n%s", path, Diff(string(want), string(got)))
+	}
+}
+
+// Diff returns a line diff of want and got showing the first lines that
+// differ with a little context, enough to locate a change.
+func Diff(want, got string) string {
+	w := strings.Split(want, "\n")
+	g := strings.Split(got, "\n")
+
+	first := 0
+	for first < len(w) && first < len(g) && w[first] == g[first] {
+		first++
+	}
+	// Trim the common suffix so only the changed block is shown.
+	wEnd, gEnd := len(w), len(g)
+	for wEnd > first && gEnd > first && w[wEnd-1] == g[gEnd-1] {
+		wEnd--
+		gEnd--
+	}
+
+	const context = 2
+	var sb strings.Builder
+	for i := max(0, first-context); i < first; i++ {
+		fmt.Fprintf(&sb, "  %s\n", w[i])
+	}
+	for _, line := range w[first:wEnd] {
+		fmt.Fprintf(&sb, "- %s\n", line)
+	}
+	for _, line := range g[first:gEnd] {
+		fmt.Fprintf(&sb, "+ %s\n", line)
+	}
+	for i := wEnd; i < min(len(w), wEnd+context); i++ {
+		fmt.Fprintf(&sb, "  %s\n", w[i])
+	}
+	return sb.String()
+}
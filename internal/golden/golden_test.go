@@ -0,0 +1,31 @@
+package golden
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		diff      string
+	}{
+		{
+			name: "changed line",
+			want: "a\nb\nc\nd\ne\nf",
+			got:  "a\nb\nc\nX\ne\nf",
+			diff: "  b\n  c\n- d\n+ X\n  e\n  f\n",
+		},
+		{
+			name: "added line",
+			want: "a\nb",
+			got:  "a\nb\nc",
+			diff: "  a\n  b\n+ c\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.want, tt.got); got != tt.diff {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// Package i18n translates the help and diagnostic messages of the CLI.
+//
+// Messages are written in English in the code, and the English text is
+// the key of its translation: a catalog maps each message, or the printf
+// format that produces it, to the same message in another language. A
+// format key such as "unknown resource: %s" also matches messages
+// already formatted from it, so errors built deep inside other packages,
+// such as the GAQL validator, are translated where they are printed. The
+// values a matched message carries, which may be messages themselves,
+// are translated in turn. A translation refers to the values in order,
+// or by position with %[n]s when the language orders them differently.
+// Messages without a translation stay in English.
+//
+// The catalogs are embedded JSON objects, one per language besides
+// English, such as ja.json.
+//
+// # Basic Usage
+//
+//	if err := i18n.SetLanguage("ja"); err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(i18n.T("gaql: validation error: FROM clause is required"))
+//	// gaql: 検証エラー: FROM 句が必要です
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Languages lists the languages messages can be shown in, by ISO 639-1
+// code. English is the language of the code and needs no catalog.
+var Languages = []string{"en", "es", "ja"}
+
+//go:embed es.json ja.json
+var catalogs embed.FS
+
+var (
+	language = "en"
+	current  *catalog // nil for English
+)
+
+// Match returns the language of a locale name such as ja, ja_JP.UTF-8,
+// or es-MX, and whether it is one of Languages. C and POSIX are not.
+func Match(locale string) (string, bool) {
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang, slices.Contains(Languages, lang)
+}
+
+// SetLanguage makes T and Text translate into lang, a language or locale
+// name accepted by Match. It is meant to be called once, before any
+// message is printed.
+func SetLanguage(lang string) error {
+	l, ok := Match(lang)
+	if !ok {
+		return fmt.Errorf("i18n: unsupported language %q (expected one of %s)", lang, strings.Join(Languages, ", "))
+	}
+	var c *catalog
+	if l != "en" {
+		var err error
+		if c, err = load(l); err != nil {
+			return err
+		}
+	}
+	language, current = l, c
+	return nil
+}
+
+// Language returns the language set by SetLanguage, en by default.
+func Language() string {
+	return language
+}
+
+// T translates a message into the current language. It is returned
+// unchanged when the catalog has no translation for it.
+func T(msg string) string {
+	if current == nil {
+		return msg
+	}
+	return current.translate(msg)
+}
+
+// Text translates text line by line, keeping the indentation, for
+// multi-line reports and help. In a line of two columns separated by two
+// or more spaces, such as a command and its description, only the last
+// column is translated if the line as a whole is not.
+func Text(text string) string {
+	if current == nil {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = current.translateLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// catalog is the translations of one language.
+type catalog struct {
+	messages map[string]string // keys without verbs
+	patterns []pattern         // keys with verbs, most specific first
+}
+
+// pattern matches messages formatted from a key with verbs.
+type pattern struct {
+	re          *regexp.Regexp
+	literal     int // length of the key outside its verbs
+	translation string
+}
+
+// verb matches a printf verb, with an optional argument index for
+// translations.
+var verb = regexp.MustCompile(`%(?:\[(\d+)\])?[-+# 0]*\d*(?:\.\d+)?[a-zA-Z%]`)
+
+// columns matches a line of two columns: a first column of words
+// separated by single spaces, then two or more spaces.
+var columns = regexp.MustCompile(`^(\S+(?: \S+)*)( {2,})(\S.*)$`)
+
+func load(lang string) (*catalog, error) {
+	data, err := catalogs.ReadFile(lang + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("i18n: %s catalog: %w", lang, err)
+	}
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("i18n: %s catalog: %w", lang, err)
+	}
+	c := &catalog{messages: make(map[string]string)}
+	for key, translation := range entries {
+		if !verb.MatchString(key) {
+			c.messages[key] = translation
+			continue
+		}
+		c.patterns = append(c.patterns, compile(key, translation))
+	}
+	// A longer literal text is a more specific match: "unknown resource:
+	// %s" is tried before "%s (default %s)".
+	sort.Slice(c.patterns, func(i, j int) bool {
+		pi, pj := c.patterns[i], c.patterns[j]
+		if pi.literal != pj.literal {
+			return pi.literal > pj.literal
+		}
+		return pi.re.String() < pj.re.String()
+	})
+	return c, nil
+}
+
+// compile turns a format key into a pattern matching the messages it
+// formats. %d matches an integer; other verbs match any text.
+func compile(key, translation string) pattern {
+	var sb strings.Builder
+	sb.WriteString(`^(?s)`)
+	literal, last := 0, 0
+	for _, loc := range verb.FindAllStringIndex(key, -1) {
+		text := key[last:loc[0]]
+		sb.WriteString(regexp.QuoteMeta(text))
+		literal += len(text)
+		switch v := key[loc[0]:loc[1]]; {
+		case v == "%%":
+			sb.WriteString("%")
+			literal++
+		case strings.HasSuffix(v, "d"):
+			sb.WriteString(`(-?\d+)`)
+		default:
+			sb.WriteString(`(.+?)`)
+		}
+		last = loc[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(key[last:]))
+	sb.WriteString(`$`)
+	literal += len(key) - last
+	return pattern{re: regexp.MustCompile(sb.String()), literal: literal, translation: translation}
+}
+
+func (c *catalog) translate(msg string) string {
+	if msg == "" {
+		return msg
+	}
+	if t, ok := c.messages[msg]; ok {
+		return t
+	}
+	for _, p := range c.patterns {
+		m := p.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		args := m[1:]
+		for i, arg := range args {
+			args[i] = c.translate(arg)
+		}
+		return substitute(p.translation, args)
+	}
+	// A labeled message, such as "queries.gaql:3: FROM clause is
+	// required", translates as its label and the rest.
+	if label, rest, ok := strings.Cut(msg, ": "); ok {
+		if t := c.translate(rest); t != rest {
+			return c.translate(label) + ": " + t
+		}
+	}
+	return msg
+}
+
+func (c *catalog) translateLine(line string) string {
+	body := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(body)]
+	if body == "" {
+		return line
+	}
+	if t := c.translate(body); t != body {
+		return indent + t
+	}
+	if m := columns.FindStringSubmatch(body); m != nil {
+		if t := c.translate(m[3]); t != m[3] {
+			return indent + m[1] + m[2] + t
+		}
+	}
+	return line
+}
+
+// substitute fills the verbs of a translation with the text of args,
+// taken in order or by their %[n] index.
+func substitute(translation string, args []string) string {
+	next := 0
+	return verb.ReplaceAllStringFunc(translation, func(v string) string {
+		if v == "%%" {
+			return "%"
+		}
+		i := next
+		if m := verb.FindStringSubmatch(v); m[1] != "" {
+			n, _ := strconv.Atoi(m[1])
+			i = n - 1
+		}
+		next = i + 1
+		if i < 0 || i >= len(args) {
+			return v
+		}
+		return args[i]
+	})
+}
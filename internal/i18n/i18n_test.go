@@ -0,0 +1,144 @@
+package i18n
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func setLanguage(t *testing.T, lang string) {
+	t.Helper()
+	if err := SetLanguage(lang); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetLanguage("en") })
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+		ok     bool
+	}{
+		{"ja", "ja", true},
+		{"ja_JP.UTF-8", "ja", true},
+		{"es-MX", "es", true},
+		{"EN_us", "en", true},
+		{"C.UTF-8", "c", false},
+		{"POSIX", "posix", false},
+		{"fr_FR", "fr", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Match(tt.locale)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Match(%q) = %q, %v; want %q, %v", tt.locale, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	setLanguage(t, "es_ES.UTF-8")
+	if Language() != "es" {
+		t.Errorf("Language() = %q, want es", Language())
+	}
+	if err := SetLanguage("fr"); err == nil {
+		t.Error("SetLanguage(fr) succeeded")
+	}
+	if Language() != "es" {
+		t.Errorf("failed SetLanguage changed the language to %q", Language())
+	}
+}
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		lang string
+		msg  string
+		want string
+	}{
+		{"en", "FROM clause is required", "FROM clause is required"},
+		{"ja", "FROM clause is required", "FROM 句が必要です"},
+		{"es", "FROM clause is required", "la cláusula FROM es obligatoria"},
+		// Formatted messages, with their values translated in turn.
+		{"ja", "Validation error: gaql: validation error on FROM: unknown resource: campain",
+			"検証エラー: gaql: FROM の検証エラー: 不明なリソース: campain"},
+		{"es", "Validation error: gaql: validation error on FROM: unknown resource: campain",
+			"Error de validación: gaql: error de validación en FROM: recurso desconocido: campain"},
+		{"ja", "gaql: expected FROM clause at line 2, column 7", "gaql: 2 行 7 列: FROM 句が必要です"},
+		{"es", "change_event LIMIT must be at most 10000, got 20000",
+			"el LIMIT de change_event debe ser 10000 como máximo; se recibió 20000"},
+		// %d matches only integers.
+		{"ja", "change_event LIMIT must be at most many, got 5", "change_event LIMIT must be at most many, got 5"},
+		// A labeled message translates after its label.
+		{"ja", "Validation error: report.gaql:3: gaql: validation error: FROM clause is required",
+			"検証エラー: report.gaql:3: gaql: 検証エラー: FROM 句が必要です"},
+		{"ja", "something unknown", "something unknown"},
+		{"ja", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang+"/"+tt.msg, func(t *testing.T) {
+			setLanguage(t, tt.lang)
+			if got := T(tt.msg); got != tt.want {
+				t.Errorf("T(%q) = %q, want %q", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	setLanguage(t, "ja")
+	text := "Commands:\n" +
+		"  search       Execute a GAQL query against the API\n" +
+		"  unknown      Not in the catalog\n" +
+		"  -customer-id string\n" +
+		"    \tCustomer ID to query (10 digits, no hyphens) (default \"1234567890\")\n"
+	want := "コマンド:\n" +
+		"  search       API に対して GAQL クエリを実行する\n" +
+		"  unknown      Not in the catalog\n" +
+		"  -customer-id string\n" +
+		"    \tクエリ対象の顧客 ID (ハイフンなしの 10 桁) (既定値 \"1234567890\")\n"
+	if got := Text(text); got != want {
+		t.Errorf("Text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestCatalogs checks that every language translates the same messages
+// and that each translation uses the values of its message.
+func TestCatalogs(t *testing.T) {
+	var keys []string
+	for _, lang := range Languages[1:] {
+		c, err := load(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for key := range c.messages {
+			got = append(got, key)
+		}
+		for _, p := range c.patterns {
+			got = append(got, p.re.String())
+		}
+		slices.Sort(got)
+		if keys == nil {
+			keys = got
+		} else if !slices.Equal(got, keys) {
+			t.Errorf("%s catalog translates other messages than %s", lang, Languages[1])
+		}
+		for _, p := range c.patterns {
+			args := make([]string, p.re.NumSubexp())
+			for i := range args {
+				args[i] = "<" + strconv.Itoa(i) + ">"
+			}
+			out := substitute(p.translation, args)
+			for _, arg := range args {
+				if strings.Count(out, arg) != 1 {
+					t.Errorf("%s: translation %q of %s does not use value %s once", lang, p.translation, p.re, arg)
+				}
+			}
+			if verb.MatchString(out) {
+				t.Errorf("%s: translation %q of %s has verbs without values", lang, p.translation, p.re)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package lint checks GAQL queries for patterns that are valid but
+// costly, fragile, or misleading.
+//
+// Rules are pluggable: each Rule is a named check over a parsed query and
+// the schema catalog. The built-in rules are registered at init time and
+// additional rules can be added with Register. Findings can be rendered
+// for humans, as JSON, or as SARIF for CI code-scanning integrations.
+//
+// # Basic Usage
+//
+//	l := lint.New()
+//	l.Disable("metrics-without-date-segment")
+//	findings := l.LintSource("reports/spend.gaql", src)
+//	lint.WriteHuman(os.Stdout, findings)
+package lint
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Rule is a single lint check.
+type Rule struct {
+	Name        string
+	Description string
+	Severity    gaql.Severity
+
+	// Check inspects a parsed query and returns its findings. The linter
+	// fills in Rule, Severity, and File on each returned finding.
+	Check func(q *gaql.Query, cat *gaql.Catalog) []Finding
+}
+
+// Finding is a single problem reported by a rule.
+type Finding struct {
+	Rule     string
+	Severity gaql.Severity
+	Message  string
+	File     string
+	Span     gaql.Span
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []Rule
+)
+
+// Register adds a rule to the set returned by Rules. Registering a name
+// twice replaces the earlier rule.
+func Register(r Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for i, existing := range registry {
+		if existing.Name == r.Name {
+			registry[i] = r
+			return
+		}
+	}
+	registry = append(registry, r)
+}
+
+// Rules returns the registered rules sorted by name.
+func Rules() []Rule {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	out := append([]Rule(nil), registry...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Linter applies a set of rules to queries.
+type Linter struct {
+	Rules   []Rule
+	Catalog *gaql.Catalog
+}
+
+// New creates a linter with every registered rule and the default catalog.
+func New() *Linter {
+	return &Linter{Rules: Rules(), Catalog: gaql.DefaultCatalog()}
+}
+
+// Disable removes the named rules from the linter.
+func (l *Linter) Disable(names ...string) {
+	skip := make(map[string]bool, len(names))
+	for _, n := range names {
+		skip[n] = true
+	}
+	kept := l.Rules[:0]
+	for _, r := range l.Rules {
+		if !skip[r.Name] {
+			kept = append(kept, r)
+		}
+	}
+	l.Rules = kept
+}
+
+// LintQuery runs every rule against a parsed query.
+func (l *Linter) LintQuery(q *gaql.Query) []Finding {
+	var findings []Finding
+	for _, r := range l.Rules {
+		for _, f := range r.Check(q, l.Catalog) {
+			f.Rule = r.Name
+			f.Severity = r.Severity
+			findings = append(findings, f)
+		}
+	}
+	sortFindings(findings)
+	return findings
+}
+
+// LintSource parses src and lints the resulting query. A syntax error is
+// reported as a finding of the "syntax" rule rather than returned.
+func (l *Linter) LintSource(file, src string) []Finding {
+	q, err := gaql.Parse(StripComments(src))
+	if err != nil {
+		f := Finding{Rule: "syntax", Severity: gaql.SeverityError, Message: err.Error(), File: file}
+		var perr *gaql.ParseError
+		if errors.As(err, &perr) {
+			f.Message = perr.Message
+			pos := gaql.Pos{Line: perr.Line, Column: perr.Column, Offset: perr.Offset}
+			f.Span = gaql.Span{Start: pos, End: pos}
+		}
+		return []Finding{f}
+	}
+
+	findings := l.LintQuery(q)
+	for i := range findings {
+		findings[i].File = file
+	}
+	return findings
+}
+
+// HasErrors reports whether any finding has error severity.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == gaql.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// StripComments blanks out "--" line comments outside string literals so
+// stored query files with header comments parse. Comment characters are
+// replaced with spaces, keeping line and column positions intact.
+func StripComments(src string) string {
+	b := []byte(src)
+	var quote byte
+	for i := 0; i < len(b); i++ {
+		switch {
+		case quote != 0:
+			if b[i] == '\\' {
+				i++
+			} else if b[i] == quote {
+				quote = 0
+			}
+		case b[i] == '\'' || b[i] == '"':
+			quote = b[i]
+		case b[i] == '-' && i+1 < len(b) && b[i+1] == '-':
+			for i < len(b) && b[i] != '\n' {
+				b[i] = ' '
+				i++
+			}
+		}
+	}
+	return string(b)
+}
+
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Span.Start, findings[j].Span.Start
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return strings.Compare(findings[i].Rule, findings[j].Rule) < 0
+	})
+}
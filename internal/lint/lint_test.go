@@ -0,0 +1,151 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "clean query",
+			input: "SELECT campaign.id, segments.date, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS ORDER BY metrics.clicks DESC",
+		},
+		{
+			name:  "missing limit on high-volume resource",
+			input: "SELECT search_term_view.search_term FROM search_term_view WHERE segments.date DURING LAST_7_DAYS",
+			want:  []string{"missing-limit"},
+		},
+		{
+			name:  "unsortable order by",
+			input: "SELECT ad_group_ad.ad.id FROM ad_group_ad ORDER BY ad_group_ad.ad.final_urls",
+			want:  []string{"unsortable-order-by"},
+		},
+		{
+			name:  "duplicate condition",
+			input: "SELECT campaign.id FROM campaign WHERE campaign.status = 'ENABLED' AND campaign.status = 'ENABLED'",
+			want:  []string{"redundant-condition"},
+		},
+		{
+			name:  "two date ranges",
+			input: "SELECT campaign.id, segments.date FROM campaign WHERE segments.date DURING LAST_7_DAYS AND segments.date BETWEEN '2026-01-01' AND '2026-01-31'",
+			want:  []string{"redundant-condition"},
+		},
+		{
+			name:  "deprecated field",
+			input: "SELECT ad_group_ad.ad.expanded_text_ad.headline_part1 FROM ad_group_ad",
+			want:  []string{"deprecated-field"},
+		},
+		{
+			name:  "metrics without date segment",
+			input: "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS",
+			want:  []string{"metrics-without-date-segment"},
+		},
+		{
+			name:  "broad click_view range",
+			input: "SELECT click_view.gclid FROM click_view WHERE segments.date DURING LAST_7_DAYS LIMIT 10",
+			want:  []string{"click-view-date-range"},
+		},
+		{
+			name:  "single day click_view",
+			input: "SELECT click_view.gclid FROM click_view WHERE segments.date = '2026-02-01' LIMIT 10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := New().LintSource("q.gaql", tt.input)
+			var got []string
+			for _, f := range findings {
+				got = append(got, f.Rule)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, findings)
+			}
+		})
+	}
+}
+
+func TestLintSourceSyntaxError(t *testing.T) {
+	findings := New().LintSource("bad.gaql", "SELECT campaign.id\nFROM")
+	if len(findings) != 1 || findings[0].Rule != "syntax" {
+		t.Fatalf("expected one syntax finding, got %v", findings)
+	}
+	if findings[0].Span.Start.Line != 2 || !HasErrors(findings) {
+		t.Errorf("unexpected finding: %+v", findings[0])
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	src := "-- name: spend\nSELECT campaign.name -- the name\nFROM campaign WHERE campaign.name = 'a--b'"
+	got := StripComments(src)
+	if len(got) != len(src) {
+		t.Fatalf("length changed: %d != %d", len(got), len(src))
+	}
+	q, err := gaql.Parse(got)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if q.Where[0].Value.Str != "a--b" {
+		t.Errorf("comment stripping touched a string literal: %q", q.Where[0].Value.Str)
+	}
+	if q.Select[0].Span.Start.Line != 2 {
+		t.Errorf("positions shifted: %s", q.Select[0].Span)
+	}
+}
+
+func TestDisable(t *testing.T) {
+	l := New()
+	l.Disable("metrics-without-date-segment")
+	findings := l.LintSource("q.gaql", "SELECT campaign.id, metrics.clicks FROM campaign WHERE segments.date DURING LAST_7_DAYS")
+	if len(findings) != 0 {
+		t.Errorf("expected disabled rule to be skipped, got %v", findings)
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	findings := New().LintSource("q.gaql", "SELECT ad_group_ad.ad.id FROM ad_group_ad ORDER BY ad_group_ad.ad.final_urls")
+
+	var human bytes.Buffer
+	WriteHuman(&human, findings)
+	if !strings.HasPrefix(human.String(), "q.gaql:1:") || !strings.Contains(human.String(), "[unsortable-order-by]") {
+		t.Errorf("unexpected human output: %q", human.String())
+	}
+
+	var js bytes.Buffer
+	if err := WriteJSON(&js, findings); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded) != 1 || decoded[0]["severity"] != "error" {
+		t.Errorf("unexpected JSON output: %s", js.String())
+	}
+
+	var sarif bytes.Buffer
+	if err := WriteSARIF(&sarif, findings, Rules(), "test"); err != nil {
+		t.Fatalf("sarif: %v", err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID string `json:"ruleId"`
+				Level  string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(sarif.Bytes(), &log); err != nil {
+		t.Fatalf("sarif decode: %v", err)
+	}
+	if log.Version != "2.1.0" || log.Runs[0].Results[0].RuleID != "unsortable-order-by" || log.Runs[0].Results[0].Level != "error" {
+		t.Errorf("unexpected SARIF output: %s", sarif.String())
+	}
+}
@@ -0,0 +1,162 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// WriteHuman writes findings as "file:line:col: severity: message [rule]".
+func WriteHuman(w io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		loc := f.File
+		if f.Span.IsValid() {
+			loc = fmt.Sprintf("%s:%d:%d", f.File, f.Span.Start.Line, f.Span.Start.Column)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s: %s [%s]\n", loc, f.Severity, f.Message, f.Rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFinding is the JSON shape of a Finding.
+type jsonFinding struct {
+	File      string `json:"file"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	EndColumn int    `json:"end_column,omitempty"`
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+}
+
+// WriteJSON writes findings as a JSON array.
+func WriteJSON(w io.Writer, findings []Finding) error {
+	out := make([]jsonFinding, 0, len(findings))
+	for _, f := range findings {
+		out = append(out, jsonFinding{
+			File:      f.File,
+			Line:      f.Span.Start.Line,
+			Column:    f.Span.Start.Column,
+			EndLine:   f.Span.End.Line,
+			EndColumn: f.Span.End.Column,
+			Rule:      f.Rule,
+			Severity:  f.Severity.String(),
+			Message:   f.Message,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// SARIF 2.1.0 document types, limited to the properties adtap emits.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string       `json:"id"`
+		ShortDescription     sarifMessage `json:"shortDescription"`
+		DefaultConfiguration struct {
+			Level string `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region *sarifRegion `json:"region,omitempty"`
+		} `json:"physicalLocation"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine,omitempty"`
+		EndColumn   int `json:"endColumn,omitempty"`
+	}
+)
+
+// WriteSARIF writes findings as a SARIF 2.1.0 log. The rules are listed
+// in the tool driver so code-scanning UIs can show their descriptions.
+func WriteSARIF(w io.Writer, findings []Finding, rules []Rule, toolVersion string) error {
+	driver := sarifDriver{
+		Name:           "adtap",
+		Version:        toolVersion,
+		InformationURI: "https://github.com/aygp-dr/adtap",
+	}
+	for _, r := range rules {
+		sr := sarifRule{ID: r.Name, ShortDescription: sarifMessage{Text: r.Description}}
+		sr.DefaultConfiguration.Level = sarifLevel(r.Severity)
+		driver.Rules = append(driver.Rules, sr)
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = f.File
+		if f.Span.IsValid() {
+			loc.PhysicalLocation.Region = &sarifRegion{
+				StartLine:   f.Span.Start.Line,
+				StartColumn: f.Span.Start.Column,
+				EndLine:     f.Span.End.Line,
+				EndColumn:   f.Span.End.Column,
+			}
+		}
+		results = append(results, sarifResult{
+			RuleID:    f.Rule,
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+func sarifLevel(s gaql.Severity) string {
+	switch s {
+	case gaql.SeverityError:
+		return "error"
+	case gaql.SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
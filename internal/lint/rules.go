@@ -0,0 +1,163 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func init() {
+	Register(Rule{
+		Name:        "missing-limit",
+		Description: "Queries against high-volume resources should have a LIMIT clause.",
+		Severity:    gaql.SeverityWarning,
+		Check:       checkMissingLimit,
+	})
+	Register(Rule{
+		Name:        "unsortable-order-by",
+		Description: "ORDER BY fields must be sortable.",
+		Severity:    gaql.SeverityError,
+		Check:       checkUnsortableOrderBy,
+	})
+	Register(Rule{
+		Name:        "redundant-condition",
+		Description: "WHERE conditions should not repeat or overlap.",
+		Severity:    gaql.SeverityWarning,
+		Check:       checkRedundantCondition,
+	})
+	Register(Rule{
+		Name:        "deprecated-field",
+		Description: "Deprecated fields should be replaced before they are removed from the API.",
+		Severity:    gaql.SeverityWarning,
+		Check:       checkDeprecatedField,
+	})
+	Register(Rule{
+		Name:        "metrics-without-date-segment",
+		Description: "Metrics without segments.date in SELECT are aggregated over the whole date range.",
+		Severity:    gaql.SeverityInfo,
+		Check:       checkMetricsWithoutDateSegment,
+	})
+	Register(Rule{
+		Name:        "click-view-date-range",
+		Description: "click_view only accepts single-day date ranges.",
+		Severity:    gaql.SeverityError,
+		Check:       checkClickViewDateRange,
+	})
+}
+
+func checkMissingLimit(q *gaql.Query, _ *gaql.Catalog) []Finding {
+	if q.Limit > 0 || !gaql.HighVolumeResources[q.From] {
+		return nil
+	}
+	return []Finding{{
+		Message: fmt.Sprintf("%s is a high-volume resource; add a LIMIT clause", q.From),
+		Span:    q.FromSpan,
+	}}
+}
+
+func checkUnsortableOrderBy(q *gaql.Query, cat *gaql.Catalog) []Finding {
+	var findings []Finding
+	for _, o := range q.OrderBy {
+		info, ok := cat.Field(o.Field)
+		if ok && !info.Sortable {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("%s is not sortable", o.Field),
+				Span:    o.Span,
+			})
+		}
+	}
+	return findings
+}
+
+func checkRedundantCondition(q *gaql.Query, _ *gaql.Catalog) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+	dateRanges := 0
+	for _, c := range q.Where {
+		key := c.String()
+		if seen[key] {
+			findings = append(findings, Finding{
+				Message: "duplicate condition: " + key,
+				Span:    c.Span,
+			})
+			continue
+		}
+		seen[key] = true
+
+		if c.Field == "segments.date" && (c.Operator == gaql.OpDuring || c.Operator == gaql.OpBetween) {
+			dateRanges++
+			if dateRanges > 1 {
+				findings = append(findings, Finding{
+					Message: "segments.date is constrained by more than one date range",
+					Span:    c.Span,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func checkDeprecatedField(q *gaql.Query, cat *gaql.Catalog) []Finding {
+	var findings []Finding
+	report := func(name string, span gaql.Span) {
+		if info, ok := cat.Field(name); ok && info.Deprecated != "" {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("%s is deprecated: %s", name, info.Deprecated),
+				Span:    span,
+			})
+		}
+	}
+	for _, f := range q.Select {
+		report(f.Name, f.Span)
+	}
+	for _, c := range q.Where {
+		report(c.Field, c.FieldSpan)
+	}
+	for _, o := range q.OrderBy {
+		report(o.Field, o.Span)
+	}
+	return findings
+}
+
+func checkMetricsWithoutDateSegment(q *gaql.Query, _ *gaql.Catalog) []Finding {
+	var metric *gaql.Field
+	for i, f := range q.Select {
+		if f.Name == "segments.date" {
+			return nil
+		}
+		if metric == nil && strings.HasPrefix(f.Name, "metrics.") {
+			metric = &q.Select[i]
+		}
+	}
+	if metric == nil {
+		return nil
+	}
+	return []Finding{{
+		Message: "metrics are aggregated over the whole date range; select segments.date for a daily breakdown",
+		Span:    metric.Span,
+	}}
+}
+
+func checkClickViewDateRange(q *gaql.Query, _ *gaql.Catalog) []Finding {
+	if q.From != "click_view" {
+		return nil
+	}
+	if days, ok := q.DateSpanDays(); ok && days <= 1 {
+		return nil
+	}
+	span := q.WhereSpan
+	for _, c := range q.Where {
+		if c.Field == "segments.date" {
+			span = c.Span
+			break
+		}
+	}
+	if !span.IsValid() {
+		span = q.FromSpan
+	}
+	return []Finding{{
+		Message: "click_view requires a single-day segments.date range (e.g. DURING YESTERDAY)",
+		Span:    span,
+	}}
+}
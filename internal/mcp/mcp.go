@@ -0,0 +1,297 @@
+// Package mcp is a minimal Model Context Protocol server over stdio.
+//
+// It speaks JSON-RPC 2.0, one message per line, and implements the parts
+// of the protocol needed to expose tools: initialize, ping, tools/list,
+// and tools/call. Resources, prompts, and sampling are not supported.
+//
+// # Basic Usage
+//
+//	s := mcp.NewServer("adtap", "0.1.0")
+//	s.AddTool(mcp.Tool{
+//		Name:        "echo",
+//		Description: "Return the input text",
+//		InputSchema: mcp.Object(map[string]any{"text": mcp.String("Text to return")}, "text"),
+//		ReadOnly:    true,
+//		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+//			var in struct{ Text string }
+//			err := mcp.Decode(args, &in)
+//			return in.Text, err
+//		},
+//	})
+//	err := s.Serve(ctx, os.Stdin, os.Stdout)
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the MCP revision the server implements.
+const ProtocolVersion = "2025-06-18"
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool is a function the server exposes to clients.
+type Tool struct {
+	Name        string
+	Description string
+
+	// InputSchema is the JSON Schema of the tool's arguments object.
+	InputSchema map[string]any
+
+	// ReadOnly marks tools that do not modify their environment; it is
+	// advertised to clients as the readOnlyHint annotation.
+	ReadOnly bool
+
+	// Handler runs the tool with the raw arguments object. A string
+	// result is returned as text; anything else is encoded as JSON. An
+	// error is reported to the client as a failed tool call, not a
+	// protocol error, so the model can see it and correct itself.
+	Handler func(ctx context.Context, args json.RawMessage) (any, error)
+}
+
+// Server dispatches MCP requests to registered tools.
+type Server struct {
+	name    string
+	version string
+
+	mu    sync.Mutex
+	tools map[string]Tool
+
+	requests, toolCalls, toolErrors atomic.Int64
+}
+
+// Stats counts the messages a Server has handled.
+type Stats struct {
+	Requests   int64 `json:"requests"`    // requests answered, including failures
+	ToolCalls  int64 `json:"tool_calls"`  // tools/call requests
+	ToolErrors int64 `json:"tool_errors"` // tool calls whose handler failed
+}
+
+// Stats returns the counts so far. It is safe to call while Serve runs.
+func (s *Server) Stats() Stats {
+	return Stats{
+		Requests:   s.requests.Load(),
+		ToolCalls:  s.toolCalls.Load(),
+		ToolErrors: s.toolErrors.Load(),
+	}
+}
+
+// NewServer returns a server that identifies itself as name and version.
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version, tools: make(map[string]Tool)}
+}
+
+// AddTool registers t, replacing any tool with the same name.
+func (s *Server) AddTool(t Tool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tools[t.Name] = t
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// Serve reads requests from r and writes responses to w until r is
+// exhausted or ctx is done. Requests are handled one at a time.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+	for sc.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		resp := s.handle(ctx, line)
+		if resp == nil {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return fmt.Errorf("mcp: writing response: %w", err)
+		}
+	}
+	return sc.Err()
+}
+
+// handle answers one message. Notifications get no response.
+func (s *Server) handle(ctx context.Context, line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error: " + err.Error()}}
+	}
+	if req.ID == nil {
+		return nil
+	}
+	s.requests.Add(1)
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &rpcError{codeInvalidRequest, "invalid request"}
+		return resp
+	}
+
+	result, err := s.dispatch(ctx, req.Method, req.Params)
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = &rpcError{codeInvalidParams, err.Error()}
+		}
+		resp.Error = rerr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": s.name, "version": s.version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.listTools()}, nil
+	case "tools/call":
+		var call struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &call); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid tools/call params: " + err.Error()}
+		}
+		return s.callTool(ctx, call.Name, call.Arguments)
+	default:
+		return nil, &rpcError{codeMethodNotFound, "method not found: " + method}
+	}
+}
+
+func (s *Server) listTools() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.tools))
+	for name := range s.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		t := s.tools[name]
+		out = append(out, map[string]any{
+			"name":        t.Name,
+			"description": t.Description,
+			"inputSchema": t.InputSchema,
+			"annotations": map[string]any{"readOnlyHint": t.ReadOnly},
+		})
+	}
+	return out
+}
+
+func (s *Server) callTool(ctx context.Context, name string, args json.RawMessage) (any, error) {
+	s.mu.Lock()
+	t, ok := s.tools[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, &rpcError{codeInvalidParams, "unknown tool: " + name}
+	}
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+
+	s.toolCalls.Add(1)
+	v, err := t.Handler(ctx, args)
+	if err != nil {
+		s.toolErrors.Add(1)
+		return toolResult(err.Error(), true), nil
+	}
+	text, ok := v.(string)
+	if !ok {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return toolResult("encoding result: "+err.Error(), true), nil
+		}
+		text = string(data)
+	}
+	return toolResult(text, false), nil
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// Decode unmarshals tool arguments into v, rejecting unknown fields so a
+// misspelled argument is reported instead of ignored.
+func Decode(args json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// Object returns the JSON Schema of an object with the given properties,
+// of which required must be present. Unknown properties are rejected.
+func Object(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// String returns the JSON Schema of a string property.
+func String(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+// Integer returns the JSON Schema of an integer property.
+func Integer(description string) map[string]any {
+	return map[string]any{"type": "integer", "description": description}
+}
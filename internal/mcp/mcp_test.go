@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestServer() *Server {
+	s := NewServer("adtap", "test")
+	s.AddTool(Tool{
+		Name:        "echo",
+		Description: "Return the input text",
+		InputSchema: Object(map[string]any{"text": String("Text to return")}, "text"),
+		ReadOnly:    true,
+		Handler: func(_ context.Context, args json.RawMessage) (any, error) {
+			var in struct {
+				Text string `json:"text"`
+			}
+			if err := Decode(args, &in); err != nil {
+				return nil, err
+			}
+			if in.Text == "" {
+				return nil, errors.New("text is required")
+			}
+			return map[string]string{"text": in.Text}, nil
+		},
+	})
+	return s
+}
+
+func TestServe(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // substring of the response line; "" expects none
+	}{
+		{
+			name: "initialize",
+			in:   `{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2025-06-18"}}`,
+			want: `"serverInfo":{"name":"adtap","version":"test"}`,
+		},
+		{
+			name: "initialized notification",
+			in:   `{"jsonrpc": "2.0", "method": "notifications/initialized"}`,
+		},
+		{
+			name: "tools/list",
+			in:   `{"jsonrpc": "2.0", "id": 2, "method": "tools/list"}`,
+			want: `"annotations":{"readOnlyHint":true},"description":"Return the input text","inputSchema":{"additionalProperties":false,"properties":{"text":{"description":"Text to return","type":"string"}},"required":["text"],"type":"object"},"name":"echo"`,
+		},
+		{
+			name: "tools/call",
+			in:   `{"jsonrpc": "2.0", "id": "a", "method": "tools/call", "params": {"name": "echo", "arguments": {"text": "hi"}}}`,
+			want: `"id":"a","result":{"content":[{"text":"{\n  \"text\": \"hi\"\n}","type":"text"}],"isError":false}`,
+		},
+		{
+			name: "tool error",
+			in:   `{"jsonrpc": "2.0", "id": 3, "method": "tools/call", "params": {"name": "echo", "arguments": {}}}`,
+			want: `"content":[{"text":"text is required","type":"text"}],"isError":true`,
+		},
+		{
+			name: "unknown argument",
+			in:   `{"jsonrpc": "2.0", "id": 4, "method": "tools/call", "params": {"name": "echo", "arguments": {"txt": "hi"}}}`,
+			want: `invalid arguments: json: unknown field \"txt\"`,
+		},
+		{
+			name: "unknown tool",
+			in:   `{"jsonrpc": "2.0", "id": 5, "method": "tools/call", "params": {"name": "mutate"}}`,
+			want: `"error":{"code":-32602,"message":"unknown tool: mutate"}`,
+		},
+		{
+			name: "unknown method",
+			in:   `{"jsonrpc": "2.0", "id": 6, "method": "resources/list"}`,
+			want: `"error":{"code":-32601,"message":"method not found: resources/list"}`,
+		},
+		{
+			name: "parse error",
+			in:   `{"jsonrpc": `,
+			want: `"id":null,"error":{"code":-32700`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := newTestServer().Serve(context.Background(), strings.NewReader(tt.in+"\n"), &out); err != nil {
+				t.Fatalf("Serve: %v", err)
+			}
+			got := out.String()
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("expected no response, got %s", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("response %s\ndoes not contain %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	in := strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize"}`,
+		`{"jsonrpc": "2.0", "method": "notifications/initialized"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "echo", "arguments": {"text": "hi"}}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "tools/call", "params": {"name": "echo", "arguments": {}}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "tools/call", "params": {"name": "mutate"}}`,
+	}, "\n")
+	s := newTestServer()
+	if err := s.Serve(context.Background(), strings.NewReader(in), io.Discard); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	if got, want := s.Stats(), (Stats{Requests: 4, ToolCalls: 2, ToolErrors: 1}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
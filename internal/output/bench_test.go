@@ -0,0 +1,79 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+// benchRows is the number of rows in the large result sets rendered by
+// the benchmarks, about what a month of daily campaign rows returns for
+// a mid-sized account.
+const benchRows = 10000
+
+// largeResult repeats the fixture rows up to benchRows, with distinct
+// IDs so no output can be shared between rows.
+func largeResult(b *testing.B) ([]string, []map[string]any) {
+	f := loadFixture(b)
+	rows := make([]map[string]any, benchRows)
+	for i := range rows {
+		src := f.Results[i%len(f.Results)]
+		row := make(map[string]any, len(src))
+		for k, v := range src {
+			row[k] = v
+		}
+		row["campaign"] = map[string]any{
+			"id":                     fmt.Sprint(1000000000 + i),
+			"name":                   fmt.Sprintf("Campaign %d", i),
+			"status":                 "ENABLED",
+			"advertisingChannelType": "SEARCH",
+		}
+		rows[i] = row
+	}
+	return f.Fields, rows
+}
+
+func BenchmarkValue(b *testing.B) {
+	fields, rows := largeResult(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		row := rows[i%len(rows)]
+		for _, f := range fields {
+			Value(row, f)
+		}
+	}
+}
+
+// BenchmarkWriteRows renders the large result set in every machine
+// format with the options the CLI uses; bytes per second are of output.
+func BenchmarkWriteRows(b *testing.B) {
+	fields, rows := largeResult(b)
+	for _, format := range []Format{FormatCSV, FormatTSV, FormatJSON, FormatJSONL} {
+		b.Run(string(format), func(b *testing.B) {
+			opts := Options{RawEnums: true}
+			var n countingWriter
+			r, _ := NewRenderer(&n, format)
+			if err := WriteRows(r, fields, rows, opts); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(n))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r, _ := NewRenderer(io.Discard, format)
+				if err := WriteRows(r, fields, rows, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// enumLabels overrides the generated label for values whose display name
+// is not their title-cased words.
+var enumLabels = map[string]string{
+	"MULTI_CHANNEL": "Multi-channel",
+	"NEAR_EXACT":    "Exact (close variant)",
+	"NEAR_PHRASE":   "Phrase (close variant)",
+}
+
+// enumWords holds words that keep a fixed spelling inside labels.
+var enumWords = map[string]string{
+	"API":     "API",
+	"CPA":     "CPA",
+	"CPC":     "CPC",
+	"CPM":     "CPM",
+	"CPV":     "CPV",
+	"GMAIL":   "Gmail",
+	"ROAS":    "ROAS",
+	"TV":      "TV",
+	"URL":     "URL",
+	"URLS":    "URLs",
+	"YOUTUBE": "YouTube",
+}
+
+// Proto numbers for enums that some sources report as integers. The REST
+// API itself returns names, so only commonly selected enums are listed.
+var (
+	entityStatusNumbers = map[int]string{2: "ENABLED", 3: "PAUSED", 4: "REMOVED"}
+
+	enumNumbers = map[string]map[int]string{
+		"campaign.status":           entityStatusNumbers,
+		"ad_group.status":           entityStatusNumbers,
+		"ad_group_ad.status":        entityStatusNumbers,
+		"ad_group_criterion.status": entityStatusNumbers,
+		"campaign_criterion.status": entityStatusNumbers,
+		"campaign.advertising_channel_type": {
+			2: "SEARCH", 3: "DISPLAY", 4: "SHOPPING", 5: "HOTEL", 6: "VIDEO",
+			7: "MULTI_CHANNEL", 8: "LOCAL", 9: "SMART", 10: "PERFORMANCE_MAX",
+			11: "LOCAL_SERVICES", 13: "TRAVEL", 14: "DEMAND_GEN",
+		},
+		"ad_group_criterion.keyword.match_type": {2: "EXACT", 3: "PHRASE", 4: "BROAD"},
+		"segments.day_of_week": {
+			2: "MONDAY", 3: "TUESDAY", 4: "WEDNESDAY", 5: "THURSDAY",
+			6: "FRIDAY", 7: "SATURDAY", 8: "SUNDAY",
+		},
+		"segments.device": {2: "MOBILE", 3: "TABLET", 4: "DESKTOP", 5: "OTHER", 6: "CONNECTED_TV"},
+	}
+)
+
+// EnumName returns the API name of an enum value of field. Names are
+// returned unchanged; integers are decoded where the field's numbering
+// is known. Every enum reserves 0 and 1 for UNSPECIFIED and UNKNOWN.
+func EnumName(field string, v any) string {
+	var n int
+	switch v := v.(type) {
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return v
+		}
+		n = i
+	case float64:
+		n = int(v)
+	case int:
+		n = v
+	case int64:
+		n = int(v)
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return v.String()
+		}
+		n = int(i)
+	default:
+		return fmt.Sprint(v)
+	}
+
+	switch n {
+	case 0:
+		return "UNSPECIFIED"
+	case 1:
+		return "UNKNOWN"
+	}
+	if name, ok := enumNumbers[field][n]; ok {
+		return name
+	}
+	return strconv.Itoa(n)
+}
+
+// EnumLabel returns the human-readable label for an enum value of field,
+// e.g. "Performance Max" for PERFORMANCE_MAX. Values that cannot be
+// decoded are returned as-is.
+func EnumLabel(field string, v any) string {
+	name := EnumName(field, v)
+	if label, ok := enumLabels[name]; ok {
+		return label
+	}
+	if name == "" || strings.ToUpper(name) != name {
+		return name
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return name
+	}
+
+	words := strings.Split(name, "_")
+	for i, w := range words {
+		if fixed, ok := enumWords[w]; ok {
+			words[i] = fixed
+		} else if w != "" {
+			words[i] = w[:1] + strings.ToLower(w[1:])
+		}
+	}
+	return strings.Join(words, " ")
+}
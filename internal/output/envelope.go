@@ -0,0 +1,185 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// EnvelopeRenderer writes rows together with the errors and metadata of
+// the query that produced them, so consumers can tell an empty result
+// from one that is empty or short because accounts failed.
+//
+// The JSON format is one object:
+//
+//	{"rows": [{...}, ...], "errors": [{...}, ...], "metadata": {...}}
+//
+// The JSONL format tags each line with its type, rows first and the
+// metadata always last, so a stream cut short lacks its metadata line:
+//
+//	{"type": "row", "row": {...}}
+//	{"type": "error", "error": {...}}
+//	{"type": "metadata", "metadata": {...}}
+//
+// A summary row, when written, follows the rows: as "summary_row" after
+// "rows", or as a line of type summary_row.
+//
+// Errors and Metadata are filled in by the caller before Flush, which
+// completes the output; errors is an empty array, never null.
+type EnvelopeRenderer struct {
+	Errors   []ResultError
+	Metadata Metadata
+
+	rows    jsonRenderer
+	summary []byte // the encoded summary row, if any
+	w       io.Writer
+}
+
+// ResultError is the failure of one account or shard of a query.
+type ResultError struct {
+	CustomerID string `json:"customer_id,omitempty"`
+	Status     string `json:"status,omitempty"` // API status, e.g. PERMISSION_DENIED
+	Message    string `json:"message"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+// Metadata describes the query behind an envelope.
+type Metadata struct {
+	Query          string `json:"query,omitempty"`
+	Accounts       int    `json:"accounts"`        // accounts queried
+	FailedAccounts int    `json:"failed_accounts"` // accounts with an error
+	Rows           int    `json:"rows"`            // rows written; set by Flush
+
+	// Truncated is set when rows were left out on purpose, such as by
+	// --max-rows.
+	Truncated bool `json:"truncated"`
+
+	// Interrupted is set when a signal stopped the query before its rows
+	// were all read.
+	Interrupted bool `json:"interrupted,omitempty"`
+
+	// TotalResultsCount is the number of results the API reports for
+	// the query, ignoring LIMIT, when it was asked for.
+	TotalResultsCount int64 `json:"total_results_count,omitempty"`
+
+	// RequestIDs are the Google Ads request IDs of the pages read, which
+	// API support asks for; Pages counts those pages and DurationMS is
+	// the time they took.
+	RequestIDs []string `json:"request_ids,omitempty"`
+	Pages      int      `json:"pages,omitempty"`
+	DurationMS int64    `json:"duration_ms,omitempty"`
+
+	// Complete reports that every account answered in full: no errors,
+	// no truncation, and no interruption. Set by Flush.
+	Complete bool `json:"complete"`
+}
+
+// NewEnvelopeRenderer returns an envelope renderer for FormatJSON or
+// FormatJSONL.
+func NewEnvelopeRenderer(w io.Writer, format Format) (*EnvelopeRenderer, error) {
+	switch format {
+	case FormatJSON:
+		return &EnvelopeRenderer{w: w, rows: jsonRenderer{w: w}}, nil
+	case FormatJSONL:
+		return &EnvelopeRenderer{w: w, rows: jsonRenderer{w: w, lines: true}}, nil
+	default:
+		return nil, fmt.Errorf("output: the envelope is written as json or jsonl, not %s", format)
+	}
+}
+
+func (e *EnvelopeRenderer) WriteHeader(columns []string) error {
+	return e.rows.WriteHeader(columns)
+}
+
+func (e *EnvelopeRenderer) WriteRow(values []string) error {
+	vs := make([]any, len(values))
+	for i, v := range values {
+		vs[i] = v
+	}
+	return e.WriteValues(vs)
+}
+
+func (e *EnvelopeRenderer) WriteValues(values []any) error {
+	obj, err := e.rows.object(values)
+	if err != nil {
+		return err
+	}
+	switch {
+	case e.rows.lines:
+		obj = append(append([]byte(`{"type":"row","row":`), obj...), "}\n"...)
+	case e.rows.rows == 0:
+		obj = append([]byte("{\"rows\": [\n  "), obj...)
+	default:
+		obj = append([]byte(",\n  "), obj...)
+	}
+	e.rows.rows++
+	_, err = e.w.Write(obj)
+	return err
+}
+
+// WriteSummaryValues keeps the summary row for Flush, which writes it
+// after the rows.
+func (e *EnvelopeRenderer) WriteSummaryValues(values []any) error {
+	obj, err := e.rows.object(values)
+	if err != nil {
+		return err
+	}
+	e.summary = obj
+	return nil
+}
+
+// Flush writes the summary row, errors, and metadata, completing the
+// envelope.
+func (e *EnvelopeRenderer) Flush() error {
+	m := e.Metadata
+	m.Rows = e.rows.rows
+	m.Complete = len(e.Errors) == 0 && !m.Truncated && !m.Interrupted
+	errs := e.Errors
+	if errs == nil {
+		errs = []ResultError{}
+	}
+
+	var buf bytes.Buffer
+	if e.rows.lines {
+		if e.summary != nil {
+			buf.WriteString(`{"type":"summary_row","summary_row":`)
+			buf.Write(e.summary)
+			buf.WriteString("}\n")
+		}
+		for _, re := range errs {
+			buf.WriteString(`{"type":"error","error":`)
+			if err := encodeJSON(&buf, re); err != nil {
+				return err
+			}
+			buf.WriteString("}\n")
+		}
+		buf.WriteString(`{"type":"metadata","metadata":`)
+		if err := encodeJSON(&buf, m); err != nil {
+			return err
+		}
+		buf.WriteString("}\n")
+	} else {
+		if e.rows.rows == 0 {
+			buf.WriteString(`{"rows": [],`)
+		} else {
+			buf.WriteString("\n],")
+		}
+		if e.summary != nil {
+			buf.WriteString("\n\"summary_row\": ")
+			buf.Write(e.summary)
+			buf.WriteByte(',')
+		}
+		buf.WriteString("\n\"errors\": ")
+		if err := encodeJSON(&buf, errs); err != nil {
+			return err
+		}
+		buf.WriteString(",\n\"metadata\": ")
+		if err := encodeJSON(&buf, m); err != nil {
+			return err
+		}
+		buf.WriteString("}\n")
+	}
+	e.rows.rows, e.summary = 0, nil
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,130 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEnvelopeRenderer(t *testing.T) {
+	fields := []string{"customer.id", "metrics.clicks"}
+	rows := []map[string]any{
+		{"customer": map[string]any{"id": "1234567890"}, "metrics": map[string]any{"clicks": "7"}},
+	}
+	failure := ResultError{CustomerID: "9876543210", Status: "PERMISSION_DENIED", Message: "denied"}
+
+	tests := []struct {
+		name        string
+		rows        []map[string]any
+		errors      []ResultError
+		interrupted bool
+		complete    bool
+	}{
+		{"complete", rows, nil, false, true},
+		{"empty", nil, nil, false, true},
+		{"partial", rows, []ResultError{failure}, false, false},
+		{"all failed", nil, []ResultError{failure}, false, false},
+		{"interrupted", rows, nil, true, false},
+	}
+	for _, tt := range tests {
+		for _, format := range []Format{FormatJSON, FormatJSONL} {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				var buf bytes.Buffer
+				r, err := NewEnvelopeRenderer(&buf, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r.Errors = tt.errors
+				r.Metadata = Metadata{Query: "SELECT customer.id, metrics.clicks FROM customer", Accounts: 2, FailedAccounts: len(tt.errors), Interrupted: tt.interrupted}
+				if err := WriteRows(r, fields, tt.rows, Options{}); err != nil {
+					t.Fatal(err)
+				}
+
+				var env struct {
+					Rows     []map[string]any `json:"rows"`
+					Errors   []ResultError    `json:"errors"`
+					Metadata *Metadata        `json:"metadata"`
+				}
+				if format == FormatJSON {
+					if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+						t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+					}
+					if env.Errors == nil {
+						t.Error("errors must be an array, not null")
+					}
+				} else {
+					lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+					for i, line := range lines {
+						var l struct {
+							Type     string         `json:"type"`
+							Row      map[string]any `json:"row"`
+							Error    ResultError    `json:"error"`
+							Metadata *Metadata      `json:"metadata"`
+						}
+						if err := json.Unmarshal([]byte(line), &l); err != nil {
+							t.Fatalf("invalid line %q: %v", line, err)
+						}
+						if (l.Type == "metadata") != (i == len(lines)-1) {
+							t.Errorf("line %d has type %s; metadata must come last", i, l.Type)
+						}
+						switch l.Type {
+						case "row":
+							env.Rows = append(env.Rows, l.Row)
+						case "error":
+							env.Errors = append(env.Errors, l.Error)
+						case "metadata":
+							env.Metadata = l.Metadata
+						}
+					}
+				}
+				if len(env.Rows) != len(tt.rows) || len(env.Errors) != len(tt.errors) {
+					t.Errorf("got %d rows and %d errors, want %d and %d", len(env.Rows), len(env.Errors), len(tt.rows), len(tt.errors))
+				}
+				if m := env.Metadata; m == nil || m.Rows != len(tt.rows) || m.Complete != tt.complete || m.Interrupted != tt.interrupted || m.Accounts != 2 {
+					t.Errorf("unexpected metadata %+v", m)
+				}
+			})
+		}
+	}
+
+	if _, err := NewEnvelopeRenderer(&bytes.Buffer{}, FormatCSV); err == nil {
+		t.Error("expected an error for csv")
+	}
+}
+
+func TestEnvelopeSummaryRow(t *testing.T) {
+	fields := []string{"customer.id", "metrics.clicks"}
+	tests := []struct {
+		format Format
+		want   string // the summary row's part of the output
+	}{
+		{FormatJSON, "\n\"summary_row\": {\"customer.id\":null,\"metrics.clicks\":7},\n\"errors\""},
+		{FormatJSONL, "\n{\"type\":\"summary_row\",\"summary_row\":{\"customer.id\":null,\"metrics.clicks\":7}}\n{\"type\":\"metadata\""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			r, _ := NewEnvelopeRenderer(&buf, tt.format)
+			r.Metadata.TotalResultsCount = 1
+			opts := Options{}
+			r.WriteHeader(fields)
+			opts.WriteRecord(r, fields, []any{"1234567890", "7"})
+			if err := opts.WriteSummary(r, fields, []any{nil, "7"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output lacks %q:\n%s", tt.want, buf.String())
+			}
+			if !strings.Contains(buf.String(), `"total_results_count":1`) {
+				t.Errorf("metadata lacks the total results count:\n%s", buf.String())
+			}
+			if tt.format == FormatJSON && !json.Valid(buf.Bytes()) {
+				t.Errorf("invalid JSON:\n%s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/golden"
+)
+
+// fixture is a canned result set in the shape the REST API returns.
+type fixture struct {
+	Fields  []string         `json:"fields"`
+	Results []map[string]any `json:"results"`
+}
+
+func loadFixture(t testing.TB) fixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/results.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// TestGolden renders the fixture through every format with the options
+// the CLI uses, and once more with micros converted, comparing each
+// against testdata/golden/<variant>.<format>.
+func TestGolden(t *testing.T) {
+	f := loadFixture(t)
+	geo := constants{
+		"geoTargetConstants/2840": "United States",
+		"geoTargetConstants/2276": "Germany",
+	}
+
+	variants := []struct {
+		name string
+		opts func(Format) Options
+	}{
+		{"default", func(format Format) Options {
+			return Options{RawEnums: !format.Human(), Constants: geo}
+		}},
+		{"micros", func(format Format) Options {
+			return Options{RawEnums: !format.Human(), Constants: geo, Micros: true}
+		}},
+	}
+
+	for _, v := range variants {
+		for _, format := range Formats {
+			t.Run(v.name+"/"+string(format), func(t *testing.T) {
+				var buf bytes.Buffer
+				r, err := NewRenderer(&buf, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := WriteRows(r, f.Fields, f.Results, v.opts(format)); err != nil {
+					t.Fatalf("WriteRows: %v", err)
+				}
+				golden.Assert(t, filepath.Join("testdata", "golden", v.name+"."+string(format)), buf.Bytes())
+			})
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// valueWriter is implemented by renderers that keep values typed rather
+// than converting them to display strings, so JSON numbers stay numbers.
+type valueWriter interface {
+	WriteValues(values []any) error
+}
+
+// jsonRenderer writes a JSON array of objects keyed by column, or one
+// object per line when lines is set.
+type jsonRenderer struct {
+	w       io.Writer
+	lines   bool
+	columns []string
+	rows    int
+}
+
+func (j *jsonRenderer) WriteHeader(columns []string) error {
+	j.columns = append([]string(nil), columns...)
+	return nil
+}
+
+func (j *jsonRenderer) WriteRow(values []string) error {
+	vs := make([]any, len(values))
+	for i, v := range values {
+		vs[i] = v
+	}
+	return j.WriteValues(vs)
+}
+
+func (j *jsonRenderer) WriteValues(values []any) error {
+	obj, err := j.object(values)
+	if err != nil {
+		return err
+	}
+	switch {
+	case j.lines:
+		obj = append(obj, '\n')
+	case j.rows == 0:
+		obj = append([]byte("[\n  "), obj...)
+	default:
+		obj = append([]byte(",\n  "), obj...)
+	}
+	j.rows++
+	_, err = j.w.Write(obj)
+	return err
+}
+
+// WriteSummaryValues writes the summary row as {"summary_row": {...}},
+// the last element of the array or line of the stream.
+func (j *jsonRenderer) WriteSummaryValues(values []any) error {
+	obj, err := j.object(values)
+	if err != nil {
+		return err
+	}
+	obj = append(append([]byte(`{"summary_row":`), obj...), '}')
+	switch {
+	case j.lines:
+		obj = append(obj, '\n')
+	case j.rows == 0:
+		obj = append([]byte("[\n  "), obj...)
+	default:
+		obj = append([]byte(",\n  "), obj...)
+	}
+	j.rows++
+	_, err = j.w.Write(obj)
+	return err
+}
+
+// object encodes values as a JSON object with keys in column order,
+// which encoding/json does not preserve for maps.
+func (j *jsonRenderer) object(values []any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, col := range j.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		var v any
+		if i < len(values) {
+			v = values[i]
+		}
+		if err := encodeJSON(&buf, col); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := encodeJSON(&buf, v); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeJSON appends v to buf without escaping <, >, and &, which would
+// only obscure campaign names in output meant for terminals and jq.
+func encodeJSON(buf *bytes.Buffer, v any) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline
+	return nil
+}
+
+func (j *jsonRenderer) Flush() error {
+	if j.lines {
+		return nil
+	}
+	end := "\n]\n"
+	if j.rows == 0 {
+		end = "[]\n"
+	}
+	j.rows = 0
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// csvRenderer writes comma- or tab-separated values with a header row.
+type csvRenderer struct {
+	w *csv.Writer
+}
+
+func newCSVRenderer(w io.Writer, comma rune) *csvRenderer {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &csvRenderer{w: cw}
+}
+
+func (c *csvRenderer) WriteHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvRenderer) WriteRow(values []string) error {
+	return c.w.Write(values)
+}
+
+func (c *csvRenderer) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
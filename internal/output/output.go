@@ -0,0 +1,484 @@
+// Package output renders search results for people and programs.
+//
+// Rows are the nested JSON objects returned by the Google Ads REST API;
+// columns are the GAQL field names from the query's SELECT clause. A
+// Renderer receives display strings one row at a time, so commands can
+// stream results without caring about the target format.
+//
+// # Basic Usage
+//
+//	r, err := output.NewRenderer(os.Stdout, output.FormatTable)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = output.WriteRows(r, fields, rows, output.Options{})
+//
+// # Formats
+//
+// Table (the default), Markdown, and HTML are for people. JSON writes an
+// array of objects keyed by field name, JSONL one object per line for
+// piping into jq, and CSV and TSV a header row followed by the values.
+// JSON formats keep numbers typed; INT64 fields, which the API sends as
+// strings, become JSON numbers.
+//
+// # Envelope
+//
+// EnvelopeRenderer writes JSON or JSONL rows together with the errors and
+// metadata of the query, so a result that is short because accounts
+// failed can be told from one that is legitimately empty.
+//
+// # Enum Labels
+//
+// Human-facing formats show enum values as labels, so
+// campaign.advertising_channel_type renders PERFORMANCE_MAX as
+// "Performance Max". Set Options.RawEnums to keep the API values;
+// commands do so by default for formats where Format.Human is false.
+//
+// # Micros
+//
+// Options.Micros converts amounts in micros of the account currency to
+// currency units, so metrics.cost_micros 1234560000 becomes the column
+// metrics.cost with value 1234.56.
+//
+// # Constants
+//
+// Setting Options.Constants (usually geo.Default()) replaces geo target
+// and language IDs with their names.
+//
+// # Summary Rows
+//
+// Options.WriteSummary writes the API's summary row after the rows, set
+// apart from them: Table and HTML separate it as a footer, Markdown bolds
+// it, and CSV and TSV label its first column Total. JSON and JSONL write
+// it as an object of its own, {"summary_row": {...}}, so it cannot be
+// mistaken for a result.
+//
+// # Summary Footer
+//
+// A Summary counts rows as they stream past and, optionally, totals
+// additive metrics and computes weighted averages of ratio metrics (CTR
+// weighted by impressions, average CPC weighted by clicks):
+//
+//	sum := output.NewSummary(fields, true)
+//	for _, row := range rows {
+//		sum.Add(row)
+//	}
+//	sum.WriteTo(os.Stdout)
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+)
+
+// Format names an output format.
+type Format string
+
+const (
+	FormatTable    Format = "table"
+	FormatJSON     Format = "json"
+	FormatJSONL    Format = "jsonl"
+	FormatCSV      Format = "csv"
+	FormatTSV      Format = "tsv"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+// Formats lists the supported formats in display order.
+var Formats = []Format{FormatTable, FormatJSON, FormatJSONL, FormatCSV, FormatTSV, FormatMarkdown, FormatHTML}
+
+// Human reports whether the format is meant to be read by people rather
+// than parsed by programs. Human formats show enum labels by default.
+func (f Format) Human() bool {
+	switch f {
+	case FormatTable, FormatMarkdown, FormatHTML:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseFormat returns the Format named by s.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("output: invalid format %q (expected %s)", s, strings.Join(names, ", "))
+}
+
+// Renderer writes a header followed by rows of display strings.
+type Renderer interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []string) error
+
+	// Flush writes any buffered output. Formats that align columns
+	// buffer every row until Flush.
+	Flush() error
+}
+
+// NewRenderer returns a renderer for format writing to w.
+func NewRenderer(w io.Writer, format Format) (Renderer, error) {
+	switch format {
+	case FormatTable:
+		return &tableRenderer{w: w}, nil
+	case FormatJSON:
+		return &jsonRenderer{w: w}, nil
+	case FormatJSONL:
+		return &jsonRenderer{w: w, lines: true}, nil
+	case FormatCSV:
+		return newCSVRenderer(w, ','), nil
+	case FormatTSV:
+		return newCSVRenderer(w, '\t'), nil
+	case FormatMarkdown:
+		return &markdownRenderer{w: w}, nil
+	case FormatHTML:
+		return &htmlRenderer{w: w}, nil
+	default:
+		return nil, fmt.Errorf("output: unsupported format %q", format)
+	}
+}
+
+// Options controls how values are converted to display strings.
+type Options struct {
+	// RawEnums keeps enum values as returned by the API instead of
+	// converting them to labels.
+	RawEnums bool
+
+	// Catalog identifies enum fields. Nil uses gaql.DefaultCatalog.
+	Catalog *gaql.Catalog
+
+	// Constants, when set, replaces geo target and language constants
+	// with their names, e.g. "geoTargetConstants/2840" becomes
+	// "United States (2840)".
+	Constants ConstantResolver
+
+	// Micros converts amounts in micros (cost_micros, average_cpc, ...)
+	// to currency units. Columns named *_micros lose the suffix.
+	Micros bool
+
+	// Rename maps fields to the column names written in their place,
+	// e.g. "metrics.cost_micros" to "Cost".
+	Rename map[string]string
+
+	// Redact, when set, reports the fields whose values are written as
+	// Redacted, such as those an access policy hides.
+	Redact func(field string) bool
+}
+
+// Redacted replaces the values of the fields Options.Redact hides.
+const Redacted = "[redacted]"
+
+// microsFields are amounts in micros whose names do not say so.
+var microsFields = map[string]bool{
+	"metrics.active_view_cpm":                              true,
+	"metrics.average_cost":                                 true,
+	"metrics.average_cpc":                                  true,
+	"metrics.average_cpe":                                  true,
+	"metrics.average_cpm":                                  true,
+	"metrics.average_cpv":                                  true,
+	"metrics.cost_per_all_conversions":                     true,
+	"metrics.cost_per_conversion":                          true,
+	"metrics.cost_per_current_model_attributed_conversion": true,
+}
+
+// IsMicros reports whether field holds an amount in micros of the
+// account currency.
+func IsMicros(field string) bool {
+	return strings.HasSuffix(field, "_micros") || microsFields[field]
+}
+
+// Column returns the header for field.
+func (o Options) Column(field string) string {
+	if name, ok := o.Rename[field]; ok {
+		return name
+	}
+	if o.Micros {
+		return strings.TrimSuffix(field, "_micros")
+	}
+	return field
+}
+
+// Columns returns the headers for fields.
+func (o Options) Columns(fields []string) []string {
+	out := make([]string, len(fields))
+	for i, f := range fields {
+		out[i] = o.Column(f)
+	}
+	return out
+}
+
+// ConstantResolver names API constants by resource name. geo.Table
+// implements it.
+type ConstantResolver interface {
+	ResolveConstant(resourceName string) (string, bool)
+}
+
+// constantIDFields are fields holding a bare criterion ID, mapped to the
+// resource name prefix of the constant they identify.
+var constantIDFields = map[string]string{
+	"geographic_view.country_criterion_id":    "geoTargetConstants/",
+	"user_location_view.country_criterion_id": "geoTargetConstants/",
+}
+
+// Cell converts the value of field to its display string.
+func (o Options) Cell(field string, v any) string {
+	if v == nil {
+		return ""
+	}
+	if o.Redact != nil && o.Redact(field) {
+		return Redacted
+	}
+	if o.Constants != nil {
+		if name, ok := o.constantName(field, v); ok {
+			return name
+		}
+	}
+	if !o.RawEnums && o.isEnum(field) {
+		return EnumLabel(field, v)
+	}
+	if o.Micros && IsMicros(field) {
+		if f, ok := toFloat(v); ok {
+			return strconv.FormatFloat(f/1e6, 'f', 2, 64)
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = o.Cell(field, item)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// constantName resolves v when it is a constant resource name, or a bare
+// criterion ID in one of constantIDFields.
+func (o Options) constantName(field string, v any) (string, bool) {
+	var resourceName string
+	switch v := v.(type) {
+	case string:
+		resourceName = v
+		if prefix, ok := constantIDFields[field]; ok {
+			resourceName = prefix + v
+		}
+	case float64:
+		prefix, ok := constantIDFields[field]
+		if !ok {
+			return "", false
+		}
+		resourceName = prefix + strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return "", false
+	}
+
+	name, ok := o.Constants.ResolveConstant(resourceName)
+	if !ok {
+		return "", false
+	}
+	_, id, _ := strings.Cut(resourceName, "/")
+	return name + " (" + id + ")", true
+}
+
+func (o Options) isEnum(field string) bool {
+	return o.dataType(field) == "ENUM"
+}
+
+func (o Options) isInt64(field string) bool {
+	return o.dataType(field) == "INT64"
+}
+
+func (o Options) dataType(field string) string {
+	cat := o.Catalog
+	if cat == nil {
+		cat = gaql.DefaultCatalog()
+	}
+	info, _ := cat.Field(field)
+	return info.DataType
+}
+
+// toFloat converts a numeric value of any representation rows carry.
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// Typed converts the value of field for formats that keep types, such
+// as JSON. It applies the same constant, enum, and micros conversions as
+// Cell, and turns INT64 fields, which the API sends as strings, into
+// numbers.
+func (o Options) Typed(field string, v any) any {
+	if v == nil {
+		return nil
+	}
+	if o.Redact != nil && o.Redact(field) {
+		return Redacted
+	}
+	if o.Constants != nil {
+		if name, ok := o.constantName(field, v); ok {
+			return name
+		}
+	}
+	if !o.RawEnums && o.isEnum(field) {
+		return EnumLabel(field, v)
+	}
+	if o.Micros && IsMicros(field) {
+		if f, ok := toFloat(v); ok {
+			return f / 1e6
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		if o.isInt64(field) {
+			if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return json.Number(v)
+			}
+		}
+	case float64:
+		// JSON has no infinities or NaN.
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = o.Typed(field, item)
+		}
+		return out
+	}
+	return v
+}
+
+// WriteRecord writes one row of raw values, one per field. Typed
+// renderers receive Typed values, the others Cell strings.
+func (o Options) WriteRecord(r Renderer, fields []string, values []any) error {
+	if vw, ok := r.(valueWriter); ok {
+		typed := make([]any, len(fields))
+		for i, f := range fields {
+			typed[i] = o.Typed(f, values[i])
+		}
+		return vw.WriteValues(typed)
+	}
+	cells := make([]string, len(fields))
+	for i, f := range fields {
+		cells[i] = o.Cell(f, values[i])
+	}
+	return r.WriteRow(cells)
+}
+
+// SummaryLabel marks the summary row in its first column when the first
+// field has no value there, as every field but the metrics has not.
+const SummaryLabel = "Total"
+
+// summaryWriter is implemented by renderers that set the summary row
+// apart from the other rows.
+type summaryWriter interface {
+	WriteSummary(values []string) error
+}
+
+// summaryValueWriter is a summaryWriter for renderers that keep values
+// typed.
+type summaryValueWriter interface {
+	WriteSummaryValues(values []any) error
+}
+
+// WriteSummary writes the summary row of raw values, one per field,
+// after the last WriteRecord and before Flush. Renderers that cannot set
+// it apart write it as one more record.
+func (o Options) WriteSummary(r Renderer, fields []string, values []any) error {
+	if _, ok := r.(valueWriter); ok {
+		typed := make([]any, len(fields))
+		for i, f := range fields {
+			typed[i] = o.Typed(f, values[i])
+		}
+		if sw, ok := r.(summaryValueWriter); ok {
+			return sw.WriteSummaryValues(typed)
+		}
+		return r.(valueWriter).WriteValues(typed)
+	}
+	cells := make([]string, len(fields))
+	for i, f := range fields {
+		cells[i] = o.Cell(f, values[i])
+	}
+	if len(cells) > 0 && cells[0] == "" {
+		cells[0] = SummaryLabel
+	}
+	if sw, ok := r.(summaryWriter); ok {
+		return sw.WriteSummary(cells)
+	}
+	return r.WriteRow(cells)
+}
+
+// WriteRows renders rows with one column per field, then flushes r.
+func WriteRows(r Renderer, fields []string, rows []map[string]any, opts Options) error {
+	if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+		return err
+	}
+	flat := rowflat.Compile(fields)
+	values := make([]any, len(fields))
+	for _, row := range rows {
+		flat.Values(row, values)
+		if err := opts.WriteRecord(r, fields, values); err != nil {
+			return err
+		}
+	}
+	return r.Flush()
+}
+
+// Value looks up a GAQL field in a REST result row. Field path segments
+// are snake_case while the JSON keys are lowerCamelCase, so
+// "campaign.advertising_channel_type" reads
+// row["campaign"]["advertisingChannelType"]. It is rowflat.Lookup.
+func Value(row map[string]any, field string) (any, bool) {
+	return rowflat.Lookup(row, field)
+}
+
+// SetValue sets a GAQL field in a REST result row, creating nested
+// objects as needed. It is the inverse of Value.
+func SetValue(row map[string]any, field string, v any) {
+	parts := strings.Split(field, ".")
+	m := row
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[rowflat.JSONName(part)].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			m[rowflat.JSONName(part)] = next
+		}
+		m = next
+	}
+	m[rowflat.JSONName(parts[len(parts)-1])] = v
+}
@@ -0,0 +1,289 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEnumLabel(t *testing.T) {
+	tests := []struct {
+		field string
+		value any
+		want  string
+	}{
+		{"campaign.advertising_channel_type", "PERFORMANCE_MAX", "Performance Max"},
+		{"campaign.advertising_channel_type", "MULTI_CHANNEL", "Multi-channel"},
+		{"campaign.advertising_channel_type", float64(10), "Performance Max"},
+		{"campaign.advertising_channel_type", "2", "Search"},
+		{"campaign.bidding_strategy_type", "TARGET_ROAS", "Target ROAS"},
+		{"campaign.status", float64(3), "Paused"},
+		{"campaign.status", float64(0), "Unspecified"},
+		{"segments.device", "CONNECTED_TV", "Connected TV"},
+		{"segments.day_of_week", 8, "Sunday"},
+		{"campaign.serving_status", float64(42), "42"},
+		{"campaign.name", "Mixed Case", "Mixed Case"},
+	}
+	for _, tt := range tests {
+		if got := EnumLabel(tt.field, tt.value); got != tt.want {
+			t.Errorf("EnumLabel(%s, %v) = %q, want %q", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteRows(t *testing.T) {
+	fields := []string{"campaign.name", "campaign.advertising_channel_type", "metrics.clicks"}
+	rows := []map[string]any{
+		{
+			"campaign": map[string]any{"name": "Brand | US", "advertisingChannelType": "PERFORMANCE_MAX"},
+			"metrics":  map[string]any{"clicks": "120"},
+		},
+		{
+			"campaign": map[string]any{"name": "<Generic>", "advertisingChannelType": "SEARCH"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+		opts   Options
+		want   string
+	}{
+		{
+			name:   "table",
+			format: FormatTable,
+			want: "campaign.name  campaign.advertising_channel_type  metrics.clicks\n" +
+				"-------------  ---------------------------------  --------------\n" +
+				"Brand | US     Performance Max                    120\n" +
+				"<Generic>      Search                             \n",
+		},
+		{
+			name:   "table raw enums",
+			format: FormatTable,
+			opts:   Options{RawEnums: true},
+			want: "campaign.name  campaign.advertising_channel_type  metrics.clicks\n" +
+				"-------------  ---------------------------------  --------------\n" +
+				"Brand | US     PERFORMANCE_MAX                    120\n" +
+				"<Generic>      SEARCH                             \n",
+		},
+		{
+			name:   "markdown",
+			format: FormatMarkdown,
+			want: "| campaign.name | campaign.advertising_channel_type | metrics.clicks |\n" +
+				"| --- | --- | --- |\n" +
+				"| Brand \\| US | Performance Max | 120 |\n" +
+				"| <Generic> | Search |  |\n",
+		},
+		{
+			name:   "html",
+			format: FormatHTML,
+			want: "<table>\n<thead>\n" +
+				"<tr><th>campaign.name</th><th>campaign.advertising_channel_type</th><th>metrics.clicks</th></tr>\n" +
+				"</thead>\n<tbody>\n" +
+				"<tr><td>Brand | US</td><td>Performance Max</td><td>120</td></tr>\n" +
+				"<tr><td>&lt;Generic&gt;</td><td>Search</td><td></td></tr>\n" +
+				"</tbody>\n</table>\n",
+		},
+		{
+			name:   "json",
+			format: FormatJSON,
+			opts:   Options{RawEnums: true},
+			want: "[\n" +
+				`  {"campaign.name":"Brand | US","campaign.advertising_channel_type":"PERFORMANCE_MAX","metrics.clicks":120},` + "\n" +
+				`  {"campaign.name":"<Generic>","campaign.advertising_channel_type":"SEARCH","metrics.clicks":null}` + "\n" +
+				"]\n",
+		},
+		{
+			name:   "jsonl",
+			format: FormatJSONL,
+			want: `{"campaign.name":"Brand | US","campaign.advertising_channel_type":"Performance Max","metrics.clicks":120}` + "\n" +
+				`{"campaign.name":"<Generic>","campaign.advertising_channel_type":"Search","metrics.clicks":null}` + "\n",
+		},
+		{
+			name:   "csv",
+			format: FormatCSV,
+			opts:   Options{RawEnums: true},
+			want: "campaign.name,campaign.advertising_channel_type,metrics.clicks\n" +
+				"Brand | US,PERFORMANCE_MAX,120\n" +
+				"<Generic>,SEARCH,\n",
+		},
+		{
+			name:   "tsv",
+			format: FormatTSV,
+			opts:   Options{RawEnums: true},
+			want: "campaign.name\tcampaign.advertising_channel_type\tmetrics.clicks\n" +
+				"Brand | US\tPERFORMANCE_MAX\t120\n" +
+				"<Generic>\tSEARCH\t\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r, err := NewRenderer(&buf, tt.format)
+			if err != nil {
+				t.Fatalf("NewRenderer: %v", err)
+			}
+			if err := WriteRows(r, fields, rows, tt.opts); err != nil {
+				t.Fatalf("WriteRows: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+type constants map[string]string
+
+func (c constants) ResolveConstant(resourceName string) (string, bool) {
+	name, ok := c[resourceName]
+	return name, ok
+}
+
+func TestCellConstants(t *testing.T) {
+	opts := Options{Constants: constants{
+		"geoTargetConstants/2840": "United States",
+		"languageConstants/1000":  "English",
+	}}
+	tests := []struct {
+		field string
+		value any
+		want  string
+	}{
+		{"segments.geo_target_country", "geoTargetConstants/2840", "United States (2840)"},
+		{"campaign_criterion.language.language_constant", "languageConstants/1000", "English (1000)"},
+		{"geographic_view.country_criterion_id", "2840", "United States (2840)"},
+		{"geographic_view.country_criterion_id", float64(2840), "United States (2840)"},
+		{"segments.geo_target_city", "geoTargetConstants/1018127", "geoTargetConstants/1018127"},
+		{"campaign.id", "2840", "2840"},
+	}
+	for _, tt := range tests {
+		if got := opts.Cell(tt.field, tt.value); got != tt.want {
+			t.Errorf("Cell(%s, %v) = %q, want %q", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteRowsEmptyJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r, _ := NewRenderer(&buf, FormatJSON)
+	if err := WriteRows(r, []string{"campaign.id"}, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("got %q, want empty array", buf.String())
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	fields := []string{"campaign.name", "metrics.clicks"}
+	rows := []map[string]any{
+		{"campaign": map[string]any{"name": "Brand"}, "metrics": map[string]any{"clicks": "3"}},
+		{"campaign": map[string]any{"name": "Generic"}, "metrics": map[string]any{"clicks": "12"}},
+	}
+	summary := []any{nil, "15"}
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatTable, "campaign.name  metrics.clicks\n" +
+			"-------------  --------------\n" +
+			"Brand          3\n" +
+			"Generic        12\n" +
+			"-------------  --------------\n" +
+			"Total          15\n"},
+		{FormatJSON, "[\n  {\"campaign.name\":\"Brand\",\"metrics.clicks\":3},\n" +
+			"  {\"campaign.name\":\"Generic\",\"metrics.clicks\":12},\n" +
+			"  {\"summary_row\":{\"campaign.name\":null,\"metrics.clicks\":15}}\n]\n"},
+		{FormatJSONL, "{\"campaign.name\":\"Brand\",\"metrics.clicks\":3}\n" +
+			"{\"campaign.name\":\"Generic\",\"metrics.clicks\":12}\n" +
+			"{\"summary_row\":{\"campaign.name\":null,\"metrics.clicks\":15}}\n"},
+		{FormatCSV, "campaign.name,metrics.clicks\nBrand,3\nGeneric,12\nTotal,15\n"},
+		{FormatMarkdown, "| campaign.name | metrics.clicks |\n| --- | --- |\n| Brand | 3 |\n| Generic | 12 |\n| **Total** | **15** |\n"},
+		{FormatHTML, "<table>\n<thead>\n<tr><th>campaign.name</th><th>metrics.clicks</th></tr>\n</thead>\n<tbody>\n" +
+			"<tr><td>Brand</td><td>3</td></tr>\n<tr><td>Generic</td><td>12</td></tr>\n" +
+			"</tbody>\n<tfoot>\n<tr><td>Total</td><td>15</td></tr>\n</tfoot>\n</table>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			r, _ := NewRenderer(&buf, tt.format)
+			opts := Options{}
+			if err := r.WriteHeader(opts.Columns(fields)); err != nil {
+				t.Fatal(err)
+			}
+			values := make([]any, len(fields))
+			for _, row := range rows {
+				for i, f := range fields {
+					values[i], _ = Value(row, f)
+				}
+				if err := opts.WriteRecord(r, fields, values); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := opts.WriteSummary(r, fields, summary); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMicros(t *testing.T) {
+	fields := []string{"metrics.cost_micros", "metrics.average_cpc", "campaign.id"}
+	rows := []map[string]any{{
+		"metrics":  map[string]any{"costMicros": "1234560000", "averageCpc": 1500000.0},
+		"campaign": map[string]any{"id": "42"},
+	}}
+	opts := Options{Micros: true}
+
+	var buf bytes.Buffer
+	r, _ := NewRenderer(&buf, FormatCSV)
+	if err := WriteRows(r, fields, rows, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "metrics.cost,metrics.average_cpc,campaign.id\n1234.56,1.50,42\n"
+	if buf.String() != want {
+		t.Errorf("csv got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	r, _ = NewRenderer(&buf, FormatJSONL)
+	if err := WriteRows(r, fields, rows, opts); err != nil {
+		t.Fatal(err)
+	}
+	want = `{"metrics.cost":1234.56,"metrics.average_cpc":1.5,"campaign.id":42}` + "\n"
+	if buf.String() != want {
+		t.Errorf("jsonl got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	fields := []string{"user_list.id", "user_list.name"}
+	rows := []map[string]any{{"userList": map[string]any{"id": "7", "name": "Past buyers"}}}
+	opts := Options{Redact: func(field string) bool { return field == "user_list.name" }}
+
+	var buf bytes.Buffer
+	r, _ := NewRenderer(&buf, FormatJSONL)
+	if err := WriteRows(r, fields, rows, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"user_list.id":"7","user_list.name":"[redacted]"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %s, want %s", buf.String(), want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("Markdown"); err != nil || f != FormatMarkdown {
+		t.Errorf("ParseFormat(Markdown) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,159 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// additiveMetrics can be summed across rows.
+var additiveMetrics = map[string]bool{
+	"metrics.all_conversions":          true,
+	"metrics.all_conversions_value":    true,
+	"metrics.clicks":                   true,
+	"metrics.conversions":              true,
+	"metrics.conversions_value":        true,
+	"metrics.cost_micros":              true,
+	"metrics.engagements":              true,
+	"metrics.impressions":              true,
+	"metrics.interactions":             true,
+	"metrics.view_through_conversions": true,
+}
+
+// ratioWeights maps ratio metrics to the metric they are averaged over.
+// Weighting by it reproduces the account-level value: the CTR of several
+// rows is their clicks over their impressions, not the mean of their
+// CTRs.
+var ratioWeights = map[string]string{
+	"metrics.average_cost":                       "metrics.interactions",
+	"metrics.average_cpc":                        "metrics.clicks",
+	"metrics.average_cpm":                        "metrics.impressions",
+	"metrics.conversions_from_interactions_rate": "metrics.interactions",
+	"metrics.cost_per_conversion":                "metrics.conversions",
+	"metrics.ctr":                                "metrics.impressions",
+	"metrics.interaction_rate":                   "metrics.impressions",
+	"metrics.value_per_conversion":               "metrics.conversions",
+}
+
+// Stat is one column statistic in a summary footer.
+type Stat struct {
+	Field string
+	Value float64
+
+	// WeightedBy names the metric a weighted average is taken over.
+	// Empty for totals.
+	WeightedBy string
+}
+
+// Summary accumulates a footer for a result stream one row at a time, so
+// totals never require buffering the rows themselves.
+type Summary struct {
+	Rows int
+
+	fields   []string
+	stats    bool
+	sums     map[string]float64
+	weighted map[string]*[2]float64 // numerator, denominator
+}
+
+// NewSummary creates a summary of rows with the given selected fields.
+// With stats set, it also totals additive metrics and computes weighted
+// averages of ratio metrics whose weight metric is selected.
+func NewSummary(fields []string, stats bool) *Summary {
+	s := &Summary{
+		fields:   fields,
+		stats:    stats,
+		sums:     make(map[string]float64),
+		weighted: make(map[string]*[2]float64),
+	}
+	selected := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		selected[f] = true
+	}
+	for _, f := range fields {
+		if w, ok := ratioWeights[f]; ok && selected[w] {
+			s.weighted[f] = new([2]float64)
+		}
+	}
+	return s
+}
+
+// Add folds a result row into the summary.
+func (s *Summary) Add(row map[string]any) {
+	s.Rows++
+	if !s.stats {
+		return
+	}
+	for _, f := range s.fields {
+		if additiveMetrics[f] {
+			s.sums[f] += number(row, f)
+		}
+		if acc, ok := s.weighted[f]; ok {
+			weight := number(row, ratioWeights[f])
+			acc[0] += number(row, f) * weight
+			acc[1] += weight
+		}
+	}
+}
+
+// Stats returns the column statistics in SELECT order. It is empty
+// unless the summary was created with stats.
+func (s *Summary) Stats() []Stat {
+	if !s.stats {
+		return nil
+	}
+	var out []Stat
+	for _, f := range s.fields {
+		switch {
+		case additiveMetrics[f]:
+			out = append(out, Stat{Field: f, Value: s.sums[f]})
+		case s.weighted[f] != nil:
+			acc := s.weighted[f]
+			var avg float64
+			if acc[1] != 0 {
+				avg = acc[0] / acc[1]
+			}
+			out = append(out, Stat{Field: f, Value: avg, WeightedBy: ratioWeights[f]})
+		}
+	}
+	return out
+}
+
+// WriteTo writes the footer: the row count, then one line per statistic.
+func (s *Summary) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	if s.Rows == 1 {
+		sb.WriteString("1 row\n")
+	} else {
+		fmt.Fprintf(&sb, "%d rows\n", s.Rows)
+	}
+	for _, st := range s.Stats() {
+		if st.WeightedBy == "" {
+			fmt.Fprintf(&sb, "  %s: total %s\n", st.Field, strconv.FormatFloat(st.Value, 'f', -1, 64))
+		} else {
+			fmt.Fprintf(&sb, "  %s: %.4f (weighted by %s)\n", st.Field, st.Value, st.WeightedBy)
+		}
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// number reads a numeric field from a row. INT64 values arrive as JSON
+// strings; missing and non-numeric values count as zero.
+func number(row map[string]any, field string) float64 {
+	v, _ := Value(row, field)
+	switch v := v.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	default:
+		return 0
+	}
+}
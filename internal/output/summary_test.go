@@ -0,0 +1,47 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	fields := []string{"campaign.name", "metrics.impressions", "metrics.clicks", "metrics.ctr", "metrics.average_cpc", "metrics.cost_per_conversion"}
+	rows := []map[string]any{
+		{"metrics": map[string]any{"impressions": "1000", "clicks": "10", "ctr": 0.01, "averageCpc": 200000.0}},
+		{"metrics": map[string]any{"impressions": "100", "clicks": "30", "ctr": 0.3, "averageCpc": 100000.0}},
+		{"metrics": map[string]any{}},
+	}
+
+	s := NewSummary(fields, true)
+	for _, row := range rows {
+		s.Add(row)
+	}
+
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// CTR is clicks over impressions (40/1100), not the mean of the row
+	// CTRs; cost_per_conversion is omitted because conversions are not
+	// selected.
+	want := "3 rows\n" +
+		"  metrics.impressions: total 1100\n" +
+		"  metrics.clicks: total 40\n" +
+		"  metrics.ctr: 0.0364 (weighted by metrics.impressions)\n" +
+		"  metrics.average_cpc: 125000.0000 (weighted by metrics.clicks)\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestSummaryWithoutStats(t *testing.T) {
+	s := NewSummary([]string{"metrics.clicks"}, false)
+	s.Add(map[string]any{"metrics": map[string]any{"clicks": "5"}})
+
+	var buf bytes.Buffer
+	s.WriteTo(&buf)
+	if buf.String() != "1 row\n" || len(s.Stats()) != 0 {
+		t.Errorf("unexpected footer %q, stats %v", buf.String(), s.Stats())
+	}
+}
@@ -0,0 +1,203 @@
+package output
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// tableRenderer writes space-aligned columns. Alignment needs every
+// width, so rows are buffered until Flush.
+type tableRenderer struct {
+	w       io.Writer
+	rows    [][]string
+	summary []string // written below a rule after the rows
+}
+
+func (t *tableRenderer) WriteHeader(columns []string) error {
+	return t.WriteRow(columns)
+}
+
+func (t *tableRenderer) WriteRow(values []string) error {
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = controlSpaces.Replace(v)
+	}
+	t.rows = append(t.rows, row)
+	return nil
+}
+
+func (t *tableRenderer) WriteSummary(values []string) error {
+	t.summary = make([]string, len(values))
+	for i, v := range values {
+		t.summary[i] = controlSpaces.Replace(v)
+	}
+	return nil
+}
+
+// controlSpaces replaces characters that would break column alignment.
+var controlSpaces = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+func (t *tableRenderer) Flush() error {
+	if len(t.rows) == 0 {
+		return nil
+	}
+	widths := make([]int, len(t.rows[0]))
+	for _, row := range append(t.rows, t.summary) {
+		for i, v := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(v))
+			}
+		}
+	}
+
+	rule := make([]string, len(widths))
+	for i, w := range widths {
+		rule[i] = strings.Repeat("-", w)
+	}
+	for n, row := range t.rows {
+		if err := t.writeLine(row, widths); err != nil {
+			return err
+		}
+		if n == 0 {
+			if err := t.writeLine(rule, widths); err != nil {
+				return err
+			}
+		}
+	}
+	if t.summary != nil {
+		if err := t.writeLine(rule, widths); err != nil {
+			return err
+		}
+		if err := t.writeLine(t.summary, widths); err != nil {
+			return err
+		}
+	}
+	t.rows, t.summary = nil, nil
+	return nil
+}
+
+func (t *tableRenderer) writeLine(values []string, widths []int) error {
+	var sb strings.Builder
+	for i, v := range values {
+		if i >= len(widths) {
+			break
+		}
+		if i > 0 {
+			sb.WriteString("  ")
+		}
+		sb.WriteString(v)
+		if i < len(widths)-1 {
+			sb.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)))
+		}
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(t.w, sb.String())
+	return err
+}
+
+// markdownRenderer writes a GitHub-flavored Markdown table.
+type markdownRenderer struct {
+	w io.Writer
+}
+
+func (m *markdownRenderer) WriteHeader(columns []string) error {
+	if err := m.writeLine(columns); err != nil {
+		return err
+	}
+	rule := make([]string, len(columns))
+	for i := range rule {
+		rule[i] = "---"
+	}
+	return m.writeLine(rule)
+}
+
+func (m *markdownRenderer) WriteRow(values []string) error {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		v = strings.ReplaceAll(v, "|", `\|`)
+		escaped[i] = strings.ReplaceAll(v, "\n", "<br>")
+	}
+	return m.writeLine(escaped)
+}
+
+// WriteSummary writes the summary row in bold.
+func (m *markdownRenderer) WriteSummary(values []string) error {
+	bold := make([]string, len(values))
+	for i, v := range values {
+		if v != "" {
+			bold[i] = "**" + v + "**"
+		}
+	}
+	return m.WriteRow(bold)
+}
+
+func (m *markdownRenderer) writeLine(cells []string) error {
+	_, err := fmt.Fprintf(m.w, "| %s |\n", strings.Join(cells, " | "))
+	return err
+}
+
+func (m *markdownRenderer) Flush() error { return nil }
+
+// htmlRenderer writes an HTML table fragment.
+type htmlRenderer struct {
+	w       io.Writer
+	started bool
+	footer  bool // the summary row ended the body
+}
+
+func (h *htmlRenderer) WriteHeader(columns []string) error {
+	h.started = true
+	if _, err := io.WriteString(h.w, "<table>\n<thead>\n"); err != nil {
+		return err
+	}
+	if err := h.writeLine("th", columns); err != nil {
+		return err
+	}
+	_, err := io.WriteString(h.w, "</thead>\n<tbody>\n")
+	return err
+}
+
+func (h *htmlRenderer) WriteRow(values []string) error {
+	return h.writeLine("td", values)
+}
+
+// WriteSummary ends the table body and writes the summary row as its
+// footer.
+func (h *htmlRenderer) WriteSummary(values []string) error {
+	h.footer = true
+	if _, err := io.WriteString(h.w, "</tbody>\n<tfoot>\n"); err != nil {
+		return err
+	}
+	if err := h.writeLine("td", values); err != nil {
+		return err
+	}
+	_, err := io.WriteString(h.w, "</tfoot>\n")
+	return err
+}
+
+func (h *htmlRenderer) writeLine(tag string, cells []string) error {
+	var sb strings.Builder
+	sb.WriteString("<tr>")
+	for _, c := range cells {
+		fmt.Fprintf(&sb, "<%s>%s</%s>", tag, html.EscapeString(c), tag)
+	}
+	sb.WriteString("</tr>\n")
+	_, err := io.WriteString(h.w, sb.String())
+	return err
+}
+
+func (h *htmlRenderer) Flush() error {
+	if !h.started {
+		return nil
+	}
+	end := "</tbody>\n</table>\n"
+	if h.footer {
+		end = "</table>\n"
+	}
+	h.started, h.footer = false, false
+	_, err := io.WriteString(h.w, end)
+	return err
+}
@@ -0,0 +1,173 @@
+// Package pacing projects month-to-date campaign spend against budgets.
+//
+// Budgets are daily amounts. A budget's month is its daily amount times
+// the number of days in the calendar month; spend so far is projected
+// linearly to the end of the month and compared with it. Campaigns that
+// share a budget are pooled, since they draw on the same amount.
+// ProjectPeriod does the same for a week, quarter, or year to date, with
+// spend grouped by campaign, budget, channel, or account.
+//
+// # Basic Usage
+//
+//	resp, err := client.Search(ctx, customerID, pacing.Query)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	now := time.Now()
+//	for _, p := range pacing.Project(pacing.Budgets(resp.Results), now) {
+//		if p.Ratio >= 0.9 {
+//			fmt.Printf("%s projects %.0f%% of budget\n", p.Name, p.Ratio*100)
+//		}
+//	}
+package pacing
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// Query fetches month-to-date spend of every campaign with a daily
+// budget, along with the account time zone that defines "this month".
+const Query = `SELECT customer.time_zone, campaign.id, campaign.name, campaign_budget.resource_name, campaign_budget.name, campaign_budget.amount_micros, campaign_budget.explicitly_shared, metrics.cost_micros FROM campaign WHERE segments.date DURING THIS_MONTH AND campaign.status != 'REMOVED' AND campaign_budget.period = 'DAILY'`
+
+// Campaign identifies a campaign drawing on a budget.
+type Campaign struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Budget is a campaign budget and the spend charged to it this month.
+type Budget struct {
+	ResourceName string     `json:"resource_name"`
+	Name         string     `json:"name"`
+	Shared       bool       `json:"shared"`
+	DailyMicros  int64      `json:"daily_budget_micros"`
+	SpendMicros  int64      `json:"spend_micros"`
+	Campaigns    []Campaign `json:"campaigns"`
+}
+
+// Budgets groups Query result rows by budget, summing the spend of
+// campaigns that share one. Budgets are ordered by name.
+func Budgets(rows []adsapi.Row) []*Budget {
+	byName := make(map[string]*Budget)
+	var out []*Budget
+	for _, row := range rows {
+		rn := str(row, "campaign_budget.resource_name")
+		b, ok := byName[rn]
+		if !ok {
+			shared, _ := value(row, "campaign_budget.explicitly_shared").(bool)
+			b = &Budget{
+				ResourceName: rn,
+				Name:         str(row, "campaign_budget.name"),
+				Shared:       shared,
+				DailyMicros:  micros(row, "campaign_budget.amount_micros"),
+			}
+			byName[rn] = b
+			out = append(out, b)
+		}
+		b.SpendMicros += micros(row, "metrics.cost_micros")
+		b.Campaigns = append(b.Campaigns, Campaign{ID: str(row, "campaign.id"), Name: str(row, "campaign.name")})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// TimeZone returns the account time zone reported in Query results, or
+// nil when it is missing or unknown to the system.
+func TimeZone(rows []adsapi.Row) *time.Location {
+	for _, row := range rows {
+		if tz := str(row, "customer.time_zone"); tz != "" {
+			loc, err := time.LoadLocation(tz)
+			if err != nil {
+				return nil
+			}
+			return loc
+		}
+	}
+	return nil
+}
+
+// Pace is a budget's projected spend for the month.
+type Pace struct {
+	*Budget
+	MonthMicros     int64   `json:"month_budget_micros"`
+	ProjectedMicros int64   `json:"projected_micros"`
+	Ratio           float64 `json:"projected_ratio"` // projected spend / month budget
+}
+
+// Project projects each budget's spend to the end of the month containing
+// now. Elapsed time counts from midnight on the first, in now's location,
+// and is at least one day so early-month projections stay sane.
+func Project(budgets []*Budget, now time.Time) []Pace {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	days := float64(start.AddDate(0, 1, -1).Day())
+	elapsed := now.Sub(start).Hours() / 24
+	if elapsed < 1 {
+		elapsed = 1
+	}
+
+	paces := make([]Pace, len(budgets))
+	for i, b := range budgets {
+		p := Pace{Budget: b}
+		p.MonthMicros, p.ProjectedMicros, p.Ratio = project(b.DailyMicros, b.SpendMicros, days, elapsed)
+		paces[i] = p
+	}
+	return paces
+}
+
+// project scales a daily budget to a period of days and projects the
+// spend of the days elapsed so far over the whole period.
+func project(dailyMicros, spendMicros int64, days, elapsed float64) (budget, projected int64, ratio float64) {
+	budget = int64(float64(dailyMicros) * days)
+	projected = int64(float64(spendMicros) / elapsed * days)
+	if budget > 0 {
+		ratio = float64(projected) / float64(budget)
+	}
+	return budget, projected, ratio
+}
+
+// Over returns the paces projected to spend at least threshold of their
+// month budget, highest ratio first.
+func Over(paces []Pace, threshold float64) []Pace {
+	var out []Pace
+	for _, p := range paces {
+		if p.MonthMicros > 0 && p.Ratio >= threshold {
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Ratio > out[j].Ratio })
+	return out
+}
+
+func value(row adsapi.Row, field string) any {
+	v, _ := output.Value(row, field)
+	return v
+}
+
+func str(row adsapi.Row, field string) string {
+	switch v := value(row, field).(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// micros reads an INT64 field, which the REST API encodes as a string.
+func micros(row adsapi.Row, field string) int64 {
+	switch v := value(row, field).(type) {
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
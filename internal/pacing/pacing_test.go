@@ -0,0 +1,92 @@
+package pacing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func campaignRow(id, name, budget string, daily, spend string, shared bool) adsapi.Row {
+	return adsapi.Row{
+		"customer": map[string]any{"timeZone": "America/New_York"},
+		"campaign": map[string]any{"id": id, "name": name},
+		"campaignBudget": map[string]any{
+			"resourceName":     "customers/1234567890/campaignBudgets/" + budget,
+			"name":             "Budget " + budget,
+			"amountMicros":     daily,
+			"explicitlyShared": shared,
+		},
+		"metrics": map[string]any{"costMicros": spend},
+	}
+}
+
+func TestQueryIsValid(t *testing.T) {
+	q, err := gaql.Parse(Query)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := gaql.NewValidator().Validate(q); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestProject(t *testing.T) {
+	rows := []adsapi.Row{
+		campaignRow("1", "Brand", "10", "100000000", "1000000000", false),  // $100/day, $1000 spent
+		campaignRow("2", "Generic", "20", "50000000", "300000000", true),   // shared $50/day
+		campaignRow("3", "Generic 2", "20", "50000000", "200000000", true), // same budget
+	}
+	budgets := Budgets(rows)
+	if len(budgets) != 2 {
+		t.Fatalf("expected 2 budgets, got %d", len(budgets))
+	}
+	if b := budgets[1]; b.SpendMicros != 500000000 || len(b.Campaigns) != 2 {
+		t.Errorf("shared budget not pooled: %+v", b)
+	}
+
+	loc := TimeZone(rows)
+	if loc == nil || loc.String() != "America/New_York" {
+		t.Fatalf("TimeZone = %v", loc)
+	}
+
+	// Ten days into a 30-day month.
+	now := time.Date(2026, 9, 11, 0, 0, 0, 0, loc)
+	paces := Project(budgets, now)
+
+	tests := []struct {
+		name      string
+		month     int64
+		projected int64
+		ratio     float64
+	}{
+		{"Budget 10", 3000000000, 3000000000, 1.0},
+		{"Budget 20", 1500000000, 1500000000, 1.0},
+	}
+	for i, tt := range tests {
+		p := paces[i]
+		if p.Name != tt.name || p.MonthMicros != tt.month || p.ProjectedMicros != tt.projected || p.Ratio != tt.ratio {
+			t.Errorf("pace %d = %s month=%d projected=%d ratio=%v, want %+v", i, p.Name, p.MonthMicros, p.ProjectedMicros, p.Ratio, tt)
+		}
+	}
+
+	// Early in the month at least one day counts as elapsed.
+	early := Project(budgets, time.Date(2026, 9, 1, 2, 0, 0, 0, loc))
+	if early[0].ProjectedMicros != 30000000000 {
+		t.Errorf("early projection = %d", early[0].ProjectedMicros)
+	}
+}
+
+func TestOver(t *testing.T) {
+	paces := []Pace{
+		{Budget: &Budget{Name: "a"}, MonthMicros: 100, Ratio: 0.5},
+		{Budget: &Budget{Name: "b"}, MonthMicros: 100, Ratio: 0.95},
+		{Budget: &Budget{Name: "c"}, MonthMicros: 100, Ratio: 1.2},
+		{Budget: &Budget{Name: "d"}, MonthMicros: 0, Ratio: 0},
+	}
+	over := Over(paces, 0.9)
+	if len(over) != 2 || over[0].Name != "c" || over[1].Name != "b" {
+		t.Errorf("unexpected alerts %+v", over)
+	}
+}
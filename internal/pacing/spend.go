@@ -0,0 +1,170 @@
+package pacing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Periods lists the period names ParsePeriod accepts: week, month,
+// quarter, and year to date. Weeks start on Monday.
+var Periods = []string{"WTD", "MTD", "QTD", "YTD"}
+
+// Period is a calendar period containing today. Start and End are its
+// first and last day, at midnight UTC.
+type Period struct {
+	Name       string
+	Start, End time.Time
+}
+
+// ParsePeriod returns the period called name that contains now's date.
+func ParsePeriod(name string, now time.Time) (Period, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	p := Period{Name: strings.ToUpper(name)}
+	switch p.Name {
+	case "WTD":
+		p.Start = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		p.End = p.Start.AddDate(0, 0, 6)
+	case "MTD":
+		p.Start = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+		p.End = p.Start.AddDate(0, 1, -1)
+	case "QTD":
+		p.Start = time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+		p.End = p.Start.AddDate(0, 3, -1)
+	case "YTD":
+		p.Start = time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+		p.End = time.Date(y, 12, 31, 0, 0, 0, 0, time.UTC)
+	default:
+		return Period{}, fmt.Errorf("pacing: invalid period %q (expected one of %s)", name, strings.Join(Periods, ", "))
+	}
+	return p, nil
+}
+
+// Days returns the number of days in the whole period.
+func (p Period) Days() int {
+	return int(p.End.Sub(p.Start).Hours()/24) + 1
+}
+
+// elapsed returns the days of the period elapsed at now, counted from
+// midnight on its first day in now's location, and at least one.
+func (p Period) elapsed(now time.Time) float64 {
+	start := time.Date(p.Start.Year(), p.Start.Month(), p.Start.Day(), 0, 0, 0, 0, now.Location())
+	return max(now.Sub(start).Hours()/24, 1)
+}
+
+// TimeZoneQuery fetches the account time zone, which decides the dates a
+// period covers.
+const TimeZoneQuery = `SELECT customer.time_zone FROM customer`
+
+// SpendQuery returns the query fetching the spend of every campaign with
+// a daily budget from the start of p up to today, the last day
+// included.
+func SpendQuery(p Period, today time.Time) *gaql.Query {
+	return gaql.Select("customer.id", "customer.descriptive_name",
+		"campaign.id", "campaign.name", "campaign.advertising_channel_type",
+		"campaign_budget.resource_name", "campaign_budget.name", "campaign_budget.amount_micros",
+		"metrics.cost_micros").
+		From("campaign").
+		Between(p.Start.Format(time.DateOnly), today.Format(time.DateOnly)).
+		Where("campaign.status", gaql.OpNeq, gaql.StringValue("REMOVED")).
+		Where("campaign_budget.period", gaql.OpEq, gaql.StringValue("DAILY")).
+		Query()
+}
+
+// Groupings maps each way spend can be grouped to the fields giving a
+// group's key and name. Channels have no separate name.
+var Groupings = map[string][2]string{
+	"account":  {"customer.id", "customer.descriptive_name"},
+	"budget":   {"campaign_budget.resource_name", "campaign_budget.name"},
+	"campaign": {"campaign.id", "campaign.name"},
+	"channel":  {"campaign.advertising_channel_type", ""},
+}
+
+// Group is the spend of a group of campaigns, such as one channel, and
+// the daily budgets they draw on.
+type Group struct {
+	Key         string `json:"key"`
+	Name        string `json:"name,omitempty"`
+	Campaigns   int    `json:"campaigns"`
+	DailyMicros int64  `json:"daily_budget_micros"`
+	SpendMicros int64  `json:"spend_micros"`
+}
+
+// GroupSpend groups SpendQuery result rows by one of Groupings. A budget
+// shared by campaigns in a group counts once in its daily amount, but
+// in full in each group drawing on it, as it cannot be split. Groups are
+// ordered by spend, highest first.
+func GroupSpend(rows []adsapi.Row, by string) ([]*Group, error) {
+	fields, ok := Groupings[by]
+	if !ok {
+		names := make([]string, 0, len(Groupings))
+		for name := range Groupings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("pacing: invalid grouping %q (expected one of %s)", by, strings.Join(names, ", "))
+	}
+	byKey := make(map[string]*Group)
+	budgets := make(map[string]map[string]bool)
+	var out []*Group
+	for _, row := range rows {
+		key := str(row, fields[0])
+		g, ok := byKey[key]
+		if !ok {
+			g = &Group{Key: key}
+			if fields[1] != "" {
+				g.Name = str(row, fields[1])
+			}
+			byKey[key] = g
+			budgets[key] = make(map[string]bool)
+			out = append(out, g)
+		}
+		g.Campaigns++
+		g.SpendMicros += micros(row, "metrics.cost_micros")
+		if rn := str(row, "campaign_budget.resource_name"); !budgets[key][rn] {
+			budgets[key][rn] = true
+			g.DailyMicros += micros(row, "campaign_budget.amount_micros")
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].SpendMicros > out[j].SpendMicros })
+	return out, nil
+}
+
+// GroupPace is a group's projected spend for a period.
+type GroupPace struct {
+	*Group
+	PeriodMicros    int64   `json:"period_budget_micros"`
+	ProjectedMicros int64   `json:"projected_micros"`
+	Ratio           float64 `json:"projected_ratio"` // projected spend / period budget
+}
+
+// ProjectPeriod projects each group's spend to the end of p, as Project
+// does for a month.
+func ProjectPeriod(groups []*Group, p Period, now time.Time) []GroupPace {
+	days, elapsed := float64(p.Days()), p.elapsed(now)
+	paces := make([]GroupPace, len(groups))
+	for i, g := range groups {
+		gp := GroupPace{Group: g}
+		gp.PeriodMicros, gp.ProjectedMicros, gp.Ratio = project(g.DailyMicros, g.SpendMicros, days, elapsed)
+		paces[i] = gp
+	}
+	return paces
+}
+
+// GroupsOver returns the paces projected to spend at least threshold of
+// their period budget, highest ratio first.
+func GroupsOver(paces []GroupPace, threshold float64) []GroupPace {
+	var out []GroupPace
+	for _, p := range paces {
+		if p.PeriodMicros > 0 && p.Ratio >= threshold {
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Ratio > out[j].Ratio })
+	return out
+}
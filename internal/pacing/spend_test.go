@@ -0,0 +1,136 @@
+package pacing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestParsePeriod(t *testing.T) {
+	now := time.Date(2026, 8, 13, 15, 0, 0, 0, time.UTC) // a Thursday
+	tests := []struct {
+		name       string
+		start, end string
+		days       int
+	}{
+		{"wtd", "2026-08-10", "2026-08-16", 7},
+		{"MTD", "2026-08-01", "2026-08-31", 31},
+		{"QTD", "2026-07-01", "2026-09-30", 92},
+		{"YTD", "2026-01-01", "2026-12-31", 365},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePeriod(tt.name, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Start.Format(time.DateOnly); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := p.End.Format(time.DateOnly); got != tt.end {
+				t.Errorf("end = %s, want %s", got, tt.end)
+			}
+			if p.Days() != tt.days {
+				t.Errorf("days = %d, want %d", p.Days(), tt.days)
+			}
+		})
+	}
+	if _, err := ParsePeriod("LAST_MONTH", now); err == nil {
+		t.Error("expected an error for an unknown period")
+	}
+}
+
+func TestSpendQueryIsValid(t *testing.T) {
+	p, _ := ParsePeriod("QTD", time.Date(2026, 8, 13, 0, 0, 0, 0, time.UTC))
+	q := SpendQuery(p, time.Date(2026, 8, 13, 0, 0, 0, 0, time.UTC))
+	want := "SELECT customer.id, customer.descriptive_name, campaign.id, campaign.name, campaign.advertising_channel_type, campaign_budget.resource_name, campaign_budget.name, campaign_budget.amount_micros, metrics.cost_micros FROM campaign WHERE segments.date BETWEEN '2026-07-01' AND '2026-08-13' AND campaign.status != 'REMOVED' AND campaign_budget.period = 'DAILY'"
+	if q.String() != want {
+		t.Errorf("got:  %s\nwant: %s", q, want)
+	}
+	if err := gaql.NewValidator().Validate(q); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func spendRow(campaign, channel, budget, daily, spend string) adsapi.Row {
+	return adsapi.Row{
+		"customer": map[string]any{"id": "1234567890", "descriptiveName": "Outdoor"},
+		"campaign": map[string]any{"id": campaign, "name": "Campaign " + campaign, "advertisingChannelType": channel},
+		"campaignBudget": map[string]any{
+			"resourceName": "customers/1234567890/campaignBudgets/" + budget,
+			"name":         "Budget " + budget,
+			"amountMicros": daily,
+		},
+		"metrics": map[string]any{"costMicros": spend},
+	}
+}
+
+func TestGroupSpend(t *testing.T) {
+	rows := []adsapi.Row{
+		spendRow("1", "SEARCH", "10", "100000000", "1000000000"), // $100/day, $1000 spent
+		spendRow("2", "SEARCH", "20", "50000000", "300000000"),   // shares budget 20
+		spendRow("3", "DISPLAY", "20", "50000000", "200000000"),
+	}
+	// Ten days into a 30-day month.
+	p, _ := ParsePeriod("MTD", time.Date(2026, 9, 11, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2026, 9, 11, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		by   string
+		want []GroupPace
+	}{
+		{"account", []GroupPace{
+			{Group: &Group{Key: "1234567890", Name: "Outdoor", Campaigns: 3, DailyMicros: 150000000, SpendMicros: 1500000000},
+				PeriodMicros: 4500000000, ProjectedMicros: 4500000000, Ratio: 1},
+		}},
+		{"channel", []GroupPace{
+			{Group: &Group{Key: "SEARCH", Campaigns: 2, DailyMicros: 150000000, SpendMicros: 1300000000},
+				PeriodMicros: 4500000000, ProjectedMicros: 3900000000, Ratio: 3900.0 / 4500},
+			{Group: &Group{Key: "DISPLAY", Campaigns: 1, DailyMicros: 50000000, SpendMicros: 200000000},
+				PeriodMicros: 1500000000, ProjectedMicros: 600000000, Ratio: 0.4},
+		}},
+		{"budget", []GroupPace{
+			{Group: &Group{Key: "customers/1234567890/campaignBudgets/10", Name: "Budget 10", Campaigns: 1, DailyMicros: 100000000, SpendMicros: 1000000000},
+				PeriodMicros: 3000000000, ProjectedMicros: 3000000000, Ratio: 1},
+			{Group: &Group{Key: "customers/1234567890/campaignBudgets/20", Name: "Budget 20", Campaigns: 2, DailyMicros: 50000000, SpendMicros: 500000000},
+				PeriodMicros: 1500000000, ProjectedMicros: 1500000000, Ratio: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			groups, err := GroupSpend(rows, tt.by)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ProjectPeriod(groups, p, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d groups, want %d", len(got), len(tt.want))
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if *g.Group != *w.Group || g.PeriodMicros != w.PeriodMicros || g.ProjectedMicros != w.ProjectedMicros || g.Ratio != w.Ratio {
+					t.Errorf("group %d = %+v %+v, want %+v %+v", i, *g.Group, g, *w.Group, w)
+				}
+			}
+		})
+	}
+
+	if _, err := GroupSpend(rows, "ad_group"); err == nil {
+		t.Error("expected an error for an unknown grouping")
+	}
+}
+
+func TestGroupsOver(t *testing.T) {
+	paces := []GroupPace{
+		{Group: &Group{Key: "a"}, PeriodMicros: 100, Ratio: 0.5},
+		{Group: &Group{Key: "b"}, PeriodMicros: 100, Ratio: 0.95},
+		{Group: &Group{Key: "c"}, PeriodMicros: 100, Ratio: 1.2},
+		{Group: &Group{Key: "d"}, PeriodMicros: 0, Ratio: 0},
+	}
+	over := GroupsOver(paces, 0.9)
+	if len(over) != 2 || over[0].Key != "c" || over[1].Key != "b" {
+		t.Errorf("unexpected alerts %+v", over)
+	}
+}
@@ -0,0 +1,154 @@
+// Package policy loads the access policies of shared deployments from a
+// YAML file. A policy limits what queries may read beyond the API
+// client being read-only: the resources and fields they may touch, the
+// fields whose values are redacted, and how many rows and days they may
+// span. Rules at the top of the file apply to everyone; a role
+// overrides the rules it sets.
+//
+//	# Nobody reads the change history or user access.
+//	deny_resources: [change_event, customer_user_access]
+//	redact_fields: [user_list.name]
+//	max_limit: 10000
+//	max_days: 365
+//
+//	roles:
+//	  analyst:
+//	    allow_resources:
+//	      - campaign
+//	      - ad_group
+//	      - keyword_view
+//	      - search_term_view
+//	    max_limit: 1000
+//	    max_days: 90
+//
+// For returns the gaql.AccessPolicy of a role, which a gaql.Validator
+// enforces.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Keys are the rule keys, valid at the top level and in each role.
+var Keys = []string{"allow_resources", "deny_resources", "deny_fields", "redact_fields", "max_limit", "max_days"}
+
+// Policy is a loaded policy file.
+type Policy struct {
+	// Default holds the top-level rules.
+	Default gaql.AccessPolicy
+
+	// Roles holds each role's rules merged over Default.
+	Roles map[string]gaql.AccessPolicy
+}
+
+// Load reads the policy file at path.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w (in %s)", err, path)
+	}
+	return p, nil
+}
+
+// Parse parses a policy file.
+func Parse(data []byte) (*Policy, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %v", err)
+	}
+	p := &Policy{Roles: map[string]gaql.AccessPolicy{}}
+	roles := doc["roles"]
+	delete(doc, "roles")
+	if err := decodeRules(doc, &p.Default, ""); err != nil {
+		return nil, err
+	}
+	if roles == nil || roles == "" {
+		return p, nil
+	}
+	byName, ok := roles.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("policy: roles must map role names to rules")
+	}
+	for name, v := range byName {
+		rules, ok := v.(map[string]any)
+		if !ok && v != "" {
+			return nil, fmt.Errorf("policy: role %s must map rule keys to values", name)
+		}
+		r := p.Default
+		if err := decodeRules(rules, &r, "role "+name+": "); err != nil {
+			return nil, err
+		}
+		p.Roles[name] = r
+	}
+	return p, nil
+}
+
+// decodeRules sets the rules of doc on r, leaving the others as they
+// are. An empty list, [], clears a list the defaults set.
+func decodeRules(doc map[string]any, r *gaql.AccessPolicy, where string) error {
+	for key, v := range doc {
+		switch key {
+		case "allow_resources", "deny_resources", "deny_fields", "redact_fields":
+			list, ok := v.([]string)
+			if !ok {
+				return fmt.Errorf("policy: %s%s must be a list", where, key)
+			}
+			switch key {
+			case "allow_resources":
+				r.AllowResources = list
+			case "deny_resources":
+				r.DenyResources = list
+			case "deny_fields":
+				r.DenyFields = list
+			default:
+				r.RedactFields = list
+			}
+		case "max_limit", "max_days":
+			s, _ := v.(string)
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return fmt.Errorf("policy: %s%s must be a non-negative number, got %v", where, key, v)
+			}
+			if key == "max_limit" {
+				r.MaxLimit = n
+			} else {
+				r.MaxDays = n
+			}
+		default:
+			return fmt.Errorf("policy: %sunknown key %q (expected one of %s, or roles)", where, key, strings.Join(Keys, ", "))
+		}
+	}
+	return nil
+}
+
+// For returns the rules of role, or the top-level rules when role is
+// empty.
+func (p *Policy) For(role string) (*gaql.AccessPolicy, error) {
+	if role == "" {
+		r := p.Default
+		return &r, nil
+	}
+	r, ok := p.Roles[role]
+	if !ok {
+		names := make([]string, 0, len(p.Roles))
+		for name := range p.Roles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("policy: unknown role %q (the policy defines no roles)", role)
+		}
+		return nil, fmt.Errorf("policy: unknown role %q (expected one of %s)", role, strings.Join(names, ", "))
+	}
+	return &r, nil
+}
@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+const example = `# Shared deployment policy.
+deny_resources: [change_event, customer_user_access]
+redact_fields:
+  - user_list.name   # audience names are confidential
+max_limit: 10000
+max_days: 365
+
+roles:
+  analyst:
+    allow_resources:
+    - campaign
+    - "keyword_view"
+    max_limit: 1000
+  admin:
+    deny_resources: []
+    redact_fields: []
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(example))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		role string
+		want gaql.AccessPolicy
+	}{
+		{"", gaql.AccessPolicy{
+			DenyResources: []string{"change_event", "customer_user_access"},
+			RedactFields:  []string{"user_list.name"},
+			MaxLimit:      10000,
+			MaxDays:       365,
+		}},
+		{"analyst", gaql.AccessPolicy{
+			AllowResources: []string{"campaign", "keyword_view"},
+			DenyResources:  []string{"change_event", "customer_user_access"},
+			RedactFields:   []string{"user_list.name"},
+			MaxLimit:       1000,
+			MaxDays:        365,
+		}},
+		{"admin", gaql.AccessPolicy{
+			DenyResources: []string{},
+			RedactFields:  []string{},
+			MaxLimit:      10000,
+			MaxDays:       365,
+		}},
+	}
+	for _, tt := range tests {
+		got, err := p.For(tt.role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("For(%q) = %+v, want %+v", tt.role, *got, tt.want)
+		}
+	}
+	if _, err := p.For("intern"); err == nil || !strings.Contains(err.Error(), "expected one of admin, analyst") {
+		t.Errorf("For(intern) = %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, wantErr string
+	}{
+		{"deny_resource: [change_event]", `unknown key "deny_resource"`},
+		{"max_limit: lots", "max_limit must be a non-negative number"},
+		{"deny_fields: user_list.name", "deny_fields must be a list"},
+		{"roles:\n  analyst:\n    max_days: -1", "role analyst: max_days must be"},
+		{"roles:\n  analyst:\n      max_days: 1\n    max_limit: 2", "line 4: unexpected indentation"},
+		{"redact_fields: ['user_list.name]", "unterminated string"},
+		{"max_limit 10", "line 1: expected key: value"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
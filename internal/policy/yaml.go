@@ -0,0 +1,184 @@
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The policy file is read with a YAML subset: nested mappings by
+// indentation, block lists of "- item" lines, inline [a, b] lists,
+// quoted or plain scalars, and # comments. Anchors, multi-line strings,
+// and flow mappings are not supported.
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+// parseYAML parses data into nested map[string]any values whose leaves
+// are strings and []string lists.
+func parseYAML(data []byte) (map[string]any, error) {
+	var p yamlParser
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		raw := strings.TrimRight(stripComment(sc.Text()), " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: indent with spaces, not tabs", n)
+		}
+		p.lines = append(p.lines, yamlLine{num: n, indent: len(raw) - len(text), text: text})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 {
+		return map[string]any{}, nil
+	}
+	m, err := p.mapping(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.i].num)
+	}
+	return m, nil
+}
+
+// mapping parses the "key: value" lines at indent.
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent {
+		l := p.lines[p.i]
+		if isListItem(l.text) {
+			return nil, fmt.Errorf("line %d: unexpected list item", l.num)
+		}
+		key, rest, ok := strings.Cut(l.text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", l.num)
+		}
+		key, rest = strings.TrimSpace(key), strings.TrimSpace(rest)
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", l.num, key)
+		}
+		p.i++
+		if rest != "" {
+			v, err := scalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", l.num, err)
+			}
+			m[key] = v
+			continue
+		}
+		var next *yamlLine
+		if p.i < len(p.lines) {
+			next = &p.lines[p.i]
+		}
+		switch {
+		case next != nil && isListItem(next.text) && next.indent >= indent:
+			items, err := p.list(next.indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = items
+		case next != nil && next.indent > indent:
+			sub, err := p.mapping(next.indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = sub
+		default:
+			m[key] = ""
+		}
+	}
+	if p.i < len(p.lines) && p.lines[p.i].indent > indent {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.i].num)
+	}
+	return m, nil
+}
+
+// list parses the "- item" lines at indent.
+func (p *yamlParser) list(indent int) ([]string, error) {
+	items := []string{}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent && isListItem(p.lines[p.i].text) {
+		l := p.lines[p.i]
+		v, err := scalar(strings.TrimSpace(strings.TrimPrefix(l.text, "-")))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", l.num, err)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("line %d: nested lists are not supported", l.num)
+		}
+		items = append(items, s)
+		p.i++
+	}
+	return items, nil
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// scalar parses a value: a quoted or plain string, or an inline list.
+func scalar(s string) (any, error) {
+	if strings.HasPrefix(s, "[") {
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated list %s", s)
+		}
+		items := []string{}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, item := range strings.Split(inner, ",") {
+			v, err := unquote(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	}
+	return unquote(s)
+}
+
+func unquote(s string) (string, error) {
+	if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+		if len(s) < 2 || s[len(s)-1] != s[0] {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	}
+	return s, nil
+}
+
+// stripComment removes a # comment that is not inside a string. As in
+// YAML, # starts a comment only at the start of the line or after a
+// space.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,197 @@
+// Package queryerr maps Google Ads API failures back onto the GAQL text
+// that caused them.
+//
+// The API reports a rejected query as a GoogleAdsFailure whose entries
+// carry an error code, a message, and usually the offending value as the
+// trigger, located at the "query" field of the request. It gives no
+// line or column. Locate finds the trigger (or a value quoted in the
+// message) among the parsed query's nodes and returns its source
+// position, so the error can be reported against what the user wrote:
+//
+//	error at line 3, column 14: UNRECOGNIZED_FIELD near 'metrics.clickz'
+//
+// # Basic Usage
+//
+//	q, _ := gaql.Parse(src)
+//	_, err := client.Search(ctx, customerID, q.String())
+//	for _, e := range queryerr.Translate(err, q, src) {
+//		fmt.Println(e)
+//	}
+package queryerr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Error is a GoogleAdsFailure entry located in the query source.
+type Error struct {
+	adsapi.GoogleAdsError
+
+	// Span is the source range of the node the failure refers to. It is
+	// zero when the failure could not be placed.
+	Span gaql.Span
+
+	// Near is the source text covered by Span.
+	Near string
+}
+
+func (e *Error) Error() string {
+	if !e.Span.IsValid() {
+		return fmt.Sprintf("error: %s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("error at line %d, column %d: %s near '%s'", e.Span.Start.Line, e.Span.Start.Column, e.Code, e.Near)
+}
+
+// Translate locates every entry of the GoogleAdsFailure in err, which is
+// usually returned by adsapi.Client.Search, in order. It returns nil
+// when err is not an *adsapi.APIError.
+func Translate(err error, q *gaql.Query, src string) []*Error {
+	var apiErr *adsapi.APIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+	out := make([]*Error, len(apiErr.Errors))
+	for i, ge := range apiErr.Errors {
+		out[i] = Locate(q, src, ge)
+	}
+	return out
+}
+
+// quoted matches values the API quotes in its messages, e.g.
+// "Unrecognized field in the query: 'metrics.clickz'."
+var quoted = regexp.MustCompile(`'([^']+)'|"([^"]+)"`)
+
+// Locate places one failure entry in src, the text q was parsed from.
+// The trigger is tried first, then values quoted in the message; each is
+// matched against field names, the resource, and condition values, and
+// finally searched for in the raw text. Entries about other parts of the
+// request, such as the customer ID, are left unplaced.
+func Locate(q *gaql.Query, src string, ge adsapi.GoogleAdsError) *Error {
+	e := &Error{GoogleAdsError: ge}
+	if len(ge.FieldPath) > 0 && ge.FieldPath[0] != "query" {
+		return e
+	}
+
+	var candidates []string
+	if ge.Trigger != "" {
+		candidates = append(candidates, ge.Trigger)
+	}
+	for _, m := range quoted.FindAllStringSubmatch(ge.Message, -1) {
+		candidates = append(candidates, m[1]+m[2])
+	}
+
+	for _, c := range candidates {
+		if span, ok := find(q, src, c); ok {
+			e.Span = span
+			e.Near = span.Text(src)
+			return e
+		}
+	}
+	return e
+}
+
+// find returns the span of the first node whose text is s.
+func find(q *gaql.Query, src, s string) (gaql.Span, bool) {
+	var found gaql.Span
+	gaql.Walk(q, func(n gaql.Node) bool {
+		if found.IsValid() {
+			return false
+		}
+		switch n := n.(type) {
+		case *gaql.Field:
+			if n.Name == s {
+				found = n.Span
+			}
+		case *gaql.Condition:
+			switch {
+			case n.Field == s:
+				found = n.FieldSpan
+			case conditionHas(n, s):
+				// Point at the value itself when it appears verbatim.
+				found = n.Span
+				if i := strings.Index(n.Span.Text(src), s); i >= 0 {
+					found = spanAt(src, n.Span.Start.Offset+i, len(s))
+				}
+			}
+		case *gaql.Ordering:
+			if n.Field == s {
+				found = gaql.Span{Start: n.Span.Start, End: advance(n.Span.Start, n.Field)}
+			}
+		}
+		return true
+	})
+	if found.IsValid() {
+		return found, true
+	}
+
+	// The resource has no span of its own; look for it in the FROM
+	// clause.
+	if q.From == s && q.FromSpan.IsValid() {
+		if i := strings.Index(q.FromSpan.Text(src), s); i >= 0 {
+			return spanAt(src, q.FromSpan.Start.Offset+i, len(s)), true
+		}
+	}
+
+	// Fall back to the raw text, matching whole words only.
+	for from := 0; ; {
+		i := strings.Index(src[from:], s)
+		if i < 0 {
+			break
+		}
+		i += from
+		if !isWordByte(src, i-1) && !isWordByte(src, i+len(s)) {
+			return spanAt(src, i, len(s)), true
+		}
+		from = i + 1
+	}
+	return gaql.Span{}, false
+}
+
+func conditionHas(c *gaql.Condition, s string) bool {
+	switch c.Value.Type {
+	case gaql.ValueString:
+		return c.Value.Str == s
+	case gaql.ValueList:
+		for _, item := range c.Value.List {
+			if item == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isWordByte(src string, i int) bool {
+	if i < 0 || i >= len(src) {
+		return false
+	}
+	b := src[i]
+	return b == '_' || b == '.' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// spanAt returns the span of n bytes at offset in src.
+func spanAt(src string, offset, n int) gaql.Span {
+	start := gaql.Pos{Line: 1, Column: 1}
+	start = advance(start, src[:offset])
+	return gaql.Span{Start: start, End: advance(start, src[offset:offset+n])}
+}
+
+// advance returns the position after text that starts at p.
+func advance(p gaql.Pos, text string) gaql.Pos {
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+		p.Offset++
+	}
+	return p
+}
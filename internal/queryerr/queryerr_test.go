@@ -0,0 +1,78 @@
+package queryerr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+const src = `SELECT campaign.id,
+       campaign.name,
+       metrics.clickz
+FROM campaing
+WHERE campaign.status = 'ENABLD'
+ORDER BY metrics.clickz DESC`
+
+func TestLocate(t *testing.T) {
+	q, err := gaql.Parse(src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ge   adsapi.GoogleAdsError
+		want string
+	}{
+		{
+			name: "trigger names a select field",
+			ge:   adsapi.GoogleAdsError{Category: "queryError", Code: "UNRECOGNIZED_FIELD", Trigger: "metrics.clickz"},
+			want: "error at line 3, column 8: UNRECOGNIZED_FIELD near 'metrics.clickz'",
+		},
+		{
+			name: "value quoted in the message",
+			ge:   adsapi.GoogleAdsError{Category: "queryError", Code: "INVALID_RESOURCE_NAME", Message: "Invalid resource name 'campaing'."},
+			want: "error at line 4, column 6: INVALID_RESOURCE_NAME near 'campaing'",
+		},
+		{
+			name: "condition value",
+			ge:   adsapi.GoogleAdsError{Category: "queryError", Code: "INVALID_ENUM_VALUE", Trigger: "ENABLD"},
+			want: "error at line 5, column 26: INVALID_ENUM_VALUE near 'ENABLD'",
+		},
+		{
+			name: "unplaced",
+			ge:   adsapi.GoogleAdsError{Category: "queryError", Code: "LIMIT_VALUE_TOO_LARGE", Message: "LIMIT is too large."},
+			want: "error: LIMIT_VALUE_TOO_LARGE: LIMIT is too large.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Locate(q, src, tt.ge).Error(); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	q, _ := gaql.Parse(src)
+	err := fmt.Errorf("search: %w", &adsapi.APIError{
+		StatusCode: 400,
+		Errors: []adsapi.GoogleAdsError{
+			{Category: "queryError", Code: "UNRECOGNIZED_FIELD", Trigger: "metrics.clickz", FieldPath: []string{"query"}},
+			{Category: "fieldError", Code: "REQUIRED", FieldPath: []string{"customer_id"}},
+		},
+	})
+
+	got := Translate(err, q, src)
+	if len(got) != 2 || got[0].Near != "metrics.clickz" || got[1].Span.IsValid() {
+		t.Fatalf("unexpected translation %v", got)
+	}
+	if Translate(errors.New("network down"), q, src) != nil {
+		t.Error("expected nil for errors other than APIError")
+	}
+}
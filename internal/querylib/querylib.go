@@ -0,0 +1,279 @@
+// Package querylib keeps a library of named GAQL queries as a directory
+// of .gaql files, one query per file, so a team can share it under
+// version control.
+//
+// A file is named after its query and starts with "--" comment lines
+// holding the query's description, default customer, and defaults for
+// its @placeholders; the query follows. The header is plain GAQL
+// comments, so a library file runs as is with adtap search --file:
+//
+//	-- description: Spend by campaign between two dates
+//	-- customer_id: 1234567890
+//	-- param.start: 2026-01-01
+//	SELECT campaign.name, metrics.cost_micros
+//	FROM campaign
+//	WHERE segments.date BETWEEN @start AND @end
+//
+// # Basic Usage
+//
+//	lib := querylib.New(querylib.DefaultDir())
+//	q, err := lib.Load("weekly-spend")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(q.Description, q.Text)
+package querylib
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/textenc"
+)
+
+// Ext is the file name extension of library queries.
+const Ext = ".gaql"
+
+// ErrNotFound is returned by Load and Delete for names the library does
+// not have.
+var ErrNotFound = errors.New("querylib: no such query")
+
+// Query is a named query of the library.
+type Query struct {
+	Name        string
+	Description string
+	CustomerID  string            // the customer the query runs against by default
+	Params      map[string]string // default values of @placeholders, by name
+	Text        string            // the GAQL, without comments
+}
+
+// ParamNames returns the names of the placeholders of q in order of
+// first appearance, or nil when the query does not parse.
+func (q *Query) ParamNames() []string {
+	t, err := gaql.NewTemplate(q.Text)
+	if err != nil {
+		return nil
+	}
+	return t.Params()
+}
+
+// Check reports whether q can be saved: a valid name, a query that
+// parses, and defaults only for placeholders the query has.
+func (q *Query) Check() error {
+	if !ValidName(q.Name) {
+		return fmt.Errorf("querylib: invalid name %q (use letters, digits, '-', and '_')", q.Name)
+	}
+	if strings.ContainsAny(q.Description, "\r\n") {
+		return errors.New("querylib: the description must be one line")
+	}
+	t, err := gaql.NewTemplate(q.Text)
+	if err != nil {
+		return err
+	}
+	names := t.Params()
+	for name := range q.Params {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("querylib: default for %s matches no @%s placeholder in the query", name, name)
+		}
+	}
+	return nil
+}
+
+// Format returns q as the content of its library file.
+func (q *Query) Format() []byte {
+	var sb strings.Builder
+	if q.Description != "" {
+		fmt.Fprintf(&sb, "-- description: %s\n", q.Description)
+	}
+	if q.CustomerID != "" {
+		fmt.Fprintf(&sb, "-- customer_id: %s\n", q.CustomerID)
+	}
+	names := make([]string, 0, len(q.Params))
+	for name := range q.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "-- param.%s: %s\n", name, q.Params[name])
+	}
+	sb.WriteString(strings.TrimSpace(q.Text))
+	sb.WriteString("\n")
+	return []byte(sb.String())
+}
+
+// headerLine matches a "-- key: value" line of a file header.
+var headerLine = regexp.MustCompile(`^--\s*([a-z_]+(?:\.[A-Za-z0-9_]+)?)\s*:\s*(.*?)\s*$`)
+
+// Parse reads the library file content src of the query name. Lines of
+// the leading comment block that are not "key: value" pairs are ignored,
+// as are comments in the query.
+func Parse(name, src string) (*Query, error) {
+	q := &Query{Name: name, Params: map[string]string{}}
+	lines := strings.Split(src, "\n")
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		m := headerLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch key, value := m[1], m[2]; {
+		case key == "description":
+			q.Description = value
+		case key == "customer_id":
+			q.CustomerID = value
+		case strings.HasPrefix(key, "param."):
+			q.Params[strings.TrimPrefix(key, "param.")] = value
+		case key == "name":
+			// The file name names the query.
+		default:
+			return nil, fmt.Errorf("querylib: %s: line %d: unknown key %q (expected description, customer_id, or param.NAME)", name, i+1, key)
+		}
+	}
+	stmts := gaql.SplitStatements(strings.Join(lines[i:], "\n"))
+	switch len(stmts) {
+	case 0:
+		return nil, fmt.Errorf("querylib: %s: no query", name)
+	case 1:
+		q.Text = stmts[0].Text
+	default:
+		return nil, fmt.Errorf("querylib: %s: %d queries; a library file holds one", name, len(stmts))
+	}
+	return q, nil
+}
+
+// ValidName reports whether name can name a library query: letters,
+// digits, '-', and '_', starting with a letter or digit.
+func ValidName(name string) bool {
+	if name == "" || name[0] == '-' || name[0] == '_' {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Library is a directory of query files.
+type Library struct {
+	Dir string
+}
+
+// New returns the library in dir, which need not exist yet.
+func New(dir string) *Library {
+	return &Library{Dir: dir}
+}
+
+// DefaultDir returns the directory named by ADTAP_QUERIES, or queries in
+// the adtap directory of the user config directory, beside config.toml.
+func DefaultDir() string {
+	if dir := os.Getenv("ADTAP_QUERIES"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join(".adtap", "queries")
+	}
+	return filepath.Join(dir, "adtap", "queries")
+}
+
+func (l *Library) path(name string) string {
+	return filepath.Join(l.Dir, name+Ext)
+}
+
+// Names returns the names of the queries in the library, sorted. A
+// missing directory is an empty library.
+func (l *Library) Names() ([]string, error) {
+	entries, err := os.ReadDir(l.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querylib: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), Ext)
+		if ok && !e.IsDir() && ValidName(name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Load reads the query name from the library.
+func (l *Library) Load(name string) (*Query, error) {
+	if !ValidName(name) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(l.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querylib: %w", err)
+	}
+	return Parse(name, string(textenc.Decode(data)))
+}
+
+// Save writes q to the library, creating the directory if needed. An
+// existing query of the same name is replaced only if overwrite is set;
+// otherwise Save fails with an error matching fs.ErrExist.
+func (l *Library) Save(q *Query, overwrite bool) error {
+	if err := q.Check(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(l.Dir, 0o755); err != nil {
+		return fmt.Errorf("querylib: %w", err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(l.path(q.Name), flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("querylib: %s: %w", q.Name, fs.ErrExist)
+	}
+	if err != nil {
+		return fmt.Errorf("querylib: %w", err)
+	}
+	if _, err := f.Write(q.Format()); err != nil {
+		f.Close()
+		return fmt.Errorf("querylib: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("querylib: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the query name from the library.
+func (l *Library) Delete(name string) error {
+	if !ValidName(name) {
+		return ErrNotFound
+	}
+	err := os.Remove(l.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("querylib: %w", err)
+	}
+	return nil
+}
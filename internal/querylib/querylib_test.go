@@ -0,0 +1,132 @@
+package querylib
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    Query
+		wantErr bool
+	}{
+		{
+			name: "header",
+			src: "-- description: Spend by campaign\n-- customer_id: 1234567890\n-- param.start: 2026-01-01\n\n" +
+				"SELECT campaign.name FROM campaign\n-- until yesterday\nWHERE segments.date BETWEEN @start AND @end;\n",
+			want: Query{
+				Description: "Spend by campaign",
+				CustomerID:  "1234567890",
+				Params:      map[string]string{"start": "2026-01-01"},
+				Text:        "SELECT campaign.name FROM campaign\n\nWHERE segments.date BETWEEN @start AND @end",
+			},
+		},
+		{
+			name: "plain comments and name",
+			src:  "-- Reviewed every quarter.\n-- name: spend\nSELECT campaign.id FROM campaign",
+			want: Query{Params: map[string]string{}, Text: "SELECT campaign.id FROM campaign"},
+		},
+		{name: "unknown key", src: "-- custmer_id: 1234567890\nSELECT campaign.id FROM campaign", wantErr: true},
+		{name: "no query", src: "-- description: nothing\n", wantErr: true},
+		{name: "two queries", src: "SELECT campaign.id FROM campaign; SELECT ad_group.id FROM ad_group", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse("q", tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse succeeded: %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Name = "q"
+			if got.Name != tt.want.Name || got.Description != tt.want.Description || got.CustomerID != tt.want.CustomerID ||
+				got.Text != tt.want.Text || !maps.Equal(got.Params, tt.want.Params) {
+				t.Errorf("Parse = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		q    Query
+		ok   bool
+	}{
+		{"valid", Query{Name: "weekly-spend", Text: "SELECT campaign.id FROM campaign WHERE campaign.id = @id", Params: map[string]string{"id": "1"}}, true},
+		{"bad name", Query{Name: "../spend", Text: "SELECT campaign.id FROM campaign"}, false},
+		{"bad query", Query{Name: "spend", Text: "SELECT FROM campaign"}, false},
+		{"unused default", Query{Name: "spend", Text: "SELECT campaign.id FROM campaign", Params: map[string]string{"id": "1"}}, false},
+		{"two line description", Query{Name: "spend", Description: "a\nb", Text: "SELECT campaign.id FROM campaign"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.q.Check(); (err == nil) != tt.ok {
+				t.Errorf("Check() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	lib := New(t.TempDir() + "/queries")
+	if names, err := lib.Names(); err != nil || names != nil {
+		t.Fatalf("Names of a missing directory = %v, %v", names, err)
+	}
+	q := &Query{
+		Name:        "weekly-spend",
+		Description: "Spend by campaign",
+		CustomerID:  "1234567890",
+		Params:      map[string]string{"start": "2026-01-01", "end": "2026-01-07"},
+		Text:        "SELECT campaign.name FROM campaign WHERE segments.date BETWEEN @start AND @end",
+	}
+	if err := lib.Save(q, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.Save(q, false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Save over an existing query without overwrite: %v, want fs.ErrExist", err)
+	}
+	if err := lib.Save(q, true); err != nil {
+		t.Errorf("Save with overwrite: %v", err)
+	}
+	if err := lib.Save(&Query{Name: "adhoc", Text: "SELECT customer.id FROM customer"}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := lib.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"adhoc", "weekly-spend"}; !slices.Equal(names, want) {
+		t.Errorf("Names = %v, want %v", names, want)
+	}
+	got, err := lib.Load("weekly-spend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Description != q.Description || got.CustomerID != q.CustomerID || got.Text != q.Text || !maps.Equal(got.Params, q.Params) {
+		t.Errorf("Load = %+v, want %+v", *got, *q)
+	}
+	if want := []string{"start", "end"}; !slices.Equal(got.ParamNames(), want) {
+		t.Errorf("ParamNames = %v, want %v", got.ParamNames(), want)
+	}
+
+	if err := lib.Delete("adhoc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.Load("adhoc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load after Delete: %v, want ErrNotFound", err)
+	}
+	if err := lib.Delete("adhoc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,138 @@
+package repl
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// fromClause finds the resource of a statement typed so far.
+var fromClause = regexp.MustCompile(`(?i)\bFROM\s+([a-z_]+)\b`)
+
+// Complete returns completions for the word ending text, the statement
+// typed so far up to the cursor. start is the offset in text where the
+// word begins; each candidate replaces text[start:].
+//
+// After FROM, candidates are resource names; after DURING, date range
+// keywords; elsewhere, GAQL keywords and field names, restricted to the
+// fields selectable with the FROM resource once one is typed. Field
+// names complete one dotted segment at a time. Meta-commands complete
+// their names, and \format and \during their arguments.
+func Complete(cat *gaql.Catalog, text string) (start int, candidates []string) {
+	start = len(text)
+	for start > 0 && isWordByte(text[start-1]) {
+		start--
+	}
+	word := text[start:]
+	before := strings.TrimRight(text[:start], " \t\r\n")
+
+	if IsCommand(text) {
+		line := strings.TrimLeft(text, " \t")
+		if start > 0 && text[start-1] == '\\' && !strings.ContainsAny(line, " \t") {
+			var names []string
+			for name := range commands {
+				names = append(names, name)
+			}
+			return start, matching(names, word, false)
+		}
+		switch name, _, _ := strings.Cut(strings.TrimPrefix(line, `\`), " "); strings.ToLower(name) {
+		case "format":
+			names := make([]string, len(output.Formats))
+			for i, f := range output.Formats {
+				names[i] = string(f)
+			}
+			return start, matching(names, word, false)
+		case "during":
+			keywords, _ := gaql.DateRangeKeywordsFor(gaql.DefaultAPIVersion)
+			return start, matching(append(keywords, "off"), word, true)
+		}
+		return start, nil
+	}
+
+	switch previousWord(before) {
+	case "FROM":
+		var resources []string
+		for _, f := range cat.Fields() {
+			if f.Category == "RESOURCE" {
+				resources = append(resources, f.Name)
+			}
+		}
+		return start, matching(resources, word, false)
+	case "DURING":
+		keywords, _ := gaql.DateRangeKeywordsFor(gaql.DefaultAPIVersion)
+		return start, matching(keywords, word, true)
+	}
+
+	var resource *gaql.FieldInfo
+	if m := fromClause.FindStringSubmatch(text); m != nil {
+		if f, ok := cat.Field(strings.ToLower(m[1])); ok && f.Category == "RESOURCE" {
+			resource = &f
+		}
+	}
+	seen := map[string]bool{}
+	for _, f := range cat.Fields() {
+		if f.Category == "RESOURCE" || !strings.HasPrefix(f.Name, word) || !selectableWith(f, resource) {
+			continue
+		}
+		// Stop at the end of the next segment, so "camp" offers
+		// "campaign." rather than every campaign field.
+		name := f.Name
+		if i := strings.IndexByte(name[len(word):], '.'); i >= 0 {
+			name = name[:len(word)+i+1]
+		}
+		seen[name] = true
+	}
+	if !strings.Contains(word, ".") {
+		for kw := range gaql.Keywords {
+			if word != "" && strings.HasPrefix(kw, strings.ToUpper(word)) {
+				seen[kw] = true
+			}
+		}
+	}
+	for name := range seen {
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	return start, candidates
+}
+
+// selectableWith reports whether f may be selected in a query FROM
+// resource; any field may be when the resource is unknown.
+func selectableWith(f gaql.FieldInfo, resource *gaql.FieldInfo) bool {
+	if resource == nil {
+		return true
+	}
+	prefix, _, _ := strings.Cut(f.Name, ".")
+	return prefix == resource.Name || slices.Contains(resource.SelectableWith, f.Name) ||
+		f.Category == "ATTRIBUTE" && slices.Contains(resource.SelectableWith, prefix)
+}
+
+// matching returns the names starting with word, sorted. With fold, the
+// comparison ignores case.
+func matching(names []string, word string, fold bool) []string {
+	var out []string
+	for _, name := range names {
+		if strings.HasPrefix(name, word) || fold && strings.HasPrefix(strings.ToUpper(name), strings.ToUpper(word)) {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// previousWord returns the last word of s, upper-cased.
+func previousWord(s string) string {
+	i := len(s)
+	for i > 0 && isWordByte(s[i-1]) {
+		i--
+	}
+	return strings.ToUpper(s[i:])
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
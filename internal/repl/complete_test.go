@@ -0,0 +1,51 @@
+package repl
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestComplete(t *testing.T) {
+	cat := gaql.DefaultCatalog()
+	tests := []struct {
+		text      string
+		wantStart int
+		want      []string // must be among the candidates
+		notWant   []string // must not be
+	}{
+		{text: "SELECT campaign.na", wantStart: 7, want: []string{"campaign.name"}},
+		{text: "SELECT camp", wantStart: 7, want: []string{"campaign.", "campaign_budget."}, notWant: []string{"campaign.name"}},
+		{text: "SELECT metrics.cl", wantStart: 7, want: []string{"metrics.clicks"}},
+		{text: "sel", wantStart: 0, want: []string{"SELECT"}},
+		{text: "SELECT campaign.id FROM ad_gr", wantStart: 24, want: []string{"ad_group"}, notWant: []string{"ad_group."}},
+		{text: "SELECT campaign.id FROM campaign WHERE segments.date DURING last_", wantStart: 60, want: []string{"LAST_7_DAYS", "LAST_30_DAYS"}},
+		// Once FROM is typed, only selectable fields are offered.
+		{text: "SELECT campaign.name FROM campaign WHERE ad_gr", wantStart: 41, notWant: []string{"ad_group."}},
+		{text: "SELECT campaign.name FROM campaign WHERE campaign_bu", wantStart: 41, want: []string{"campaign_budget."}},
+		{text: "SELECT campaign.name\nFROM campaign WHERE segments.d", wantStart: 41, want: []string{"segments.date", "segments.device"}},
+		{text: `\fo`, wantStart: 1, want: []string{"format"}},
+		{text: `\format j`, wantStart: 8, want: []string{"json", "jsonl"}, notWant: []string{"csv"}},
+		{text: `\during y`, wantStart: 8, want: []string{"YESTERDAY"}},
+		{text: `\use 12`, wantStart: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			start, got := Complete(cat, tt.text)
+			if start != tt.wantStart {
+				t.Errorf("start = %d, want %d", start, tt.wantStart)
+			}
+			for _, w := range tt.want {
+				if !slices.Contains(got, w) {
+					t.Errorf("candidates %v lack %q", got, w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if slices.Contains(got, w) {
+					t.Errorf("candidates %v include %q", got, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInterrupt is returned by Editor.ReadLine when the user presses
+// Ctrl-C.
+var ErrInterrupt = errors.New("repl: interrupted")
+
+// Editor reads lines from a terminal with Emacs-style editing, history
+// recall, and tab completion. The terminal must be in the mode set by
+// RawMode, so keys arrive one at a time and are not echoed.
+//
+// Keys: arrows, Home/End, and Ctrl-A/E/B/F move; Backspace, Delete,
+// Ctrl-D, Ctrl-K, Ctrl-U, and Ctrl-W delete; Up/Down and Ctrl-P/N walk
+// the history; Tab completes; Ctrl-L clears the screen; Ctrl-C abandons
+// the line; Ctrl-D on an empty line ends input.
+type Editor struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// History is recalled with Up and Down; nil disables recall.
+	History *History
+
+	// Complete returns completions for the text before the cursor, as
+	// the Complete function does; nil disables completion.
+	Complete func(text string) (start int, candidates []string)
+}
+
+// NewEditor returns an editor reading keys from in and drawing on out.
+func NewEditor(in io.Reader, out io.Writer) *Editor {
+	return &Editor{in: bufio.NewReader(in), out: out}
+}
+
+// lineState is the line being edited.
+type lineState struct {
+	prompt string
+	buf    []rune
+	pos    int // cursor, as an index into buf
+}
+
+// ReadLine shows prompt and returns the line entered, without its
+// newline. It returns io.EOF when input ends or Ctrl-D is pressed on an
+// empty line, and ErrInterrupt for Ctrl-C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	l := &lineState{prompt: prompt}
+	// hist indexes the history entry shown; draft keeps the new line
+	// while older entries are shown.
+	hist, draft := 0, ""
+	if e.History != nil {
+		hist = len(e.History.Entries)
+	}
+	recall := func(i int) {
+		if e.History == nil || i < 0 || i > len(e.History.Entries) {
+			return
+		}
+		if hist == len(e.History.Entries) {
+			draft = string(l.buf)
+		}
+		hist = i
+		text := draft
+		if i < len(e.History.Entries) {
+			text = e.History.Entries[i]
+		}
+		l.buf, l.pos = []rune(text), utf8.RuneCountInString(text)
+	}
+
+	e.refresh(l)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(l.buf) > 0 {
+				fmt.Fprintln(e.out)
+				return string(l.buf), nil
+			}
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprintln(e.out)
+			return string(l.buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprintln(e.out, "^C")
+			return "", ErrInterrupt
+		case 4: // Ctrl-D
+			if len(l.buf) == 0 {
+				fmt.Fprintln(e.out)
+				return "", io.EOF
+			}
+			l.delete(l.pos, l.pos+1)
+		case 1: // Ctrl-A
+			l.pos = 0
+		case 5: // Ctrl-E
+			l.pos = len(l.buf)
+		case 2: // Ctrl-B
+			l.pos = max(l.pos-1, 0)
+		case 6: // Ctrl-F
+			l.pos = min(l.pos+1, len(l.buf))
+		case 8, 127: // Backspace
+			if l.pos > 0 {
+				l.delete(l.pos-1, l.pos)
+			}
+		case 11: // Ctrl-K
+			l.delete(l.pos, len(l.buf))
+		case 21: // Ctrl-U
+			l.delete(0, l.pos)
+		case 23: // Ctrl-W
+			i := l.pos
+			for i > 0 && l.buf[i-1] == ' ' {
+				i--
+			}
+			for i > 0 && l.buf[i-1] != ' ' {
+				i--
+			}
+			l.delete(i, l.pos)
+		case 12: // Ctrl-L
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case 16: // Ctrl-P
+			recall(hist - 1)
+		case 14: // Ctrl-N
+			recall(hist + 1)
+		case '\t':
+			e.complete(l)
+		case 27: // escape sequence
+			switch e.escape() {
+			case "[A", "OA":
+				recall(hist - 1)
+			case "[B", "OB":
+				recall(hist + 1)
+			case "[C", "OC":
+				l.pos = min(l.pos+1, len(l.buf))
+			case "[D", "OD":
+				l.pos = max(l.pos-1, 0)
+			case "[H", "OH", "[1~":
+				l.pos = 0
+			case "[F", "OF", "[4~":
+				l.pos = len(l.buf)
+			case "[3~":
+				l.delete(l.pos, l.pos+1)
+			}
+		default:
+			if r >= ' ' {
+				l.buf = append(l.buf[:l.pos], append([]rune{r}, l.buf[l.pos:]...)...)
+				l.pos++
+			}
+		}
+		e.refresh(l)
+	}
+}
+
+// escape reads the rest of an escape sequence: [ or O, any parameter
+// bytes, and the final byte.
+func (e *Editor) escape() string {
+	var sb strings.Builder
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			return sb.String()
+		}
+		sb.WriteByte(b)
+		if sb.Len() > 1 && (b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b == '~') {
+			return sb.String()
+		}
+	}
+}
+
+// delete removes buf[i:j], clamped to the line, leaving the cursor at i.
+func (l *lineState) delete(i, j int) {
+	j = min(j, len(l.buf))
+	if i >= j {
+		return
+	}
+	l.buf = append(l.buf[:i], l.buf[j:]...)
+	l.pos = i
+}
+
+// complete replaces the word before the cursor with its only completion,
+// or with the prefix the completions share; when that adds nothing, it
+// lists them.
+func (e *Editor) complete(l *lineState) {
+	if e.Complete == nil {
+		return
+	}
+	text := string(l.buf[:l.pos])
+	start, candidates := e.Complete(text)
+	if len(candidates) == 0 {
+		return
+	}
+	word := text[start:]
+	repl := candidates[0]
+	for _, c := range candidates[1:] {
+		repl = commonPrefix(repl, c)
+	}
+	if len(candidates) == 1 && !strings.HasSuffix(repl, ".") {
+		repl += " "
+	}
+	if repl == word && len(candidates) > 1 {
+		fmt.Fprintf(e.out, "\r\n%s\n", strings.Join(candidates, "  "))
+		return
+	}
+	if len(repl) < len(word) {
+		// A case-insensitive match shorter than the word typed.
+		return
+	}
+	head := []rune(text[:start] + repl)
+	l.buf = append(head, l.buf[l.pos:]...)
+	l.pos = len(head)
+}
+
+// commonPrefix returns the longest common prefix of a and b, ignoring
+// case; the result keeps the case of a.
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && strings.EqualFold(a[n:n+1], b[n:n+1]) {
+		n++
+	}
+	return a[:n]
+}
+
+// refresh redraws the line and places the cursor.
+func (e *Editor) refresh(l *lineState) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", l.prompt, string(l.buf))
+	if back := len(l.buf) - l.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
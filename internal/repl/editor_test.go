@@ -0,0 +1,59 @@
+package repl
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEditorReadLine(t *testing.T) {
+	history := &History{Entries: []string{"SELECT campaign.id FROM campaign", "SELECT ad_group.id FROM ad_group"}}
+	tests := []struct {
+		name    string
+		keys    string
+		want    string
+		wantErr error
+	}{
+		{name: "plain", keys: "SELECT 1\r", want: "SELECT 1"},
+		{name: "backspace", keys: "SELECX\x7fT\r", want: "SELECT"},
+		{name: "home and insert", keys: "ELECT\x01S\r", want: "SELECT"},
+		{name: "arrows", keys: "SLECT\x1b[D\x1b[D\x1b[D\x1b[DE\r", want: "SELECT"},
+		{name: "kill to end", keys: "SELECT campaign\x1b[D\x1b[D\x0b\r", want: "SELECT campai"},
+		{name: "kill word", keys: "SELECT campaign.id \x17\r", want: "SELECT "},
+		{name: "kill line", keys: "SELECT\x15FROM\r", want: "FROM"},
+		{name: "delete key", keys: "SELECT\x01\x1b[3~\r", want: "ELECT"},
+		{name: "history up", keys: "\x1b[A\r", want: "SELECT ad_group.id FROM ad_group"},
+		{name: "history up twice", keys: "\x10\x10\r", want: "SELECT campaign.id FROM campaign"},
+		{name: "history back to draft", keys: "SEL\x1b[A\x1b[B\r", want: "SEL"},
+		{name: "complete one", keys: "SELECT campaign.na\t\r", want: "SELECT campaign.name "},
+		{name: "complete prefix", keys: "SELECT campaign.bidding_strat\t\r", want: "SELECT campaign.bidding_strategy"},
+		{name: "ctrl-c", keys: "SELECT\x03", wantErr: ErrInterrupt},
+		{name: "ctrl-d", keys: "\x04", wantErr: io.EOF},
+		{name: "ctrl-d deletes", keys: "SELECT\x01\x04\r", want: "ELECT"},
+		{name: "end of input", keys: "SELECT", want: "SELECT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ed := NewEditor(strings.NewReader(tt.keys), io.Discard)
+			ed.History = history
+			ed.Complete = func(text string) (int, []string) {
+				start := strings.LastIndexByte(text, ' ') + 1
+				var out []string
+				for _, c := range []string{"campaign.name", "campaign.bidding_strategy", "campaign.bidding_strategy_type"} {
+					if strings.HasPrefix(c, text[start:]) {
+						out = append(out, c)
+					}
+				}
+				return start, out
+			}
+			got, err := ed.ReadLine("adtap> ")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultHistorySize is the number of entries a History keeps.
+const DefaultHistorySize = 1000
+
+// History is the list of statements entered in past sessions, oldest
+// first, persisted one per line.
+type History struct {
+	Path    string // file the history is saved to; empty keeps it in memory
+	Max     int    // entries kept; zero means DefaultHistorySize
+	Entries []string
+}
+
+// DefaultHistoryPath returns the file named by ADTAP_HISTORY, or history
+// in the adtap directory of the user config directory.
+func DefaultHistoryPath() string {
+	if path := os.Getenv("ADTAP_HISTORY"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "adtap", "history")
+}
+
+// LoadHistory reads the history saved at path. A missing file is an
+// empty history.
+func LoadHistory(path string) (*History, error) {
+	h := &History{Path: path}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repl: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			h.Entries = append(h.Entries, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("repl: reading history: %w", err)
+	}
+	h.trim()
+	return h, nil
+}
+
+// Add appends an entry, joining the lines of a multi-line statement with
+// spaces. Blank entries and repeats of the last entry are skipped.
+func (h *History) Add(entry string) {
+	entry = strings.Join(strings.Fields(entry), " ")
+	if entry == "" || len(h.Entries) > 0 && h.Entries[len(h.Entries)-1] == entry {
+		return
+	}
+	h.Entries = append(h.Entries, entry)
+	h.trim()
+}
+
+func (h *History) trim() {
+	max := h.Max
+	if max == 0 {
+		max = DefaultHistorySize
+	}
+	if len(h.Entries) > max {
+		h.Entries = h.Entries[len(h.Entries)-max:]
+	}
+}
+
+// Save writes the history to Path, readable only by its owner since
+// queries may name accounts.
+func (h *History) Save() error {
+	if h.Path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.Path), 0o700); err != nil {
+		return fmt.Errorf("repl: saving history: %w", err)
+	}
+	var sb strings.Builder
+	for _, e := range h.Entries {
+		sb.WriteString(e)
+		sb.WriteByte('\n')
+	}
+	if err := os.WriteFile(h.Path, []byte(sb.String()), 0o600); err != nil {
+		return fmt.Errorf("repl: saving history: %w", err)
+	}
+	return nil
+}
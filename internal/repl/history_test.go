@@ -0,0 +1,42 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "adtap", "history")
+	h, err := LoadHistory(path)
+	if err != nil || len(h.Entries) != 0 {
+		t.Fatalf("a missing file should be an empty history, got %v, %v", h.Entries, err)
+	}
+	h.Max = 3
+	for _, e := range []string{
+		"SELECT campaign.id\n  FROM campaign;",
+		"SELECT campaign.id FROM campaign;", // the same statement on one line
+		"   ",
+		`\use 1234567890`,
+		"SELECT ad_group.id FROM ad_group;",
+		"SELECT customer.id FROM customer;",
+	} {
+		h.Add(e)
+	}
+	want := []string{`\use 1234567890`, "SELECT ad_group.id FROM ad_group;", "SELECT customer.id FROM customer;"}
+	if !slices.Equal(h.Entries, want) {
+		t.Errorf("entries = %q, want %q", h.Entries, want)
+	}
+
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("history file mode = %v, %v; want 0600", fi.Mode().Perm(), err)
+	}
+	loaded, err := LoadHistory(path)
+	if err != nil || !slices.Equal(loaded.Entries, want) {
+		t.Errorf("reloaded %q, %v; want %q", loaded.Entries, err, want)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package repl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RawMode puts the terminal f into the mode Editor expects: keys are
+// delivered as typed, without echo or signal generation. It returns a
+// function restoring the previous mode. The mode is set with stty(1), so
+// it fails where that is unavailable.
+func RawMode(f *os.File) (restore func() error, err error) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = f
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("repl: reading terminal mode: %w", err)
+	}
+	if _, err := stty("-icanon", "-echo", "-isig", "min", "1", "time", "0"); err != nil {
+		return nil, fmt.Errorf("repl: setting terminal mode: %w", err)
+	}
+	return func() error {
+		_, err := stty(saved)
+		return err
+	}, nil
+}
@@ -0,0 +1,55 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// Console mode flags; see the SetConsoleMode documentation.
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableProcessedOutput           = 0x0001
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+// RawMode puts the console f into the mode Editor expects: keys are
+// delivered as typed, without echo or Ctrl-C handling, and arrive as the
+// escape sequences of a VT terminal. Standard output is switched to VT
+// processing so the escape sequences Editor draws with take effect. It
+// returns a function restoring both previous modes. It fails on consoles
+// without VT support, which predate Windows 10.
+func RawMode(f *os.File) (restore func() error, err error) {
+	in, out := syscall.Handle(f.Fd()), syscall.Handle(os.Stdout.Fd())
+	var inMode, outMode uint32
+	if err := syscall.GetConsoleMode(in, &inMode); err != nil {
+		return nil, fmt.Errorf("repl: reading terminal mode: %w", err)
+	}
+	if err := syscall.GetConsoleMode(out, &outMode); err != nil {
+		return nil, fmt.Errorf("repl: reading terminal mode: %w", err)
+	}
+	raw := inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if err := setConsoleMode(in, raw); err != nil {
+		return nil, fmt.Errorf("repl: setting terminal mode: %w", err)
+	}
+	if err := setConsoleMode(out, outMode|enableProcessedOutput|enableVirtualTerminalProcessing); err != nil {
+		setConsoleMode(in, inMode)
+		return nil, fmt.Errorf("repl: setting terminal mode: %w", err)
+	}
+	return func() error {
+		return errors.Join(setConsoleMode(in, inMode), setConsoleMode(out, outMode))
+	}, nil
+}
+
+func setConsoleMode(h syscall.Handle, mode uint32) error {
+	if ok, _, err := procSetConsoleMode.Call(uintptr(h), uintptr(mode)); ok == 0 {
+		return err
+	}
+	return nil
+}
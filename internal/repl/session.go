@@ -0,0 +1,200 @@
+// Package repl holds the state of an interactive GAQL session.
+//
+// A Session remembers settings that apply to every query typed in it, so
+// exploration does not repeat them: the customer queries run against and
+// a default date range for metric queries that have none, a row limit,
+// and the output format. Meta-commands, lines starting with a backslash,
+// change the settings:
+//
+//	\use 123-456-7890     run queries against this customer (also \customer)
+//	\during LAST_7_DAYS   add this date range to metric queries without one
+//	\during off           stop adding a date range
+//	\limit 100            add LIMIT 100 to queries without a LIMIT (0 for none)
+//	\format csv           print results as CSV
+//
+// The adtap repl command reads lines with an Editor, which offers
+// completion of field and resource names from the catalog (see Complete)
+// and keeps a History.
+//
+// # Basic Usage
+//
+//	s := &repl.Session{}
+//	msg, err := s.Command(`\use 1234567890`)
+//	q, diags, err := s.Prepare("SELECT campaign.name, metrics.clicks FROM campaign")
+//	resp, err := client.Search(ctx, s.CustomerID, q.String())
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// ErrNoCustomer is returned by Prepare before a customer is selected.
+var ErrNoCustomer = errors.New(`repl: no customer selected (use \use 1234567890)`)
+
+// Session is the state shared by the queries of an interactive session.
+type Session struct {
+	// CustomerID is the customer queries run against.
+	CustomerID string
+
+	// During is the DURING keyword added to metric queries that lack
+	// date context, or empty to add none.
+	During string
+
+	// Limit is added as the LIMIT of queries without one; zero adds none.
+	Limit int
+
+	// Format is the output format for results; empty means table.
+	Format output.Format
+}
+
+// command is a meta-command handler. It receives the text after the
+// command name and returns a message to show.
+type command struct {
+	usage string
+	run   func(s *Session, arg string) (string, error)
+}
+
+var commands = map[string]command{
+	"use":      {usage: `\use [CUSTOMER_ID]`, run: useCustomer},
+	"customer": {usage: `\customer [CUSTOMER_ID]`, run: useCustomer},
+	"during": {
+		usage: `\during [KEYWORD|off]`,
+		run: func(s *Session, arg string) (string, error) {
+			switch {
+			case arg == "":
+				if s.During == "" {
+					return "no default date range", nil
+				}
+				return "default date range " + s.During, nil
+			case strings.EqualFold(arg, "off"):
+				s.During = ""
+				return "no default date range", nil
+			}
+			dr, ok := gaql.LookupDateRange(arg)
+			if !ok || dr == gaql.DateRangeCustom {
+				return "", fmt.Errorf("repl: unknown date range %q", arg)
+			}
+			s.During = dr.String()
+			return "default date range " + s.During, nil
+		},
+	},
+	"limit": {
+		usage: `\limit [N|off]`,
+		run: func(s *Session, arg string) (string, error) {
+			switch {
+			case arg == "":
+			case strings.EqualFold(arg, "off"):
+				s.Limit = 0
+			default:
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 0 {
+					return "", fmt.Errorf("repl: invalid limit %q (expected a row count, or off)", arg)
+				}
+				s.Limit = n
+			}
+			if s.Limit == 0 {
+				return "no default limit", nil
+			}
+			return fmt.Sprintf("default limit %d", s.Limit), nil
+		},
+	},
+	"format": {
+		usage: `\format [FORMAT]`,
+		run: func(s *Session, arg string) (string, error) {
+			if arg != "" {
+				f, err := output.ParseFormat(arg)
+				if err != nil {
+					return "", fmt.Errorf("repl: %w", err)
+				}
+				s.Format = f
+			}
+			return "format " + string(s.OutputFormat()), nil
+		},
+	},
+}
+
+// useCustomer runs \use and \customer.
+func useCustomer(s *Session, arg string) (string, error) {
+	if arg == "" {
+		if s.CustomerID == "" {
+			return "no customer selected", nil
+		}
+		return "using customer " + s.CustomerID, nil
+	}
+	id, err := adsapi.NormalizeCustomerID(arg)
+	if err != nil {
+		return "", fmt.Errorf("repl: invalid customer ID %q (expected 10 digits, e.g. 1234567890)", arg)
+	}
+	s.CustomerID = id
+	return "using customer " + id, nil
+}
+
+// OutputFormat returns the session's output format, table by default.
+func (s *Session) OutputFormat() output.Format {
+	if s.Format == "" {
+		return output.FormatTable
+	}
+	return s.Format
+}
+
+// IsCommand reports whether line is a meta-command rather than a query.
+func IsCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), `\`)
+}
+
+// Command runs a meta-command line such as `\use 1234567890` and returns
+// a message describing the resulting state.
+func (s *Session) Command(line string) (string, error) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), `\`), " ")
+	cmd, ok := commands[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf(`repl: unknown command \%s (expected one of %s)`, name, strings.Join(Commands(), ", "))
+	}
+	return cmd.run(s, strings.TrimSpace(arg))
+}
+
+// Commands returns the usage of every meta-command, sorted.
+func Commands() []string {
+	usages := make([]string, 0, len(commands))
+	for _, c := range commands {
+		usages = append(usages, c.usage)
+	}
+	sort.Strings(usages)
+	return usages
+}
+
+// Prepare parses and validates a query typed in the session. Metric
+// queries without date context get the session's date range, reported
+// as a date-context-added diagnostic, and queries without a LIMIT get the
+// session's limit. It fails with ErrNoCustomer until
+// a customer is selected, so a query is never sent without one.
+func (s *Session) Prepare(query string) (*gaql.Query, []gaql.Diagnostic, error) {
+	if s.CustomerID == "" {
+		return nil, nil, ErrNoCustomer
+	}
+	q, err := gaql.Parse(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := gaql.NewValidator()
+	if dr, ok := gaql.LookupDateRange(s.During); ok && s.During != "" {
+		v.AutoAddDateContext = true
+		v.DefaultDateRange = dr
+	}
+	q, diags, err := v.Prepare(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if q.Limit == 0 && s.Limit > 0 {
+		q.Limit = s.Limit
+	}
+	return q, diags, nil
+}
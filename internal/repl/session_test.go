@@ -0,0 +1,96 @@
+package repl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSessionCommands(t *testing.T) {
+	tests := []struct {
+		line     string
+		want     string
+		wantErr  string
+		customer string
+		during   string
+		limit    int
+		format   string
+	}{
+		{line: `\use`, want: "no customer selected"},
+		{line: `\use 123-456-7890`, want: "using customer 1234567890", customer: "1234567890"},
+		{line: `\use 12345`, wantErr: "invalid customer ID", customer: "1234567890"},
+		{line: `\during last_7_days`, want: "default date range LAST_7_DAYS", customer: "1234567890", during: "LAST_7_DAYS"},
+		{line: `\during NEXT_WEEK`, wantErr: "unknown date range", customer: "1234567890", during: "LAST_7_DAYS"},
+		{line: `\during`, want: "default date range LAST_7_DAYS", customer: "1234567890", during: "LAST_7_DAYS"},
+		{line: `\during off`, want: "no default date range", customer: "1234567890"},
+		{line: `\frobnicate`, wantErr: `unknown command \frobnicate`, customer: "1234567890"},
+		{line: `\customer 2345678901`, want: "using customer 2345678901", customer: "2345678901"},
+		{line: `\limit 50`, want: "default limit 50", customer: "2345678901", limit: 50},
+		{line: `\limit -1`, wantErr: "invalid limit", customer: "2345678901", limit: 50},
+		{line: `\limit off`, want: "no default limit", customer: "2345678901"},
+		{line: `\format`, want: "format table", customer: "2345678901"},
+		{line: `\format CSV`, want: "format csv", customer: "2345678901", format: "csv"},
+		{line: `\format yaml`, wantErr: "invalid format", customer: "2345678901", format: "csv"},
+	}
+
+	// The commands run in order against one session.
+	s := &Session{}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if !IsCommand(tt.line) {
+				t.Fatalf("IsCommand(%q) = false", tt.line)
+			}
+			got, err := s.Command(tt.line)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil || got != tt.want {
+				t.Errorf("got %q, %v; want %q", got, err, tt.want)
+			}
+			if s.CustomerID != tt.customer || s.During != tt.during || s.Limit != tt.limit || string(s.Format) != tt.format {
+				t.Errorf("session is %+v", *s)
+			}
+		})
+	}
+}
+
+func TestSessionPrepare(t *testing.T) {
+	s := &Session{}
+	const query = "SELECT campaign.name, metrics.clicks FROM campaign"
+	if _, _, err := s.Prepare(query); !errors.Is(err, ErrNoCustomer) {
+		t.Fatalf("expected ErrNoCustomer, got %v", err)
+	}
+
+	s.CustomerID = "1234567890"
+	if _, _, err := s.Prepare(query); err == nil || !strings.Contains(err.Error(), "segments.date") {
+		t.Errorf("expected a missing date context error, got %v", err)
+	}
+
+	s.During = "LAST_7_DAYS"
+	q, diags, err := s.Prepare(query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := query + " WHERE segments.date DURING LAST_7_DAYS"; q.String() != want {
+		t.Errorf("got:  %s\nwant: %s", q, want)
+	}
+	if len(diags) == 0 || diags[0].Code != "date-context-added" {
+		t.Errorf("unexpected diagnostics %v", diags)
+	}
+
+	q, _, err = s.Prepare(query + " WHERE segments.date DURING YESTERDAY")
+	if err != nil || !strings.HasSuffix(q.String(), "DURING YESTERDAY") {
+		t.Errorf("an explicit date range must win, got %v, %v", q, err)
+	}
+
+	s.Limit = 10
+	q, _, err = s.Prepare("SELECT campaign.name FROM campaign")
+	if err != nil || q.String() != "SELECT campaign.name FROM campaign LIMIT 10" {
+		t.Errorf("expected the session limit, got %v, %v", q, err)
+	}
+	q, _, err = s.Prepare("SELECT campaign.name FROM campaign LIMIT 3")
+	if err != nil || q.Limit != 3 {
+		t.Errorf("an explicit LIMIT must win, got %v, %v", q, err)
+	}
+}
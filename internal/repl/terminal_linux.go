@@ -0,0 +1,5 @@
+package repl
+
+import "syscall"
+
+const ioctlReadTermios = syscall.TCGETS
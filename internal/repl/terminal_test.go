@@ -0,0 +1,27 @@
+package repl
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	// The null device is a character device, but not a terminal.
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	if IsTerminal(null) {
+		t.Errorf("IsTerminal(%s) = true", os.DevNull)
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsTerminal(f) {
+		t.Error("IsTerminal(regular file) = true")
+	}
+}
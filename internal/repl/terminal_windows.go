@@ -0,0 +1,13 @@
+package repl
+
+import (
+	"os"
+	"syscall"
+)
+
+// IsTerminal reports whether f is a console. Other character devices,
+// such as NUL, are not.
+func IsTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
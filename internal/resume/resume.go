@@ -0,0 +1,104 @@
+// Package resume keeps the progress of a search streamed into a file,
+// so a run killed after hundreds of thousands of rows continues where it
+// stopped instead of starting over.
+//
+// A Checkpoint holds the adsapi.Cursor of the search and the length of
+// the output when the rows before the cursor were written. Resuming cuts
+// the output back to that length, dropping rows written after the
+// checkpoint, and reads on from the cursor.
+//
+// # Basic Usage
+//
+//	cp, _ := resume.Load(path, resume.Checkpoint{CustomerID: id, Query: q, Format: "csv"})
+//	file.Truncate(cp.Offset)
+//	next := client.SearchIterAt(ctx, id, q, &cp.Cursor)
+//	for ... {
+//		// write rows; at page boundaries:
+//		cp.Offset, _ = file.Seek(0, io.SeekCurrent)
+//		cp.Save(path)
+//	}
+package resume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+// Checkpoint is the progress of a search into an output file. The
+// fields other than Cursor, Rows, and Offset identify the search, so a
+// checkpoint is only resumed by the same one.
+type Checkpoint struct {
+	CustomerID string `json:"customer_id"`
+	Query      string `json:"query"`
+	Format     string `json:"format"`
+
+	// Cursor is the position of the next row to read.
+	Cursor adsapi.Cursor `json:"cursor"`
+
+	// Rows is the number of rows written before the cursor.
+	Rows int64 `json:"rows"`
+
+	// Offset is the length of the output after those rows.
+	Offset int64 `json:"offset"`
+}
+
+// Started reports whether the checkpoint has rows written, so the
+// output already has its header.
+func (c Checkpoint) Started() bool {
+	return c.Offset > 0
+}
+
+// ErrMismatch is returned by Load when a checkpoint belongs to another
+// search.
+var ErrMismatch = errors.New("resume: the checkpoint is of another search")
+
+// Load reads the checkpoint at path and checks that it belongs to the
+// same search as c, which it returns with the progress filled in. A
+// missing checkpoint returns c unchanged, to start from the first row.
+func Load(path string, c Checkpoint) (Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("resume: %w", err)
+	}
+	var saved Checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return c, fmt.Errorf("resume: reading %s: %w", path, err)
+	}
+	if saved.CustomerID != c.CustomerID || saved.Query != c.Query || saved.Format != c.Format {
+		return c, fmt.Errorf("%w: %s", ErrMismatch, path)
+	}
+	return saved, nil
+}
+
+// Save writes the checkpoint to path, replacing the file atomically so
+// an interruption leaves the previous checkpoint intact.
+func (c Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("resume: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("resume: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("resume: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package resume
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv.checkpoint")
+	c := Checkpoint{CustomerID: "1234567890", Query: "SELECT campaign.id FROM campaign", Format: "csv"}
+
+	got, err := Load(path, c)
+	if err != nil || got != c || got.Started() {
+		t.Fatalf("Load without a checkpoint = %+v, %v", got, err)
+	}
+	saved := c
+	saved.Cursor = adsapi.Cursor{PageToken: "page-2", Offset: 17}
+	saved.Rows, saved.Offset = 10017, 524288
+	if err := saved.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = Load(path, c); err != nil || got != saved || !got.Started() {
+		t.Errorf("Load = %+v, %v; want %+v", got, err, saved)
+	}
+
+	other := c
+	other.Format = "jsonl"
+	if _, err := Load(path, other); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Load of another search: err = %v, want ErrMismatch", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Package rowflat flattens result rows into maps keyed by GAQL field
+// name, so formatters and exporters need not walk rows themselves.
+//
+// The REST interface returns each GoogleAdsRow as nested JSON objects,
+// one per resource, with lowerCamelCase keys: campaign.bidding_strategy_type
+// arrives as {"campaign": {"biddingStrategyType": ...}}. Flatten turns
+// that into {"campaign.bidding_strategy_type": ...} for the selected
+// fields.
+//
+// Fields the API leaves out, such as the unset members of a oneof
+// (ad_group_criterion.keyword.text on a location criterion) or a
+// segment with no value, flatten to nil rather than being dropped, so
+// every row has every selected field. Repeated fields keep their values
+// as a []any; a path that continues into a repeated message collects
+// the field from each element, so
+// ad_group_ad.ad.responsive_search_ad.headlines.text lists the text of
+// every headline.
+//
+// # Basic Usage
+//
+//	for _, row := range resp.Results {
+//		flat := rowflat.Flatten(row, q.FieldNames())
+//		fmt.Println(flat["campaign.id"], flat["metrics.clicks"])
+//	}
+//
+// Values fills a slice in field order instead, reusing it across rows:
+//
+//	values := make([]any, len(fields))
+//	for _, row := range resp.Results {
+//		rowflat.Values(row, fields, values)
+//		...
+//	}
+//
+// Streams of many rows should compile the fields once, so their paths
+// are not split and converted to JSON names again for every row:
+//
+//	flat := rowflat.Compile(fields)
+//	for _, row := range resp.Results {
+//		flat.Values(row, values)
+//		...
+//	}
+package rowflat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Flatten returns the values of fields in row keyed by field name. Fields
+// missing from row are nil.
+func Flatten(row map[string]any, fields []string) map[string]any {
+	flat := make(map[string]any, len(fields))
+	for _, f := range fields {
+		flat[f], _ = Lookup(row, f)
+	}
+	return flat
+}
+
+// Values stores the value of each field of row in values, which must be
+// at least as long as fields, and returns it. Fields missing from row
+// are nil.
+func Values(row map[string]any, fields []string, values []any) []any {
+	for i, f := range fields {
+		values[i], _ = Lookup(row, f)
+	}
+	return values
+}
+
+// Fields are GAQL fields compiled for flattening many rows: the path of
+// each is split and converted to JSON names once, so Values allocates
+// nothing for rows without repeated fields.
+type Fields struct {
+	names []string
+	paths [][]string
+}
+
+// Compile prepares fields for Fields.Values.
+func Compile(fields []string) Fields {
+	f := Fields{names: fields, paths: make([][]string, len(fields))}
+	for i, name := range fields {
+		f.paths[i] = jsonPath(name)
+	}
+	return f
+}
+
+// Names returns the fields as passed to Compile.
+func (f Fields) Names() []string {
+	return f.names
+}
+
+// Values is the package's Values for the compiled fields: it stores the
+// value of each field of row in values, which must be at least as long
+// as the fields, and returns it.
+func (f Fields) Values(row map[string]any, values []any) []any {
+	for i, path := range f.paths {
+		values[i], _ = lookup(row, path)
+	}
+	return values
+}
+
+// Lookup returns the value of a GAQL field in a row and whether the row
+// has it. Once the path reaches a repeated field, the rest of it is
+// looked up in every element, and the values found are returned as a
+// []any.
+func Lookup(row map[string]any, field string) (any, bool) {
+	return lookup(row, jsonPath(field))
+}
+
+// jsonPath splits a field into the JSON keys of its path.
+func jsonPath(field string) []string {
+	path := strings.Split(field, ".")
+	for i, part := range path {
+		path[i] = JSONName(part)
+	}
+	return path
+}
+
+// lookup follows path, a list of JSON keys, from v.
+func lookup(v any, path []string) (any, bool) {
+	for i, key := range path {
+		switch cur := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = cur[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			out := make([]any, 0, len(cur))
+			for _, elem := range cur {
+				if ev, ok := lookup(elem, path[i:]); ok {
+					out = append(out, ev)
+				}
+			}
+			return out, true
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// JSONName returns the JSON key of a snake_case field path segment, such
+// as advertisingChannelType for advertising_channel_type.
+func JSONName(part string) string {
+	if !strings.Contains(part, "_") {
+		return part
+	}
+	var sb strings.Builder
+	sb.Grow(len(part))
+	upper := false
+	for _, r := range part {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			sb.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
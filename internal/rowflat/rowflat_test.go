@@ -0,0 +1,117 @@
+package rowflat
+
+import (
+	"reflect"
+	"testing"
+)
+
+var row = map[string]any{
+	"campaign": map[string]any{
+		"id":                     "123",
+		"advertisingChannelType": "SEARCH",
+		"frequencyCaps": []any{
+			map[string]any{"cap": 3, "key": map[string]any{"level": "AD_GROUP"}},
+			map[string]any{"cap": 5},
+		},
+	},
+	"adGroupAd": map[string]any{
+		"ad": map[string]any{
+			"finalUrls": []any{"https://example.com/a", "https://example.com/b"},
+			"responsiveSearchAd": map[string]any{
+				"headlines": []any{
+					map[string]any{"text": "Tents", "pinnedField": "HEADLINE_1"},
+					map[string]any{"text": "Free shipping"},
+				},
+			},
+		},
+	},
+	"metrics": map[string]any{"clicks": "7"},
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		field string
+		want  any
+		ok    bool
+	}{
+		{"campaign.id", "123", true},
+		{"campaign.advertising_channel_type", "SEARCH", true},
+		{"metrics.clicks", "7", true},
+		{"ad_group_ad.ad.final_urls", []any{"https://example.com/a", "https://example.com/b"}, true},
+		// Repeated messages: the rest of the path is read from each element.
+		{"ad_group_ad.ad.responsive_search_ad.headlines.text", []any{"Tents", "Free shipping"}, true},
+		{"ad_group_ad.ad.responsive_search_ad.headlines.pinned_field", []any{"HEADLINE_1"}, true},
+		{"campaign.frequency_caps.key.level", []any{"AD_GROUP"}, true},
+		// Unset oneof members and absent fields.
+		{"ad_group_ad.ad.expanded_text_ad.headline_part1", nil, false},
+		{"segments.date", nil, false},
+		// A path continuing past a scalar.
+		{"campaign.id.value", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := Lookup(row, tt.field)
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.ok {
+			t.Errorf("Lookup(%s) = %#v, %v; want %#v, %v", tt.field, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	got := Flatten(row, []string{"campaign.id", "metrics.clicks", "segments.device"})
+	want := map[string]any{"campaign.id": "123", "metrics.clicks": "7", "segments.device": nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Flatten = %#v, want %#v", got, want)
+	}
+}
+
+func TestValues(t *testing.T) {
+	values := []any{"stale", "stale"}
+	got := Values(row, []string{"campaign.id", "ad_group_criterion.keyword.text"}, values)
+	if want := []any{"123", nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Values = %#v, want %#v", got, want)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	fields := []string{"campaign.id", "campaign.advertising_channel_type", "ad_group_ad.ad.responsive_search_ad.headlines.text", "segments.date"}
+	flat := Compile(fields)
+	got := flat.Values(row, make([]any, len(fields)))
+	if want := Values(row, fields, make([]any, len(fields))); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields.Values = %#v, want %#v", got, want)
+	}
+	if !reflect.DeepEqual(flat.Names(), fields) {
+		t.Errorf("Names = %v", flat.Names())
+	}
+}
+
+func BenchmarkValues(b *testing.B) {
+	fields := []string{"campaign.id", "campaign.advertising_channel_type", "metrics.clicks", "segments.date", "ad_group_ad.ad.final_urls"}
+	values := make([]any, len(fields))
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Values(row, fields, values)
+		}
+	})
+	b.Run("compiled", func(b *testing.B) {
+		flat := Compile(fields)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			flat.Values(row, values)
+		}
+	})
+}
+
+func TestJSONName(t *testing.T) {
+	tests := map[string]string{
+		"id":                       "id",
+		"advertising_channel_type": "advertisingChannelType",
+		"headline_part1":           "headlinePart1",
+	}
+	for in, want := range tests {
+		if got := JSONName(in); got != want {
+			t.Errorf("JSONName(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
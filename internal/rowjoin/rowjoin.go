@@ -0,0 +1,212 @@
+// Package rowjoin joins the rows of two queries client-side. GAQL
+// selects from one resource at a time, and some views need fields the
+// resource cannot reach: a campaign's budget is a campaign_budget row,
+// and a keyword's quality score and its keyword_view metrics come from
+// separate queries.
+//
+// Rows are joined on keys, pairs of a left and a right field whose
+// values must be equal. Keys infers them from the two queries:
+//
+//   - a left field naming the right resource, such as
+//     campaign.campaign_budget, pairs with the right query's
+//     campaign_budget.resource_name, and the other way around;
+//   - otherwise, the resource names, IDs, and segments both queries
+//     select pair with themselves, so two queries selecting campaign.id
+//     and segments.date join row by row per campaign and day.
+//
+// # Basic Usage
+//
+//	keys, err := rowjoin.Keys(left, right)
+//	if err != nil {
+//		return err
+//	}
+//	fields, rows, err := rowjoin.Join(
+//		rowjoin.Table{Fields: left.FieldNames(), Rows: leftRows},
+//		rowjoin.Table{Fields: right.FieldNames(), Rows: rightRows},
+//		keys, rowjoin.Inner)
+package rowjoin
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Kind selects the rows a join keeps.
+type Kind int
+
+const (
+	// Inner keeps the pairs of left and right rows whose keys match.
+	Inner Kind = iota
+
+	// Left also keeps the left rows no right row matches, with nil
+	// right values.
+	Left
+)
+
+// ParseKind parses "inner" or "left".
+func ParseKind(s string) (Kind, error) {
+	switch strings.ToLower(s) {
+	case "inner":
+		return Inner, nil
+	case "left":
+		return Left, nil
+	}
+	return 0, fmt.Errorf("rowjoin: unknown join type %q (expected inner or left)", s)
+}
+
+// Key is a pair of fields whose values join a left and a right row.
+type Key struct {
+	Left, Right string
+}
+
+func (k Key) String() string {
+	if k.Left == k.Right {
+		return k.Left
+	}
+	return k.Left + "=" + k.Right
+}
+
+// ParseKeys parses a comma-separated list of keys, each LEFT=RIGHT or a
+// field both sides select.
+func ParseKeys(s string) ([]Key, error) {
+	var keys []Key
+	for _, part := range strings.Split(s, ",") {
+		l, r, ok := strings.Cut(part, "=")
+		l, r = strings.TrimSpace(l), strings.TrimSpace(r)
+		if !ok {
+			r = l
+		}
+		if l == "" || r == "" {
+			return nil, fmt.Errorf("rowjoin: invalid key %q (expected LEFT=RIGHT or a field)", part)
+		}
+		keys = append(keys, Key{l, r})
+	}
+	return keys, nil
+}
+
+// Keys infers the keys joining the rows of left and right, as the
+// package documentation describes.
+func Keys(left, right *gaql.Query) ([]Key, error) {
+	lf, rf := left.FieldNames(), right.FieldNames()
+	if slices.Contains(lf, left.From+"."+right.From) && slices.Contains(rf, right.From+".resource_name") {
+		return []Key{{left.From + "." + right.From, right.From + ".resource_name"}}, nil
+	}
+	if slices.Contains(rf, right.From+"."+left.From) && slices.Contains(lf, left.From+".resource_name") {
+		return []Key{{left.From + ".resource_name", right.From + "." + left.From}}, nil
+	}
+	var keys []Key
+	for _, f := range lf {
+		if slices.Contains(rf, f) && isKeyField(f) {
+			keys = append(keys, Key{f, f})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("rowjoin: the queries share no resource_name, ID, or segment field to join on; select one in both or name the keys")
+	}
+	return keys, nil
+}
+
+// isKeyField reports whether field identifies rows: a resource name,
+// an ID, or a segment.
+func isKeyField(field string) bool {
+	return strings.HasPrefix(field, "segments.") ||
+		strings.HasSuffix(field, ".resource_name") ||
+		strings.HasSuffix(field, ".id") ||
+		strings.HasSuffix(field, "_id")
+}
+
+// Table is the rows of a query, each with one value per field.
+type Table struct {
+	Fields []string
+	Rows   [][]any
+}
+
+// Join joins the rows of left and right on keys. The result has the
+// left fields, then the right fields the left lacks; rows are in left
+// order, each followed by its matches in right order. Keys must name
+// fields of their side, and a nil key value matches nothing.
+func Join(left, right Table, keys []Key, kind Kind) ([]string, [][]any, error) {
+	lk, err := indexes(left.Fields, keys, func(k Key) string { return k.Left })
+	if err != nil {
+		return nil, nil, err
+	}
+	rk, err := indexes(right.Fields, keys, func(k Key) string { return k.Right })
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields := slices.Clone(left.Fields)
+	var extra []int // the right columns added
+	for i, f := range right.Fields {
+		if !slices.Contains(left.Fields, f) {
+			fields = append(fields, f)
+			extra = append(extra, i)
+		}
+	}
+
+	byKey := map[string][]int{}
+	for i, row := range right.Rows {
+		if k, ok := keyOf(row, rk); ok {
+			byKey[k] = append(byKey[k], i)
+		}
+	}
+	var rows [][]any
+	for _, row := range left.Rows {
+		var matches []int
+		if k, ok := keyOf(row, lk); ok {
+			matches = byKey[k]
+		}
+		if len(matches) == 0 && kind == Left {
+			rows = append(rows, append(slices.Clone(row), make([]any, len(extra))...))
+		}
+		for _, m := range matches {
+			out := slices.Clone(row)
+			for _, i := range extra {
+				out = append(out, right.Rows[m][i])
+			}
+			rows = append(rows, out)
+		}
+	}
+	return fields, rows, nil
+}
+
+// indexes returns the column of each key's field on one side.
+func indexes(fields []string, keys []Key, side func(Key) string) ([]int, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("rowjoin: no keys to join on")
+	}
+	idx := make([]int, len(keys))
+	for i, k := range keys {
+		if idx[i] = slices.Index(fields, side(k)); idx[i] < 0 {
+			return nil, fmt.Errorf("rowjoin: key %s is not selected (have %s)", side(k), strings.Join(fields, ", "))
+		}
+	}
+	return idx, nil
+}
+
+// keyOf returns the values of row at idx as a map key, or false if one
+// is nil.
+func keyOf(row []any, idx []int) (string, bool) {
+	var sb strings.Builder
+	for _, i := range idx {
+		switch v := row[i].(type) {
+		case nil:
+			return "", false
+		case string:
+			sb.WriteString(v)
+		case float64:
+			// IDs are strings in REST rows, but numbers once decoded
+			// from other sources; both spell the same key.
+			sb.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			fmt.Fprint(&sb, v)
+		}
+		sb.WriteByte(0)
+	}
+	return sb.String(), true
+}
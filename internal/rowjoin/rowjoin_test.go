@@ -0,0 +1,150 @@
+package rowjoin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		left, right string
+		want        []Key
+		wantErr     string
+	}{
+		{
+			left:  "SELECT campaign.name, campaign.campaign_budget FROM campaign",
+			right: "SELECT campaign_budget.resource_name, campaign_budget.amount_micros FROM campaign_budget",
+			want:  []Key{{"campaign.campaign_budget", "campaign_budget.resource_name"}},
+		},
+		{
+			left:  "SELECT campaign_budget.resource_name, campaign_budget.amount_micros FROM campaign_budget",
+			right: "SELECT campaign.name, campaign.campaign_budget FROM campaign",
+			want:  []Key{{"campaign_budget.resource_name", "campaign.campaign_budget"}},
+		},
+		{
+			left:  "SELECT ad_group_criterion.resource_name, ad_group_criterion.quality_info.quality_score FROM ad_group_criterion",
+			right: "SELECT ad_group_criterion.resource_name, segments.date, metrics.clicks FROM keyword_view",
+			want:  []Key{{"ad_group_criterion.resource_name", "ad_group_criterion.resource_name"}},
+		},
+		{
+			left:  "SELECT campaign.id, segments.date, metrics.clicks FROM campaign",
+			right: "SELECT campaign.id, segments.date, metrics.conversions FROM campaign",
+			want:  []Key{{"campaign.id", "campaign.id"}, {"segments.date", "segments.date"}},
+		},
+		{
+			left:    "SELECT campaign.name FROM campaign",
+			right:   "SELECT campaign.name FROM campaign",
+			wantErr: "share no resource_name",
+		},
+	}
+	for _, tt := range tests {
+		left, err := gaql.Parse(tt.left)
+		if err != nil {
+			t.Fatal(err)
+		}
+		right, err := gaql.Parse(tt.right)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Keys(left, right)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Keys(%q, %q) = %v, want an error containing %q", tt.left, tt.right, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Keys(%q, %q) = %v, %v, want %v", tt.left, tt.right, got, err, tt.want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	campaigns := Table{
+		Fields: []string{"campaign.name", "campaign.campaign_budget"},
+		Rows: [][]any{
+			{"Brand", "customers/1234567890/campaignBudgets/1"},
+			{"Generic", "customers/1234567890/campaignBudgets/2"},
+			{"Shared", "customers/1234567890/campaignBudgets/1"},
+			{"Orphan", nil},
+		},
+	}
+	budgets := Table{
+		Fields: []string{"campaign_budget.resource_name", "campaign_budget.amount_micros"},
+		Rows: [][]any{
+			{"customers/1234567890/campaignBudgets/1", "5000000"},
+			{"customers/1234567890/campaignBudgets/3", "1000000"},
+		},
+	}
+	keys := []Key{{"campaign.campaign_budget", "campaign_budget.resource_name"}}
+	wantFields := []string{"campaign.name", "campaign.campaign_budget", "campaign_budget.resource_name", "campaign_budget.amount_micros"}
+
+	tests := []struct {
+		kind Kind
+		want [][]any
+	}{
+		{Inner, [][]any{
+			{"Brand", "customers/1234567890/campaignBudgets/1", "customers/1234567890/campaignBudgets/1", "5000000"},
+			{"Shared", "customers/1234567890/campaignBudgets/1", "customers/1234567890/campaignBudgets/1", "5000000"},
+		}},
+		{Left, [][]any{
+			{"Brand", "customers/1234567890/campaignBudgets/1", "customers/1234567890/campaignBudgets/1", "5000000"},
+			{"Generic", "customers/1234567890/campaignBudgets/2", nil, nil},
+			{"Shared", "customers/1234567890/campaignBudgets/1", "customers/1234567890/campaignBudgets/1", "5000000"},
+			{"Orphan", nil, nil, nil},
+		}},
+	}
+	for _, tt := range tests {
+		fields, rows, err := Join(campaigns, budgets, keys, tt.kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fields, wantFields) {
+			t.Errorf("fields = %v, want %v", fields, wantFields)
+		}
+		if !reflect.DeepEqual(rows, tt.want) {
+			t.Errorf("kind %d: rows = %v, want %v", tt.kind, rows, tt.want)
+		}
+	}
+
+	if _, _, err := Join(campaigns, budgets, []Key{{"campaign.id", "campaign_budget.resource_name"}}, Inner); err == nil {
+		t.Error("Join with an unselected key succeeded")
+	}
+}
+
+func TestJoinSharedFields(t *testing.T) {
+	left := Table{
+		Fields: []string{"campaign.id", "segments.date", "metrics.clicks"},
+		Rows:   [][]any{{"1", "2026-01-01", "10"}, {"1", "2026-01-02", "20"}},
+	}
+	right := Table{
+		Fields: []string{"campaign.id", "segments.date", "metrics.conversions"},
+		Rows:   [][]any{{1.0, "2026-01-02", 2.5}},
+	}
+	keys, err := ParseKeys("campaign.id, segments.date")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, rows, err := Join(left, right, keys, Left)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]any{{"1", "2026-01-01", "10", nil}, {"1", "2026-01-02", "20", 2.5}}
+	if !reflect.DeepEqual(rows, want) || len(fields) != 4 {
+		t.Errorf("Join = %v, %v, want %v", fields, rows, want)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got, err := ParseKeys("campaign.campaign_budget=campaign_budget.resource_name, segments.date")
+	want := []Key{{"campaign.campaign_budget", "campaign_budget.resource_name"}, {"segments.date", "segments.date"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseKeys = %v, %v, want %v", got, err, want)
+	}
+	if _, err := ParseKeys("campaign.id,"); err == nil {
+		t.Error("ParseKeys accepted an empty key")
+	}
+}
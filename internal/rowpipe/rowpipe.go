@@ -0,0 +1,540 @@
+// Package rowpipe post-processes flattened result rows locally, for the
+// aggregations GAQL cannot express: it has no GROUP BY or HAVING, so
+// totals per campaign name or per label otherwise need another tool.
+//
+// A pipeline is a list of stages separated by "|", applied in order to
+// rows of values, one per column:
+//
+//	where campaign.status = ENABLED
+//	group by campaign.name
+//	sum(metrics.clicks), avg(metrics.ctr)
+//	having sum > 1000
+//	sort by sum desc
+//	limit 10
+//
+// "where" and "having" are the same stage, keeping the rows whose
+// conditions all hold; conditions are joined by "and" and compare a
+// column with =, !=, >, >=, <, <=, LIKE, NOT LIKE, REGEXP_MATCH, or NOT
+// REGEXP_MATCH, with GAQL's pattern semantics. "group by" sets the keys
+// of the aggregate stage that follows it; without one, aggregates are
+// taken over every row. The functions are sum, avg, min, max, and count,
+// which counts rows, or with a column its non-null values. A group by
+// followed by anything else counts the rows of each group.
+//
+// An aggregate makes the column sum(metrics.clicks), which later stages
+// may call sum as long as the pipeline has no other sum. Values compare
+// as numbers when both sides are numbers, and as text otherwise; null
+// values fail every condition and sort last.
+//
+// # Basic Usage
+//
+//	p, err := rowpipe.Parse("group by campaign.name | sum(metrics.clicks)", fields)
+//	if err != nil {
+//		return err
+//	}
+//	var rows [][]any
+//	for _, row := range resp.Results {
+//		rows = append(rows, rowflat.Values(row, fields, make([]any, len(fields))))
+//	}
+//	out := p.Run(rows) // one row per name, in p.Fields() order
+package rowpipe
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Pipeline is a parsed pipeline, bound to the columns of its input.
+type Pipeline struct {
+	stages []stage
+	fields []string
+}
+
+// stage transforms rows. Stages are bound to column indexes when the
+// pipeline is parsed, so running them looks nothing up by name.
+type stage interface {
+	run(rows [][]any) [][]any
+}
+
+// Parse parses src into a pipeline over rows with one value per field.
+// Columns are checked as the stages are parsed, so a pipeline that
+// parses runs without errors.
+func Parse(src string, fields []string) (*Pipeline, error) {
+	p := &Pipeline{fields: slices.Clone(fields)}
+	var keys []int
+	grouping := false
+	for _, text := range splitStages(src) {
+		toks, err := scan(text)
+		if err != nil {
+			return nil, err
+		}
+		if len(toks) == 0 {
+			return nil, fmt.Errorf("rowpipe: empty stage in %q", src)
+		}
+		if grouping && !isAggregate(toks) {
+			// A group by on its own counts the rows of each group.
+			p.add(p.aggregate(keys, []aggregate{{fn: "count", col: -1}}))
+			grouping = false
+		}
+		word := strings.ToLower(toks[0].text)
+		switch {
+		case word == "where" || word == "having":
+			f, err := p.parseFilter(toks[1:])
+			if err != nil {
+				return nil, err
+			}
+			p.add(f)
+		case word == "group":
+			if keys, err = p.parseColumns(toks, "group by"); err != nil {
+				return nil, err
+			}
+			grouping = true
+		case word == "sort" || word == "order":
+			s, err := p.parseSort(toks)
+			if err != nil {
+				return nil, err
+			}
+			p.add(s)
+		case word == "limit":
+			n, err := strconv.Atoi(tokenText(toks, 1))
+			if err != nil || n < 0 || len(toks) != 2 {
+				return nil, fmt.Errorf("rowpipe: limit needs a row count, as in limit 10")
+			}
+			p.add(limitStage(n))
+		case isAggregate(toks):
+			aggs, err := p.parseAggregates(toks)
+			if err != nil {
+				return nil, err
+			}
+			if !grouping {
+				keys = nil
+			}
+			p.add(p.aggregate(keys, aggs))
+			grouping = false
+		default:
+			return nil, fmt.Errorf("rowpipe: unknown stage %q (expected where, group by, sum(...), avg(...), min(...), max(...), count(), having, sort by, or limit)", text)
+		}
+	}
+	if grouping {
+		p.add(p.aggregate(keys, []aggregate{{fn: "count", col: -1}}))
+	}
+	return p, nil
+}
+
+// Fields returns the columns of the rows Run returns.
+func (p *Pipeline) Fields() []string {
+	return p.fields
+}
+
+// Run applies the pipeline to rows, which must have one value per field
+// given to Parse. It may reorder and modify rows.
+func (p *Pipeline) Run(rows [][]any) [][]any {
+	for _, s := range p.stages {
+		rows = s.run(rows)
+	}
+	return rows
+}
+
+func (p *Pipeline) add(s stage) {
+	p.stages = append(p.stages, s)
+}
+
+// column returns the index of the column name refers to: its full name,
+// or the name of an aggregate function if a single column applies it.
+func (p *Pipeline) column(name string) (int, error) {
+	if i := slices.Index(p.fields, name); i >= 0 {
+		return i, nil
+	}
+	found := -1
+	for i, f := range p.fields {
+		if fn, _, ok := strings.Cut(f, "("); ok && strings.EqualFold(fn, name) {
+			if found >= 0 {
+				return 0, fmt.Errorf("rowpipe: %s is ambiguous: name one of %s or %s", name, p.fields[found], f)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("rowpipe: unknown column %s (have %s)", name, strings.Join(p.fields, ", "))
+	}
+	return found, nil
+}
+
+func (p *Pipeline) parseColumns(toks []token, clause string) ([]int, error) {
+	if len(toks) < 3 || !strings.EqualFold(toks[1].text, "by") {
+		return nil, fmt.Errorf("rowpipe: %s needs at least one column", clause)
+	}
+	var cols []int
+	for _, list := range split(toks[2:], ",") {
+		if len(list) != 1 || list[0].quoted {
+			return nil, fmt.Errorf("rowpipe: %s takes a comma-separated list of columns", clause)
+		}
+		i, err := p.column(list[0].text)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, i)
+	}
+	return cols, nil
+}
+
+// filterStage keeps the rows matching every condition.
+type filterStage []condition
+
+type condition struct {
+	col   int
+	op    gaql.Operator
+	value any            // float64 or string
+	re    *regexp.Regexp // for the pattern operators
+}
+
+func (p *Pipeline) parseFilter(toks []token) (filterStage, error) {
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("rowpipe: where needs a condition, as in where metrics.clicks > 100")
+	}
+	var f filterStage
+	for _, list := range split(toks, "and") {
+		c, err := p.parseCondition(list)
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, c)
+	}
+	return f, nil
+}
+
+// operators maps the operator words, in upper case, to GAQL operators.
+var operators = map[string]gaql.Operator{
+	"=": gaql.OpEq, "!=": gaql.OpNeq, "<>": gaql.OpNeq,
+	">": gaql.OpGt, ">=": gaql.OpGte, "<": gaql.OpLt, "<=": gaql.OpLte,
+	"LIKE": gaql.OpLike, "NOT LIKE": gaql.OpNotLike,
+	"REGEXP_MATCH": gaql.OpRegexpMatch, "NOT REGEXP_MATCH": gaql.OpNotRegexpMatch,
+}
+
+func (p *Pipeline) parseCondition(toks []token) (condition, error) {
+	text := joinTokens(toks)
+	if len(toks) < 3 || len(toks) > 4 || toks[0].quoted {
+		return condition{}, fmt.Errorf("rowpipe: invalid condition %q (expected COLUMN OP VALUE, as in metrics.clicks > 100)", text)
+	}
+	col, err := p.column(toks[0].text)
+	if err != nil {
+		return condition{}, err
+	}
+	opWords := toks[1 : len(toks)-1]
+	op, ok := operators[strings.ToUpper(joinTokens(opWords))]
+	if !ok || slices.ContainsFunc(opWords, func(t token) bool { return t.quoted }) {
+		return condition{}, fmt.Errorf("rowpipe: invalid operator %q in %q", joinTokens(opWords), text)
+	}
+	lit := toks[len(toks)-1]
+	c := condition{col: col, op: op, value: lit.text}
+	if f, err := strconv.ParseFloat(lit.text, 64); err == nil && !lit.quoted {
+		c.value = f
+	}
+	switch op {
+	case gaql.OpLike, gaql.OpNotLike, gaql.OpRegexpMatch, gaql.OpNotRegexpMatch:
+		c.re, err = gaql.CompileRegexp(gaql.Condition{Field: p.fields[col], Operator: op, Value: gaql.StringValue(lit.text)})
+		if err != nil {
+			return condition{}, fmt.Errorf("rowpipe: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (f filterStage) run(rows [][]any) [][]any {
+	return slices.DeleteFunc(rows, func(row []any) bool {
+		for _, c := range f {
+			if !c.match(row[c.col]) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (c condition) match(v any) bool {
+	if v == nil {
+		return false
+	}
+	if c.re != nil {
+		return c.re.MatchString(text(v)) != c.op.Negated()
+	}
+	n := compare(v, c.value)
+	switch c.op {
+	case gaql.OpEq:
+		return n == 0
+	case gaql.OpNeq:
+		return n != 0
+	case gaql.OpGt:
+		return n > 0
+	case gaql.OpGte:
+		return n >= 0
+	case gaql.OpLt:
+		return n < 0
+	case gaql.OpLte:
+		return n <= 0
+	}
+	return false
+}
+
+// aggregateStage replaces the rows with one row per distinct key: the
+// key columns, then one column per aggregate.
+type aggregateStage struct {
+	keys []int
+	aggs []aggregate
+}
+
+type aggregate struct {
+	fn  string // sum, avg, min, max, or count
+	col int    // -1 for count()
+}
+
+// aggregateCall matches an aggregate function applied to a column, or
+// count().
+var aggregateCall = regexp.MustCompile(`(?i)^(sum|avg|min|max|count)\((.*)\)$`)
+
+func isAggregate(toks []token) bool {
+	return len(toks) > 0 && !toks[0].quoted && aggregateCall.MatchString(toks[0].text)
+}
+
+func (p *Pipeline) parseAggregates(toks []token) ([]aggregate, error) {
+	var aggs []aggregate
+	for _, list := range split(toks, ",") {
+		if len(list) != 1 || !isAggregate(list) {
+			return nil, fmt.Errorf("rowpipe: invalid aggregate %q (expected sum, avg, min, max, or count of a column, as in sum(metrics.clicks))", joinTokens(list))
+		}
+		m := aggregateCall.FindStringSubmatch(list[0].text)
+		a := aggregate{fn: strings.ToLower(m[1]), col: -1}
+		switch {
+		case m[2] != "":
+			var err error
+			if a.col, err = p.column(m[2]); err != nil {
+				return nil, err
+			}
+		case a.fn != "count":
+			return nil, fmt.Errorf("rowpipe: %s needs a column, as in %s(metrics.clicks)", a.fn, a.fn)
+		}
+		aggs = append(aggs, a)
+	}
+	return aggs, nil
+}
+
+// aggregate returns the stage computing aggs over the groups of keys,
+// and makes its columns the pipeline's.
+func (p *Pipeline) aggregate(keys []int, aggs []aggregate) *aggregateStage {
+	var fields []string
+	for _, k := range keys {
+		fields = append(fields, p.fields[k])
+	}
+	for _, a := range aggs {
+		if a.col < 0 {
+			fields = append(fields, "count")
+		} else {
+			fields = append(fields, a.fn+"("+p.fields[a.col]+")")
+		}
+	}
+	p.fields = fields
+	return &aggregateStage{keys: keys, aggs: aggs}
+}
+
+func (s *aggregateStage) run(rows [][]any) [][]any {
+	type group struct {
+		key  []any
+		rows [][]any
+	}
+	var groups []*group
+	byKey := map[string]*group{}
+	for _, row := range rows {
+		key := make([]any, len(s.keys))
+		var id strings.Builder
+		for i, k := range s.keys {
+			key[i] = row[k]
+			fmt.Fprintf(&id, "%T\x00%v\x00", row[k], row[k])
+		}
+		g, ok := byKey[id.String()]
+		if !ok {
+			g = &group{key: key}
+			byKey[id.String()] = g
+			groups = append(groups, g)
+		}
+		g.rows = append(g.rows, row)
+	}
+	if len(groups) == 0 && len(s.keys) == 0 {
+		// Totals of no rows are still one row.
+		groups = append(groups, &group{})
+	}
+	out := make([][]any, len(groups))
+	for i, g := range groups {
+		row := g.key
+		for _, a := range s.aggs {
+			row = append(row, a.compute(g.rows))
+		}
+		out[i] = row
+	}
+	return out
+}
+
+// compute returns the aggregate of rows: a float64 for sum and avg, an
+// int for count, and a value of the column for min and max. Null and,
+// for sum and avg, non-numeric values are skipped; avg, min, and max of
+// no values are nil.
+func (a aggregate) compute(rows [][]any) any {
+	if a.col < 0 {
+		return len(rows)
+	}
+	var (
+		n    int
+		sum  float64
+		best any
+	)
+	for _, row := range rows {
+		v := row[a.col]
+		if v == nil {
+			continue
+		}
+		switch a.fn {
+		case "sum", "avg":
+			f, ok := number(v)
+			if !ok {
+				continue
+			}
+			sum += f
+		case "min":
+			if best == nil || compare(v, best) < 0 {
+				best = v
+			}
+		case "max":
+			if best == nil || compare(v, best) > 0 {
+				best = v
+			}
+		}
+		n++
+	}
+	switch a.fn {
+	case "count":
+		return n
+	case "sum":
+		return sum
+	case "avg":
+		if n == 0 {
+			return nil
+		}
+		return sum / float64(n)
+	}
+	return best
+}
+
+// sortStage orders rows by columns, keeping the order of equal rows.
+type sortStage []sortKey
+
+type sortKey struct {
+	col  int
+	desc bool
+}
+
+func (p *Pipeline) parseSort(toks []token) (sortStage, error) {
+	if len(toks) < 3 || !strings.EqualFold(toks[1].text, "by") {
+		return nil, fmt.Errorf("rowpipe: sort needs at least one column, as in sort by metrics.clicks desc")
+	}
+	var s sortStage
+	for _, list := range split(toks[2:], ",") {
+		if len(list) == 0 || len(list) > 2 || list[0].quoted {
+			return nil, fmt.Errorf("rowpipe: invalid sort key %q (expected COLUMN [asc|desc])", joinTokens(list))
+		}
+		col, err := p.column(list[0].text)
+		if err != nil {
+			return nil, err
+		}
+		k := sortKey{col: col}
+		if len(list) == 2 {
+			switch strings.ToLower(list[1].text) {
+			case "asc":
+			case "desc":
+				k.desc = true
+			default:
+				return nil, fmt.Errorf("rowpipe: invalid sort direction %q (expected asc or desc)", list[1].text)
+			}
+		}
+		s = append(s, k)
+	}
+	return s, nil
+}
+
+func (s sortStage) run(rows [][]any) [][]any {
+	slices.SortStableFunc(rows, func(a, b []any) int {
+		for _, k := range s {
+			x, y := a[k.col], b[k.col]
+			var n int
+			switch {
+			case x == nil && y == nil:
+			case x == nil:
+				n = 1 // nulls last, in either direction
+			case y == nil:
+				n = -1
+			case k.desc:
+				n = compare(y, x)
+			default:
+				n = compare(x, y)
+			}
+			if n != 0 {
+				return n
+			}
+		}
+		return 0
+	})
+	return rows
+}
+
+// limitStage keeps the first rows.
+type limitStage int
+
+func (n limitStage) run(rows [][]any) [][]any {
+	return rows[:min(int(n), len(rows))]
+}
+
+// compare orders a and b as numbers if both are, and as text otherwise.
+func compare(a, b any) int {
+	x, aok := number(a)
+	y, bok := number(b)
+	if aok && bok {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(text(a), text(b))
+}
+
+// number converts a numeric value of any representation rows carry,
+// including the strings of INT64 fields.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return 0, false
+}
+
+func text(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,146 @@
+package rowpipe
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var (
+	fields = []string{"campaign.name", "campaign.status", "segments.date", "metrics.clicks", "metrics.ctr"}
+	rows   = [][]any{
+		{"Brand", "ENABLED", "2026-01-01", "400", 0.1},
+		{"Brand", "ENABLED", "2026-01-02", "700", 0.3},
+		{"Generic", "ENABLED", "2026-01-01", "90", nil},
+		{"Generic", "PAUSED", "2026-01-02", "10", 0.2},
+		{"Shopping", "ENABLED", "2026-01-02", "2000", 0.05},
+	}
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		src    string
+		fields []string
+		want   [][]any
+	}{
+		{
+			src:    "where campaign.status = ENABLED and metrics.clicks >= 400",
+			fields: fields,
+			want:   [][]any{rows[0], rows[1], rows[4]},
+		},
+		{
+			src:    "group by campaign.name | sum(metrics.clicks), avg(metrics.ctr) | having sum > 1000",
+			fields: []string{"campaign.name", "sum(metrics.clicks)", "avg(metrics.ctr)"},
+			want:   [][]any{{"Brand", 1100.0, 0.2}, {"Shopping", 2000.0, 0.05}},
+		},
+		{
+			src:    "group by campaign.status | sort by count desc",
+			fields: []string{"campaign.status", "count"},
+			want:   [][]any{{"ENABLED", 4}, {"PAUSED", 1}},
+		},
+		{
+			src:    "min(segments.date), max(metrics.clicks), count(metrics.ctr), count()",
+			fields: []string{"min(segments.date)", "max(metrics.clicks)", "count(metrics.ctr)", "count"},
+			want:   [][]any{{"2026-01-01", "2000", 4, 5}},
+		},
+		{
+			src:    "where campaign.name NOT LIKE 'B%' | sort by metrics.ctr, campaign.name desc | limit 2",
+			fields: fields,
+			want:   [][]any{rows[4], rows[3]},
+		},
+		{
+			src:    "where campaign.name REGEXP_MATCH '(?i)brand|shop.*' | group by campaign.name, campaign.status | sum(metrics.clicks) | sort by sum(metrics.clicks)",
+			fields: []string{"campaign.name", "campaign.status", "sum(metrics.clicks)"},
+			want:   [][]any{{"Brand", "ENABLED", 1100.0}, {"Shopping", "ENABLED", 2000.0}},
+		},
+		{
+			src:    "where metrics.clicks > 5000 | sum(metrics.clicks), avg(metrics.ctr)",
+			fields: []string{"sum(metrics.clicks)", "avg(metrics.ctr)"},
+			want:   [][]any{{0.0, nil}},
+		},
+		{
+			src:    "where campaign.name = 'Brand' | group by campaign.name",
+			fields: []string{"campaign.name", "count"},
+			want:   [][]any{{"Brand", 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			p, err := Parse(tt.src, fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p.Fields(), tt.fields) {
+				t.Errorf("Fields() = %v, want %v", p.Fields(), tt.fields)
+			}
+			in := make([][]any, len(rows))
+			for i, row := range rows {
+				in[i] = append([]any(nil), row...)
+			}
+			if got := p.Run(in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunNonASCII(t *testing.T) {
+	rows := [][]any{
+		{"Le café du coin", "ENABLED", "2026-01-01", "10", 0.1},
+		{"Le cafe du coin", "ENABLED", "2026-01-01", "20", 0.1},
+		{"東京 - Search", "ENABLED", "2026-01-01", "30", 0.1},
+	}
+	tests := []struct {
+		src  string
+		want [][]any
+	}{
+		{"where campaign.name LIKE '%café%'", rows[:1]},
+		{"where campaign.name LIKE '__ - Search'", rows[2:]},
+		{"where campaign.name NOT LIKE '%é%'", rows[1:]},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.src, fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Run(slices.Clone(rows)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Run = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{"where metrics.impressions > 1", "unknown column metrics.impressions"},
+		{"where metrics.clicks >", "invalid condition"},
+		{"where metrics.clicks ~ 1", `invalid operator "~"`},
+		{"where campaign.name LIKE 'a[b]'", "invalid escape"},
+		{"where campaign.name = 'open", "unterminated string"},
+		{"sum(metrics.clicks), sum(metrics.ctr) | sort by sum", "sum is ambiguous"},
+		{"sum()", "sum needs a column"},
+		{"median(metrics.clicks)", "unknown stage"},
+		{"group by", "group by needs at least one column"},
+		{"limit ten", "limit needs a row count"},
+		{"sort by metrics.clicks sideways", "invalid sort direction"},
+		{"where campaign.status = ENABLED |", "empty stage"},
+		{"sum(metrics.clicks) | where campaign.name = 'Brand'", "unknown column campaign.name"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src, fields)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSplitStages(t *testing.T) {
+	got := splitStages(`where campaign.name = 'a|b' | limit 1`)
+	want := []string{`where campaign.name = 'a|b'`, "limit 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitStages = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package rowpipe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// token is a word, operator, comma, or quoted string of a stage. An
+// aggregate call such as sum(metrics.clicks) is one word.
+type token struct {
+	text   string
+	quoted bool
+}
+
+// splitStages splits src at the | characters outside quoted strings.
+func splitStages(src string) []string {
+	var stages []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(src); i++ {
+		switch ch := src[i]; {
+		case quote != 0 && ch == '\\':
+			i++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '|':
+			stages = append(stages, strings.TrimSpace(src[start:i]))
+			start = i + 1
+		}
+	}
+	return append(stages, strings.TrimSpace(src[start:]))
+}
+
+// scan splits a stage into tokens.
+func scan(stage string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(stage); {
+		ch := stage[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == ',':
+			toks = append(toks, token{text: ","})
+			i++
+		case ch == '\'' || ch == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(stage) && stage[j] != ch; j++ {
+				if stage[j] == '\\' && j+1 < len(stage) {
+					j++
+				}
+				sb.WriteByte(stage[j])
+			}
+			if j == len(stage) {
+				return nil, fmt.Errorf("rowpipe: unterminated string in %q", stage)
+			}
+			toks = append(toks, token{text: sb.String(), quoted: true})
+			i = j + 1
+		case strings.IndexByte("=!<>", ch) >= 0:
+			j := i + 1
+			for j < len(stage) && strings.IndexByte("=<>", stage[j]) >= 0 {
+				j++
+			}
+			toks = append(toks, token{text: stage[i:j]})
+			i = j
+		default:
+			j := i
+			for j < len(stage) && strings.IndexByte(" \t\n\r,'\"=!<>", stage[j]) < 0 {
+				j++
+			}
+			toks = append(toks, token{text: stage[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// split splits toks at the unquoted tokens equal to sep, ignoring case.
+func split(toks []token, sep string) [][]token {
+	var lists [][]token
+	start := 0
+	for i, t := range toks {
+		if !t.quoted && strings.EqualFold(t.text, sep) {
+			lists = append(lists, toks[start:i])
+			start = i + 1
+		}
+	}
+	return append(lists, toks[start:])
+}
+
+// joinTokens returns toks as text, for error messages.
+func joinTokens(toks []token) string {
+	words := make([]string, len(toks))
+	for i, t := range toks {
+		words[i] = t.text
+		if t.quoted {
+			words[i] = "'" + t.text + "'"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// tokenText returns the text of toks[i], or "" past the end.
+func tokenText(toks []token, i int) string {
+	if i >= len(toks) {
+		return ""
+	}
+	return toks[i].text
+}
@@ -0,0 +1,173 @@
+// Package rowtransform normalizes the values of result rows for people
+// to read, before they are rendered or stored.
+//
+// The API reports money in micros of the account currency, so a cost of
+// 1234.56 USD arrives as "1234560000", and some sources report enum
+// values by proto number instead of name. Options converts both:
+// amounts in micros, including cost-per metrics such as
+// metrics.average_cpc, become decimal strings rounded to the currency's
+// minor unit and followed by its code, and enum integers become their
+// API names.
+//
+// # Basic Usage
+//
+//	t := rowtransform.Humanize("USD")
+//	t.Value("metrics.cost_micros", "1234567890") // "1234.57 USD"
+//	t.Value("campaign.status", 3)                // "PAUSED"
+//	t.Row(row, fields)                           // in place
+package rowtransform
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// Options selects the conversions applied to values. The zero value
+// leaves values unchanged.
+type Options struct {
+	// Micros converts amounts in micros, the fields output.IsMicros
+	// reports, to decimal strings.
+	Micros bool
+
+	// Currency is the ISO 4217 code of the amounts. It sets the number
+	// of decimals and is appended to them; empty means two decimals and
+	// no code.
+	Currency string
+
+	// Enums replaces enum values given as integers with their names.
+	Enums bool
+
+	// Catalog identifies enum fields. Nil uses gaql.DefaultCatalog.
+	Catalog *gaql.Catalog
+}
+
+// Humanize returns options applying every conversion, for amounts in
+// currency.
+func Humanize(currency string) Options {
+	return Options{Micros: true, Currency: currency, Enums: true}
+}
+
+// Value returns v, the value of field, converted.
+func (o Options) Value(field string, v any) any {
+	if items, ok := v.([]any); ok {
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = o.Value(field, item)
+		}
+		return out
+	}
+	if v == nil {
+		return nil
+	}
+	if o.Micros && output.IsMicros(field) {
+		if s, ok := o.amount(v); ok {
+			return s
+		}
+	}
+	if o.Enums && o.isEnum(field) {
+		return output.EnumName(field, v)
+	}
+	return v
+}
+
+// Values converts values, one per field, in place.
+func (o Options) Values(fields []string, values []any) {
+	for i, f := range fields {
+		values[i] = o.Value(f, values[i])
+	}
+}
+
+// Row converts the fields of a result row in place.
+func (o Options) Row(row map[string]any, fields []string) {
+	for _, f := range fields {
+		if v, ok := output.Value(row, f); ok {
+			output.SetValue(row, f, o.Value(f, v))
+		}
+	}
+}
+
+func (o Options) isEnum(field string) bool {
+	cat := o.Catalog
+	if cat == nil {
+		cat = gaql.DefaultCatalog()
+	}
+	info, _ := cat.Field(field)
+	return info.DataType == "ENUM"
+}
+
+// amount formats an amount in micros. INT64 amounts, which arrive as
+// strings, are rounded exactly; DOUBLE ones as floats.
+func (o Options) amount(v any) (string, bool) {
+	digits := Digits(o.Currency)
+	var s string
+	switch v := v.(type) {
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "", false
+		}
+		s = formatMicros(n, digits)
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return "", false
+		}
+		s = strconv.FormatFloat(v/1e6, 'f', digits, 64)
+	case int64:
+		s = formatMicros(v, digits)
+	case int:
+		s = formatMicros(int64(v), digits)
+	default:
+		return "", false
+	}
+	if o.Currency != "" {
+		s += " " + strings.ToUpper(o.Currency)
+	}
+	return s, true
+}
+
+// formatMicros writes n micros as units with digits decimals, rounding
+// half away from zero.
+func formatMicros(n int64, digits int) string {
+	unit := int64(1)
+	for range 6 - digits {
+		unit *= 10
+	}
+	neg := n < 0
+	u := uint64(n)
+	if neg {
+		u = -u
+	}
+	u = (u + uint64(unit)/2) / uint64(unit)
+	s := strconv.FormatUint(u, 10)
+	if digits > 0 {
+		if len(s) <= digits {
+			s = strings.Repeat("0", digits-len(s)+1) + s
+		}
+		s = s[:len(s)-digits] + "." + s[len(s)-digits:]
+	}
+	if neg && strings.Trim(s, "0.") != "" {
+		s = "-" + s
+	}
+	return s
+}
+
+// minorDigits lists the currencies whose minor unit is not a hundredth.
+var minorDigits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// Digits returns the number of decimals of an amount in currency: 0 for
+// JPY, 3 for KWD, and 2 for the rest.
+func Digits(currency string) int {
+	if d, ok := minorDigits[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return 2
+}
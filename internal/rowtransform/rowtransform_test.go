@@ -0,0 +1,57 @@
+package rowtransform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		field string
+		in    any
+		want  any
+	}{
+		{"int64 micros", Humanize("USD"), "metrics.cost_micros", "1234567890", "1234.57 USD"},
+		{"round half away", Humanize("usd"), "metrics.cost_micros", "-5000", "-0.01 USD"},
+		{"negative zero", Humanize("USD"), "metrics.cost_micros", "-4000", "0.00 USD"},
+		{"small", Humanize("USD"), "metrics.cost_micros", "70000", "0.07 USD"},
+		{"zero decimals", Humanize("JPY"), "metrics.cost_micros", "1500500000", "1501 JPY"},
+		{"three decimals", Humanize("KWD"), "metrics.cost_micros", "1234567", "1.235 KWD"},
+		{"double cost-per", Humanize("EUR"), "metrics.average_cpc", 1.5e6, "1.50 EUR"},
+		{"no currency", Options{Micros: true}, "metrics.cost_micros", "2000000", "2.00"},
+		{"not micros", Humanize("USD"), "metrics.clicks", "12", "12"},
+		{"unparsable", Humanize("USD"), "metrics.cost_micros", "n/a", "n/a"},
+		{"micros off", Options{Enums: true}, "metrics.cost_micros", "2000000", "2000000"},
+		{"enum number", Humanize(""), "campaign.status", 3.0, "PAUSED"},
+		{"enum string number", Humanize(""), "segments.device", "2", "MOBILE"},
+		{"enum name", Humanize(""), "campaign.status", "ENABLED", "ENABLED"},
+		{"enums off", Options{Micros: true}, "campaign.status", 3.0, 3.0},
+		{"nil", Humanize("USD"), "metrics.cost_micros", nil, nil},
+		{"repeated", Humanize(""), "segments.device", []any{2.0, "DESKTOP"}, []any{"MOBILE", "DESKTOP"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Value(tt.field, tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Value(%s, %#v) = %#v, want %#v", tt.field, tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRow(t *testing.T) {
+	row := map[string]any{
+		"campaign": map[string]any{"id": "1", "status": 2.0},
+		"metrics":  map[string]any{"costMicros": "990000", "clicks": "4"},
+	}
+	fields := []string{"campaign.id", "campaign.status", "metrics.cost_micros", "metrics.clicks", "metrics.conversions"}
+	Humanize("GBP").Row(row, fields)
+	want := map[string]any{
+		"campaign": map[string]any{"id": "1", "status": "ENABLED"},
+		"metrics":  map[string]any{"costMicros": "0.99 GBP", "clicks": "4"},
+	}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("Row = %v, want %v", row, want)
+	}
+}
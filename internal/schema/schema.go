@@ -0,0 +1,120 @@
+// Package schema keeps a copy of the API's field metadata on disk, one
+// catalog per API version, so strict validation checks every field
+// without asking GoogleAdsFieldService for each query, and works offline
+// once synced.
+//
+// The embedded gaql.DefaultCatalog covers commonly queried fields only;
+// a synced catalog is the API's complete schema for its version.
+//
+// # Basic Usage
+//
+//	fields, err := client.SearchFields(ctx, schema.AllFields)
+//	err = schema.Save(schema.DefaultDir(), gaql.NewCatalog(client.Version(), fields))
+//
+//	v := gaql.NewValidator()
+//	v.Catalog = schema.Lazy(schema.DefaultDir(), "v23")
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// AllFields is the GoogleAdsFieldService condition matching every
+// resource, attribute, segment, and metric.
+const AllFields = "category IN ('RESOURCE', 'ATTRIBUTE', 'SEGMENT', 'METRIC')"
+
+// ErrNotSynced is returned by Load when no catalog was synced for the
+// version.
+var ErrNotSynced = errors.New("schema: not synced")
+
+// DefaultDir returns the schema directory next to the result cache: the
+// schema directory under ADTAP_CACHE_DIR, or in the adtap directory of
+// the user cache directory.
+func DefaultDir() string {
+	if dir := os.Getenv("ADTAP_CACHE_DIR"); dir != "" {
+		return filepath.Join(dir, "schema")
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "adtap", "schema")
+}
+
+// Path returns the file holding the catalog of version in dir.
+func Path(dir, version string) string {
+	return filepath.Join(dir, "fields-"+version+".json")
+}
+
+// Load reads the catalog of version synced to dir.
+func Load(dir, version string) (*gaql.Catalog, error) {
+	f, err := os.Open(Path(dir, version))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w for %s; run 'adtap schema sync'", ErrNotSynced, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	defer f.Close()
+	c, err := gaql.LoadCatalog(f)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %s: %w", f.Name(), err)
+	}
+	if c.Version != version {
+		return nil, fmt.Errorf("schema: %s holds the %s catalog, not %s", f.Name(), c.Version, version)
+	}
+	return c, nil
+}
+
+// Save writes c to dir as the catalog of its version, replacing the
+// file atomically so concurrent readers see the old or the new one.
+func Save(dir string, c *gaql.Catalog) error {
+	if c.Version == "" {
+		return errors.New("schema: the catalog has no version")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "fields-*.tmp")
+	if err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+	_, err = c.WriteTo(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), Path(dir, c.Version))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("schema: %w", err)
+	}
+	return nil
+}
+
+// Synced returns when the catalog of version was last synced to dir,
+// and false when it never was.
+func Synced(dir, version string) (time.Time, bool) {
+	info, err := os.Stat(Path(dir, version))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// Lazy returns the catalog of version synced to dir, read on first use.
+// Until a catalog is synced it holds gaql.DefaultCatalog, and its Err
+// method returns ErrNotSynced.
+func Lazy(dir, version string) *gaql.Catalog {
+	return gaql.NewLazyCatalog(version, func() (*gaql.Catalog, error) {
+		return Load(dir, version)
+	})
+}
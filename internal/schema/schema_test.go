@@ -0,0 +1,76 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := Synced(dir, "v99"); ok {
+		t.Error("Synced before saving")
+	}
+	if _, err := Load(dir, "v99"); !errors.Is(err, ErrNotSynced) {
+		t.Errorf("Load = %v, want ErrNotSynced", err)
+	}
+
+	c := gaql.NewCatalog("v99", []gaql.FieldInfo{
+		{Name: "widget", Category: "RESOURCE"},
+		{Name: "widget.id", Category: "ATTRIBUTE", DataType: "INT64", Selectable: true},
+	})
+	if err := Save(dir, c); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Synced(dir, "v99"); !ok {
+		t.Error("not Synced after saving")
+	}
+	loaded, err := Load(dir, "v99")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := loaded.Field("widget.id"); !ok || f.DataType != "INT64" {
+		t.Errorf("widget.id = %+v, %v", f, ok)
+	}
+	if _, err := Load(dir, "v98"); !errors.Is(err, ErrNotSynced) {
+		t.Errorf("Load of another version = %v, want ErrNotSynced", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("dir has %d files, want the catalog only", len(entries))
+	}
+}
+
+func TestLoadVersionMismatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := Save(dir, gaql.NewCatalog("v98", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(Path(dir, "v98"), Path(dir, "v99")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir, "v99"); err == nil || errors.Is(err, ErrNotSynced) {
+		t.Errorf("Load = %v, want a version mismatch", err)
+	}
+}
+
+func TestLazy(t *testing.T) {
+	dir := t.TempDir()
+	c := Lazy(dir, "v99")
+	if _, ok := c.Field("campaign.id"); !ok {
+		t.Error("an unsynced catalog should hold the default fields")
+	}
+	if !errors.Is(c.Err(), ErrNotSynced) {
+		t.Errorf("Err = %v, want ErrNotSynced", c.Err())
+	}
+
+	if err := Save(dir, gaql.NewCatalog("v99", []gaql.FieldInfo{{Name: "widget.id"}})); err != nil {
+		t.Fatal(err)
+	}
+	c = Lazy(dir, "v99")
+	if _, ok := c.Field("widget.id"); !ok || c.Err() != nil {
+		t.Errorf("synced catalog: widget.id %v, Err %v", ok, c.Err())
+	}
+}
@@ -0,0 +1,185 @@
+// Package searchterms totals the search terms that triggered ads and
+// suggests negative keywords for those that spend without converting.
+//
+// search_term_view reports a term once for every ad group, keyword, and
+// match type that showed an ad for it. Aggregate adds these rows up per
+// campaign, or across the account, before anything is filtered, so a
+// term that converted in one ad group is not suggested as a negative for
+// the campaign it converted in. Terms already excluded are left out by
+// Query. Terms added as keywords are kept, but get no suggestion: the
+// remedy there is pausing the keyword, not negating it.
+//
+// # Basic Usage
+//
+//	q := searchterms.Query(gaql.DateRangeLast30Days, 0)
+//	resp, err := client.Search(ctx, customerID, q.String())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	terms, _ := searchterms.Aggregate(resp.Results, searchterms.ByCampaign)
+//	for _, t := range searchterms.Filter(terms, 50_000_000, true) {
+//		fmt.Println(t.CampaignName, t.Negative())
+//	}
+package searchterms
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/output"
+)
+
+// Levels at which terms are aggregated, and negatives suggested.
+const (
+	ByCampaign = "campaign"
+	ByAccount  = "account"
+)
+
+// Levels lists the aggregation levels, sorted.
+var Levels = []string{ByAccount, ByCampaign}
+
+// Query returns the query fetching every search term with spend in
+// during, optionally in one campaign only. Terms already excluded by a
+// negative keyword are left out.
+func Query(during gaql.DateRange, campaignID int64) *gaql.Query {
+	b := gaql.Select("campaign.id", "campaign.name", "ad_group.id",
+		"search_term_view.search_term", "search_term_view.status",
+		"metrics.impressions", "metrics.clicks", "metrics.cost_micros", "metrics.conversions").
+		From("search_term_view").
+		Where("search_term_view.status", gaql.OpNotIn, gaql.ListValue("EXCLUDED", "ADDED_EXCLUDED")).
+		Where("metrics.cost_micros", gaql.OpGt, gaql.NumberValue(0))
+	if campaignID != 0 {
+		b.Where("campaign.id", gaql.OpEq, gaql.NumberValue(float64(campaignID)))
+	}
+	return b.During(during).OrderBy("metrics.cost_micros", gaql.Desc).Query()
+}
+
+// Term is a search term's totals in a campaign, or in the whole account
+// when aggregated ByAccount, which leaves CampaignID and CampaignName
+// empty.
+type Term struct {
+	Text         string  `json:"search_term"`
+	CampaignID   string  `json:"campaign_id,omitempty"`
+	CampaignName string  `json:"campaign_name,omitempty"`
+	Campaigns    int     `json:"campaigns"`
+	AdGroups     int     `json:"ad_groups"`
+	Added        bool    `json:"added"` // a keyword in one of the ad groups
+	Impressions  int64   `json:"impressions"`
+	Clicks       int64   `json:"clicks"`
+	CostMicros   int64   `json:"cost_micros"`
+	Conversions  float64 `json:"conversions"`
+}
+
+// Negative returns the term as an exact match negative keyword, such as
+// [cheap tents], or "" when it converted or is a keyword already.
+func (t *Term) Negative() string {
+	if t.Conversions > 0 || t.Added {
+		return ""
+	}
+	return "[" + t.Text + "]"
+}
+
+// Aggregate totals Query result rows per term at level, ByCampaign or
+// ByAccount. Terms are matched ignoring case and repeated spaces, and
+// ordered by cost, highest first.
+func Aggregate(rows []adsapi.Row, level string) ([]*Term, error) {
+	if level != ByCampaign && level != ByAccount {
+		return nil, fmt.Errorf("searchterms: invalid level %q (expected one of %s)", level, strings.Join(Levels, ", "))
+	}
+	byKey := make(map[string]*Term)
+	seen := make(map[string]bool) // key, campaign, and ad group already counted
+	var out []*Term
+	for _, row := range rows {
+		text := strings.Join(strings.Fields(strings.ToLower(str(row, "search_term_view.search_term"))), " ")
+		campaign := str(row, "campaign.id")
+		key := text
+		if level == ByCampaign {
+			key = campaign + "\x00" + text
+		}
+		t, ok := byKey[key]
+		if !ok {
+			t = &Term{Text: text}
+			if level == ByCampaign {
+				t.CampaignID, t.CampaignName = campaign, str(row, "campaign.name")
+			}
+			byKey[key] = t
+			out = append(out, t)
+		}
+		if k := key + "\x00c" + campaign; !seen[k] {
+			seen[k] = true
+			t.Campaigns++
+		}
+		if k := key + "\x00g" + str(row, "ad_group.id"); !seen[k] {
+			seen[k] = true
+			t.AdGroups++
+		}
+		if status := str(row, "search_term_view.status"); status == "ADDED" {
+			t.Added = true
+		}
+		t.Impressions += integer(row, "metrics.impressions")
+		t.Clicks += integer(row, "metrics.clicks")
+		t.CostMicros += integer(row, "metrics.cost_micros")
+		t.Conversions += number(row, "metrics.conversions")
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CostMicros > out[j].CostMicros })
+	return out, nil
+}
+
+// Filter returns the terms that cost at least minCostMicros and, if
+// noConversions is set, had no conversions, keeping their order.
+func Filter(terms []*Term, minCostMicros int64, noConversions bool) []*Term {
+	var out []*Term
+	for _, t := range terms {
+		if t.CostMicros < minCostMicros || (noConversions && t.Conversions > 0) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func value(row adsapi.Row, field string) any {
+	v, _ := output.Value(row, field)
+	return v
+}
+
+func str(row adsapi.Row, field string) string {
+	switch v := value(row, field).(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// integer reads an INT64 field, which the REST API encodes as a string.
+func integer(row adsapi.Row, field string) int64 {
+	switch v := value(row, field).(type) {
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// number reads a DOUBLE field.
+func number(row adsapi.Row, field string) float64 {
+	switch v := value(row, field).(type) {
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
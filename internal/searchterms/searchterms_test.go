@@ -0,0 +1,111 @@
+package searchterms
+
+import (
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+func termRow(campaign, adGroup, term, status, cost string, conversions float64) adsapi.Row {
+	return adsapi.Row{
+		"campaign":       map[string]any{"id": campaign, "name": "Campaign " + campaign},
+		"adGroup":        map[string]any{"id": adGroup},
+		"searchTermView": map[string]any{"searchTerm": term, "status": status},
+		"metrics": map[string]any{
+			"impressions": "100",
+			"clicks":      "10",
+			"costMicros":  cost,
+			"conversions": conversions,
+		},
+	}
+}
+
+var rows = []adsapi.Row{
+	termRow("1", "11", "cheap tents", "NONE", "30000000", 0),
+	termRow("1", "12", "Cheap  Tents", "NONE", "25000000", 0),
+	termRow("1", "11", "cheap tents", "NONE", "5000000", 0), // another keyword of ad group 11
+	termRow("2", "21", "cheap tents", "NONE", "40000000", 1),
+	termRow("1", "11", "tent pegs", "ADDED", "80000000", 0),
+	termRow("2", "21", "free tents", "NONE", "10000000", 0),
+}
+
+func TestQuery(t *testing.T) {
+	got := Query(gaql.DateRangeLast30Days, 123).String()
+	want := "SELECT campaign.id, campaign.name, ad_group.id, search_term_view.search_term, search_term_view.status, " +
+		"metrics.impressions, metrics.clicks, metrics.cost_micros, metrics.conversions FROM search_term_view " +
+		"WHERE search_term_view.status NOT IN ('EXCLUDED', 'ADDED_EXCLUDED') AND metrics.cost_micros > 0 " +
+		"AND campaign.id = 123 AND segments.date DURING LAST_30_DAYS ORDER BY metrics.cost_micros DESC"
+	if got != want {
+		t.Errorf("Query:\n got %s\nwant %s", got, want)
+	}
+	if _, err := gaql.Parse(got); err != nil {
+		t.Errorf("Query does not parse: %v", err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		level string
+		want  []Term
+	}{
+		{ByCampaign, []Term{
+			{Text: "tent pegs", CampaignID: "1", CampaignName: "Campaign 1", Campaigns: 1, AdGroups: 1, Added: true, Impressions: 100, Clicks: 10, CostMicros: 80000000},
+			{Text: "cheap tents", CampaignID: "1", CampaignName: "Campaign 1", Campaigns: 1, AdGroups: 2, Impressions: 300, Clicks: 30, CostMicros: 60000000},
+			{Text: "cheap tents", CampaignID: "2", CampaignName: "Campaign 2", Campaigns: 1, AdGroups: 1, Impressions: 100, Clicks: 10, CostMicros: 40000000, Conversions: 1},
+			{Text: "free tents", CampaignID: "2", CampaignName: "Campaign 2", Campaigns: 1, AdGroups: 1, Impressions: 100, Clicks: 10, CostMicros: 10000000},
+		}},
+		{ByAccount, []Term{
+			{Text: "cheap tents", Campaigns: 2, AdGroups: 3, Impressions: 400, Clicks: 40, CostMicros: 100000000, Conversions: 1},
+			{Text: "tent pegs", Campaigns: 1, AdGroups: 1, Added: true, Impressions: 100, Clicks: 10, CostMicros: 80000000},
+			{Text: "free tents", Campaigns: 1, AdGroups: 1, Impressions: 100, Clicks: 10, CostMicros: 10000000},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := Aggregate(rows, tt.level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d terms, want %d", len(got), len(tt.want))
+			}
+			for i, term := range got {
+				if *term != tt.want[i] {
+					t.Errorf("term %d = %+v, want %+v", i, *term, tt.want[i])
+				}
+			}
+		})
+	}
+	if _, err := Aggregate(rows, "ad_group"); err == nil {
+		t.Error("Aggregate(ad_group) succeeded")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	terms, _ := Aggregate(rows, ByCampaign)
+	tests := []struct {
+		name          string
+		minCost       int64
+		noConversions bool
+		want          []string // negatives
+	}{
+		{"all", 0, false, []string{"", "[cheap tents]", "", "[free tents]"}},
+		{"min cost", 50000000, false, []string{"", "[cheap tents]"}},
+		{"no conversions", 0, true, []string{"", "[cheap tents]", "[free tents]"}},
+		{"both", 20000000, true, []string{"", "[cheap tents]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Filter(terms, tt.minCost, tt.noConversions)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d terms, want %d", len(got), len(tt.want))
+			}
+			for i, term := range got {
+				if term.Negative() != tt.want[i] {
+					t.Errorf("term %d (%s) negative = %q, want %q", i, term.Text, term.Negative(), tt.want[i])
+				}
+			}
+		})
+	}
+}
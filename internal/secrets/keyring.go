@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Keyring reads secrets from the OS keychain: the login keychain through
+// security(1) on macOS, the Secret Service through secret-tool(1) on
+// Linux, or the Credential Manager through Windows PowerShell on
+// Windows. Each secret is an item of Service whose account is the secret
+// name.
+type Keyring struct {
+	Service string
+}
+
+func (k Keyring) String() string {
+	return fmt.Sprintf("OS keychain (service %s)", k.Service)
+}
+
+// KeyringAvailable reports whether the keychain tool for this OS is
+// installed.
+func KeyringAvailable() bool {
+	tool := map[string]string{"darwin": "security", "linux": "secret-tool", "windows": "powershell"}[runtime.GOOS]
+	if tool == "" {
+		return false
+	}
+	_, err := exec.LookPath(tool)
+	return err == nil
+}
+
+// Secret returns the item for name.
+func (k Keyring) Secret(ctx context.Context, name string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", k.Service, "-a", name, "-w")
+	case "linux":
+		cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", k.Service, "account", name)
+	case "windows":
+		cmd = k.powershell(ctx, name, `$c = $vault.Retrieve($env:ADTAP_KEYRING_SERVICE, $env:ADTAP_KEYRING_ACCOUNT)
+$c.RetrievePassword()
+[Console]::Out.Write($c.Password)`)
+	default:
+		return "", fmt.Errorf("secrets: no keychain support for %s", runtime.GOOS)
+	}
+	out, err := cmd.Output()
+	if len(bytes.TrimSpace(out)) == 0 {
+		// The tools exit non-zero, or print nothing, for a missing item.
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("secrets: reading keychain: %w", err)
+		}
+		return "", notFound(name, k)
+	}
+	if err != nil {
+		return "", fmt.Errorf("secrets: reading keychain: %w", err)
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+// SetSecret stores value as the item for name, replacing any existing
+// one.
+func (k Keyring) SetSecret(ctx context.Context, name, value string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security(1) takes the secret as an argument, which ps shows
+		// to every user, so the command is written to the stdin of
+		// security -i instead; -U updates an existing item.
+		if strings.ContainsAny(value, "\r\n") {
+			return errors.New("secrets: keychain secrets cannot contain line breaks")
+		}
+		cmd = exec.CommandContext(ctx, "security", "-i")
+		cmd.Stdin = strings.NewReader(securityCommand("add-generic-password", "-U", "-s", k.Service, "-a", name, "-w", value))
+	case "linux":
+		cmd = exec.CommandContext(ctx, "secret-tool", "store", "--label="+k.Service+" "+name, "service", k.Service, "account", name)
+		cmd.Stdin = strings.NewReader(value)
+	case "windows":
+		// The vault keeps one password per resource and user only when
+		// the old one is removed first.
+		cmd = k.powershell(ctx, name, `$value = [Console]::In.ReadToEnd()
+try { $vault.Remove($vault.Retrieve($env:ADTAP_KEYRING_SERVICE, $env:ADTAP_KEYRING_ACCOUNT)) } catch {}
+$vault.Add((New-Object Windows.Security.Credentials.PasswordCredential($env:ADTAP_KEYRING_SERVICE, $env:ADTAP_KEYRING_ACCOUNT, $value)))`)
+		cmd.Stdin = strings.NewReader(value)
+	default:
+		return fmt.Errorf("secrets: no keychain support for %s", runtime.GOOS)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("secrets: writing keychain: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	if runtime.GOOS == "darwin" {
+		// security -i reports a failed command only in its output.
+		if got, err := k.Secret(ctx, name); err != nil || got != value {
+			return fmt.Errorf("secrets: writing keychain: %s", strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// DeleteSecret removes the item for name; deleting a missing item is not
+// an error.
+func (k Keyring) DeleteSecret(ctx context.Context, name string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "security", "delete-generic-password", "-s", k.Service, "-a", name)
+	case "linux":
+		cmd = exec.CommandContext(ctx, "secret-tool", "clear", "service", k.Service, "account", name)
+	case "windows":
+		cmd = k.powershell(ctx, name, `$vault.Remove($vault.Retrieve($env:ADTAP_KEYRING_SERVICE, $env:ADTAP_KEYRING_ACCOUNT))`)
+	default:
+		return fmt.Errorf("secrets: no keychain support for %s", runtime.GOOS)
+	}
+	// A missing item makes security(1) and the vault fail; that is not
+	// an error here.
+	if _, err := k.Secret(ctx, name); errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secrets: deleting from keychain: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// securityCommand returns the command line of args for security -i,
+// which splits it on spaces outside double quotes.
+func securityCommand(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		a = strings.ReplaceAll(a, `\`, `\\`)
+		quoted[i] = `"` + strings.ReplaceAll(a, `"`, `\"`) + `"`
+	}
+	return strings.Join(quoted, " ") + "\n"
+}
+
+// powershellPrelude loads the Windows Runtime credential vault, which
+// Windows PowerShell 5.1 can reach but PowerShell 7 cannot, and makes
+// the secret travel as UTF-8.
+const powershellPrelude = `$ErrorActionPreference = 'Stop'
+[Console]::InputEncoding = [Console]::OutputEncoding = New-Object System.Text.UTF8Encoding $false
+[void][Windows.Security.Credentials.PasswordVault, Windows.Security.Credentials, ContentType = WindowsRuntime]
+$vault = New-Object Windows.Security.Credentials.PasswordVault
+`
+
+// powershell returns a command running script, after powershellPrelude,
+// with Windows PowerShell. The service and secret name are passed in the
+// environment so they need no quoting.
+func (k Keyring) powershell(ctx context.Context, name, script string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", powershellPrelude+script)
+	cmd.Env = append(os.Environ(), "ADTAP_KEYRING_SERVICE="+k.Service, "ADTAP_KEYRING_ACCOUNT="+name)
+	return cmd
+}
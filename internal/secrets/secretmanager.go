@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultSecretPrefix starts the Secret Manager secret of each name.
+const DefaultSecretPrefix = "adtap-"
+
+// SecretManager reads secrets from Google Cloud Secret Manager. Each
+// name is its own secret, named with Prefix and the name with
+// underscores replaced by hyphens, such as adtap-developer-token; the
+// latest version is read and cached.
+type SecretManager struct {
+	Project string
+	Prefix  string
+
+	// Token returns an access token with the cloud-platform scope, for
+	// example the Token method of auth.DefaultCredentials. It must be
+	// set before secrets are read.
+	Token func(ctx context.Context) (string, error)
+
+	// Endpoint is the API root; empty means
+	// https://secretmanager.googleapis.com.
+	Endpoint string
+
+	// HTTPClient is used for requests; nil means http.DefaultClient.
+	HTTPClient *http.Client
+
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewSecretManager returns a provider for the secrets of a project,
+// given as PROJECT or PROJECT/PREFIX. An empty project is taken from
+// GOOGLE_PROJECT_ID or GOOGLE_CLOUD_PROJECT. The caller sets Token.
+func NewSecretManager(location string) (*SecretManager, error) {
+	project, prefix, hasPrefix := strings.Cut(location, "/")
+	if !hasPrefix {
+		prefix = DefaultSecretPrefix
+	}
+	project = cmp.Or(project, os.Getenv("GOOGLE_PROJECT_ID"), os.Getenv("GOOGLE_CLOUD_PROJECT"))
+	if project == "" {
+		return nil, fmt.Errorf("secrets: no Google Cloud project (use gcp:PROJECT or set GOOGLE_PROJECT_ID)")
+	}
+	return &SecretManager{Project: project, Prefix: prefix}, nil
+}
+
+func (m *SecretManager) String() string {
+	return fmt.Sprintf("Secret Manager secrets %s* in project %s", m.Prefix, m.Project)
+}
+
+// SecretID returns the Secret Manager secret holding name.
+func (m *SecretManager) SecretID(name string) string {
+	return m.Prefix + strings.ReplaceAll(name, "_", "-")
+}
+
+// Secret returns the latest version of the secret holding name.
+func (m *SecretManager) Secret(ctx context.Context, name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.values[name]; ok {
+		if v == "" {
+			return "", notFound(name, m)
+		}
+		return v, nil
+	}
+	v, err := m.access(ctx, m.SecretID(name))
+	if err != nil {
+		return "", err
+	}
+	if m.values == nil {
+		m.values = map[string]string{}
+	}
+	m.values[name] = v
+	if v == "" {
+		return "", notFound(name, m)
+	}
+	return v, nil
+}
+
+// access reads the latest version of a secret. A missing secret, or one
+// without an enabled version, reads as empty.
+func (m *SecretManager) access(ctx context.Context, id string) (string, error) {
+	if m.Token == nil {
+		return "", fmt.Errorf("secrets: no Google Cloud credentials for %s", m)
+	}
+	token, err := m.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %w", err)
+	}
+	endpoint := cmp.Or(m.Endpoint, "https://secretmanager.googleapis.com")
+	u := fmt.Sprintf("%s/v1/projects/%s/secrets/%s/versions/latest:access",
+		strings.TrimRight(endpoint, "/"), url.PathEscape(m.Project), url.PathEscape(id))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	hc := m.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secrets: reading %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("secrets: reading %s: %w", id, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &e)
+		if e.Error.Status == "FAILED_PRECONDITION" {
+			// The latest version is disabled or destroyed.
+			return "", nil
+		}
+		msg := cmp.Or(e.Error.Message, http.StatusText(resp.StatusCode))
+		return "", fmt.Errorf("secrets: reading %s in project %s: HTTP %d: %s", id, m.Project, resp.StatusCode, msg)
+	}
+	var out struct {
+		Payload struct {
+			Data       string `json:"data"`
+			DataCrc32c string `json:"dataCrc32c"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("secrets: decoding %s: %w", id, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("secrets: decoding %s: %w", id, err)
+	}
+	if out.Payload.DataCrc32c != "" {
+		want, err := strconv.ParseUint(out.Payload.DataCrc32c, 10, 32)
+		if err != nil || crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) != uint32(want) {
+			return "", fmt.Errorf("secrets: %s failed its checksum", id)
+		}
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,87 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecretManager(t *testing.T) {
+	payload := func(data string) string {
+		sum := crc32.Checksum([]byte(data), crc32.MakeTable(crc32.Castagnoli))
+		return fmt.Sprintf(`{"name": "projects/123/secrets/x/versions/2", "payload": {"data": %q, "dataCrc32c": "%d"}}`,
+			base64.StdEncoding.EncodeToString([]byte(data)), sum)
+	}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.Header.Get("Authorization") != "Bearer gcp-token":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": {"code": 403, "message": "Permission 'secretmanager.versions.access' denied", "status": "PERMISSION_DENIED"}}`))
+		case r.URL.Path == "/v1/projects/ads-tools/secrets/adtap-developer-token/versions/latest:access":
+			w.Write([]byte(payload("dev-token\n")))
+		case r.URL.Path == "/v1/projects/ads-tools/secrets/adtap-refresh-token/versions/latest:access":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"code": 400, "message": "is in DISABLED state", "status": "FAILED_PRECONDITION"}}`))
+		case r.URL.Path == "/v1/projects/ads-tools/secrets/adtap-client-id/versions/latest:access":
+			w.Write([]byte(`{"payload": {"data": "aWQ=", "dataCrc32c": "1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": 404, "message": "Secret not found", "status": "NOT_FOUND"}}`))
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	token := func(context.Context) (string, error) { return "gcp-token", nil }
+
+	m := &SecretManager{Project: "ads-tools", Prefix: DefaultSecretPrefix, Token: token, Endpoint: srv.URL}
+	for i := 0; i < 2; i++ {
+		if got, err := m.Secret(ctx, DeveloperToken); err != nil || got != "dev-token" {
+			t.Errorf("developer token = %q, %v", got, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1 (values are cached)", requests)
+	}
+	for _, name := range []string{Credentials, RefreshToken} {
+		if _, err := m.Secret(ctx, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+	}
+	if _, err := m.Secret(ctx, ClientID); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+
+	denied := &SecretManager{Project: "ads-tools", Prefix: DefaultSecretPrefix, Endpoint: srv.URL,
+		Token: func(context.Context) (string, error) { return "wrong", nil }}
+	if _, err := denied.Secret(ctx, DeveloperToken); err == nil || !strings.Contains(err.Error(), "HTTP 403: Permission") {
+		t.Errorf("expected a permission error, got %v", err)
+	}
+
+	unset := &SecretManager{Project: "ads-tools", Endpoint: srv.URL}
+	if _, err := unset.Secret(ctx, DeveloperToken); err == nil {
+		t.Error("Secret without Token succeeded")
+	}
+}
+
+func TestNewSecretManager(t *testing.T) {
+	t.Setenv("GOOGLE_PROJECT_ID", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	if _, err := NewSecretManager(""); err == nil {
+		t.Error("NewSecretManager without a project succeeded")
+	}
+	m, err := NewSecretManager("ads-tools/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.SecretID(ClientSecret); got != "client-secret" {
+		t.Errorf("SecretID with an empty prefix = %q, want client-secret", got)
+	}
+}
@@ -0,0 +1,145 @@
+// Package secrets looks up the secrets adtap needs — the developer
+// token and OAuth2 credentials — in a configurable backend, so
+// deployments can keep them out of environment variables.
+//
+// A Provider returns secrets by name. The backends are:
+//
+//	env                     GOOGLE_ADS_<NAME> environment variables
+//	file:PATH               a JSON object of names to values, readable only by its owner
+//	keyring[:SERVICE]       the OS keychain (service "adtap" by default)
+//	vault:MOUNT/PATH        a HashiCorp Vault KV version 2 secret
+//	gcp[:PROJECT[/PREFIX]]  Google Cloud Secret Manager, one secret per name
+//
+// Open selects one from such a specification, which comes from the
+// secrets setting of a config profile or ADTAP_SECRETS.
+//
+// # Basic Usage
+//
+//	p, err := secrets.Open("vault:secret/adtap")
+//	token, err := p.Secret(ctx, secrets.DeveloperToken)
+//	if errors.Is(err, secrets.ErrNotFound) {
+//		// fall back to another source
+//	}
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Secret names.
+const (
+	DeveloperToken = "developer_token"
+	ClientID       = "client_id"
+	ClientSecret   = "client_secret"
+	RefreshToken   = "refresh_token"
+
+	// Credentials is a whole Google JSON credentials file, such as a
+	// service account key.
+	Credentials = "credentials"
+)
+
+// ErrNotFound is returned by Provider.Secret for a secret the backend
+// does not hold.
+var ErrNotFound = errors.New("secrets: not found")
+
+// Provider looks up secrets by name.
+type Provider interface {
+	// Secret returns the named secret, or an error wrapping ErrNotFound.
+	Secret(ctx context.Context, name string) (string, error)
+
+	// String describes the backend, without revealing secrets.
+	String() string
+}
+
+// Open returns the provider selected by spec: env, file:PATH,
+// keyring[:SERVICE], vault:MOUNT/PATH, or gcp[:PROJECT[/PREFIX]]. The
+// Token of a SecretManager is left for the caller to set.
+func Open(spec string) (Provider, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "env":
+		if arg != "" {
+			break
+		}
+		return Env{}, nil
+	case "file":
+		if arg == "" {
+			return nil, errors.New("secrets: file needs a path (file:PATH)")
+		}
+		return File{Path: arg}, nil
+	case "keyring":
+		if arg == "" {
+			arg = "adtap"
+		}
+		return Keyring{Service: arg}, nil
+	case "vault":
+		return NewVault(arg)
+	case "gcp":
+		return NewSecretManager(arg)
+	}
+	return nil, fmt.Errorf("secrets: invalid provider %q (expected env, file:PATH, keyring[:SERVICE], vault:MOUNT/PATH, or gcp[:PROJECT[/PREFIX]])", spec)
+}
+
+// notFound wraps ErrNotFound with the secret name and where it was
+// looked for.
+func notFound(name string, p Provider) error {
+	return fmt.Errorf("%w: %s in %s", ErrNotFound, name, p)
+}
+
+// Env reads secrets from environment variables named GOOGLE_ADS_ and the
+// upper-cased secret name, such as GOOGLE_ADS_DEVELOPER_TOKEN.
+type Env struct{}
+
+func (Env) String() string { return "environment" }
+
+// Secret returns the value of the secret's environment variable.
+func (e Env) Secret(_ context.Context, name string) (string, error) {
+	if v := os.Getenv("GOOGLE_ADS_" + strings.ToUpper(name)); v != "" {
+		return v, nil
+	}
+	return "", notFound(name, e)
+}
+
+// File reads secrets from a JSON object keyed by secret name. The file
+// must not be readable by other users.
+type File struct {
+	Path string
+}
+
+func (f File) String() string { return f.Path }
+
+// Secret reads the file and returns the named value.
+func (f File) Secret(_ context.Context, name string) (string, error) {
+	fi, err := os.Stat(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %w", err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("secrets: %s is accessible by other users; run chmod 600 %s", f.Path, f.Path)
+	}
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %w", err)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return "", fmt.Errorf("secrets: parsing %s: %w", f.Path, err)
+	}
+	raw, ok := values[name]
+	if !ok || string(raw) == "null" || string(raw) == `""` {
+		return "", notFound(name, f)
+	}
+	// Strings are returned as is, and other values, such as an inline
+	// credentials object, as JSON.
+	var v string
+	if json.Unmarshal(raw, &v) == nil {
+		return v, nil
+	}
+	return string(raw), nil
+}
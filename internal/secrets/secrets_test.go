@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "https://vault.example.com")
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	t.Setenv("GOOGLE_PROJECT_ID", "ads-tools")
+	tests := []struct {
+		spec    string
+		want    string // String() of the provider
+		wantErr string
+	}{
+		{spec: "env", want: "environment"},
+		{spec: "file:/etc/adtap/secrets.json", want: "/etc/adtap/secrets.json"},
+		{spec: "keyring", want: "OS keychain (service adtap)"},
+		{spec: "keyring:adtap-prod", want: "OS keychain (service adtap-prod)"},
+		{spec: "vault:secret/adtap", want: "Vault secret secret/adtap at https://vault.example.com"},
+		{spec: "gcp", want: "Secret Manager secrets adtap-* in project ads-tools"},
+		{spec: "gcp:agency-project/agency-", want: "Secret Manager secrets agency-* in project agency-project"},
+		{spec: "vault:secret", wantErr: "invalid Vault secret"},
+		{spec: "file:", wantErr: "needs a path"},
+		{spec: "env:X", wantErr: "invalid provider"},
+		{spec: "lastpass", wantErr: "invalid provider"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			p, err := Open(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || p.String() != tt.want {
+				t.Errorf("got %v, %v; want %s", p, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("GOOGLE_ADS_DEVELOPER_TOKEN", "dev-token")
+	t.Setenv("GOOGLE_ADS_REFRESH_TOKEN", "")
+	ctx := context.Background()
+	if v, err := (Env{}).Secret(ctx, DeveloperToken); err != nil || v != "dev-token" {
+		t.Errorf("developer token = %q, %v", v, err)
+	}
+	if _, err := (Env{}).Secret(ctx, RefreshToken); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	os.WriteFile(path, []byte(`{"developer_token": "dev-token", "client_id": "", "credentials": {"type": "service_account"}}`), 0o600)
+	f := File{Path: path}
+	ctx := context.Background()
+
+	if v, err := f.Secret(ctx, DeveloperToken); err != nil || v != "dev-token" {
+		t.Errorf("developer token = %q, %v", v, err)
+	}
+	if v, err := f.Secret(ctx, Credentials); err != nil || v != `{"type": "service_account"}` {
+		t.Errorf("credentials = %q, %v", v, err)
+	}
+	for _, name := range []string{ClientID, RefreshToken} {
+		if _, err := f.Secret(ctx, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+	}
+
+	os.Chmod(path, 0o644)
+	if _, err := f.Secret(ctx, DeveloperToken); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("expected a permissions error, got %v", err)
+	}
+}
+
+func TestSecurityCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"add-generic-password", "-w", "1//token"}, `"add-generic-password" "-w" "1//token"` + "\n"},
+		{[]string{"-w", `a "quoted" \ secret`}, `"-w" "a \"quoted\" \\ secret"` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := securityCommand(tt.args...); got != tt.want {
+			t.Errorf("securityCommand(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
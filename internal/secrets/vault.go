@@ -0,0 +1,144 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Vault reads secrets from the fields of one HashiCorp Vault KV version 2
+// secret. The secret is fetched once and cached.
+type Vault struct {
+	Address   string // e.g. https://vault.example.com:8200
+	Token     string
+	Namespace string // Vault Enterprise namespace, if any
+	Mount     string // KV engine mount, e.g. "secret"
+	Path      string // secret path within the mount, e.g. "adtap"
+
+	// HTTPClient is used for requests; nil means http.DefaultClient.
+	HTTPClient *http.Client
+
+	mu     sync.Mutex
+	fields map[string]any
+}
+
+// NewVault returns a provider for the secret at MOUNT/PATH, configured
+// like the vault command: VAULT_ADDR, VAULT_TOKEN (or ~/.vault-token),
+// and VAULT_NAMESPACE.
+func NewVault(location string) (*Vault, error) {
+	mount, path, ok := strings.Cut(strings.Trim(location, "/"), "/")
+	if !ok || mount == "" || path == "" {
+		return nil, fmt.Errorf("secrets: invalid Vault secret %q (expected vault:MOUNT/PATH, e.g. vault:secret/adtap)", location)
+	}
+	v := &Vault{
+		Address:   os.Getenv("VAULT_ADDR"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+		Mount:     mount,
+		Path:      path,
+	}
+	if v.Address == "" {
+		return nil, fmt.Errorf("secrets: VAULT_ADDR is not set")
+	}
+	if v.Token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			data, _ := os.ReadFile(filepath.Join(home, ".vault-token"))
+			v.Token = strings.TrimSpace(string(data))
+		}
+	}
+	if v.Token == "" {
+		return nil, fmt.Errorf("secrets: no Vault token (set VAULT_TOKEN or run vault login)")
+	}
+	return v, nil
+}
+
+func (v *Vault) String() string {
+	return fmt.Sprintf("Vault secret %s/%s at %s", v.Mount, v.Path, v.Address)
+}
+
+// Secret returns the field name of the secret.
+func (v *Vault) Secret(ctx context.Context, name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.fields == nil {
+		fields, err := v.fetch(ctx)
+		if err != nil {
+			return "", err
+		}
+		v.fields = fields
+	}
+	switch val := v.fields[name].(type) {
+	case nil:
+		return "", notFound(name, v)
+	case string:
+		if val == "" {
+			return "", notFound(name, v)
+		}
+		return val, nil
+	default:
+		// An object, such as inline credentials, is returned as JSON.
+		data, err := json.Marshal(val)
+		return string(data), err
+	}
+}
+
+// fetch reads the latest version of the secret.
+func (v *Vault) fetch(ctx context.Context) (map[string]any, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(v.Address, "/"), v.Mount, v.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	hc := v.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: reading %s: %w", v, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: reading %s: %w", v, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// A missing secret holds no fields.
+		return map[string]any{}, nil
+	default:
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(body, &e)
+		msg := strings.Join(e.Errors, "; ")
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return nil, fmt.Errorf("secrets: reading %s: HTTP %d: %s", v, resp.StatusCode, msg)
+	}
+	var out struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("secrets: decoding %s: %w", v, err)
+	}
+	if out.Data.Data == nil {
+		return map[string]any{}, nil
+	}
+	return out.Data.Data, nil
+}
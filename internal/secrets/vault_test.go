@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVault(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.Header.Get("X-Vault-Token") != "vault-token":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+		case r.URL.Path == "/v1/secret/data/adtap":
+			w.Write([]byte(`{"data": {"data": {"developer_token": "dev-token", "credentials": {"type": "authorized_user"}}, "metadata": {"version": 3}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	t.Setenv("VAULT_NAMESPACE", "")
+	ctx := context.Background()
+
+	v, err := NewVault("secret/adtap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Secret(ctx, DeveloperToken); err != nil || got != "dev-token" {
+		t.Errorf("developer token = %q, %v", got, err)
+	}
+	if got, err := v.Secret(ctx, Credentials); err != nil || got != `{"type":"authorized_user"}` {
+		t.Errorf("credentials = %q, %v", got, err)
+	}
+	if _, err := v.Secret(ctx, RefreshToken); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1 (the secret is cached)", requests)
+	}
+
+	missing, _ := NewVault("secret/other")
+	if _, err := missing.Secret(ctx, DeveloperToken); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing secret: expected ErrNotFound, got %v", err)
+	}
+
+	denied, _ := NewVault("secret/adtap")
+	denied.Token = "wrong"
+	if _, err := denied.Secret(ctx, DeveloperToken); err == nil || !strings.Contains(err.Error(), "HTTP 403: permission denied") {
+		t.Errorf("expected a permission error, got %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+// Package serve exposes adtap's read-only capabilities over HTTP, for
+// tools that want to validate and run GAQL queries without shelling out
+// to the CLI.
+//
+// The handler answers three endpoints, each behind an API key sent as
+// "Authorization: Bearer KEY" or "X-API-Key: KEY":
+//
+//	POST /validate   {"query": "...", "api_version": "v23"}
+//	                 -> {"valid": true, "query": "...", "diagnostics": [...]}
+//	POST /search     {"customer_id": "1234567890", "query": "..."}
+//	                 -> NDJSON, one {"type": "row", "row": {...}} line per row,
+//	                    then error lines and a closing metadata line
+//	GET  /customers  -> {"customer_ids": ["1234567890", ...]}
+//
+// Search results stream as the envelope JSONL of the output package, so
+// a consumer can tell a complete result from one cut short: the
+// metadata line comes last, with "truncated" set when a budget left
+// rows out and the request IDs of the pages read, and a failure after
+// the first row arrives as an error line rather than an HTTP status. Failures before it, such as an invalid
+// query or an API error, are answered with a status and
+// {"error": {"status": "...", "message": "..."}}.
+//
+// Queries are validated by gaql.Check, as the MCP server validates them,
+// and held to the access policy, if any: denied resources and fields are
+// refused, redacted fields are returned as "[redacted]", and LIMIT and
+// date ranges are capped.
+//
+// # Basic Usage
+//
+//	h := serve.NewHandler(serve.Config{
+//		Client:  func() (*adsapi.Client, error) { return client, nil },
+//		APIKeys: []string{os.Getenv("ADTAP_API_KEY")},
+//	})
+//	err := http.ListenAndServe("localhost:8080", h)
+package serve
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+	"github.com/aygp-dr/adtap/internal/geo"
+	"github.com/aygp-dr/adtap/internal/output"
+	"github.com/aygp-dr/adtap/internal/rowflat"
+)
+
+// maxRequestBytes caps the JSON body of a request.
+const maxRequestBytes = 1 << 20
+
+// flushEvery is the number of rows written between flushes of a search
+// response, so rows reach the client as pages arrive without a flush
+// per row.
+const flushEvery = 100
+
+// Config configures a Handler.
+type Config struct {
+	// Client returns the API client. It is called on the first request
+	// that needs one, so /validate works without credentials, and again
+	// on later requests until it succeeds; an error is returned to the
+	// request that called it.
+	Client func() (*adsapi.Client, error)
+
+	// APIKeys are the keys clients may authenticate with. A handler
+	// without keys refuses every request.
+	APIKeys []string
+
+	// Access, when set, is the access policy queries are held to.
+	Access *gaql.AccessPolicy
+
+	// MaxRows and MaxBytes bound every search, as adsapi.WithMaxRows and
+	// adsapi.WithMaxBytes do; zero leaves them unbounded.
+	MaxRows  int
+	MaxBytes int64
+
+	// Logger, when set, logs every request at info level.
+	Logger *slog.Logger
+}
+
+// Handler serves the endpoints. It is safe for concurrent use.
+type Handler struct {
+	cfg  Config
+	keys [][]byte
+	mux  *http.ServeMux
+
+	mu     sync.Mutex
+	client *adsapi.Client
+}
+
+// NewHandler returns a handler serving the endpoints as cfg says.
+func NewHandler(cfg Config) *Handler {
+	h := &Handler{cfg: cfg, mux: http.NewServeMux()}
+	for _, k := range cfg.APIKeys {
+		if k = strings.TrimSpace(k); k != "" {
+			h.keys = append(h.keys, []byte(k))
+		}
+	}
+	h.mux.HandleFunc("POST /validate", h.validate)
+	h.mux.HandleFunc("POST /search", h.search)
+	h.mux.HandleFunc("GET /customers", h.customers)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	if h.authorized(r) {
+		h.mux.ServeHTTP(rec, r)
+	} else {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="adtap"`)
+		writeError(rec, http.StatusUnauthorized, output.ResultError{Status: "UNAUTHENTICATED", Message: "missing or unknown API key"})
+	}
+	if h.cfg.Logger != nil {
+		h.cfg.Logger.InfoContext(r.Context(), "request", "method", r.Method, "path", r.URL.Path,
+			"status", rec.status, "duration", time.Since(start), "remote", r.RemoteAddr)
+	}
+}
+
+// authorized reports whether r carries one of the API keys, comparing
+// in constant time.
+func (h *Handler) authorized(r *http.Request) bool {
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); key == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		key = auth[7:]
+	}
+	if key == "" {
+		return false
+	}
+	ok := 0
+	for _, k := range h.keys {
+		ok |= subtle.ConstantTimeCompare([]byte(key), k)
+	}
+	return ok == 1
+}
+
+// apiClient returns the client, creating it on first use. A failure is
+// not kept: the next request tries again, so a passing problem such as a
+// failed token refresh does not outlast it.
+func (h *Handler) apiClient() (*adsapi.Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.client != nil {
+		return h.client, nil
+	}
+	if h.cfg.Client == nil {
+		return nil, errors.New("no API client is configured")
+	}
+	client, err := h.cfg.Client()
+	if err != nil {
+		return nil, err
+	}
+	h.client = client
+	return client, nil
+}
+
+func (h *Handler) validate(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Query      string `json:"query"`
+		APIVersion string `json:"api_version"`
+	}
+	if !decode(w, r, &in) {
+		return
+	}
+	writeJSON(w, http.StatusOK, gaql.NewCheckResult(gaql.Check(in.Query, in.APIVersion, h.cfg.Access)))
+}
+
+func (h *Handler) search(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		CustomerID string `json:"customer_id"`
+		Query      string `json:"query"`
+		APIVersion string `json:"api_version"`
+	}
+	if !decode(w, r, &in) {
+		return
+	}
+	id, err := adsapi.NormalizeCustomerID(in.CustomerID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, output.ResultError{Status: "INVALID_ARGUMENT",
+			Message: fmt.Sprintf("invalid customer_id %q: expected 10 digits, e.g. 1234567890", in.CustomerID)})
+		return
+	}
+	q, _, err := gaql.Check(in.Query, in.APIVersion, h.cfg.Access)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, output.ResultError{Status: "INVALID_ARGUMENT", Message: err.Error()})
+		return
+	}
+	client, err := h.apiClient()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, output.ResultError{Status: "UNAVAILABLE", Message: err.Error()})
+		return
+	}
+	if h.cfg.MaxRows > 0 || h.cfg.MaxBytes > 0 {
+		client = client.WithBudget(h.cfg.MaxRows, h.cfg.MaxBytes)
+	}
+
+	// The first row is read before answering, so a query the API
+	// rejects gets an error status rather than an error line.
+	next, totals := client.SearchIterWithOptions(r.Context(), id, q.String(), adsapi.SearchOptions{})
+	row, err := next()
+	if err != nil && err != adsapi.Done && err != adsapi.ErrTruncated {
+		writeError(w, apiStatus(err), resultError(id, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	env, _ := output.NewEnvelopeRenderer(w, output.FormatJSONL)
+	env.Metadata = output.Metadata{Query: q.String(), Accounts: 1}
+	opts := output.Options{RawEnums: true, Constants: geo.Default()}
+	if h.cfg.Access != nil {
+		opts.Redact = h.cfg.Access.Redacts
+	}
+	fields := q.FieldNames()
+	flat := rowflat.Compile(fields)
+	values := make([]any, len(fields))
+	flusher, _ := w.(http.Flusher)
+	// A write error means the client went away; the rest is dropped.
+	failed := env.WriteHeader(fields) != nil
+	for n := 1; err == nil && !failed; n++ {
+		failed = opts.WriteRecord(env, fields, flat.Values(row, values)) != nil
+		if flusher != nil && n%flushEvery == 0 {
+			flusher.Flush()
+		}
+		row, err = next()
+	}
+	switch {
+	case failed:
+		return
+	case err == adsapi.ErrTruncated:
+		env.Metadata.Truncated = true
+	case err != adsapi.Done:
+		env.Errors = append(env.Errors, resultError(id, err))
+		env.Metadata.FailedAccounts = 1
+	}
+	env.Metadata.RequestIDs = totals.Metadata.RequestIDs
+	env.Metadata.Pages = totals.Metadata.Pages
+	env.Metadata.DurationMS = totals.Metadata.Duration.Milliseconds()
+	env.Flush()
+}
+
+func (h *Handler) customers(w http.ResponseWriter, r *http.Request) {
+	client, err := h.apiClient()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, output.ResultError{Status: "UNAVAILABLE", Message: err.Error()})
+		return
+	}
+	ids, err := client.ListAccessibleCustomers(r.Context())
+	if err != nil {
+		writeError(w, apiStatus(err), resultError("", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"customer_ids": ids})
+}
+
+// decode reads the JSON body of r into v, answering 400 and returning
+// false when it cannot.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		msg := "invalid JSON body: " + err.Error()
+		if err == io.EOF {
+			msg = "a JSON body is required"
+		}
+		writeError(w, http.StatusBadRequest, output.ResultError{Status: "INVALID_ARGUMENT", Message: msg})
+		return false
+	}
+	return true
+}
+
+// resultError describes the failure of a request to the API.
+func resultError(customerID string, err error) output.ResultError {
+	re := output.ResultError{CustomerID: customerID, Message: err.Error()}
+	var apiErr *adsapi.APIError
+	if errors.As(err, &apiErr) {
+		re.Status, re.Message, re.RequestID = apiErr.Status, apiErr.Message, apiErr.RequestID
+	}
+	return re
+}
+
+// apiStatus returns the HTTP status answering a failed API request: the
+// API's own for errors of the request, such as an invalid query or a
+// customer without access, and 502 for the rest.
+func apiStatus(err error) int {
+	var apiErr *adsapi.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests:
+			return apiErr.StatusCode
+		}
+	}
+	return http.StatusBadGateway
+}
+
+func writeError(w http.ResponseWriter, status int, re output.ResultError) {
+	writeJSON(w, status, map[string]any{"error": re})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// statusRecorder keeps the status of a response for the log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets search responses stream through the recorder.
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,264 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// newTestServer serves a handler configured by cfg, backed by a fake API that answers searches
+// with two campaigns, each on its own page, and rejects queries of
+// ad_group.
+func newTestServer(t *testing.T, cfg Config) *httptest.Server {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "customers:listAccessibleCustomers") {
+			w.Write([]byte(`{"resourceNames": ["customers/1234567890", "customers/2345678901"]}`))
+			return
+		}
+		var req struct {
+			Query     string `json:"query"`
+			PageToken string `json:"pageToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case strings.Contains(req.Query, "FROM ad_group"):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"code": 400, "status": "INVALID_ARGUMENT", "message": "bad query"}}`))
+		case req.PageToken == "":
+			w.Write([]byte(`{"results": [{"campaign": {"id": "1", "name": "Brand"}}], "nextPageToken": "page-2"}`))
+		default:
+			w.Write([]byte(`{"results": [{"campaign": {"id": "2", "name": "Generic"}}]}`))
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	cfg.APIKeys = []string{"secret"}
+	cfg.Client = func() (*adsapi.Client, error) {
+		return adsapi.New("dev-token", adsapi.StaticToken("access-token"), adsapi.WithEndpoint(api.URL)), nil
+	}
+	srv := httptest.NewServer(NewHandler(cfg))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, srv *httptest.Server, method, path, key, body string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestAuth(t *testing.T) {
+	srv := newTestServer(t, Config{})
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no key", "", "", http.StatusUnauthorized},
+		{"wrong key", "Authorization", "Bearer wrong", http.StatusUnauthorized},
+		{"not bearer", "Authorization", "Basic secret", http.StatusUnauthorized},
+		{"bearer", "Authorization", "Bearer secret", http.StatusOK},
+		{"bearer lower case", "Authorization", "bearer secret", http.StatusOK},
+		{"header", "X-API-Key", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", srv.URL+"/customers", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	// A handler without keys refuses everyone.
+	h := NewHandler(Config{APIKeys: []string{" "}})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/customers", nil)
+	req.Header.Set("X-API-Key", " ")
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("empty key: got status %d", rec.Code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	srv := newTestServer(t, Config{Access: &gaql.AccessPolicy{DenyResources: []string{"change_event"}}})
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantValid bool
+		wantText  string
+	}{
+		{"valid", `{"query": "SELECT campaign.id FROM campaign"}`, 200, true, `"query":"SELECT campaign.id FROM campaign"`},
+		{"syntax error", `{"query": "SELECT FROM campaign"}`, 200, false, `"error"`},
+		{"denied", `{"query": "SELECT change_event.change_date_time FROM change_event"}`, 200, false, "change_event"},
+		{"no query", `{}`, 200, false, "query is required"},
+		{"unknown field", `{"qeury": "SELECT campaign.id FROM campaign"}`, 400, false, "INVALID_ARGUMENT"},
+		{"no body", ``, 400, false, "a JSON body is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := do(t, srv, "POST", "/validate", "secret", tt.body)
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("got status %d, want %d: %s", resp.StatusCode, tt.wantCode, body)
+			}
+			if got := strings.Contains(body, `"valid":true`); got != tt.wantValid {
+				t.Errorf("valid = %v, want %v: %s", got, tt.wantValid, body)
+			}
+			if !strings.Contains(body, tt.wantText) {
+				t.Errorf("body %s does not contain %q", body, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		body     string
+		wantCode int
+		wantRows int
+		wantMeta string
+		wantText string
+	}{
+		{"rows", Config{}, `{"customer_id": "123-456-7890", "query": "SELECT campaign.id, campaign.name FROM campaign"}`,
+			200, 2, `"truncated":false`, `"campaign.name":"Generic"`},
+		{"row budget", Config{MaxRows: 1}, `{"customer_id": "1234567890", "query": "SELECT campaign.id FROM campaign"}`,
+			200, 1, `"truncated":true`, `"campaign.id":1}`},
+		{"redacted", Config{Access: &gaql.AccessPolicy{RedactFields: []string{"campaign.name"}}},
+			`{"customer_id": "1234567890", "query": "SELECT campaign.id, campaign.name FROM campaign"}`,
+			200, 2, `"rows":2`, `"campaign.name":"[redacted]"`},
+		{"denied", Config{Access: &gaql.AccessPolicy{DenyFields: []string{"campaign.name"}}},
+			`{"customer_id": "1234567890", "query": "SELECT campaign.name FROM campaign"}`,
+			400, 0, "", `"INVALID_ARGUMENT"`},
+		{"bad customer", Config{}, `{"customer_id": "12345", "query": "SELECT campaign.id FROM campaign"}`,
+			400, 0, "", "invalid customer_id"},
+		{"api error", Config{}, `{"customer_id": "1234567890", "query": "SELECT ad_group.id FROM ad_group"}`,
+			400, 0, "", `"message":"bad query"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.cfg)
+			resp, body := do(t, srv, "POST", "/search", "secret", tt.body)
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("got status %d, want %d: %s", resp.StatusCode, tt.wantCode, body)
+			}
+			if !strings.Contains(body, tt.wantText) {
+				t.Errorf("body %s does not contain %q", body, tt.wantText)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			lines := strings.Split(strings.TrimSpace(body), "\n")
+			rows := 0
+			for _, l := range lines {
+				var v struct{ Type string }
+				if err := json.Unmarshal([]byte(l), &v); err != nil {
+					t.Fatalf("line %q: %v", l, err)
+				}
+				if v.Type == "row" {
+					rows++
+				}
+			}
+			if rows != tt.wantRows {
+				t.Errorf("got %d rows, want %d", rows, tt.wantRows)
+			}
+			if last := lines[len(lines)-1]; !strings.Contains(last, `"type":"metadata"`) || !strings.Contains(last, tt.wantMeta) {
+				t.Errorf("last line %s is not metadata with %s", last, tt.wantMeta)
+			}
+		})
+	}
+}
+
+func TestCustomers(t *testing.T) {
+	srv := newTestServer(t, Config{})
+	resp, body := do(t, srv, "GET", "/customers", "secret", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"customer_ids":["1234567890","2345678901"]`) {
+		t.Errorf("got %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := do(t, srv, "POST", "/customers", "secret", ""); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /customers: got status %d", resp.StatusCode)
+	}
+
+	// Without a client, /validate still works and the rest fail.
+	h := NewHandler(Config{APIKeys: []string{"secret"}, Client: func() (*adsapi.Client, error) {
+		return nil, errors.New("no credentials")
+	}})
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"GET", "/customers", "", http.StatusServiceUnavailable},
+		{"POST", "/search", `{"customer_id": "1234567890", "query": "SELECT campaign.id FROM campaign"}`, http.StatusServiceUnavailable},
+		{"POST", "/validate", `{"query": "SELECT campaign.id FROM campaign"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("X-API-Key", "secret")
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: got status %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
+
+func TestClientRetried(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"resourceNames": ["customers/1234567890"]}`))
+	}))
+	t.Cleanup(api.Close)
+	calls := 0
+	h := NewHandler(Config{APIKeys: []string{"secret"}, Client: func() (*adsapi.Client, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("token refresh failed")
+		}
+		return adsapi.New("dev-token", adsapi.StaticToken("access-token"), adsapi.WithEndpoint(api.URL)), nil
+	}})
+
+	// A failure to create the client is not kept; a success is.
+	for i, want := range []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusOK} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/customers", nil)
+		req.Header.Set("X-API-Key", "secret")
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("request %d: got status %d, want %d: %s", i+1, rec.Code, want, rec.Body)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Client called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,179 @@
+// Package snapshot writes point-in-time copies of an account's
+// structure: one JSONL file of API rows per table and a manifest saying
+// what was taken, when, and by which query.
+//
+// Rows are written as the API returns them, one JSON object per line
+// with its keys sorted, so snapshots of the same account diff line by
+// line with ordinary tools, and the manifest's checksums tell unchanged
+// tables apart without reading them.
+//
+// # Basic Usage
+//
+//	m := snapshot.Manifest{CustomerID: id, APIVersion: "v23", TakenAt: time.Now().UTC()}
+//	for _, t := range tables {
+//		table, err := snapshot.WriteTable(dir, t.Name, t.Query, client.SearchIter(ctx, id, t.Query))
+//		m.Tables = append(m.Tables, table)
+//	}
+//	err := m.Write(dir)
+package snapshot
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+// ManifestFile is the name of the manifest in a snapshot directory.
+const ManifestFile = "manifest.json"
+
+// Manifest describes a snapshot.
+type Manifest struct {
+	CustomerID string    `json:"customer_id"`
+	APIVersion string    `json:"api_version"`
+	TakenAt    time.Time `json:"taken_at"`
+	Tables     []Table   `json:"tables"`
+}
+
+// Table describes one file of a snapshot.
+type Table struct {
+	Name  string `json:"name"`
+	File  string `json:"file,omitempty"`
+	Query string `json:"query"`
+	Rows  int    `json:"rows"`
+
+	// SHA256 is the hex checksum of the file.
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Error is why the table is missing, when its query failed.
+	Error string `json:"error,omitempty"`
+}
+
+// WriteTable writes the rows next returns to name.jsonl in dir, until
+// it returns adsapi.Done. The file is only put in place once every row
+// is written, so a failed query leaves no partial table; the returned
+// Table then records the error, which is also returned.
+func WriteTable(dir, name, query string, next func() (adsapi.Row, error)) (Table, error) {
+	t := Table{Name: name, Query: query}
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return t.failed(fmt.Errorf("snapshot: %w", err))
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(tmp, h))
+	for {
+		row, err := next()
+		if err == adsapi.Done {
+			break
+		}
+		if err != nil {
+			tmp.Close()
+			return t.failed(err)
+		}
+		data, err := json.Marshal(row)
+		if err != nil {
+			tmp.Close()
+			return t.failed(fmt.Errorf("snapshot: %s: %w", name, err))
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+		t.Rows++
+	}
+	err = w.Flush()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	t.File = name + ".jsonl"
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, t.File))
+	}
+	if err != nil {
+		t.File = ""
+		return t.failed(fmt.Errorf("snapshot: %w", err))
+	}
+	t.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return t, nil
+}
+
+func (t Table) failed(err error) (Table, error) {
+	t.Rows = 0
+	t.Error = err.Error()
+	return t, err
+}
+
+// Write writes the manifest to dir.
+func (m *Manifest) Write(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest reads the manifest of the snapshot in dir.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("snapshot: reading %s: %w", ManifestFile, err)
+	}
+	return &m, nil
+}
+
+// ErrNotEmpty is returned by Prepare for a directory that is not empty
+// and may not be replaced.
+var ErrNotEmpty = errors.New("snapshot: the directory is not empty")
+
+// Prepare creates dir, or checks that an existing one is empty. With
+// replace, a directory holding only an earlier snapshot is emptied for
+// the new one; other files are never removed.
+func Prepare(dir string, replace bool) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	m, err := ReadManifest(dir)
+	if !replace || err != nil {
+		return fmt.Errorf("%w: %s", ErrNotEmpty, dir)
+	}
+	ours := map[string]bool{ManifestFile: true}
+	for _, t := range m.Tables {
+		ours[t.File] = true
+	}
+	for _, e := range entries {
+		if !ours[e.Name()] {
+			return fmt.Errorf("%w: %s", ErrNotEmpty, filepath.Join(dir, e.Name()))
+		}
+	}
+	for _, e := range entries {
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+	}
+	return nil
+}
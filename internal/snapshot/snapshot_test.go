@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aygp-dr/adtap/internal/adsapi"
+)
+
+// rows returns an iterator over rows, failing with err after them when
+// err is not nil.
+func rows(err error, rs ...adsapi.Row) func() (adsapi.Row, error) {
+	return func() (adsapi.Row, error) {
+		if len(rs) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			return nil, adsapi.Done
+		}
+		r := rs[0]
+		rs = rs[1:]
+		return r, nil
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	dir := t.TempDir()
+	next := rows(nil,
+		adsapi.Row{"campaign": map[string]any{"name": "Brand", "id": "1"}},
+		adsapi.Row{"campaign": map[string]any{"name": "Tents", "id": "2"}},
+	)
+	table, err := WriteTable(dir, "campaigns", "SELECT campaign.id, campaign.name FROM campaign", next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.File != "campaigns.jsonl" || table.Rows != 2 || len(table.SHA256) != 64 {
+		t.Errorf("WriteTable = %+v", table)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "campaigns.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"campaign":{"id":"1","name":"Brand"}}` + "\n" + `{"campaign":{"id":"2","name":"Tents"}}` + "\n"
+	if string(data) != want {
+		t.Errorf("file =\n%s\nwant\n%s", data, want)
+	}
+
+	// The same rows give the same checksum.
+	again, err := WriteTable(t.TempDir(), "campaigns", "", rows(nil,
+		adsapi.Row{"campaign": map[string]any{"id": "1", "name": "Brand"}},
+		adsapi.Row{"campaign": map[string]any{"id": "2", "name": "Tents"}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.SHA256 != table.SHA256 {
+		t.Errorf("checksums differ: %s and %s", again.SHA256, table.SHA256)
+	}
+}
+
+func TestWriteTableError(t *testing.T) {
+	dir := t.TempDir()
+	failure := errors.New("queryError.PROHIBITED_RESOURCE_TYPE_IN_FROM_CLAUSE")
+	table, err := WriteTable(dir, "conversion_actions", "", rows(failure, adsapi.Row{"a": 1}))
+	if !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if table.File != "" || table.Rows != 0 || table.Error != failure.Error() {
+		t.Errorf("WriteTable = %+v", table)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("left %d files behind", len(entries))
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manifest{
+		CustomerID: "1234567890",
+		APIVersion: "v23",
+		TakenAt:    time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC),
+		Tables:     []Table{{Name: "campaigns", File: "campaigns.jsonl", Query: "SELECT campaign.id FROM campaign", Rows: 2}},
+	}
+	if err := m.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CustomerID != m.CustomerID || !got.TakenAt.Equal(m.TakenAt) || len(got.Tables) != 1 || got.Tables[0] != m.Tables[0] {
+		t.Errorf("ReadManifest = %+v, want %+v", got, m)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	snapshotDir := func(t *testing.T, extra ...string) string {
+		dir := t.TempDir()
+		m := &Manifest{Tables: []Table{{Name: "campaigns", File: "campaigns.jsonl"}}}
+		if err := m.Write(dir); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range append([]string{"campaigns.jsonl"}, extra...) {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	t.Run("new", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "a", "b")
+		if err := Prepare(dir, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(dir); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("snapshot", func(t *testing.T) {
+		dir := snapshotDir(t)
+		if err := Prepare(dir, false); !errors.Is(err, ErrNotEmpty) {
+			t.Errorf("Prepare = %v, want ErrNotEmpty", err)
+		}
+	})
+	t.Run("replace", func(t *testing.T) {
+		dir := snapshotDir(t)
+		if err := Prepare(dir, true); err != nil {
+			t.Fatal(err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("left %d files", len(entries))
+		}
+	})
+	t.Run("other files", func(t *testing.T) {
+		dir := snapshotDir(t, "notes.txt")
+		if err := Prepare(dir, true); !errors.Is(err, ErrNotEmpty) {
+			t.Errorf("Prepare = %v, want ErrNotEmpty", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "campaigns.jsonl")); err != nil {
+			t.Error("removed files of the snapshot before refusing")
+		}
+	})
+}
@@ -0,0 +1,50 @@
+// Package textenc reads text files in the encodings Windows tools write.
+//
+// Notepad may save UTF-8 with a byte order mark, and Windows PowerShell
+// 5.1 writes UTF-16 with one for redirections such as
+// `"SELECT ..." > query.gaql`. Decode turns both into plain UTF-8, so
+// query and configuration files read the same whichever tool wrote them.
+package textenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Byte order marks.
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Decode returns data as UTF-8 without a byte order mark. Data starting
+// with a UTF-16 byte order mark is converted from UTF-16; other data is
+// returned as is, minus any UTF-8 byte order mark. Carriage returns are
+// kept: the parsers reading the text treat them as white space.
+func Decode(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return fromUTF16(data[2:], binary.LittleEndian)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return fromUTF16(data[2:], binary.BigEndian)
+	default:
+		return data
+	}
+}
+
+func fromUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	out := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	return out
+}
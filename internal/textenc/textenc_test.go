@@ -0,0 +1,26 @@
+package textenc
+
+import "testing"
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"plain", []byte("SELECT campaign.id FROM campaign\n"), "SELECT campaign.id FROM campaign\n"},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFSELECT 'café'\r\n"), "SELECT 'café'\r\n"},
+		{"utf-16le", []byte{0xFF, 0xFE, 'S', 0, 0xE9, 0, '\r', 0, '\n', 0}, "Sé\r\n"},
+		{"utf-16be", []byte{0xFE, 0xFF, 0, 'S', 0, 0xE9}, "Sé"},
+		{"utf-16le surrogate pair", []byte{0xFF, 0xFE, 0x3D, 0xD8, 0x00, 0xDE}, "😀"},
+		{"utf-16 odd trailing byte", []byte{0xFF, 0xFE, 'S', 0, 'x'}, "S"},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Decode(tt.in)); got != tt.want {
+				t.Errorf("Decode(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Package timing accounts for where a command spends its time.
+//
+// A Recorder sums durations per Phase. The API client adds the time
+// spent obtaining tokens, connecting, waiting for the server, and reading
+// responses; the CLI adds the time spent formatting rows and writing
+// them out. The report tells a slow API apart from a slow sink.
+//
+// A nil *Recorder records nothing, so code can time phases
+// unconditionally.
+//
+// # Basic Usage
+//
+//	rec := timing.NewRecorder()
+//	c := adsapi.New(token, ts, adsapi.WithRecorder(rec))
+//	w := rec.Writer(os.Stdout) // time spent writing output
+//	// ...
+//	rec.WriteTo(os.Stderr)
+package timing
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phase names a part of a command's work.
+type Phase string
+
+const (
+	Auth    Phase = "auth"       // obtaining access tokens
+	Connect Phase = "connect"    // DNS, TCP, and TLS for new connections
+	Wait    Phase = "wait"       // rate limiting and retry backoff
+	Server  Phase = "server"     // request sent until the first response byte
+	Stream  Phase = "stream"     // reading and decoding response bodies
+	Format  Phase = "format"     // rendering rows
+	Write   Phase = "sink write" // writing rendered output
+)
+
+// Phases lists the phases in report order.
+var Phases = []Phase{Auth, Connect, Wait, Server, Stream, Format, Write}
+
+// Stat is the time recorded for one phase.
+type Stat struct {
+	Phase Phase
+	Total time.Duration
+	Count int // intervals recorded
+}
+
+// Recorder sums durations per phase. It is safe for concurrent use.
+type Recorder struct {
+	start time.Time
+
+	mu    sync.Mutex
+	stats map[Phase]*Stat
+
+	// now is replaced by tests.
+	now func() time.Time
+}
+
+// NewRecorder returns a Recorder whose wall clock starts now.
+func NewRecorder() *Recorder {
+	return &Recorder{start: time.Now(), stats: map[Phase]*Stat{}, now: time.Now}
+}
+
+// Add records d against phase, one of Phases.
+func (r *Recorder) Add(phase Phase, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.stats[phase]
+	if s == nil {
+		s = &Stat{Phase: phase}
+		r.stats[phase] = s
+	}
+	s.Total += d
+	s.Count++
+}
+
+// Start begins an interval of phase; calling the returned function ends
+// it and returns its length.
+func (r *Recorder) Start(phase Phase) func() time.Duration {
+	if r == nil {
+		return func() time.Duration { return 0 }
+	}
+	t := r.now()
+	return func() time.Duration {
+		d := r.now().Sub(t)
+		r.Add(phase, d)
+		return d
+	}
+}
+
+// Total returns the time recorded against phase so far.
+func (r *Recorder) Total(phase Phase) time.Duration {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s := r.stats[phase]; s != nil {
+		return s.Total
+	}
+	return 0
+}
+
+// Stats returns the phases with time recorded, in Phases order.
+func (r *Recorder) Stats() []Stat {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Stat
+	for _, p := range Phases {
+		if s := r.stats[p]; s != nil {
+			out = append(out, *s)
+		}
+	}
+	return out
+}
+
+// Elapsed returns the wall-clock time since the Recorder was created.
+func (r *Recorder) Elapsed() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.now().Sub(r.start)
+}
+
+// WriteTo writes a report with one line per phase: its total, its share
+// of the wall-clock time, and the number of intervals. Time outside every
+// phase is reported as other. Concurrent requests overlap, so the phases
+// can add up to more than the wall clock.
+func (r *Recorder) WriteTo(w io.Writer) (int64, error) {
+	wall := r.Elapsed()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Timing: %v wall clock\n", round(wall))
+	var sum time.Duration
+	line := func(name string, d time.Duration, count int) {
+		share := 0.0
+		if wall > 0 {
+			share = float64(d) / float64(wall) * 100
+		}
+		fmt.Fprintf(&sb, "  %-10s %10v %6.1f%%", name, round(d), share)
+		if count > 0 {
+			fmt.Fprintf(&sb, "  (%d)", count)
+		}
+		sb.WriteByte('\n')
+	}
+	for _, s := range r.Stats() {
+		line(string(s.Phase), s.Total, s.Count)
+		sum += s.Total
+	}
+	if sum < wall {
+		line("other", wall-sum, 0)
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// round shortens a duration for display.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// Writer returns a writer that records the time spent writing to w as
+// Write. With a nil Recorder it returns w itself.
+func (r *Recorder) Writer(w io.Writer) io.Writer {
+	if r == nil {
+		return w
+	}
+	return &writer{w: w, rec: r}
+}
+
+type writer struct {
+	w   io.Writer
+	rec *Recorder
+}
+
+func (tw *writer) Write(p []byte) (int, error) {
+	defer tw.rec.Start(Write)()
+	return tw.w.Write(p)
+}
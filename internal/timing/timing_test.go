@@ -0,0 +1,86 @@
+package timing
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a Recorder whose clock advances only through the
+// returned function.
+func fakeClock() (*Recorder, func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &Recorder{start: now, stats: map[Phase]*Stat{}}
+	r.now = func() time.Time { return now }
+	return r, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestRecorder(t *testing.T) {
+	r, advance := fakeClock()
+	stop := r.Start(Server)
+	advance(300 * time.Millisecond)
+	if d := stop(); d != 300*time.Millisecond {
+		t.Errorf("stop() = %v, want 300ms", d)
+	}
+	r.Add(Auth, 100*time.Millisecond)
+	r.Add(Server, 200*time.Millisecond)
+	advance(700 * time.Millisecond)
+
+	want := []Stat{
+		{Auth, 100 * time.Millisecond, 1},
+		{Server, 500 * time.Millisecond, 2},
+	}
+	got := r.Stats()
+	if len(got) != len(want) {
+		t.Fatalf("Stats() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Stats()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if d := r.Total(Server); d != 500*time.Millisecond {
+		t.Errorf("Total(Server) = %v, want 500ms", d)
+	}
+
+	var buf bytes.Buffer
+	r.WriteTo(&buf)
+	for _, line := range []string{
+		"Timing: 1s wall clock",
+		"auth            100ms   10.0%  (1)",
+		"server          500ms   50.0%  (2)",
+		"other           400ms   40.0%",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("report lacks %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	r.Add(Auth, time.Second)
+	r.Start(Format)()
+	if r.Stats() != nil || r.Total(Auth) != 0 {
+		t.Error("nil Recorder recorded time")
+	}
+	var buf bytes.Buffer
+	if w := r.Writer(&buf); w != &buf {
+		t.Error("nil Recorder wrapped the writer")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	r, _ := fakeClock()
+	var buf bytes.Buffer
+	w := r.Writer(&buf)
+	w.Write([]byte("a"))
+	w.Write([]byte("b"))
+	if buf.String() != "ab" {
+		t.Errorf("wrote %q, want ab", buf.String())
+	}
+	if s := r.Stats(); len(s) != 1 || s[0].Phase != Write || s[0].Count != 2 {
+		t.Errorf("Stats() = %v, want two writes", s)
+	}
+}
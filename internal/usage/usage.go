@@ -0,0 +1,233 @@
+// Package usage reports which resources, fields, segments, and metrics
+// a corpus of stored GAQL queries uses, and where, so the reach of an
+// API version migration can be judged before it is made.
+//
+// Every query of every source is parsed; each name in its SELECT, FROM,
+// WHERE, and ORDER BY clauses is recorded with its file, line, and
+// clause. Names the catalog marks deprecated, or does not know, are
+// flagged.
+//
+// # Basic Usage
+//
+//	a := usage.New()
+//	a.AddSource("reports/spend.gaql", src)
+//	r := a.Report()
+//	r.WriteText(os.Stdout, false)
+package usage
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aygp-dr/adtap/internal/gaql"
+)
+
+// Location is one use of a name.
+type Location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Query  string `json:"query"`  // statement label, e.g. "line 3" or its name
+	Clause string `json:"clause"` // SELECT, FROM, WHERE, or ORDER BY
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d (%s, %s)", l.File, l.Line, l.Column, l.Query, l.Clause)
+}
+
+// Item is a resource or field with every place it is used.
+type Item struct {
+	Name     string `json:"name"`
+	Category string `json:"category"` // RESOURCE, ATTRIBUTE, SEGMENT, or METRIC
+	Queries  int    `json:"queries"`  // distinct queries using it
+	Files    int    `json:"files"`    // distinct files using it
+
+	// Deprecated is the catalog's deprecation note; Unknown is set for
+	// names the catalog lacks, which may be misspelled or newer than it.
+	Deprecated string `json:"deprecated,omitempty"`
+	Unknown    bool   `json:"unknown,omitempty"`
+
+	Uses []Location `json:"uses"`
+}
+
+// Failure is a query that did not parse.
+type Failure struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Report is the usage found in a corpus.
+type Report struct {
+	Files    int       `json:"files"`
+	Queries  int       `json:"queries"`
+	Items    []Item    `json:"items"`
+	Failures []Failure `json:"failures"`
+}
+
+// Analyzer accumulates the usage of the sources added to it.
+type Analyzer struct {
+	// Catalog classifies names and flags deprecated and unknown ones.
+	// Nil uses gaql.DefaultCatalog.
+	Catalog *gaql.Catalog
+
+	files    map[string]bool
+	queries  int
+	items    map[string]*item
+	failures []Failure
+}
+
+// item is an Item being accumulated.
+type item struct {
+	uses    []Location
+	queries map[string]bool // file and label
+}
+
+// New returns an empty analyzer.
+func New() *Analyzer {
+	return &Analyzer{files: map[string]bool{}, items: map[string]*item{}}
+}
+
+// AddSource records the usage of every query in src, a file of
+// semicolon-separated queries with optional "--" comments. Queries that
+// do not parse are reported as failures.
+func (a *Analyzer) AddSource(file, src string) {
+	a.files[file] = true
+	for _, st := range gaql.SplitStatements(src) {
+		a.queries++
+		q, err := gaql.Parse(st.Text)
+		if err != nil {
+			a.failures = append(a.failures, Failure{File: file, Line: st.Line, Error: err.Error()})
+			continue
+		}
+		a.addQuery(file, st, q)
+	}
+}
+
+func (a *Analyzer) addQuery(file string, st gaql.Statement, q *gaql.Query) {
+	use := func(name string, span gaql.Span, clause string) {
+		it := a.items[name]
+		if it == nil {
+			it = &item{queries: map[string]bool{}}
+			a.items[name] = it
+		}
+		loc := Location{File: file, Line: st.Line, Column: 1, Query: st.Label(), Clause: clause}
+		if span.IsValid() {
+			loc.Line = st.Line + span.Start.Line - 1
+			loc.Column = span.Start.Column
+		}
+		it.uses = append(it.uses, loc)
+		it.queries[file+"\x00"+st.Label()] = true
+	}
+
+	for _, f := range q.Select {
+		use(f.Name, f.Span, "SELECT")
+	}
+	use(q.From, q.FromSpan, "FROM")
+	for _, c := range q.Where {
+		use(c.Field, c.FieldSpan, "WHERE")
+	}
+	for _, o := range q.OrderBy {
+		use(o.Field, o.Span, "ORDER BY")
+	}
+}
+
+// Report returns the usage found so far, resources first and then
+// attributes, segments, and metrics, each sorted by name.
+func (a *Analyzer) Report() Report {
+	catalog := a.Catalog
+	if catalog == nil {
+		catalog = gaql.DefaultCatalog()
+	}
+	r := Report{Files: len(a.files), Queries: a.queries, Items: []Item{}, Failures: slices.Clone(a.failures)}
+	for name, it := range a.items {
+		files := map[string]bool{}
+		for _, u := range it.uses {
+			files[u.File] = true
+		}
+		entry := Item{Name: name, Queries: len(it.queries), Files: len(files), Uses: it.uses}
+		if f, ok := catalog.Field(name); ok {
+			entry.Category, entry.Deprecated = f.Category, f.Deprecated
+		} else {
+			entry.Unknown = true
+			entry.Category = category(name, it.uses)
+		}
+		r.Items = append(r.Items, entry)
+	}
+	slices.SortFunc(r.Items, func(x, y Item) int {
+		return cmp.Or(cmp.Compare(categoryOrder(x.Category), categoryOrder(y.Category)), cmp.Compare(x.Name, y.Name))
+	})
+	if r.Failures == nil {
+		r.Failures = []Failure{}
+	}
+	return r
+}
+
+// category classifies a name the catalog does not know from its prefix
+// and where it is used.
+func category(name string, uses []Location) string {
+	switch {
+	case strings.HasPrefix(name, "metrics."):
+		return "METRIC"
+	case strings.HasPrefix(name, "segments."):
+		return "SEGMENT"
+	case uses[0].Clause == "FROM":
+		return "RESOURCE"
+	}
+	return "ATTRIBUTE"
+}
+
+func categoryOrder(category string) int {
+	return slices.Index([]string{"RESOURCE", "ATTRIBUTE", "SEGMENT", "METRIC"}, category)
+}
+
+// WriteText writes the report as a table of names with their query and
+// file counts; with locations, the uses of each name follow the table.
+func (r Report) WriteText(w io.Writer, locations bool) error {
+	fmt.Fprintf(w, "%d queries in %d files use %d resources and fields.\n\n", r.Queries, r.Files, len(r.Items))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCATEGORY\tQUERIES\tFILES\tNOTE")
+	for _, it := range r.Items {
+		note := ""
+		switch {
+		case it.Unknown:
+			note = "not in the catalog"
+		case it.Deprecated != "":
+			note = "deprecated: " + it.Deprecated
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", it.Name, it.Category, it.Queries, it.Files, note)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if locations {
+		for _, it := range r.Items {
+			fmt.Fprintf(w, "\n%s\n", it.Name)
+			for _, u := range it.Uses {
+				fmt.Fprintf(w, "  %s\n", u)
+			}
+		}
+	}
+	for _, f := range r.Failures {
+		if _, err := fmt.Fprintf(w, "\n%s:%d: not analyzed: %s", f.File, f.Line, f.Error); err != nil {
+			return err
+		}
+	}
+	if len(r.Failures) > 0 {
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+	return nil
+}
+
+// WriteJSON writes the report as one JSON object.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
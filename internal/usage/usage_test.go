@@ -0,0 +1,78 @@
+package usage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	a := New()
+	a.AddSource("spend.gaql", `-- name: spend
+SELECT campaign.id, metrics.cost_micros FROM campaign
+WHERE segments.date DURING LAST_7_DAYS
+ORDER BY metrics.cost_micros DESC;
+SELECT ad_group.id FROM ad_group WHERE campaign.id = 1;
+SELECT FROM;
+`)
+	a.AddSource("other.gaql", "SELECT campaign.id, campaign.no_such_field FROM campaign")
+	r := a.Report()
+
+	if r.Files != 2 || r.Queries != 4 {
+		t.Errorf("got %d files and %d queries, want 2 and 4", r.Files, r.Queries)
+	}
+	if len(r.Failures) != 1 || r.Failures[0].File != "spend.gaql" || r.Failures[0].Line != 6 {
+		t.Errorf("unexpected failures %+v", r.Failures)
+	}
+
+	var names []string
+	for _, it := range r.Items {
+		names = append(names, it.Name)
+	}
+	want := "ad_group campaign ad_group.id campaign.id campaign.no_such_field segments.date metrics.cost_micros"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("items\n got %s\nwant %s", got, want)
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		queries  int
+		files    int
+		unknown  bool
+		uses     []string
+	}{
+		{"campaign", "RESOURCE", 2, 2, false, []string{"spend.gaql:2:41 (spend, FROM)", "other.gaql:1:44 (line 1, FROM)"}},
+		{"campaign.id", "ATTRIBUTE", 3, 2, false, []string{"spend.gaql:2:8 (spend, SELECT)", "spend.gaql:5:40 (line 5, WHERE)", "other.gaql:1:8 (line 1, SELECT)"}},
+		{"campaign.no_such_field", "ATTRIBUTE", 1, 1, true, []string{"other.gaql:1:21 (line 1, SELECT)"}},
+		{"metrics.cost_micros", "METRIC", 1, 1, false, []string{"spend.gaql:2:21 (spend, SELECT)", "spend.gaql:4:10 (spend, ORDER BY)"}},
+	}
+	for _, tt := range tests {
+		var it Item
+		for _, i := range r.Items {
+			if i.Name == tt.name {
+				it = i
+			}
+		}
+		if it.Category != tt.category || it.Queries != tt.queries || it.Files != tt.files || it.Unknown != tt.unknown {
+			t.Errorf("%s: unexpected item %+v", tt.name, it)
+		}
+		var uses []string
+		for _, u := range it.Uses {
+			uses = append(uses, u.String())
+		}
+		if strings.Join(uses, "; ") != strings.Join(tt.uses, "; ") {
+			t.Errorf("%s: uses\n got %v\nwant %v", tt.name, uses, tt.uses)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"4 queries in 2 files", "not in the catalog", "spend.gaql:6: not analyzed"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("text report lacks %q:\n%s", s, buf.String())
+		}
+	}
+}
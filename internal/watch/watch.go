@@ -0,0 +1,146 @@
+// Package watch compares the results of successive runs of a query, so
+// that rows whose metrics changed since the previous run stand out.
+//
+// Rows are matched across runs by the values of their non-metric fields
+// (attributes and segments): a campaign on a date is the same row in
+// every run, while its clicks may change. Rows that share those values
+// are matched in the order they arrive.
+//
+// # Basic Usage
+//
+//	t := watch.NewTracker(q.FieldNames())
+//	for {
+//		rows := t.Update(run(q)) // values in the order of the fields
+//		for _, row := range watch.Changes(rows) {
+//			fmt.Println(row.Status, row.Values)
+//		}
+//		time.Sleep(5 * time.Minute)
+//	}
+package watch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Status tells how a row compares with the previous run.
+type Status string
+
+// Row statuses. Every row of the first run is Unchanged.
+const (
+	Unchanged Status = ""
+	Changed   Status = "changed" // a metric has another value
+	Added     Status = "new"     // not in the previous run
+	Removed   Status = "removed" // in the previous run only
+)
+
+// Row is a row of a run and how it compares with the previous run.
+type Row struct {
+	Status Status
+	Values []any // in field order; the last values seen for a Removed row
+	// Previous holds the values of a Changed row in the previous run.
+	Previous []any
+}
+
+// FieldChanged reports whether the value of field i changed.
+func (r Row) FieldChanged(i int) bool {
+	return r.Status == Changed && !equal(r.Values[i], r.Previous[i])
+}
+
+// Tracker remembers the rows of the last run. It is not safe for
+// concurrent use.
+type Tracker struct {
+	fields []string
+	metric []bool
+	prev   map[string][]any
+	order  []string // keys of prev, in row order
+	runs   int
+}
+
+// NewTracker returns a tracker for the results of a query selecting
+// fields.
+func NewTracker(fields []string) *Tracker {
+	metric := make([]bool, len(fields))
+	for i, f := range fields {
+		metric[i] = strings.HasPrefix(f, "metrics.")
+	}
+	return &Tracker{fields: fields, metric: metric}
+}
+
+// Runs returns the number of runs seen by Update.
+func (t *Tracker) Runs() int {
+	return t.runs
+}
+
+// Update compares the rows of a run, each holding its values in field
+// order, with the previous run. It returns them in the same order,
+// followed by the rows of the previous run that are gone.
+func (t *Tracker) Update(rows [][]any) []Row {
+	first := t.runs == 0
+	t.runs++
+	cur := make(map[string][]any, len(rows))
+	order := make([]string, 0, len(rows))
+	out := make([]Row, 0, len(rows))
+	for _, values := range rows {
+		key := t.key(values, cur)
+		cur[key] = values
+		order = append(order, key)
+
+		row := Row{Values: values}
+		if prev, ok := t.prev[key]; ok {
+			for i := range values {
+				if t.metric[i] && !equal(values[i], prev[i]) {
+					row.Status, row.Previous = Changed, prev
+					break
+				}
+			}
+		} else if !first {
+			row.Status = Added
+		}
+		out = append(out, row)
+	}
+	for _, key := range t.order {
+		if _, ok := cur[key]; !ok {
+			out = append(out, Row{Status: Removed, Values: t.prev[key]})
+		}
+	}
+	t.prev, t.order = cur, order
+	return out
+}
+
+// key identifies a row by its non-metric values, numbered when rows of
+// the run in seen share them.
+func (t *Tracker) key(values []any, seen map[string][]any) string {
+	var sb strings.Builder
+	for i, v := range values {
+		if !t.metric[i] {
+			fmt.Fprintf(&sb, "%v\x00", v)
+		}
+	}
+	base := sb.String()
+	key := base
+	for n := 2; ; n++ {
+		if _, dup := seen[key]; !dup {
+			return key
+		}
+		key = base + "#" + strconv.Itoa(n)
+	}
+}
+
+// Changes returns the rows that are not Unchanged.
+func Changes(rows []Row) []Row {
+	var out []Row
+	for _, r := range rows {
+		if r.Status != Unchanged {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// equal compares values decoded from JSON, where the API encodes INT64
+// as strings and DOUBLE as numbers.
+func equal(a, b any) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
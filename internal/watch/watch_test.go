@@ -0,0 +1,72 @@
+package watch
+
+import (
+	"slices"
+	"testing"
+)
+
+func statuses(rows []Row) []Status {
+	out := make([]Status, len(rows))
+	for i, r := range rows {
+		out[i] = r.Status
+	}
+	return out
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker([]string{"campaign.id", "segments.device", "metrics.clicks", "metrics.ctr"})
+	runs := []struct {
+		name string
+		rows [][]any
+		want []Status
+	}{
+		{"first", [][]any{
+			{"1", "MOBILE", "10", 0.1},
+			{"1", "DESKTOP", "5", 0.2},
+			{"2", "MOBILE", "0", 0.0},
+		}, []Status{Unchanged, Unchanged, Unchanged}},
+		{"same", [][]any{
+			{"1", "MOBILE", "10", 0.1},
+			{"1", "DESKTOP", "5", 0.2},
+			{"2", "MOBILE", "0", 0.0},
+		}, []Status{Unchanged, Unchanged, Unchanged}},
+		{"changed, reordered, added, removed", [][]any{
+			{"1", "DESKTOP", "5", 0.25},
+			{"1", "MOBILE", "12", 0.1},
+			{"3", "MOBILE", "1", 1.0},
+		}, []Status{Changed, Changed, Added, Removed}},
+		{"duplicates matched in order", [][]any{
+			{"1", "DESKTOP", "5", 0.25},
+			{"1", "DESKTOP", "7", 0.25},
+		}, []Status{Unchanged, Added, Removed, Removed}},
+	}
+	for _, run := range runs {
+		got := tr.Update(run.rows)
+		if !slices.Equal(statuses(got), run.want) {
+			t.Errorf("%s: statuses %q, want %q", run.name, statuses(got), run.want)
+		}
+	}
+	if tr.Runs() != len(runs) {
+		t.Errorf("Runs() = %d, want %d", tr.Runs(), len(runs))
+	}
+}
+
+func TestFieldChanged(t *testing.T) {
+	tr := NewTracker([]string{"campaign.id", "metrics.clicks", "metrics.ctr"})
+	tr.Update([][]any{{"1", "10", 0.1}})
+	rows := tr.Update([][]any{{"1", "12", 0.1}})
+	if len(rows) != 1 || rows[0].Status != Changed {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	for i, want := range []bool{false, true, false} {
+		if got := rows[0].FieldChanged(i); got != want {
+			t.Errorf("FieldChanged(%d) = %v, want %v", i, got, want)
+		}
+	}
+	if rows[0].Previous[1] != "10" {
+		t.Errorf("Previous = %v", rows[0].Previous)
+	}
+	if got := Changes(tr.Update([][]any{{"1", "12", 0.1}})); len(got) != 0 {
+		t.Errorf("Changes of an unchanged run = %+v", got)
+	}
+}
@@ -0,0 +1,56 @@
+#!/bin/sh
+# bench-compare.sh compares the benchmarks of the working tree with those
+# of a base revision and fails when one got significantly slower.
+#
+# Usage: scripts/bench-compare.sh [BASE] [THRESHOLD]
+#
+# BASE is a git revision (default HEAD, the last commit); THRESHOLD is the slowdown in
+# percent that fails the comparison (default 10). Only changes benchstat
+# reports as significant (p < 0.05) count. Set BENCH to choose the
+# benchmarks (a -bench regexp, default .), COUNT for the runs of each
+# (default 10), and PACKAGES for the packages (default ./internal/...).
+#
+# benchstat is taken from PATH, or run with 'go run' when absent:
+#
+#	go install golang.org/x/perf/cmd/benchstat@latest
+set -eu
+
+base=${1:-HEAD}
+threshold=${2:-10}
+bench=${BENCH:-.}
+count=${COUNT:-10}
+packages=${PACKAGES:-./internal/...}
+
+root=$(git rev-parse --show-toplevel)
+work=$(mktemp -d "${TMPDIR:-/tmp}/adtap-bench.XXXXXX")
+trap 'git -C "$root" worktree remove --force "$work/base" >/dev/null 2>&1 || true; rm -rf "$work"' EXIT
+
+run() {
+	(cd "$1" && go test -run '^$' -bench "$bench" -benchmem -count "$count" $packages) >"$2"
+}
+
+echo "Benchmarking $base..." >&2
+git -C "$root" worktree add --detach --quiet "$work/base" "$base"
+run "$work/base" "$work/old.txt"
+echo "Benchmarking the working tree..." >&2
+run "$root" "$work/new.txt"
+
+if command -v benchstat >/dev/null 2>&1; then
+	benchstat "$work/old.txt" "$work/new.txt" | tee "$work/stat.txt"
+else
+	go run golang.org/x/perf/cmd/benchstat@latest "$work/old.txt" "$work/new.txt" | tee "$work/stat.txt"
+fi
+
+# benchstat marks significant changes as "+12.34% (p=0.000 n=10)". For
+# sec/op, B/op, and allocs/op higher is worse; B/s tables are skipped.
+awk -v limit="$threshold" '
+	/^ .*│/ && /vs base/ { throughput = /B\/s/ }
+	!throughput && /^[^ ].* ± / && match($0, /[+][0-9.]+% \(p=/) {
+		pct = substr($0, RSTART + 1, RLENGTH - 6) + 0
+		if (pct > limit) {
+			print "Regression: " $1 " +" pct "%" > "/dev/stderr"
+			failed = 1
+		}
+	}
+	END { exit failed }
+' "$work/stat.txt"